@@ -0,0 +1,318 @@
+/*
+ * achievement.go
+ *
+ * This file implements achievements: milestones such as a player's first
+ * kill, reaching a level, or exploring every room in an area. Earned
+ * achievements are saved with the character along with the kill count and
+ * the rooms the player has visited, announced to everyone on the info
+ * channel, and some of them award a new title. The 'achievements' command
+ * lists what a player has earned and their progress toward the rest.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exploreAchievementPrefix starts the ID of each area's exploration achievement
+const exploreAchievementPrefix = "explore:"
+
+// Achievement describes a milestone a player can reach
+type Achievement struct {
+	ID          string // Short ID saved with the character
+	Name        string // Name shown as the player's badge
+	Description string // What the player must do to earn it
+	Title       string // Title awarded when it's earned, if any
+	Kills       int    // Mobs the player must slay, if a kill milestone
+	Level       int    // Level the player must reach, if a level milestone
+}
+
+// achievements lists the kill and level milestones in the order they're shown
+var achievements = []Achievement{
+	{ID: "first_kill", Name: "First Blood", Description: "Slay your first creature", Kills: 1},
+	{ID: "kills_100", Name: "Centurion", Description: "Slay 100 creatures", Title: "the Centurion", Kills: 100},
+	{ID: "level_10", Name: "Seasoned Adventurer", Description: "Reach level 10", Title: "the Seasoned", Level: 10},
+}
+
+// exploreAchievement returns the achievement for exploring every room in an area
+func exploreAchievement(areaName string) Achievement {
+	name := areaName
+	if area := GetArea(areaName); area != nil && area.Name != "" {
+		name = area.Name
+	}
+	return Achievement{
+		ID:          exploreAchievementPrefix + areaName,
+		Name:        "Explorer of " + name,
+		Description: "Visit every room in " + name,
+	}
+}
+
+// findAchievement looks up an achievement by ID
+func findAchievement(id string) (Achievement, bool) {
+	if strings.HasPrefix(id, exploreAchievementPrefix) {
+		return exploreAchievement(strings.TrimPrefix(id, exploreAchievementPrefix)), true
+	}
+	for _, a := range achievements {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// HasAchievement reports whether the player has earned the given achievement
+func (p *Player) HasAchievement(id string) bool {
+	_, ok := p.Achievements[id]
+	return ok
+}
+
+// AwardAchievement records an achievement, announces it, and grants its title
+// Returns false if the player had already earned it.
+func (p *Player) AwardAchievement(a Achievement) bool {
+	if p.HasAchievement(a.ID) {
+		return false
+	}
+
+	if p.Achievements == nil {
+		p.Achievements = make(map[string]time.Time)
+	}
+	now := time.Now()
+	p.Achievements[a.ID] = now
+
+	if err := AddPlayerAchievement(p.Name, a.ID, now); err != nil {
+		log.Printf("Error saving achievement %s for %s: %v", a.ID, p.Name, err)
+	}
+	log.Printf("[ACHIEVEMENT] %s earned %s.", p.Name, a.ID)
+
+	AnnounceInfo(fmt.Sprintf("%s has earned the achievement {W}%s{x}!", p.Name, a.Name))
+
+	if a.Title != "" {
+		p.Title = a.Title
+		if err := UpdatePlayerTitle(p.Name, a.Title); err != nil {
+			log.Printf("Error saving achievement title for %s: %v", p.Name, err)
+		}
+		p.Send(fmt.Sprintf("You are now known as %s %s.", p.Name, a.Title))
+	}
+	return true
+}
+
+// RecordKill counts a slain mob toward the player's kill milestones
+func (p *Player) RecordKill() {
+	p.Kills++
+	if err := UpdatePlayerKills(p.Name, p.Kills); err != nil {
+		log.Printf("Error saving kill count for %s: %v", p.Name, err)
+	}
+
+	for _, a := range achievements {
+		if a.Kills > 0 && p.Kills >= a.Kills {
+			p.AwardAchievement(a)
+		}
+	}
+}
+
+// onAchievementKill counts a mob the player slew themselves toward their kill milestones
+func onAchievementKill(event Event) {
+	if event.Killer == nil {
+		event.Player.RecordKill()
+	}
+}
+
+// onAchievementLevel awards any level milestones a player reached on leveling
+func onAchievementLevel(event Event) {
+	event.Player.CheckLevelAchievements()
+}
+
+// onAchievementEnter records a room a player entered toward exploring its area
+func onAchievementEnter(event Event) {
+	event.Player.VisitRoom(event.Room)
+}
+
+// CheckLevelAchievements awards any level milestones the player has reached
+func (p *Player) CheckLevelAchievements() {
+	for _, a := range achievements {
+		if a.Level > 0 && p.Level >= a.Level {
+			p.AwardAchievement(a)
+		}
+	}
+}
+
+// VisitRoom records that the player has entered a room and awards the
+// area's exploration achievement once every room in it has been seen
+func (p *Player) VisitRoom(room *Room) {
+	if room == nil || p.VisitedRooms[room.ID] {
+		return
+	}
+
+	if p.VisitedRooms == nil {
+		p.VisitedRooms = make(map[int]bool)
+	}
+	p.VisitedRooms[room.ID] = true
+
+	if err := AddVisitedRoom(p.Name, room.ID); err != nil {
+		log.Printf("Error saving visited room %d for %s: %v", room.ID, p.Name, err)
+	}
+
+	if visited, total := p.exploreProgress(room.Area); total > 0 && visited == total {
+		p.AwardAchievement(exploreAchievement(room.Area))
+	}
+}
+
+// exploreProgress returns how many of an area's rooms the player has visited
+func (p *Player) exploreProgress(areaName string) (visited, total int) {
+	area := GetArea(areaName)
+	if area == nil {
+		return 0, 0
+	}
+	for id := range area.Rooms {
+		if p.VisitedRooms[id] {
+			visited++
+		}
+	}
+	return visited, len(area.Rooms)
+}
+
+// LoadAchievements restores the player's achievements, kill count, and visited rooms
+func (p *Player) LoadAchievements() {
+	earned, err := LoadPlayerAchievements(p.Name)
+	if err != nil {
+		log.Printf("Error loading achievements for %s: %v", p.Name, err)
+		earned = make(map[string]time.Time)
+	}
+	p.Achievements = earned
+
+	if kills, err := LoadPlayerKills(p.Name); err != nil {
+		log.Printf("Error loading kill count for %s: %v", p.Name, err)
+	} else {
+		p.Kills = kills
+	}
+
+	p.VisitedRooms = make(map[int]bool)
+	roomIDs, err := LoadVisitedRooms(p.Name)
+	if err != nil {
+		log.Printf("Error loading visited rooms for %s: %v", p.Name, err)
+		return
+	}
+	for _, id := range roomIDs {
+		p.VisitedRooms[id] = true
+	}
+}
+
+// earnedAchievements returns a player's achievements, oldest first
+func earnedAchievements(earned map[string]time.Time) []Achievement {
+	ids := make([]string, 0, len(earned))
+	for id := range earned {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if earned[ids[i]].Equal(earned[ids[j]]) {
+			return ids[i] < ids[j]
+		}
+		return earned[ids[i]].Before(earned[ids[j]])
+	})
+
+	var list []Achievement
+	for _, id := range ids {
+		if a, ok := findAchievement(id); ok {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// formatBadges renders a player's achievements as a line of badges
+func formatBadges(earned map[string]time.Time) string {
+	var badges []string
+	for _, a := range earnedAchievements(earned) {
+		badges = append(badges, fmt.Sprintf("[%s]", a.Name))
+	}
+	return strings.Join(badges, " ")
+}
+
+// handleAchievements lists a player's achievements and progress toward the rest
+func handleAchievements(player *Player, args []string) string {
+	if len(args) > 0 && !strings.EqualFold(args[0], player.Name) {
+		return showOtherAchievements(args[0])
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{C}Your achievements:{x}\r\n")
+
+	earned := earnedAchievements(player.Achievements)
+	if len(earned) == 0 {
+		sb.WriteString("  None yet.\r\n")
+	}
+	for _, a := range earned {
+		sb.WriteString(fmt.Sprintf("  {W}%-28s{x} %s (%s)\r\n", a.Name, a.Description,
+			player.Locale.Date(player.Achievements[a.ID])))
+	}
+
+	sb.WriteString("{C}In progress:{x}\r\n")
+	for _, a := range achievements {
+		if player.HasAchievement(a.ID) {
+			continue
+		}
+		progress := ""
+		if a.Kills > 0 {
+			progress = fmt.Sprintf("%d/%d", player.Kills, a.Kills)
+		} else if a.Level > 0 {
+			progress = fmt.Sprintf("%d/%d", player.Level, a.Level)
+		}
+		sb.WriteString(fmt.Sprintf("  %-28s %s (%s)\r\n", a.Name, a.Description, progress))
+	}
+
+	// Show exploration progress for the areas the player has set foot in
+	var areaNames []string
+	for name := range allAreas() {
+		if visited, _ := player.exploreProgress(name); visited > 0 {
+			areaNames = append(areaNames, name)
+		}
+	}
+	sort.Strings(areaNames)
+	for _, name := range areaNames {
+		a := exploreAchievement(name)
+		if player.HasAchievement(a.ID) {
+			continue
+		}
+		visited, total := player.exploreProgress(name)
+		sb.WriteString(fmt.Sprintf("  %-28s %s (%d/%d rooms)\r\n", a.Name, a.Description, visited, total))
+	}
+
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// showOtherAchievements lists the achievements another player has earned
+func showOtherAchievements(name string) string {
+	name, ok := resolvePlayerName(name)
+	if !ok {
+		return "There is no player by that name."
+	}
+
+	var earned map[string]time.Time
+	if target := FindPlayerByName(name); target != nil {
+		earned = target.Achievements
+	} else {
+		var err error
+		earned, err = LoadPlayerAchievements(name)
+		if err != nil {
+			log.Printf("Error loading achievements for %s: %v", name, err)
+			return "Error looking up that player's achievements."
+		}
+	}
+
+	list := earnedAchievements(earned)
+	if len(list) == 0 {
+		return fmt.Sprintf("%s hasn't earned any achievements yet.", name)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("{C}%s's achievements:{x}\r\n", name))
+	for _, a := range list {
+		sb.WriteString(fmt.Sprintf("  {W}%-28s{x} %s\r\n", a.Name, a.Description))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
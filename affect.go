@@ -0,0 +1,324 @@
+/*
+ * affect.go
+ *
+ * This file implements affects: temporary buffs and debuffs attached to
+ * players and mobs. An affect has a name, a set of modifiers to attributes
+ * and combat rolls, a duration in ticks, and messages shown when it is
+ * applied and when it wears off. Durations are counted down by the
+ * TimeManager on every tick. Player affects are saved with the character
+ * so they survive logging out, and active affects are listed on the
+ * score sheet.
+ *
+ * Affects don't stack with themselves. Reapplying an affect of the same
+ * strength refreshes its duration, a stronger one replaces it, and a
+ * weaker one has no effect.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// AffectPermanent marks an affect that never wears off on its own
+const AffectPermanent = -1
+
+// Modifier keys an affect may change
+const (
+	ApplySTR     = "STR"
+	ApplyDEX     = "DEX"
+	ApplyCON     = "CON"
+	ApplyINT     = "INT"
+	ApplyWIS     = "WIS"
+	ApplyPRE     = "PRE"
+	ApplyHitroll = "HIT" // Percentage points added to the chance to hit
+	ApplyDamroll = "DAM" // Flat damage added to each hit
+)
+
+// isModifierKey reports whether key is a modifier an affect may change
+func isModifierKey(key string) bool {
+	switch key {
+	case ApplySTR, ApplyDEX, ApplyCON, ApplyINT, ApplyWIS, ApplyPRE, ApplyHitroll, ApplyDamroll:
+		return true
+	}
+	return false
+}
+
+// Affect is a timed buff or debuff on a player or mob
+type Affect struct {
+	Name          string         `yaml:"name" json:"name"`
+	Modifiers     map[string]int `yaml:"modifiers" json:"modifiers"`
+	Duration      int            `yaml:"duration" json:"duration"`             // Ticks remaining (AffectPermanent = never expires)
+	ApplyMessage  string         `yaml:"apply_message" json:"apply_message"`   // Shown when the affect takes hold
+	ExpireMessage string         `yaml:"expire_message" json:"expire_message"` // Shown when the affect wears off
+	Level         int            `yaml:"level" json:"level,omitempty"`         // Strength of the affect, usually the caster's level
+	Magical       bool           `yaml:"magical" json:"magical,omitempty"`     // Whether dispel magic can remove it
+}
+
+// What happened when an affect was added
+const (
+	AffectAdded     = iota // The affect was new
+	AffectRefreshed        // An affect of the same strength had its duration renewed
+	AffectReplaced         // A weaker affect was replaced
+	AffectBlocked          // A stronger affect was already present
+)
+
+// AffectList holds the affects currently on a player or mob
+type AffectList []*Affect
+
+// Find returns the named affect, or nil if it isn't present
+func (l AffectList) Find(name string) *Affect {
+	for _, a := range l {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// Add applies an affect following the stacking rules and reports what happened
+func (l *AffectList) Add(affect *Affect) int {
+	existing := l.Find(affect.Name)
+	switch {
+	case existing == nil:
+		*l = append(*l, affect)
+		return AffectAdded
+	case affect.Level < existing.Level:
+		return AffectBlocked
+	case affect.Level == existing.Level:
+		if existing.Duration != AffectPermanent &&
+			(affect.Duration == AffectPermanent || affect.Duration > existing.Duration) {
+			existing.Duration = affect.Duration
+		}
+		return AffectRefreshed
+	}
+	l.Remove(affect.Name)
+	*l = append(*l, affect)
+	return AffectReplaced
+}
+
+// Remove takes the named affect off the list and returns it, or nil if it wasn't present
+func (l *AffectList) Remove(name string) *Affect {
+	for i, a := range *l {
+		if strings.EqualFold(a.Name, name) {
+			*l = append((*l)[:i], (*l)[i+1:]...)
+			return a
+		}
+	}
+	return nil
+}
+
+// Modifier returns the total change all affects make to the given key
+func (l AffectList) Modifier(key string) int {
+	total := 0
+	for _, a := range l {
+		total += a.Modifiers[key]
+	}
+	return total
+}
+
+// Tick counts down every timed affect and removes and returns the ones that expired
+func (l *AffectList) Tick() []*Affect {
+	var expired []*Affect
+	remaining := (*l)[:0]
+	for _, a := range *l {
+		if a.Duration > 0 {
+			a.Duration--
+			if a.Duration == 0 {
+				expired = append(expired, a)
+				continue
+			}
+		}
+		remaining = append(remaining, a)
+	}
+	*l = remaining
+	return expired
+}
+
+// Summary describes the affects for the score sheet
+func (l AffectList) Summary() []string {
+	var lines []string
+	for _, a := range l {
+		duration := "permanent"
+		if a.Duration > 0 {
+			duration = fmt.Sprintf("%d tick(s)", a.Duration)
+		}
+
+		line := fmt.Sprintf("%s (%s)", a.Name, duration)
+		if mods := describeModifiers(a.Modifiers); mods != "" {
+			line += " " + mods
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// describeModifiers formats modifiers as "STR +2, HIT -5" in a stable order
+func describeModifiers(modifiers map[string]int) string {
+	keys := make([]string, 0, len(modifiers))
+	for key, value := range modifiers {
+		if value != 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s %+d", key, modifiers[key]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// copyAffect returns an independent copy so templates are never modified
+func copyAffect(affect *Affect) *Affect {
+	c := *affect
+	c.Modifiers = make(map[string]int, len(affect.Modifiers))
+	for k, v := range affect.Modifiers {
+		c.Modifiers[k] = v
+	}
+	return &c
+}
+
+// ApplyAffect puts an affect on the player and shows its apply message
+func (p *Player) ApplyAffect(affect *Affect) {
+	switch p.Affects.Add(copyAffect(affect)) {
+	case AffectBlocked:
+		p.Send(fmt.Sprintf("A stronger %s is already on you.", affect.Name))
+		return
+	case AffectRefreshed:
+		p.Send(fmt.Sprintf("Your %s is renewed.", affect.Name))
+		return
+	}
+	p.UpdateDerivedStats()
+	p.SendDefences()
+	if affect.ApplyMessage != "" {
+		p.Send(affect.ApplyMessage)
+	}
+}
+
+// RemoveAffect takes an affect off the player early, showing its expire message
+func (p *Player) RemoveAffect(name string) bool {
+	affect := p.Affects.Remove(name)
+	if affect == nil {
+		return false
+	}
+	p.UpdateDerivedStats()
+	p.SendDefences()
+	if affect.ExpireMessage != "" {
+		p.Send(affect.ExpireMessage)
+	}
+	return true
+}
+
+// Stat returns an attribute including any modifiers from affects and set bonuses
+func (p *Player) Stat(attribute string) int {
+	var base int
+	switch attribute {
+	case ApplySTR:
+		base = p.STR
+	case ApplyDEX:
+		base = p.DEX
+	case ApplyCON:
+		base = p.CON
+	case ApplyINT:
+		base = p.INT
+	case ApplyWIS:
+		base = p.WIS
+	case ApplyPRE:
+		base = p.PRE
+	}
+	return base + p.Modifier(attribute)
+}
+
+// LoadAffects restores the player's saved affects from the database
+func (p *Player) LoadAffects() {
+	affects, err := LoadPlayerAffects(p.Name)
+	if err != nil {
+		log.Printf("Error loading affects for %s: %v", p.Name, err)
+		return
+	}
+	p.Affects = affects
+}
+
+// ApplyAffect puts an affect on the mob, showing its apply message to the room
+// Messages may use $n for the mob's name.
+func (m *MobInstance) ApplyAffect(affect *Affect) {
+	if m.Affects.Add(copyAffect(affect)) == AffectBlocked {
+		return
+	}
+	if affect.ApplyMessage != "" {
+		BroadcastToRoom(mobAffectMessage(m, affect.ApplyMessage), m.Room, nil)
+	}
+}
+
+// RemoveAffect takes an affect off the mob early, showing its expire message to the room
+func (m *MobInstance) RemoveAffect(name string) bool {
+	affect := m.Affects.Remove(name)
+	if affect == nil {
+		return false
+	}
+	if affect.ExpireMessage != "" {
+		BroadcastToRoom(mobAffectMessage(m, affect.ExpireMessage), m.Room, nil)
+	}
+	if affect.Name == CharmAffect {
+		ReleaseFollower(m)
+	}
+	return true
+}
+
+// mobAffectMessage fills in the mob's name for an affect message
+func mobAffectMessage(m *MobInstance, message string) string {
+	return act(message, m.ShortDescription, "", false)
+}
+
+// ProcessAffects counts down affects on every player and mob each tick
+func ProcessAffects() {
+	playersMutex.Lock()
+	var players []*Player
+	for _, p := range activePlayers {
+		players = append(players, p)
+	}
+	playersMutex.Unlock()
+
+	for _, p := range players {
+		expired := p.Affects.Tick()
+		if len(expired) == 0 {
+			continue
+		}
+		p.UpdateDerivedStats()
+		p.SendDefences()
+		for _, a := range expired {
+			if a.ExpireMessage != "" {
+				p.Send(a.ExpireMessage)
+			}
+		}
+	}
+
+	type mobExpiry struct {
+		mob    *MobInstance
+		affect *Affect
+	}
+	var mobExpired []mobExpiry
+
+	mobMutex.Lock()
+	for _, m := range mobInstances {
+		for _, a := range m.Affects.Tick() {
+			mobExpired = append(mobExpired, mobExpiry{m, a})
+		}
+	}
+	mobMutex.Unlock()
+
+	// Notify rooms outside the lock
+	for _, e := range mobExpired {
+		if e.affect.ExpireMessage != "" {
+			BroadcastToRoom(mobAffectMessage(e.mob, e.affect.ExpireMessage), e.mob.Room, nil)
+		}
+		if e.affect.Name == CharmAffect {
+			ReleaseFollower(e.mob)
+		}
+	}
+}
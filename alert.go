@@ -0,0 +1,125 @@
+/*
+ * alert.go
+ *
+ * This file implements low resource alerts. When a player's HP or mana
+ * drops below their alert threshold they are warned once, optionally with
+ * a bell to get their attention, and their prompt changes color until
+ * they recover. Thresholds are percentages of the maximum; the defaults
+ * come from config.yml and each player can change their own with the
+ * 'alert' command.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Bell is the character that makes a client beep
+const Bell = "\a"
+
+// belowThreshold reports whether current is under percent of max
+func belowThreshold(current, max, percent int) bool {
+	return max > 0 && current*100 < max*percent
+}
+
+// LowHP reports whether the player's HP is under their alert threshold
+func (p *Player) LowHP() bool {
+	return belowThreshold(p.HP, p.MaxHP, p.AlertHP)
+}
+
+// LowMP reports whether the player's mana is under their alert threshold
+func (p *Player) LowMP() bool {
+	return belowThreshold(p.MP, p.MaxMP, p.AlertMP)
+}
+
+// CheckAlerts warns the player once each time HP or mana crosses below its threshold
+func (p *Player) CheckAlerts() {
+	lowHP, lowMP := p.LowHP(), p.LowMP()
+	if lowHP && !p.alertedHP {
+		p.sendAlert("{R}*Your health is critically low!*{x}")
+	}
+	if lowMP && !p.alertedMP {
+		p.sendAlert("{M}*Your mana is running low!*{x}")
+	}
+	p.alertedHP, p.alertedMP = lowHP, lowMP
+}
+
+// sendAlert shows an alert, ringing the bell if the player wants it
+func (p *Player) sendAlert(message string) {
+	if p.AlertBell {
+		message += Bell
+	}
+	p.Send(message)
+}
+
+// LoadAlerts restores the player's alert settings, falling back to the server defaults
+func (p *Player) LoadAlerts() {
+	defaults := config.Alerts
+	p.AlertHP, p.AlertMP, p.AlertBell = defaults.HPPercent, defaults.MPPercent, defaults.Bell
+
+	hp, mp, bell, err := LoadPlayerAlerts(p.Name, defaults.HPPercent, defaults.MPPercent, defaults.Bell)
+	if err != nil {
+		log.Printf("Error loading alert settings for %s: %v", p.Name, err)
+		return
+	}
+	p.AlertHP, p.AlertMP, p.AlertBell = hp, mp, bell
+}
+
+// handleAlert shows or changes the player's low resource alerts
+func handleAlert(player *Player, args []string) string {
+	if len(args) == 0 {
+		bell := "off"
+		if player.AlertBell {
+			bell = "on"
+		}
+		return fmt.Sprintf("You are alerted when HP drops below {R}%d%%{x} and mana below {M}%d%%{x}. The bell is %s.\r\n"+
+			"Use 'alert hp <percent>', 'alert mp <percent>', or 'alert bell on|off' to change them.",
+			player.AlertHP, player.AlertMP, bell)
+	}
+	if len(args) != 2 {
+		return "Usage: alert [hp <percent>|mp <percent>|bell on|off]"
+	}
+
+	var message string
+	switch strings.ToLower(args[0]) {
+	case "hp", "mp":
+		percent, err := strconv.Atoi(args[1])
+		if err != nil || percent < 0 || percent > 100 {
+			return "The percent must be a number from 0 to 100. Use 0 to turn the alert off."
+		}
+		name := "HP"
+		if strings.EqualFold(args[0], "hp") {
+			player.AlertHP = percent
+		} else {
+			player.AlertMP = percent
+			name = "mana"
+		}
+		message = fmt.Sprintf("You will be alerted when your %s drops below %d%%.", name, percent)
+		if percent == 0 {
+			message = fmt.Sprintf("You will no longer be alerted about low %s.", name)
+		}
+	case "bell":
+		switch strings.ToLower(args[1]) {
+		case "on":
+			player.AlertBell = true
+			message = "Alerts will ring the bell."
+		case "off":
+			player.AlertBell = false
+			message = "Alerts will no longer ring the bell."
+		default:
+			return "Usage: alert bell on|off"
+		}
+	default:
+		return "Usage: alert [hp <percent>|mp <percent>|bell on|off]"
+	}
+
+	if err := UpdatePlayerAlerts(player.Name, player.AlertHP, player.AlertMP, player.AlertBell); err != nil {
+		log.Printf("Error saving alert settings for %s: %v", player.Name, err)
+		return message + " (This change could not be saved and will only last this session.)"
+	}
+	return message
+}
@@ -0,0 +1,159 @@
+/*
+ * alias.go
+ *
+ * This file implements command aliases. A player can give a short name to
+ * a command they type often, as in 'alias gs cast magic missile', and then
+ * type 'gs' instead, with anything after the alias added to the end of the
+ * command. Aliases may use other aliases, but not in a loop. They're saved
+ * with the character, and 'unalias' removes one.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Limits on a player's aliases
+const (
+	MaxAliases      = 30  // The most aliases a character can have
+	MaxAliasLength  = 200 // The longest an alias's command may be
+	MaxAliasExpands = 10  // The most aliases one command may pass through
+)
+
+// reservedAliases are the commands that can't be aliased, so a player can always undo an alias
+var reservedAliases = map[string]bool{
+	"alias":   true,
+	"unalias": true,
+}
+
+// expandAlias replaces an alias at the start of the input with its command, following aliases of aliases
+// Returns a refusal instead if the aliases loop.
+func (p *Player) expandAlias(input string) (string, string) {
+	seen := make(map[string]bool)
+	for {
+		word, rest, _ := strings.Cut(strings.TrimSpace(input), " ")
+		name := strings.ToLower(word)
+		command, ok := p.Aliases[name]
+		if !ok {
+			return input, ""
+		}
+		if seen[name] || len(seen) >= MaxAliasExpands {
+			return "", fmt.Sprintf("Your alias '%s' loops back on itself.", name)
+		}
+		seen[name] = true
+		input = strings.TrimSpace(command + " " + rest)
+	}
+}
+
+// aliasLoops reports whether setting an alias would make it lead back to itself
+func (p *Player) aliasLoops(name, command string) bool {
+	seen := map[string]bool{name: true}
+	for len(seen) <= MaxAliasExpands {
+		word, _, _ := strings.Cut(command, " ")
+		word = strings.ToLower(word)
+		if seen[word] {
+			return true
+		}
+		next, ok := p.Aliases[word]
+		if !ok {
+			return false
+		}
+		seen[word] = true
+		command = next
+	}
+	return true
+}
+
+// aliasNames returns the names of the player's aliases, sorted
+func (p *Player) aliasNames() []string {
+	names := make([]string, 0, len(p.Aliases))
+	for name := range p.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadAliases restores the player's aliases
+func (p *Player) LoadAliases() {
+	aliases, err := LoadPlayerAliases(p.Name)
+	if err != nil {
+		log.Printf("Error loading aliases for %s: %v", p.Name, err)
+	}
+	if aliases == nil {
+		aliases = make(map[string]string)
+	}
+	p.Aliases = aliases
+}
+
+// handleAlias lists the player's aliases, shows one, or sets one
+// Usage: alias [<name> [<command>]]
+func handleAlias(player *Player, args []string) string {
+	if len(args) == 0 {
+		names := player.aliasNames()
+		if len(names) == 0 {
+			return "You have no aliases. Type 'alias <name> <command>' to make one."
+		}
+		var sb strings.Builder
+		sb.WriteString("Your aliases:\r\n")
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("  %-12s %s\r\n", name, player.Aliases[name]))
+		}
+		return strings.TrimSuffix(sb.String(), "\r\n")
+	}
+
+	name := strings.ToLower(args[0])
+	if len(args) == 1 {
+		command, ok := player.Aliases[name]
+		if !ok {
+			return fmt.Sprintf("You have no alias called '%s'.", name)
+		}
+		return fmt.Sprintf("%s is an alias for: %s", name, command)
+	}
+
+	if reservedAliases[name] || strings.HasPrefix(name, "'") {
+		return fmt.Sprintf("You can't make an alias called '%s'.", name)
+	}
+	command := strings.TrimSpace(SanitizeText(strings.Join(args[1:], " ")))
+	if len(command) > MaxAliasLength {
+		return fmt.Sprintf("An alias's command must be no longer than %d characters.", MaxAliasLength)
+	}
+	_, replacing := player.Aliases[name]
+	if !replacing && len(player.Aliases) >= MaxAliases {
+		return fmt.Sprintf("You can't have more than %d aliases.", MaxAliases)
+	}
+	if player.aliasLoops(name, command) {
+		return fmt.Sprintf("That would make '%s' loop back on itself.", name)
+	}
+
+	if player.Aliases == nil {
+		player.Aliases = make(map[string]string)
+	}
+	player.Aliases[name] = command
+	if err := SetPlayerAlias(player.Name, name, command); err != nil {
+		log.Printf("Error saving alias for %s: %v", player.Name, err)
+	}
+	return fmt.Sprintf("%s is now an alias for: %s", name, command)
+}
+
+// handleUnalias removes one of the player's aliases
+// Usage: unalias <name>
+func handleUnalias(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Remove which alias?"
+	}
+
+	name := strings.ToLower(args[0])
+	if _, ok := player.Aliases[name]; !ok {
+		return fmt.Sprintf("You have no alias called '%s'.", name)
+	}
+	delete(player.Aliases, name)
+	if err := SetPlayerAlias(player.Name, name, ""); err != nil {
+		log.Printf("Error removing alias for %s: %v", player.Name, err)
+	}
+	return fmt.Sprintf("Alias '%s' removed.", name)
+}
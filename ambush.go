@@ -0,0 +1,178 @@
+/*
+ * ambush.go
+ *
+ * This file implements room triggers that spring ambushes. A builder can
+ * attach triggers to a room that spawn a group of mobs when a player walks
+ * in or opens something in the room, such as a chest. Triggered spawns
+ * ignore the normal reset timing but still respect each mob's world limit.
+ * A trigger rearms itself after a cooldown so it can't be farmed.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events that can set off a room trigger
+const (
+	TriggerOnEnter = "enter" // A player walks into the room
+	TriggerOnOpen  = "open"  // A player opens something matching the trigger's keywords
+)
+
+// DefaultTriggerCooldown is the number of ticks before a trigger can fire again if the area doesn't say
+const DefaultTriggerCooldown = 10
+
+// RoomTrigger spawns mobs when a player sets it off
+type RoomTrigger struct {
+	On       string   `yaml:"on"`                 // "enter" or "open"
+	Keywords []string `yaml:"keywords,omitempty"` // What the player opens, for "open" triggers
+	Mobs     []int    `yaml:"mobs"`               // Vnums of the mobs to spawn
+	Message  string   `yaml:"message,omitempty"`  // Shown to the room when the trigger fires
+	Chance   int      `yaml:"chance,omitempty"`   // Percent chance to fire (0 = always)
+	Cooldown int      `yaml:"cooldown,omitempty"` // Ticks before it can fire again (0 = default)
+	Attack   bool     `yaml:"attack,omitempty"`   // The first mob spawned attacks the player at once
+
+	readyAt time.Time // When the trigger is armed again
+}
+
+// triggerMutex guards trigger cooldowns, which are shared by every player in the room
+var triggerMutex sync.Mutex
+
+// validateTrigger checks that a room trigger is well formed
+func validateTrigger(trigger *RoomTrigger) error {
+	switch trigger.On {
+	case TriggerOnEnter:
+	case TriggerOnOpen:
+		if len(trigger.Keywords) == 0 {
+			return fmt.Errorf("open triggers need keywords")
+		}
+	default:
+		return fmt.Errorf("unknown event %q", trigger.On)
+	}
+	if len(trigger.Mobs) == 0 {
+		return fmt.Errorf("no mobs to spawn")
+	}
+	if trigger.Chance < 0 || trigger.Chance > 100 {
+		return fmt.Errorf("chance must be between 0 and 100, got %d", trigger.Chance)
+	}
+	return nil
+}
+
+// matches reports whether the trigger answers to the given keyword
+func (t *RoomTrigger) matches(keyword string) bool {
+	for _, k := range t.Keywords {
+		if strings.EqualFold(k, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// arm checks whether the trigger is ready and rolls its chance, starting the
+// cooldown if it fires
+func (t *RoomTrigger) arm() bool {
+	triggerMutex.Lock()
+	defer triggerMutex.Unlock()
+
+	if time.Now().Before(t.readyAt) {
+		return false
+	}
+	if t.Chance > 0 && rng.Intn(100) >= t.Chance {
+		return false
+	}
+
+	cooldown := t.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultTriggerCooldown
+	}
+	t.readyAt = time.Now().Add(time.Duration(cooldown) * time.Minute) // One tick is one minute
+	return true
+}
+
+// spawnTriggeredMob spawns a mob for a trigger, skipping room limits but not the world limit
+func spawnTriggeredMob(mobID int, room *Room) *MobInstance {
+	mobMutex.Lock()
+	defer mobMutex.Unlock()
+
+	template := mobRegistry[mobID]
+	if template == nil || worldMobCounts[mobID] >= GetMobMaxWorld(mobID) {
+		return nil
+	}
+	return createMobInstance(template, room)
+}
+
+// springTrigger spawns a trigger's mobs around the player who set it off
+func springTrigger(player *Player, trigger *RoomTrigger) {
+	room := player.Room
+
+	var spawned []*MobInstance
+	for _, mobID := range trigger.Mobs {
+		if mob := spawnTriggeredMob(mobID, room); mob != nil {
+			spawned = append(spawned, mob)
+		}
+	}
+	if len(spawned) == 0 {
+		return // Everything it would call is already out in the world
+	}
+
+	if trigger.Message != "" {
+		player.Send("{R}" + trigger.Message + "{x}")
+		BroadcastToRoom("{R}"+trigger.Message+"{x}", room, player)
+	}
+	for _, mob := range spawned {
+		BroadcastToRoom(fmt.Sprintf("%s appears!", capitalizeFirst(mob.ShortDescription)), room, nil)
+	}
+
+	if trigger.Attack && !player.IsInCombat() && !player.IsDead && MobCanSeePlayer(spawned[0], player) {
+		mob := spawned[0]
+		player.CancelCamp("You are attacked and stop making camp!")
+		player.EnterCombat(mob)
+		player.Send(fmt.Sprintf("{R}%s attacks you!{x}", capitalizeFirst(mob.ShortDescription)))
+		BroadcastCombatMessage(fmt.Sprintf("%s attacks %s!", capitalizeFirst(mob.ShortDescription), player.Name), room, player)
+		player.SendStatus()
+	}
+}
+
+// onAmbushEnter springs the ambushes in a room a player walked into
+func onAmbushEnter(event Event) {
+	if event.Direction != "" {
+		FireEnterTriggers(event.Player)
+	}
+}
+
+// FireEnterTriggers springs any ambushes waiting in the room the player just entered
+func FireEnterTriggers(player *Player) {
+	if player.Room == nil {
+		return
+	}
+	for _, trigger := range player.Room.Triggers {
+		if trigger.On == TriggerOnEnter && trigger.arm() {
+			springTrigger(player, trigger)
+		}
+	}
+}
+
+// OpenTrigger handles a player opening something that sets off a trigger, such as a trapped chest
+// It returns false if nothing in the room answers to the keyword.
+func OpenTrigger(player *Player, keyword string) (string, bool) {
+	for _, trigger := range player.Room.Triggers {
+		if trigger.On != TriggerOnOpen || !trigger.matches(keyword) {
+			continue
+		}
+
+		name := trigger.Keywords[0]
+		BroadcastToRoom(fmt.Sprintf("$n opens the %s.", name), player.Room, player)
+		if !trigger.arm() {
+			return fmt.Sprintf("You open the %s, but find nothing inside.", name), true
+		}
+
+		player.Send(fmt.Sprintf("You open the %s.", name))
+		springTrigger(player, trigger)
+		return "", true
+	}
+	return "", false
+}
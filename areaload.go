@@ -0,0 +1,180 @@
+/*
+ * areaload.go
+ *
+ * This file implements the 'loadarea' command, which adds a new area to the
+ * running game. A builder drops the area's .yml file into the areas folder
+ * and loads it by name; its rooms, mobs, items, and recipes are registered,
+ * its resets join the reset cycle and spawn its mobs straight away, and its
+ * script is started. Areas that are already running are left exactly as
+ * they are, so the new area may not reuse any of their vnums, and exits
+ * into the new area from the old ones wait for the next copyover.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxAreaClashes is the most clashing vnums listed when an area can't be loaded
+const MaxAreaClashes = 10
+
+// areaClashes lists anything in an area file that's already defined by a running area
+func areaClashes(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var area Area
+	if err := yaml.Unmarshal(data, &area); err != nil {
+		return nil, err
+	}
+
+	var clashes []string
+	for id := range area.Rooms {
+		if existing := allRooms()[id]; existing != nil {
+			clashes = append(clashes, fmt.Sprintf("room %d is in %s", id, existing.Area))
+		}
+	}
+	mobMutex.RLock()
+	for id := range area.Mobiles {
+		if existing := mobRegistry[id]; existing != nil {
+			clashes = append(clashes, fmt.Sprintf("mob %d is in %s", id, existing.HomeArea))
+		}
+	}
+	mobMutex.RUnlock()
+	for id := range area.Objects {
+		if GetItemTemplate(id) != nil {
+			clashes = append(clashes, fmt.Sprintf("item %d already exists", id))
+		}
+	}
+	for id := range area.Sets {
+		if GetItemSet(id) != nil {
+			clashes = append(clashes, fmt.Sprintf("item set %s already exists", id))
+		}
+	}
+	for name := range area.Recipes {
+		if existing := lookupRecipe(name); existing != nil {
+			clashes = append(clashes, fmt.Sprintf("recipe %s is in %s", name, existing.Area))
+		}
+	}
+	sort.Strings(clashes)
+	return clashes, nil
+}
+
+// hotAddArea loads a new area file into the running game
+// The caller must hold olcMutex.
+func hotAddArea(file string) (*Area, error) {
+	path := filepath.Join(AreaDir, file)
+
+	// Game goroutines read the room and area maps without a lock, so the new
+	// area is loaded into copies of them that are then published in their place
+	current := world.Load()
+	newRooms := make(map[int]*Room, len(current.rooms))
+	for id, room := range current.rooms {
+		newRooms[id] = room
+	}
+	newAreas := make(map[string]*Area, len(current.areas)+1)
+	for name, area := range current.areas {
+		newAreas[name] = area
+	}
+	if err := loadArea(path, newRooms, newAreas); err != nil {
+		return nil, err
+	}
+	area := newAreas[file]
+	world.Store(&worldMaps{rooms: newRooms, areas: newAreas})
+
+	// Link the new rooms; doors into other areas stay one-sided so the running areas are left alone
+	linked := 0
+	for id, room := range area.Rooms {
+		l, _ := linkRoomExits(id, room)
+		linked += l
+	}
+	for id, room := range area.Rooms {
+		linkRoomDoors(id, room, true)
+		validateRoomResources(id, room)
+	}
+	for name, recipe := range area.Recipes {
+		if err := validateRecipe(recipe); err != nil {
+			areaProblem(file, "recipes."+name, "Recipe %q is invalid, ignoring it: %v", name, err)
+			unregisterRecipe(name)
+		}
+	}
+	validateAreaResets(file, area)
+	log.Printf("Linked %d exits in %s", linked, file)
+
+	// Add the resets to the reset cycle and fill the area now rather than at the next reset
+	rebuildMobResets()
+	mobMutex.Lock()
+	spawnResets(area.MobResets, false)
+	mobMutex.Unlock()
+
+	if area.Script != "" {
+		if err := LoadAreaScript(file); err != nil {
+			log.Printf("[WARNING] Area %s's script couldn't be loaded: %v", file, err)
+		}
+	}
+	BuildSearchIndex()
+	return area, nil
+}
+
+// handleLoadarea adds a new area file from the areas folder to the running game
+// Usage: loadarea <file>
+func handleLoadarea(player *Player, args []string) string {
+	if len(args) != 1 {
+		return "Usage: loadarea <file>"
+	}
+
+	file := filepath.Base(args[0])
+	if !strings.HasSuffix(file, ".yml") {
+		file += ".yml"
+	}
+
+	olcMutex.Lock()
+	defer olcMutex.Unlock()
+
+	if allAreas()[file] != nil {
+		return fmt.Sprintf("%s is already loaded. Changes to a running area take a copyover.", file)
+	}
+	clashes, err := areaClashes(filepath.Join(AreaDir, file))
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("There is no %s in the %s folder.", file, AreaDir)
+	}
+	if err != nil {
+		return fmt.Sprintf("%s can't be read: %v", file, err)
+	}
+	if len(clashes) > 0 {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s can't be loaded without changing the running areas:\r\n", file))
+		for i, clash := range clashes {
+			if i == MaxAreaClashes {
+				sb.WriteString(fmt.Sprintf("  ...and %d more\r\n", len(clashes)-MaxAreaClashes))
+				break
+			}
+			sb.WriteString("  " + clash + "\r\n")
+		}
+		return strings.TrimSuffix(sb.String(), "\r\n")
+	}
+
+	problems := areaProblems
+	area, err := hotAddArea(file)
+	if err != nil {
+		log.Printf("%s couldn't load area %s: %v", player.Name, file, err)
+		return fmt.Sprintf("%s couldn't be loaded: %v", file, err)
+	}
+	log.Printf("%s loaded area %s", player.Name, file)
+
+	reply := fmt.Sprintf("Loaded %s: %d rooms, %d mobs, %d items, and %d resets.",
+		area.Name, len(area.Rooms), len(area.Mobiles), len(area.Objects), len(area.MobResets))
+	if found := areaProblems - problems; found > 0 {
+		reply += fmt.Sprintf("\r\nFound %d problems in it; see the server log.", found)
+	}
+	return reply
+}
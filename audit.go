@@ -0,0 +1,93 @@
+/*
+ * audit.go
+ *
+ * This file implements the staff audit log. Every time a staff member uses
+ * a privileged command, whether it needs trust to use at all or is marked
+ * for auditing in commandRules, the command and its arguments are saved in
+ * the database with who used it and when. Implementors review the log with
+ * the 'auditlog' command, optionally narrowed to one staff member.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audit log tuning
+const (
+	AuditLogDefault = 20  // Entries 'auditlog' shows when no count is given
+	AuditLogMax     = 200 // Most entries 'auditlog' will show at once
+)
+
+// AuditEntry is a privileged command a staff member used
+type AuditEntry struct {
+	ID        int
+	Actor     string    // Staff member who used the command
+	Command   string    // Command they used
+	Args      string    // Arguments they gave it
+	CreatedAt time.Time // When they used it
+}
+
+// audited reports whether uses of a command by staff go in the audit log
+func audited(command string) bool {
+	rule := commandRules[command]
+	return rule.Trust > TrustMortal
+}
+
+// AuditCommand saves a staff member's use of a privileged command
+func AuditCommand(player *Player, command string, args []string) {
+	if !player.Staff || !audited(command) {
+		return
+	}
+	if err := AddAuditRecord(player.Name, command, strings.Join(args, " "), time.Now()); err != nil {
+		log.Printf("Error recording %s's use of %s in the audit log: %v", player.Name, command, err)
+	}
+}
+
+// handleAuditlog shows the most recent privileged commands staff have used
+// Usage: auditlog [player] [count]
+func handleAuditlog(player *Player, args []string) string {
+	actor := ""
+	count := AuditLogDefault
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n < 1 {
+				return "Usage: auditlog [player] [count]"
+			}
+			count = min(n, AuditLogMax)
+			continue
+		}
+		actor = arg
+	}
+
+	entries, err := LoadAuditRecords(actor, count)
+	if err != nil {
+		log.Printf("Error loading audit log: %v", err)
+		return "Error loading the audit log."
+	}
+	if len(entries) == 0 {
+		if actor != "" {
+			return fmt.Sprintf("%s hasn't used any privileged commands.", capitalizeFirst(strings.ToLower(actor)))
+		}
+		return "The audit log is empty."
+	}
+
+	var sb strings.Builder
+	if actor != "" {
+		sb.WriteString(fmt.Sprintf("{Y}Audit log for %s{x}\r\n", entries[0].Actor))
+	} else {
+		sb.WriteString("{Y}Audit log{x}\r\n")
+	}
+
+	// Oldest first, so the most recent entry is nearest the prompt
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		sb.WriteString(fmt.Sprintf("%s  %-12s {C}%s{x} %s\r\n", e.CreatedAt.Format("2006-01-02 15:04:05"), e.Actor, e.Command, e.Args))
+	}
+	return strings.TrimRight(sb.String(), "\r\n")
+}
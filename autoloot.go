@@ -0,0 +1,91 @@
+/*
+ * autoloot.go
+ *
+ * This file implements the automatic actions a player can turn on for
+ * their kills. With autogold on, the gold in a slain mob's corpse goes
+ * straight into their purse; with autoloot on, everything in it does.
+ * With autosac on, a corpse left empty is sacrificed to the gods for a
+ * token amount of experience, so it doesn't clutter the room. Each toggle
+ * is saved with the character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// autoToggle is an automatic action a player can turn on or off
+type autoToggle struct {
+	name    string
+	enabled *bool // The player's setting
+	help    string
+}
+
+// autoToggles lists the player's automatic actions
+func autoToggles(p *Player) []autoToggle {
+	return []autoToggle{
+		{"autoloot", &p.AutoLoot, "take everything from the corpses of your kills"},
+		{"autogold", &p.AutoGold, "take the gold from the corpses of your kills"},
+		{"autosac", &p.AutoSac, "sacrifice empty corpses to the gods"},
+	}
+}
+
+// LoadAutoToggles restores the player's automatic action settings
+func (p *Player) LoadAutoToggles() {
+	loot, gold, sac, err := LoadPlayerAutoToggles(p.Name)
+	if err != nil {
+		log.Printf("Error loading auto toggles for %s: %v", p.Name, err)
+		return
+	}
+	p.AutoLoot, p.AutoGold, p.AutoSac = loot, gold, sac
+}
+
+// handleAuto lists the player's automatic actions, or toggles one when called as its name
+func handleAuto(player *Player, args []string) string {
+	command := strings.ToLower(player.LastCommand)
+	for _, toggle := range autoToggles(player) {
+		if toggle.name != command {
+			continue
+		}
+		*toggle.enabled = !*toggle.enabled
+		state := "off"
+		if *toggle.enabled {
+			state = "{G}on{x}"
+		}
+		message := fmt.Sprintf("%s is now %s.", capitalizeFirst(toggle.name), state)
+		if err := UpdatePlayerAutoToggles(player.Name, player.AutoLoot, player.AutoGold, player.AutoSac); err != nil {
+			log.Printf("Error saving auto toggles for %s: %v", player.Name, err)
+			message += " (This change could not be saved and will only last this session.)"
+		}
+		return message
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Automatic actions:\r\n")
+	for _, toggle := range autoToggles(player) {
+		state := "off"
+		if *toggle.enabled {
+			state = "{G}on{x} "
+		}
+		sb.WriteString(fmt.Sprintf("  %-9s %s - %s\r\n", toggle.name, state, toggle.help))
+	}
+	sb.WriteString("Type the name of an action to turn it on or off.")
+	return sb.String()
+}
+
+// autoLoot runs the player's automatic actions on the corpse of a mob they killed
+func (p *Player) autoLoot(corpse *Item) {
+	switch {
+	case p.AutoLoot:
+		p.Send(takeFromContainer(p, "all", corpse, false))
+	case p.AutoGold && corpse.Gold > 0:
+		p.Send(takeFromContainer(p, "gold", corpse, false))
+	}
+
+	if p.AutoSac && len(corpse.Contents) == 0 && corpse.Gold == 0 {
+		p.Send(sacrificeCorpse(p, corpse))
+	}
+}
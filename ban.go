@@ -0,0 +1,397 @@
+/*
+ * ban.go
+ *
+ * This file implements banning. Staff can ban a character, which keeps
+ * them from logging in, or ban a site, which turns away every connection
+ * from an address or range of addresses before it gets as far as the
+ * login prompt. Bans last for a set time or until they are lifted, carry
+ * the reason they were given, and are kept in the database so they
+ * survive a reboot. Anyone online when they're banned is thrown out.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of ban
+const (
+	BanPlayer = "player" // Keeps a character from logging in
+	BanSite   = "site"   // Turns away connections from an address or range
+)
+
+// Ban keeps a character or a site out of the game
+type Ban struct {
+	ID        int
+	Kind      string    // BanPlayer or BanSite
+	Target    string    // Character name, or an IP address or CIDR range
+	Reason    string    // Why the ban was given
+	By        string    // Staff member who gave it
+	CreatedAt time.Time // When it was given
+	Expires   time.Time // When it lifts (zero = never)
+}
+
+// Global variables for ban management
+var (
+	bans      []*Ban       // Bans in force, loaded at startup
+	bansMutex sync.RWMutex // Mutex for thread-safe ban operations
+)
+
+// Permanent reports whether the ban lasts until it's lifted
+func (b *Ban) Permanent() bool {
+	return b.Expires.IsZero()
+}
+
+// Expired reports whether the ban has run out
+func (b *Ban) Expired() bool {
+	return !b.Permanent() && time.Now().After(b.Expires)
+}
+
+// covers reports whether a site ban applies to the address
+func (b *Ban) covers(ip net.IP) bool {
+	if _, network, err := net.ParseCIDR(b.Target); err == nil {
+		return network.Contains(ip)
+	}
+	banned := net.ParseIP(b.Target)
+	return banned != nil && banned.Equal(ip)
+}
+
+// remaining describes how long the ban has left
+func (b *Ban) remaining() string {
+	if b.Permanent() {
+		return "permanent"
+	}
+	return formatBanDuration(time.Until(b.Expires))
+}
+
+// LoadBans reads the bans in force from the database, dropping any that have run out
+func LoadBans() error {
+	loaded, err := LoadBanRecords()
+	if err != nil {
+		return err
+	}
+
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+	bans = nil
+	for _, b := range loaded {
+		if b.Expired() {
+			if err := DeleteBanRecord(b.ID); err != nil {
+				log.Printf("Error removing expired ban %d: %v", b.ID, err)
+			}
+			continue
+		}
+		bans = append(bans, b)
+	}
+	return nil
+}
+
+// findBan returns the ban of the given kind in force against the target, or nil
+func findBan(kind, target string) *Ban {
+	bansMutex.RLock()
+	defer bansMutex.RUnlock()
+
+	for _, b := range bans {
+		if b.Kind == kind && strings.EqualFold(b.Target, target) && !b.Expired() {
+			return b
+		}
+	}
+	return nil
+}
+
+// PlayerBan returns the ban keeping a character from logging in, or nil
+func PlayerBan(name string) *Ban {
+	return findBan(BanPlayer, name)
+}
+
+// SiteBan returns the ban covering the address a connection comes from, or nil
+func SiteBan(addr net.Addr) *Ban {
+	ip := addrIP(addr)
+	if ip == nil {
+		return nil
+	}
+
+	bansMutex.RLock()
+	defer bansMutex.RUnlock()
+
+	for _, b := range bans {
+		if b.Kind == BanSite && !b.Expired() && b.covers(ip) {
+			return b
+		}
+	}
+	return nil
+}
+
+// addrIP returns the IP address of a connection's remote end, or nil
+func addrIP(addr net.Addr) net.IP {
+	if addr == nil {
+		return nil
+	}
+	return hostIP(addr.String())
+}
+
+// hostIP returns the IP address in a host:port string, or nil
+func hostIP(hostport string) net.IP {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	return net.ParseIP(host)
+}
+
+// BanMessage is what a banned connection is told before it's closed
+func BanMessage(b *Ban) string {
+	var sb strings.Builder
+	if b.Kind == BanSite {
+		sb.WriteString("Your site has been banned from this game.\r\n")
+	} else {
+		sb.WriteString("You have been banned from this game.\r\n")
+	}
+	if b.Reason != "" {
+		sb.WriteString(fmt.Sprintf("Reason: %s\r\n", b.Reason))
+	}
+	if !b.Permanent() {
+		sb.WriteString(fmt.Sprintf("The ban lifts in %s.\r\n", b.remaining()))
+	}
+	return sb.String()
+}
+
+// addBan saves a new ban and puts it in force
+func addBan(b *Ban) error {
+	id, err := AddBanRecord(b)
+	if err != nil {
+		return err
+	}
+	b.ID = id
+
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+	bans = append(bans, b)
+	return nil
+}
+
+// liftBan removes the ban of the given kind against the target, reporting whether there was one
+func liftBan(kind, target string) (bool, error) {
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+
+	for i, b := range bans {
+		if b.Kind == kind && strings.EqualFold(b.Target, target) {
+			if err := DeleteBanRecord(b.ID); err != nil {
+				return false, err
+			}
+			bans = append(bans[:i], bans[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseBanDuration reads a duration such as 30m, 12h, 7d, or 2w; "perm" means no end
+func parseBanDuration(s string) (time.Duration, bool) {
+	s = strings.ToLower(s)
+	if s == "perm" || s == "permanent" {
+		return 0, true
+	}
+	if len(s) < 2 {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	switch s[len(s)-1] {
+	case 'm':
+		return time.Duration(n) * time.Minute, true
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// formatBanDuration describes a duration in its largest whole unit
+func formatBanDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return "under a minute"
+}
+
+// newBan builds a ban from the optional duration and reason following its target
+func newBan(kind, target string, by *Player, args []string) *Ban {
+	b := &Ban{Kind: kind, Target: target, By: by.Name, CreatedAt: time.Now()}
+	if len(args) > 0 {
+		if d, ok := parseBanDuration(args[0]); ok {
+			if d > 0 {
+				b.Expires = b.CreatedAt.Add(d)
+			}
+			args = args[1:]
+		}
+	}
+	b.Reason = strings.Join(args, " ")
+	return b
+}
+
+// disconnectBanned throws a banned player out of the game
+func disconnectBanned(p *Player, b *Ban) {
+	p.Send(strings.TrimSuffix(BanMessage(b), "\r\n"))
+	BroadcastToRoom("$n is banished from the realm!", p.Room, p)
+	p.Send(saveAndQuit(p))
+
+	// Closing the connection ends the player's game loop
+	p.Conn.Close()
+}
+
+// handleBan keeps a character from logging in
+// Usage: ban <player> [duration|perm] [reason]
+func handleBan(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Usage: ban <player> [duration|perm] [reason]"
+	}
+
+	name, ok := resolvePlayerName(args[0])
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", args[0])
+	}
+	if strings.EqualFold(name, player.Name) {
+		return "You can't ban yourself."
+	}
+	target := FindPlayerByName(name)
+	trust := 0
+	if target != nil {
+		trust = target.Trust
+	} else if t, err := LoadPlayerTrust(name); err == nil {
+		trust = t
+	}
+	if trust >= player.Trust {
+		return fmt.Sprintf("%s is as trusted as you are.", name)
+	}
+	if PlayerBan(name) != nil {
+		return fmt.Sprintf("%s is already banned.", name)
+	}
+
+	b := newBan(BanPlayer, name, player, args[1:])
+	if err := addBan(b); err != nil {
+		log.Printf("Error saving ban on %s: %v", name, err)
+		return "Error saving the ban."
+	}
+	log.Printf("%s banned %s (%s): %s", player.Name, name, b.remaining(), b.Reason)
+
+	if target != nil {
+		disconnectBanned(target, b)
+	}
+	return fmt.Sprintf("%s is banned (%s).", name, b.remaining())
+}
+
+// handleSiteban turns away connections from an address or range of addresses
+// Usage: siteban <ip|cidr> [duration|perm] [reason]
+func handleSiteban(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Usage: siteban <ip|cidr> [duration|perm] [reason]"
+	}
+
+	site := args[0]
+	if _, network, err := net.ParseCIDR(site); err == nil {
+		site = network.String()
+	} else if ip := net.ParseIP(site); ip != nil {
+		site = ip.String()
+	} else {
+		return "That isn't an IP address or CIDR range."
+	}
+	if findBan(BanSite, site) != nil {
+		return fmt.Sprintf("%s is already banned.", site)
+	}
+
+	b := newBan(BanSite, site, player, args[1:])
+	if err := addBan(b); err != nil {
+		log.Printf("Error saving site ban on %s: %v", site, err)
+		return "Error saving the ban."
+	}
+	log.Printf("%s banned site %s (%s): %s", player.Name, site, b.remaining(), b.Reason)
+
+	// Throw out anyone already connected from there, staff excepted
+	kicked := 0
+	for _, p := range otherPlayers(player) {
+		if ip := addrIP(p.Conn.RemoteAddr()); ip != nil && !p.Staff && b.covers(ip) {
+			disconnectBanned(p, b)
+			kicked++
+		}
+	}
+	if kicked > 0 {
+		return fmt.Sprintf("%s is banned (%s), and %d players connected from there were disconnected.", site, b.remaining(), kicked)
+	}
+	return fmt.Sprintf("%s is banned (%s).", site, b.remaining())
+}
+
+// handleUnban lifts a ban on a character or site
+// Usage: unban <player|ip|cidr>
+func handleUnban(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Usage: unban <player|ip|cidr>"
+	}
+
+	kind, target := BanPlayer, args[0]
+	if _, network, err := net.ParseCIDR(target); err == nil {
+		kind, target = BanSite, network.String()
+	} else if ip := net.ParseIP(target); ip != nil {
+		kind, target = BanSite, ip.String()
+	}
+	if kind == BanSite && player.Trust < commandRules["siteban"].Trust {
+		return "You don't have the trust to lift site bans."
+	}
+
+	lifted, err := liftBan(kind, target)
+	if err != nil {
+		log.Printf("Error lifting ban on %s: %v", target, err)
+		return "Error lifting the ban."
+	}
+	if !lifted {
+		return fmt.Sprintf("%s isn't banned.", target)
+	}
+	log.Printf("%s lifted the ban on %s", player.Name, target)
+	return fmt.Sprintf("The ban on %s is lifted.", target)
+}
+
+// handleBanlist lists the bans in force
+func handleBanlist(player *Player, args []string) string {
+	bansMutex.RLock()
+	var current []*Ban
+	for _, b := range bans {
+		if !b.Expired() {
+			current = append(current, b)
+		}
+	}
+	bansMutex.RUnlock()
+
+	if len(current) == 0 {
+		return "No one is banned."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{Y}Bans in force{x}\r\n")
+	for _, b := range current {
+		sb.WriteString(fmt.Sprintf("%-6s {R}%-18s{x} %-10s by %-12s %s", b.Kind, b.Target, b.remaining(), b.By, b.CreatedAt.Format("2006-01-02")))
+		if b.Reason != "" {
+			sb.WriteString("  " + b.Reason)
+		}
+		sb.WriteString("\r\n")
+	}
+	return strings.TrimRight(sb.String(), "\r\n")
+}
@@ -0,0 +1,109 @@
+/*
+ * bank.go
+ *
+ * This file implements banking. Bankers are mobs that keep gold safe for
+ * players: gold in the bank isn't carried, so it can't be lost when a
+ * player dies. Players 'deposit' and 'withdraw' gold while standing with
+ * a banker, and can check their 'balance' there. Moving gold in and out
+ * of the bank neither creates nor destroys it, so it isn't recorded in
+ * the economy ledger.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// findBanker returns a banker in the player's room, or nil
+func findBanker(player *Player) *MobInstance {
+	for _, mob := range GetMobsInRoom(player.Room.ID) {
+		if mob.Banker {
+			return mob
+		}
+	}
+	return nil
+}
+
+// parseGoldAmount reads an amount of gold, where "all" means everything available
+func parseGoldAmount(args []string, available int) (int, bool) {
+	if len(args) != 1 {
+		return 0, false
+	}
+	if strings.EqualFold(args[0], "all") {
+		return available, true
+	}
+	amount, err := strconv.Atoi(args[0])
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+// saveBank stores the player's gold and bank balance
+func (p *Player) saveBank() {
+	if err := UpdatePlayerBank(p.Name, p.Gold, p.BankBalance); err != nil {
+		log.Printf("Error updating bank balance for %s: %v", p.Name, err)
+	}
+	p.SendStatus()
+}
+
+// handleDeposit puts gold from the player's purse into the bank
+func handleDeposit(player *Player, args []string) string {
+	banker := findBanker(player)
+	if banker == nil {
+		return "There is no banker here."
+	}
+	amount, ok := parseGoldAmount(args, player.Gold)
+	if !ok {
+		return "Usage: deposit <amount|all>"
+	}
+	if amount == 0 {
+		return "You have no gold to deposit."
+	}
+	if amount > player.Gold {
+		return "You don't have that much gold."
+	}
+
+	player.Gold -= amount
+	player.BankBalance += amount
+	player.saveBank()
+	BroadcastToRoom(fmt.Sprintf("$n makes a deposit with %s.", banker.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You deposit {Y}%s gold{x}. Your balance is now {Y}%s gold{x}.", player.Locale.Number(amount), player.Locale.Number(player.BankBalance))
+}
+
+// handleWithdraw takes gold out of the bank and into the player's purse
+func handleWithdraw(player *Player, args []string) string {
+	banker := findBanker(player)
+	if banker == nil {
+		return "There is no banker here."
+	}
+	amount, ok := parseGoldAmount(args, player.BankBalance)
+	if !ok {
+		return "Usage: withdraw <amount|all>"
+	}
+	if amount == 0 {
+		return fmt.Sprintf("%s says, 'Your account is empty.'", capitalizeFirst(banker.ShortDescription))
+	}
+	if amount > player.BankBalance {
+		return fmt.Sprintf("%s says, 'Your account doesn't hold that much.'", capitalizeFirst(banker.ShortDescription))
+	}
+
+	player.BankBalance -= amount
+	player.Gold += amount
+	player.saveBank()
+	BroadcastToRoom(fmt.Sprintf("$n makes a withdrawal from %s.", banker.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You withdraw {Y}%s gold{x}. Your balance is now {Y}%s gold{x}.", player.Locale.Number(amount), player.Locale.Number(player.BankBalance))
+}
+
+// handleBalance tells the player how much gold they have in the bank
+func handleBalance(player *Player, args []string) string {
+	banker := findBanker(player)
+	if banker == nil {
+		return "There is no banker here."
+	}
+	return fmt.Sprintf("%s says, 'Your account holds {Y}%s gold{x}.'", capitalizeFirst(banker.ShortDescription), player.Locale.Number(player.BankBalance))
+}
@@ -0,0 +1,217 @@
+/*
+ * botcheck.go
+ *
+ * This file teaches the watchdog to spot players left running on a script.
+ * People don't type like clockwork: the time between one command and the
+ * next wanders by a good fraction of a second. A client trigger or timer
+ * sends its commands at the same spacing every time, so when a long run of
+ * commands comes in evenly spaced, the watchdog flags the player as a
+ * likely bot and tells the staff online.
+ *
+ * If the server turns on bot checks, a flagged player is also asked a
+ * simple question, such as what seven plus three is, and must 'answer' it
+ * before doing anything but talk. A player who doesn't answer in time, or
+ * answers wrong too often, is flagged again and logged out, which keeps an
+ * unattended script from farming gold and experience. Staff can put the
+ * question to anyone themselves with 'watchdog check <player>'.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of behavior flagged by bot detection (see watchdog.go for the others)
+const (
+	WatchBot   = "bot"   // A long run of commands sent at the same spacing
+	WatchCheck = "check" // A bot check that went unanswered or was answered wrong
+)
+
+// MaxCheckMisses is how many wrong answers to a bot check a player may give before being logged out
+const MaxCheckMisses = 3
+
+// checkCommands are the commands a player may use while a bot check is waiting for an answer
+var checkCommands = map[string]bool{
+	"answer": true,
+	"say":    true,
+	"tell":   true,
+	"reply":  true,
+	"help":   true,
+}
+
+// numberWords spells out the numbers used in bot check questions
+var numberWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+
+// botCheck is a question a player suspected of botting must answer
+type botCheck struct {
+	question string
+	answer   int
+	expires  time.Time
+	misses   int
+}
+
+// newBotCheck makes up a question, spelled out so a script can't simply match on digits
+func newBotCheck() *botCheck {
+	a, b := rng.Intn(len(numberWords)), rng.Intn(len(numberWords))
+	check := &botCheck{expires: time.Now().Add(time.Duration(config.Watchdog.CheckSeconds) * time.Second)}
+	if a >= b && rng.Intn(2) == 0 {
+		check.question = fmt.Sprintf("What is %s minus %s?", numberWords[a], numberWords[b])
+		check.answer = a - b
+	} else {
+		check.question = fmt.Sprintf("What is %s plus %s?", numberWords[a], numberWords[b])
+		check.answer = a + b
+	}
+	return check
+}
+
+// watchRhythm flags the player if their last run of commands came in at the same spacing
+func (p *Player) watchRhythm(now time.Time) {
+	n := config.Watchdog.BotCommands
+	if n == 0 {
+		return
+	}
+
+	p.watch.mu.Lock()
+	if !p.watch.lastCommand.IsZero() {
+		p.watch.intervals = append(p.watch.intervals, now.Sub(p.watch.lastCommand))
+		if len(p.watch.intervals) > n {
+			p.watch.intervals = p.watch.intervals[1:]
+		}
+	}
+	p.watch.lastCommand = now
+	if len(p.watch.intervals) < n {
+		p.watch.mu.Unlock()
+		return
+	}
+	mean, spread := intervalSpread(p.watch.intervals)
+	if spread >= time.Duration(config.Watchdog.BotJitterMS)*time.Millisecond {
+		p.watch.mu.Unlock()
+		return
+	}
+	// Start counting afresh, so the same run isn't flagged with every command that follows
+	p.watch.intervals = nil
+	p.watch.mu.Unlock()
+
+	p.Flag(WatchBot, fmt.Sprintf("sent %d commands %.1f seconds apart, give or take %dms", n+1, mean.Seconds(), spread.Milliseconds()))
+	if config.Watchdog.BotChecks {
+		p.StartBotCheck()
+	}
+}
+
+// intervalSpread returns the mean of the intervals and their standard deviation
+func intervalSpread(intervals []time.Duration) (time.Duration, time.Duration) {
+	var sum float64
+	for _, d := range intervals {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(intervals))
+	var variance float64
+	for _, d := range intervals {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	variance /= float64(len(intervals))
+	return time.Duration(mean), time.Duration(math.Sqrt(variance))
+}
+
+// StartBotCheck asks the player a question they must answer to carry on, unless they're already being asked one
+func (p *Player) StartBotCheck() {
+	p.watch.mu.Lock()
+	if p.watch.check != nil {
+		p.watch.mu.Unlock()
+		return
+	}
+	check := newBotCheck()
+	p.watch.check = check
+	p.watch.mu.Unlock()
+
+	p.Send(fmt.Sprintf("\r\n{Y}[Watchdog]{x} Are you there? Type 'answer <number>' within %d seconds to carry on.\r\n%s",
+		config.Watchdog.CheckSeconds, check.question))
+}
+
+// checkRefusal returns why the player can't use a command while a bot check waits for an answer, or ""
+func (p *Player) checkRefusal(command string) string {
+	p.watch.mu.Lock()
+	check := p.watch.check
+	p.watch.mu.Unlock()
+	if check == nil || checkCommands[command] {
+		return ""
+	}
+	return fmt.Sprintf("You must answer the watchdog first: %s (Type 'answer <number>'.)", check.question)
+}
+
+// failBotCheck flags a player who didn't pass a bot check and logs them out
+func (p *Player) failBotCheck(detail string) string {
+	p.watch.mu.Lock()
+	p.watch.check = nil
+	p.watch.mu.Unlock()
+
+	p.Flag(WatchCheck, detail)
+	return "{R}[Watchdog]{x} You didn't pass the check, so you've been logged out.\r\n" + saveAndQuit(p)
+}
+
+// handleAnswer answers the question a bot check asked
+// Usage: answer <number>
+func handleAnswer(player *Player, args []string) string {
+	player.watch.mu.Lock()
+	check := player.watch.check
+	player.watch.mu.Unlock()
+	if check == nil {
+		return "Nobody has asked you anything."
+	}
+	if len(args) == 0 {
+		return fmt.Sprintf("%s (Type 'answer <number>'.)", check.question)
+	}
+
+	guess, err := strconv.Atoi(args[0])
+	if err != nil {
+		for n, word := range numberWords {
+			if strings.EqualFold(args[0], word) {
+				guess, err = n, nil
+			}
+		}
+	}
+	if err == nil && guess == check.answer {
+		player.watch.mu.Lock()
+		player.watch.check = nil
+		player.watch.mu.Unlock()
+		log.Printf("%s passed a watchdog bot check", player.Name)
+		return "Thank you. Carry on."
+	}
+
+	player.watch.mu.Lock()
+	check.misses++
+	misses := check.misses
+	player.watch.mu.Unlock()
+	if misses >= MaxCheckMisses {
+		return player.failBotCheck(fmt.Sprintf("answered a bot check wrong %d times", misses))
+	}
+	return fmt.Sprintf("That isn't right. %s", check.question)
+}
+
+// ProcessBotChecks logs out players who let a bot check run out without answering it
+func ProcessBotChecks() {
+	now := time.Now()
+	playersMutex.Lock()
+	var expired []*Player
+	for _, p := range activePlayers {
+		p.watch.mu.Lock()
+		if p.watch.check != nil && now.After(p.watch.check.expires) {
+			expired = append(expired, p)
+		}
+		p.watch.mu.Unlock()
+	}
+	playersMutex.Unlock()
+
+	for _, p := range expired {
+		p.Send(p.failBotCheck("didn't answer a bot check in time"))
+
+		// Closing the connection ends the player's game loop
+		p.Conn.Close()
+	}
+}
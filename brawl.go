@@ -0,0 +1,146 @@
+/*
+ * brawl.go
+ *
+ * This file implements fights between mobs of opposing factions. A mob
+ * with a faction belongs to a side, and a mob with enemies attacks mobs
+ * of those factions it finds sharing its room, such as the city watch
+ * falling on thieves. Brawls run on a lightweight loop of their own:
+ * each pulse every brawling mob takes one swing at the mob it is
+ * fighting. Players in the room see the fight play out and can join in,
+ * either by attacking one side directly or with the 'assist' command.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BrawlChance is the percent chance each pulse that a mob attacks an enemy sharing its room
+const BrawlChance = 25
+
+// IsEnemyOf reports whether the mob attacks mobs of the other's faction on sight
+func (m *MobInstance) IsEnemyOf(other *MobInstance) bool {
+	if other.Faction == "" || other == m {
+		return false
+	}
+	for _, faction := range m.Enemies {
+		if strings.EqualFold(faction, other.Faction) {
+			return true
+		}
+	}
+	return false
+}
+
+// findEnemy returns a mob of an enemy faction in the mob's room, or nil
+// Charmed followers and pets are left alone.
+func (m *MobInstance) findEnemy() *MobInstance {
+	mobMutex.RLock()
+	defer mobMutex.RUnlock()
+
+	for _, other := range roomMobs[m.Room.ID] {
+		if other.HP > 0 && other.Master == nil && m.IsEnemyOf(other) {
+			return other
+		}
+	}
+	return nil
+}
+
+// ProcessMobBrawls starts fights between mobs of opposing factions and
+// runs a round of combat for every mob already in one
+func ProcessMobBrawls() {
+	mobMutex.RLock()
+	var brawlers []*MobInstance
+	for _, mob := range mobInstances {
+		// Followers fight on their master's orders in follower.go
+		if mob.Master == nil && mob.Room != nil && (mob.Fighting != nil || len(mob.Enemies) > 0) {
+			brawlers = append(brawlers, mob)
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range brawlers {
+		// Killed earlier this pulse
+		if mob.HP <= 0 {
+			continue
+		}
+
+		if victim := mob.Fighting; victim != nil {
+			if victim.HP <= 0 || victim.Room != mob.Room {
+				mob.Fighting = nil
+				continue
+			}
+			mobStrike(mob, victim)
+			if victim.HP <= 0 {
+				brawlKill(mob, victim)
+			}
+			continue
+		}
+
+		// Mobs busy with a player don't go looking for another fight
+		if IsMobInCombat(mob) || rng.Intn(100) >= BrawlChance {
+			continue
+		}
+		if enemy := mob.findEnemy(); enemy != nil {
+			startBrawl(mob, enemy)
+		}
+	}
+}
+
+// startBrawl sets a mob on an enemy, who turns to fight back if it's free to
+func startBrawl(mob, enemy *MobInstance) {
+	mob.Fighting = enemy
+	if enemy.Fighting == nil {
+		enemy.Fighting = mob
+	}
+	BroadcastCombatMessage(fmt.Sprintf("%s attacks %s!", capitalizeFirst(mob.ShortDescription), enemy.ShortDescription), mob.Room, nil)
+}
+
+// brawlKill handles a mob killing another in a brawl; nobody earns experience
+func brawlKill(mob, victim *MobInstance) {
+	room := victim.Room
+	mob.Fighting = nil
+
+	BroadcastCombatMessage(fmt.Sprintf("%s has slain %s!", capitalizeFirst(mob.ShortDescription), victim.ShortDescription), room, nil)
+	AddItemToRoom(CreateMobCorpse(victim), room)
+	RemoveMobFromRoom(victim)
+}
+
+// handleAssist joins the fight a mob in the room is having with another mob
+// Usage: assist <mob>
+func handleAssist(player *Player, args []string) string {
+	if player.IsInCombat() {
+		return "You are already in combat!"
+	}
+	if len(args) == 0 {
+		return "Assist whom?"
+	}
+
+	ally := FindMobByTarget(player.Room.ID, strings.ToLower(strings.Join(args, " ")))
+	if ally == nil {
+		return "You don't see that here."
+	}
+	enemy := ally.Fighting
+	if enemy == nil || enemy.HP <= 0 || enemy.Room != player.Room {
+		return fmt.Sprintf("%s isn't fighting anyone.", capitalizeFirst(ally.ShortDescription))
+	}
+	if enemy.IsFollowing(player) {
+		return "You can't attack your own follower."
+	}
+	ok, notice := player.mayAttack(enemy)
+	if !ok {
+		return notice
+	}
+	if notice != "" {
+		player.Send(notice)
+	}
+
+	player.CancelCamp("You stop making camp.")
+	player.BecomeVisible()
+	player.EnterCombat(enemy)
+	player.SendStatus()
+
+	BroadcastCombatMessage(fmt.Sprintf("%s leaps to assist %s!", player.Name, ally.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You leap to assist %s and attack %s!", ally.ShortDescription, enemy.ShortDescription)
+}
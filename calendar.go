@@ -0,0 +1,221 @@
+/*
+ * calendar.go
+ *
+ * This file implements the game calendar and the cycle of day and night.
+ * Each tick is an hour of game time, and the hours make up days, weeks,
+ * months, and years. The calendar is set from the real clock when the
+ * server starts, so game time carries on across reboots. Players outdoors
+ * see the sun rise and set. At night, rooms out in the wilds are dark and
+ * need a light, while city streets stay lit. Nocturnal mobs only appear
+ * at night and fade away at dawn. The 'time' command shows the date and
+ * the hour.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Calendar units
+const (
+	HoursPerDay   = 24
+	DaysPerWeek   = 7
+	DaysPerMonth  = 30
+	MonthsPerYear = 12
+)
+
+// Hours at which the sun changes
+const (
+	DawnHour    = 5  // Night ends
+	SunriseHour = 6  // The sun comes up
+	SunsetHour  = 19 // The sun goes down
+	NightHour   = 20 // Night begins
+)
+
+// calendarEpoch is when game time began, at one game hour per real minute
+var calendarEpoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// dayNames are the days of the week
+var dayNames = []string{
+	"the Moon", "the Bull", "Deception", "Thunder", "Freedom", "the Great Gods", "the Sun",
+}
+
+// monthNames are the months of the year
+var monthNames = []string{
+	"Winter", "the Winter Wolf", "the Frost Giant", "the Spring", "Nature", "the Dragon",
+	"the Sun", "the Heat", "the Battle", "the Dark Shades", "the Long Shadows", "the Ancient Darkness",
+}
+
+// sunMessages are shown to players outdoors when the sun changes, keyed by hour
+var sunMessages = map[int]string{
+	DawnHour:    "{y}The day has begun.{x}",
+	SunriseHour: "{Y}The sun rises in the east.{x}",
+	SunsetHour:  "{y}The sun slowly disappears in the west.{x}",
+	NightHour:   "{D}The night has begun.{x}",
+}
+
+// GameTime is a moment on the game calendar; day and month count from zero
+type GameTime struct {
+	Hour  int
+	Day   int
+	Month int
+	Year  int
+}
+
+var (
+	gameTime      GameTime
+	calendarMutex sync.RWMutex
+)
+
+// InitCalendar sets the calendar from the real time elapsed since the epoch
+func InitCalendar() {
+	hours := int(time.Since(calendarEpoch) / time.Minute)
+
+	calendarMutex.Lock()
+	defer calendarMutex.Unlock()
+	gameTime = GameTime{
+		Hour:  hours % HoursPerDay,
+		Day:   hours / HoursPerDay % DaysPerMonth,
+		Month: hours / (HoursPerDay * DaysPerMonth) % MonthsPerYear,
+		Year:  hours / (HoursPerDay * DaysPerMonth * MonthsPerYear),
+	}
+}
+
+// CurrentTime returns the time on the game calendar
+func CurrentTime() GameTime {
+	calendarMutex.RLock()
+	defer calendarMutex.RUnlock()
+	return gameTime
+}
+
+// IsNight reports whether the sun is down
+func IsNight() bool {
+	hour := CurrentTime().Hour
+	return hour >= NightHour || hour < DawnHour
+}
+
+// ordinal returns a number with its English suffix, such as 1st or 12th
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// clockTime returns the hour as it would be told on a clock, such as "3 o'clock pm"
+func (t GameTime) clockTime() string {
+	hour := t.Hour % 12
+	if hour == 0 {
+		hour = 12
+	}
+	if t.Hour < 12 {
+		return fmt.Sprintf("%d o'clock am", hour)
+	}
+	return fmt.Sprintf("%d o'clock pm", hour)
+}
+
+// shortTime returns the hour in a few letters, such as "3pm"
+func (t GameTime) shortTime() string {
+	hour := t.Hour % 12
+	if hour == 0 {
+		hour = 12
+	}
+	if t.Hour < 12 {
+		return fmt.Sprintf("%dam", hour)
+	}
+	return fmt.Sprintf("%dpm", hour)
+}
+
+// dayName returns the name of the day of the week
+func (t GameTime) dayName() string {
+	days := (t.Year*MonthsPerYear+t.Month)*DaysPerMonth + t.Day
+	return dayNames[days%DaysPerWeek]
+}
+
+// AdvanceCalendar moves game time forward an hour each tick and marks the changes of the sun
+func AdvanceCalendar() {
+	calendarMutex.Lock()
+	gameTime.Hour++
+	if gameTime.Hour >= HoursPerDay {
+		gameTime.Hour = 0
+		gameTime.Day++
+	}
+	if gameTime.Day >= DaysPerMonth {
+		gameTime.Day = 0
+		gameTime.Month++
+	}
+	if gameTime.Month >= MonthsPerYear {
+		gameTime.Month = 0
+		gameTime.Year++
+	}
+	hour := gameTime.Hour
+	calendarMutex.Unlock()
+
+	message, sunChanged := sunMessages[hour]
+	playersMutex.Lock()
+	for _, p := range activePlayers {
+		if sunChanged && p.Room != nil && !p.Room.IsIndoors() {
+			p.Send(message)
+		}
+		p.SendTime("IRE.Time.Update")
+	}
+	playersMutex.Unlock()
+
+	switch hour {
+	case NightHour:
+		processMobResets(true) // Night is shorter than the reset cycle, so don't wait for it
+	case DawnHour:
+		dismissNocturnalMobs()
+	}
+}
+
+// dismissNocturnalMobs sends away the creatures of the night when day breaks
+// Those that are fighting or following someone stay until they're done.
+func dismissNocturnalMobs() {
+	var nocturnal []*MobInstance
+	mobMutex.RLock()
+	for _, mob := range mobInstances {
+		if mob.Nocturnal && mob.Master == nil {
+			nocturnal = append(nocturnal, mob)
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range nocturnal {
+		if IsMobInCombat(mob) {
+			continue
+		}
+		room := mob.Room
+		RemoveMobFromRoom(mob)
+		BroadcastToRoom(fmt.Sprintf("%s fades away with the coming of the dawn.", capitalizeFirst(mob.ShortDescription)), room, nil)
+	}
+}
+
+// handleTime tells the player the hour and the date
+func handleTime(player *Player, args []string) string {
+	now := CurrentTime()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("It is %s, on the Day of %s.\r\n", now.clockTime(), now.dayName()))
+	sb.WriteString(fmt.Sprintf("It is the %s day of the Month of %s, in the year %d.", ordinal(now.Day+1), monthNames[now.Month], now.Year+1))
+	if !player.Room.IsIndoors() {
+		if IsNight() {
+			sb.WriteString("\r\nThe stars are out.")
+		} else {
+			sb.WriteString("\r\nThe sun is up.")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,311 @@
+/*
+ * channel.go
+ *
+ * This file implements the chat channels heard across the whole game:
+ * ooc, gossip, newbie, auction, clan, and the info channel of server
+ * announcements. Each channel has its own command, a colored tag, and a
+ * short history that players can play back with '<channel> history'.
+ * Players can turn any channel off with '<channel> off' and back on with
+ * '<channel> on'; the channels they've turned off are saved with their
+ * character. The clan channel is split into one conversation per clan,
+ * and the info channel carries announcements only. 'channels' lists every
+ * channel and whether the player has it on.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Channel is a chat channel heard by every player online who has it turned on
+type Channel struct {
+	Name        string // Name the channel is toggled and looked up by
+	Command     string // Command that talks on the channel, if not its name
+	Color       string // Color code the channel's tag is shown in
+	Description string // Summary shown by 'channels'
+	Listen      bool   // Announcements only; players can listen but not talk
+
+	// Scope, when set, splits the channel into separate conversations, such as
+	// one per clan. It returns the tag and history of the player's conversation,
+	// or a nil history if the player isn't part of one.
+	Scope   func(p *Player) (string, *ChannelHistory)
+	NoScope string // Told to players who aren't part of any conversation
+
+	history *ChannelHistory
+}
+
+// The game's channels
+var (
+	channelOOC = &Channel{
+		Name:        "ooc",
+		Color:       "{C}",
+		Description: "Out-of-character chat with everyone online",
+		history:     NewChannelHistory(ChannelHistorySize),
+	}
+	channelGossip = &Channel{
+		Name:        "gossip",
+		Color:       "{m}",
+		Description: "Idle chatter, rumors, and tall tales",
+		history:     NewChannelHistory(ChannelHistorySize),
+	}
+	channelNewbie = &Channel{
+		Name:        "newbie",
+		Color:       "{g}",
+		Description: "Questions from new players, and answers from old ones",
+		history:     NewChannelHistory(ChannelHistorySize),
+	}
+	channelAuction = &Channel{
+		Name:        "auction",
+		Color:       "{B}",
+		Description: "Buying, selling, and trading",
+		history:     NewChannelHistory(ChannelHistorySize),
+	}
+	channelClan = &Channel{
+		Name:        "clan",
+		Command:     "ctalk",
+		Color:       "{G}",
+		Description: "Talk with the members of your clan",
+		Scope: func(p *Player) (string, *ChannelHistory) {
+			if p.Clan == nil {
+				return "", nil
+			}
+			return p.Clan.Name, p.Clan.history
+		},
+		NoScope: "You aren't in a clan.",
+	}
+	channelInfo = &Channel{
+		Name:        "info",
+		Color:       "{Y}",
+		Description: "Server announcements, such as achievements",
+		Listen:      true,
+		history:     NewChannelHistory(ChannelHistorySize),
+	}
+)
+
+// channels lists every channel in the order 'channels' shows them
+var channels = []*Channel{channelOOC, channelGossip, channelNewbie, channelAuction, channelClan, channelInfo}
+
+// findChannel returns the channel with the given name or command, or nil if there is none
+func findChannel(name string) *Channel {
+	for _, c := range channels {
+		if strings.EqualFold(c.Name, name) || strings.EqualFold(c.command(), name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// command is the command that talks on the channel
+func (c *Channel) command() string {
+	if c.Command != "" {
+		return c.Command
+	}
+	return c.Name
+}
+
+// scope returns the tag and history of the player's conversation on the channel
+func (c *Channel) scope(p *Player) (string, *ChannelHistory) {
+	if c.Scope != nil {
+		return c.Scope(p)
+	}
+	return strings.ToUpper(c.Name), c.history
+}
+
+// send delivers a message to everyone listening to one of the channel's conversations and returns the line sent
+// Those ignoring the player it's from don't hear it.
+func (c *Channel) send(tag string, history *ChannelHistory, message string, from, exclude *Player) string {
+	line := fmt.Sprintf("%s[%s]{x} %s", c.Color, tag, message)
+
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	for _, p := range activePlayers {
+		if p == exclude || !p.ChannelOn(c) || p.Ignores(from) {
+			continue
+		}
+		if c.Scope != nil {
+			if _, h := c.Scope(p); h != history {
+				continue
+			}
+		}
+		p.Send(line)
+	}
+	return line
+}
+
+// Announce sends a message on a channel that isn't split into conversations and records it in the history
+func (c *Channel) Announce(message string) {
+	tag, history := c.scope(nil)
+	history.Add(c.send(tag, history, message, nil, nil))
+}
+
+// Notify sends a passing notice about a player, such as them connecting, to everyone else without recording it
+func (c *Channel) Notify(message string, about *Player) {
+	tag, history := c.scope(nil)
+	c.send(tag, history, message, about, about)
+}
+
+// Talk sends a player's message on the channel
+func (c *Channel) Talk(player *Player, message string) string {
+	if c.Listen {
+		return fmt.Sprintf("You can't talk on the %s channel.", c.Name)
+	}
+	tag, history := c.scope(player)
+	if history == nil {
+		return c.NoScope
+	}
+	if !player.ChannelOn(c) {
+		return fmt.Sprintf("You have the %s channel turned off. Type '%s on' to turn it back on.", c.Name, c.command())
+	}
+
+	message = FilterText(player, SanitizeText(message))
+	history.Add(c.send(tag, history, fmt.Sprintf("%s: %s", player.Name, message), player, nil))
+	return ""
+}
+
+// History plays back the recent messages of the player's conversation on the channel
+func (c *Channel) History(player *Player) string {
+	tag, history := c.scope(player)
+	if history == nil {
+		return c.NoScope
+	}
+	return formatChannelHistory(tag, history, player.Locale)
+}
+
+// ChannelOn reports whether the player is listening to a channel
+func (p *Player) ChannelOn(c *Channel) bool {
+	return !p.ChannelsOff[c.Name]
+}
+
+// SetChannel turns a channel on or off for the player and saves the choice
+func (p *Player) SetChannel(c *Channel, on bool) string {
+	if p.ChannelOn(c) == on {
+		if on {
+			return fmt.Sprintf("Your %s channel is already on.", c.Name)
+		}
+		return fmt.Sprintf("Your %s channel is already off.", c.Name)
+	}
+
+	// Broadcasts read the toggles while holding the players mutex
+	playersMutex.Lock()
+	if p.ChannelsOff == nil {
+		p.ChannelsOff = make(map[string]bool)
+	}
+	if on {
+		delete(p.ChannelsOff, c.Name)
+	} else {
+		p.ChannelsOff[c.Name] = true
+	}
+	playersMutex.Unlock()
+
+	if err := SetPlayerChannelOff(p.Name, c.Name, !on); err != nil {
+		log.Printf("Error saving %s channel toggle for %s: %v", c.Name, p.Name, err)
+	}
+	if on {
+		return fmt.Sprintf("You turn your %s channel on.", c.Name)
+	}
+	return fmt.Sprintf("You turn your %s channel off.", c.Name)
+}
+
+// LoadChannels restores the channels the player has turned off
+func (p *Player) LoadChannels() {
+	p.ChannelsOff = make(map[string]bool)
+	off, err := LoadPlayerChannelsOff(p.Name)
+	if err != nil {
+		log.Printf("Error loading channel toggles for %s: %v", p.Name, err)
+	}
+	for _, name := range off {
+		p.ChannelsOff[name] = true
+	}
+}
+
+// handleChannelCommand talks on a channel, plays back its history, or turns it on or off
+// Usage: <channel> <message>|history|on|off
+func handleChannelCommand(player *Player, c *Channel, args []string) string {
+	if len(args) == 0 {
+		return fmt.Sprintf("%s.\r\nUsage: %s <message>|history|on|off", c.Description, c.command())
+	}
+
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "history":
+			return c.History(player)
+		case "on":
+			return player.SetChannel(c, true)
+		case "off":
+			return player.SetChannel(c, false)
+		}
+	}
+	return c.Talk(player, strings.Join(args, " "))
+}
+
+// handleOOC talks on the ooc channel
+func handleOOC(player *Player, args []string) string {
+	return handleChannelCommand(player, channelOOC, args)
+}
+
+// handleGossip talks on the gossip channel
+func handleGossip(player *Player, args []string) string {
+	return handleChannelCommand(player, channelGossip, args)
+}
+
+// handleNewbie talks on the newbie channel
+func handleNewbie(player *Player, args []string) string {
+	return handleChannelCommand(player, channelNewbie, args)
+}
+
+// handleAuction talks on the auction channel
+func handleAuction(player *Player, args []string) string {
+	return handleChannelCommand(player, channelAuction, args)
+}
+
+// handleInfo plays back the info channel or turns it on or off
+func handleInfo(player *Player, args []string) string {
+	return handleChannelCommand(player, channelInfo, args)
+}
+
+// handleChannels lists every channel and whether the player has it on
+func handleChannels(player *Player, args []string) string {
+	var sb strings.Builder
+	sb.WriteString("{C}Channels:{x}\r\n")
+	for _, c := range channels {
+		state := "{G}on {x}"
+		if !player.ChannelOn(c) {
+			state = "{R}off{x}"
+		}
+		sb.WriteString(fmt.Sprintf("  %s%-8s{x} %s  %s\r\n", c.Color, c.command(), state, c.Description))
+	}
+	sb.WriteString("Type '<channel> on' or '<channel> off' to change one, or '<channel> history' to catch up.")
+	return sb.String()
+}
+
+// handleChannel processes channel subcommands by channel name
+// Usage: channel history|on|off <name>
+func handleChannel(player *Player, args []string) string {
+	if len(args) < 2 {
+		return "Usage: channel history|on|off <name>\r\nType 'channels' to see them all."
+	}
+
+	c := findChannel(args[1])
+	if c == nil {
+		return fmt.Sprintf("There is no channel called '%s'.", args[1])
+	}
+	switch strings.ToLower(args[0]) {
+	case "history":
+		return c.History(player)
+	case "on":
+		return player.SetChannel(c, true)
+	case "off":
+		return player.SetChannel(c, false)
+	default:
+		return "Usage: channel history|on|off <name>"
+	}
+}
+
+// AnnounceInfo broadcasts a server announcement on the info channel
+func AnnounceInfo(message string) {
+	channelInfo.Announce(message)
+}
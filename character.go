@@ -30,7 +30,7 @@ func CreateNewCharacter(conn net.Conn, reader *bufio.Reader, name string) (*Play
 	var race string
 	for {
 		conn.Write([]byte("Enter your choice (1-4): "))
-		input, err := reader.ReadString('\n')
+		input, err := ReadInput(reader)
 		if err != nil {
 			return nil, fmt.Errorf("connection error during race selection: %v", err)
 		}
@@ -58,7 +58,7 @@ func CreateNewCharacter(conn net.Conn, reader *bufio.Reader, name string) (*Play
 	var class string
 	for {
 		conn.Write([]byte("Enter your choice (1-4): "))
-		input, err := reader.ReadString('\n')
+		input, err := ReadInput(reader)
 		if err != nil {
 			return nil, fmt.Errorf("connection error during class selection: %v", err)
 		}
@@ -92,7 +92,7 @@ func CreateNewCharacter(conn net.Conn, reader *bufio.Reader, name string) (*Play
 	for remainingPoints > 0 {
 		conn.Write([]byte(fmt.Sprintf("\nRemaining points: %d\n", remainingPoints)))
 		conn.Write([]byte("Enter stat to increase (STR/DEX/CON/INT/WIS/PRE) or 'done' to finish: "))
-		input, err := reader.ReadString('\n')
+		input, err := ReadInput(reader)
 		if err != nil {
 			return nil, fmt.Errorf("connection error during stat allocation: %v", err)
 		}
@@ -135,23 +135,25 @@ func CreateNewCharacter(conn net.Conn, reader *bufio.Reader, name string) (*Play
 
 	// Create and return the player object
 	player := &Player{
-		Name:         name,
-		Race:         race,
-		Class:        class,
-		Title:        "the Newbie",
-		Room:         room,
-		Conn:         conn,
-		STR:          stats["STR"],
-		DEX:          stats["DEX"],
-		CON:          stats["CON"],
-		INT:          stats["INT"],
-		WIS:          stats["WIS"],
-		PRE:          stats["PRE"],
-		Level:        1,
-		Stamina:      100,
-		MaxStamina:   100,
-		Gold:         0,    // Start with 0 gold
-		ColorEnabled: true, // Default to colors enabled, will be overridden by the connection prompt
+		Name:           name,
+		Race:           race,
+		Class:          class,
+		Title:          "the Newbie",
+		Room:           room,
+		Conn:           conn,
+		STR:            stats["STR"],
+		DEX:            stats["DEX"],
+		CON:            stats["CON"],
+		INT:            stats["INT"],
+		WIS:            stats["WIS"],
+		PRE:            stats["PRE"],
+		Level:          1,
+		Stamina:        100,
+		MaxStamina:     100,
+		Gold:           0,    // Start with 0 gold
+		ColorEnabled:   true, // Default to colors enabled, will be overridden by the connection prompt
+		SquelchEnabled: true, // Collapse repeated combat lines by default
+		Locale:         DefaultLocale,
 	}
 
 	// Calculate derived stats based on class and base stats
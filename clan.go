@@ -0,0 +1,641 @@
+/*
+ * clan.go
+ *
+ * This file implements player clans. Staff create a clan and name its
+ * first leader; from then on the clan runs itself. Members hold one of
+ * four ranks, and each rank grants permissions: officers may invite and
+ * kick lower-ranked members, and leaders may also promote and demote them
+ * and withdraw from the clan treasury, which any member may pay into.
+ * Every clan has a private channel, and staff may give it a hall, a room
+ * that only its members can enter. Clans, memberships, and treasuries are
+ * saved in the database.
+ */
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Clan is a group of players with a shared channel, treasury, and hall
+type Clan struct {
+	Name     string
+	Hall     int // ID of the room only members may enter (0 for none)
+	Treasury int // Gold held in common
+	history  *ChannelHistory
+}
+
+// ClanMember is a player's standing in a clan
+type ClanMember struct {
+	Name string
+	Rank int
+}
+
+// Clan ranks, from lowest to highest
+const (
+	ClanRankRecruit = iota
+	ClanRankMember
+	ClanRankOfficer
+	ClanRankLeader
+)
+
+// Clan permissions
+const (
+	ClanPermInvite   = "invite"   // Invite new members
+	ClanPermKick     = "kick"     // Remove lower-ranked members
+	ClanPermPromote  = "promote"  // Promote and demote lower-ranked members
+	ClanPermWithdraw = "withdraw" // Take gold from the treasury
+)
+
+// ClanRank names a rank and lists what its holders may do
+type ClanRank struct {
+	Name        string
+	Permissions []string
+}
+
+// clanRanks lists the ranks, indexed by rank number
+var clanRanks = []ClanRank{
+	ClanRankRecruit: {Name: "Recruit"},
+	ClanRankMember:  {Name: "Member"},
+	ClanRankOfficer: {Name: "Officer", Permissions: []string{ClanPermInvite, ClanPermKick}},
+	ClanRankLeader:  {Name: "Leader", Permissions: []string{ClanPermInvite, ClanPermKick, ClanPermPromote, ClanPermWithdraw}},
+}
+
+// Clan name limits
+const (
+	MinClanNameLength = 3
+	MaxClanNameLength = 20
+)
+
+var (
+	clans      = make(map[string]*Clan) // Keyed by lowercase name
+	clansMutex sync.RWMutex
+)
+
+// rankName returns the name of a clan rank
+func rankName(rank int) string {
+	if rank < 0 || rank >= len(clanRanks) {
+		return "Unknown"
+	}
+	return clanRanks[rank].Name
+}
+
+// rankAllows reports whether a clan rank grants a permission
+func rankAllows(rank int, permission string) bool {
+	if rank < 0 || rank >= len(clanRanks) {
+		return false
+	}
+	for _, p := range clanRanks[rank].Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadClans reads every clan from the database
+func LoadClans() error {
+	records, err := LoadClanRecords()
+	if err != nil {
+		return err
+	}
+
+	clansMutex.Lock()
+	defer clansMutex.Unlock()
+	for _, c := range records {
+		c.history = NewChannelHistory(ChannelHistorySize)
+		clans[strings.ToLower(c.Name)] = c
+	}
+	log.Printf("Loaded %d clans", len(records))
+	return nil
+}
+
+// FindClan returns the clan with the given name, or nil
+func FindClan(name string) *Clan {
+	clansMutex.RLock()
+	defer clansMutex.RUnlock()
+	return clans[strings.ToLower(name)]
+}
+
+// clanHallOwner returns the clan whose hall is the given room, or nil
+func clanHallOwner(room *Room) *Clan {
+	if room == nil {
+		return nil
+	}
+	clansMutex.RLock()
+	defer clansMutex.RUnlock()
+	for _, c := range clans {
+		if c.Hall == room.ID {
+			return c
+		}
+	}
+	return nil
+}
+
+// CheckClanHall returns an error if the room is another clan's hall
+func CheckClanHall(player *Player, room *Room) error {
+	owner := clanHallOwner(room)
+	if owner == nil || owner == player.Clan || player.Staff {
+		return nil
+	}
+	return fmt.Errorf("only members of %s may enter there", owner.Name)
+}
+
+// LoadClan restores the player's clan membership
+func (p *Player) LoadClan() {
+	name, rank, err := LoadClanMember(p.Name)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading clan for %s: %v", p.Name, err)
+		return
+	}
+	if clan := FindClan(name); clan != nil {
+		p.Clan = clan
+		p.ClanRank = rank
+	}
+}
+
+// ClanCan reports whether the player's clan rank grants a permission
+func (p *Player) ClanCan(permission string) bool {
+	return p.Clan != nil && rankAllows(p.ClanRank, permission)
+}
+
+// clanMembersOnline returns the online members of a clan
+func clanMembersOnline(clan *Clan) []*Player {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	var members []*Player
+	for _, p := range activePlayers {
+		if p.Clan == clan {
+			members = append(members, p)
+		}
+	}
+	return members
+}
+
+// clanAnnounce sends a message on a clan's channel
+func clanAnnounce(clan *Clan, message string) {
+	clan.history.Add(channelClan.send(clan.Name, clan.history, message, nil, nil))
+}
+
+// saveClanMember stores a player's membership, logging any failure
+func saveClanMember(name string, clan *Clan, rank int) {
+	if err := SetClanMember(name, clan.Name, rank); err != nil {
+		log.Printf("Error saving clan membership for %s: %v", name, err)
+	}
+}
+
+// validClanName reports whether a name may be used for a clan
+func validClanName(name string) bool {
+	if len(name) < MinClanNameLength || len(name) > MaxClanNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleClanTalk sends a message on the player's clan channel
+func handleClanTalk(player *Player, args []string) string {
+	return handleChannelCommand(player, channelClan, args)
+}
+
+// handleClan dispatches the clan subcommands
+func handleClan(player *Player, args []string) string {
+	if len(args) == 0 {
+		return clanInfo(player)
+	}
+
+	sub := strings.ToLower(args[0])
+	rest := args[1:]
+	switch sub {
+	case "list":
+		return clanList()
+	case "create":
+		return clanCreate(player, rest)
+	case "disband":
+		return clanDisband(player, rest)
+	case "hall":
+		return clanSetHall(player, rest)
+	case "invite":
+		return clanInvite(player, rest)
+	case "accept":
+		return clanAccept(player)
+	case "decline":
+		return clanDecline(player)
+	case "leave":
+		return clanLeave(player)
+	case "kick":
+		return clanKick(player, rest)
+	case "promote":
+		return clanChangeRank(player, rest, 1)
+	case "demote":
+		return clanChangeRank(player, rest, -1)
+	case "deposit":
+		return clanDeposit(player, rest)
+	case "withdraw":
+		return clanWithdraw(player, rest)
+	default:
+		return "Usage: clan [list|invite|accept|decline|leave|kick|promote|demote|deposit|withdraw]"
+	}
+}
+
+// clanInfo describes the player's clan and its members
+func clanInfo(player *Player) string {
+	if player.Clan == nil {
+		if player.clanInvite != nil {
+			return fmt.Sprintf("You aren't in a clan, but %s has invited you to join. Type 'clan accept' or 'clan decline'.", player.clanInvite.Name)
+		}
+		return "You aren't in a clan. Use 'clan list' to see the clans of the realm."
+	}
+
+	clan := player.Clan
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("{C}%s{x}\r\n", clan.Name))
+	sb.WriteString(fmt.Sprintf("Your rank: %s\r\n", rankName(player.ClanRank)))
+	sb.WriteString(fmt.Sprintf("Treasury: {Y}%s gold{x}\r\n", player.Locale.Number(clan.Treasury)))
+	if clan.Hall != 0 {
+		if hall, err := GetRoom(clan.Hall); err == nil {
+			sb.WriteString(fmt.Sprintf("Clan hall: %s\r\n", hall.Name))
+		}
+	}
+
+	members, err := LoadClanMembers(clan.Name)
+	if err != nil {
+		log.Printf("Error loading members of %s: %v", clan.Name, err)
+		return strings.TrimSuffix(sb.String(), "\r\n")
+	}
+	sb.WriteString("Members:\r\n")
+	for _, m := range members {
+		status := ""
+		if FindPlayerByName(m.Name) != nil {
+			status = " {G}(online){x}"
+		}
+		sb.WriteString(fmt.Sprintf("  %-12s %s%s\r\n", m.Name, rankName(m.Rank), status))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// clanList lists every clan
+func clanList() string {
+	clansMutex.RLock()
+	var names []string
+	for _, c := range clans {
+		names = append(names, c.Name)
+	}
+	clansMutex.RUnlock()
+
+	if len(names) == 0 {
+		return "There are no clans in the realm."
+	}
+	sort.Strings(names)
+	return "{C}Clans of the realm:{x}\r\n  " + strings.Join(names, "\r\n  ")
+}
+
+// clanCreate founds a new clan with the named player as its leader (staff only)
+func clanCreate(player *Player, args []string) string {
+	if !player.Staff {
+		return "You don't have permission to do that."
+	}
+	if len(args) != 2 {
+		return "Usage: clan create <name> <leader>"
+	}
+
+	name := args[0]
+	if !validClanName(name) {
+		return fmt.Sprintf("Clan names must be %d to %d letters.", MinClanNameLength, MaxClanNameLength)
+	}
+	if FindClan(name) != nil {
+		return fmt.Sprintf("There is already a clan called %s.", name)
+	}
+
+	leaderName, ok := resolvePlayerName(args[1])
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", args[1])
+	}
+	if _, _, err := LoadClanMember(leaderName); err == nil {
+		return fmt.Sprintf("%s already belongs to a clan.", leaderName)
+	}
+
+	if err := CreateClanRecord(name); err != nil {
+		log.Printf("Error creating clan %s: %v", name, err)
+		return "Error creating the clan."
+	}
+	clan := &Clan{Name: name, history: NewChannelHistory(ChannelHistorySize)}
+	clansMutex.Lock()
+	clans[strings.ToLower(name)] = clan
+	clansMutex.Unlock()
+
+	saveClanMember(leaderName, clan, ClanRankLeader)
+	if leader := FindPlayerByName(leaderName); leader != nil {
+		leader.Clan = clan
+		leader.ClanRank = ClanRankLeader
+		leader.Send(fmt.Sprintf("You are now the leader of the clan %s.", name))
+	}
+
+	log.Printf("[CLAN] %s created clan %s led by %s.", player.Name, name, leaderName)
+	AnnounceInfo(fmt.Sprintf("The clan {W}%s{x} has been founded, led by %s.", name, leaderName))
+	return fmt.Sprintf("You create the clan %s, led by %s.", name, leaderName)
+}
+
+// clanDisband dissolves a clan, returning its treasury to nobody (staff only)
+func clanDisband(player *Player, args []string) string {
+	if !player.Staff {
+		return "You don't have permission to do that."
+	}
+	if len(args) != 1 {
+		return "Usage: clan disband <name>"
+	}
+	clan := FindClan(args[0])
+	if clan == nil {
+		return fmt.Sprintf("There is no clan called %s.", args[0])
+	}
+
+	if err := DeleteClanRecord(clan.Name); err != nil {
+		log.Printf("Error disbanding clan %s: %v", clan.Name, err)
+		return "Error disbanding the clan."
+	}
+	clansMutex.Lock()
+	delete(clans, strings.ToLower(clan.Name))
+	clansMutex.Unlock()
+
+	for _, p := range clanMembersOnline(clan) {
+		p.Clan = nil
+		p.ClanRank = 0
+		p.Send(fmt.Sprintf("The clan %s has been disbanded.", clan.Name))
+	}
+	RecordGoldDestroyed(GoldSourceClans, clan.Treasury)
+
+	log.Printf("[CLAN] %s disbanded clan %s.", player.Name, clan.Name)
+	return fmt.Sprintf("You disband the clan %s.", clan.Name)
+}
+
+// clanSetHall makes a room a clan's hall (staff only)
+func clanSetHall(player *Player, args []string) string {
+	if !player.Staff {
+		return "You don't have permission to do that."
+	}
+	if len(args) != 2 {
+		return "Usage: clan hall <name> <room_id|none>"
+	}
+	clan := FindClan(args[0])
+	if clan == nil {
+		return fmt.Sprintf("There is no clan called %s.", args[0])
+	}
+
+	hall := 0
+	if !strings.EqualFold(args[1], "none") {
+		roomID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "Invalid room ID. Please provide a number."
+		}
+		room, err := GetRoom(roomID)
+		if err != nil {
+			return fmt.Sprintf("Room %d does not exist.", roomID)
+		}
+		if owner := clanHallOwner(room); owner != nil && owner != clan {
+			return fmt.Sprintf("That room is already the hall of %s.", owner.Name)
+		}
+		hall = roomID
+	}
+
+	if err := UpdateClanHall(clan.Name, hall); err != nil {
+		log.Printf("Error saving hall for clan %s: %v", clan.Name, err)
+		return "Error saving the clan hall."
+	}
+	clan.Hall = hall
+	if hall == 0 {
+		return fmt.Sprintf("%s no longer has a clan hall.", clan.Name)
+	}
+	return fmt.Sprintf("Room %d is now the hall of %s.", hall, clan.Name)
+}
+
+// clanInvite invites a player in the realm to join the clan
+func clanInvite(player *Player, args []string) string {
+	if !player.ClanCan(ClanPermInvite) {
+		return "You don't have the rank to invite members."
+	}
+	if len(args) != 1 {
+		return "Usage: clan invite <player>"
+	}
+	target := FindPlayerByName(args[0])
+	if target == nil || !player.CanSeePlayer(target) {
+		return "They aren't in the realm."
+	}
+	if target.Clan != nil {
+		return fmt.Sprintf("%s already belongs to a clan.", target.Name)
+	}
+
+	target.clanInvite = player.Clan
+	target.Send(fmt.Sprintf("%s invites you to join the clan %s. Type 'clan accept' or 'clan decline'.", player.Name, player.Clan.Name))
+	return fmt.Sprintf("You invite %s to join %s.", target.Name, player.Clan.Name)
+}
+
+// clanAccept joins the clan the player was invited to
+func clanAccept(player *Player) string {
+	clan := player.clanInvite
+	if clan == nil {
+		return "You haven't been invited to a clan."
+	}
+	player.clanInvite = nil
+	if player.Clan != nil {
+		return "You already belong to a clan."
+	}
+	if FindClan(clan.Name) != clan {
+		return "That clan no longer exists."
+	}
+
+	player.Clan = clan
+	player.ClanRank = ClanRankRecruit
+	saveClanMember(player.Name, clan, ClanRankRecruit)
+	clanAnnounce(clan, fmt.Sprintf("%s has joined the clan.", player.Name))
+	return ""
+}
+
+// clanDecline turns down a clan invitation
+func clanDecline(player *Player) string {
+	clan := player.clanInvite
+	if clan == nil {
+		return "You haven't been invited to a clan."
+	}
+	player.clanInvite = nil
+	clanAnnounce(clan, fmt.Sprintf("%s has declined the invitation to join.", player.Name))
+	return fmt.Sprintf("You decline the invitation to join %s.", clan.Name)
+}
+
+// clanLeave takes the player out of their clan
+func clanLeave(player *Player) string {
+	clan := player.Clan
+	if clan == nil {
+		return "You aren't in a clan."
+	}
+	if player.ClanRank == ClanRankLeader {
+		return "Leaders can't leave their clan. Promote another leader and have them demote you first."
+	}
+
+	if err := RemoveClanMember(player.Name); err != nil {
+		log.Printf("Error removing %s from clan %s: %v", player.Name, clan.Name, err)
+		return "Error leaving the clan."
+	}
+	clanAnnounce(clan, fmt.Sprintf("%s has left the clan.", player.Name))
+	player.Clan = nil
+	player.ClanRank = 0
+	return fmt.Sprintf("You leave %s.", clan.Name)
+}
+
+// findClanMember looks up a member of the player's clan, online or off
+func findClanMember(player *Player, name string) (ClanMember, *Player, bool) {
+	name, ok := resolvePlayerName(name)
+	if !ok {
+		return ClanMember{}, nil, false
+	}
+	if target := FindPlayerByName(name); target != nil {
+		return ClanMember{Name: target.Name, Rank: target.ClanRank}, target, target.Clan == player.Clan
+	}
+	clan, rank, err := LoadClanMember(name)
+	if err != nil || !strings.EqualFold(clan, player.Clan.Name) {
+		return ClanMember{}, nil, false
+	}
+	return ClanMember{Name: name, Rank: rank}, nil, true
+}
+
+// clanKick removes a lower-ranked member from the clan
+func clanKick(player *Player, args []string) string {
+	if !player.ClanCan(ClanPermKick) {
+		return "You don't have the rank to kick members."
+	}
+	if len(args) != 1 {
+		return "Usage: clan kick <player>"
+	}
+	member, online, ok := findClanMember(player, args[0])
+	if !ok {
+		return "There is no such member of your clan."
+	}
+	if member.Rank >= player.ClanRank {
+		return fmt.Sprintf("You can't kick %s.", member.Name)
+	}
+
+	if err := RemoveClanMember(member.Name); err != nil {
+		log.Printf("Error removing %s from clan %s: %v", member.Name, player.Clan.Name, err)
+		return "Error kicking that member."
+	}
+	clanAnnounce(player.Clan, fmt.Sprintf("%s has been kicked out of the clan by %s.", member.Name, player.Name))
+	if online != nil {
+		online.Clan = nil
+		online.ClanRank = 0
+		online.Send(fmt.Sprintf("You have been kicked out of %s.", player.Clan.Name))
+	}
+	return ""
+}
+
+// clanChangeRank moves a lower-ranked member up or down a rank
+func clanChangeRank(player *Player, args []string, step int) string {
+	if !player.ClanCan(ClanPermPromote) {
+		return "You don't have the rank to promote or demote members."
+	}
+	if len(args) != 1 {
+		return "Usage: clan promote|demote <player>"
+	}
+	member, online, ok := findClanMember(player, args[0])
+	if !ok {
+		return "There is no such member of your clan."
+	}
+	if member.Rank >= player.ClanRank && member.Name != player.Name {
+		return fmt.Sprintf("You can't change the rank of %s.", member.Name)
+	}
+
+	rank := member.Rank + step
+	switch {
+	case rank > player.ClanRank:
+		return fmt.Sprintf("You can't promote %s above your own rank.", member.Name)
+	case rank < ClanRankRecruit:
+		return fmt.Sprintf("%s is already a %s.", member.Name, rankName(member.Rank))
+	case rank == member.Rank:
+		return fmt.Sprintf("%s is already a %s.", member.Name, rankName(member.Rank))
+	}
+
+	saveClanMember(member.Name, player.Clan, rank)
+	if online != nil {
+		online.ClanRank = rank
+	}
+	verb := "promoted"
+	if step < 0 {
+		verb = "demoted"
+	}
+	clanAnnounce(player.Clan, fmt.Sprintf("%s has been %s to %s by %s.", member.Name, verb, rankName(rank), player.Name))
+	return ""
+}
+
+// clanDeposit pays gold into the clan treasury
+func clanDeposit(player *Player, args []string) string {
+	if player.Clan == nil {
+		return "You aren't in a clan."
+	}
+	amount, ok := parseGoldAmount(args, player.Gold)
+	if !ok {
+		return "Usage: clan deposit <amount|all>"
+	}
+	if amount == 0 {
+		return "You have no gold to deposit."
+	}
+	if amount > player.Gold {
+		return "You don't have that much gold."
+	}
+
+	player.Gold -= amount
+	if err := UpdatePlayerGold(player.Name, player.Gold); err != nil {
+		log.Printf("Error saving gold for %s: %v", player.Name, err)
+	}
+	player.SendStatus()
+	player.Clan.Treasury += amount
+	if err := UpdateClanTreasury(player.Clan.Name, player.Clan.Treasury); err != nil {
+		log.Printf("Error saving treasury of %s: %v", player.Clan.Name, err)
+	}
+
+	clanAnnounce(player.Clan, fmt.Sprintf("%s deposits %d gold in the treasury.", player.Name, amount))
+	return ""
+}
+
+// clanWithdraw takes gold out of the clan treasury
+func clanWithdraw(player *Player, args []string) string {
+	if !player.ClanCan(ClanPermWithdraw) {
+		return "You don't have the rank to withdraw from the treasury."
+	}
+	clan := player.Clan
+	amount, ok := parseGoldAmount(args, clan.Treasury)
+	if !ok {
+		return "Usage: clan withdraw <amount|all>"
+	}
+	if amount == 0 {
+		return "The treasury is empty."
+	}
+	if amount > clan.Treasury {
+		return "The treasury doesn't hold that much."
+	}
+
+	clan.Treasury -= amount
+	if err := UpdateClanTreasury(clan.Name, clan.Treasury); err != nil {
+		log.Printf("Error saving treasury of %s: %v", clan.Name, err)
+	}
+	player.Gold += amount
+	if err := UpdatePlayerGold(player.Name, player.Gold); err != nil {
+		log.Printf("Error saving gold for %s: %v", player.Name, err)
+	}
+	player.SendStatus()
+
+	clanAnnounce(clan, fmt.Sprintf("%s withdraws %d gold from the treasury.", player.Name, amount))
+	return ""
+}
@@ -0,0 +1,146 @@
+/*
+ * client.go
+ *
+ * This file describes the client a player is connected with, from what
+ * the telnet layer negotiated: the client's name and terminal type, its
+ * window size, whether it speaks GMCP, and the capabilities it advertises
+ * through MTTS (the MUD Terminal Type Standard), such as 256 colors or
+ * UTF-8. Code that wants to tailor output to the client asks the player's
+ * Client(). The 'client' command shows a player what the server knows
+ * about their client; staff can look at anyone's.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MTTS capability flags, reported by clients as a terminal type of "MTTS <flags>"
+const (
+	MTTSANSI         = 1
+	MTTSVT100        = 2
+	MTTSUTF8         = 4
+	MTTS256Colors    = 8
+	MTTSMouse        = 16
+	MTTSOSCColors    = 32
+	MTTSScreenReader = 64
+	MTTSProxy        = 128
+	MTTSTrueColor    = 256
+)
+
+// mttsNames describes each MTTS flag, in the order they're listed
+var mttsNames = []struct {
+	flag int
+	name string
+}{
+	{MTTSANSI, "ANSI color"},
+	{MTTSVT100, "VT100"},
+	{MTTSUTF8, "UTF-8"},
+	{MTTS256Colors, "256 colors"},
+	{MTTSMouse, "mouse tracking"},
+	{MTTSOSCColors, "OSC color palette"},
+	{MTTSScreenReader, "screen reader"},
+	{MTTSProxy, "proxy"},
+	{MTTSTrueColor, "true color"},
+}
+
+// ClientInfo is what the server knows about a player's client
+type ClientInfo struct {
+	Web      bool   // Connected through the web client rather than telnet
+	Name     string // The client's name, such as "MUDLET" (empty if it didn't say)
+	Terminal string // The terminal it emulates, such as "XTERM-256COLOR"
+	MTTS     int    // MTTS capability flags (0 if not reported)
+	GMCP     bool   // Whether it receives GMCP
+	Width    int    // Window size reported through NAWS (0 = unknown)
+	Height   int
+}
+
+// Has reports whether the client advertised an MTTS capability
+func (c ClientInfo) Has(flag int) bool {
+	return c.MTTS&flag != 0
+}
+
+// Client returns what the server knows about the player's client
+func (p *Player) Client() ClientInfo {
+	tc, ok := p.Conn.(*TelnetConn)
+	if !ok {
+		return ClientInfo{}
+	}
+	info := ClientInfo{GMCP: tc.GMCPEnabled()}
+	info.Width, info.Height = tc.WindowSize()
+	if _, isWeb := tc.Conn.(*WebSocketConn); isWeb {
+		info.Web = true
+		return info
+	}
+
+	// MTTS clients report their name, then their terminal, then "MTTS <flags>"
+	for i, ttype := range tc.TerminalTypes() {
+		if flags, ok := strings.CutPrefix(strings.ToUpper(ttype), "MTTS "); ok {
+			info.MTTS, _ = strconv.Atoi(flags)
+			continue
+		}
+		switch i {
+		case 0:
+			info.Name = ttype
+		case 1:
+			info.Terminal = ttype
+		}
+	}
+	return info
+}
+
+// describe lists what the server knows about the client
+func (c ClientInfo) describe() string {
+	var sb strings.Builder
+	switch {
+	case c.Web:
+		sb.WriteString("  Client:    the web client\r\n")
+	case c.Name != "":
+		sb.WriteString(fmt.Sprintf("  Client:    %s\r\n", c.Name))
+	default:
+		sb.WriteString("  Client:    unknown (it didn't report a terminal type)\r\n")
+	}
+	if c.Terminal != "" && !strings.EqualFold(c.Terminal, c.Name) {
+		sb.WriteString(fmt.Sprintf("  Terminal:  %s\r\n", c.Terminal))
+	}
+	if c.Width > 0 {
+		sb.WriteString(fmt.Sprintf("  Window:    %d x %d\r\n", c.Width, c.Height))
+	} else {
+		sb.WriteString("  Window:    unknown\r\n")
+	}
+	gmcp := "no"
+	if c.GMCP {
+		gmcp = "yes"
+	}
+	sb.WriteString(fmt.Sprintf("  GMCP:      %s\r\n", gmcp))
+
+	var caps []string
+	for _, m := range mttsNames {
+		if c.Has(m.flag) {
+			caps = append(caps, m.name)
+		}
+	}
+	if len(caps) > 0 {
+		sb.WriteString(fmt.Sprintf("  Supports:  %s\r\n", strings.Join(caps, ", ")))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// handleClient shows what the server knows about a player's client
+// Usage: client [<player>]
+func handleClient(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Your connection:\r\n" + player.Client().describe()
+	}
+	if !player.Staff {
+		return "You can only see your own client."
+	}
+	target := FindPlayerByName(args[0])
+	if target == nil {
+		return fmt.Sprintf("%s isn't online.", capitalizeFirst(args[0]))
+	}
+	return fmt.Sprintf("%s's connection:\r\n%s", target.Name, target.Client().describe())
+}
@@ -41,6 +41,15 @@ Default Color Scheme:
   - Items: {G} Green
   - Skills: {B} Blue
   - Notifications: {D} Dark Gray
+  - Say: {y} Yellow
+  - Tells: {M} Magenta
+  - Whispers: {m} Magenta
+
+Item Rarity Colors:
+  - Common: {G} Green (same as other items)
+  - Uncommon: {C} Cyan
+  - Rare: {B} Blue
+  - Epic: {M} Magenta
 
 To use colors in your code:
   1. For direct player output: player.Send("{R}Colored text{x}")
@@ -103,6 +112,18 @@ var DefaultColorScheme = map[string]string{
 	"item":         "{G}", // Green for items
 	"skill":        "{B}", // Blue for skills
 	"notification": "{D}", // Dark gray for notifications
+	"say":          "{y}", // Yellow for room speech
+	"tell":         "{M}", // Magenta for private tells
+	"whisper":      "{m}", // Dark magenta for whispers
+	"shout":        "{Y}", // Bold yellow for shouts and yells
+}
+
+// RarityColorScheme maps item rarity tiers to the color of the item's name
+var RarityColorScheme = map[string]string{
+	"common":   "{G}", // Green, like any other item
+	"uncommon": "{C}", // Cyan
+	"rare":     "{B}", // Blue
+	"epic":     "{M}", // Magenta
 }
 
 // ProcessColors replaces ROM-style color codes with ANSI escape sequences
@@ -146,3 +167,13 @@ func ColorizeByType(text string, messageType string) string {
 	// Add color code at the beginning and reset at the end
 	return colorCode + text + "{x}"
 }
+
+// ColorizeByRarity applies the color for an item rarity tier to the given text
+// Unknown tiers fall back to the default item color.
+func ColorizeByRarity(text string, rarity string) string {
+	colorCode, exists := RarityColorScheme[rarity]
+	if !exists {
+		return ColorizeByType(text, "item")
+	}
+	return colorCode + text + "{x}"
+}
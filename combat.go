@@ -5,11 +5,33 @@
  * It implements mechanics for calculating and processing combat outcomes,
  * including evasion chances, critical hit chances, and related combat
  * calculations. The functions handle the randomized aspects of combat
- * while accounting for level differences between combatants.
+ * while accounting for level differences between combatants. It also
+ * provides the 'consider' command, which uses the same calculations to
+ * estimate how a fight against a mob would go.
  */
 
 package main
 
+import (
+	"fmt"
+	"strings"
+)
+
+// considerVerdicts maps how many times faster the player would win than lose
+// to a color-coded difficulty verdict, from easiest to hardest
+var considerVerdicts = []struct {
+	ratio   float64
+	verdict string
+}{
+	{4.0, "{G}%s would be an easy kill.{x}"},
+	{2.0, "{G}%s should not give you much trouble.{x}"},
+	{1.25, "{C}%s should fall to you with a little effort.{x}"},
+	{0.8, "{Y}%s looks like an even match.{x}"},
+	{0.5, "{Y}%s would be a tough fight.{x}"},
+	{0.25, "{R}%s would take a lot of luck to beat.{x}"},
+	{0, "{R}%s? You would be annihilated.{x}"},
+}
+
 // CalculateEvasionChance determines the chance to dodge an attack based on level difference
 func CalculateEvasionChance(defenderLevel, attackerLevel int) float64 {
 	baseEvasionChance := 0.05 // 5% base evasion chance
@@ -95,3 +117,61 @@ func ProcessCriticalHit(attackerLevel, defenderLevel int) bool {
 
 	return false
 }
+
+// handleConsider estimates how a fight against a mob would go
+func handleConsider(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Consider killing whom?"
+	}
+
+	mob := FindMobByTarget(player.Room.ID, strings.ToLower(strings.Join(args, " ")))
+	if mob == nil {
+		return "You don't see that here."
+	}
+
+	// Expected damage per round each side deals to the other
+	hitChance := CalculateHitChance(player.Level, mob.Level) + float64(player.Modifier(ApplyHitroll))/100
+	hitChance -= CombatHitPenalty(player.Room)
+	hitChance = min(max(hitChance, 0.05), 1.0)
+	playerDamage := float64(max(CalculateDamage(player.Level)+player.Modifier(ApplyDamroll), 1)) * hitChance
+
+	mobHitChance := CalculateHitChance(mob.Level, player.Level) + float64(mob.Affects.Modifier(ApplyHitroll))/100
+	mobHitChance -= CombatHitPenalty(player.Room)
+	mobHitChance = min(max(mobHitChance, 0.05), 1.0)
+	mobDamage := float64(max(CalculateDamage(mob.Level)+mob.Affects.Modifier(ApplyDamroll), 1)) * mobHitChance
+
+	// Compare the rounds each side would need to finish the other
+	roundsToWin := float64(mob.HP) / playerDamage
+	roundsToLose := float64(player.HP) / mobDamage
+	ratio := roundsToLose / roundsToWin
+
+	name := capitalizeFirst(mob.ShortDescription)
+	verdict := considerVerdicts[len(considerVerdicts)-1].verdict
+	for _, v := range considerVerdicts {
+		if ratio >= v.ratio {
+			verdict = v.verdict
+			break
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(verdict, name) + "\r\n")
+
+	switch diff := mob.Level - player.Level; {
+	case diff > 0:
+		sb.WriteString(fmt.Sprintf("It is %d level(s) above you.", diff))
+	case diff < 0:
+		sb.WriteString(fmt.Sprintf("It is %d level(s) below you.", -diff))
+	default:
+		sb.WriteString("It is the same level as you.")
+	}
+	sb.WriteString(fmt.Sprintf(" You would hit it about %.0f%% of the time.\r\n", hitChance*100))
+
+	if xp := CalculateXPGain(player.Level, mob.Level); xp > 0 {
+		sb.WriteString(fmt.Sprintf("Defeating it would earn you about %d experience.", xp))
+	} else {
+		sb.WriteString("You would learn nothing from defeating it.")
+	}
+
+	return sb.String()
+}
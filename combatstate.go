@@ -0,0 +1,342 @@
+/*
+ * combatstate.go
+ *
+ * This file implements the states a fighter can be knocked into: prone,
+ * stunned, and disarmed. A warrior's bash knocks their opponent to the
+ * ground, and a hard one leaves them stunned as well; a rogue's disarm
+ * sends a player's weapon clattering to the floor, or throws a mob's
+ * attacks off so it hits for half damage. Mobs with bash or disarm special
+ * attacks do the same to players. Each state wears off after a few pulses.
+ *
+ * Someone on the ground is easier to hit and swings less accurately, and
+ * a player who is down can't flee or use most commands. Someone stunned
+ * doesn't swing at all, and a disarmed player can't wield a weapon again
+ * until feeling returns to their hand.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+// How long each state lasts, in pulses
+const (
+	BashPronePulses = 3 // A bashed fighter stays on the ground
+	BashStunPulses  = 2 // A hard bash leaves them stunned
+	BashFallPulses  = 2 // A failed basher lands on the ground themselves
+	DisarmPulses    = 8 // A disarmed fighter can't wield a weapon again
+)
+
+// Changes to the chance to hit from fighting someone on the ground
+const (
+	ProneHitBonus   = 0.15 // Added to the chance to hit someone who is down
+	ProneHitPenalty = 0.15 // Taken from the chance to hit while down
+)
+
+// Bounds on the chance for bash and disarm to succeed
+const (
+	MinCombatSkillChance = 5
+	MaxCombatSkillChance = 95
+)
+
+// Special attacks that knock a player into a state rather than damaging a resource
+const (
+	SpecialBash   = "bash"
+	SpecialDisarm = "disarm"
+)
+
+// A bash can kill, which runs death progs that look skills up in skillTable,
+// so the combat skills are given their uses here rather than in the table
+func init() {
+	FindSkill("bash").Use = useBash
+	FindSkill("disarm").Use = useDisarm
+}
+
+// CombatState holds the pulses left on each state a fighter is in
+type CombatState struct {
+	Prone    int // Pulses until they get back on their feet
+	Stunned  int // Pulses until they can act again
+	Disarmed int // Pulses until they can wield a weapon again
+}
+
+// IsDown reports whether they are on the ground
+func (s *CombatState) IsDown() bool {
+	return s.Prone > 0 || s.Stunned > 0
+}
+
+// knockDown puts them on the ground, and stuns them if stun is set
+// A stunned fighter stays down at least until the stun wears off.
+func (s *CombatState) knockDown(pulses, stun int) {
+	s.Prone = max(s.Prone, pulses, stun)
+	s.Stunned = max(s.Stunned, stun)
+}
+
+// tickStates counts down each state, reporting which ones wore off
+func (s *CombatState) tickStates() (stood, recovered, rearmed bool) {
+	tick := func(pulses *int) bool {
+		if *pulses == 0 {
+			return false
+		}
+		*pulses--
+		return *pulses == 0
+	}
+	recovered = tick(&s.Stunned)
+	stood = tick(&s.Prone)
+	rearmed = tick(&s.Disarmed)
+	return stood, recovered, rearmed
+}
+
+// stateHitModifier returns the change to an attacker's chance to hit from either fighter being down
+func stateHitModifier(attacker, defender *CombatState) float64 {
+	modifier := 0.0
+	if attacker.IsDown() {
+		modifier -= ProneHitPenalty
+	}
+	if defender.IsDown() {
+		modifier += ProneHitBonus
+	}
+	return modifier
+}
+
+// stateSummary describes the states a fighter is in for the combat status, or "" if none
+func (s *CombatState) stateSummary(who string) string {
+	summary := ""
+	switch {
+	case s.Stunned > 0:
+		summary += fmt.Sprintf("%s stunned.\r\n", who)
+	case s.Prone > 0:
+		summary += fmt.Sprintf("%s on the ground.\r\n", who)
+	}
+	if s.Disarmed > 0 {
+		summary += fmt.Sprintf("%s disarmed.\r\n", who)
+	}
+	return summary
+}
+
+// combatSkillChance returns the percent chance for bash or disarm to work against a fighter of defenderLevel
+func combatSkillChance(attackerLevel, defenderLevel int) int {
+	chance := 50 + (attackerLevel-defenderLevel)*5
+	if chance < MinCombatSkillChance {
+		return MinCombatSkillChance
+	}
+	if chance > MaxCombatSkillChance {
+		return MaxCombatSkillChance
+	}
+	return chance
+}
+
+// loseWeapon takes the player's wielded weapon away and keeps them from wielding another for a while
+// The weapon falls to the floor, or into their pack if they keep it. Returns nil if they weren't wielding anything.
+func (p *Player) loseWeapon() *Item {
+	weapon := p.Equipment["wield"]
+	if weapon == nil {
+		return nil
+	}
+
+	delete(p.Equipment, "wield")
+	if !p.keepsDisarmedWeapon(weapon) {
+		AddItemToRoom(weapon, p.Room)
+	} else {
+		p.Inventory = append(p.Inventory, weapon)
+	}
+	p.Disarmed = DisarmPulses
+	for _, message := range p.UpdateSetBonuses() {
+		p.Send(message)
+	}
+	return weapon
+}
+
+// keepsDisarmedWeapon reports whether a weapon knocked from the player's hand stays with them
+// Soulbound weapons can't be left lying about, and a spar shouldn't cost anyone their weapon.
+func (p *Player) keepsDisarmedWeapon(weapon *Item) bool {
+	return !weapon.CanTransfer() || p.Sparring()
+}
+
+// disarmMessage tells a player where their weapon went
+func (p *Player) disarmMessage(weapon *Item) string {
+	if !p.keepsDisarmedWeapon(weapon) {
+		return fmt.Sprintf("%s clatters to the ground!", capitalizeFirst(weapon.ShortDescription))
+	}
+	return fmt.Sprintf("You catch %s before it falls and stow it away.", weapon.Name())
+}
+
+// useBash knocks the player's opponent to the ground, and sometimes stuns them
+// A failed bash leaves the player on the ground instead.
+func useBash(player *Player, target string) (string, bool) {
+	mob, opp := player.Target, player.Opponent
+	if !player.IsInCombat() {
+		return "You aren't fighting anyone.", false
+	}
+
+	var name string
+	var victim *CombatState
+	var level int
+	if opp != nil {
+		name, victim, level = opp.Name, &opp.CombatState, opp.Level
+	} else {
+		name, victim, level = mob.ShortDescription, &mob.CombatState, mob.Level
+	}
+	if victim.IsDown() {
+		return fmt.Sprintf("%s is already on the ground.", capitalizeFirst(name)), false
+	}
+
+	chance := combatSkillChance(player.Level, level)
+	roll := rng.Intn(100)
+	if roll >= chance {
+		player.knockDown(BashFallPulses, 0)
+		if opp != nil {
+			opp.SendType(fmt.Sprintf("%s lunges at you and sprawls on the ground.", player.Name), "combat")
+			sendToOthers(fmt.Sprintf("%s lunges at %s and sprawls on the ground.", player.Name, name), player.Room, player, opp)
+		} else {
+			BroadcastCombatMessage(fmt.Sprintf("%s lunges at %s and sprawls on the ground.", player.Name, name), player.Room, player)
+		}
+		return fmt.Sprintf("You throw yourself at %s, miss, and fall flat on your face!", name), true
+	}
+
+	// A bash well inside the chance to land one stuns as well
+	stun := 0
+	if roll < chance/4 {
+		stun = BashStunPulses
+	}
+	victim.knockDown(BashPronePulses, stun)
+	damage := max(CalculateDamage(player.Level)/2, 1)
+
+	how := "knocking"
+	if stun > 0 {
+		how = "stunning and knocking"
+	}
+	message := fmt.Sprintf("You slam into %s for {R}%d{x} damage, %s them to the ground!", name, damage, how)
+	if opp != nil {
+		player.damageOpponent(damage, false)
+		opp.SendType(fmt.Sprintf("%s slams into you for {R}%d{x} damage, %s you to the ground!", player.Name, damage, how), "combat")
+		sendToOthers(fmt.Sprintf("%s slams into %s, %s them to the ground!", player.Name, name, how), player.Room, player, opp)
+		opp.SendVitals()
+		if player.hasDefeated(opp) {
+			player.Send(message)
+			endPvP(player, opp)
+			return "", true
+		}
+		return message, true
+	}
+
+	if !mob.Dummy {
+		mob.HP -= damage
+	}
+	player.Meter.hit(damage, false)
+	BroadcastCombatMessage(fmt.Sprintf("%s slams into %s, %s them to the ground!", player.Name, name, how), player.Room, player)
+	if mob.HP <= 0 {
+		player.Send(message)
+		player.HandleMobDeath(mob)
+		return "", true
+	}
+	return message, true
+}
+
+// useDisarm knocks the weapon from the player's opponent's hand
+// A disarmed mob hits for half damage until it recovers.
+func useDisarm(player *Player, target string) (string, bool) {
+	mob, opp := player.Target, player.Opponent
+	if !player.IsInCombat() {
+		return "You aren't fighting anyone.", false
+	}
+
+	if opp != nil {
+		if opp.Equipment["wield"] == nil {
+			return fmt.Sprintf("%s isn't wielding anything.", opp.Name), false
+		}
+		if rng.Intn(100) >= combatSkillChance(player.Level, opp.Level) {
+			opp.SendType(fmt.Sprintf("%s tries to disarm you, but you keep hold of your weapon.", player.Name), "combat")
+			sendToOthers(fmt.Sprintf("%s tries to disarm %s, but fails.", player.Name, opp.Name), player.Room, player, opp)
+			return fmt.Sprintf("You try to disarm %s, but fail.", opp.Name), true
+		}
+		weapon := opp.loseWeapon()
+		opp.SendType(fmt.Sprintf("%s disarms you! %s", player.Name, opp.disarmMessage(weapon)), "combat")
+		sendToOthers(fmt.Sprintf("%s disarms %s!", player.Name, opp.Name), player.Room, player, opp)
+		return fmt.Sprintf("You disarm %s!", opp.Name), true
+	}
+
+	if mob.Disarmed > 0 {
+		return fmt.Sprintf("%s is still recovering from the last time.", capitalizeFirst(mob.ShortDescription)), false
+	}
+	if rng.Intn(100) >= combatSkillChance(player.Level, mob.Level) {
+		BroadcastCombatMessage(fmt.Sprintf("%s tries to disarm %s, but fails.", player.Name, mob.ShortDescription), player.Room, player)
+		return fmt.Sprintf("You try to disarm %s, but fail.", mob.ShortDescription), true
+	}
+	mob.Disarmed = DisarmPulses
+	BroadcastCombatMessage(fmt.Sprintf("%s disarms %s, throwing off its attacks!", player.Name, mob.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You disarm %s, throwing off its attacks!", mob.ShortDescription), true
+}
+
+// ReceiveStateAttack applies a mob's bash or disarm special attack to the player
+func (p *Player) ReceiveStateAttack(attacker *MobInstance, special *SpecialAttack) {
+	name := capitalizeFirst(attacker.ShortDescription)
+	var message, roomMessage string
+	switch special.Type {
+	case SpecialBash:
+		if p.IsDown() {
+			return
+		}
+		p.knockDown(BashPronePulses, 0)
+		message = fmt.Sprintf("%s slams into you, knocking you to the ground!", name)
+		roomMessage = fmt.Sprintf("%s slams into %s, knocking them to the ground!", name, p.Name)
+	case SpecialDisarm:
+		weapon := p.loseWeapon()
+		if weapon == nil {
+			return
+		}
+		message = fmt.Sprintf("%s disarms you! %s", name, p.disarmMessage(weapon))
+		roomMessage = fmt.Sprintf("%s disarms %s!", name, p.Name)
+	}
+	if special.Message != "" {
+		message = act(special.Message, attacker.ShortDescription, "", false)
+	}
+
+	p.SendType(message, "combat")
+	BroadcastCombatMessage(roomMessage, p.Room, p)
+}
+
+// ProcessCombatStates counts down the states of every player and mob each pulse
+func ProcessCombatStates() {
+	playersMutex.Lock()
+	var players []*Player
+	for _, p := range activePlayers {
+		players = append(players, p)
+	}
+	playersMutex.Unlock()
+
+	for _, p := range players {
+		stood, recovered, rearmed := p.tickStates()
+		if recovered {
+			p.Send("You shake off the daze.")
+		}
+		if stood {
+			p.Send("You get back on your feet.")
+			BroadcastToRoom("$n gets back on their feet.", p.Room, p)
+		}
+		if rearmed {
+			p.Send("Feeling returns to your weapon hand.")
+		}
+	}
+
+	var risen, rearmed []*MobInstance
+	mobMutex.Lock()
+	for _, m := range mobInstances {
+		stood, _, armed := m.tickStates()
+		if stood {
+			risen = append(risen, m)
+		}
+		if armed {
+			rearmed = append(rearmed, m)
+		}
+	}
+	mobMutex.Unlock()
+
+	// Notify rooms outside the lock
+	for _, m := range risen {
+		BroadcastToRoom(fmt.Sprintf("%s gets back on its feet.", capitalizeFirst(m.ShortDescription)), m.Room, nil)
+	}
+	for _, m := range rearmed {
+		BroadcastCombatMessage(fmt.Sprintf("%s steadies itself.", capitalizeFirst(m.ShortDescription)), m.Room, nil)
+	}
+}
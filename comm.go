@@ -2,10 +2,11 @@
  * comm.go
  *
  * This file implements the communication systems for the MUD.
- * It contains the OOCManager which handles out-of-character (OOC)
- * communication between players. The OOCManager provides functionality
- * for processing OOC commands and broadcasting messages to all connected
- * players, with options to exclude specific players from broadcasts.
+ * It implements the say, sayto, tell, reply, and whisper commands used for
+ * in-room and private player-to-player communication, and the history kept
+ * by each channel so that players who just logged in can catch up on the
+ * conversation with 'ooc history' or 'channel history'. The channels
+ * themselves are in channel.go.
  */
 
 package main
@@ -14,43 +15,210 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
-// OOCManager handles out-of-character communication functionality
-type OOCManager struct {
-	playersMutex *sync.Mutex
-	players      map[string]*Player
+// ChannelHistorySize is the number of recent messages kept per channel
+const ChannelHistorySize = 20
+
+// channelMessage is a single message recorded in a channel's history
+type channelMessage struct {
+	Time time.Time
+	Text string
 }
 
-// NewOOCManager creates a new OOCManager instance
-func NewOOCManager(playersMutex *sync.Mutex, players map[string]*Player) *OOCManager {
-	return &OOCManager{
-		playersMutex: playersMutex,
-		players:      players,
-	}
+// ChannelHistory is a fixed-size ring buffer of a channel's recent messages
+type ChannelHistory struct {
+	mu       sync.Mutex
+	messages []channelMessage
+	next     int // Index the next message will be written to
+}
+
+// NewChannelHistory creates an empty history holding up to size messages
+func NewChannelHistory(size int) *ChannelHistory {
+	return &ChannelHistory{messages: make([]channelMessage, 0, size)}
 }
 
-// HandleOOCCommand processes out-of-character messages
-func (m *OOCManager) HandleOOCCommand(player *Player, input string) {
-	// If the input is exactly "ooc", show the help message
-	if input == "ooc" {
-		player.Send("OOC (Out of Character) lets you chat with other players.\r\nUsage: ooc <message>")
+// Add records a message, overwriting the oldest one once the buffer is full
+func (h *ChannelHistory) Add(text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msg := channelMessage{Time: time.Now(), Text: text}
+	if len(h.messages) < cap(h.messages) {
+		h.messages = append(h.messages, msg)
 		return
 	}
+	h.messages[h.next] = msg
+	h.next = (h.next + 1) % len(h.messages)
+}
+
+// Playback formats the recorded messages from oldest to newest, with times written for the locale
+func (h *ChannelHistory) Playback(locale Locale) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.messages) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(h.messages); i++ {
+		msg := h.messages[(h.next+i)%len(h.messages)]
+		sb.WriteString(fmt.Sprintf("[%s] %s\r\n", locale.Time(msg.Time), msg.Text))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// formatChannelHistory renders a channel's history with a header line
+func formatChannelHistory(name string, history *ChannelHistory, locale Locale) string {
+	playback := history.Playback(locale)
+	if playback == "" {
+		return fmt.Sprintf("There is no recent %s history.", name)
+	}
+	return fmt.Sprintf("{C}Recent %s messages:{x}\r\n%s", name, playback)
+}
+
+// FindPlayerByName looks up an online player by name (case-insensitive)
+func FindPlayerByName(name string) *Player {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	for _, p := range activePlayers {
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// handleSay sends a message to everyone in the player's room
+func handleSay(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Say what?"
+	}
+
+	message := FilterText(player, SanitizeText(strings.Join(args, " ")))
+	lang := player.SpeakingLanguage()
+	for _, p := range playersInRoom(player.Room) {
+		if p == player {
+			continue
+		}
+		// Those who can't see the speaker don't learn who it was
+		name := player.Name
+		if !p.CanSeePlayer(player) {
+			name = "Someone"
+		}
+		p.SendRepeatable(icTag(p) + ColorizeByType(fmt.Sprintf("%s says%s '%s'", name, languageTag(lang), p.Hear(lang, message)), "say"))
+	}
+	player.Send(icTag(player) + ColorizeByType(fmt.Sprintf("You say%s '%s'", languageTag(lang), message), "say"))
+
+	// Mobs answer after the player has heard themselves speak
+	FireSpeechProgs(player, message)
+	FireScriptSpeech(player, message)
+	return ""
+}
+
+// handleSayTo speaks to one player in the room, in the hearing of everyone there
+func handleSayTo(player *Player, args []string) string {
+	if len(args) < 2 {
+		return "Say what to whom?"
+	}
 
-	// Otherwise, strip the "ooc " prefix and broadcast the message
-	message := strings.TrimPrefix(input, "ooc ")
-	m.BroadcastMessage(fmt.Sprintf("[OOC] %s: %s", player.Name, message), nil)
+	target := player.FindVisiblePlayerInRoom(args[0])
+	if target == nil {
+		return "They aren't here."
+	}
+	if target == player {
+		return "You mutter something to yourself."
+	}
+
+	// The message is added after formatting so players can't inject $n or $N
+	message := FilterText(player, SanitizeText(strings.Join(args[1:], " ")))
+	lang := player.SpeakingLanguage()
+	for _, p := range playersInRoom(player.Room) {
+		if p == player || !p.CanSeePlayer(player) {
+			continue
+		}
+		line := act("$n says to $N", player.Name, target.Name, p == target)
+		p.Send(icTag(p) + ColorizeByType(fmt.Sprintf("%s%s '%s'", line, languageTag(lang), p.Hear(lang, message)), "say"))
+	}
+	return icTag(player) + ColorizeByType(fmt.Sprintf("You say to %s%s '%s'", target.Name, languageTag(lang), message), "say")
+}
+
+// handleTell sends a private message to a player anywhere in the world
+func handleTell(player *Player, args []string) string {
+	if len(args) < 2 {
+		return "Tell whom what?"
+	}
+
+	return sendTell(player, args[0], strings.Join(args[1:], " "))
+}
+
+// handleReply answers the last player who sent a tell
+func handleReply(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Reply what?"
+	}
+
+	if player.ReplyTo == "" {
+		return "You have no one to reply to."
+	}
+
+	return sendTell(player, player.ReplyTo, strings.Join(args, " "))
 }
 
-// BroadcastMessage sends a message to all connected players, excluding the specified player (if any)
-func (m *OOCManager) BroadcastMessage(message string, exclude *Player) {
-	m.playersMutex.Lock()
-	defer m.playersMutex.Unlock()
+// sendTell delivers a private message and records the sender for 'reply'
+func sendTell(player *Player, targetName string, message string) string {
+	target := FindPlayerByName(targetName)
+	if target == nil {
+		return fmt.Sprintf("%s is not online.", capitalizeFirst(targetName))
+	}
+
+	if target == player {
+		return "You talk to yourself for a while. It doesn't help."
+	}
+	if target.Ignores(player) {
+		return fmt.Sprintf("%s is ignoring you.", target.Name)
+	}
+	message = FilterText(player, SanitizeText(message))
+
+	target.ReplyTo = player.Name
+	target.Send(ColorizeByType(fmt.Sprintf("%s tells you '%s'", player.Name, message), "tell"))
+	return ColorizeByType(fmt.Sprintf("You tell %s '%s'", target.Name, message), "tell")
+}
+
+// handleWhisper sends a private message to a player in the same room
+func handleWhisper(player *Player, args []string) string {
+	if len(args) < 2 {
+		return "Whisper to whom what?"
+	}
 
-	for _, p := range m.players {
-		if p != exclude {
-			p.Send(message)
+	target := player.FindVisiblePlayerInRoom(args[0])
+	if target == nil {
+		return "They aren't here."
+	}
+
+	if target == player {
+		return "You mumble something to yourself."
+	}
+	if target.Ignores(player) {
+		return fmt.Sprintf("%s is ignoring you.", target.Name)
+	}
+
+	message := FilterText(player, SanitizeText(strings.Join(args[1:], " ")))
+	lang := player.SpeakingLanguage()
+	target.ReplyTo = player.Name
+	target.Send(ColorizeByType(fmt.Sprintf("%s whispers to you%s '%s'", player.Name, languageTag(lang), target.Hear(lang, message)), "whisper"))
+
+	// Others in the room notice the whisper but not what was said
+	playersMutex.Lock()
+	for _, p := range activePlayers {
+		if p != player && p != target && p.Room == player.Room && p.CanSeePlayer(player) {
+			p.Send(fmt.Sprintf("%s whispers something to %s.", player.Name, target.Name))
 		}
 	}
+	playersMutex.Unlock()
+
+	return ColorizeByType(fmt.Sprintf("You whisper to %s%s '%s'", target.Name, languageTag(lang), message), "whisper")
 }
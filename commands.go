@@ -27,16 +27,25 @@ type CommandHandler func(player *Player, args []string) string
 var commandHandlers = map[string]CommandHandler{
 	"quit":      handleQuit,
 	"look":      handleLook,
+	"examine":   handleExamine,
+	"lore":      handleLore,
 	"score":     handleScore,
 	"scorecard": handleScore,
 	"gainxp":    handleGainXP,
 	"save":      handleSave,
 	// Combat commands
-	"attack": handleAttack,
-	"kill":   handleAttack,
-	"flee":   handleFlee,
-	"status": handleStatus,
-	"combat": handleStatus,
+	"attack":   handleAttack,
+	"kill":     handleAttack,
+	"assist":   handleAssist,
+	"bribe":    handleBribe,
+	"consider": handleConsider,
+	"cast":     handleCast,
+	"use":      handleCast,
+	"con":      handleConsider,
+	"duel":     handleDuel,
+	"flee":     handleFlee,
+	"status":   handleStatus,
+	"combat":   handleStatus,
 	// Debug commands
 	"debug": handleDebug,
 	// Movement commands
@@ -52,16 +61,79 @@ var commandHandlers = map[string]CommandHandler{
 	"w":     handleMove,
 	"u":     handleMove,
 	"d":     handleMove,
+	"map":   handleMap,
+	"exits": handleExits,
+	"scan":  handleScan,
+	// Weather command
+	"weather": handleWeather,
+	"time":    handleTime,
+	"uptime":  handleUptime,
 	// Death commands
 	"respawn": handleRespawn,
 	// Color commands
 	"color": handleColor,
+	// Spam squelch command
+	"squelch": handleSquelch,
+	// Low resource alerts
+	"alert": handleAlert,
+	// Output paging and wrapping
+	"pagelength": handlePageLength,
+	"width":      handleWidth,
+
+	// Client capabilities
+	"client": handleClient,
+	// Number and time formatting
+	"locale": handleLocale,
+	// Watchdog bot checks
+	"answer": handleAnswer,
 	// Recall command
 	"recall": handleRecall,
 	// Title command
 	"title": handleTitle,
 	// Who command
 	"who": handleWho,
+	// Roleplay commands
+	"rp":    handleRP,
+	"rpwho": handleRPWho,
+	"looc":  handleLOOC,
+	// Achievements command
+	"achievements": handleAchievements,
+	// Bank commands
+	"deposit":  handleDeposit,
+	"withdraw": handleWithdraw,
+	"balance":  handleBalance,
+	// Staff commands
+	"copyover": handleCopyover,
+	"economy":  handleEconomy,
+	"untitle":  handleUntitle,
+	"scripts":  handleScripts,
+	"inactive": handleInactive,
+	"plugins":  handlePlugins,
+	"watchdog": handleWatchdog,
+	// Immortal commands
+	"restore":  handleRestore,
+	"slay":     handleSlay,
+	"transfer": handleTransfer,
+	"invis":    handleInvis,
+	"snoop":    handleSnoop,
+	"peace":    handlePeace,
+	"purge":    handlePurge,
+	"trust":    handleTrust,
+	"ban":      handleBan,
+	"siteban":  handleSiteban,
+	"unban":    handleUnban,
+	"banlist":  handleBanlist,
+	"auditlog": handleAuditlog,
+	"shutdown": handleShutdown,
+	"reboot":   handleReboot,
+	// Building commands
+	"medit":    handleMedit,
+	"reset":    handleReset,
+	"asave":    handleAsave,
+	"loadarea": handleLoadarea,
+	// Player lookup and notes
+	"whois": handleWhois,
+	"pnote": handlePnote,
 	// Help command
 	"help": handleHelp,
 	// Door commands
@@ -69,14 +141,102 @@ var commandHandlers = map[string]CommandHandler{
 	"close": handleClose,
 	// Teleport command
 	"goto": handleGoto,
+	// Builder search
+	"rsearch": handleRsearch,
+	// World-state dashboard
+	"worldstat": handleWorldstat,
+	// Communication commands
+	"say":      handleSay,
+	"tell":     handleTell,
+	"reply":    handleReply,
+	"whisper":  handleWhisper,
+	"shout":    handleShout,
+	"yell":     handleYell,
+	"sayto":    handleSayTo,
+	"speak":    handleSpeak,
+	"learn":    handleLearn,
+	"socials":  handleSocials,
+	"channel":  handleChannel,
+	"channels": handleChannels,
+	"ignore":   handleIgnore,
+	"friend":   handleFriend,
+	"friends":  handleFriends,
+	"ooc":      handleOOC,
+	"gossip":   handleGossip,
+	"newbie":   handleNewbie,
+	"auction":  handleAuction,
+	"info":     handleInfo,
+	// Clan commands
+	"clan":     handleClan,
+	"ctalk":    handleClanTalk,
+	"clantalk": handleClanTalk,
+	// Housing commands
+	"house": handleHouse,
+	"home":  handleHome,
+	// Session logging
+	"log": handleLog,
+	// Logout commands
+	"camp": handleCamp,
+	"rent": handleRent,
+	// Item commands
+	"get":    handleGet,
+	"take":   handleGet,
+	"drop":   handleDrop,
+	"give":   handleGive,
+	"put":    handlePut,
+	"wear":   handleWear,
+	"eat":    handleEat,
+	"drink":  handleDrink,
+	"fill":   handleFill,
+	"train":  handleTrain,
+	"skills": handleSkills,
+	"quest":  handleQuest,
+	// Follower commands
+	"order":     handleOrder,
+	"followers": handleFollowers,
+	"list":      handleList,
+	"buy":       handleBuy,
+	"mount":     handleMount,
+	"ride":      handleMount,
+	"dismount":  handleDismount,
+	"gather":    handleGather,
+	"craft":     handleCraft,
+	"wield":     handleWear,
+	"remove":    handleRemove,
+	"equipment": handleEquipment,
+	"eq":        handleEquipment,
+	"loot":      handleLoot,
+	"auto":      handleAuto,
+	"display":   handleDisplay,
+	"prompt":    handlePrompt,
+	"spar":      handleSpar,
+	"alias":     handleAlias,
+	"unalias":   handleUnalias,
+	"autoloot":  handleAuto,
+	"autogold":  handleAuto,
+	"autosac":   handleAuto,
+	"sacrifice": handleSacrifice,
+	"sac":       handleSacrifice,
+	"junk":      handleJunk,
+	"inventory": handleInventory,
+	"inv":       handleInventory,
+	"i":         handleInventory,
 }
 
 // HandleCommand processes a player's command and returns the appropriate response
 func HandleCommand(player *Player, input string) string {
-	// Handle OOC chat separately
-	if input == "ooc" || strings.HasPrefix(input, "ooc ") {
-		oocManager.HandleOOCCommand(player, input)
-		return ""
+	// Let the watchdog see how fast commands are arriving
+	player.WatchCommand()
+
+	// Replace an alias with the command it stands for
+	input, refusal := player.expandAlias(input)
+	if refusal != "" {
+		return refusal
+	}
+
+	// Allow the classic ' shortcut for say
+	if strings.HasPrefix(input, "'") {
+		input = "say " + strings.TrimPrefix(input, "'")
 	}
 
 	// Store the last command for reference
@@ -91,23 +251,29 @@ func HandleCommand(player *Player, input string) string {
 	command := strings.ToLower(parts[0])
 	args := parts[1:]
 
-	// Check if player is dead
-	if player.IsDead {
-		// Only allow certain commands when dead
-		switch command {
-		case "look", "score", "quit", "respawn":
-			// These commands are allowed when dead
-		default:
-			return "You are dead and cannot do that. Type 'respawn' to return to life."
-		}
+	// A player the watchdog suspects of botting must answer its question first
+	if refusal := player.checkRefusal(command); refusal != "" {
+		return refusal
+	}
+
+	// Check the command's position, combat, cooldown, and cost requirements
+	if refusal := checkCommandRule(player, command); refusal != "" {
+		return refusal
 	}
 
 	// Look up the handler for this command
 	handler, exists := commandHandlers[command]
 	if !exists {
+		// Anything that isn't a command may be a social
+		if social := FindSocial(command); social != nil {
+			return PerformSocial(player, social, args)
+		}
 		return fmt.Sprintf("Unknown command: %s", command)
 	}
 
+	// Keep a record of staff using privileged commands
+	AuditCommand(player, command, args)
+
 	// Execute the handler and return its response
 	return handler(player, args)
 }
@@ -115,6 +281,21 @@ func HandleCommand(player *Player, input string) string {
 // Individual command handlers
 
 func handleQuit(player *Player, args []string) string {
+	// Outside an inn, leaving the realm requires making camp first
+	if !player.IsDead && !player.Room.Inn {
+		return startCamp(player)
+	}
+
+	// At an inn, quitting means paying for a room
+	if !player.IsDead {
+		return handleRent(player, args)
+	}
+
+	return saveAndQuit(player)
+}
+
+// saveAndQuit saves the player's progress and marks the session for logout
+func saveAndQuit(player *Player) string {
 	// Save player's progress before quitting
 	if err := UpdatePlayerXP(player.Name, player.XP, player.NextLevelXP); err != nil {
 		log.Printf("Error saving player XP on quit: %v", err)
@@ -128,10 +309,85 @@ func handleQuit(player *Player, args []string) string {
 		log.Printf("Error saving player stats on quit: %v", err)
 	}
 
+	if err := UpdatePlayerGold(player.Name, player.Gold); err != nil {
+		log.Printf("Error saving player gold on quit: %v", err)
+	}
+
+	if err := SavePlayerInventory(player.Name, player.Inventory, player.Equipment); err != nil {
+		log.Printf("Error saving player inventory on quit: %v", err)
+	}
+
+	if err := SavePlayerAffects(player.Name, player.Affects); err != nil {
+		log.Printf("Error saving player affects on quit: %v", err)
+	}
+
+	if err := player.SaveSurvival(); err != nil {
+		log.Printf("Error saving player hunger and thirst on quit: %v", err)
+	}
+
+	if err := player.SavePlaytime(); err != nil {
+		log.Printf("Error saving player playtime on quit: %v", err)
+	}
+
+	player.CampTimer = 0
+	player.Quitting = true
+
 	return "Your progress has been saved. Goodbye!"
 }
 
+// handleRent lets a player log out instantly when staying at an inn
+func handleRent(player *Player, args []string) string {
+	if !player.Room.Inn {
+		return "You can't rent a room here. Find an inn, or 'camp' to leave the realm."
+	}
+
+	if !ChargeGold(player, economy.RentCost, GoldSourceRent) {
+		return fmt.Sprintf("A room costs %d gold, which you don't have. You could 'camp' instead.", economy.RentCost)
+	}
+
+	BroadcastToRoom("$n rents a room for the night.", player.Room, player)
+	if economy.RentCost > 0 {
+		player.Send(fmt.Sprintf("You pay the innkeeper %d gold.", economy.RentCost))
+	}
+	player.Send("The innkeeper hands you a key and shows you to your room.")
+	return saveAndQuit(player)
+}
+
+// handleCamp starts the camping countdown that safely logs the player out
+func handleCamp(player *Player, args []string) string {
+	if len(args) > 0 && strings.ToLower(args[0]) == "stop" {
+		if player.CampTimer == 0 {
+			return "You are not making camp."
+		}
+		player.CancelCamp("You break camp.")
+		return ""
+	}
+
+	// Camping isn't needed inside an inn, unless the player can't pay for a room
+	if player.Room.Inn && player.Gold >= economy.RentCost {
+		return "You are at an inn. Use 'rent' or 'quit' to leave the realm immediately."
+	}
+
+	return startCamp(player)
+}
+
+// startCamp begins the camping countdown for a player
+func startCamp(player *Player) string {
+	if player.CampTimer > 0 {
+		return fmt.Sprintf("You are already making camp. You will leave the realm in %d seconds.", player.CampTimer)
+	}
+
+	player.CampTimer = CampDurationSeconds
+	BroadcastToRoom("$n begins making camp.", player.Room, player)
+
+	return fmt.Sprintf("You begin making camp. You will leave the realm in %d seconds unless you are disturbed.\r\n"+
+		"(Type 'camp stop' to cancel, or find an inn to leave immediately.)", CampDurationSeconds)
+}
+
 func handleScore(player *Player, args []string) string {
+	if len(args) > 0 && strings.EqualFold(args[0], "export") {
+		return handleScoreExport(player, args[1:])
+	}
 	return GetScorecard(player)
 }
 
@@ -162,6 +418,31 @@ func handleSave(player *Player, args []string) string {
 		return "Error saving your progress."
 	}
 
+	if err := UpdatePlayerGold(player.Name, player.Gold); err != nil {
+		log.Printf("Error saving player gold: %v", err)
+		return "Error saving your progress."
+	}
+
+	if err := SavePlayerInventory(player.Name, player.Inventory, player.Equipment); err != nil {
+		log.Printf("Error saving player inventory: %v", err)
+		return "Error saving your progress."
+	}
+
+	if err := SavePlayerAffects(player.Name, player.Affects); err != nil {
+		log.Printf("Error saving player affects: %v", err)
+		return "Error saving your progress."
+	}
+
+	if err := player.SaveSurvival(); err != nil {
+		log.Printf("Error saving player hunger and thirst: %v", err)
+		return "Error saving your progress."
+	}
+
+	if err := player.SavePlaytime(); err != nil {
+		log.Printf("Error saving player playtime: %v", err)
+		return "Error saving your progress."
+	}
+
 	return "Your progress has been saved."
 }
 
@@ -169,10 +450,14 @@ func handleMove(player *Player, args []string) string {
 	// Get the direction from the player's last command
 	direction := strings.ToLower(player.LastCommand)
 
-	// Handle movement
+	// Handle movement, slowed by a heavy load
+	if err := player.checkEncumbrance(); err != nil {
+		return err.Error()
+	}
 	if err := HandleMovement(player, direction); err != nil {
 		return err.Error()
 	}
+	player.payEncumbrance()
 
 	// Return empty string as the movement function will send the room description
 	return ""
@@ -186,6 +471,14 @@ func handleLook(player *Player, args []string) string {
 	return lookResult
 }
 
+// handleExamine looks closely at a mob, item, or feature of the room
+func handleExamine(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Examine what?"
+	}
+	return HandleLook(player, args)
+}
+
 // handleAttack processes a player's attempt to attack a mob
 func handleAttack(player *Player, args []string) string {
 	// Check if player is already in combat
@@ -205,6 +498,10 @@ func handleAttack(player *Player, args []string) string {
 	mob := FindMobByTarget(player.Room.ID, targetName)
 
 	if mob == nil {
+		// Players can only be attacked in the arena
+		if target := player.FindVisiblePlayerInRoom(targetName); target != nil {
+			return attackPlayer(player, target)
+		}
 		return "You don't see that here.\r\n"
 	}
 
@@ -213,8 +510,24 @@ func handleAttack(player *Player, args []string) string {
 		return fmt.Sprintf("The %s is already dead!\r\n", mob.ShortDescription)
 	}
 
+	if mob.IsFollowing(player) {
+		return "You can't attack your own follower.\r\n"
+	}
+	ok, notice := player.mayAttack(mob)
+	if !ok {
+		return notice + "\r\n"
+	}
+	if notice != "" {
+		player.Send(notice)
+	}
+
+	// Starting a fight breaks camp and invisibility
+	player.CancelCamp("You stop making camp.")
+	player.BecomeVisible()
+
 	// Set the player's combat state
 	player.EnterCombat(mob)
+	player.SendStatus()
 
 	// Broadcast the combat initiation to other players in the room
 	BroadcastCombatMessage(fmt.Sprintf("%s attacks the %s!",
@@ -229,37 +542,16 @@ func handleAttack(player *Player, args []string) string {
 		mob.ShortDescription, mob.ShortDescription)
 }
 
-// handleFlee processes a player's attempt to flee from combat
-func handleFlee(player *Player, args []string) string {
-	// Check if player is in combat
-	if !player.IsInCombat() {
-		return "You're not in combat.\r\n"
-	}
-
-	// Get the mob name for the message
-	mobName := "something"
-	if player.Target != nil {
-		mobName = player.Target.ShortDescription
-	}
-
-	// Exit combat BEFORE broadcasting to avoid deadlocks
-	player.ExitCombat()
-
-	// Broadcast the flee to other players in the room
-	BroadcastCombatMessage(fmt.Sprintf("%s flees from the %s!",
-		player.Name, mobName), player.Room, player)
-
-	// Log the flee
-	//log.Printf("[COMBAT] Player %s fled from combat", player.Name)
-
-	// Return success message
-	return fmt.Sprintf("You flee from the %s!\r\n", mobName)
-}
-
 // handleStatus shows the player's current combat status
 func handleStatus(player *Player, args []string) string {
-	if !player.IsInCombat() {
-		return "You are not in combat.\r\n"
+	if opp := player.Opponent; opp != nil {
+		return fmt.Sprintf("You are fighting %s.\r\n"+
+			"Your health: %d/%d\r\n"+
+			"Your level: %d, Opponent level: %d\r\n"+
+			"Hit chance: %.0f%%\r\n",
+			opp.Name, player.HP, player.MaxHP, player.Level, opp.Level,
+			CalculateHitChance(player.Level, opp.Level)*100) +
+			player.stateSummary("You are") + opp.stateSummary(opp.Name+" is")
 	}
 
 	if player.Target == nil {
@@ -285,7 +577,8 @@ func handleStatus(player *Player, args []string) string {
 		player.Target.HP, player.Target.MaxHP,
 		player.Level, player.Target.Level,
 		finalHitChance*100,
-		expectedDamage)
+		expectedDamage) +
+		player.stateSummary("You are") + player.Target.stateSummary(capitalizeFirst(player.Target.ShortDescription)+" is")
 }
 
 // handleDebug provides debug information for testing
@@ -296,7 +589,7 @@ func handleDebug(player *Player, args []string) string {
 
 	switch args[0] {
 	case "combat":
-		if !player.IsInCombat() {
+		if !player.IsInCombat() || player.Target == nil {
 			return "You are not in combat.\r\n"
 		}
 		return fmt.Sprintf("Combat Debug:\r\n"+
@@ -373,9 +666,10 @@ func handleRespawn(player *Player, args []string) string {
 
 	// Broadcast departure and arrival messages
 	if oldRoom != startRoom {
-		BroadcastToRoom(fmt.Sprintf("%s's body fades away.", player.Name), oldRoom, player)
+		BroadcastToRoom("$n's body fades away.", oldRoom, player)
 	}
-	BroadcastToRoom(ColorizeByType(fmt.Sprintf("%s appears in a flash of divine light.", player.Name), "system"), startRoom, player)
+	BroadcastToRoom(ColorizeByType("$n appears in a flash of divine light.", "system"), startRoom, player)
+	player.SendRoomInfo()
 
 	return "{G}You feel your spirit being pulled back to the world of the living...{x}"
 }
@@ -415,11 +709,6 @@ func handleColor(player *Player, args []string) string {
 
 // handleRecall processes a player's attempt to recall to the respawn point (RespawnRoomID)
 func handleRecall(player *Player, args []string) string {
-	// Check if player is in combat
-	if player.IsInCombat() {
-		return "You cannot recall while fighting!"
-	}
-
 	// Get the destination room
 	destRoom, err := GetRoom(RespawnRoomID)
 	if err != nil {
@@ -427,6 +716,12 @@ func handleRecall(player *Player, args []string) string {
 		return "The recall magic fizzles. The destination seems to be missing."
 	}
 
+	// The temple asks a donation from those who can afford it
+	cost := economy.RecallCost * player.Level
+	if !ChargeGold(player, cost, GoldSourceRecall) {
+		return fmt.Sprintf("The gods require a %d gold donation to recall you.", cost)
+	}
+
 	// Store the old room for notifications
 	oldRoom := player.Room
 
@@ -439,6 +734,7 @@ func handleRecall(player *Player, args []string) string {
 
 	// Update player's room in memory
 	player.Room = destRoom
+	Publish(Event{Type: EventRoomEntered, Player: player, Room: destRoom})
 
 	// Log the recall event
 	log.Printf("[RECALL] Player %s recalled to Room %d.", player.Name, RespawnRoomID)
@@ -446,7 +742,7 @@ func handleRecall(player *Player, args []string) string {
 	// Notify players in the old room about departure
 	playersMutex.Lock()
 	for _, p := range activePlayers {
-		if p != player && p.Room == oldRoom {
+		if p != player && p.Room == oldRoom && p.CanSeePlayer(player) {
 			p.Send(fmt.Sprintf("%s disappears in a flash of light.", player.Name))
 		}
 	}
@@ -455,7 +751,7 @@ func handleRecall(player *Player, args []string) string {
 	// Notify players in the new room about arrival
 	playersMutex.Lock()
 	for _, p := range activePlayers {
-		if p != player && p.Room == destRoom {
+		if p != player && p.Room == destRoom && p.CanSeePlayer(player) {
 			p.Send(fmt.Sprintf("%s appears in a flash of light.", player.Name))
 		}
 	}
@@ -464,6 +760,7 @@ func handleRecall(player *Player, args []string) string {
 	// Send success message and room description to the player
 	player.Send("A bright flash surrounds you, and you find yourself back at the Temple Square.")
 	player.Send(DescribeRoom(destRoom, player))
+	player.SendRoomInfo()
 
 	return ""
 }
@@ -480,8 +777,8 @@ func handleTitle(player *Player, args []string) string {
 		return "Your title has been removed."
 	}
 
-	// Combine all arguments into a single title
-	title := strings.Join(args, " ")
+	// Combine all arguments into a single title, without any control characters
+	title := SanitizeText(strings.Join(args, " "))
 
 	// Trim any trailing spaces
 	title = strings.TrimSpace(title)
@@ -490,6 +787,9 @@ func handleTitle(player *Player, args []string) string {
 	if LengthWithoutColorCodes(title) > 40 {
 		return "Titles must be no longer than 40 characters."
 	}
+	if ContainsFilteredWord(player, title) {
+		return "That title isn't allowed."
+	}
 
 	// Ensure the title ends with a color reset code
 	if !strings.HasSuffix(title, "{x}") {
@@ -534,11 +834,22 @@ func handleWho(player *Player, args []string) string {
 	output += "{C}----------------------------------------{x}\r\n"
 
 	// Format each player's information
+	count := 0
 	for _, p := range activePlayers {
+		if !player.CanSeePlayer(p) {
+			continue
+		}
+		count++
+
 		// Format the player's race, class, and level within brackets
 		bracketInfo := fmt.Sprintf("[{G}%-6s{x} {B}%-8s{x} {M}%-3d{x}]",
 			p.Race, p.Class, p.Level)
 
+		// Mark players looking to roleplay
+		if p.Roleplay {
+			bracketInfo += " " + rpWhoTag
+		}
+
 		// Add the player's name and title (if they have one)
 		if p.Title != "" {
 			output += fmt.Sprintf("%s {W}%s{x} %s\r\n", bracketInfo, p.Name, p.Title)
@@ -549,7 +860,7 @@ func handleWho(player *Player, args []string) string {
 
 	// Add a footer with the total count
 	output += "{C}----------------------------------------{x}\r\n"
-	output += fmt.Sprintf("{Y}Total players online: {W}%d{x}\r\n", len(activePlayers))
+	output += fmt.Sprintf("{Y}Total players online: {W}%d{x}\r\n", count)
 
 	return output
 }
@@ -596,7 +907,7 @@ func handleOpen(player *Player, args []string) string {
 		message := fmt.Sprintf("You open the %s.", exit.Door.ShortDescription)
 
 		// Notify other players in the room
-		BroadcastToRoom(fmt.Sprintf("%s opens the %s.", player.Name, exit.Door.ShortDescription), player.Room, player)
+		BroadcastToRoom(fmt.Sprintf("$n opens the %s.", exit.Door.ShortDescription), player.Room, player)
 
 		return message
 	}
@@ -626,7 +937,7 @@ func handleOpen(player *Player, args []string) string {
 					message := fmt.Sprintf("You open the %s to the %s.", exit.Door.ShortDescription, direction)
 
 					// Notify other players in the room
-					BroadcastToRoom(fmt.Sprintf("%s opens the %s to the %s.", player.Name, exit.Door.ShortDescription, direction), player.Room, player)
+					BroadcastToRoom(fmt.Sprintf("$n opens the %s to the %s.", exit.Door.ShortDescription, direction), player.Room, player)
 
 					return message
 				}
@@ -634,6 +945,11 @@ func handleOpen(player *Player, args []string) string {
 		}
 	}
 
+	// Something in the room that springs a trap when opened
+	if message, ok := OpenTrigger(player, target); ok {
+		return message
+	}
+
 	return "You don't see that here."
 }
 
@@ -674,7 +990,7 @@ func handleClose(player *Player, args []string) string {
 		message := fmt.Sprintf("You close the %s.", exit.Door.ShortDescription)
 
 		// Notify other players in the room
-		BroadcastToRoom(fmt.Sprintf("%s closes the %s.", player.Name, exit.Door.ShortDescription), player.Room, player)
+		BroadcastToRoom(fmt.Sprintf("$n closes the %s.", exit.Door.ShortDescription), player.Room, player)
 
 		return message
 	}
@@ -699,7 +1015,7 @@ func handleClose(player *Player, args []string) string {
 					message := fmt.Sprintf("You close the %s to the %s.", exit.Door.ShortDescription, direction)
 
 					// Notify other players in the room
-					BroadcastToRoom(fmt.Sprintf("%s closes the %s to the %s.", player.Name, exit.Door.ShortDescription, direction), player.Room, player)
+					BroadcastToRoom(fmt.Sprintf("$n closes the %s to the %s.", exit.Door.ShortDescription, direction), player.Room, player)
 
 					return message
 				}
@@ -742,6 +1058,9 @@ func handleGoto(player *Player, args []string) string {
 	// Log the teleportation for debugging
 	log.Printf("Player %s teleported to room %d (%s)", player.Name, roomID, newRoom.Name)
 
+	// Let GMCP clients update their map
+	player.SendRoomInfo()
+
 	// Return success message
 	return fmt.Sprintf("You teleport to Room %d (%s).", roomID, newRoom.Name)
 }
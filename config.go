@@ -0,0 +1,293 @@
+/*
+ * config.go
+ *
+ * This file loads the server configuration. Settings have built-in defaults
+ * and may be overridden in config.yml without recompiling; any setting left
+ * out of the file keeps its default. Economy settings live separately in
+ * economy.yml.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServerConfigFile is the optional file that overrides the default server settings
+const ServerConfigFile = "config.yml"
+
+// ServerConfig holds the server-wide settings
+type ServerConfig struct {
+	Death       DeathConfig       `yaml:"death"`
+	Alerts      AlertsConfig      `yaml:"alerts"`
+	Survival    SurvivalConfig    `yaml:"survival"`
+	Filter      FilterConfig      `yaml:"filter"`
+	Purge       PurgeConfig       `yaml:"purge"`
+	Watchdog    WatchdogConfig    `yaml:"watchdog"`
+	Web         WebConfig         `yaml:"web"`
+	Listeners   []ListenerConfig  `yaml:"listeners"` // Ports players connect on (see listen.go)
+	Flood       FloodConfig       `yaml:"flood"`
+	Connections ConnectionsConfig `yaml:"connections"`
+	Plugins     map[string]bool   `yaml:"plugins"` // Optional systems turned on or off; any not listed are on
+}
+
+// WebConfig holds settings for the web server
+type WebConfig struct {
+	PublicURL string `yaml:"public_url"` // Address players reach the web server at, used in links to shared character sheets
+}
+
+// WatchdogConfig holds the limits past which the anti-cheat watchdog flags a player
+type WatchdogConfig struct {
+	Enabled           bool `yaml:"enabled"`              // Whether players who aren't staff are watched
+	CommandsPerSecond int  `yaml:"commands_per_second"`  // Most commands a player may send in one second (0 = unchecked)
+	XPLevelsPerMinute int  `yaml:"xp_levels_per_minute"` // Most levels' worth of XP a player may gain in one minute (0 = unchecked)
+	ReportInterval    int  `yaml:"report_interval"`      // Seconds before a player is flagged again for the same kind of behavior
+	BotCommands       int  `yaml:"bot_commands"`         // Commands in a row compared for clockwork spacing (0 = unchecked)
+	BotJitterMS       int  `yaml:"bot_jitter_ms"`        // Flag a run whose spacing varies by less than this many milliseconds
+	BotChecks         bool `yaml:"bot_checks"`           // Ask players flagged as bots a question they must answer
+	CheckSeconds      int  `yaml:"check_seconds"`        // Seconds a player has to answer a bot check
+}
+
+// PurgeConfig controls the removal of characters that haven't logged in for a long time
+type PurgeConfig struct {
+	InactiveDays int  `yaml:"inactive_days"` // Days without logging in before a character may be purged
+	ProtectLevel int  `yaml:"protect_level"` // Characters at or above this level are never purged (0 = none are kept)
+	Archive      bool `yaml:"archive"`       // Save each purged character to the archive folder first
+	Auto         bool `yaml:"auto"`          // Purge once a day without waiting for staff
+}
+
+// FilterConfig controls the word filter
+type FilterConfig struct {
+	Enabled bool     `yaml:"enabled"` // Whether filtered words are masked and refused
+	Words   []string `yaml:"words"`   // Words that aren't allowed, matched whole and ignoring case
+}
+
+// SurvivalConfig controls hunger and thirst
+type SurvivalConfig struct {
+	Enabled              bool `yaml:"enabled"`                // Whether players grow hungry and thirsty
+	HungerPerTick        int  `yaml:"hunger_per_tick"`        // Food used up each tick
+	ThirstPerTick        int  `yaml:"thirst_per_tick"`        // Water used up each tick
+	StarvingRegenPercent int  `yaml:"starving_regen_percent"` // Percent of normal regeneration while starving or parched
+}
+
+// AlertsConfig holds the default low resource alerts for players who haven't set their own
+type AlertsConfig struct {
+	HPPercent int  `yaml:"hp_percent"` // Alert when HP drops below this percent of max (0 = off)
+	MPPercent int  `yaml:"mp_percent"` // Alert when mana drops below this percent of max (0 = off)
+	Bell      bool `yaml:"bell"`       // Ring the client's bell with each alert
+}
+
+// DeathConfig controls the penalties a player pays for dying
+type DeathConfig struct {
+	XPLossPercent   int            `yaml:"xp_loss_percent"`   // Percent of the XP earned toward the next level that is lost
+	GoldDropPercent int            `yaml:"gold_drop_percent"` // Percent of carried gold left in the corpse
+	DebuffDuration  int            `yaml:"debuff_duration"`   // Ticks of death sickness after respawning (0 = none)
+	DebuffModifiers map[string]int `yaml:"debuff_modifiers"`  // Modifiers applied by death sickness
+}
+
+// config holds the active settings, starting from the defaults
+var config = defaultServerConfig()
+
+// defaultServerConfig returns the built-in settings
+// Each call builds them afresh, so nothing loaded over them can change the defaults.
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Death: DeathConfig{
+			XPLossPercent:   10,
+			GoldDropPercent: 50,
+			DebuffDuration:  5,
+			DebuffModifiers: map[string]int{
+				ApplySTR: -2,
+				ApplyDEX: -2,
+				ApplyCON: -2,
+			},
+		},
+		Alerts: AlertsConfig{
+			HPPercent: 20,
+			MPPercent: 10,
+		},
+		Survival: SurvivalConfig{
+			Enabled:              true,
+			HungerPerTick:        1,
+			ThirstPerTick:        1,
+			StarvingRegenPercent: 50,
+		},
+		Filter: FilterConfig{
+			Enabled: true,
+		},
+		Purge: PurgeConfig{
+			InactiveDays: 365,
+			ProtectLevel: 10,
+			Archive:      true,
+		},
+		Watchdog: WatchdogConfig{
+			Enabled:           true,
+			CommandsPerSecond: 15,
+			XPLevelsPerMinute: 3,
+			ReportInterval:    60,
+			BotCommands:       50,
+			BotJitterMS:       40,
+			BotChecks:         false,
+			CheckSeconds:      120,
+		},
+		Web: WebConfig{
+			PublicURL: "http://localhost:4001",
+		},
+		Listeners: []ListenerConfig{
+			{Address: "0.0.0.0:4000", Protocol: ProtocolTelnet},
+			{Address: "0.0.0.0:4001", Protocol: ProtocolWebSocket},
+		},
+		Flood: FloodConfig{
+			CommandsPerSecond: 10,
+			Burst:             20,
+			DisconnectAfter:   200,
+			MaxInputLength:    512,
+		},
+		Connections: ConnectionsConfig{
+			MaxTotal:     256,
+			MaxPerIP:     8,
+			LoginTimeout: 300,
+		},
+	}
+}
+
+// LoadServerConfig applies any overrides found in config.yml
+func LoadServerConfig() error {
+	data, err := os.ReadFile(ServerConfigFile)
+	if os.IsNotExist(err) {
+		return nil // Defaults are fine
+	}
+	if err != nil {
+		return err
+	}
+
+	loaded := defaultServerConfig()
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("error parsing %s: %w", ServerConfigFile, err)
+	}
+
+	// Unmarshalling adds to a map rather than replacing it, so debuff modifiers
+	// listed in the file are read again on their own to take the defaults' place
+	var modifiers struct {
+		Death struct {
+			DebuffModifiers map[string]int `yaml:"debuff_modifiers"`
+		} `yaml:"death"`
+	}
+	if err := yaml.Unmarshal(data, &modifiers); err != nil {
+		return fmt.Errorf("error parsing %s: %w", ServerConfigFile, err)
+	}
+	if modifiers.Death.DebuffModifiers != nil {
+		loaded.Death.DebuffModifiers = modifiers.Death.DebuffModifiers
+	}
+
+	death := loaded.Death
+	if death.XPLossPercent < 0 || death.XPLossPercent > 100 {
+		return fmt.Errorf("death.xp_loss_percent must be between 0 and 100, got %d", death.XPLossPercent)
+	}
+	if death.GoldDropPercent < 0 || death.GoldDropPercent > 100 {
+		return fmt.Errorf("death.gold_drop_percent must be between 0 and 100, got %d", death.GoldDropPercent)
+	}
+	if death.DebuffDuration < 0 {
+		return fmt.Errorf("death.debuff_duration must not be negative, got %d", death.DebuffDuration)
+	}
+	for key := range death.DebuffModifiers {
+		if !isModifierKey(key) {
+			return fmt.Errorf("death.debuff_modifiers has unknown modifier %q", key)
+		}
+	}
+
+	alerts := loaded.Alerts
+	if alerts.HPPercent < 0 || alerts.HPPercent > 100 {
+		return fmt.Errorf("alerts.hp_percent must be between 0 and 100, got %d", alerts.HPPercent)
+	}
+	if alerts.MPPercent < 0 || alerts.MPPercent > 100 {
+		return fmt.Errorf("alerts.mp_percent must be between 0 and 100, got %d", alerts.MPPercent)
+	}
+
+	survival := loaded.Survival
+	if survival.HungerPerTick < 0 {
+		return fmt.Errorf("survival.hunger_per_tick must not be negative, got %d", survival.HungerPerTick)
+	}
+	if survival.ThirstPerTick < 0 {
+		return fmt.Errorf("survival.thirst_per_tick must not be negative, got %d", survival.ThirstPerTick)
+	}
+	if survival.StarvingRegenPercent < 0 || survival.StarvingRegenPercent > 100 {
+		return fmt.Errorf("survival.starving_regen_percent must be between 0 and 100, got %d", survival.StarvingRegenPercent)
+	}
+
+	for _, word := range loaded.Filter.Words {
+		if strings.TrimSpace(word) == "" {
+			return fmt.Errorf("filter.words must not contain empty words")
+		}
+	}
+
+	if loaded.Purge.InactiveDays < 1 {
+		return fmt.Errorf("purge.inactive_days must be at least 1, got %d", loaded.Purge.InactiveDays)
+	}
+	if loaded.Purge.ProtectLevel < 0 {
+		return fmt.Errorf("purge.protect_level must not be negative, got %d", loaded.Purge.ProtectLevel)
+	}
+
+	watchdog := loaded.Watchdog
+	if watchdog.CommandsPerSecond < 0 {
+		return fmt.Errorf("watchdog.commands_per_second must not be negative, got %d", watchdog.CommandsPerSecond)
+	}
+	if watchdog.XPLevelsPerMinute < 0 {
+		return fmt.Errorf("watchdog.xp_levels_per_minute must not be negative, got %d", watchdog.XPLevelsPerMinute)
+	}
+	if watchdog.ReportInterval < 0 {
+		return fmt.Errorf("watchdog.report_interval must not be negative, got %d", watchdog.ReportInterval)
+	}
+	if watchdog.BotCommands < 0 {
+		return fmt.Errorf("watchdog.bot_commands must not be negative, got %d", watchdog.BotCommands)
+	}
+	if watchdog.BotJitterMS < 0 {
+		return fmt.Errorf("watchdog.bot_jitter_ms must not be negative, got %d", watchdog.BotJitterMS)
+	}
+	if watchdog.CheckSeconds < 1 {
+		return fmt.Errorf("watchdog.check_seconds must be at least 1, got %d", watchdog.CheckSeconds)
+	}
+
+	if len(loaded.Listeners) == 0 {
+		return fmt.Errorf("listeners must list at least one port")
+	}
+	for i, l := range loaded.Listeners {
+		if err := l.validate(i); err != nil {
+			return err
+		}
+	}
+
+	flood := loaded.Flood
+	if flood.CommandsPerSecond < 0 {
+		return fmt.Errorf("flood.commands_per_second must not be negative, got %d", flood.CommandsPerSecond)
+	}
+	if flood.Burst < 0 {
+		return fmt.Errorf("flood.burst must not be negative, got %d", flood.Burst)
+	}
+	if flood.DisconnectAfter < 0 {
+		return fmt.Errorf("flood.disconnect_after must not be negative, got %d", flood.DisconnectAfter)
+	}
+	if flood.MaxInputLength < 1 {
+		return fmt.Errorf("flood.max_input_length must be at least 1, got %d", flood.MaxInputLength)
+	}
+
+	connections := loaded.Connections
+	if connections.MaxTotal < 0 {
+		return fmt.Errorf("connections.max_total must not be negative, got %d", connections.MaxTotal)
+	}
+	if connections.MaxPerIP < 0 {
+		return fmt.Errorf("connections.max_per_ip must not be negative, got %d", connections.MaxPerIP)
+	}
+	if connections.LoginTimeout < 0 {
+		return fmt.Errorf("connections.login_timeout must not be negative, got %d", connections.LoginTimeout)
+	}
+
+	config = loaded
+	log.Printf("Loaded server settings from %s", ServerConfigFile)
+	return nil
+}
@@ -0,0 +1,229 @@
+/*
+ * copyover.go
+ *
+ * This file implements copyover (also called hotboot), which restarts the
+ * server with a freshly built binary without disconnecting anyone. Before
+ * restarting, every player and the items lying in rooms are saved, and each
+ * open socket is written to a state file along with the name of the
+ * character using it. The server then
+ * re-executes itself with the -copyover flag; the new process reads the state
+ * file, reattaches each socket to its character, and drops the players back
+ * into the game loop as if nothing had happened.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+)
+
+// CopyoverStateFile holds the sessions handed from the old process to the new one
+const CopyoverStateFile = "copyover.json"
+
+// copyoverSession describes one connection preserved across a copyover
+type copyoverSession struct {
+	Name      string   `json:"name"`
+	FD        int      `json:"fd"`
+	WebSocket bool     `json:"websocket"`
+	GMCP      bool     `json:"gmcp"`
+	Width     int      `json:"width,omitempty"` // Window size the client reported through NAWS
+	Height    int      `json:"height,omitempty"`
+	Terminals []string `json:"terminals,omitempty"`  // Terminal types the client reported through TTYPE
+	LoginTime int64    `json:"login_time,omitempty"` // Unix time the player's session began
+}
+
+// handleCopyover restarts the server while keeping players connected
+func handleCopyover(player *Player, args []string) string {
+	if err := performCopyover(player.Name); err != nil {
+		log.Printf("Copyover failed: %v", err)
+		return fmt.Sprintf("{R}Copyover failed:{x} %v", err)
+	}
+
+	// Unreachable on success; the process image has been replaced
+	return ""
+}
+
+// performCopyover saves every player, records their sockets, and re-executes the server
+func performCopyover(initiator string) error {
+	playersMutex.Lock()
+	var players []*Player
+	for _, p := range activePlayers {
+		players = append(players, p)
+	}
+	playersMutex.Unlock()
+
+	var sessions []copyoverSession
+	var files []*os.File
+
+	for _, p := range players {
+		// Save progress and any transcript being recorded, exactly as on quit
+		handleSave(p, nil)
+		p.SavePet()
+		if _, err := FinishTranscript(p); err != nil {
+			log.Printf("Error saving transcript for %s: %v", p.Name, err)
+		}
+
+		file, session, err := copyoverFile(p)
+		if err != nil {
+			log.Printf("Copyover can't preserve %s's connection: %v", p.Name, err)
+			p.Send("{R}Your connection can't be preserved across the reboot. Please reconnect in a moment.{x}")
+			continue
+		}
+		files = append(files, file)
+		sessions = append(sessions, session)
+	}
+
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		closeFiles(files)
+		return err
+	}
+	if err := os.WriteFile(CopyoverStateFile, data, 0600); err != nil {
+		closeFiles(files)
+		return err
+	}
+
+	// Keep the items lying in rooms for the new process
+	SaveRoomItems()
+
+	log.Printf("Copyover initiated by %s with %d session(s)", initiator, len(sessions))
+	for _, p := range players {
+		p.Send(fmt.Sprintf("{Y}*** COPYOVER by %s - please remain seated! ***{x}", initiator))
+	}
+
+	// Only returns if the exec itself failed
+	err = execCopyover(files)
+	os.Remove(CopyoverStateFile)
+	if clearErr := ClearWorldItems(); clearErr != nil {
+		log.Printf("Error clearing saved room items: %v", clearErr)
+	}
+	closeFiles(files)
+	for _, p := range players {
+		if err := DeletePlayerPet(p.Name); err != nil {
+			log.Printf("Error clearing %s's stabled pet: %v", p.Name, err)
+		}
+		p.Send("{R}The copyover failed. Carry on.{x}")
+	}
+	return err
+}
+
+// copyoverFile duplicates a player's socket so it survives the exec
+func copyoverFile(p *Player) (*os.File, copyoverSession, error) {
+	session := copyoverSession{Name: p.Name, LoginTime: p.LoginTime.Unix()}
+
+	tc, ok := p.Conn.(*TelnetConn)
+	if !ok {
+		return nil, session, errors.New("not a telnet connection")
+	}
+	session.GMCP = tc.GMCPEnabled()
+	session.Width, session.Height = tc.WindowSize()
+	session.Terminals = tc.TerminalTypes()
+
+	raw := tc.Conn
+	if ws, ok := raw.(*WebSocketConn); ok {
+		raw = ws.Conn
+		session.WebSocket = true
+	}
+
+	filer, ok := raw.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, session, errors.New("connection has no file descriptor")
+	}
+
+	file, err := filer.File()
+	if err != nil {
+		return nil, session, err
+	}
+	session.FD = int(file.Fd())
+	return file, session, nil
+}
+
+// closeFiles closes the duplicated sockets after a failed copyover
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// RestoreCopyover reattaches the sessions left behind by the previous process
+func RestoreCopyover() {
+	data, err := os.ReadFile(CopyoverStateFile)
+	if err != nil {
+		log.Printf("No copyover state to restore: %v", err)
+		return
+	}
+	os.Remove(CopyoverStateFile)
+
+	var sessions []copyoverSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		log.Printf("Error reading copyover state: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		file := os.NewFile(uintptr(session.FD), session.Name)
+		conn, err := net.FileConn(file)
+		file.Close() // FileConn keeps its own duplicate
+		if err != nil {
+			log.Printf("Error restoring connection for %s: %v", session.Name, err)
+			continue
+		}
+
+		if session.WebSocket {
+			conn = &WebSocketConn{Conn: conn, reader: bufio.NewReader(conn)}
+		}
+		go resumeSession(session, conn)
+	}
+
+	fmt.Printf("Copyover complete: restored %d session(s)\n", len(sessions))
+}
+
+// resumeSession puts a preserved connection back into the game loop
+func resumeSession(session copyoverSession, rawConn net.Conn) {
+	defer rawConn.Close()
+
+	tconn := NewTelnetConn(rawConn)
+	if session.GMCP {
+		tconn.restoreOption(true, telnetOptGMCP)
+	}
+	if session.Width > 0 {
+		tconn.restoreOption(false, telnetOptNAWS)
+	}
+	if len(session.Terminals) > 0 {
+		tconn.restoreOption(false, telnetOptTTYPE)
+	}
+	tconn.width, tconn.height = session.Width, session.Height
+	tconn.terminalTypes = session.Terminals
+	reader := bufio.NewReader(tconn)
+
+	player, err := loadExistingPlayer(session.Name, tconn)
+	if err != nil {
+		log.Printf("Error reloading %s after copyover: %v", session.Name, err)
+		tconn.Write([]byte("Error reloading your character after the reboot. Please reconnect.\r\n"))
+		return
+	}
+	if session.LoginTime > 0 {
+		player.LoginTime = time.Unix(session.LoginTime, 0)
+	}
+
+	AddPlayer(player)
+
+	player.Send("{G}Copyover complete. Welcome back!{x}")
+	player.FetchPet()
+	player.Send(DescribeRoom(player.Room, player))
+	player.SendRoomInfo()
+	player.SendStatus()
+	player.SendTime("IRE.Time.Info")
+	player.SendDefences()
+
+	playGame(player, reader)
+
+	endSession(player, tconn)
+}
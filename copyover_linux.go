@@ -0,0 +1,32 @@
+//go:build linux
+
+/*
+ * copyover_linux.go
+ *
+ * This file implements the platform-specific half of copyover: clearing the
+ * close-on-exec flag on the preserved sockets and replacing the running
+ * process with a new copy of the server binary.
+ */
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// execCopyover re-executes the server, keeping the given files open across the exec
+func execCopyover(files []*os.File) error {
+	for _, f := range files {
+		if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_SETFD, 0); errno != 0 {
+			return errno
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(exe, []string{os.Args[0], "-copyover"}, os.Environ())
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+/*
+ * copyover_other.go
+ *
+ * Copyover relies on passing open sockets through exec, which is only
+ * implemented on Linux. Other platforms report that it is unavailable.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// execCopyover is not supported on this platform
+func execCopyover(files []*os.File) error {
+	return errors.New("copyover is only supported on Linux")
+}
@@ -0,0 +1,126 @@
+/*
+ * corpse.go
+ *
+ * This file describes corpses as they decay and lets scavengers eat them.
+ * A corpse starts out fresh and passes through the stages in corpseStages
+ * as its timer runs down, reading differently at each one and telling
+ * anyone in the room, until it finally crumbles to dust. Scavenger mobs
+ * devour the corpses of mobs once they've begun to rot, spilling whatever
+ * the corpse held onto the floor. Players' corpses hold their belongings,
+ * so scavengers leave them alone.
+ */
+
+package main
+
+import "fmt"
+
+// corpseStage is how a corpse looks over part of its lifetime
+type corpseStage struct {
+	Percent     int    // The stage begins once this percent of the corpse's lifetime remains
+	Adjective   string // Added to the corpse's name, as in "the rotting corpse of the wolf"
+	Long        string // Shown in the room; %s is the victim
+	Description string // Shown when a player looks at the corpse; %s is the victim
+	Message     string // Told to the room when the corpse reaches the stage; %s is the victim
+}
+
+// corpseStages are the stages a corpse passes through, from fresh to nearly gone
+var corpseStages = []corpseStage{
+	{
+		Percent:     100,
+		Adjective:   "fresh",
+		Long:        "The fresh corpse of %s is lying here.",
+		Description: "This is the lifeless body of %s.",
+	},
+	{
+		Percent:     50,
+		Adjective:   "rotting",
+		Long:        "The rotting corpse of %s is lying here, buzzing with flies.",
+		Description: "The body of %s is bloated and foul, and flies crawl over it.",
+		Message:     "The corpse of %s begins to rot.",
+	},
+}
+
+// corpseStageAt returns the stage a corpse is in with the given ticks left of its lifetime
+func corpseStageAt(timer, lifetime int) int {
+	stage := 0
+	for i, s := range corpseStages {
+		if timer*100 <= s.Percent*lifetime {
+			stage = i
+		}
+	}
+	return stage
+}
+
+// setCorpseStage describes a corpse as it looks at the given stage of decay
+func (i *Item) setCorpseStage(stage int) {
+	s := corpseStages[stage]
+	i.DecayStage = stage
+	i.ShortDescription = fmt.Sprintf("the %s corpse of %s", s.Adjective, i.Victim)
+	i.LongDescription = fmt.Sprintf(s.Long, i.Victim)
+	i.Description = fmt.Sprintf(s.Description, i.Victim)
+}
+
+// advanceCorpse moves a corpse on to the stage its timer has reached, returning the room message
+// for the new stage, or "" if it hasn't changed. The caller must hold itemMutex.
+func (i *Item) advanceCorpse() string {
+	if i.Victim == "" || i.Lifetime == 0 {
+		return ""
+	}
+	stage := corpseStageAt(i.Timer, i.Lifetime)
+	if stage == i.DecayStage {
+		return ""
+	}
+	i.setCorpseStage(stage)
+	if corpseStages[stage].Message == "" {
+		return ""
+	}
+	return fmt.Sprintf(corpseStages[stage].Message, i.Victim)
+}
+
+// edible reports whether a scavenger will eat the item: the corpse of a mob that has begun to rot
+func (i *Item) edible() bool {
+	return i.Type == "corpse" && i.Owner == "" && i.Victim != "" && i.DecayStage > 0
+}
+
+// ProcessScavengers has each scavenger that isn't fighting eat a rotting corpse in its room
+func ProcessScavengers() {
+	mobMutex.RLock()
+	var scavengers []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.Scavenger && mob.Room != nil {
+			scavengers = append(scavengers, mob)
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range scavengers {
+		if IsMobInCombat(mob) || mob.Fighting != nil {
+			continue
+		}
+		if corpse := eatCorpse(mob.Room); corpse != nil {
+			// Coins in a devoured corpse are gone for good
+			RecordGoldDestroyed(GoldSourceCorpseDecay, corpse.Gold)
+			message := fmt.Sprintf("%s devours %s.", capitalizeFirst(mob.ShortDescription), corpse.ShortDescription)
+			if len(corpse.Contents) > 0 {
+				message += " Its contents spill onto the floor."
+			}
+			BroadcastToRoom(message, mob.Room, nil)
+		}
+	}
+}
+
+// eatCorpse removes the first rotting mob corpse in a room, leaving its contents on the floor
+func eatCorpse(room *Room) *Item {
+	itemMutex.Lock()
+	defer itemMutex.Unlock()
+
+	items := roomItems[room.ID]
+	for _, item := range items {
+		if item.edible() {
+			items = removeItemFromList(items, item)
+			roomItems[room.ID] = append(items, item.Contents...)
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+/*
+ * craft.go
+ *
+ * This file implements gathering and crafting. Rooms may hold resource
+ * nodes, such as ore veins and herb patches, defined in the area files.
+ * Players who have learned the matching gathering skill from their
+ * guildmaster can 'gather' materials from a node until it is exhausted,
+ * after which it replenishes over a number of ticks. Areas also define
+ * recipes, which 'craft' turns into new items by consuming the materials
+ * they call for from the player's inventory.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Gathering skills
+const (
+	SkillMining    = "mining"
+	SkillHerbalism = "herbalism"
+)
+
+// Resource node defaults
+const (
+	DefaultNodeAmount  = 3  // Times a node can be gathered from before it's exhausted
+	DefaultNodeRespawn = 10 // Ticks an exhausted node takes to replenish
+)
+
+// ResourceNode is a source of crafting materials in a room
+type ResourceNode struct {
+	Keywords        []string `yaml:"keywords"`
+	LongDescription string   `yaml:"long_description"`  // Shown in the room while the node can be gathered from
+	Description     string   `yaml:"description"`       // Shown when a player looks at the node
+	Skill           string   `yaml:"skill"`             // Gathering skill needed to use the node
+	Item            int      `yaml:"item"`              // Vnum of the material gathered
+	Amount          int      `yaml:"amount,omitempty"`  // Times it can be gathered before it's exhausted
+	Respawn         int      `yaml:"respawn,omitempty"` // Ticks it takes to replenish once exhausted
+
+	remaining int // Gatherings left before the node is exhausted
+	regrowIn  int // Ticks until an exhausted node replenishes
+}
+
+// Recipe turns materials into a crafted item
+type Recipe struct {
+	Name      string           `yaml:"-"`
+	Area      string           `yaml:"-"` // File the recipe was loaded from
+	Materials []RecipeMaterial `yaml:"materials"`
+	Produces  int              `yaml:"produces"`        // Vnum of the item crafted
+	Level     int              `yaml:"level,omitempty"` // Minimum level to craft it
+}
+
+// RecipeMaterial is an item a recipe consumes
+type RecipeMaterial struct {
+	Item  int `yaml:"item"`
+	Count int `yaml:"count,omitempty"` // Defaults to 1
+}
+
+var (
+	recipeRegistry = make(map[string]*Recipe)
+	recipeMutex    sync.RWMutex // 'loadarea' adds recipes while players are crafting
+	nodeMutex      sync.Mutex
+)
+
+// gatherSkill builds the Use function for a gathering skill
+func gatherSkill(skill string) func(player *Player, target string) (string, bool) {
+	return func(player *Player, target string) (string, bool) {
+		return gatherFrom(player, skill, target)
+	}
+}
+
+// RegisterRecipe adds a recipe to the registry, keyed by its name
+func RegisterRecipe(recipe *Recipe) {
+	key := strings.ToLower(recipe.Name)
+	recipeMutex.Lock()
+	defer recipeMutex.Unlock()
+	if _, exists := recipeRegistry[key]; exists {
+		areaProblem(recipe.Area, "recipes."+recipe.Name, "Recipe %q is defined more than once, keeping the last", recipe.Name)
+	}
+	recipeRegistry[key] = recipe
+}
+
+// lookupRecipe returns the recipe registered under exactly the given name, or nil
+func lookupRecipe(name string) *Recipe {
+	recipeMutex.RLock()
+	defer recipeMutex.RUnlock()
+	return recipeRegistry[strings.ToLower(name)]
+}
+
+// unregisterRecipe removes a recipe from the registry
+func unregisterRecipe(name string) {
+	recipeMutex.Lock()
+	defer recipeMutex.Unlock()
+	delete(recipeRegistry, strings.ToLower(name))
+}
+
+// validateCrafting drops recipes and resource nodes that refer to missing
+// items or skills. It runs once every area has loaded, since they may
+// refer to items from other areas.
+func validateCrafting() {
+	recipeMutex.Lock()
+	for key, recipe := range recipeRegistry {
+		if err := validateRecipe(recipe); err != nil {
+			areaProblem(recipe.Area, "recipes."+recipe.Name, "Recipe %q is invalid, ignoring it: %v", recipe.Name, err)
+			delete(recipeRegistry, key)
+		}
+	}
+	recipeMutex.Unlock()
+
+	for id, room := range allRooms() {
+		validateRoomResources(id, room)
+	}
+}
+
+// validateRoomResources drops a room's resource nodes that refer to missing items
+func validateRoomResources(id int, room *Room) {
+	nodes := room.Resources[:0]
+	for _, node := range room.Resources {
+		if err := validateResourceNode(node); err != nil {
+			areaProblem(room.Area, fmt.Sprintf("rooms.%d.resources", id), "Room %d has an invalid resource node, ignoring it: %v", id, err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	room.Resources = nodes
+}
+
+// validateRecipe checks a recipe's items exist, filling in default counts
+func validateRecipe(recipe *Recipe) error {
+	if GetItemTemplate(recipe.Produces) == nil {
+		return fmt.Errorf("produces unknown item %d", recipe.Produces)
+	}
+	if len(recipe.Materials) == 0 {
+		return fmt.Errorf("no materials")
+	}
+	for i := range recipe.Materials {
+		material := &recipe.Materials[i]
+		if GetItemTemplate(material.Item) == nil {
+			return fmt.Errorf("needs unknown item %d", material.Item)
+		}
+		if material.Count <= 0 {
+			material.Count = 1
+		}
+	}
+	return nil
+}
+
+// validateResourceNode checks a node's skill and item, filling in defaults and stocking it
+func validateResourceNode(node *ResourceNode) error {
+	if len(node.Keywords) == 0 {
+		return fmt.Errorf("no keywords")
+	}
+	skill := FindSkill(node.Skill)
+	if skill == nil || skill.Name != node.Skill {
+		return fmt.Errorf("unknown skill %q", node.Skill)
+	}
+	if GetItemTemplate(node.Item) == nil {
+		return fmt.Errorf("yields unknown item %d", node.Item)
+	}
+	if node.Amount <= 0 {
+		node.Amount = DefaultNodeAmount
+	}
+	if node.Respawn <= 0 {
+		node.Respawn = DefaultNodeRespawn
+	}
+	node.LongDescription = strings.TrimSpace(node.LongDescription)
+	node.Description = strings.TrimSpace(node.Description)
+	node.remaining = node.Amount
+	return nil
+}
+
+// matches reports whether the node answers to the given name
+func (n *ResourceNode) matches(name string) bool {
+	for _, keyword := range n.Keywords {
+		if strings.HasPrefix(strings.ToLower(keyword), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Available reports whether the node has anything left to gather
+func (n *ResourceNode) Available() bool {
+	nodeMutex.Lock()
+	defer nodeMutex.Unlock()
+	return n.remaining > 0
+}
+
+// findResourceNode returns the node in the room that answers to name, or nil
+func findResourceNode(room *Room, name string) *ResourceNode {
+	name = strings.ToLower(name)
+	for _, node := range room.Resources {
+		if node.matches(name) {
+			return node
+		}
+	}
+	return nil
+}
+
+// describeResourceNodes returns the room lines of nodes that can be gathered from
+func describeResourceNodes(room *Room) string {
+	var sb strings.Builder
+	for _, node := range room.Resources {
+		if node.Available() && node.LongDescription != "" {
+			sb.WriteString(node.LongDescription + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// lookAtResourceNode describes a node the player looks at
+func lookAtResourceNode(node *ResourceNode) string {
+	description := node.Description
+	if description == "" {
+		description = node.LongDescription
+	}
+	if !node.Available() {
+		description += "\nIt has been picked clean for now."
+	}
+	return description
+}
+
+// ProcessResourceNodes replenishes exhausted resource nodes each tick
+func ProcessResourceNodes() {
+	nodeMutex.Lock()
+	defer nodeMutex.Unlock()
+
+	for _, room := range allRooms() {
+		for _, node := range room.Resources {
+			if node.remaining > 0 {
+				continue
+			}
+			node.regrowIn--
+			if node.regrowIn <= 0 {
+				node.remaining = node.Amount
+			}
+		}
+	}
+}
+
+// gatherFrom gathers materials from a resource node in the player's room
+// It reports whether the attempt was made, so a bad target costs no stamina.
+func gatherFrom(player *Player, skill, target string) (string, bool) {
+	if target == "" {
+		return "Gather from what?", false
+	}
+	node := findResourceNode(player.Room, target)
+	if node == nil {
+		return "You don't see that here.", false
+	}
+	if node.Skill != skill {
+		return fmt.Sprintf("You need %s to gather from that.", node.Skill), false
+	}
+	if player.IsInCombat() {
+		return "You're too busy fighting!", false
+	}
+
+	template := GetItemTemplate(node.Item)
+	material, err := CreateItem(node.Item)
+	if err != nil {
+		log.Printf("Error creating gathered item %d: %v", node.Item, err)
+		return "You find nothing useful.", false
+	}
+	if !player.CanLift(material) {
+		return fmt.Sprintf("You can't carry %s as well.", template.ShortDescription), false
+	}
+
+	nodeMutex.Lock()
+	if node.remaining <= 0 {
+		nodeMutex.Unlock()
+		return "There's nothing left to gather there for now.", false
+	}
+	node.remaining--
+	if node.remaining == 0 {
+		node.regrowIn = node.Respawn
+	}
+	nodeMutex.Unlock()
+
+	player.Inventory = append(player.Inventory, material)
+	BroadcastToRoom(fmt.Sprintf("$n gathers %s.", material.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You gather %s.", material.Name()), true
+}
+
+// handleGather gathers from a resource node using whichever gathering skill it needs
+// Usage: gather <node>
+func handleGather(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Gather from what?"
+	}
+	node := findResourceNode(player.Room, strings.Join(args, " "))
+	if node == nil {
+		return "You don't see that here."
+	}
+	if !player.KnowsSkill(node.Skill) {
+		return fmt.Sprintf("You need to learn %s from your guildmaster to gather from that.", node.Skill)
+	}
+	return handleCast(player, append([]string{node.Skill}, args...))
+}
+
+// FindRecipe returns the recipe with the given name or name prefix, or nil
+func FindRecipe(name string) *Recipe {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if recipe := lookupRecipe(name); recipe != nil {
+		return recipe
+	}
+	for _, recipe := range sortedRecipes() {
+		if strings.HasPrefix(strings.ToLower(recipe.Name), name) {
+			return recipe
+		}
+	}
+	return nil
+}
+
+// sortedRecipes returns every recipe, by level and then name
+func sortedRecipes() []*Recipe {
+	var recipes []*Recipe
+	recipeMutex.RLock()
+	for _, recipe := range recipeRegistry {
+		recipes = append(recipes, recipe)
+	}
+	recipeMutex.RUnlock()
+	sort.Slice(recipes, func(i, j int) bool {
+		if recipes[i].Level != recipes[j].Level {
+			return recipes[i].Level < recipes[j].Level
+		}
+		return recipes[i].Name < recipes[j].Name
+	})
+	return recipes
+}
+
+// describeMaterials lists what a recipe consumes
+func (r *Recipe) describeMaterials() string {
+	var parts []string
+	for _, material := range r.Materials {
+		parts = append(parts, fmt.Sprintf("%d x %s", material.Count, GetItemTemplate(material.Item).ShortDescription))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countCarried returns how many items with the vnum the player is carrying
+func (p *Player) countCarried(vnum int) int {
+	count := 0
+	for _, item := range p.Inventory {
+		if item.ID == vnum {
+			count++
+		}
+	}
+	return count
+}
+
+// consumeCarried removes count items with the vnum from the player's inventory
+func (p *Player) consumeCarried(vnum, count int) {
+	kept := p.Inventory[:0]
+	for _, item := range p.Inventory {
+		if item.ID == vnum && count > 0 {
+			count--
+			continue
+		}
+		kept = append(kept, item)
+	}
+	p.Inventory = kept
+}
+
+// handleCraft lists the recipes, or crafts one from the materials the player carries
+// Usage: craft [recipe]
+func handleCraft(player *Player, args []string) string {
+	if len(args) == 0 {
+		recipes := sortedRecipes()
+		if len(recipes) == 0 {
+			return "There is nothing you can craft."
+		}
+		var sb strings.Builder
+		sb.WriteString("Recipes:\r\n")
+		for _, recipe := range recipes {
+			sb.WriteString(fmt.Sprintf("  [Lv %2d] %-20s %s\r\n", max(recipe.Level, 1), recipe.Name, recipe.describeMaterials()))
+		}
+		sb.WriteString("Use 'craft <recipe>' to make one.")
+		return sb.String()
+	}
+
+	recipe := FindRecipe(strings.Join(args, " "))
+	if recipe == nil {
+		return "You don't know how to make that."
+	}
+	if player.Level < recipe.Level {
+		return fmt.Sprintf("You must be level %d to craft %s.", recipe.Level, recipe.Name)
+	}
+	for _, material := range recipe.Materials {
+		if player.countCarried(material.Item) < material.Count {
+			return fmt.Sprintf("You need %s to craft %s.", recipe.describeMaterials(), recipe.Name)
+		}
+	}
+
+	product, err := CreateItem(recipe.Produces)
+	if err != nil {
+		log.Printf("Error creating crafted item %d: %v", recipe.Produces, err)
+		return "Something goes wrong, and you stop before wasting your materials."
+	}
+	for _, material := range recipe.Materials {
+		player.consumeCarried(material.Item, material.Count)
+	}
+	player.Inventory = append(player.Inventory, product)
+	if message := product.BindTo(player, BindOnPickup); message != "" {
+		player.Send(message)
+	}
+
+	BroadcastToRoom(fmt.Sprintf("$n crafts %s.", product.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You craft %s.", product.Name())
+}
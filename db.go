@@ -12,8 +12,10 @@
 package main
 
 import (
-	"database/sql" // Import the database/sql package to enable SQL database operations
-	"log"          // Import log package for logging error messages
+	"database/sql"  // Import the database/sql package to enable SQL database operations
+	"encoding/json" // Import encoding/json to store affects as JSON
+	"log"           // Import log package for logging error messages
+	"time"          // Import time package for timestamps
 
 	_ "modernc.org/sqlite" // Import the SQLite driver for database connections
 )
@@ -94,6 +96,432 @@ func InitDB() {
 	addColumnIfNotExists("max_stamina", "INTEGER")
 	addColumnIfNotExists("gold", "INTEGER")
 	addColumnIfNotExists("color_enabled", "INTEGER NOT NULL DEFAULT 1") // 1 = true, 0 = false
+	addColumnIfNotExists("squelch", "INTEGER NOT NULL DEFAULT 1")       // 1 = collapse repeated lines
+	addColumnIfNotExists("roleplay", "INTEGER NOT NULL DEFAULT 0")      // 1 = flagged for roleplay
+	addColumnIfNotExists("staff", "INTEGER NOT NULL DEFAULT 0")         // 1 = may read and write staff notes
+	addColumnIfNotExists("bank_balance", "INTEGER NOT NULL DEFAULT 0")  // Gold deposited with a banker
+	addColumnIfNotExists("alert_hp", "INTEGER")                         // NULL = server default
+	addColumnIfNotExists("alert_mp", "INTEGER")                         // NULL = server default
+	addColumnIfNotExists("alert_bell", "INTEGER")                       // NULL = server default
+	addColumnIfNotExists("auto_loot", "INTEGER NOT NULL DEFAULT 0")     // 1 = loot corpses of kills
+	addColumnIfNotExists("auto_gold", "INTEGER NOT NULL DEFAULT 0")     // 1 = take gold from corpses of kills
+	addColumnIfNotExists("auto_sac", "INTEGER NOT NULL DEFAULT 0")      // 1 = sacrifice empty corpses
+	addColumnIfNotExists("brief", "INTEGER NOT NULL DEFAULT 0")         // 1 = leave out room descriptions when moving
+	addColumnIfNotExists("screen_reader", "INTEGER NOT NULL DEFAULT 0") // 1 = plain text rooms for screen readers
+	addColumnIfNotExists("minimap", "INTEGER NOT NULL DEFAULT 0")       // 1 = draw a map above each room
+	addColumnIfNotExists("prompt", "TEXT NOT NULL DEFAULT ''")          // Custom prompt format ('' = standard)
+	addColumnIfNotExists("page_length", "INTEGER NOT NULL DEFAULT 0")   // Lines per page of output (0 = window height, -1 = off)
+	addColumnIfNotExists("width", "INTEGER NOT NULL DEFAULT 0")         // Columns output is wrapped to (0 = window width, -1 = off)
+	addColumnIfNotExists("kills", "INTEGER NOT NULL DEFAULT 0")         // Mobs slain, for achievements
+	addColumnIfNotExists("speaking", "TEXT NOT NULL DEFAULT ''")        // Language the player talks in ('' = common)
+	addColumnIfNotExists("food", "INTEGER")                             // NULL = full
+	addColumnIfNotExists("water", "INTEGER")                            // NULL = full
+	addColumnIfNotExists("playtime", "INTEGER NOT NULL DEFAULT 0")      // Minutes played
+	addColumnIfNotExists("last_login", "INTEGER")                       // Unix time of the last login
+	addColumnIfNotExists("last_logout", "INTEGER")                      // Unix time of the last logout
+	addColumnIfNotExists("trust", "INTEGER NOT NULL DEFAULT 0")         // Staff trust level (0 = mortal)
+
+	// How the player likes numbers and times written (see locale.go)
+	addColumnIfNotExists("locale_numbers", "TEXT NOT NULL DEFAULT 'comma'")
+	addColumnIfNotExists("locale_clock", "INTEGER NOT NULL DEFAULT 24")
+	addColumnIfNotExists("locale_dates", "TEXT NOT NULL DEFAULT 'ymd'")
+
+	// Characters from before logins were recorded count as seen when recording began
+	if _, err := db.Exec("UPDATE players SET last_login = ? WHERE last_login IS NULL", time.Now().Unix()); err != nil {
+		log.Fatal("Failed to set missing login times:", err)
+	}
+
+	// Staff from before trust levels keep every privilege they had
+	if _, err := db.Exec("UPDATE players SET trust = ? WHERE staff = 1 AND trust = 0", TrustImplementor); err != nil {
+		log.Fatal("Failed to set trust for existing staff:", err)
+	}
+
+	// Create the player_items table to store carried items by vnum
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_name TEXT NOT NULL,
+		item_vnum INTEGER NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_items table:", err)
+	}
+
+	// Add columns to player_items if they don't exist
+	addItemColumnIfNotExists := func(columnName, columnDef string) {
+		var columnExists bool
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('player_items') WHERE name=?", columnName).Scan(&columnExists)
+		if err != nil {
+			log.Fatal("Failed to check if column exists:", columnName, err)
+		}
+		if !columnExists {
+			_, err := db.Exec("ALTER TABLE player_items ADD COLUMN " + columnName + " " + columnDef)
+			if err != nil {
+				log.Fatal("Failed to add column:", columnName, err)
+			}
+			log.Printf("Added column: %s", columnName)
+		}
+	}
+
+	addItemColumnIfNotExists("worn", "TEXT NOT NULL DEFAULT ''")        // Wear slot of a worn item ('' = carried)
+	addItemColumnIfNotExists("bound", "INTEGER NOT NULL DEFAULT 0")     // 1 = soulbound to the player
+	addItemColumnIfNotExists("container", "INTEGER NOT NULL DEFAULT 0") // ID of the row for the container holding it (0 = none)
+	addItemColumnIfNotExists("sips", "INTEGER")                         // Sips left in a drink container (NULL = full)
+
+	// Create the transcripts table to store recorded player sessions
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS transcripts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_name TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		ended_at DATETIME NOT NULL,
+		content TEXT NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create transcripts table:", err)
+	}
+
+	// Create the player_notes table for notes players keep about each other
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		author TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		note TEXT NOT NULL,
+		staff INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_notes table:", err)
+	}
+
+	// Create the server_state table for small pieces of persistent world state
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS server_state (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create server_state table:", err)
+	}
+
+	// Create the lottery_tickets table for tickets bought toward the next drawing
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS lottery_tickets (
+		player_name TEXT PRIMARY KEY,
+		tickets INTEGER NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create lottery_tickets table:", err)
+	}
+
+	// Create the economy_ledger table tracking gold created and destroyed by source
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS economy_ledger (
+		source TEXT PRIMARY KEY,
+		created INTEGER NOT NULL DEFAULT 0,
+		destroyed INTEGER NOT NULL DEFAULT 0,
+		events INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create economy_ledger table:", err)
+	}
+
+	// Create the player_quests table to record the quests each player has completed
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_quests (
+		player_name TEXT NOT NULL,
+		quest TEXT NOT NULL,
+		completed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (player_name, quest)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_quests table:", err)
+	}
+
+	// Create the player_achievements table to record the milestones each player has reached
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_achievements (
+		player_name TEXT NOT NULL,
+		achievement TEXT NOT NULL,
+		earned_at DATETIME NOT NULL,
+		PRIMARY KEY (player_name, achievement)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_achievements table:", err)
+	}
+
+	// Create the player_rooms table to record the rooms each player has explored
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_rooms (
+		player_name TEXT NOT NULL,
+		room_id INTEGER NOT NULL,
+		PRIMARY KEY (player_name, room_id)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_rooms table:", err)
+	}
+
+	// Create the player_languages table to record the languages each player has learned
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_languages (
+		player_name TEXT NOT NULL,
+		language TEXT NOT NULL,
+		PRIMARY KEY (player_name, language)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_languages table:", err)
+	}
+
+	// Create the player_pets table to stable each player's pet while they're away
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_pets (
+		player_name TEXT PRIMARY KEY,
+		mob_vnum INTEGER NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		hp INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_pets table:", err)
+	}
+
+	// Create the clans table for player clans and their treasuries
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS clans (
+		name TEXT PRIMARY KEY,
+		hall INTEGER NOT NULL DEFAULT 0,
+		treasury INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create clans table:", err)
+	}
+
+	// Create the clan_members table recording each player's clan and rank
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS clan_members (
+		player_name TEXT PRIMARY KEY,
+		clan TEXT NOT NULL,
+		rank INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create clan_members table:", err)
+	}
+
+	// Create the world_items table to keep items lying in rooms across a reboot
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS world_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		room_id INTEGER NOT NULL,
+		item_vnum INTEGER NOT NULL,
+		container INTEGER NOT NULL DEFAULT 0,
+		bound_to TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL DEFAULT '',
+		gold INTEGER NOT NULL DEFAULT 0,
+		timer INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create world_items table:", err)
+	}
+
+	// Create the houses table recording who owns each house room
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS houses (
+		room_id INTEGER PRIMARY KEY,
+		owner TEXT NOT NULL UNIQUE,
+		bought_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create houses table:", err)
+	}
+
+	// Create the house_guests table listing the players let into each house
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS house_guests (
+		room_id INTEGER NOT NULL,
+		guest TEXT NOT NULL,
+		PRIMARY KEY (room_id, guest)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create house_guests table:", err)
+	}
+
+	// Create the house_items table holding the items stored in each house
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS house_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		room_id INTEGER NOT NULL,
+		item_vnum INTEGER NOT NULL,
+		bound INTEGER NOT NULL DEFAULT 0,
+		container INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create house_items table:", err)
+	}
+
+	// Create the player_skills table to record the skills each player has learned
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_skills (
+		player_name TEXT NOT NULL,
+		skill TEXT NOT NULL,
+		PRIMARY KEY (player_name, skill)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_skills table:", err)
+	}
+
+	// Create the player_affects table so buffs and debuffs survive logging out
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_affects (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_name TEXT NOT NULL,
+		affect TEXT NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_affects table:", err)
+	}
+
+	// Create the watchdog_flags table to keep suspicious actions for staff review
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS watchdog_flags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create watchdog_flags table:", err)
+	}
+
+	// Create the bans table to keep banned characters and sites out
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS bans (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		target TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		banned_by TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create bans table:", err)
+	}
+
+	// Create the snoops table to keep an audit trail of staff snooping players
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS snoops (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		snooper TEXT NOT NULL,
+		target TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		ended_at INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create snoops table:", err)
+	}
+
+	// Create the audit_log table to record staff use of privileged commands
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		command TEXT NOT NULL,
+		args TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create audit_log table:", err)
+	}
+
+	// Create the character_sheets table to hold character sheets players have shared
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS character_sheets (
+		token TEXT PRIMARY KEY,
+		player_name TEXT NOT NULL,
+		format TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE(player_name, format)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create character_sheets table:", err)
+	}
+
+	// Create the player_channels table to record the channels each player has turned off
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_channels (
+		player_name TEXT NOT NULL,
+		channel TEXT NOT NULL,
+		PRIMARY KEY (player_name, channel)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_channels table:", err)
+	}
+
+	// Create the player_ignores table to record who each player is ignoring
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_ignores (
+		player_name TEXT NOT NULL,
+		ignored TEXT NOT NULL,
+		PRIMARY KEY (player_name, ignored)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_ignores table:", err)
+	}
+
+	// Create the player_friends table to record each player's friends list
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_friends (
+		player_name TEXT NOT NULL,
+		friend TEXT NOT NULL,
+		PRIMARY KEY (player_name, friend)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_friends table:", err)
+	}
+
+	// Create the player_aliases table to record each player's command aliases
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS player_aliases (
+		player_name TEXT NOT NULL,
+		alias TEXT NOT NULL,
+		command TEXT NOT NULL,
+		PRIMARY KEY (player_name, alias)
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create player_aliases table:", err)
+	}
 }
 
 // CreatePlayer adds a new player to the database with their stats
@@ -241,6 +669,127 @@ func UpdatePlayerColorPreference(name string, colorEnabled bool) error {
 	return err
 }
 
+// UpdatePlayerSquelch updates a player's spam squelch preference in the database
+func UpdatePlayerSquelch(name string, squelch bool) error {
+	_, err := db.Exec("UPDATE players SET squelch = ? WHERE name = ?", squelch, name)
+	return err
+}
+
+// LoadPlayerSquelch retrieves a player's spam squelch preference
+func LoadPlayerSquelch(name string) (bool, error) {
+	var squelch bool
+	err := db.QueryRow("SELECT COALESCE(squelch, 1) FROM players WHERE name = ?", name).Scan(&squelch)
+	return squelch, err
+}
+
+// UpdatePlayerRoleplay updates a player's roleplay flag in the database
+func UpdatePlayerRoleplay(name string, roleplay bool) error {
+	_, err := db.Exec("UPDATE players SET roleplay = ? WHERE name = ?", roleplay, name)
+	return err
+}
+
+// LoadPlayerRoleplay retrieves a player's roleplay flag
+func LoadPlayerRoleplay(name string) (bool, error) {
+	var roleplay bool
+	err := db.QueryRow("SELECT COALESCE(roleplay, 0) FROM players WHERE name = ?", name).Scan(&roleplay)
+	return roleplay, err
+}
+
+// UpdatePlayerAlerts saves a player's low resource alert settings
+func UpdatePlayerAlerts(name string, hp, mp int, bell bool) error {
+	_, err := db.Exec("UPDATE players SET alert_hp = ?, alert_mp = ?, alert_bell = ? WHERE name = ?", hp, mp, bell, name)
+	return err
+}
+
+// LoadPlayerAlerts retrieves a player's alert settings, using the defaults for any never set
+func LoadPlayerAlerts(name string, defaultHP, defaultMP int, defaultBell bool) (int, int, bool, error) {
+	var hp, mp int
+	var bell bool
+	err := db.QueryRow("SELECT COALESCE(alert_hp, ?), COALESCE(alert_mp, ?), COALESCE(alert_bell, ?) FROM players WHERE name = ?",
+		defaultHP, defaultMP, defaultBell, name).Scan(&hp, &mp, &bell)
+	return hp, mp, bell, err
+}
+
+// UpdatePlayerAutoToggles saves a player's automatic looting and sacrifice settings
+func UpdatePlayerAutoToggles(name string, loot, gold, sac bool) error {
+	_, err := db.Exec("UPDATE players SET auto_loot = ?, auto_gold = ?, auto_sac = ? WHERE name = ?", loot, gold, sac, name)
+	return err
+}
+
+// LoadPlayerAutoToggles retrieves a player's automatic looting and sacrifice settings
+func LoadPlayerAutoToggles(name string) (loot, gold, sac bool, err error) {
+	err = db.QueryRow("SELECT COALESCE(auto_loot, 0), COALESCE(auto_gold, 0), COALESCE(auto_sac, 0) FROM players WHERE name = ?",
+		name).Scan(&loot, &gold, &sac)
+	return loot, gold, sac, err
+}
+
+// UpdatePlayerDisplay saves how a player has chosen to see rooms
+func UpdatePlayerDisplay(name string, brief, screenReader, minimap bool) error {
+	_, err := db.Exec("UPDATE players SET brief = ?, screen_reader = ?, minimap = ? WHERE name = ?", brief, screenReader, minimap, name)
+	return err
+}
+
+// LoadPlayerDisplay retrieves how a player has chosen to see rooms
+func LoadPlayerDisplay(name string) (brief, screenReader, minimap bool, err error) {
+	err = db.QueryRow("SELECT COALESCE(brief, 0), COALESCE(screen_reader, 0), COALESCE(minimap, 0) FROM players WHERE name = ?",
+		name).Scan(&brief, &screenReader, &minimap)
+	return brief, screenReader, minimap, err
+}
+
+// UpdatePlayerPrompt saves a player's custom prompt format
+func UpdatePlayerPrompt(name, prompt string) error {
+	_, err := db.Exec("UPDATE players SET prompt = ? WHERE name = ?", prompt, name)
+	return err
+}
+
+// LoadPlayerPrompt retrieves a player's custom prompt format
+func LoadPlayerPrompt(name string) (string, error) {
+	var prompt string
+	err := db.QueryRow("SELECT COALESCE(prompt, '') FROM players WHERE name = ?", name).Scan(&prompt)
+	return prompt, err
+}
+
+// UpdatePlayerPageLength saves how many lines of output a player sees per page
+func UpdatePlayerPageLength(name string, length int) error {
+	_, err := db.Exec("UPDATE players SET page_length = ? WHERE name = ?", length, name)
+	return err
+}
+
+// LoadPlayerPageLength retrieves how many lines of output a player sees per page
+func LoadPlayerPageLength(name string) (int, error) {
+	var length int
+	err := db.QueryRow("SELECT COALESCE(page_length, 0) FROM players WHERE name = ?", name).Scan(&length)
+	return length, err
+}
+
+// UpdatePlayerWidth saves how many columns a player's output is wrapped to
+func UpdatePlayerWidth(name string, width int) error {
+	_, err := db.Exec("UPDATE players SET width = ? WHERE name = ?", width, name)
+	return err
+}
+
+// LoadPlayerWidth retrieves how many columns a player's output is wrapped to
+func LoadPlayerWidth(name string) (int, error) {
+	var width int
+	err := db.QueryRow("SELECT COALESCE(width, 0) FROM players WHERE name = ?", name).Scan(&width)
+	return width, err
+}
+
+// UpdatePlayerLocale saves how a player likes numbers and times written
+func UpdatePlayerLocale(name string, locale Locale) error {
+	_, err := db.Exec("UPDATE players SET locale_numbers = ?, locale_clock = ?, locale_dates = ? WHERE name = ?",
+		locale.Numbers, locale.Clock, locale.Dates, name)
+	return err
+}
+
+// LoadPlayerLocale retrieves how a player likes numbers and times written
+func LoadPlayerLocale(name string) (Locale, error) {
+	var locale Locale
+	err := db.QueryRow("SELECT COALESCE(locale_numbers, 'comma'), COALESCE(locale_clock, 24), COALESCE(locale_dates, 'ymd') FROM players WHERE name = ?",
+		name).Scan(&locale.Numbers, &locale.Clock, &locale.Dates)
+	return locale, err
+}
+
 // UpdatePlayerTitle updates the player's title in the database
 func UpdatePlayerTitle(name string, title string) error {
 	_, err := db.Exec("UPDATE players SET title = ? WHERE name = ?", title, name)
@@ -259,3 +808,1320 @@ func UpdatePlayerXP(playerName string, xp int, nextLevelXP int) error {
 
 	return nil
 }
+
+// UpdatePlayerGold updates the amount of gold a player is carrying
+func UpdatePlayerGold(name string, gold int) error {
+	_, err := db.Exec("UPDATE players SET gold = ? WHERE name = ?", gold, name)
+	return err
+}
+
+// UpdatePlayerBank saves a player's gold on hand and bank balance together
+func UpdatePlayerBank(name string, gold, balance int) error {
+	_, err := db.Exec("UPDATE players SET gold = ?, bank_balance = ? WHERE name = ?", gold, balance, name)
+	return err
+}
+
+// LoadPlayerBank retrieves a player's bank balance
+func LoadPlayerBank(name string) (int, error) {
+	var balance int
+	err := db.QueryRow("SELECT COALESCE(bank_balance, 0) FROM players WHERE name = ?", name).Scan(&balance)
+	return balance, err
+}
+
+// AddPlayerGold adds gold to a player who may not be online
+func AddPlayerGold(name string, amount int) error {
+	_, err := db.Exec("UPDATE players SET gold = COALESCE(gold, 0) + ? WHERE name = ?", amount, name)
+	return err
+}
+
+// SavedItem is an item stored with a player's character
+type SavedItem struct {
+	ID        int
+	Vnum      int
+	Worn      string // Wear slot, or "" if the item is carried
+	Bound     bool   // Whether the item is soulbound to the player
+	Container int    // ID of the saved container holding this item, or 0
+	Sips      int    // Sips left in a drink container, or -1 if it's full or not one
+}
+
+// SavePlayerInventory replaces the stored inventory and equipment of a player with the given items
+func SavePlayerInventory(name string, items []*Item, equipment map[string]*Item) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM player_items WHERE player_name = ?", name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Containers are saved before their contents, which refer back to them
+	var insert func(item *Item, slot string, container int64) error
+	insert = func(item *Item, slot string, container int64) error {
+		// Generated items such as corpses have no template and are not saved
+		if item.ID == 0 {
+			return nil
+		}
+		var sips any
+		if item.Type == "drink" && item.SipsLeft < item.Sips {
+			sips = item.SipsLeft
+		}
+		result, err := tx.Exec("INSERT INTO player_items (player_name, item_vnum, worn, bound, container, sips) VALUES (?, ?, ?, ?, ?, ?)",
+			name, item.ID, slot, item.BoundTo != "", container, sips)
+		if err != nil {
+			return err
+		}
+		if len(item.Contents) == 0 {
+			return nil
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, content := range item.Contents {
+			if err := insert(content, "", id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		if err := insert(item, "", 0); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for slot, item := range equipment {
+		if err := insert(item, slot, 0); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadPlayerItems returns the items a player was carrying and wearing
+func LoadPlayerItems(name string) ([]SavedItem, error) {
+	rows, err := db.Query("SELECT id, item_vnum, worn, bound, container, COALESCE(sips, -1) FROM player_items WHERE player_name = ? ORDER BY id", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SavedItem
+	for rows.Next() {
+		var item SavedItem
+		if err := rows.Scan(&item.ID, &item.Vnum, &item.Worn, &item.Bound, &item.Container, &item.Sips); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SaveTranscript stores a recorded session and returns its ID
+func SaveTranscript(name string, startedAt, endedAt time.Time, content string) (int, error) {
+	result, err := db.Exec(`
+		INSERT INTO transcripts (player_name, started_at, ended_at, content)
+		VALUES (?, ?, ?, ?)`,
+		name, startedAt, endedAt, content)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// ListTranscripts returns the transcripts a player has saved, newest first
+func ListTranscripts(name string) ([]TranscriptInfo, error) {
+	rows, err := db.Query(`
+		SELECT id, started_at, ended_at, LENGTH(content)
+		FROM transcripts WHERE player_name = ? ORDER BY id DESC`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transcripts []TranscriptInfo
+	for rows.Next() {
+		var t TranscriptInfo
+		if err := rows.Scan(&t.ID, &t.StartedAt, &t.EndedAt, &t.Size); err != nil {
+			return nil, err
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, rows.Err()
+}
+
+// LoadTranscript returns the content of one of a player's transcripts
+func LoadTranscript(name string, id int) (string, error) {
+	var content string
+	err := db.QueryRow("SELECT content FROM transcripts WHERE id = ? AND player_name = ?", id, name).Scan(&content)
+	return content, err
+}
+
+// LoadPlayerTrust returns a player's trust level
+func LoadPlayerTrust(name string) (int, error) {
+	var trust int
+	err := db.QueryRow("SELECT COALESCE(trust, 0) FROM players WHERE name = ?", name).Scan(&trust)
+	return trust, err
+}
+
+// SetPlayerTrust saves a player's trust level, keeping the staff flag in step with it
+func SetPlayerTrust(name string, trust int) error {
+	_, err := db.Exec("UPDATE players SET trust = ?, staff = ? WHERE name = ?", trust, trust > TrustMortal, name)
+	return err
+}
+
+// AddPlayerNote stores a note written by author about subject
+func AddPlayerNote(author, subject, note string, staff bool) (int, error) {
+	result, err := db.Exec(`
+		INSERT INTO player_notes (author, subject, note, staff, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		author, subject, note, staff, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// LoadPlayerNotes returns the notes about subject that viewer may read:
+// their own private notes, plus staff notes when includeStaff is set
+func LoadPlayerNotes(viewer, subject string, includeStaff bool) ([]PlayerNote, error) {
+	rows, err := db.Query(`
+		SELECT id, author, subject, note, staff, created_at
+		FROM player_notes
+		WHERE subject = ? AND ((staff = 0 AND author = ?) OR (staff = 1 AND ?))
+		ORDER BY id`, subject, viewer, includeStaff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []PlayerNote
+	for rows.Next() {
+		var n PlayerNote
+		if err := rows.Scan(&n.ID, &n.Author, &n.Subject, &n.Note, &n.Staff, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// DeletePlayerNote removes a note, returning false if viewer may not delete it
+func DeletePlayerNote(viewer string, id int, isStaff bool) (bool, error) {
+	result, err := db.Exec(`
+		DELETE FROM player_notes
+		WHERE id = ? AND ((staff = 0 AND author = ?) OR (staff = 1 AND ?))`,
+		id, viewer, isStaff)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetServerStateInt returns an integer server state value, or 0 if it has never been set
+func GetServerStateInt(key string) (int, error) {
+	var value int
+	err := db.QueryRow("SELECT CAST(value AS INTEGER) FROM server_state WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return value, err
+}
+
+// SetServerStateInt stores an integer server state value
+func SetServerStateInt(key string, value int) error {
+	_, err := db.Exec("INSERT OR REPLACE INTO server_state (key, value) VALUES (?, ?)", key, value)
+	return err
+}
+
+// AddLotteryTickets records tickets bought by a player for the next drawing
+func AddLotteryTickets(name string, count int) error {
+	_, err := db.Exec(`
+		INSERT INTO lottery_tickets (player_name, tickets) VALUES (?, ?)
+		ON CONFLICT(player_name) DO UPDATE SET tickets = tickets + excluded.tickets`,
+		name, count)
+	return err
+}
+
+// LoadLotteryTickets returns the number of tickets each player holds
+func LoadLotteryTickets() (map[string]int, error) {
+	rows, err := db.Query("SELECT player_name, tickets FROM lottery_tickets")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tickets := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		tickets[name] = count
+	}
+	return tickets, rows.Err()
+}
+
+// ClearLotteryTickets removes every ticket after a drawing
+func ClearLotteryTickets() error {
+	_, err := db.Exec("DELETE FROM lottery_tickets")
+	return err
+}
+
+// LedgerEntry is the running gold total for one source in the economy ledger
+type LedgerEntry struct {
+	Source    string
+	Created   int
+	Destroyed int
+	Events    int
+}
+
+// RecordLedgerEntry adds gold created and destroyed by a source to the economy ledger
+func RecordLedgerEntry(source string, created, destroyed int) error {
+	_, err := db.Exec(`
+		INSERT INTO economy_ledger (source, created, destroyed, events) VALUES (?, ?, ?, 1)
+		ON CONFLICT(source) DO UPDATE SET
+			created = created + excluded.created,
+			destroyed = destroyed + excluded.destroyed,
+			events = events + 1`,
+		source, created, destroyed)
+	return err
+}
+
+// LoadLedger returns every source in the economy ledger
+func LoadLedger() ([]LedgerEntry, error) {
+	rows, err := db.Query("SELECT source, created, destroyed, events FROM economy_ledger ORDER BY source")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.Source, &e.Created, &e.Destroyed, &e.Events); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// TotalPlayerGold returns the gold held by every character
+func TotalPlayerGold() (int, error) {
+	var total int
+	err := db.QueryRow("SELECT COALESCE(SUM(gold), 0) FROM players").Scan(&total)
+	return total, err
+}
+
+// TotalBankGold returns the gold deposited in the bank by every character
+func TotalBankGold() (int, error) {
+	var total int
+	err := db.QueryRow("SELECT COALESCE(SUM(bank_balance), 0) FROM players").Scan(&total)
+	return total, err
+}
+
+// CreateClanRecord stores a new clan
+func CreateClanRecord(name string) error {
+	_, err := db.Exec("INSERT INTO clans (name, created_at) VALUES (?, ?)", name, time.Now())
+	return err
+}
+
+// DeleteClanRecord removes a clan and all of its memberships
+func DeleteClanRecord(name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM clan_members WHERE clan = ?", name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM clans WHERE name = ?", name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadClanRecords returns every clan with its hall and treasury
+func LoadClanRecords() ([]*Clan, error) {
+	rows, err := db.Query("SELECT name, hall, treasury FROM clans ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clans []*Clan
+	for rows.Next() {
+		c := &Clan{}
+		if err := rows.Scan(&c.Name, &c.Hall, &c.Treasury); err != nil {
+			return nil, err
+		}
+		clans = append(clans, c)
+	}
+	return clans, rows.Err()
+}
+
+// UpdateClanHall saves the room a clan uses as its hall
+func UpdateClanHall(name string, hall int) error {
+	_, err := db.Exec("UPDATE clans SET hall = ? WHERE name = ?", hall, name)
+	return err
+}
+
+// UpdateClanTreasury saves the gold in a clan's treasury
+func UpdateClanTreasury(name string, treasury int) error {
+	_, err := db.Exec("UPDATE clans SET treasury = ? WHERE name = ?", treasury, name)
+	return err
+}
+
+// TotalClanGold returns the gold held in every clan's treasury
+func TotalClanGold() (int, error) {
+	var total int
+	err := db.QueryRow("SELECT COALESCE(SUM(treasury), 0) FROM clans").Scan(&total)
+	return total, err
+}
+
+// SetClanMember records a player's clan and rank, replacing any earlier membership
+func SetClanMember(name, clan string, rank int) error {
+	_, err := db.Exec("INSERT OR REPLACE INTO clan_members (player_name, clan, rank) VALUES (?, ?, ?)", name, clan, rank)
+	return err
+}
+
+// RemoveClanMember removes a player from their clan
+func RemoveClanMember(name string) error {
+	_, err := db.Exec("DELETE FROM clan_members WHERE player_name = ?", name)
+	return err
+}
+
+// LoadClanMember returns a player's clan and rank, or sql.ErrNoRows if they have none
+func LoadClanMember(name string) (string, int, error) {
+	var clan string
+	var rank int
+	err := db.QueryRow("SELECT clan, rank FROM clan_members WHERE player_name = ?", name).Scan(&clan, &rank)
+	return clan, rank, err
+}
+
+// LoadClanMembers returns the members of a clan, highest rank first
+func LoadClanMembers(clan string) ([]ClanMember, error) {
+	rows, err := db.Query("SELECT player_name, rank FROM clan_members WHERE clan = ? ORDER BY rank DESC, player_name", clan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []ClanMember
+	for rows.Next() {
+		var m ClanMember
+		if err := rows.Scan(&m.Name, &m.Rank); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// SavePlayerAffects replaces a player's saved affects with the ones currently on them
+func SavePlayerAffects(name string, affects AffectList) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM player_affects WHERE player_name = ?", name); err != nil {
+		return err
+	}
+
+	for _, affect := range affects {
+		data, err := json.Marshal(affect)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO player_affects (player_name, affect) VALUES (?, ?)", name, string(data)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadPlayerAffects returns the affects a player had when they were last saved
+func LoadPlayerAffects(name string) (AffectList, error) {
+	rows, err := db.Query("SELECT affect FROM player_affects WHERE player_name = ? ORDER BY id", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var affects AffectList
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		affect := &Affect{}
+		if err := json.Unmarshal([]byte(data), affect); err != nil {
+			log.Printf("Skipping unreadable affect for %s: %v", name, err)
+			continue
+		}
+		affects = append(affects, affect)
+	}
+	return affects, rows.Err()
+}
+
+// AddCompletedQuest records that a player has completed a quest
+func AddCompletedQuest(name, quest string) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO player_quests (player_name, quest) VALUES (?, ?)", name, quest)
+	return err
+}
+
+// LoadCompletedQuests returns the IDs of the quests a player has completed
+func LoadCompletedQuests(name string) ([]string, error) {
+	rows, err := db.Query("SELECT quest FROM player_quests WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quests []string
+	for rows.Next() {
+		var quest string
+		if err := rows.Scan(&quest); err != nil {
+			return nil, err
+		}
+		quests = append(quests, quest)
+	}
+	return quests, rows.Err()
+}
+
+// AddPlayerAchievement records that a player has earned an achievement
+func AddPlayerAchievement(name, achievement string, earnedAt time.Time) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO player_achievements (player_name, achievement, earned_at) VALUES (?, ?, ?)",
+		name, achievement, earnedAt)
+	return err
+}
+
+// LoadPlayerAchievements returns the achievements a player has earned and when
+func LoadPlayerAchievements(name string) (map[string]time.Time, error) {
+	rows, err := db.Query("SELECT achievement, earned_at FROM player_achievements WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	earned := make(map[string]time.Time)
+	for rows.Next() {
+		var achievement string
+		var earnedAt time.Time
+		if err := rows.Scan(&achievement, &earnedAt); err != nil {
+			return nil, err
+		}
+		earned[achievement] = earnedAt
+	}
+	return earned, rows.Err()
+}
+
+// AddVisitedRoom records that a player has entered a room
+func AddVisitedRoom(name string, roomID int) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO player_rooms (player_name, room_id) VALUES (?, ?)", name, roomID)
+	return err
+}
+
+// LoadVisitedRooms returns the IDs of the rooms a player has entered
+func LoadVisitedRooms(name string) ([]int, error) {
+	rows, err := db.Query("SELECT room_id FROM player_rooms WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roomIDs []int
+	for rows.Next() {
+		var roomID int
+		if err := rows.Scan(&roomID); err != nil {
+			return nil, err
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+	return roomIDs, rows.Err()
+}
+
+// UpdatePlayerKills saves the number of mobs a player has slain
+func UpdatePlayerKills(name string, kills int) error {
+	_, err := db.Exec("UPDATE players SET kills = ? WHERE name = ?", kills, name)
+	return err
+}
+
+// LoadPlayerKills retrieves the number of mobs a player has slain
+func LoadPlayerKills(name string) (int, error) {
+	var kills int
+	err := db.QueryRow("SELECT COALESCE(kills, 0) FROM players WHERE name = ?", name).Scan(&kills)
+	return kills, err
+}
+
+// AddPlayerLanguage records that a player has learned a language
+func AddPlayerLanguage(name, language string) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO player_languages (player_name, language) VALUES (?, ?)", name, language)
+	return err
+}
+
+// LoadPlayerLanguages returns the languages a player has learned
+func LoadPlayerLanguages(name string) ([]string, error) {
+	rows, err := db.Query("SELECT language FROM player_languages WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var languages []string
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, err
+		}
+		languages = append(languages, language)
+	}
+	return languages, rows.Err()
+}
+
+// UpdatePlayerSpeaking saves the language a player talks in
+func UpdatePlayerSpeaking(name, language string) error {
+	_, err := db.Exec("UPDATE players SET speaking = ? WHERE name = ?", language, name)
+	return err
+}
+
+// LoadPlayerSpeaking retrieves the language a player talks in
+func LoadPlayerSpeaking(name string) (string, error) {
+	var language string
+	err := db.QueryRow("SELECT COALESCE(speaking, '') FROM players WHERE name = ?", name).Scan(&language)
+	return language, err
+}
+
+// AddPlayerSkill records that a player has learned a skill
+func AddPlayerSkill(name, skill string) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO player_skills (player_name, skill) VALUES (?, ?)", name, skill)
+	return err
+}
+
+// LoadPlayerSkills returns the names of the skills a player has learned
+func LoadPlayerSkills(name string) ([]string, error) {
+	rows, err := db.Query("SELECT skill FROM player_skills WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var skills []string
+	for rows.Next() {
+		var skill string
+		if err := rows.Scan(&skill); err != nil {
+			return nil, err
+		}
+		skills = append(skills, skill)
+	}
+	return skills, rows.Err()
+}
+
+// SavedWorldItem is an item stored while it lay in a room
+type SavedWorldItem struct {
+	ID        int
+	RoomID    int
+	Vnum      int    // Item template, or 0 for a player's corpse
+	Container int    // ID of the saved container holding this item, or 0
+	BoundTo   string // Player the item is soulbound to
+	Owner     string // Player whose corpse this is
+	Gold      int    // Coins held inside a container or corpse
+	Timer     int    // Ticks remaining before the item decays
+}
+
+// SaveWorldItems replaces the stored room items with the given items, keyed by room ID
+// The caller decides which items are worth keeping; every item handed in is written.
+func SaveWorldItems(rooms map[int][]*Item) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM world_items"); err != nil {
+		return err
+	}
+
+	// Containers are saved before their contents, which refer back to them
+	var insert func(roomID int, item *Item, container int64) error
+	insert = func(roomID int, item *Item, container int64) error {
+		result, err := tx.Exec(`
+			INSERT INTO world_items (room_id, item_vnum, container, bound_to, owner, gold, timer)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			roomID, item.ID, container, item.BoundTo, item.Owner, item.Gold, item.Timer)
+		if err != nil {
+			return err
+		}
+		if len(item.Contents) == 0 {
+			return nil
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, content := range item.Contents {
+			if err := insert(roomID, content, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for roomID, items := range rooms {
+		for _, item := range items {
+			if err := insert(roomID, item, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadWorldItems returns the items stored by the last SaveWorldItems
+func LoadWorldItems() ([]SavedWorldItem, error) {
+	rows, err := db.Query(`
+		SELECT id, room_id, item_vnum, container, bound_to, owner, gold, timer
+		FROM world_items ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SavedWorldItem
+	for rows.Next() {
+		var item SavedWorldItem
+		if err := rows.Scan(&item.ID, &item.RoomID, &item.Vnum, &item.Container, &item.BoundTo, &item.Owner, &item.Gold, &item.Timer); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ClearWorldItems removes every stored room item
+func ClearWorldItems() error {
+	_, err := db.Exec("DELETE FROM world_items")
+	return err
+}
+
+// AddHouse records a player's purchase of a house room
+func AddHouse(roomID int, owner string) error {
+	_, err := db.Exec("INSERT INTO houses (room_id, owner) VALUES (?, ?)", roomID, owner)
+	return err
+}
+
+// LoadHouseRecords returns every owned house, without guests or storage
+func LoadHouseRecords() ([]*House, error) {
+	rows, err := db.Query("SELECT room_id, owner FROM houses")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var houses []*House
+	for rows.Next() {
+		house := &House{}
+		if err := rows.Scan(&house.RoomID, &house.Owner); err != nil {
+			return nil, err
+		}
+		houses = append(houses, house)
+	}
+	return houses, rows.Err()
+}
+
+// AddHouseGuest lets a player into a house
+func AddHouseGuest(roomID int, guest string) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO house_guests (room_id, guest) VALUES (?, ?)", roomID, guest)
+	return err
+}
+
+// RemoveHouseGuest takes a player off a house's guest list
+func RemoveHouseGuest(roomID int, guest string) error {
+	_, err := db.Exec("DELETE FROM house_guests WHERE room_id = ? AND guest = ?", roomID, guest)
+	return err
+}
+
+// LoadHouseGuests returns the players let into a house
+func LoadHouseGuests(roomID int) ([]string, error) {
+	rows, err := db.Query("SELECT guest FROM house_guests WHERE room_id = ? ORDER BY guest", roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var guests []string
+	for rows.Next() {
+		var guest string
+		if err := rows.Scan(&guest); err != nil {
+			return nil, err
+		}
+		guests = append(guests, guest)
+	}
+	return guests, rows.Err()
+}
+
+// SaveHouseStorage replaces the items stored in a house with the given items
+func SaveHouseStorage(roomID int, items []*Item) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM house_items WHERE room_id = ?", roomID); err != nil {
+		return err
+	}
+
+	// Containers are saved before their contents, which refer back to them
+	var insert func(item *Item, container int64) error
+	insert = func(item *Item, container int64) error {
+		result, err := tx.Exec("INSERT INTO house_items (room_id, item_vnum, bound, container) VALUES (?, ?, ?, ?)",
+			roomID, item.ID, item.BoundTo != "", container)
+		if err != nil {
+			return err
+		}
+		if len(item.Contents) == 0 {
+			return nil
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, content := range item.Contents {
+			if err := insert(content, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		if err := insert(item, 0); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadHouseStorage returns the items stored in a house
+func LoadHouseStorage(roomID int) ([]SavedItem, error) {
+	rows, err := db.Query("SELECT id, item_vnum, bound, container FROM house_items WHERE room_id = ? ORDER BY id", roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SavedItem
+	for rows.Next() {
+		var item SavedItem
+		if err := rows.Scan(&item.ID, &item.Vnum, &item.Bound, &item.Container); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// StabledPet is a player's pet waiting in the stables
+type StabledPet struct {
+	Vnum int
+	Name string
+	HP   int
+}
+
+// SavePlayerPet stables a player's pet, replacing any pet stabled before
+func SavePlayerPet(name string, vnum int, petName string, hp int) error {
+	_, err := db.Exec("INSERT OR REPLACE INTO player_pets (player_name, mob_vnum, name, hp) VALUES (?, ?, ?, ?)",
+		name, vnum, petName, hp)
+	return err
+}
+
+// LoadPlayerPet returns a player's stabled pet, or sql.ErrNoRows if they have none
+func LoadPlayerPet(name string) (StabledPet, error) {
+	var pet StabledPet
+	err := db.QueryRow("SELECT mob_vnum, name, hp FROM player_pets WHERE player_name = ?", name).
+		Scan(&pet.Vnum, &pet.Name, &pet.HP)
+	return pet, err
+}
+
+// DeletePlayerPet takes a player's pet out of the stables
+func DeletePlayerPet(name string) error {
+	_, err := db.Exec("DELETE FROM player_pets WHERE player_name = ?", name)
+	return err
+}
+
+// UpdatePlayerSurvival saves how fed and watered a player is
+func UpdatePlayerSurvival(name string, food, water int) error {
+	_, err := db.Exec("UPDATE players SET food = ?, water = ? WHERE name = ?", food, water, name)
+	return err
+}
+
+// LoadPlayerSurvival retrieves how fed and watered a player is, treating unset values as full
+func LoadPlayerSurvival(name string, full int) (food, water int, err error) {
+	err = db.QueryRow("SELECT COALESCE(food, ?), COALESCE(water, ?) FROM players WHERE name = ?", full, full, name).Scan(&food, &water)
+	return food, water, err
+}
+
+// UpdatePlayerPlaytime saves how many minutes a player has played
+func UpdatePlayerPlaytime(name string, minutes int) error {
+	_, err := db.Exec("UPDATE players SET playtime = ? WHERE name = ?", minutes, name)
+	return err
+}
+
+// LoadPlayerPlaytime retrieves how many minutes a player has played
+func LoadPlayerPlaytime(name string) (int, error) {
+	var minutes int
+	err := db.QueryRow("SELECT COALESCE(playtime, 0) FROM players WHERE name = ?", name).Scan(&minutes)
+	return minutes, err
+}
+
+// UpdatePlayerLastLogin records when a player logged in
+func UpdatePlayerLastLogin(name string, when time.Time) error {
+	_, err := db.Exec("UPDATE players SET last_login = ? WHERE name = ?", when.Unix(), name)
+	return err
+}
+
+// UpdatePlayerLastLogout records when a player logged out
+func UpdatePlayerLastLogout(name string, when time.Time) error {
+	_, err := db.Exec("UPDATE players SET last_logout = ? WHERE name = ?", when.Unix(), name)
+	return err
+}
+
+// LoadPlayerLastSeen retrieves when a player last logged in or out, whichever was later
+func LoadPlayerLastSeen(name string) (time.Time, error) {
+	var unix int64
+	err := db.QueryRow("SELECT MAX(COALESCE(last_login, 0), COALESCE(last_logout, 0)) FROM players WHERE name = ?", name).Scan(&unix)
+	return time.Unix(unix, 0), err
+}
+
+// InactivePlayer is a character that hasn't been seen since a cutoff
+type InactivePlayer struct {
+	Name     string
+	Level    int
+	Staff    bool
+	LastSeen time.Time
+	House    bool // Owns a house, which would be lost with them
+}
+
+// LoadInactivePlayers returns the characters last seen before the cutoff, longest gone first
+func LoadInactivePlayers(cutoff time.Time) ([]InactivePlayer, error) {
+	rows, err := db.Query(`
+		SELECT p.name, p.level, COALESCE(p.staff, 0),
+			MAX(COALESCE(p.last_login, 0), COALESCE(p.last_logout, 0)) AS seen,
+			EXISTS (SELECT 1 FROM houses h WHERE h.owner = p.name)
+		FROM players p
+		WHERE seen < ?
+		ORDER BY seen, p.name`, cutoff.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []InactivePlayer
+	for rows.Next() {
+		var p InactivePlayer
+		var seen int64
+		if err := rows.Scan(&p.Name, &p.Level, &p.Staff, &seen, &p.House); err != nil {
+			return nil, err
+		}
+		p.LastSeen = time.Unix(seen, 0)
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+// playerDataTables lists each table holding a character's data and the column naming them
+var playerDataTables = []struct{ table, column string }{
+	{"player_items", "player_name"},
+	{"player_affects", "player_name"},
+	{"player_quests", "player_name"},
+	{"player_achievements", "player_name"},
+	{"player_rooms", "player_name"},
+	{"player_languages", "player_name"},
+	{"player_channels", "player_name"},
+	{"player_ignores", "player_name"},
+	{"player_ignores", "ignored"},
+	{"player_friends", "player_name"},
+	{"player_friends", "friend"},
+	{"player_aliases", "player_name"},
+	{"player_skills", "player_name"},
+	{"player_pets", "player_name"},
+	{"clan_members", "player_name"},
+	{"lottery_tickets", "player_name"},
+	{"transcripts", "player_name"},
+	{"character_sheets", "player_name"},
+	{"watchdog_flags", "player_name"},
+	{"player_notes", "author"},
+	{"player_notes", "subject"},
+	{"house_guests", "guest"},
+	{"players", "name"},
+}
+
+// LoadPlayerData returns every row of a character's data, keyed by table, for archiving
+func LoadPlayerData(name string) (map[string][]map[string]interface{}, error) {
+	data := make(map[string][]map[string]interface{})
+	for _, t := range playerDataTables {
+		rows, err := db.Query("SELECT * FROM "+t.table+" WHERE "+t.column+" = ?", name)
+		if err != nil {
+			return nil, err
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			row := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+			data[t.table] = append(data[t.table], row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// DeletePlayerData removes a character and everything saved about them
+func DeletePlayerData(name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range playerDataTables {
+		if _, err := tx.Exec("DELETE FROM "+t.table+" WHERE "+t.column+" = ?", name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveWatchdogFlag records a suspicious action for staff review
+func SaveWatchdogFlag(name, kind, detail string, count int, at time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO watchdog_flags (player_name, kind, detail, count, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		name, kind, detail, count, at)
+	return err
+}
+
+// LoadWatchdogFlags returns the most recent flags, newest first, for one player or for everyone if name is ""
+func LoadWatchdogFlags(name string, limit int) ([]WatchdogFlag, error) {
+	rows, err := db.Query(`
+		SELECT id, player_name, kind, detail, count, created_at
+		FROM watchdog_flags
+		WHERE ? = '' OR player_name = ? COLLATE NOCASE
+		ORDER BY id DESC LIMIT ?`, name, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []WatchdogFlag
+	for rows.Next() {
+		var f WatchdogFlag
+		if err := rows.Scan(&f.ID, &f.Player, &f.Kind, &f.Detail, &f.Count, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// ClearWatchdogFlags removes every flag recorded for a player, returning how many there were
+func ClearWatchdogFlags(name string) (int, error) {
+	result, err := db.Exec("DELETE FROM watchdog_flags WHERE player_name = ? COLLATE NOCASE", name)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// AddBanRecord saves a ban, returning its ID
+func AddBanRecord(b *Ban) (int, error) {
+	var expires int64
+	if !b.Permanent() {
+		expires = b.Expires.Unix()
+	}
+	result, err := db.Exec(`
+		INSERT INTO bans (kind, target, reason, banned_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		b.Kind, b.Target, b.Reason, b.By, b.CreatedAt, expires)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// LoadBanRecords returns every saved ban, oldest first
+func LoadBanRecords() ([]*Ban, error) {
+	rows, err := db.Query("SELECT id, kind, target, reason, banned_by, created_at, expires_at FROM bans ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []*Ban
+	for rows.Next() {
+		b := &Ban{}
+		var expires int64
+		if err := rows.Scan(&b.ID, &b.Kind, &b.Target, &b.Reason, &b.By, &b.CreatedAt, &expires); err != nil {
+			return nil, err
+		}
+		if expires > 0 {
+			b.Expires = time.Unix(expires, 0)
+		}
+		bans = append(bans, b)
+	}
+	return bans, rows.Err()
+}
+
+// DeleteBanRecord removes a ban
+func DeleteBanRecord(id int) error {
+	_, err := db.Exec("DELETE FROM bans WHERE id = ?", id)
+	return err
+}
+
+// AddSnoopRecord records the start of a snoop, returning its ID
+func AddSnoopRecord(snooper, target string, startedAt time.Time) (int, error) {
+	result, err := db.Exec(`
+		INSERT INTO snoops (snooper, target, started_at)
+		VALUES (?, ?, ?)`,
+		snooper, target, startedAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// EndSnoopRecord records when a snoop ended
+func EndSnoopRecord(id int, endedAt time.Time) error {
+	_, err := db.Exec("UPDATE snoops SET ended_at = ? WHERE id = ?", endedAt.Unix(), id)
+	return err
+}
+
+// RecentSnoopRecords returns the most recent snoops, newest first
+func RecentSnoopRecords(limit int) ([]SnoopRecord, error) {
+	rows, err := db.Query("SELECT id, snooper, target, started_at, ended_at FROM snoops ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []SnoopRecord
+	for rows.Next() {
+		var r SnoopRecord
+		var ended int64
+		if err := rows.Scan(&r.ID, &r.Snooper, &r.Target, &r.StartedAt, &ended); err != nil {
+			return nil, err
+		}
+		if ended > 0 {
+			r.EndedAt = time.Unix(ended, 0)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// AddAuditRecord saves a staff member's use of a privileged command
+func AddAuditRecord(actor, command, args string, createdAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO audit_log (actor, command, args, created_at)
+		VALUES (?, ?, ?, ?)`,
+		actor, command, args, createdAt)
+	return err
+}
+
+// LoadAuditRecords returns the most recent audit log entries, newest first
+// An empty actor returns entries for every staff member.
+func LoadAuditRecords(actor string, limit int) ([]AuditEntry, error) {
+	query := "SELECT id, actor, command, args, created_at FROM audit_log"
+	params := []interface{}{}
+	if actor != "" {
+		query += " WHERE actor = ? COLLATE NOCASE"
+		params = append(params, actor)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	params = append(params, limit)
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Command, &e.Args, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SaveCharacterSheet shares a character sheet under a token, replacing the player's last one in that format
+func SaveCharacterSheet(token, name, format, content string) error {
+	_, err := db.Exec(`
+		INSERT OR REPLACE INTO character_sheets (token, player_name, format, content, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		token, name, format, content, time.Now())
+	return err
+}
+
+// LoadCharacterSheet returns the format and content of a shared character sheet
+func LoadCharacterSheet(token string) (string, string, error) {
+	var format, content string
+	err := db.QueryRow("SELECT format, content FROM character_sheets WHERE token = ?", token).Scan(&format, &content)
+	return format, content, err
+}
+
+// SetPlayerChannelOff records whether a player has turned a channel off
+func SetPlayerChannelOff(name, channel string, off bool) error {
+	if off {
+		_, err := db.Exec("INSERT OR IGNORE INTO player_channels (player_name, channel) VALUES (?, ?)", name, channel)
+		return err
+	}
+	_, err := db.Exec("DELETE FROM player_channels WHERE player_name = ? AND channel = ?", name, channel)
+	return err
+}
+
+// LoadPlayerChannelsOff returns the channels a player has turned off
+func LoadPlayerChannelsOff(name string) ([]string, error) {
+	rows, err := db.Query("SELECT channel FROM player_channels WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var off []string
+	for rows.Next() {
+		var channel string
+		if err := rows.Scan(&channel); err != nil {
+			return nil, err
+		}
+		off = append(off, channel)
+	}
+	return off, rows.Err()
+}
+
+// SetPlayerIgnore records whether a player is ignoring another
+func SetPlayerIgnore(name, ignored string, ignore bool) error {
+	if ignore {
+		_, err := db.Exec("INSERT OR IGNORE INTO player_ignores (player_name, ignored) VALUES (?, ?)", name, ignored)
+		return err
+	}
+	_, err := db.Exec("DELETE FROM player_ignores WHERE player_name = ? AND ignored = ?", name, ignored)
+	return err
+}
+
+// LoadPlayerIgnores returns the players a player is ignoring
+func LoadPlayerIgnores(name string) ([]string, error) {
+	rows, err := db.Query("SELECT ignored FROM player_ignores WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ignoring []string
+	for rows.Next() {
+		var ignored string
+		if err := rows.Scan(&ignored); err != nil {
+			return nil, err
+		}
+		ignoring = append(ignoring, ignored)
+	}
+	return ignoring, rows.Err()
+}
+
+// SetPlayerFriend records whether a player has another on their friends list
+func SetPlayerFriend(name, friend string, add bool) error {
+	if add {
+		_, err := db.Exec("INSERT OR IGNORE INTO player_friends (player_name, friend) VALUES (?, ?)", name, friend)
+		return err
+	}
+	_, err := db.Exec("DELETE FROM player_friends WHERE player_name = ? AND friend = ?", name, friend)
+	return err
+}
+
+// LoadPlayerFriends returns the players on a player's friends list
+func LoadPlayerFriends(name string) ([]string, error) {
+	rows, err := db.Query("SELECT friend FROM player_friends WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friends []string
+	for rows.Next() {
+		var friend string
+		if err := rows.Scan(&friend); err != nil {
+			return nil, err
+		}
+		friends = append(friends, friend)
+	}
+	return friends, rows.Err()
+}
+
+// SetPlayerAlias records one of a player's aliases, or removes it if command is ""
+func SetPlayerAlias(name, alias, command string) error {
+	if command == "" {
+		_, err := db.Exec("DELETE FROM player_aliases WHERE player_name = ? AND alias = ?", name, alias)
+		return err
+	}
+	_, err := db.Exec("INSERT OR REPLACE INTO player_aliases (player_name, alias, command) VALUES (?, ?, ?)", name, alias, command)
+	return err
+}
+
+// LoadPlayerAliases returns a player's aliases, keyed by name
+func LoadPlayerAliases(name string) (map[string]string, error) {
+	rows, err := db.Query("SELECT alias, command FROM player_aliases WHERE player_name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]string)
+	for rows.Next() {
+		var alias, command string
+		if err := rows.Scan(&alias, &command); err != nil {
+			return nil, err
+		}
+		aliases[alias] = command
+	}
+	return aliases, rows.Err()
+}
@@ -0,0 +1,233 @@
+/*
+ * dispatch.go
+ *
+ * This file implements the checks HandleCommand makes before running a
+ * command's handler. Commands declare what they need in commandRules: the
+ * trust level needed to use them at all, the position the player must be
+ * in, whether they can be used in a fight, how long the player must wait
+ * between uses, and the stamina or mana each use costs. Commands without a
+ * rule can be used by any living player at any time, for free. Players
+ * without the trust for a command are told it doesn't exist. Commands that
+ * need trust go in the staff audit log.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Position is the state a player is in, from the least able to the most
+type Position int
+
+const (
+	PositionDead     Position = iota + 1 // Dead and waiting to respawn
+	PositionStunned                      // Stunned by a blow and unable to act
+	PositionProne                        // Knocked to the ground
+	PositionStanding                     // Alive and on their feet
+)
+
+// Combat restrictions on a command
+const (
+	CombatAllowed = iota // Can be used in or out of a fight
+	NotInCombat          // Can't be used while fighting
+	OnlyInCombat         // Can only be used while fighting
+)
+
+// CommandRule declares what a command needs before its handler runs
+type CommandRule struct {
+	Name     string        // Name shared by the command and its aliases for cooldowns (default: the command)
+	Trust    int           // Lowest trust level that can use the command (0 = anyone)
+	Position Position      // Lowest position the command can be used in (0 = standing)
+	Combat   int           // CombatAllowed, NotInCombat, or OnlyInCombat
+	Cooldown time.Duration // Time the player must wait between uses
+	Stamina  int           // Stamina each use costs
+	Mana     int           // Mana each use costs
+}
+
+// commandRules maps command names to their requirements
+var commandRules = map[string]CommandRule{
+	// Commands the dead can use
+	"look":    {Position: PositionDead},
+	"score":   {Position: PositionDead},
+	"respawn": {Position: PositionDead},
+	"quit":    {Position: PositionDead, Combat: NotInCombat},
+	// Information and settings, which need no body
+	"help":         {Position: PositionDead},
+	"who":          {Position: PositionDead},
+	"whois":        {Position: PositionDead},
+	"rpwho":        {Position: PositionDead},
+	"scorecard":    {Position: PositionDead},
+	"achievements": {Position: PositionDead},
+	"skills":       {Position: PositionDead},
+	"balance":      {Position: PositionDead},
+	"time":         {Position: PositionDead},
+	"uptime":       {Position: PositionDead},
+	"socials":      {Position: PositionDead},
+	"friends":      {Position: PositionDead},
+	"friend":       {Position: PositionDead},
+	"ignore":       {Position: PositionDead},
+	"color":        {Position: PositionDead},
+	"squelch":      {Position: PositionDead},
+	"alert":        {Position: PositionDead},
+	"pagelength":   {Position: PositionDead},
+	"width":        {Position: PositionDead},
+	"client":       {Position: PositionDead},
+	"locale":       {Position: PositionDead},
+	"prompt":       {Position: PositionDead},
+	"display":      {Position: PositionDead},
+	"auto":         {Position: PositionDead},
+	"alias":        {Position: PositionDead},
+	"unalias":      {Position: PositionDead},
+	// Out-of-character channels, which the dead can still use
+	"channel":  {Position: PositionDead},
+	"channels": {Position: PositionDead},
+	"ooc":      {Position: PositionDead},
+	"looc":     {Position: PositionDead},
+	"gossip":   {Position: PositionDead},
+	"newbie":   {Position: PositionDead},
+	"auction":  {Position: PositionDead},
+	"info":     {Position: PositionDead},
+	"ctalk":    {Position: PositionDead},
+	"clantalk": {Position: PositionDead},
+	// Commands that can be used from the ground
+	"say":       {Position: PositionProne},
+	"tell":      {Position: PositionProne},
+	"reply":     {Position: PositionProne},
+	"inventory": {Position: PositionProne},
+	"inv":       {Position: PositionProne},
+	"i":         {Position: PositionProne},
+	"equipment": {Position: PositionProne},
+	"eq":        {Position: PositionProne},
+	// Commands that can't be used in a fight
+	"rent":   {Combat: NotInCombat},
+	"camp":   {Combat: NotInCombat},
+	"recall": {Combat: NotInCombat},
+	"home":   {Combat: NotInCombat},
+	"mount":  {Combat: NotInCombat},
+	"ride":   {Combat: NotInCombat},
+	"eat":    {Combat: NotInCombat},
+	"drink":  {Combat: NotInCombat},
+	"craft":  {Combat: NotInCombat},
+	// Commands that only make sense in a fight
+	"flee":   {Combat: OnlyInCombat, Stamina: 5},
+	"status": {Position: PositionStunned, Combat: OnlyInCombat},
+	"combat": {Name: "status", Position: PositionStunned, Combat: OnlyInCombat},
+	// Commands that can't be spammed
+	"save": {Cooldown: 10 * time.Second},
+	// Commands that take breath
+	"shout": {Position: PositionProne, Stamina: 10},
+	"yell":  {Position: PositionProne, Stamina: 5},
+	// Immortal commands
+	"goto":      {Trust: TrustImmortal},
+	"debug":     {Trust: TrustImmortal},
+	"restore":   {Trust: TrustImmortal},
+	"transfer":  {Trust: TrustImmortal},
+	"peace":     {Trust: TrustImmortal},
+	"invis":     {Trust: TrustImmortal},
+	"ban":       {Trust: TrustImmortal},
+	"unban":     {Trust: TrustImmortal},
+	"banlist":   {Trust: TrustImmortal},
+	"worldstat": {Trust: TrustImmortal},
+	"untitle":   {Trust: TrustImmortal},
+	"watchdog":  {Trust: TrustImmortal},
+	"economy":   {Trust: TrustImmortal},
+	"rsearch":   {Trust: TrustImmortal},
+	"plugins":   {Trust: TrustImmortal},
+	"slay":      {Trust: TrustGod},
+	"purge":     {Trust: TrustGod},
+	"force":     {Trust: TrustGod},
+	"snoop":     {Trust: TrustGod},
+	"siteban":   {Trust: TrustGod},
+	"gainxp":    {Trust: TrustGod},
+	"loadarea":  {Trust: TrustGod},
+	"medit":     {Trust: TrustGod},
+	"reset":     {Trust: TrustGod},
+	"asave":     {Trust: TrustGod},
+	"scripts":   {Trust: TrustGod},
+	"inactive":  {Trust: TrustGod},
+	"trust":     {Trust: TrustImplementor},
+	"auditlog":  {Trust: TrustImplementor},
+	"shutdown":  {Trust: TrustImplementor},
+	"reboot":    {Trust: TrustImplementor},
+	"copyover":  {Trust: TrustImplementor},
+}
+
+// Position returns the position the player is in
+func (p *Player) Position() Position {
+	switch {
+	case p.IsDead:
+		return PositionDead
+	case p.Stunned > 0:
+		return PositionStunned
+	case p.Prone > 0:
+		return PositionProne
+	}
+	return PositionStanding
+}
+
+// checkCommandRule returns why the player can't use a command right now, or "" if they can
+// A command the player can use is paid for and its cooldown started.
+func checkCommandRule(player *Player, command string) string {
+	rule, ok := commandRules[command]
+	if !ok {
+		rule = CommandRule{}
+	}
+	name := rule.Name
+	if name == "" {
+		name = command
+	}
+
+	if player.Trust < rule.Trust {
+		player.WatchRefused(command)
+		return fmt.Sprintf("Unknown command: %s", command)
+	}
+
+	position := rule.Position
+	if position == 0 {
+		position = PositionStanding
+	}
+	if current := player.Position(); current < position {
+		switch current {
+		case PositionDead:
+			return "You are dead and cannot do that. Type 'respawn' to return to life."
+		case PositionStunned:
+			return "You are too stunned to do that."
+		}
+		return "You can't do that while you're on the ground."
+	}
+
+	switch {
+	case rule.Combat == NotInCombat && player.IsInCombat():
+		return "No way! You are fighting."
+	case rule.Combat == OnlyInCombat && !player.IsInCombat():
+		return "You aren't fighting anyone."
+	}
+
+	if wait := time.Until(player.cooldowns[name]); wait > 0 {
+		return fmt.Sprintf("You must wait %d more seconds before you can %s again.", int(math.Ceil(wait.Seconds())), name)
+	}
+
+	switch {
+	case player.Stamina < rule.Stamina:
+		return "You are too tired."
+	case player.MP < rule.Mana:
+		return "You don't have enough mana."
+	}
+
+	if rule.Cooldown > 0 {
+		if player.cooldowns == nil {
+			player.cooldowns = make(map[string]time.Time)
+		}
+		player.cooldowns[name] = time.Now().Add(rule.Cooldown)
+	}
+	if rule.Stamina > 0 || rule.Mana > 0 {
+		player.Stamina -= rule.Stamina
+		player.MP -= rule.Mana
+		player.SendStatus()
+		player.SendVitals()
+	}
+	return ""
+}
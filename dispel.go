@@ -0,0 +1,91 @@
+/*
+ * dispel.go
+ *
+ * This file implements the dispel magic spell, which strips magical
+ * affects from a player or mob. Each affect gets its own resist check
+ * based on the difference between the caster's level and the level it
+ * was cast at, so a low level caster struggles to undo a powerful spell.
+ * Affects from skills, such as a warrior's battle cry, aren't magical and
+ * can't be dispelled.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bounds on the chance to dispel a single affect
+const (
+	MinDispelChance = 5
+	MaxDispelChance = 95
+)
+
+// dispelChance returns the percent chance to strip an affect cast at affectLevel
+func dispelChance(casterLevel, affectLevel int) int {
+	chance := 50 + (casterLevel-affectLevel)*10
+	if chance < MinDispelChance {
+		return MinDispelChance
+	}
+	if chance > MaxDispelChance {
+		return MaxDispelChance
+	}
+	return chance
+}
+
+// dispelAffects returns the names of the magical affects that fail their resist checks
+func dispelAffects(casterLevel int, affects AffectList) []string {
+	var dispelled []string
+	for _, a := range affects {
+		if a.Magical && rng.Intn(100) < dispelChance(casterLevel, a.Level) {
+			dispelled = append(dispelled, a.Name)
+		}
+	}
+	return dispelled
+}
+
+// castDispel strips magical affects from the caster, a player, or a mob in the room
+func castDispel(player *Player, targetName string) (string, bool) {
+	targetName = strings.ToLower(targetName)
+	var victim *Player
+	var mob *MobInstance
+	switch targetName {
+	case "", "self", "me":
+		victim = player
+	default:
+		if victim = player.FindVisiblePlayerInRoom(targetName); victim == nil {
+			if mob = FindMobByTarget(player.Room.ID, targetName); mob == nil || mob.HP <= 0 {
+				return "You don't see that here.", false
+			}
+		}
+	}
+
+	BroadcastToRoom("$n utters the words, 'dispel magic'.", player.Room, player)
+
+	var dispelled []string
+	var name string
+	if mob != nil {
+		name = mob.ShortDescription
+		dispelled = dispelAffects(player.Level, mob.Affects)
+		for _, affect := range dispelled {
+			mob.RemoveAffect(affect)
+		}
+	} else {
+		name = victim.Name
+		if victim == player {
+			name = "yourself"
+		} else {
+			victim.Send(fmt.Sprintf("%s tries to dispel the magic about you.", player.Name))
+		}
+		dispelled = dispelAffects(player.Level, victim.Affects)
+		for _, affect := range dispelled {
+			victim.RemoveAffect(affect)
+		}
+	}
+
+	if len(dispelled) == 0 {
+		return "Nothing seems to happen.", true
+	}
+	return fmt.Sprintf("You dispel %s from %s.", strings.Join(dispelled, ", "), name), true
+}
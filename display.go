@@ -0,0 +1,260 @@
+/*
+ * display.go
+ *
+ * This file renders rooms for players. Every room a player sees, whether
+ * they look around, walk in, log in, or are whisked somewhere, is drawn
+ * here so it comes out the same way everywhere. Players can change how
+ * rooms are shown with 'display': brief leaves out the description when
+ * moving, screenreader writes exits and tags as plain sentences instead of
+ * brackets, and minimap draws a small map of the nearby rooms above each
+ * room. Outdoor rooms mention the night sky after dark. Each setting is
+ * saved with the character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// MinimapDepth is how many rooms out from the player the minimap reaches
+const MinimapDepth = 1
+
+// RoomView holds the options that change how a room is shown
+type RoomView struct {
+	Brief        bool // Leave out the room's description
+	Night        bool // Mention the night sky
+	ScreenReader bool // Plain sentences instead of brackets and symbols
+	Minimap      bool // Draw a small map of the nearby rooms first
+}
+
+// RoomView returns how the player sees a room
+// Brief only applies when the player didn't ask to look.
+func (p *Player) RoomView(room *Room, looking bool) RoomView {
+	return RoomView{
+		Brief:        p.Brief && !looking,
+		Night:        IsNight() && !room.IsIndoors(),
+		ScreenReader: p.ScreenReader,
+		Minimap:      p.Minimap && !p.ScreenReader,
+	}
+}
+
+// DescribeRoom shows a room to a player arriving in it, the way they've chosen to see rooms
+func DescribeRoom(room *Room, viewer *Player) string {
+	return RenderRoom(room, viewer, viewer.RoomView(room, false))
+}
+
+// roomTag marks what a mob in the room is doing, such as fighting someone
+func roomTag(view RoomView, color, action, who string, isViewer bool) string {
+	if view.ScreenReader {
+		if isViewer {
+			who = "you"
+		}
+		return fmt.Sprintf(" (%s %s)", action, who)
+	}
+	if isViewer {
+		who = "YOU"
+	}
+	return fmt.Sprintf(" %s[%s %s]{x}", color, strings.ToUpper(action), who)
+}
+
+// listExits writes the exits the viewer can see
+func listExits(room *Room, viewer *Player, view RoomView) string {
+	var exits []string
+	for direction, exit := range room.Exits {
+		if !viewer.CanSeeExit(exit) {
+			continue
+		}
+		closed := exit.Door != nil && exit.Door.Closed
+		switch {
+		case closed && view.ScreenReader:
+			exits = append(exits, direction+" (closed)")
+		case closed:
+			// Show closed doors in parentheses
+			exits = append(exits, fmt.Sprintf("(%s)", direction))
+		default:
+			exits = append(exits, direction)
+		}
+	}
+	sort.Strings(exits)
+
+	if !view.ScreenReader {
+		return fmt.Sprintf("{G}Available exits:{x} [%s]", strings.Join(exits, ", "))
+	}
+	switch len(exits) {
+	case 0:
+		return "There are no obvious exits."
+	case 1:
+		return fmt.Sprintf("There is one exit: %s.", exits[0])
+	default:
+		return fmt.Sprintf("Exits: %s, and %s.", strings.Join(exits[:len(exits)-1], ", "), exits[len(exits)-1])
+	}
+}
+
+// RenderRoom draws a room for a player with the given view
+func RenderRoom(room *Room, viewer *Player, view RoomView) string {
+	// Nothing can be seen in a dark room without a light
+	if !RoomIsLit(room) {
+		return DarkMessage
+	}
+
+	// Get list of other players in the room (excluding the viewer)
+	playersMutex.Lock()
+	var otherPlayers []string
+	for _, p := range activePlayers {
+		if p != viewer && // Not the viewing player
+			p.Room != nil && viewer.Room != nil && // Both rooms exist
+			p.Room == viewer.Room && // Exact same room instance
+			viewer.CanSeePlayer(p) { // Not hidden by invisibility
+			// Include the player's title if they have one
+			if p.Title != "" {
+				otherPlayers = append(otherPlayers, fmt.Sprintf("%s %s", p.Name, p.Title))
+			} else {
+				otherPlayers = append(otherPlayers, p.Name)
+			}
+		}
+	}
+	playersMutex.Unlock()
+
+	// Build the room description with colors
+	description := ""
+	if view.Minimap {
+		description = BuildMap(room, MinimapDepth, viewer) + "\n"
+	}
+	description += fmt.Sprintf("{C}%s{x}", room.Name)
+	if !view.Brief {
+		description += "\n" + room.Description
+	}
+	if view.Night {
+		description += "\n{D}Night has fallen, and the stars are out.{x}"
+	}
+
+	// Add mobs in the room
+	mobMutex.RLock()
+	mobs := GetMobsInRoom(room.ID)
+
+	if len(mobs) > 0 {
+		description += "\n" // Single newline before mobs
+
+		// Display mobs without numbering in the description
+		for _, mob := range mobs {
+			if mob != nil {
+				// Check if this mob is in combat with any player
+				combatStatus := ""
+				playersMutex.Lock()
+				for _, p := range activePlayers {
+					if p.IsInCombat() && p.Target == mob {
+						combatStatus = roomTag(view, "{R}", "fighting", p.Name, p == viewer)
+						break
+					}
+				}
+				playersMutex.Unlock()
+				if victim := mob.Fighting; combatStatus == "" && victim != nil && victim.Room == room {
+					combatStatus = roomTag(view, "{R}", "fighting", victim.ShortDescription, false)
+				}
+
+				if rider := mob.Rider; rider != nil && rider.Room == room {
+					combatStatus += roomTag(view, "{Y}", "ridden by", rider.Name, rider == viewer)
+				}
+
+				description += fmt.Sprintf("%s%s%s\n", viewer.MobAura(mob), mob.LongDescription, combatStatus)
+			}
+		}
+	}
+	mobMutex.RUnlock()
+
+	// Add items lying on the ground
+	items := GetItemsInRoom(room)
+	if len(items) > 0 {
+		if len(mobs) == 0 {
+			description += "\n"
+		}
+		for _, item := range items {
+			description += viewer.ItemAura(item) + item.RoomLine() + "\n"
+		}
+	}
+
+	// Add resource nodes that can be gathered from
+	if nodes := describeResourceNodes(room); nodes != "" {
+		if len(mobs) == 0 && len(items) == 0 {
+			description += "\n"
+		}
+		description += nodes
+	}
+
+	// Add exits after mobs
+	description += "\n" + listExits(room, viewer, view)
+
+	// Add other players if present
+	if len(otherPlayers) > 0 {
+		description += fmt.Sprintf("\n{Y}Also here:{x} %s", strings.Join(otherPlayers, ", "))
+	}
+
+	return description
+}
+
+// displayToggle is a room display setting a player can turn on or off
+type displayToggle struct {
+	name    string
+	enabled *bool // The player's setting
+	help    string
+}
+
+// displayToggles lists the player's room display settings
+func displayToggles(p *Player) []displayToggle {
+	return []displayToggle{
+		{"brief", &p.Brief, "leave out room descriptions as you move (look still shows them)"},
+		{"screenreader", &p.ScreenReader, "write exits and tags as plain sentences, without the minimap"},
+		{"minimap", &p.Minimap, "draw a small map of the nearby rooms above each room"},
+	}
+}
+
+// LoadDisplay restores the player's room display settings
+func (p *Player) LoadDisplay() {
+	brief, screenReader, minimap, err := LoadPlayerDisplay(p.Name)
+	if err != nil {
+		log.Printf("Error loading display settings for %s: %v", p.Name, err)
+		return
+	}
+	p.Brief, p.ScreenReader, p.Minimap = brief, screenReader, minimap
+}
+
+// handleDisplay lists the player's room display settings, or toggles one
+// Usage: display [brief|screenreader|minimap]
+func handleDisplay(player *Player, args []string) string {
+	if len(args) > 0 {
+		name := strings.ToLower(args[0])
+		for _, toggle := range displayToggles(player) {
+			if toggle.name != name {
+				continue
+			}
+			*toggle.enabled = !*toggle.enabled
+			state := "off"
+			if *toggle.enabled {
+				state = "{G}on{x}"
+			}
+			message := fmt.Sprintf("%s is now %s.", capitalizeFirst(toggle.name), state)
+			if err := UpdatePlayerDisplay(player.Name, player.Brief, player.ScreenReader, player.Minimap); err != nil {
+				log.Printf("Error saving display settings for %s: %v", player.Name, err)
+				message += " (This change could not be saved and will only last this session.)"
+			}
+			return message
+		}
+		return "Usage: display [brief|screenreader|minimap]"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Room display:\r\n")
+	for _, toggle := range displayToggles(player) {
+		state := "off"
+		if *toggle.enabled {
+			state = "{G}on{x} "
+		}
+		sb.WriteString(fmt.Sprintf("  %-12s %s - %s\r\n", toggle.name, state, toggle.help))
+	}
+	sb.WriteString("Type 'display <setting>' to turn it on or off.")
+	return sb.String()
+}
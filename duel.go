@@ -0,0 +1,294 @@
+/*
+ * duel.go
+ *
+ * This file implements fights between players. Outside the arena, players
+ * only fight by consent: one challenges another with 'duel', and the fight
+ * starts when the challenge is accepted. Rooms marked as arena rooms allow
+ * full PvP, so anyone there may simply attack another player. Either way,
+ * each player strikes at their opponent on their own combat pulse, using
+ * the same hit, evasion, and critical rules as fights with mobs. Nobody
+ * dies: a player brought to zero HP is defeated and left with 1 HP, with
+ * no experience lost and no corpse. The loser of an arena fight is carried
+ * out to the arena gate.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ArenaGateRoomID is the room defeated arena fighters are carried out to
+const ArenaGateRoomID = 3400
+
+// EnterPvP puts the player in combat with another player
+func (p *Player) EnterPvP(opponent *Player) {
+	p.InCombat = true
+	p.Opponent = opponent
+}
+
+// startPvP begins a fight between two players
+func startPvP(attacker, defender *Player) {
+	attacker.CancelCamp("You stop making camp.")
+	defender.CancelCamp("You are attacked and stop making camp!")
+	attacker.BecomeVisible()
+
+	attacker.EnterPvP(defender)
+	defender.EnterPvP(attacker)
+	attacker.SendStatus()
+	defender.SendStatus()
+}
+
+// sendToOthers sends a combat message to everyone in the room except two players
+func sendToOthers(message string, room *Room, a, b *Player) {
+	colorized := ColorizeByType(message, "combat")
+	for _, p := range playersInRoom(room) {
+		if p != a && p != b {
+			p.SendRepeatable(colorized)
+		}
+	}
+}
+
+// pvpOpponentGone reports whether the player's opponent has left the fight
+func (p *Player) pvpOpponentGone() bool {
+	opp := p.Opponent
+	return opp == nil || opp.IsDead || opp.Opponent != p || opp.Room != p.Room || FindPlayerByName(opp.Name) != opp
+}
+
+// ExecutePvPAttack handles a player's attack against their opponent
+func (p *Player) ExecutePvPAttack() {
+	if p.pvpOpponentGone() {
+		p.ExitCombat()
+		p.Send("Your opponent is no longer fighting you.")
+		return
+	}
+	opp := p.Opponent
+	if p.Stunned > 0 {
+		return // Too stunned to swing
+	}
+
+	hitChance := CalculateHitChance(p.Level, opp.Level) + float64(p.Modifier(ApplyHitroll))/100
+	if !p.CanSee() {
+		hitChance -= DarkHitPenalty // Fighting blind
+	}
+	hitChance -= CombatHitPenalty(p.Room)
+	hitChance += stateHitModifier(&p.CombatState, &opp.CombatState)
+
+	if rng.Float64() > hitChance {
+		p.SendType(fmt.Sprintf("You miss %s.", opp.Name), "combat")
+		p.Meter.miss()
+		opp.SendType(fmt.Sprintf("%s misses you.", p.Name), "combat")
+		sendToOthers(fmt.Sprintf("%s misses %s.", p.Name, opp.Name), p.Room, p, opp)
+		return
+	}
+
+	if ProcessEvasion(opp.Level, p.Level) {
+		p.SendType(fmt.Sprintf("%s evades your attack.", opp.Name), "combat")
+		p.Meter.evade()
+		opp.SendType(fmt.Sprintf("%s swings at you, but you evade just in time!", p.Name), "combat")
+		sendToOthers(fmt.Sprintf("%s evades %s's attack.", opp.Name, p.Name), p.Room, p, opp)
+		return
+	}
+
+	damage := CalculateDamage(p.Level) + p.Modifier(ApplyDamroll)
+	if damage < 1 {
+		damage = 1
+	}
+	isCritical := ProcessCriticalHit(p.Level, opp.Level)
+	if isCritical {
+		damage *= 2
+	}
+	p.damageOpponent(damage, isCritical)
+
+	if isCritical {
+		p.SendType(fmt.Sprintf("You land a {R}CRITICAL{x} hit on %s for {R}%d{x} damage!", opp.Name, damage), "combat")
+		opp.SendType(fmt.Sprintf("%s lands a {R}CRITICAL HIT{x} on you for {R}%d{x} damage!", p.Name, damage), "combat")
+		sendToOthers(fmt.Sprintf("%s lands a CRITICAL hit on %s!", p.Name, opp.Name), p.Room, p, opp)
+	} else {
+		p.SendType(fmt.Sprintf("You hit %s for {R}%d{x} damage.", opp.Name, damage), "combat")
+		opp.SendType(fmt.Sprintf("%s strikes you for {R}%d{x} damage.", p.Name, damage), "combat")
+		sendToOthers(fmt.Sprintf("%s hits %s.", p.Name, opp.Name), p.Room, p, opp)
+	}
+	opp.SendVitals()
+
+	if p.hasDefeated(opp) {
+		endPvP(p, opp)
+	}
+}
+
+// endPvP ends a fight between players, leaving the loser defeated but alive
+func endPvP(winner, loser *Player) {
+	if winner.Opponent != loser {
+		return // Already over
+	}
+	if winner.Sparring() {
+		endSpar(winner, loser)
+		return
+	}
+	winner.ExitCombat()
+	loser.ExitCombat()
+	loser.HP = 1
+
+	room := loser.Room
+	where := "a duel"
+	if room.Arena {
+		where = "the arena"
+	}
+
+	winner.SendType(fmt.Sprintf("You have defeated %s!", loser.Name), "combat")
+	loser.SendType(fmt.Sprintf("You have been defeated by %s!", winner.Name), "death")
+	sendToOthers(fmt.Sprintf("%s has defeated %s!", winner.Name, loser.Name), room, winner, loser)
+	AnnounceInfo(fmt.Sprintf("%s has defeated %s in %s.", winner.Name, loser.Name, where))
+	log.Printf("[PVP] %s defeated %s in %s (Room %d).", winner.Name, loser.Name, where, room.ID)
+
+	if room.Arena {
+		carryOutOfArena(loser)
+	}
+	winner.SendStatus()
+	loser.SendStatus()
+}
+
+// carryOutOfArena moves a defeated fighter to the arena gate
+func carryOutOfArena(p *Player) {
+	gate, err := GetRoom(ArenaGateRoomID)
+	if err != nil {
+		log.Printf("[ERROR] Arena gate Room %d not found: %v", ArenaGateRoomID, err)
+		return
+	}
+	if err := UpdatePlayerRoom(p.Name, gate.ID); err != nil {
+		log.Printf("[ERROR] Failed to update player room leaving the arena: %v", err)
+		return
+	}
+
+	BroadcastToRoom("$n is carried out of the arena.", p.Room, p)
+	p.Room = gate
+	BroadcastToRoom("$n is carried in from the arena, battered and bruised.", gate, p)
+	p.Send("You are carried out of the arena.")
+	p.Send(DescribeRoom(gate, p))
+	p.SendRoomInfo()
+}
+
+// fleePvP takes the player out of a fight with another player, forfeiting it
+func fleePvP(player *Player) string {
+	opp := player.Opponent
+	room := player.Room
+
+	var exits []string
+	for _, dir := range openExits(room) {
+		if CheckExitRequirement(player, room.Exits[dir].Requires) == nil && exitGuard(player, room, dir) == nil {
+			exits = append(exits, dir)
+		}
+	}
+	if len(exits) == 0 {
+		return "There's nowhere to run!"
+	}
+	direction := exits[rng.Intn(len(exits))]
+
+	player.ExitCombat()
+	if opp != nil && opp.Opponent == player {
+		opp.ExitCombat()
+		opp.SendType(fmt.Sprintf("%s flees from you!", player.Name), "combat")
+		sendToOthers(fmt.Sprintf("%s flees from %s!", player.Name, opp.Name), room, player, opp)
+		player.Send(fmt.Sprintf("You flee from %s!", opp.Name))
+	}
+
+	if err := HandleMovement(player, direction); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// attackPlayer starts a fight with a player in an arena room
+func attackPlayer(player, target *Player) string {
+	if target == player {
+		return "You can't attack yourself.\r\n"
+	}
+	if !player.Room.Arena {
+		return fmt.Sprintf("You can only fight %s in the arena. Challenge them with 'duel' instead.\r\n", target.Name)
+	}
+	if target.IsDead {
+		return fmt.Sprintf("%s is already dead!\r\n", target.Name)
+	}
+	if target.IsInCombat() {
+		return fmt.Sprintf("%s is already fighting.\r\n", target.Name)
+	}
+
+	startPvP(player, target)
+	target.SendType(fmt.Sprintf("%s attacks you!", player.Name), "combat")
+	sendToOthers(fmt.Sprintf("%s attacks %s!", player.Name, target.Name), player.Room, player, target)
+	return fmt.Sprintf("You attack %s!\r\n", target.Name)
+}
+
+// handleDuel challenges a player to a duel, or answers a challenge
+// Usage: duel <player> | duel accept | duel decline
+func handleDuel(player *Player, args []string) string {
+	if len(args) == 0 {
+		if player.duelChallenger != nil {
+			return fmt.Sprintf("%s has challenged you to a duel. Type 'duel accept' or 'duel decline'.", player.duelChallenger.Name)
+		}
+		return "Usage: duel <player> | duel accept | duel decline"
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "accept":
+		return duelAccept(player)
+	case "decline":
+		return duelDecline(player)
+	}
+
+	if player.IsInCombat() {
+		return "You are already fighting!"
+	}
+	if player.IsDead {
+		return "You can't duel while dead."
+	}
+	target := player.FindVisiblePlayerInRoom(args[0])
+	if target == nil {
+		return "They aren't here."
+	}
+	if target == player {
+		return "You can't duel yourself."
+	}
+	if target.IsDead || target.IsInCombat() {
+		return fmt.Sprintf("%s is in no state to duel.", target.Name)
+	}
+
+	target.duelChallenger = player
+	target.Send(fmt.Sprintf("{Y}%s challenges you to a duel!{x} Type 'duel accept' or 'duel decline'.", player.Name))
+	sendToOthers(fmt.Sprintf("%s challenges %s to a duel!", player.Name, target.Name), player.Room, player, target)
+	return fmt.Sprintf("You challenge %s to a duel.", target.Name)
+}
+
+// duelAccept starts the duel the player was challenged to
+func duelAccept(player *Player) string {
+	challenger := player.duelChallenger
+	if challenger == nil {
+		return "Nobody has challenged you to a duel."
+	}
+	player.duelChallenger = nil
+
+	if challenger.Room != player.Room || FindPlayerByName(challenger.Name) != challenger {
+		return fmt.Sprintf("%s is no longer here.", challenger.Name)
+	}
+	if player.IsInCombat() || challenger.IsInCombat() || player.IsDead || challenger.IsDead {
+		return "The duel can't go ahead right now."
+	}
+
+	startPvP(challenger, player)
+	challenger.SendType(fmt.Sprintf("%s accepts your challenge. Fight!", player.Name), "combat")
+	sendToOthers(fmt.Sprintf("%s accepts %s's challenge, and the duel begins!", player.Name, challenger.Name), player.Room, player, challenger)
+	return fmt.Sprintf("You accept %s's challenge. Fight!", challenger.Name)
+}
+
+// duelDecline turns down a duel challenge
+func duelDecline(player *Player) string {
+	challenger := player.duelChallenger
+	if challenger == nil {
+		return "Nobody has challenged you to a duel."
+	}
+	player.duelChallenger = nil
+	challenger.Send(fmt.Sprintf("%s declines your challenge.", player.Name))
+	return fmt.Sprintf("You decline %s's challenge.", challenger.Name)
+}
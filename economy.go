@@ -0,0 +1,198 @@
+/*
+ * economy.go
+ *
+ * This file holds the economy settings for the MUD. Gold enters the world
+ * through mob drops, and the settings here control the gold sinks that take
+ * it back out again: the cost of renting a room at an inn, the cost of
+ * recalling, a tax on gold looted from mobs, and the lottery's ticket price
+ * and house cut. Defaults are built in, and operators can override any of
+ * them in economy.yml without recompiling. Every change to the world's
+ * gold supply is also recorded by source in an economy ledger, and the
+ * staff 'economy' command reports it so operators can tune drop rates and
+ * prices with real data.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EconomyConfigFile is the optional file that overrides the default economy settings
+const EconomyConfigFile = "economy.yml"
+
+// Game time used by recurring economy events
+const (
+	TicksPerGameDay = 24 // One tick is one game hour
+	DaysPerGameWeek = 7
+)
+
+// Gold sources tracked in the economy ledger
+const (
+	GoldSourceMobDrops       = "mob drops"
+	GoldSourceCorpseDecay    = "corpse decay"
+	GoldSourceTaxes          = "taxes"
+	GoldSourceRent           = "rent"
+	GoldSourceRecall         = "recall"
+	GoldSourceLotteryTickets = "lottery tickets"
+	GoldSourceLotteryPrizes  = "lottery prizes"
+	GoldSourceTraining       = "training"
+	GoldSourceQuests         = "quest rewards"
+	GoldSourceSacrifice      = "sacrifice"
+	GoldSourceClans          = "disbanded clans"
+	GoldSourceHousing        = "housing"
+	GoldSourcePets           = "pets"
+	GoldSourcePurge          = "purged items"
+	GoldSourceBribes         = "bribes"
+)
+
+// EconomyConfig controls the gold sinks that balance the economy
+type EconomyConfig struct {
+	RentCost             int `yaml:"rent_cost"`              // Gold to rent a room at an inn
+	RecallCost           int `yaml:"recall_cost"`            // Gold per level to recall (0 = free)
+	LotteryTicketPrice   int `yaml:"lottery_ticket_price"`   // Gold per lottery ticket
+	LotteryMaxTickets    int `yaml:"lottery_max_tickets"`    // Most tickets one player may hold per drawing
+	LotteryHouseCut      int `yaml:"lottery_house_cut"`      // Percent of the pot destroyed at each drawing
+	LotteryDrawIntervals int `yaml:"lottery_draw_intervals"` // Game weeks between drawings
+	LootTax              int `yaml:"loot_tax"`               // Percent of gold looted from mob corpses taken as tax
+}
+
+// economy holds the active settings, starting from the defaults
+var economy = EconomyConfig{
+	RentCost:             10,
+	RecallCost:           0,
+	LotteryTicketPrice:   10,
+	LotteryMaxTickets:    10,
+	LotteryHouseCut:      20,
+	LotteryDrawIntervals: 1,
+	LootTax:              0,
+}
+
+// LoadEconomyConfig applies any overrides found in economy.yml
+func LoadEconomyConfig() error {
+	data, err := os.ReadFile(EconomyConfigFile)
+	if os.IsNotExist(err) {
+		return nil // Defaults are fine
+	}
+	if err != nil {
+		return err
+	}
+
+	config := economy
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("error parsing %s: %w", EconomyConfigFile, err)
+	}
+
+	if config.LotteryHouseCut < 0 || config.LotteryHouseCut > 100 {
+		return fmt.Errorf("lottery_house_cut must be between 0 and 100, got %d", config.LotteryHouseCut)
+	}
+	if config.LootTax < 0 || config.LootTax > 100 {
+		return fmt.Errorf("loot_tax must be between 0 and 100, got %d", config.LootTax)
+	}
+	if config.LotteryDrawIntervals < 1 {
+		config.LotteryDrawIntervals = 1
+	}
+
+	economy = config
+	log.Printf("Loaded economy settings from %s", EconomyConfigFile)
+	return nil
+}
+
+// ChargeGold removes gold from a player if they can afford it, reporting success
+// The gold is recorded as destroyed by the given source.
+func ChargeGold(player *Player, amount int, source string) bool {
+	if amount <= 0 {
+		return true
+	}
+	if player.Gold < amount {
+		return false
+	}
+
+	player.Gold -= amount
+	if err := UpdatePlayerGold(player.Name, player.Gold); err != nil {
+		log.Printf("Error saving gold for %s: %v", player.Name, err)
+	}
+	player.SendStatus()
+	RecordGoldDestroyed(source, amount)
+	return true
+}
+
+// RecordGoldCreated adds newly minted gold to the economy ledger
+func RecordGoldCreated(source string, amount int) {
+	if amount <= 0 {
+		return
+	}
+	if err := RecordLedgerEntry(source, amount, 0); err != nil {
+		log.Printf("Error recording %d gold created by %s: %v", amount, source, err)
+	}
+}
+
+// RecordGoldDestroyed adds gold removed from the world to the economy ledger
+func RecordGoldDestroyed(source string, amount int) {
+	if amount <= 0 {
+		return
+	}
+	if err := RecordLedgerEntry(source, 0, amount); err != nil {
+		log.Printf("Error recording %d gold destroyed by %s: %v", amount, source, err)
+	}
+}
+
+// ApplyLootTax takes the configured tax out of gold looted from a mob, returning what's left
+func ApplyLootTax(gold int) (kept int, tax int) {
+	tax = gold * economy.LootTax / 100
+	RecordGoldDestroyed(GoldSourceTaxes, tax)
+	return gold - tax, tax
+}
+
+// lotteryPotKey is the server state key holding the lottery pot, which the
+// economy report counts even when the lottery plugin is left out
+const lotteryPotKey = "lottery_pot"
+
+// handleEconomy shows staff the gold created and destroyed by each source
+func handleEconomy(player *Player, args []string) string {
+	entries, err := LoadLedger()
+	if err != nil {
+		log.Printf("Error loading economy ledger: %v", err)
+		return "Error loading the economy ledger."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{Y}Economy Report{x}\r\n")
+	sb.WriteString("{C}------------------------------------------------------{x}\r\n")
+	sb.WriteString(fmt.Sprintf("%-18s %10s %10s %8s\r\n", "Source", "Created", "Destroyed", "Events"))
+	sb.WriteString("{C}------------------------------------------------------{x}\r\n")
+
+	created, destroyed := 0, 0
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("%-18s %10d %10d %8d\r\n", e.Source, e.Created, e.Destroyed, e.Events))
+		created += e.Created
+		destroyed += e.Destroyed
+	}
+	if len(entries) == 0 {
+		sb.WriteString("No gold has changed hands yet.\r\n")
+	}
+
+	sb.WriteString("{C}------------------------------------------------------{x}\r\n")
+	sb.WriteString(fmt.Sprintf("%-18s %10d %10d\r\n", "Total", created, destroyed))
+	sb.WriteString(fmt.Sprintf("Net change in gold supply: {Y}%+d{x}\r\n", created-destroyed))
+
+	if held, err := TotalPlayerGold(); err == nil {
+		sb.WriteString(fmt.Sprintf("Gold held by all characters: {Y}%d{x}\r\n", held))
+	}
+	if banked, err := TotalBankGold(); err == nil {
+		sb.WriteString(fmt.Sprintf("Gold in the bank: {Y}%d{x}\r\n", banked))
+	}
+	if treasuries, err := TotalClanGold(); err == nil {
+		sb.WriteString(fmt.Sprintf("Gold in clan treasuries: {Y}%d{x}\r\n", treasuries))
+	}
+	if pot, err := GetServerStateInt(lotteryPotKey); err == nil {
+		sb.WriteString(fmt.Sprintf("Gold in the lottery pot: {Y}%d{x}", pot))
+	}
+
+	return sb.String()
+}
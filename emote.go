@@ -0,0 +1,118 @@
+/*
+ * emote.go
+ *
+ * This file implements ambient mob emotes and the act()-style formatter
+ * that builds them. Mobs with emotes defined in their area file now and
+ * then perform one while players are nearby. An emote may single out a
+ * player in the room: that player sees it from their own point of view
+ * ("The beggar tugs at your sleeve.") while everyone else sees it from
+ * the outside ("The beggar tugs at Bob's sleeve.").
+ */
+
+package main
+
+import (
+	"strings"
+)
+
+// EmoteChance is the percent chance per pulse that a mob with players nearby emotes
+const EmoteChance = 2
+
+// MobEmote is an ambient action a mob performs
+// Messages use $n for the mob and $N for the targeted player, if any.
+type MobEmote struct {
+	Room   string `yaml:"room"`             // Seen by the room; mentions $N if the emote targets a player
+	Target string `yaml:"target,omitempty"` // Seen by the targeted player (default: Room from their point of view)
+}
+
+// targetsPlayer reports whether the emote singles out a player
+func (e MobEmote) targetsPlayer() bool {
+	return strings.Contains(e.Room, "$N") || strings.Contains(e.Target, "$N")
+}
+
+// act fills in an act()-style message as one viewer sees it
+// $n is replaced by the actor and $N by the target. When the viewer is the
+// target, "$N's" becomes "your" and "$N" becomes "you".
+func act(format, actor, target string, toTarget bool) string {
+	message := strings.ReplaceAll(format, "$n", actor)
+	if toTarget {
+		message = strings.ReplaceAll(message, "$N's", "your")
+		message = strings.ReplaceAll(message, "$N", "you")
+	} else {
+		message = strings.ReplaceAll(message, "$N", target)
+	}
+	return capitalizeFirst(message)
+}
+
+// playersInRoom returns the live players in a room
+func playersInRoom(room *Room) []*Player {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	var players []*Player
+	for _, p := range activePlayers {
+		if p.Room == room && !p.IsDead {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// PerformEmote shows a mob's emote to its room, from each viewer's point of view
+func PerformEmote(mob *MobInstance, emote MobEmote) {
+	players := playersInRoom(mob.Room)
+	if len(players) == 0 {
+		return
+	}
+
+	// Mobs can only single out players they can see
+	var target *Player
+	if emote.targetsPlayer() {
+		var visible []*Player
+		for _, p := range players {
+			if MobCanSeePlayer(mob, p) {
+				visible = append(visible, p)
+			}
+		}
+		if len(visible) == 0 {
+			return
+		}
+		target = visible[rng.Intn(len(visible))]
+	}
+
+	for _, p := range players {
+		if target != nil && p == target {
+			format := emote.Target
+			if format == "" {
+				format = emote.Room
+			}
+			p.Send(act(format, mob.ShortDescription, p.Name, true))
+			continue
+		}
+
+		targetName := ""
+		if target != nil {
+			targetName = target.Name
+		}
+		p.Send(act(emote.Room, mob.ShortDescription, targetName, false))
+	}
+}
+
+// ProcessMobEmotes gives mobs with ambient emotes a chance to perform one
+func ProcessMobEmotes() {
+	mobMutex.RLock()
+	var emoters []*MobInstance
+	for _, mob := range mobInstances {
+		if len(mob.Emotes) > 0 && mob.Room != nil {
+			emoters = append(emoters, mob)
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range emoters {
+		if IsMobInCombat(mob) || mob.Fighting != nil || rng.Intn(100) >= EmoteChance {
+			continue
+		}
+		PerformEmote(mob, mob.Emotes[rng.Intn(len(mob.Emotes))])
+	}
+}
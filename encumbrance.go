@@ -0,0 +1,96 @@
+/*
+ * encumbrance.go
+ *
+ * This file implements item weight and carrying capacity. Every item has
+ * a weight, and a container's weight includes everything inside it. How
+ * much a player can carry comfortably depends on their strength. Past
+ * that they are encumbered: each step costs stamina and they can't move
+ * again straight away, unless they're riding a mount that carries the load
+ * for them. Nobody can carry more than twice their capacity.
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Carrying capacity and the cost of being over it
+const (
+	BaseCarryCapacity   = 50              // Pounds anyone can carry
+	CarryCapacityPerSTR = 10              // Extra pounds per point of strength
+	MaxLoadMultiple     = 2               // Nobody can carry more than this times their capacity
+	EncumberedMoveCost  = 5               // Stamina spent on each step while encumbered
+	EncumberedMoveDelay = 2 * time.Second // Time between steps while encumbered
+)
+
+// TotalWeight returns the weight of the item and everything inside it
+func (i *Item) TotalWeight() int {
+	total := i.Weight
+	for _, content := range i.Contents {
+		total += content.TotalWeight()
+	}
+	return total
+}
+
+// CarryCapacity returns how much weight the player can carry without being encumbered
+func (p *Player) CarryCapacity() int {
+	return BaseCarryCapacity + p.Stat(ApplySTR)*CarryCapacityPerSTR
+}
+
+// CarriedWeight returns the weight of everything the player is carrying and wearing
+func (p *Player) CarriedWeight() int {
+	total := 0
+	for _, item := range p.Inventory {
+		total += item.TotalWeight()
+	}
+	for _, item := range p.Equipment {
+		total += item.TotalWeight()
+	}
+	return total
+}
+
+// IsEncumbered reports whether the player is carrying more than their capacity
+func (p *Player) IsEncumbered() bool {
+	return p.CarriedWeight() > p.CarryCapacity()
+}
+
+// CanLift reports whether the player can pick up an item without going over their maximum load
+func (p *Player) CanLift(item *Item) bool {
+	return p.CarriedWeight()+item.TotalWeight() <= p.CarryCapacity()*MaxLoadMultiple
+}
+
+// checkEncumbrance returns an error if the player's load stops them from moving right now
+func (p *Player) checkEncumbrance() error {
+	if !p.IsEncumbered() || p.Riding() != nil {
+		return nil
+	}
+	if time.Now().Before(p.nextMoveAt) {
+		return fmt.Errorf("you are carrying too much to move so quickly")
+	}
+	if p.Stamina < EncumberedMoveCost {
+		return fmt.Errorf("you are too exhausted to move under such a load")
+	}
+	return nil
+}
+
+// payEncumbrance charges an encumbered player for the step they just took
+func (p *Player) payEncumbrance() {
+	if !p.IsEncumbered() || p.Riding() != nil {
+		return
+	}
+	p.Stamina -= EncumberedMoveCost
+	p.nextMoveAt = time.Now().Add(EncumberedMoveDelay)
+	p.Send("{Y}You stagger under the weight of your load.{x}")
+	p.SendVitals()
+}
+
+// describeLoad summarizes the player's load for the score sheet and inventory
+func (p *Player) describeLoad() string {
+	load := fmt.Sprintf("%d/%d lbs", p.CarriedWeight(), p.CarryCapacity())
+	if p.IsEncumbered() {
+		load += " {Y}(encumbered){x}"
+	}
+	return load
+}
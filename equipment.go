@@ -0,0 +1,301 @@
+/*
+ * equipment.go
+ *
+ * This file implements worn equipment and item sets. Items with a wear slot
+ * can be worn with the 'wear' command and taken off with 'remove'. Items may
+ * also belong to a set defined in an area file; wearing several pieces of the
+ * same set grants escalating bonuses. Set bonuses are re-evaluated whenever
+ * the player's equipment changes and are listed on the score sheet.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Wear slots, in the order they are listed by the 'equipment' command
+var WearSlots = []string{"head", "neck", "body", "arms", "hands", "waist", "legs", "feet", "wield", "hold"}
+
+// wearSlotLabels describes each slot in equipment listings
+var wearSlotLabels = map[string]string{
+	"head":  "<worn on head>",
+	"neck":  "<worn around neck>",
+	"body":  "<worn on body>",
+	"arms":  "<worn on arms>",
+	"hands": "<worn on hands>",
+	"waist": "<worn about waist>",
+	"legs":  "<worn on legs>",
+	"feet":  "<worn on feet>",
+	"wield": "<wielded>",
+	"hold":  "<held>",
+}
+
+// ItemSet is a group of items that grant bonuses when worn together
+type ItemSet struct {
+	ID      string     `yaml:"-"`
+	Name    string     `yaml:"name"`
+	Bonuses []SetBonus `yaml:"bonuses"` // Tiers of bonuses, each unlocked at a piece count
+}
+
+// SetBonus is one tier of a set's bonuses
+type SetBonus struct {
+	Pieces    int            `yaml:"pieces"`    // Number of pieces that must be worn
+	Modifiers map[string]int `yaml:"modifiers"` // Same keys as affect modifiers
+}
+
+// ActiveSetBonus is a set the player is wearing enough of to earn a bonus
+type ActiveSetBonus struct {
+	Set       *ItemSet
+	Pieces    int            // Pieces currently worn
+	Modifiers map[string]int // Combined modifiers of every unlocked tier
+}
+
+// Global item set registry
+var (
+	itemSetRegistry = make(map[string]*ItemSet)
+	itemSetMutex    sync.RWMutex
+)
+
+// RegisterItemSet adds an item set to the registry
+func RegisterItemSet(set *ItemSet) {
+	// Tiers are unlocked from the fewest pieces up
+	sort.Slice(set.Bonuses, func(i, j int) bool {
+		return set.Bonuses[i].Pieces < set.Bonuses[j].Pieces
+	})
+
+	itemSetMutex.Lock()
+	defer itemSetMutex.Unlock()
+
+	itemSetRegistry[set.ID] = set
+}
+
+// GetItemSet returns the item set with the given ID, or nil if it doesn't exist
+func GetItemSet(id string) *ItemSet {
+	itemSetMutex.RLock()
+	defer itemSetMutex.RUnlock()
+
+	return itemSetRegistry[id]
+}
+
+// isWearSlot reports whether slot is a known wear slot
+func isWearSlot(slot string) bool {
+	_, ok := wearSlotLabels[slot]
+	return ok
+}
+
+// Modifier returns the total change affects and set bonuses make to the given key
+func (p *Player) Modifier(key string) int {
+	total := p.Affects.Modifier(key)
+	for _, bonus := range p.SetBonuses {
+		total += bonus.Modifiers[key]
+	}
+	if p.Riding() != nil {
+		total += mountModifiers[key]
+	}
+	return total
+}
+
+// UpdateSetBonuses recalculates the player's set bonuses from their worn equipment
+// It returns messages telling the player about any tiers gained or lost.
+func (p *Player) UpdateSetBonuses() []string {
+	previous := make(map[string]int)
+	for _, bonus := range p.SetBonuses {
+		previous[bonus.Set.ID] = unlockedTiers(bonus.Set, bonus.Pieces)
+	}
+
+	p.SetBonuses = calculateSetBonuses(p.Equipment)
+	p.UpdateDerivedStats()
+
+	var messages []string
+	for _, bonus := range p.SetBonuses {
+		tiers := unlockedTiers(bonus.Set, bonus.Pieces)
+		if tiers > previous[bonus.Set.ID] {
+			messages = append(messages, fmt.Sprintf("{Y}You feel the power of %s grow stronger. (%d pieces){x}", bonus.Set.Name, bonus.Pieces))
+		} else if tiers < previous[bonus.Set.ID] {
+			messages = append(messages, fmt.Sprintf("{Y}The power of %s weakens. (%d pieces){x}", bonus.Set.Name, bonus.Pieces))
+		}
+		delete(previous, bonus.Set.ID)
+	}
+
+	// Sets no longer worn in large enough numbers to grant anything
+	for id := range previous {
+		messages = append(messages, fmt.Sprintf("{Y}The power of %s fades.{x}", GetItemSet(id).Name))
+	}
+
+	return messages
+}
+
+// calculateSetBonuses returns the bonuses earned by the given equipment
+// Tiers are cumulative: every tier unlocked adds its modifiers.
+func calculateSetBonuses(equipment map[string]*Item) []ActiveSetBonus {
+	counts := make(map[string]int)
+	for _, item := range equipment {
+		if item.Set != "" {
+			counts[item.Set]++
+		}
+	}
+
+	var bonuses []ActiveSetBonus
+	for id, pieces := range counts {
+		set := GetItemSet(id)
+		if set == nil || unlockedTiers(set, pieces) == 0 {
+			continue
+		}
+
+		modifiers := make(map[string]int)
+		for _, tier := range set.Bonuses {
+			if pieces < tier.Pieces {
+				break
+			}
+			for key, value := range tier.Modifiers {
+				modifiers[key] += value
+			}
+		}
+		bonuses = append(bonuses, ActiveSetBonus{Set: set, Pieces: pieces, Modifiers: modifiers})
+	}
+
+	sort.Slice(bonuses, func(i, j int) bool {
+		return bonuses[i].Set.Name < bonuses[j].Set.Name
+	})
+	return bonuses
+}
+
+// unlockedTiers returns how many of a set's bonus tiers the given piece count unlocks
+func unlockedTiers(set *ItemSet, pieces int) int {
+	tiers := 0
+	for _, tier := range set.Bonuses {
+		if pieces >= tier.Pieces {
+			tiers++
+		}
+	}
+	return tiers
+}
+
+// SetBonusSummary describes the player's set bonuses for the score sheet
+func (p *Player) SetBonusSummary() []string {
+	var lines []string
+	for _, bonus := range p.SetBonuses {
+		line := fmt.Sprintf("%s (%d pieces)", bonus.Set.Name, bonus.Pieces)
+		if mods := describeModifiers(bonus.Modifiers); mods != "" {
+			line += " " + mods
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// HasItem reports whether the player is carrying or wearing an item with the given vnum
+func (p *Player) HasItem(vnum int) bool {
+	for _, item := range p.Inventory {
+		if item.ID == vnum {
+			return true
+		}
+	}
+	for _, item := range p.Equipment {
+		if item.ID == vnum {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWear processes the wear command
+func handleWear(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Wear what?"
+	}
+
+	item := FindItemInList(player.Inventory, strings.ToLower(strings.Join(args, " ")))
+	if item == nil {
+		return "You do not have that item."
+	}
+	if item.WearSlot == "" {
+		return fmt.Sprintf("You can't wear %s.", item.Name())
+	}
+	if item.WearSlot == "wield" && player.Disarmed > 0 {
+		return "Your weapon hand is still numb from being disarmed."
+	}
+
+	var output string
+	if worn := player.Equipment[item.WearSlot]; worn != nil {
+		player.Inventory = append(player.Inventory, worn)
+		output = fmt.Sprintf("You stop using %s.\r\n", worn.Name())
+	}
+
+	if player.Equipment == nil {
+		player.Equipment = make(map[string]*Item)
+	}
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	player.Equipment[item.WearSlot] = item
+
+	verb := "wear"
+	switch item.WearSlot {
+	case "wield":
+		verb = "wield"
+	case "hold":
+		verb = "hold"
+	}
+	BroadcastToRoom(fmt.Sprintf("$n %ss %s.", verb, item.ShortDescription), player.Room, player)
+	output += fmt.Sprintf("You %s %s.", verb, item.Name())
+	if message := item.BindTo(player, BindOnEquip); message != "" {
+		output += "\r\n" + message
+	}
+
+	for _, message := range player.UpdateSetBonuses() {
+		output += "\r\n" + message
+	}
+	return output
+}
+
+// handleRemove processes the remove command
+func handleRemove(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Remove what?"
+	}
+
+	target := strings.ToLower(strings.Join(args, " "))
+	for _, slot := range WearSlots {
+		item := player.Equipment[slot]
+		if item == nil || FindItemInList([]*Item{item}, target) == nil {
+			continue
+		}
+
+		delete(player.Equipment, slot)
+		player.Inventory = append(player.Inventory, item)
+
+		BroadcastToRoom(fmt.Sprintf("$n stops using %s.", item.ShortDescription), player.Room, player)
+		output := fmt.Sprintf("You stop using %s.", item.Name())
+
+		for _, message := range player.UpdateSetBonuses() {
+			output += "\r\n" + message
+		}
+		return output
+	}
+
+	return "You are not wearing that."
+}
+
+// handleEquipment lists the items the player is wearing
+func handleEquipment(player *Player, args []string) string {
+	var sb strings.Builder
+	sb.WriteString("You are using:\r\n")
+
+	count := 0
+	for _, slot := range WearSlots {
+		item := player.Equipment[slot]
+		if item == nil {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %-20s %s\r\n", wearSlotLabels[slot], item.ListName()))
+		count++
+	}
+	if count == 0 {
+		sb.WriteString("  Nothing.\r\n")
+	}
+
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
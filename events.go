@@ -0,0 +1,63 @@
+/*
+ * events.go
+ *
+ * This file implements the event bus that lets subsystems react to what
+ * happens in the game without the code where it happens calling each of
+ * them. Combat, movement, and leveling publish events such as a mob being
+ * killed or a player entering a room, and achievements, ambushes, mob
+ * programs, and area scripts subscribe to the ones they care about when
+ * the server starts. Handlers run in the order they subscribed, on the
+ * goroutine that published the event, before Publish returns.
+ */
+
+package main
+
+import "sync"
+
+// EventType names something that happened in the game
+type EventType string
+
+// Game events
+const (
+	EventPlayerDied  EventType = "player_died"  // A player was killed, by a mob if Mob is set
+	EventMobKilled   EventType = "mob_killed"   // A player, or their follower, killed a mob
+	EventLevelUp     EventType = "level_up"     // A player gained a level
+	EventRoomEntered EventType = "room_entered" // A player arrived in a room
+)
+
+// Event describes something that happened; fields that don't apply to its type are left empty
+type Event struct {
+	Type      EventType
+	Player    *Player      // The player it happened to
+	Mob       *MobInstance // The mob slain, or the one that killed the player
+	Killer    *MobInstance // The follower that struck the killing blow, if the player didn't
+	Room      *Room        // Where it happened
+	Direction string       // The way the player walked into the room ("" if they were moved there)
+}
+
+// EventHandler reacts to a published event
+type EventHandler func(Event)
+
+var (
+	eventHandlers = make(map[EventType][]EventHandler) // Subscribers, keyed by the event they handle
+	eventsMutex   sync.RWMutex
+)
+
+// Subscribe registers a handler to run each time an event of the given type is published
+func Subscribe(eventType EventType, handler EventHandler) {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+	eventHandlers[eventType] = append(eventHandlers[eventType], handler)
+}
+
+// Publish runs the handlers subscribed to an event
+func Publish(event Event) {
+	// Copy the handlers so one may subscribe another without deadlocking
+	eventsMutex.RLock()
+	handlers := append([]EventHandler(nil), eventHandlers[event.Type]...)
+	eventsMutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * filter.go
+ *
+ * This file implements the word filter. Operators list words they don't
+ * want seen in config.yml. Those words are masked with asterisks when a
+ * player says them or sends them over a channel, and titles and new
+ * character names containing them are refused. Color codes slipped
+ * between the letters of a word don't get it past the filter. Staff
+ * aren't filtered, and can clear another player's offensive title with
+ * 'untitle'.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	wordFilter     *regexp.Regexp // Matches any filtered word, or nil if there are none
+	wordFilterOnce sync.Once
+)
+
+// filterPattern returns the compiled word filter, building it from the configured words on first use
+func filterPattern() *regexp.Regexp {
+	wordFilterOnce.Do(func() {
+		var words []string
+		for _, word := range config.Filter.Words {
+			// Allow color codes between the letters
+			var letters []string
+			for _, r := range strings.TrimSpace(word) {
+				letters = append(letters, regexp.QuoteMeta(string(r)))
+			}
+			if len(letters) > 0 {
+				words = append(words, strings.Join(letters, `(?:\{.\})*`))
+			}
+		}
+		if len(words) > 0 {
+			wordFilter = regexp.MustCompile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)
+		}
+	})
+	return wordFilter
+}
+
+// wordFilterActive reports whether the filter applies to what a player writes
+func wordFilterActive(player *Player) bool {
+	return config.Filter.Enabled && (player == nil || !player.Staff) && filterPattern() != nil
+}
+
+// FilterText masks any filtered words in text a player wrote
+func FilterText(player *Player, text string) string {
+	if !wordFilterActive(player) {
+		return text
+	}
+	return filterPattern().ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len(StripColorCodes(word)))
+	})
+}
+
+// ContainsFilteredWord reports whether text a player wrote contains a filtered word
+func ContainsFilteredWord(player *Player, text string) bool {
+	return wordFilterActive(player) && filterPattern().MatchString(text)
+}
+
+// FilteredName reports whether a new character's name contains a filtered word anywhere in it
+func FilteredName(name string) bool {
+	if !config.Filter.Enabled {
+		return false
+	}
+	for _, word := range config.Filter.Words {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(strings.TrimSpace(word))) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleUntitle removes an offensive title from a player (staff only)
+// Usage: untitle <player>
+func handleUntitle(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Untitle whom?"
+	}
+
+	name, ok := resolvePlayerName(args[0])
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", args[0])
+	}
+	if err := UpdatePlayerTitle(name, ""); err != nil {
+		log.Printf("Error clearing the title of %s: %v", name, err)
+		return "Error clearing that title."
+	}
+	if target := FindPlayerByName(name); target != nil {
+		target.Title = ""
+		target.Send("{R}Your title has been removed by the staff.{x}")
+	}
+
+	log.Printf("%s cleared the title of %s", player.Name, name)
+	return fmt.Sprintf("%s's title has been cleared.", name)
+}
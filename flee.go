@@ -0,0 +1,206 @@
+/*
+ * flee.go
+ *
+ * This file implements fleeing and pursuit. Players who flee escape through
+ * a random open exit. Mobs make a morale check each round once their health
+ * drops below their wimpy threshold and may run away through an open exit
+ * instead of fighting to the death. Mobs flagged to pursue follow a player
+ * who flees from them into the next room, hunting them for a few pulses
+ * before giving up.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+// Flee and pursuit tuning
+const (
+	DefaultMobWimpy  = 20 // Percent of max HP below which mobs start checking morale
+	MobFleeChance    = 40 // Percent chance per round that a mob below its wimpy threshold flees
+	MobPursuitPulses = 6  // Pulses a mob keeps hunting a player who fled from it
+)
+
+// wimpyThreshold returns the percent of max HP below which the mob may flee
+func (m *MobInstance) wimpyThreshold() int {
+	if m.Fearless {
+		return 0
+	}
+	if m.Wimpy > 0 {
+		return m.Wimpy
+	}
+	return DefaultMobWimpy
+}
+
+// CheckMorale reports whether a wounded mob loses its nerve this round
+func (m *MobInstance) CheckMorale() bool {
+	if m.MaxHP <= 0 || m.HP <= 0 {
+		return false
+	}
+	if m.HP*100 >= m.MaxHP*m.wimpyThreshold() {
+		return false
+	}
+	return rng.Intn(100) < MobFleeChance
+}
+
+// openExits returns the directions out of a room that aren't blocked by a closed door
+func openExits(room *Room) []string {
+	var exits []string
+	for dir, exit := range room.Exits {
+		if exit.Door != nil && exit.Door.Closed {
+			continue
+		}
+		exits = append(exits, dir)
+	}
+	return exits
+}
+
+// exitTo returns the direction of an open exit leading from one room to another, or ""
+func exitTo(from, to *Room) string {
+	for _, dir := range openExits(from) {
+		if id, err := ResolveExitRoomID(from.Exits[dir]); err == nil && id == to.ID {
+			return dir
+		}
+	}
+	return ""
+}
+
+// mobFighters returns the players currently fighting a mob
+func mobFighters(mob *MobInstance) []*Player {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	var fighters []*Player
+	for _, p := range activePlayers {
+		if p.IsInCombat() && p.Target == mob {
+			fighters = append(fighters, p)
+		}
+	}
+	return fighters
+}
+
+// MobFlee makes a mob try to run out of the room, ending its fights
+// Returns false if the mob had nowhere to go and keeps fighting.
+func MobFlee(mob *MobInstance) bool {
+	room := mob.Room
+	if room == nil {
+		return false
+	}
+
+	// MoveMob refuses to move a mob that is in combat, so break off the fight first
+	fighters := mobFighters(mob)
+	for _, p := range fighters {
+		p.ExitCombat()
+	}
+
+	exits := openExits(room)
+	rng.Shuffle(len(exits), func(i, j int) { exits[i], exits[j] = exits[j], exits[i] })
+
+	BroadcastToRoom(fmt.Sprintf("{Y}%s panics and attempts to flee!{x}", capitalizeFirst(mob.ShortDescription)), room, nil)
+	for _, dir := range exits {
+		if err := MoveMob(mob, dir); err == nil {
+			mob.Pursuing = nil
+			for _, p := range fighters {
+				p.SendStatus()
+			}
+			return true
+		}
+	}
+
+	// Cornered: the fight goes on
+	BroadcastToRoom(fmt.Sprintf("%s has nowhere to run!", capitalizeFirst(mob.ShortDescription)), room, nil)
+	for _, p := range fighters {
+		p.EnterCombat(mob)
+	}
+	return false
+}
+
+// handleFlee processes a player's attempt to escape from combat
+func handleFlee(player *Player, args []string) string {
+	if player.Opponent != nil {
+		return fleePvP(player)
+	}
+
+	mob := player.Target
+	room := player.Room
+
+	// Pick a random way out that the player is allowed to take
+	var exits []string
+	for _, dir := range openExits(room) {
+		if CheckExitRequirement(player, room.Exits[dir].Requires) == nil && exitGuard(player, room, dir) == nil {
+			exits = append(exits, dir)
+		}
+	}
+	if len(exits) == 0 {
+		return "There's nowhere to run!"
+	}
+	direction := exits[rng.Intn(len(exits))]
+
+	// Exit combat BEFORE broadcasting to avoid deadlocks
+	player.ExitCombat()
+
+	BroadcastCombatMessage(fmt.Sprintf("%s flees from %s!", player.Name, mob.ShortDescription), room, player)
+	player.Send(fmt.Sprintf("You flee from %s!", mob.ShortDescription))
+
+	if err := HandleMovement(player, direction); err != nil {
+		return err.Error()
+	}
+
+	// Mobs that pursue give chase
+	if mob.Pursues && mob.HP > 0 {
+		mob.Pursuing = player
+		mob.PursuitPulses = MobPursuitPulses
+	}
+
+	return ""
+}
+
+// ProcessMobPursuit moves pursuing mobs after the players who fled from them
+func ProcessMobPursuit() {
+	mobMutex.RLock()
+	var hunters []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.Pursuing != nil {
+			hunters = append(hunters, mob)
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range hunters {
+		pursueTarget(mob)
+	}
+}
+
+// pursueTarget advances one mob's hunt for the player it is pursuing
+func pursueTarget(mob *MobInstance) {
+	target := mob.Pursuing
+	mob.PursuitPulses--
+
+	// Give up once time runs out or the target is gone
+	if mob.PursuitPulses < 0 || mob.HP <= 0 || mob.Room == nil || target.IsDead || FindPlayerByName(target.Name) != target || !MobCanSeePlayer(mob, target) {
+		mob.Pursuing = nil
+		return
+	}
+
+	if target.Room != mob.Room {
+		// Follow the target if they are in the next room, otherwise wait for them to show up
+		if direction := exitTo(mob.Room, target.Room); direction != "" {
+			if err := MoveMob(mob, direction); err != nil {
+				mob.Pursuing = nil
+			}
+		}
+		return
+	}
+
+	// Caught up: attack unless someone else is already fighting either of them
+	mob.Pursuing = nil
+	if target.IsInCombat() || IsMobInCombat(mob) {
+		return
+	}
+	target.CancelCamp("You are attacked and stop making camp!")
+	target.EnterCombat(mob)
+	target.Send(fmt.Sprintf("{R}%s pursues you and attacks!{x}", capitalizeFirst(mob.ShortDescription)))
+	BroadcastCombatMessage(fmt.Sprintf("%s pursues %s and attacks!", capitalizeFirst(mob.ShortDescription), target.Name), target.Room, target)
+	target.SendStatus()
+}
@@ -0,0 +1,77 @@
+/*
+ * flood.go
+ *
+ * This file implements flood protection on player input. Each connection
+ * has a token bucket: it holds a burst's worth of commands and refills at
+ * a steady rate, and every line the player sends takes one token. A line
+ * that arrives when the bucket is empty is thrown away without being run,
+ * and the player is told to slow down the first time it happens. A client
+ * that keeps flooding long after being told is disconnected. This keeps
+ * a runaway client or script from starving the pulse loop or spamming the
+ * OOC channel. The limits are set under 'flood' in config.yml.
+ */
+
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// FloodConfig holds the limits on how fast players may send input
+type FloodConfig struct {
+	CommandsPerSecond int `yaml:"commands_per_second"` // Rate the bucket refills at (0 = no limit)
+	Burst             int `yaml:"burst"`               // Commands that can be sent at once on a full bucket
+	DisconnectAfter   int `yaml:"disconnect_after"`    // Commands thrown away in a row before the client is disconnected (0 = never)
+	MaxInputLength    int `yaml:"max_input_length"`    // Longest line accepted; anything past it is discarded
+}
+
+// floodBucket is the token bucket limiting one connection's input
+type floodBucket struct {
+	tokens  float64   // Commands the player may send right now
+	last    time.Time // When the bucket was last refilled
+	dropped int       // Commands thrown away since the last one that was let through
+}
+
+// allow takes a token for a line of input, reporting whether the line may be run
+func (b *floodBucket) allow(now time.Time) bool {
+	limits := config.Flood
+	if limits.CommandsPerSecond == 0 {
+		return true
+	}
+
+	burst := float64(max(limits.Burst, 1))
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*float64(limits.CommandsPerSecond), burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		b.dropped++
+		return false
+	}
+	b.tokens--
+	b.dropped = 0
+	return true
+}
+
+// CheckFlood reports whether a line of input the player sent may be run
+// A player who is flooding is warned once, and disconnected if they keep it up;
+// the second result reports whether they should be.
+func (p *Player) CheckFlood() (allowed, disconnect bool) {
+	if p.flood.allow(time.Now()) {
+		return true, false
+	}
+
+	if limit := config.Flood.DisconnectAfter; limit > 0 && p.flood.dropped >= limit {
+		log.Printf("Disconnecting %s from %s for flooding (%d commands thrown away)", p.Name, p.Conn.RemoteAddr(), p.flood.dropped)
+		p.Send("{R}You have been disconnected for flooding.{x}")
+		return false, true
+	}
+	if p.flood.dropped == 1 {
+		p.Send("{R}You are sending commands too quickly. Slow down; commands are being ignored.{x}")
+	}
+	return false, false
+}
@@ -0,0 +1,465 @@
+/*
+ * follower.go
+ *
+ * This file implements charmed followers. A mob charmed by a player follows
+ * them from room to room until the charm wears off, and can be given orders
+ * with the 'order' command. Followers only understand a short list of
+ * commands, which are run through a small mob-side command interpreter:
+ * moving, attacking another mob, assisting its master in a fight, and
+ * guarding a player. A follower guarding someone joins any fight they get
+ * into. Pets bought from a pet shop (see pet.go) are followers too.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Charm tuning
+const (
+	CharmAffect  = "charm" // Name of the affect that binds a follower to its master
+	MaxFollowers = 2       // Most charmed followers a player may have at once
+)
+
+// MobCommandHandler runs an order given to a follower and returns the reply for its master
+type MobCommandHandler func(mob *MobInstance, master *Player, args []string) string
+
+// mobCommandHandlers is the whitelist of commands a follower will obey
+var mobCommandHandlers = map[string]MobCommandHandler{
+	"move":   mobMove,
+	"north":  mobMove,
+	"south":  mobMove,
+	"east":   mobMove,
+	"west":   mobMove,
+	"up":     mobMove,
+	"down":   mobMove,
+	"n":      mobMove,
+	"s":      mobMove,
+	"e":      mobMove,
+	"w":      mobMove,
+	"u":      mobMove,
+	"d":      mobMove,
+	"attack": mobAttack,
+	"kill":   mobAttack,
+	"guard":  mobGuard,
+	"assist": mobAssist,
+}
+
+// IsFollowing reports whether the mob is a charmed follower of the player
+func (m *MobInstance) IsFollowing(p *Player) bool {
+	return m.Master != nil && m.Master == p
+}
+
+// Followers returns the mobs currently following the player
+func (p *Player) Followers() []*MobInstance {
+	mobMutex.RLock()
+	defer mobMutex.RUnlock()
+
+	var followers []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.IsFollowing(p) {
+			followers = append(followers, mob)
+		}
+	}
+	return followers
+}
+
+// Charm makes the mob follow the player for the given number of ticks
+func (m *MobInstance) Charm(master *Player, duration int) {
+	m.Master = master
+	m.Pursuing = nil
+	m.HomeArea = "" // Followers go wherever their master leads
+	m.ApplyAffect(&Affect{
+		Name:          CharmAffect,
+		Duration:      duration,
+		Level:         master.Level,
+		Magical:       true,
+		ApplyMessage:  fmt.Sprintf("$n gazes at %s with adoring eyes.", master.Name),
+		ExpireMessage: "$n blinks and looks around, confused.",
+	})
+}
+
+// ReleaseFollower frees a mob from its master's control
+// The mob settles into the area it was released in.
+func ReleaseFollower(m *MobInstance) {
+	master := m.Master
+	if master == nil {
+		return
+	}
+
+	m.Master = nil
+	m.Pet = false
+	m.Guarding = nil
+	m.Fighting = nil
+	m.Affects.Remove(CharmAffect)
+	if m.Room != nil {
+		m.HomeArea = m.Room.Area
+	}
+	master.Send(fmt.Sprintf("%s stops following you.", capitalizeFirst(m.ShortDescription)))
+}
+
+// ReleaseFollowers frees every mob following the player
+func (p *Player) ReleaseFollowers() {
+	for _, mob := range p.Followers() {
+		ReleaseFollower(mob)
+	}
+}
+
+// MoveFollowers brings the player's followers along when they leave a room
+func (p *Player) MoveFollowers(from *Room, direction string) {
+	for _, mob := range p.Followers() {
+		if mob.Room != from || mob.Fighting != nil {
+			continue
+		}
+		MoveMob(mob, direction)
+	}
+}
+
+// answersTo reports whether one of the mob's keywords starts with name
+func (m *MobInstance) answersTo(name string) bool {
+	for _, keyword := range m.Keywords {
+		if strings.HasPrefix(strings.ToLower(keyword), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterpretMobCommand runs a command on behalf of a follower if it is on the whitelist
+func InterpretMobCommand(mob *MobInstance, master *Player, input string) string {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) == 0 {
+		return "Order it to do what?"
+	}
+
+	handler, ok := mobCommandHandlers[fields[0]]
+	if !ok {
+		return fmt.Sprintf("%s doesn't understand.", capitalizeFirst(mob.ShortDescription))
+	}
+
+	// Directions given on their own are moves
+	args := fields[1:]
+	if isDirection(fields[0]) || DirectionAliases[fields[0]] != "" {
+		args = fields[:1]
+	}
+	return handler(mob, master, args)
+}
+
+// isDirection reports whether word is a full direction name
+func isDirection(word string) bool {
+	return stringInSlice(word, Directions)
+}
+
+// mobMove orders a follower through an exit
+func mobMove(mob *MobInstance, master *Player, args []string) string {
+	if len(args) == 0 {
+		return "Move which way?"
+	}
+	direction := args[0]
+	if full, ok := DirectionAliases[direction]; ok {
+		direction = full
+	}
+	if mob.Fighting != nil {
+		return fmt.Sprintf("%s is busy fighting!", capitalizeFirst(mob.ShortDescription))
+	}
+	if err := MoveMob(mob, direction); err != nil {
+		return fmt.Sprintf("%s can't go that way.", capitalizeFirst(mob.ShortDescription))
+	}
+	return "Ok."
+}
+
+// mobAttack orders a follower to attack another mob in its room
+func mobAttack(mob *MobInstance, master *Player, args []string) string {
+	if len(args) == 0 {
+		return "Attack what?"
+	}
+
+	target := FindMobByTarget(mob.Room.ID, strings.Join(args, " "))
+	if target == nil || target.HP <= 0 {
+		return fmt.Sprintf("%s doesn't see that here.", capitalizeFirst(mob.ShortDescription))
+	}
+	if target == mob || target.IsFollowing(master) {
+		return fmt.Sprintf("%s refuses to attack a friend.", capitalizeFirst(mob.ShortDescription))
+	}
+	ok, notice := master.mayAttack(target)
+	if !ok {
+		return notice
+	}
+	if notice != "" {
+		master.Send(notice)
+	}
+
+	mob.Fighting = target
+	tagMob(target, master)
+	BroadcastCombatMessage(fmt.Sprintf("%s attacks %s!", capitalizeFirst(mob.ShortDescription), target.ShortDescription), mob.Room, nil)
+	return "Ok."
+}
+
+// mobAssist orders a follower to join the fight its master is in
+func mobAssist(mob *MobInstance, master *Player, args []string) string {
+	target := master.Target
+	if !master.IsInCombat() || target == nil || target.Room != mob.Room || target.HP <= 0 {
+		return fmt.Sprintf("%s sees no fight to help you with.", capitalizeFirst(mob.ShortDescription))
+	}
+	if mob.Fighting != nil {
+		return fmt.Sprintf("%s is busy fighting!", capitalizeFirst(mob.ShortDescription))
+	}
+	if target == mob {
+		return fmt.Sprintf("%s refuses to attack itself.", capitalizeFirst(mob.ShortDescription))
+	}
+
+	mob.Fighting = target
+	BroadcastCombatMessage(fmt.Sprintf("%s leaps to assist %s!", capitalizeFirst(mob.ShortDescription), master.Name), mob.Room, nil)
+	return "Ok."
+}
+
+// mobGuard orders a follower to guard a player, or its master if no one is named
+func mobGuard(mob *MobInstance, master *Player, args []string) string {
+	ward := master
+	if len(args) > 0 {
+		ward = master.FindVisiblePlayerInRoom(args[0])
+		if ward == nil || ward.Room != mob.Room {
+			return fmt.Sprintf("%s doesn't see them here.", capitalizeFirst(mob.ShortDescription))
+		}
+	}
+
+	mob.Guarding = ward
+	if ward != master {
+		ward.Send(fmt.Sprintf("%s begins guarding you.", capitalizeFirst(mob.ShortDescription)))
+	}
+	return fmt.Sprintf("%s begins guarding %s.", capitalizeFirst(mob.ShortDescription), pronounOrName(master, ward))
+}
+
+// pronounOrName refers to the ward as "you" when it's the player being spoken to
+func pronounOrName(viewer, ward *Player) string {
+	if viewer == ward {
+		return "you"
+	}
+	return ward.Name
+}
+
+// handleOrder processes the order command
+// Usage: order <follower|all> <command>
+func handleOrder(player *Player, args []string) string {
+	if len(args) < 2 {
+		return "Usage: order <follower|all> <command>"
+	}
+
+	followers := player.Followers()
+	if len(followers) == 0 {
+		return "You have no followers to order around."
+	}
+
+	target := strings.ToLower(args[0])
+	command := strings.Join(args[1:], " ")
+
+	var ordered []*MobInstance
+	for _, mob := range followers {
+		if mob.Room != player.Room {
+			continue
+		}
+		if target == "all" || mob.answersTo(target) {
+			ordered = append(ordered, mob)
+		}
+	}
+	if len(ordered) == 0 {
+		return "None of your followers here answer to that."
+	}
+
+	BroadcastToRoom("$n issues an order.", player.Room, player)
+
+	var replies []string
+	for _, mob := range ordered {
+		replies = append(replies, InterpretMobCommand(mob, player, command))
+	}
+	return strings.Join(replies, "\r\n")
+}
+
+// handleFollowers lists the player's charmed followers
+func handleFollowers(player *Player, args []string) string {
+	followers := player.Followers()
+	if len(followers) == 0 {
+		return "No one is following you."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your followers:\r\n")
+	for _, mob := range followers {
+		where := mob.Room.Name
+		if mob.Room == player.Room {
+			where = "here"
+		}
+		line := fmt.Sprintf("  %s (%s)", capitalizeFirst(mob.ShortDescription), where)
+		if mob.Pet {
+			line += ", your pet"
+		}
+		if mob.Guarding != nil {
+			line += fmt.Sprintf(", guarding %s", pronounOrName(player, mob.Guarding))
+		}
+		sb.WriteString(line + "\r\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// castCharm tries to charm a mob into following the player
+// It reports whether the spell was cast, so a bad target costs no mana.
+func castCharm(player *Player, targetName string) (string, bool) {
+	if targetName == "" {
+		return "Charm whom?", false
+	}
+	mob := FindMobByTarget(player.Room.ID, strings.ToLower(targetName))
+	if mob == nil || mob.HP <= 0 {
+		return "You don't see that here.", false
+	}
+	switch {
+	case mob.Master != nil:
+		return fmt.Sprintf("%s already follows someone.", capitalizeFirst(mob.ShortDescription)), false
+	case mob.IsMerchant() && !IsMobInCombat(mob):
+		// Merchants won't follow anyone, but can be talked into a better price
+		return charmMerchant(player, mob), true
+	case mob.Level > player.Level:
+		return fmt.Sprintf("%s is too powerful for you to charm.", capitalizeFirst(mob.ShortDescription)), false
+	case IsMobInCombat(mob):
+		return fmt.Sprintf("%s is too busy fighting to listen.", capitalizeFirst(mob.ShortDescription)), false
+	case len(player.Followers()) >= MaxFollowers:
+		return "You can't control any more followers.", false
+	}
+
+	BroadcastToRoom("$n utters the words, 'charm'.", player.Room, player)
+
+	// Weaker mobs are easier to charm
+	chance := 50 + (player.Level-mob.Level)*10 + player.Stat(ApplyPRE)
+	if rng.Intn(100) >= chance {
+		player.CancelCamp("You are attacked and stop making camp!")
+		player.EnterCombat(mob)
+		player.SendStatus()
+		return fmt.Sprintf("{R}%s resists your charm and attacks you!{x}", capitalizeFirst(mob.ShortDescription)), true
+	}
+
+	mob.Charm(player, 10+player.Level)
+	return fmt.Sprintf("%s now follows you.", capitalizeFirst(mob.ShortDescription)), true
+}
+
+// ProcessFollowers runs a round of combat for followers that are fighting
+// or guarding, and releases followers whose master has gone
+func ProcessFollowers() {
+	mobMutex.RLock()
+	var followers []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.Master != nil {
+			followers = append(followers, mob)
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range followers {
+		master := mob.Master
+		if master.IsDead || FindPlayerByName(master.Name) != master {
+			ReleaseFollower(mob)
+			continue
+		}
+
+		// A guard joins any fight its ward is in
+		if ward := mob.Guarding; mob.Fighting == nil && ward != nil && ward.Target != nil && ward.IsInCombat() && ward.Room == mob.Room && ward.Target != mob {
+			mob.Fighting = ward.Target
+			BroadcastCombatMessage(fmt.Sprintf("%s leaps to %s's defense!", capitalizeFirst(mob.ShortDescription), ward.Name), mob.Room, nil)
+		}
+
+		if victim := mob.Fighting; victim != nil {
+			if victim.HP <= 0 || victim.Room != mob.Room {
+				mob.Fighting = nil
+				continue
+			}
+			followerRound(mob, victim)
+		}
+	}
+}
+
+// followerRound trades blows between a follower and the mob it is fighting
+func followerRound(mob, victim *MobInstance) {
+	mobStrike(mob, victim)
+	if victim.HP <= 0 {
+		followerKill(mob, victim)
+		return
+	}
+
+	mobStrike(victim, mob)
+	if mob.HP <= 0 {
+		followerSlain(mob)
+	}
+}
+
+// mobStrike makes one attack by a mob against another mob
+func mobStrike(attacker, defender *MobInstance) {
+	// A stunned mob can't strike back, and a practice dummy never does
+	if attacker.Stunned > 0 || attacker.Dummy {
+		return
+	}
+	room := attacker.Room
+	hitChance := CalculateHitChance(attacker.Level, defender.Level) + float64(attacker.Affects.Modifier(ApplyHitroll))/100
+	hitChance -= CombatHitPenalty(room)
+	hitChance += stateHitModifier(&attacker.CombatState, &defender.CombatState)
+	if rng.Float64() > hitChance {
+		BroadcastCombatMessage(fmt.Sprintf("%s misses %s.", capitalizeFirst(attacker.ShortDescription), defender.ShortDescription), room, nil)
+		return
+	}
+
+	damage := CalculateDamage(attacker.Level) + attacker.Affects.Modifier(ApplyDamroll)
+	if attacker.Disarmed > 0 {
+		damage /= 2
+	}
+	if damage < 1 {
+		damage = 1
+	}
+	if !defender.Dummy {
+		defender.HP -= damage
+	}
+	BroadcastCombatMessage(fmt.Sprintf("%s hits %s.", capitalizeFirst(attacker.ShortDescription), defender.ShortDescription), room, nil)
+}
+
+// followerKill handles a follower killing a mob; its master gets the experience
+func followerKill(mob, victim *MobInstance) {
+	room := victim.Room
+	// Whoever tagged the victim earns the kill, even if a follower finished it
+	holder := victim.TagHolder()
+	mob.Fighting = nil
+	if victim.Master != nil {
+		ReleaseFollower(victim)
+	}
+
+	BroadcastCombatMessage(fmt.Sprintf("%s has slain %s!", capitalizeFirst(mob.ShortDescription), victim.ShortDescription), room, nil)
+	if master := mob.Master; holder != nil && holder != master {
+		creditKill(holder, victim, capitalizeFirst(mob.ShortDescription))
+	} else if master != nil && master.Room == room {
+		FireDeathProgs(victim, master)
+		xpGain := CalculateXPGain(master.Level, victim.Level)
+		master.GainXP(xpGain)
+		master.Send(fmt.Sprintf("You gain {G}%s{x} experience points.", master.Locale.Number(xpGain)))
+		master.SendStatus()
+		Publish(Event{Type: EventMobKilled, Player: master, Mob: victim, Killer: mob, Room: room})
+	}
+
+	corpse := CreateMobCorpse(victim)
+	if holder != nil {
+		corpse.Looter = holder.Name
+	}
+	AddItemToRoom(corpse, room)
+	RemoveMobFromRoom(victim)
+}
+
+// followerSlain handles a follower dying in a fight
+func followerSlain(mob *MobInstance) {
+	room := mob.Room
+	if victim := mob.Fighting; victim != nil {
+		BroadcastCombatMessage(fmt.Sprintf("%s has slain %s!", capitalizeFirst(victim.ShortDescription), mob.ShortDescription), room, nil)
+	}
+	if mob.Master != nil {
+		mob.Master.Send(fmt.Sprintf("{R}Your follower, %s, has died.{x}", mob.ShortDescription))
+		mob.Master = nil
+	}
+
+	AddItemToRoom(CreateMobCorpse(mob), room)
+	RemoveMobFromRoom(mob)
+}
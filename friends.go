@@ -0,0 +1,140 @@
+/*
+ * friends.go
+ *
+ * This file implements the friends list. 'friend <player>' adds a player to
+ * the list, or takes them off it if they're already on it, and 'friends'
+ * shows who's on it and which of them are online. Players are told when a
+ * friend enters or leaves the realm, unless the friend is hidden from them,
+ * and on logging in they're told which friends are already here. The list
+ * is saved with the character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// MaxFriends is the most players a character can have on their friends list
+const MaxFriends = 50
+
+// HasFriend reports whether another player is on the player's friends list
+func (p *Player) HasFriend(other *Player) bool {
+	p.listsMu.Lock()
+	defer p.listsMu.Unlock()
+	return p.Friends[other.Name]
+}
+
+// friendNames returns the names on the player's friends list, sorted
+func (p *Player) friendNames() []string {
+	p.listsMu.Lock()
+	defer p.listsMu.Unlock()
+
+	names := make([]string, 0, len(p.Friends))
+	for name := range p.Friends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadFriends restores the player's friends list
+func (p *Player) LoadFriends() {
+	names, err := LoadPlayerFriends(p.Name)
+	if err != nil {
+		log.Printf("Error loading friends list for %s: %v", p.Name, err)
+	}
+
+	p.listsMu.Lock()
+	defer p.listsMu.Unlock()
+	p.Friends = make(map[string]bool)
+	for _, name := range names {
+		p.Friends[name] = true
+	}
+}
+
+// NotifyFriends tells everyone who counts the player as a friend that they've arrived or left
+// On arrival the player is also told which of their own friends are online.
+func NotifyFriends(player *Player, arrived bool) {
+	var online []string
+	for _, p := range otherPlayers(player) {
+		if arrived && player.HasFriend(p) && player.CanSeePlayer(p) {
+			online = append(online, p.Name)
+		}
+		if !p.HasFriend(player) || !p.CanSeePlayer(player) {
+			continue
+		}
+		if arrived {
+			p.Send(fmt.Sprintf("{G}Your friend %s has entered the realm.{x}", player.Name))
+		} else {
+			p.Send(fmt.Sprintf("{G}Your friend %s has left the realm.{x}", player.Name))
+		}
+	}
+
+	if len(online) > 0 {
+		sort.Strings(online)
+		player.Send(fmt.Sprintf("{G}Friends online: %s{x}", strings.Join(online, ", ")))
+	}
+}
+
+// handleFriends lists the player's friends and which of them are online
+func handleFriends(player *Player, args []string) string {
+	names := player.friendNames()
+	if len(names) == 0 {
+		return "Your friends list is empty. Use 'friend <player>' to add someone."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{C}Friends:{x}\r\n")
+	for _, name := range names {
+		if p := FindPlayerByName(name); p != nil && player.CanSeePlayer(p) {
+			sb.WriteString(fmt.Sprintf("  %-15s {G}online{x}\r\n", name))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %-15s {D}offline{x}\r\n", name))
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// handleFriend adds a player to the friends list, or takes them off it
+// Usage: friend <player>
+func handleFriend(player *Player, args []string) string {
+	if len(args) == 0 {
+		return handleFriends(player, args)
+	}
+
+	name, ok := resolvePlayerName(args[0])
+	if !ok {
+		return fmt.Sprintf("There is no one called %s.", capitalizeFirst(args[0]))
+	}
+	if name == player.Name {
+		return "You're already your own best friend."
+	}
+
+	player.listsMu.Lock()
+	if player.Friends == nil {
+		player.Friends = make(map[string]bool)
+	}
+	friend := player.Friends[name]
+	if !friend && len(player.Friends) >= MaxFriends {
+		player.listsMu.Unlock()
+		return fmt.Sprintf("You can't have more than %d friends on your list.", MaxFriends)
+	}
+	if friend {
+		delete(player.Friends, name)
+	} else {
+		player.Friends[name] = true
+	}
+	player.listsMu.Unlock()
+
+	if err := SetPlayerFriend(player.Name, name, !friend); err != nil {
+		log.Printf("Error saving friends list for %s: %v", player.Name, err)
+	}
+	if friend {
+		return fmt.Sprintf("You take %s off your friends list.", name)
+	}
+	return fmt.Sprintf("You add %s to your friends list.", name)
+}
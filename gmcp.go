@@ -0,0 +1,175 @@
+/*
+ * gmcp.go
+ *
+ * This file implements GMCP (Generic MUD Communication Protocol) support.
+ * Clients such as Mudlet that negotiate GMCP receive structured JSON packages
+ * describing the character's vitals, status, and current room, allowing them
+ * to draw health bars and maps without scraping the text prompt. Packages are
+ * sent on room changes, combat rounds, regeneration, and level-ups. The game
+ * time and the affects on the character follow the IRE conventions
+ * (IRE.Time and Char.Defences) that many client status bars already read.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// GMCPVitals is the payload of the Char.Vitals package
+type GMCPVitals struct {
+	HP         int    `json:"hp"`
+	MaxHP      int    `json:"maxhp"`
+	MP         int    `json:"mp"`
+	MaxMP      int    `json:"maxmp"`
+	Stamina    int    `json:"sp"`
+	MaxStamina int    `json:"maxsp"`
+	Hour       int    `json:"hour"`    // Hour of the game day
+	Weather    string `json:"weather"` // The weather the character can see, or "indoors"
+}
+
+// GMCPTime is the payload of the IRE.Time.Info and IRE.Time.Update packages
+type GMCPTime struct {
+	Hour     int    `json:"hour"`
+	Day      int    `json:"day"`   // Day of the month, from 1
+	Mon      int    `json:"mon"`   // Month of the year, from 1
+	Month    string `json:"month"` // Name of the month
+	Year     int    `json:"year"`
+	DayNight string `json:"daynight"` // "day" or "night"
+}
+
+// GMCPDefence is one affect in the Char.Defences.List package
+type GMCPDefence struct {
+	Name string `json:"name"`
+	Desc string `json:"desc"` // The affect's modifiers, such as "STR +2, HIT -5"
+}
+
+// GMCPStatus is the payload of the Char.Status package
+type GMCPStatus struct {
+	Name        string `json:"name"`
+	Race        string `json:"race"`
+	Class       string `json:"class"`
+	Level       int    `json:"level"`
+	XP          int    `json:"xp"`
+	NextLevelXP int    `json:"tnl"`
+	Gold        int    `json:"gold"`
+	Target      string `json:"target,omitempty"`
+}
+
+// GMCPRoomInfo is the payload of the Room.Info package
+type GMCPRoomInfo struct {
+	Num   int            `json:"num"`
+	Name  string         `json:"name"`
+	Area  string         `json:"area"`
+	Exits map[string]int `json:"exits"`
+}
+
+// SendGMCP sends a GMCP package to the player if their client supports it
+func (p *Player) SendGMCP(pkg string, data interface{}) {
+	tc, ok := p.Conn.(*TelnetConn)
+	if !ok || !tc.GMCPEnabled() {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding GMCP package %s: %v", pkg, err)
+		return
+	}
+
+	tc.SendSubnegotiation(telnetOptGMCP, append([]byte(pkg+" "), payload...))
+}
+
+// SendVitals sends the Char.Vitals package
+func (p *Player) SendVitals() {
+	p.SendGMCP("Char.Vitals", GMCPVitals{
+		HP:         p.HP,
+		MaxHP:      p.MaxHP,
+		MP:         p.MP,
+		MaxMP:      p.MaxMP,
+		Stamina:    p.Stamina,
+		MaxStamina: p.MaxStamina,
+		Hour:       CurrentTime().Hour,
+		Weather:    p.skyWeather(),
+	})
+}
+
+// SendTime sends the game time in an IRE.Time package: Info at login, Update as the hours pass
+func (p *Player) SendTime(pkg string) {
+	now := CurrentTime()
+	daynight := "day"
+	if IsNight() {
+		daynight = "night"
+	}
+	p.SendGMCP(pkg, GMCPTime{
+		Hour:     now.Hour,
+		Day:      now.Day + 1,
+		Mon:      now.Month + 1,
+		Month:    monthNames[now.Month],
+		Year:     now.Year + 1,
+		DayNight: daynight,
+	})
+}
+
+// SendDefences sends the Char.Defences.List package, listing the affects on the player
+func (p *Player) SendDefences() {
+	defences := make([]GMCPDefence, len(p.Affects))
+	for i, a := range p.Affects {
+		defences[i] = GMCPDefence{Name: a.Name, Desc: describeModifiers(a.Modifiers)}
+	}
+	p.SendGMCP("Char.Defences.List", defences)
+}
+
+// SendStatus sends the Char.Status package
+func (p *Player) SendStatus() {
+	status := GMCPStatus{
+		Name:        p.Name,
+		Race:        p.Race,
+		Class:       p.Class,
+		Level:       p.Level,
+		XP:          p.XP,
+		NextLevelXP: p.NextLevelXP,
+		Gold:        p.Gold,
+	}
+	if target := p.Target; target != nil {
+		status.Target = target.ShortDescription
+	} else if opp := p.Opponent; opp != nil {
+		status.Target = opp.Name
+	}
+	p.SendGMCP("Char.Status", status)
+}
+
+// SendRoomInfo sends the Room.Info package for the player's current room
+func (p *Player) SendRoomInfo() {
+	room := p.Room
+	if room == nil {
+		return
+	}
+
+	// Exits can't be seen in the dark
+	exits := make(map[string]int)
+	if p.CanSee() {
+		for direction, exit := range room.Exits {
+			if !p.CanSeeExit(exit) {
+				continue
+			}
+			if destID, err := ResolveExitRoomID(exit); err == nil {
+				exits[direction] = destID
+			}
+		}
+	}
+
+	// Prefer the area's display name over its file name
+	areaName := room.Area
+	if area := GetArea(room.Area); area != nil && area.Name != "" {
+		areaName = area.Name
+	}
+
+	p.SendGMCP("Room.Info", GMCPRoomInfo{
+		Num:   room.ID,
+		Name:  room.Name,
+		Area:  areaName,
+		Exits: exits,
+	})
+}
@@ -0,0 +1,129 @@
+/*
+ * guard.go
+ *
+ * This file implements mobs that guard an exit. A mob set to block an exit
+ * stops players from leaving its room that way for as long as it stands
+ * there. A player can get past by killing it, by bribing it with the 'bribe'
+ * command if it takes bribes, or by carrying the pass it's been told to let
+ * through. A guard that can't see the player, is busy fighting, or has been
+ * charmed into following someone doesn't stand in anyone's way.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExitGuard is an exit a mob stops players from taking
+type ExitGuard struct {
+	Direction string `yaml:"direction"`         // Exit the mob blocks
+	Message   string `yaml:"message,omitempty"` // Shown to a player who is turned back (default: a generic refusal)
+	Bribe     int    `yaml:"bribe,omitempty"`   // Gold that buys a player passage (0 = can't be bribed)
+	Pass      int    `yaml:"pass,omitempty"`    // Vnum of an item whose bearer is let through (0 = none)
+}
+
+// validateExitGuard checks that a guarded exit names a real direction
+func validateExitGuard(guard *ExitGuard) error {
+	guard.Direction = strings.ToLower(guard.Direction)
+	if full, ok := DirectionAliases[guard.Direction]; ok {
+		guard.Direction = full
+	}
+	if !isDirection(guard.Direction) {
+		return fmt.Errorf("%q is not a direction", guard.Direction)
+	}
+	if guard.Bribe < 0 {
+		return fmt.Errorf("bribe must not be negative")
+	}
+	return nil
+}
+
+// lets reports whether the guard lets the player by without a fight
+func (m *MobInstance) lets(player *Player) bool {
+	if player.Staff || m.Bribed[player.Name] {
+		return true
+	}
+	return m.Blocks.Pass != 0 && player.HasItem(m.Blocks.Pass)
+}
+
+// exitGuard returns the mob in the room standing in the player's way out through the exit, or nil
+func exitGuard(player *Player, room *Room, direction string) *MobInstance {
+	mobMutex.RLock()
+	defer mobMutex.RUnlock()
+
+	for _, mob := range roomMobs[room.ID] {
+		if mob.Blocks == nil || mob.Blocks.Direction != direction || mob.HP <= 0 {
+			continue
+		}
+		if mob.Master != nil || mob.Fighting != nil || !MobCanSeePlayer(mob, player) || mob.lets(player) {
+			continue
+		}
+		return mob
+	}
+	return nil
+}
+
+// CheckExitGuard returns an error if a mob in the room blocks the player's way through the exit
+func CheckExitGuard(player *Player, room *Room, direction string) error {
+	guard := exitGuard(player, room, direction)
+	if guard == nil {
+		return nil
+	}
+
+	BroadcastToRoom(fmt.Sprintf("%s blocks $n's way %s.", capitalizeFirst(guard.ShortDescription), direction), room, player)
+	if guard.Blocks.Message != "" {
+		return fmt.Errorf("%s", guard.Blocks.Message)
+	}
+	return fmt.Errorf("%s steps in front of you, blocking the way %s.", capitalizeFirst(guard.ShortDescription), direction)
+}
+
+// handleBribe pays a guard to let the player through the exit it blocks
+// Usage: bribe <mob> [amount]
+func handleBribe(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Bribe whom?"
+	}
+
+	amount := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[len(args)-1])
+		if err == nil {
+			if n < 1 {
+				return "You have to offer something."
+			}
+			amount = n
+			args = args[:len(args)-1]
+		}
+	}
+
+	mob := FindMobByTarget(player.Room.ID, strings.ToLower(strings.Join(args, " ")))
+	if mob == nil {
+		return "You don't see that here."
+	}
+	name := capitalizeFirst(mob.ShortDescription)
+	guard := mob.Blocks
+	switch {
+	case guard == nil || guard.Bribe == 0:
+		return fmt.Sprintf("%s isn't interested in your money.", name)
+	case IsMobInCombat(mob) || mob.Fighting != nil:
+		return fmt.Sprintf("%s is too busy fighting to take it.", name)
+	case mob.Bribed[player.Name]:
+		return fmt.Sprintf("%s already lets you pass.", name)
+	case amount == 0:
+		return fmt.Sprintf("%s rubs its fingers together and looks at your purse.", name)
+	case amount < guard.Bribe:
+		return fmt.Sprintf("%s scoffs at your offer.", name)
+	case !ChargeGold(player, amount, GoldSourceBribes):
+		return "You don't have that much gold."
+	}
+
+	if mob.Bribed == nil {
+		mob.Bribed = make(map[string]bool)
+	}
+	mob.Bribed[player.Name] = true
+
+	BroadcastToRoom(fmt.Sprintf("$n slips %s some coins.", mob.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You slip %s %d gold. %s pockets it and lets you pass %s.", mob.ShortDescription, amount, name, guard.Direction)
+}
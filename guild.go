@@ -0,0 +1,427 @@
+/*
+ * guild.go
+ *
+ * This file implements class guilds. Each class has a guild hall guarded so
+ * that only its members may enter, and a guildmaster inside who teaches the
+ * class's skills and spells for gold and hands out class quests. Most skills
+ * are self-buffs built on the affect system: spells cost mana and other
+ * skills cost stamina. The gathering skills used for crafting are taught
+ * to every class. Learned skills and completed quests are saved with the
+ * character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// AllClasses marks a skill every class's guildmaster teaches
+const AllClasses = "All"
+
+// Skill is a class ability taught by guildmasters
+type Skill struct {
+	Name   string
+	Class  string
+	Level  int    // Level required to learn the skill
+	Price  int    // Gold the guildmaster charges to teach it
+	Spell  bool   // Spells cost mana; other skills cost stamina
+	Cost   int    // Mana or stamina spent each time the skill is used
+	Affect Affect // Applied to the user
+
+	// Use runs a targeted skill instead of applying Affect, reporting whether the cost was spent
+	Use func(player *Player, target string) (string, bool)
+}
+
+// Guildmaster marks a mob as the trainer of a class guild
+type Guildmaster struct {
+	Class  string       `yaml:"class"`
+	Quests []GuildQuest `yaml:"quests,omitempty"`
+}
+
+// GuildQuest asks a player to bring an item back to their guildmaster
+type GuildQuest struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Level       int    `yaml:"level,omitempty"` // Minimum level to take the quest
+	Item        int    `yaml:"item"`            // Vnum of the item to bring back
+	RewardGold  int    `yaml:"reward_gold,omitempty"`
+	RewardXP    int    `yaml:"reward_xp,omitempty"`
+}
+
+// skillTable lists every skill and spell, grouped by class
+var skillTable = []*Skill{
+	// Warrior
+	{Name: "battle cry", Class: "Warrior", Level: 1, Price: 20, Cost: 25, Affect: Affect{
+		Name: "battle cry", Modifiers: map[string]int{ApplySTR: 2, ApplyDamroll: 2}, Duration: 3,
+		ApplyMessage: "You let loose a fearsome battle cry!", ExpireMessage: "Your battle fury subsides.",
+	}},
+	{Name: "bash", Class: "Warrior", Level: 3, Price: 40, Cost: 20}, // Used in combatstate.go
+	{Name: "iron skin", Class: "Warrior", Level: 5, Price: 60, Cost: 40, Affect: Affect{
+		Name: "iron skin", Modifiers: map[string]int{ApplyCON: 3}, Duration: 5,
+		ApplyMessage: "You steel yourself against the blows to come.", ExpireMessage: "Your skin feels softer.",
+	}},
+
+	// Mage
+	{Name: "giant strength", Class: "Mage", Level: 1, Price: 20, Spell: true, Cost: 20, Affect: Affect{
+		Name: "giant strength", Modifiers: map[string]int{ApplySTR: 2}, Duration: 5,
+		ApplyMessage: "Your muscles surge with heightened power!", ExpireMessage: "You feel weaker.",
+	}},
+	{Name: "arcane focus", Class: "Mage", Level: 5, Price: 60, Spell: true, Cost: 35, Affect: Affect{
+		Name: "arcane focus", Modifiers: map[string]int{ApplyINT: 2, ApplyHitroll: 5}, Duration: 5,
+		ApplyMessage: "Your mind sharpens to a fine point.", ExpireMessage: "Your focus wavers and fades.",
+	}},
+	{Name: "detect invisibility", Class: "Mage", Level: 2, Price: 30, Spell: true, Cost: 15, Affect: Affect{
+		Name: AffectDetectInvis, Duration: 10,
+		ApplyMessage: "Your eyes tingle.", ExpireMessage: "The tingling in your eyes fades.",
+	}},
+	{Name: "detect magic", Class: "Mage", Level: 1, Price: 15, Spell: true, Cost: 10, Affect: Affect{
+		Name: AffectDetectMagic, Duration: 10,
+		ApplyMessage: "Your eyes tingle.", ExpireMessage: "The detect magic wears off.",
+	}},
+	{Name: "charm", Class: "Mage", Level: 3, Price: 40, Spell: true, Cost: 30, Use: castCharm},
+	{Name: "invisibility", Class: "Mage", Level: 4, Price: 50, Spell: true, Cost: 25, Affect: Affect{
+		Name: AffectInvisible, Duration: 8,
+		ApplyMessage: "You fade out of existence.", ExpireMessage: "You fade back into existence.",
+	}},
+
+	// Rogue
+	{Name: "quickness", Class: "Rogue", Level: 1, Price: 20, Cost: 25, Affect: Affect{
+		Name: "quickness", Modifiers: map[string]int{ApplyDEX: 2}, Duration: 4,
+		ApplyMessage: "You loosen up and feel light on your feet.", ExpireMessage: "You slow down.",
+	}},
+	{Name: "detect hidden", Class: "Rogue", Level: 2, Price: 30, Cost: 15, Affect: Affect{
+		Name: AffectDetectHidden, Duration: 10,
+		ApplyMessage: "Your awareness improves.", ExpireMessage: "You feel less aware of your surroundings.",
+	}},
+	{Name: "disarm", Class: "Rogue", Level: 3, Price: 40, Cost: 20}, // Used in combatstate.go
+	{Name: "dirty tricks", Class: "Rogue", Level: 5, Price: 60, Cost: 40, Affect: Affect{
+		Name: "dirty tricks", Modifiers: map[string]int{ApplyHitroll: 10, ApplyDamroll: 1}, Duration: 3,
+		ApplyMessage: "You palm a handful of sand and grin.", ExpireMessage: "You run out of tricks.",
+	}},
+
+	// Cleric
+	{Name: "bless", Class: "Cleric", Level: 1, Price: 20, Spell: true, Cost: 20, Affect: Affect{
+		Name: "bless", Modifiers: map[string]int{ApplyHitroll: 5, ApplyWIS: 1}, Duration: 6,
+		ApplyMessage: "You feel righteous.", ExpireMessage: "You feel less righteous.",
+	}},
+	{Name: "detect evil", Class: "Cleric", Level: 2, Price: 30, Spell: true, Cost: 15, Affect: Affect{
+		Name: AffectDetectEvil, Duration: 10,
+		ApplyMessage: "Your eyes tingle.", ExpireMessage: "The red in your vision disappears.",
+	}},
+	{Name: "dispel magic", Class: "Cleric", Level: 3, Price: 40, Spell: true, Cost: 25, Use: castDispel},
+	{Name: "divine favor", Class: "Cleric", Level: 5, Price: 60, Spell: true, Cost: 35, Affect: Affect{
+		Name: "divine favor", Modifiers: map[string]int{ApplyPRE: 2, ApplyCON: 1}, Duration: 6,
+		ApplyMessage: "A warm light settles over you.", ExpireMessage: "The warm light fades.",
+	}},
+
+	// Gathering skills, taught to every class
+	{Name: SkillMining, Class: AllClasses, Level: 1, Price: 25, Cost: 10, Use: gatherSkill(SkillMining)},
+	{Name: SkillHerbalism, Class: AllClasses, Level: 1, Price: 25, Cost: 10, Use: gatherSkill(SkillHerbalism)},
+}
+
+// FindSkill returns the skill with the given name or name prefix, or nil
+func FindSkill(name string) *Skill {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	for _, skill := range skillTable {
+		if skill.Name == name {
+			return skill
+		}
+	}
+	for _, skill := range skillTable {
+		if strings.HasPrefix(skill.Name, name) {
+			return skill
+		}
+	}
+	return nil
+}
+
+// classSkills returns the skills taught to a class, in the order they can be learned
+func classSkills(class string) []*Skill {
+	var skills []*Skill
+	for _, skill := range skillTable {
+		if skill.TaughtTo(class) {
+			skills = append(skills, skill)
+		}
+	}
+	sort.SliceStable(skills, func(i, j int) bool {
+		return skills[i].Level < skills[j].Level
+	})
+	return skills
+}
+
+// TaughtTo reports whether guildmasters of the class teach the skill
+func (s *Skill) TaughtTo(class string) bool {
+	return s.Class == AllClasses || strings.EqualFold(s.Class, class)
+}
+
+// resourceName returns what the skill spends when used
+func (s *Skill) resourceName() string {
+	if s.Spell {
+		return "mana"
+	}
+	return "stamina"
+}
+
+// KnowsSkill reports whether the player has learned a skill
+func (p *Player) KnowsSkill(name string) bool {
+	return p.Skills[name]
+}
+
+// LoadSkills restores the player's learned skills from the database
+func (p *Player) LoadSkills() {
+	skills, err := LoadPlayerSkills(p.Name)
+	if err != nil {
+		log.Printf("Error loading skills for %s: %v", p.Name, err)
+		return
+	}
+
+	p.Skills = make(map[string]bool)
+	for _, skill := range skills {
+		p.Skills[skill] = true
+	}
+}
+
+// findGuildmaster returns the guildmaster in the player's room, or nil
+func findGuildmaster(player *Player) *MobInstance {
+	for _, mob := range GetMobsInRoom(player.Room.ID) {
+		if mob.Guildmaster != nil {
+			return mob
+		}
+	}
+	return nil
+}
+
+// guildmasterFor returns the guildmaster in the player's room if they belong to its class
+// Otherwise it returns the message to show the player.
+func guildmasterFor(player *Player) (*MobInstance, string) {
+	master := findGuildmaster(player)
+	if master == nil {
+		return nil, "There is no guildmaster here."
+	}
+	if !strings.EqualFold(master.Guildmaster.Class, player.Class) {
+		return nil, fmt.Sprintf("%s says, 'I only teach the %s class. Seek out your own guild.'",
+			capitalizeFirst(master.ShortDescription), master.Guildmaster.Class)
+	}
+	return master, ""
+}
+
+// handleTrain lists or teaches the skills offered by a guildmaster
+func handleTrain(player *Player, args []string) string {
+	master, refusal := guildmasterFor(player)
+	if master == nil {
+		return refusal
+	}
+
+	if len(args) == 0 {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s can teach you:\r\n", capitalizeFirst(master.ShortDescription)))
+		sb.WriteString(fmt.Sprintf("  %-16s %5s %6s  %s\r\n", "Skill", "Level", "Price", "Cost"))
+		for _, skill := range classSkills(player.Class) {
+			status := fmt.Sprintf("%6d", HagglePrice(player, master, skill.Price))
+			if player.KnowsSkill(skill.Name) {
+				status = "{G}known{x} "
+			}
+			sb.WriteString(fmt.Sprintf("  %-16s %5d %s  %d %s\r\n", skill.Name, skill.Level, status, skill.Cost, skill.resourceName()))
+		}
+		sb.WriteString("Use 'train <skill>' to learn one.")
+		return sb.String()
+	}
+
+	skill := FindSkill(strings.Join(args, " "))
+	if skill == nil || !skill.TaughtTo(player.Class) {
+		return fmt.Sprintf("%s says, 'I know of no such skill.'", capitalizeFirst(master.ShortDescription))
+	}
+	if player.KnowsSkill(skill.Name) {
+		return fmt.Sprintf("You already know %s.", skill.Name)
+	}
+	if player.Level < skill.Level {
+		return fmt.Sprintf("%s says, 'Come back when you have reached level %d.'", capitalizeFirst(master.ShortDescription), skill.Level)
+	}
+	price := HagglePrice(player, master, skill.Price)
+	if !ChargeGold(player, price, GoldSourceTraining) {
+		return fmt.Sprintf("%s says, 'My teaching costs %d gold. You can't afford it.'", capitalizeFirst(master.ShortDescription), price)
+	}
+
+	if player.Skills == nil {
+		player.Skills = make(map[string]bool)
+	}
+	player.Skills[skill.Name] = true
+	if err := AddPlayerSkill(player.Name, skill.Name); err != nil {
+		log.Printf("Error saving skill %s for %s: %v", skill.Name, player.Name, err)
+	}
+
+	return fmt.Sprintf("You pay %d gold and %s teaches you %s.%s", price, master.ShortDescription, skill.Name, haggleNote(skill.Price, price))
+}
+
+// handleSkills lists the skills the player has learned
+func handleSkills(player *Player, args []string) string {
+	var sb strings.Builder
+	sb.WriteString("Your skills and spells:\r\n")
+
+	count := 0
+	for _, skill := range classSkills(player.Class) {
+		if !player.KnowsSkill(skill.Name) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %-16s %d %s\r\n", skill.Name, skill.Cost, skill.resourceName()))
+		count++
+	}
+	if count == 0 {
+		sb.WriteString("  None. Visit your guildmaster to train.\r\n")
+	}
+
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// handleCast uses a learned skill or spell
+func handleCast(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Use which skill?"
+	}
+
+	// The skill name may be followed by a target: "cast charm guard"
+	var skill *Skill
+	var target string
+	for n := len(args); n > 0 && skill == nil; n-- {
+		skill = FindSkill(strings.Join(args[:n], " "))
+		target = strings.Join(args[n:], " ")
+	}
+	if skill == nil || !player.KnowsSkill(skill.Name) {
+		return "You don't know how to do that."
+	}
+
+	if skill.Spell && player.MP < skill.Cost {
+		return "You don't have enough mana."
+	}
+	if !skill.Spell && player.Stamina < skill.Cost {
+		return "You are too tired."
+	}
+
+	if skill.Use != nil {
+		message, spent := skill.Use(player, target)
+		if spent {
+			player.spendSkillCost(skill)
+		}
+		return message
+	}
+	// Affects are as strong as their user, and only spells can be dispelled
+	affect := skill.Affect
+	affect.Level = player.Level
+	affect.Magical = skill.Spell
+	player.ApplyAffect(&affect)
+	player.spendSkillCost(skill)
+
+	if skill.Spell {
+		BroadcastToRoom(fmt.Sprintf("$n utters the words, '%s'.", skill.Name), player.Room, player)
+	} else {
+		BroadcastToRoom(fmt.Sprintf("$n uses %s.", skill.Name), player.Room, player)
+	}
+	return ""
+}
+
+// spendSkillCost takes the mana or stamina a skill costs
+func (p *Player) spendSkillCost(skill *Skill) {
+	if skill.Spell {
+		p.MP -= skill.Cost
+	} else {
+		p.Stamina -= skill.Cost
+	}
+	p.SendStatus()
+	p.SendVitals()
+}
+
+// handleQuest lists a guildmaster's quests or turns one in
+func handleQuest(player *Player, args []string) string {
+	master, refusal := guildmasterFor(player)
+	if master == nil {
+		return refusal
+	}
+	quests := master.Guildmaster.Quests
+	name := capitalizeFirst(master.ShortDescription)
+
+	if len(args) == 0 {
+		if len(quests) == 0 {
+			return fmt.Sprintf("%s has no tasks for you.", name)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s has these tasks for you:\r\n", name))
+		for _, quest := range quests {
+			status := ""
+			if player.HasCompletedQuest(quest.ID) {
+				status = " {G}(completed){x}"
+			} else if player.Level < quest.Level {
+				status = fmt.Sprintf(" {D}(level %d){x}", quest.Level)
+			}
+			sb.WriteString(fmt.Sprintf("  {Y}%s{x} [%s]%s\r\n", quest.Name, quest.ID, status))
+			description := strings.ReplaceAll(strings.TrimSpace(quest.Description), "\n", "\r\n    ")
+			sb.WriteString(fmt.Sprintf("    %s\r\n", description))
+		}
+		sb.WriteString("Use 'quest complete <id>' once you have what is asked for.")
+		return sb.String()
+	}
+
+	if strings.ToLower(args[0]) != "complete" || len(args) < 2 {
+		return "Usage: quest [complete <id>]"
+	}
+
+	var quest *GuildQuest
+	for i := range quests {
+		if strings.EqualFold(quests[i].ID, args[1]) {
+			quest = &quests[i]
+			break
+		}
+	}
+	if quest == nil {
+		return fmt.Sprintf("%s says, 'I asked no such thing of you.'", name)
+	}
+	if player.HasCompletedQuest(quest.ID) {
+		return fmt.Sprintf("%s says, 'You have already done this for me.'", name)
+	}
+	if player.Level < quest.Level {
+		return fmt.Sprintf("%s says, 'You are not ready for this task yet.'", name)
+	}
+
+	var item *Item
+	for _, carried := range player.Inventory {
+		if carried.ID == quest.Item {
+			item = carried
+			break
+		}
+	}
+	if item == nil {
+		return fmt.Sprintf("%s says, 'You don't have what I asked for.'", name)
+	}
+
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	player.CompleteQuest(quest.ID)
+
+	output := fmt.Sprintf("You give %s to %s.\r\n%s says, 'Well done!'", item.Name(), master.ShortDescription, name)
+	if quest.RewardGold > 0 {
+		player.Gold += quest.RewardGold
+		if err := UpdatePlayerGold(player.Name, player.Gold); err != nil {
+			log.Printf("Error saving quest reward for %s: %v", player.Name, err)
+		}
+		RecordGoldCreated(GoldSourceQuests, quest.RewardGold)
+		output += fmt.Sprintf("\r\nYou receive {Y}%s gold{x}.", player.Locale.Number(quest.RewardGold))
+	}
+	if quest.RewardXP > 0 {
+		output += fmt.Sprintf("\r\nYou gain {G}%s{x} experience.", player.Locale.Number(quest.RewardXP))
+	}
+	player.Send(output)
+
+	// Award experience after the message so any level up is announced last
+	if quest.RewardXP > 0 {
+		player.GainXP(quest.RewardXP)
+	}
+	player.SendStatus()
+	return ""
+}
@@ -0,0 +1,108 @@
+/*
+ * haggle.go
+ *
+ * This file implements haggling. Whenever a player pays a mob for goods or
+ * lessons (a pet shopkeeper, a guildmaster, or a language tutor) the price
+ * is adjusted by their Presence. A player with more Presence than most
+ * talks the merchant down, and one with less gets charged extra, within
+ * fixed bounds. A mage can also cast charm on a merchant: merchants won't
+ * leave their trade to follow anyone, but a charmed one gives the caster
+ * a further discount for as long as the charm lasts. A merchant who
+ * resists the charm holds it against the caster and charges them more.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+// Haggling tuning
+const (
+	HaggleBaseline    = 10 // Presence at which a player pays the listed price
+	HagglePerPoint    = 2  // Percent off for each point of Presence above the baseline, or on for each below
+	MaxHaggleDiscount = 20 // Most Presence alone can take off a price, in percent
+	MaxHaggleMarkup   = 10 // Most Presence alone can add to a price, in percent
+	BeguileDiscount   = 10 // Extra percent off from a merchant the player has charmed
+	BeguileMarkup     = 10 // Extra percent on from a merchant who resisted the player's charm
+)
+
+// Affects a charm leaves on a merchant
+const (
+	BeguiledAffect   = "beguiled"   // Charmed into giving the caster better prices
+	SuspiciousAffect = "suspicious" // Resisted a charm and charges the caster more
+)
+
+// IsMerchant reports whether the mob sells goods or lessons to players
+func (m *MobInstance) IsMerchant() bool {
+	return m.PetShop != 0 || m.Guildmaster != nil || len(m.TeachesLanguages) > 0
+}
+
+// HaggleDiscount returns the percent a merchant takes off its prices for the player
+// A negative discount is a markup.
+func HaggleDiscount(player *Player, merchant *MobInstance) int {
+	discount := (player.Stat(ApplyPRE) - HaggleBaseline) * HagglePerPoint
+	discount = min(max(discount, -MaxHaggleMarkup), MaxHaggleDiscount)
+
+	if merchant.Regarding == player.Name {
+		if merchant.Affects.Find(BeguiledAffect) != nil {
+			discount += BeguileDiscount
+		}
+		if merchant.Affects.Find(SuspiciousAffect) != nil {
+			discount -= BeguileMarkup
+		}
+	}
+	return discount
+}
+
+// HagglePrice returns what a merchant charges the player for something listed at price
+func HagglePrice(player *Player, merchant *MobInstance, price int) int {
+	if price <= 0 {
+		return price
+	}
+	return max(price*(100-HaggleDiscount(player, merchant))/100, 1)
+}
+
+// haggleNote describes how far the player haggled a listed price, or "" if they paid it
+func haggleNote(listed, paid int) string {
+	switch {
+	case paid < listed:
+		return fmt.Sprintf(" You haggled it down from %d.", listed)
+	case paid > listed:
+		return fmt.Sprintf(" You were charged more than the asking price of %d.", listed)
+	}
+	return ""
+}
+
+// charmMerchant casts charm on a merchant, which wins the caster better prices rather than a follower
+func charmMerchant(player *Player, merchant *MobInstance) string {
+	name := capitalizeFirst(merchant.ShortDescription)
+	BroadcastToRoom("$n utters the words, 'charm'.", player.Room, player)
+
+	// Another player's charm or grudge is forgotten
+	merchant.Affects.Remove(BeguiledAffect)
+	merchant.Affects.Remove(SuspiciousAffect)
+	merchant.Regarding = player.Name
+
+	chance := 50 + (player.Level-merchant.Level)*10 + player.Stat(ApplyPRE)
+	if rng.Intn(100) >= chance {
+		merchant.ApplyAffect(&Affect{
+			Name:          SuspiciousAffect,
+			Duration:      10,
+			Level:         player.Level,
+			ApplyMessage:  fmt.Sprintf("$n narrows its eyes at %s.", player.Name),
+			ExpireMessage: "$n seems to have forgotten a grudge.",
+		})
+		return fmt.Sprintf("{R}%s sees through your charm. Expect to pay for that.{x}", name)
+	}
+
+	merchant.ApplyAffect(&Affect{
+		Name:          BeguiledAffect,
+		Duration:      10 + player.Level,
+		Level:         player.Level,
+		Magical:       true,
+		ApplyMessage:  fmt.Sprintf("$n beams warmly at %s.", player.Name),
+		ExpireMessage: "$n shakes its head and goes back to its ledger.",
+	})
+	return fmt.Sprintf("%s won't leave its trade, but will give you a very good price.", name)
+}
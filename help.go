@@ -5,7 +5,10 @@
  * It provides functionality to load, parse, and search help files stored in the
  * "docs" directory. Each help file contains a YAML front matter with title and
  * keywords, followed by Markdown content that is displayed to the player.
- * The file follows a similar pattern to loader.go for loading game data.
+ * The front matter may also place the file in a category, which 'help
+ * <category>' lists, and name related topics, which are shown at the foot
+ * of the help file. The file follows a similar pattern to loader.go for
+ * loading game data.
  */
 
 package main
@@ -17,6 +20,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -27,6 +31,8 @@ import (
 type HelpFile struct {
 	Title    string   `yaml:"title"`
 	Keywords []string `yaml:"keywords"`
+	Category string   `yaml:"category"`
+	SeeAlso  []string `yaml:"see_also"` // Related topics, by title or keyword
 	Content  string   // The actual help content (not part of YAML)
 	Filename string   // The filename for reference
 }
@@ -35,6 +41,7 @@ type HelpFile struct {
 type HelpSystem struct {
 	helpFiles     map[string]*HelpFile // Map of lowercase titles to help files
 	keywordIndex  map[string][]string  // Map of keywords to help file titles
+	categories    map[string][]string  // Map of lowercase categories to help file titles
 	mutex         sync.RWMutex         // For thread-safe access
 	docsDirectory string               // Directory where help files are stored
 }
@@ -56,6 +63,7 @@ func NewHelpSystem(docsDir string) *HelpSystem {
 	return &HelpSystem{
 		helpFiles:     make(map[string]*HelpFile),
 		keywordIndex:  make(map[string][]string),
+		categories:    make(map[string][]string),
 		docsDirectory: docsDir,
 	}
 }
@@ -68,6 +76,7 @@ func (hs *HelpSystem) LoadHelpFiles() error {
 	// Clear existing data
 	hs.helpFiles = make(map[string]*HelpFile)
 	hs.keywordIndex = make(map[string][]string)
+	hs.categories = make(map[string][]string)
 
 	// Create docs directory if it doesn't exist
 	if _, err := os.Stat(hs.docsDirectory); os.IsNotExist(err) {
@@ -104,9 +113,20 @@ func (hs *HelpSystem) LoadHelpFiles() error {
 			hs.keywordIndex[keyword] = append(hs.keywordIndex[keyword], helpFile.Title)
 		}
 
+		// File it under its category
+		if helpFile.Category != "" {
+			category := strings.ToLower(helpFile.Category)
+			hs.categories[category] = append(hs.categories[category], helpFile.Title)
+		}
+
 		return nil
 	})
 
+	for _, titles := range hs.categories {
+		sort.Strings(titles)
+	}
+	hs.checkSeeAlso()
+
 	// Create a default index help file if it doesn't exist
 	if _, exists := hs.helpFiles["index"]; !exists {
 		hs.createDefaultIndexFile()
@@ -144,6 +164,8 @@ func (hs *HelpSystem) parseHelpFile(filePath string) (*HelpFile, error) {
 	type FrontMatter struct {
 		Title    string `yaml:"title"`
 		Keywords string `yaml:"keywords"`
+		Category string `yaml:"category"`
+		SeeAlso  string `yaml:"see_also"`
 	}
 
 	var frontMatter FrontMatter
@@ -154,17 +176,13 @@ func (hs *HelpSystem) parseHelpFile(filePath string) (*HelpFile, error) {
 	// Create the help file with parsed data
 	helpFile := &HelpFile{
 		Title:    frontMatter.Title,
+		Category: strings.TrimSpace(frontMatter.Category),
 		Filename: filepath.Base(filePath),
 	}
 
-	// Split the comma-separated keywords into a slice
-	if frontMatter.Keywords != "" {
-		keywordsList := strings.Split(frontMatter.Keywords, ",")
-		for i, keyword := range keywordsList {
-			keywordsList[i] = strings.TrimSpace(keyword)
-		}
-		helpFile.Keywords = keywordsList
-	}
+	// Split the comma-separated keywords and related topics into slices
+	helpFile.Keywords = splitHelpList(frontMatter.Keywords)
+	helpFile.SeeAlso = splitHelpList(frontMatter.SeeAlso)
 
 	// Read the rest of the file as content
 	var content strings.Builder
@@ -177,6 +195,30 @@ func (hs *HelpSystem) parseHelpFile(filePath string) (*HelpFile, error) {
 	return helpFile, nil
 }
 
+// splitHelpList splits a comma-separated front matter field into a slice
+func splitHelpList(field string) []string {
+	if field == "" {
+		return nil
+	}
+	list := strings.Split(field, ",")
+	for i, item := range list {
+		list[i] = strings.TrimSpace(item)
+	}
+	return list
+}
+
+// checkSeeAlso warns about related topics that don't lead to a help file.
+// The caller must hold the mutex.
+func (hs *HelpSystem) checkSeeAlso() {
+	for _, helpFile := range hs.helpFiles {
+		for _, topic := range helpFile.SeeAlso {
+			if hs.lookup(topic) == nil {
+				log.Printf("[WARNING] Help file %s refers to unknown topic '%s'", helpFile.Filename, topic)
+			}
+		}
+	}
+}
+
 // createDefaultIndexFile creates a default index help file
 func (hs *HelpSystem) createDefaultIndexFile() {
 	// Create a list of all available help topics
@@ -198,6 +240,35 @@ func (hs *HelpSystem) createDefaultIndexFile() {
 	hs.helpFiles["index"] = indexFile
 }
 
+// lookup finds a help file by title, then by keyword. The caller must hold the mutex.
+func (hs *HelpSystem) lookup(topic string) *HelpFile {
+	topic = strings.ToLower(topic)
+	if helpFile, ok := hs.helpFiles[topic]; ok {
+		return helpFile
+	}
+	if titles := hs.keywordIndex[topic]; len(titles) > 0 {
+		return hs.helpFiles[strings.ToLower(titles[0])]
+	}
+	return nil
+}
+
+// GetCategory returns the help files in a category, sorted by title, and
+// the category's name as written in the files
+func (hs *HelpSystem) GetCategory(category string) (string, []*HelpFile) {
+	hs.mutex.RLock()
+	defer hs.mutex.RUnlock()
+
+	titles := hs.categories[strings.ToLower(category)]
+	if len(titles) == 0 {
+		return "", nil
+	}
+	var files []*HelpFile
+	for _, title := range titles {
+		files = append(files, hs.helpFiles[strings.ToLower(title)])
+	}
+	return files[0].Category, files
+}
+
 // GetHelpByTitle looks up a help file by its exact title (case-insensitive)
 func (hs *HelpSystem) GetHelpByTitle(title string) *HelpFile {
 	hs.mutex.RLock()
@@ -309,6 +380,13 @@ func handleHelp(player *Player, args []string) string {
 		helpFile = helpSystem.GetHelpByKeyword(topic)
 	}
 
+	// Then try a category
+	if helpFile == nil {
+		if name, files := helpSystem.GetCategory(topic); files != nil {
+			return formatHelpCategory(name, files)
+		}
+	}
+
 	// If still no match, show error message
 	if helpFile == nil {
 		return fmt.Sprintf("No help file found for '%s'. Try 'help index' for a list of topics.", topic)
@@ -316,5 +394,22 @@ func handleHelp(player *Player, args []string) string {
 
 	// Format the content and return it
 	formattedContent := helpSystem.FormatHelpContent(helpFile.Content)
+	if len(helpFile.SeeAlso) > 0 {
+		formattedContent += fmt.Sprintf("\n{G}Related topics:{x} %s\n", strings.Join(helpFile.SeeAlso, ", "))
+	}
 	return fmt.Sprintf("{Y}%s{x}\n\n%s", helpFile.Title, formattedContent)
 }
+
+// formatHelpCategory lists the help files in a category, with the keyword to look each one up by
+func formatHelpCategory(name string, files []*HelpFile) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("{Y}%s{x}\n\n", name))
+	for _, helpFile := range files {
+		topic := strings.ToLower(helpFile.Title)
+		if len(helpFile.Keywords) > 0 {
+			topic = helpFile.Keywords[0]
+		}
+		sb.WriteString(fmt.Sprintf("  {C}*{x} %-24s {D}help %s{x}\n", helpFile.Title, topic))
+	}
+	return sb.String()
+}
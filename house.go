@@ -0,0 +1,378 @@
+/*
+ * house.go
+ *
+ * This file implements player housing. Some rooms off the residential
+ * streets are houses for sale, marked with a price in the area file. A
+ * player standing in one can buy it, after which only the owner, the
+ * guests on their access list, and staff may enter. Each house has a
+ * storage chest where the owner can keep items safe between sessions, and
+ * the 'home' command takes the owner back to their house from anywhere.
+ * Ownership, guest lists, and stored items are saved in the database.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxHouseStorage is the most items a house's storage chest can hold
+const MaxHouseStorage = 20
+
+// House is a room owned by a player
+type House struct {
+	RoomID  int
+	Owner   string
+	Guests  []string // Players let in besides the owner, sorted by name
+	Storage []*Item  // Items kept in the storage chest
+}
+
+var (
+	houses      = make(map[int]*House) // Keyed by room ID
+	housesMutex sync.RWMutex
+)
+
+// LoadHouses reads every owned house, with its guests and storage, from the database
+func LoadHouses() error {
+	records, err := LoadHouseRecords()
+	if err != nil {
+		return err
+	}
+
+	housesMutex.Lock()
+	defer housesMutex.Unlock()
+	for _, h := range records {
+		if room, err := GetRoom(h.RoomID); err != nil || room.HousePrice == 0 {
+			log.Printf("[WARNING] %s owns room %d, which is no longer a house", h.Owner, h.RoomID)
+		}
+		if h.Guests, err = LoadHouseGuests(h.RoomID); err != nil {
+			log.Printf("Error loading guests of house %d: %v", h.RoomID, err)
+		}
+		saved, err := LoadHouseStorage(h.RoomID)
+		if err != nil {
+			log.Printf("Error loading storage of house %d: %v", h.RoomID, err)
+		}
+		h.Storage = restoreStoredItems(saved, h.Owner)
+		houses[h.RoomID] = h
+	}
+	log.Printf("Loaded %d houses", len(records))
+	return nil
+}
+
+// restoreStoredItems rebuilds saved items, putting contents back in their containers
+func restoreStoredItems(saved []SavedItem, owner string) []*Item {
+	var items []*Item
+	containers := make(map[int]*Item)
+	for _, s := range saved {
+		item, err := CreateItem(s.Vnum)
+		if err != nil {
+			log.Printf("Skipping unknown item %d in %s's house: %v", s.Vnum, owner, err)
+			continue
+		}
+		if s.Bound {
+			item.BoundTo = owner
+		}
+		containers[s.ID] = item
+		if container := containers[s.Container]; container != nil {
+			container.Contents = append(container.Contents, item)
+		} else {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// houseAt returns the house owned in a room, or nil
+func houseAt(room *Room) *House {
+	if room == nil {
+		return nil
+	}
+	housesMutex.RLock()
+	defer housesMutex.RUnlock()
+	return houses[room.ID]
+}
+
+// houseOwnedBy returns the house a player owns, or nil
+func houseOwnedBy(name string) *House {
+	housesMutex.RLock()
+	defer housesMutex.RUnlock()
+	for _, h := range houses {
+		if strings.EqualFold(h.Owner, name) {
+			return h
+		}
+	}
+	return nil
+}
+
+// isGuest reports whether a player is on a house's access list
+func (h *House) isGuest(name string) bool {
+	for _, guest := range h.Guests {
+		if strings.EqualFold(guest, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHouseAccess returns an error if the room is a house the player isn't allowed into
+func CheckHouseAccess(player *Player, room *Room) error {
+	house := houseAt(room)
+	if house == nil || player.Staff || strings.EqualFold(house.Owner, player.Name) || house.isGuest(player.Name) {
+		return nil
+	}
+	return fmt.Errorf("the door of %s's house is locked", house.Owner)
+}
+
+// handleHouse dispatches the house subcommands
+// Usage: house [buy|guest|store|retrieve]
+func handleHouse(player *Player, args []string) string {
+	if len(args) == 0 {
+		return houseInfo(player)
+	}
+
+	rest := args[1:]
+	switch strings.ToLower(args[0]) {
+	case "buy":
+		return houseBuy(player)
+	case "guest", "guests":
+		return houseGuest(player, rest)
+	case "store":
+		return houseStore(player, rest)
+	case "retrieve":
+		return houseRetrieve(player, rest)
+	default:
+		return "Usage: house [buy|guest|store|retrieve]"
+	}
+}
+
+// houseInfo describes the house the player is standing in, or their own
+func houseInfo(player *Player) string {
+	room := player.Room
+	house := houseAt(room)
+	if house == nil && room.HousePrice > 0 {
+		return fmt.Sprintf("This house is for sale for {Y}%d{x} gold. Type 'house buy' to buy it.", room.HousePrice)
+	}
+	if house != nil && !strings.EqualFold(house.Owner, player.Name) {
+		return fmt.Sprintf("This house belongs to %s.", house.Owner)
+	}
+
+	house = houseOwnedBy(player.Name)
+	if house == nil {
+		return "You don't own a house. Look for one for sale on Penny Lane."
+	}
+
+	var sb strings.Builder
+	if home, err := GetRoom(house.RoomID); err == nil {
+		sb.WriteString(fmt.Sprintf("{C}Your house:{x} %s\r\n", home.Name))
+	}
+	if len(house.Guests) > 0 {
+		sb.WriteString(fmt.Sprintf("Guests: %s\r\n", strings.Join(house.Guests, ", ")))
+	} else {
+		sb.WriteString("Guests: none\r\n")
+	}
+	sb.WriteString(fmt.Sprintf("Storage (%d/%d):\r\n", len(house.Storage), MaxHouseStorage))
+	if len(house.Storage) == 0 {
+		sb.WriteString("  Nothing.")
+	}
+	for _, item := range house.Storage {
+		sb.WriteString(fmt.Sprintf("  %s\r\n", item.ListName()))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// houseBuy buys the house the player is standing in
+func houseBuy(player *Player) string {
+	room := player.Room
+	if room.HousePrice == 0 {
+		return "This room isn't for sale."
+	}
+	if house := houseAt(room); house != nil {
+		return fmt.Sprintf("This house already belongs to %s.", house.Owner)
+	}
+	if houseOwnedBy(player.Name) != nil {
+		return "You already own a house."
+	}
+	if !ChargeGold(player, room.HousePrice, GoldSourceHousing) {
+		return fmt.Sprintf("This house costs %s gold. You can't afford it.", player.Locale.Number(room.HousePrice))
+	}
+
+	if err := AddHouse(room.ID, player.Name); err != nil {
+		log.Printf("Error saving %s's purchase of house %d: %v", player.Name, room.ID, err)
+		return "Error buying the house."
+	}
+	housesMutex.Lock()
+	houses[room.ID] = &House{RoomID: room.ID, Owner: player.Name}
+	housesMutex.Unlock()
+
+	log.Printf("[HOUSE] %s bought house %d for %d gold.", player.Name, room.ID, room.HousePrice)
+	return fmt.Sprintf("You pay %s gold and receive the keys to your new home. Type 'home' to return here from anywhere.", player.Locale.Number(room.HousePrice))
+}
+
+// houseGuest shows or changes the players let into the player's house
+func houseGuest(player *Player, args []string) string {
+	house := houseOwnedBy(player.Name)
+	if house == nil {
+		return "You don't own a house."
+	}
+	if len(args) == 0 {
+		if len(house.Guests) == 0 {
+			return "You haven't let anyone into your house."
+		}
+		return "Guests: " + strings.Join(house.Guests, ", ")
+	}
+	if len(args) != 2 {
+		return "Usage: house guest [add|remove <player>]"
+	}
+
+	name, ok := resolvePlayerName(args[1])
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", args[1])
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		if strings.EqualFold(name, player.Name) {
+			return "You don't need an invitation to your own house."
+		}
+		if house.isGuest(name) {
+			return fmt.Sprintf("%s is already on your guest list.", name)
+		}
+		if err := AddHouseGuest(house.RoomID, name); err != nil {
+			log.Printf("Error adding guest %s to house %d: %v", name, house.RoomID, err)
+			return "Error updating your guest list."
+		}
+		housesMutex.Lock()
+		house.Guests = append(house.Guests, name)
+		sort.Strings(house.Guests)
+		housesMutex.Unlock()
+		return fmt.Sprintf("%s may now enter your house.", name)
+	case "remove":
+		if !house.isGuest(name) {
+			return fmt.Sprintf("%s isn't on your guest list.", name)
+		}
+		if err := RemoveHouseGuest(house.RoomID, name); err != nil {
+			log.Printf("Error removing guest %s from house %d: %v", name, house.RoomID, err)
+			return "Error updating your guest list."
+		}
+		housesMutex.Lock()
+		var guests []string
+		for _, guest := range house.Guests {
+			if !strings.EqualFold(guest, name) {
+				guests = append(guests, guest)
+			}
+		}
+		house.Guests = guests
+		housesMutex.Unlock()
+		return fmt.Sprintf("%s may no longer enter your house.", name)
+	default:
+		return "Usage: house guest [add|remove <player>]"
+	}
+}
+
+// ownHouseHere returns the player's house if they are standing in it, or nil
+func ownHouseHere(player *Player) *House {
+	house := houseAt(player.Room)
+	if house == nil || !strings.EqualFold(house.Owner, player.Name) {
+		return nil
+	}
+	return house
+}
+
+// saveHouseItems stores a house's chest and the owner's inventory together, so an item is never in both
+func saveHouseItems(player *Player, house *House) {
+	if err := SaveHouseStorage(house.RoomID, house.Storage); err != nil {
+		log.Printf("Error saving storage of house %d: %v", house.RoomID, err)
+	}
+	if err := SavePlayerInventory(player.Name, player.Inventory, player.Equipment); err != nil {
+		log.Printf("Error saving inventory for %s: %v", player.Name, err)
+	}
+}
+
+// houseStore puts an item the player is carrying into their storage chest
+func houseStore(player *Player, args []string) string {
+	house := ownHouseHere(player)
+	if house == nil {
+		return "You can only use the storage chest in your own house."
+	}
+	if len(args) == 0 {
+		return "Store what?"
+	}
+
+	item := FindItemInList(player.Inventory, strings.ToLower(strings.Join(args, " ")))
+	if item == nil {
+		return "You do not have that item."
+	}
+	if item.ID == 0 {
+		return fmt.Sprintf("%s won't keep in the chest.", capitalizeFirst(item.ShortDescription))
+	}
+	if len(house.Storage) >= MaxHouseStorage {
+		return "Your storage chest is full."
+	}
+
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	house.Storage = append(house.Storage, item)
+	saveHouseItems(player, house)
+	return fmt.Sprintf("You put %s in your storage chest.", item.Name())
+}
+
+// houseRetrieve takes an item out of the player's storage chest
+func houseRetrieve(player *Player, args []string) string {
+	house := ownHouseHere(player)
+	if house == nil {
+		return "You can only use the storage chest in your own house."
+	}
+	if len(args) == 0 {
+		return "Retrieve what?"
+	}
+
+	item := FindItemInList(house.Storage, strings.ToLower(strings.Join(args, " ")))
+	if item == nil {
+		return "Your storage chest doesn't hold that."
+	}
+	if !player.CanLift(item) {
+		return fmt.Sprintf("%s is too heavy for you to carry.", capitalizeFirst(item.ShortDescription))
+	}
+
+	house.Storage = removeItemFromList(house.Storage, item)
+	player.Inventory = append(player.Inventory, item)
+	saveHouseItems(player, house)
+	return fmt.Sprintf("You take %s out of your storage chest.", item.Name())
+}
+
+// handleHome takes the player back to their house
+func handleHome(player *Player, args []string) string {
+	house := houseOwnedBy(player.Name)
+	if house == nil {
+		return "You don't have a home to go to."
+	}
+	if player.Room.ID == house.RoomID {
+		return "You are already home."
+	}
+
+	home, err := GetRoom(house.RoomID)
+	if err != nil {
+		log.Printf("[ERROR] House %d of %s not found: %v", house.RoomID, player.Name, err)
+		return "You can't seem to find your way home."
+	}
+	if err := UpdatePlayerRoom(player.Name, home.ID); err != nil {
+		log.Printf("[ERROR] Failed to update player room during home: %v", err)
+		return "You can't seem to find your way home."
+	}
+
+	oldRoom := player.Room
+	player.Room = home
+	Publish(Event{Type: EventRoomEntered, Player: player, Room: home})
+	log.Printf("[HOME] Player %s went home to Room %d.", player.Name, home.ID)
+
+	BroadcastToRoom("$n heads for home.", oldRoom, player)
+	BroadcastToRoom("$n arrives home.", home, player)
+
+	player.Send("You make your way home.")
+	player.Send(DescribeRoom(home, player))
+	player.SendRoomInfo()
+	return ""
+}
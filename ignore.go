@@ -0,0 +1,113 @@
+/*
+ * ignore.go
+ *
+ * This file implements the 'ignore' command. Ignoring a player hides their
+ * tells, replies, and whispers, and everything they say on the chat
+ * channels and on 'looc'. They're told they're being ignored when they try
+ * to send a tell, but not when they talk on a channel. Staff can't be
+ * ignored. The list is saved with the character, and 'ignore <player>'
+ * again stops ignoring them.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// MaxIgnored is the most players a character can ignore at once
+const MaxIgnored = 50
+
+// Ignores reports whether the player is ignoring another
+func (p *Player) Ignores(other *Player) bool {
+	if other == nil {
+		return false
+	}
+	p.listsMu.Lock()
+	defer p.listsMu.Unlock()
+	return p.Ignoring[other.Name]
+}
+
+// ignoredNames returns the names of the players the player is ignoring, sorted
+func (p *Player) ignoredNames() []string {
+	p.listsMu.Lock()
+	defer p.listsMu.Unlock()
+
+	names := make([]string, 0, len(p.Ignoring))
+	for name := range p.Ignoring {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadIgnores restores the players the player is ignoring
+func (p *Player) LoadIgnores() {
+	names, err := LoadPlayerIgnores(p.Name)
+	if err != nil {
+		log.Printf("Error loading ignore list for %s: %v", p.Name, err)
+	}
+
+	p.listsMu.Lock()
+	defer p.listsMu.Unlock()
+	p.Ignoring = make(map[string]bool)
+	for _, name := range names {
+		p.Ignoring[name] = true
+	}
+}
+
+// handleIgnore lists the players being ignored, or starts or stops ignoring one
+// Usage: ignore [player]
+func handleIgnore(player *Player, args []string) string {
+	if len(args) == 0 {
+		names := player.ignoredNames()
+		if len(names) == 0 {
+			return "You aren't ignoring anyone."
+		}
+		return fmt.Sprintf("You are ignoring: %s", strings.Join(names, ", "))
+	}
+
+	name, ok := resolvePlayerName(args[0])
+	if !ok {
+		return fmt.Sprintf("There is no one called %s.", capitalizeFirst(args[0]))
+	}
+	if name == player.Name {
+		return "You can't ignore yourself, however much you'd like to."
+	}
+
+	player.listsMu.Lock()
+	ignoring := player.Ignoring[name]
+	count := len(player.Ignoring)
+	player.listsMu.Unlock()
+
+	if !ignoring {
+		if trust, err := LoadPlayerTrust(name); err == nil && trust > TrustMortal {
+			return "You can't ignore staff."
+		}
+		if count >= MaxIgnored {
+			return fmt.Sprintf("You can't ignore more than %d players.", MaxIgnored)
+		}
+	}
+
+	player.listsMu.Lock()
+	if player.Ignoring == nil {
+		player.Ignoring = make(map[string]bool)
+	}
+	if ignoring {
+		delete(player.Ignoring, name)
+	} else {
+		player.Ignoring[name] = true
+	}
+	player.listsMu.Unlock()
+
+	if err := SetPlayerIgnore(player.Name, name, !ignoring); err != nil {
+		log.Printf("Error saving ignore list for %s: %v", player.Name, err)
+	}
+	if ignoring {
+		return fmt.Sprintf("You stop ignoring %s.", name)
+	}
+	return fmt.Sprintf("You now ignore %s.", name)
+}
@@ -0,0 +1,423 @@
+/*
+ * immortal.go
+ *
+ * This file implements staff trust levels and the immortal command set.
+ * Every character has a trust level, from mortal up to implementor, and
+ * each immortal command needs a certain trust before it can be used; the
+ * requirements live in commandRules alongside the other command checks.
+ * Any trust above mortal makes a character staff. Immortals can restore,
+ * transfer, and hide from players of lower trust, gods can also slay,
+ * purge, and force, and implementors hand out trust with the 'trust'
+ * command. Nobody can slay, force, or change the trust of a character
+ * whose trust is as high as their own.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Trust levels, from the least trusted to the most
+const (
+	TrustMortal      = iota // An ordinary player
+	TrustImmortal           // May restore, transfer, peace, go invisible, and use the staff commands
+	TrustGod                // May also slay, purge, force, and grant XP
+	TrustImplementor        // May also set trust levels
+)
+
+// trustNames are the names of the trust levels, indexed by level
+var trustNames = []string{"mortal", "immortal", "god", "implementor"}
+
+// force runs other commands through HandleCommand, so it joins the command table here
+// rather than in its declaration, which can't refer to itself
+func init() {
+	commandHandlers["force"] = handleForce
+}
+
+// SetTrust gives the player a trust level, making them staff if it's above mortal
+func (p *Player) SetTrust(trust int) {
+	p.Trust = trust
+	p.Staff = trust > TrustMortal
+	if trust < TrustImmortal {
+		p.WizInvis = false
+	}
+}
+
+// parseTrust reads a trust level given by number or name
+func parseTrust(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n >= TrustMortal && n <= TrustImplementor
+	}
+	for level, name := range trustNames {
+		if strings.EqualFold(name, s) {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// staffName returns the name a staff member goes by to the viewer, which is "Someone" if they're hidden
+func staffName(staff, viewer *Player) string {
+	if !viewer.CanSeePlayer(staff) {
+		return "Someone"
+	}
+	return staff.Name
+}
+
+// otherPlayers returns the players online other than the given one
+func otherPlayers(player *Player) []*Player {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	var others []*Player
+	for _, p := range activePlayers {
+		if p != player {
+			others = append(others, p)
+		}
+	}
+	return others
+}
+
+// restorePlayer fills a player's health, mana, and stamina
+func restorePlayer(target, by *Player) {
+	target.HP = target.MaxHP
+	target.MP = target.MaxMP
+	target.Stamina = target.MaxStamina
+	if err := UpdatePlayerStats(target.Name, target.HP, target.MaxHP, target.MP, target.MaxMP, target.Stamina, target.MaxStamina); err != nil {
+		log.Printf("Error saving %s's stats after a restore: %v", target.Name, err)
+	}
+	target.SendStatus()
+	target.SendVitals()
+	if target != by {
+		target.Send(fmt.Sprintf("{W}%s has restored you.{x}", staffName(by, target)))
+	}
+}
+
+// handleRestore fills the health, mana, and stamina of the player, someone else, or everyone online
+// Usage: restore [player|all]
+func handleRestore(player *Player, args []string) string {
+	if len(args) == 0 {
+		restorePlayer(player, player)
+		return "You are fully restored."
+	}
+
+	if strings.EqualFold(args[0], "all") {
+		count := 0
+		for _, p := range otherPlayers(player) {
+			if !p.IsDead {
+				restorePlayer(p, player)
+				count++
+			}
+		}
+		restorePlayer(player, player)
+		log.Printf("%s restored everyone online", player.Name)
+		return fmt.Sprintf("You restore yourself and %d other players.", count)
+	}
+
+	target := FindPlayerByName(args[0])
+	if target == nil {
+		return "They aren't here."
+	}
+	if target.IsDead {
+		return fmt.Sprintf("%s is dead.", target.Name)
+	}
+	restorePlayer(target, player)
+	log.Printf("%s restored %s", player.Name, target.Name)
+	return fmt.Sprintf("You restore %s.", target.Name)
+}
+
+// handleSlay kills a mob or player in the room outright, without a fight or any experience
+// Usage: slay <target>
+func handleSlay(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Slay whom?"
+	}
+	name := strings.Join(args, " ")
+	room := player.Room
+
+	if mob := FindMobInRoom(room.ID, name); mob != nil {
+		for _, p := range playersInRoom(room) {
+			if p.Target == mob {
+				p.ExitCombat()
+			}
+		}
+		if mob.Master != nil {
+			ReleaseFollower(mob)
+		}
+		BroadcastToRoom(fmt.Sprintf("$n slays %s in cold blood!", mob.ShortDescription), room, player)
+		AddItemToRoom(CreateMobCorpse(mob), room)
+		RemoveMobFromRoom(mob)
+		return fmt.Sprintf("You slay %s in cold blood!", mob.ShortDescription)
+	}
+
+	target := player.FindVisiblePlayerInRoom(name)
+	if target == nil {
+		return "They aren't here."
+	}
+	if target == player {
+		return "Suicide is a mortal sin."
+	}
+	if target.Trust >= player.Trust {
+		return "You failed."
+	}
+	log.Printf("%s slew %s in room %d", player.Name, target.Name, room.ID)
+	target.Send(fmt.Sprintf("{R}%s slays you in cold blood!{x}", staffName(player, target)))
+	// Dying waits out the respawn delay, so it mustn't hold up the slayer's command
+	go target.die(staffName(player, target), nil)
+	return fmt.Sprintf("You slay %s in cold blood!", target.Name)
+}
+
+// handleTransfer brings a player, or everyone online, to the staff member's room or the given room
+// Usage: transfer <player|all> [room]
+func handleTransfer(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Usage: transfer <player|all> [room]"
+	}
+
+	destination := player.Room
+	if len(args) > 1 {
+		roomID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "Invalid room ID. Please specify a numeric room ID."
+		}
+		if destination, err = GetRoom(roomID); err != nil {
+			return fmt.Sprintf("Room %d does not exist.", roomID)
+		}
+	}
+
+	var targets []*Player
+	if strings.EqualFold(args[0], "all") {
+		for _, p := range otherPlayers(player) {
+			if !p.IsDead && p.Room != destination {
+				targets = append(targets, p)
+			}
+		}
+	} else {
+		target := FindPlayerByName(args[0])
+		switch {
+		case target == nil:
+			return "They aren't here."
+		case target == player:
+			return "Use goto to move yourself."
+		case target.IsDead:
+			return fmt.Sprintf("%s is dead.", target.Name)
+		case target.Room == destination:
+			return fmt.Sprintf("%s is already there.", target.Name)
+		}
+		targets = append(targets, target)
+	}
+
+	for _, target := range targets {
+		TransferPlayer(target, destination)
+		log.Printf("%s transferred %s to room %d", player.Name, target.Name, destination.ID)
+	}
+	if len(targets) == 1 {
+		return fmt.Sprintf("You transfer %s to %s.", targets[0].Name, destination.Name)
+	}
+	return fmt.Sprintf("You transfer %d players to %s.", len(targets), destination.Name)
+}
+
+// forcePlayer makes a player carry out a command as though they'd typed it
+func forcePlayer(target, by *Player, command string) {
+	target.Send(fmt.Sprintf("%s forces you to '%s'.", staffName(by, target), command))
+	if response := HandleCommand(target, command); response != "" {
+		target.Send(response)
+	}
+}
+
+// handleForce makes a player of lower trust, or all of them, carry out a command
+// Usage: force <player|all> <command>
+func handleForce(player *Player, args []string) string {
+	if len(args) < 2 {
+		return "Usage: force <player|all> <command>"
+	}
+	command := strings.Join(args[1:], " ")
+
+	if strings.EqualFold(args[0], "all") {
+		count := 0
+		for _, p := range otherPlayers(player) {
+			if p.Trust < player.Trust {
+				forcePlayer(p, player, command)
+				count++
+			}
+		}
+		log.Printf("%s forced everyone to '%s'", player.Name, command)
+		return fmt.Sprintf("You force %d players to '%s'.", count, command)
+	}
+
+	target := FindPlayerByName(args[0])
+	switch {
+	case target == nil:
+		return "They aren't here."
+	case target == player:
+		return "Just type it yourself."
+	case target.Trust >= player.Trust:
+		return "Do it yourself!"
+	}
+	log.Printf("%s forced %s to '%s'", player.Name, target.Name, command)
+	forcePlayer(target, player, command)
+	return fmt.Sprintf("You force %s to '%s'.", target.Name, command)
+}
+
+// handleInvis hides the staff member from players and mobs of lower trust, or brings them back into view
+func handleInvis(player *Player, args []string) string {
+	if player.WizInvis {
+		player.WizInvis = false
+		BroadcastToRoom("$n slowly fades into existence.", player.Room, player)
+		return "You slowly fade back into existence."
+	}
+	BroadcastToRoom("$n slowly fades into thin air.", player.Room, player)
+	player.WizInvis = true
+	return "You slowly vanish into thin air."
+}
+
+// handlePeace stops every fight in the room
+func handlePeace(player *Player, args []string) string {
+	for _, p := range playersInRoom(player.Room) {
+		if p.IsInCombat() {
+			p.ExitCombat()
+			if p != player {
+				p.Send("A sudden calm comes over you, and you stop fighting.")
+			}
+		}
+	}
+
+	mobMutex.RLock()
+	for _, mob := range GetMobsInRoom(player.Room.ID) {
+		mob.Fighting = nil
+		mob.Pursuing = nil
+	}
+	mobMutex.RUnlock()
+
+	BroadcastToRoom("$n raises a hand, and a sudden calm falls over the room.", player.Room, player)
+	return "You raise a hand, and all fighting in the room stops."
+}
+
+// purgeMob removes a mob from the world, ending any fight with it
+func purgeMob(mob *MobInstance) {
+	for _, p := range playersInRoom(mob.Room) {
+		if p.Target == mob {
+			p.ExitCombat()
+		}
+	}
+	if mob.Master != nil {
+		ReleaseFollower(mob)
+	}
+	RemoveMobFromRoom(mob)
+}
+
+// itemGold returns the gold held by an item and everything inside it
+func itemGold(item *Item) int {
+	gold := item.Gold
+	for _, content := range item.Contents {
+		gold += itemGold(content)
+	}
+	return gold
+}
+
+// handlePurge removes a mob or item from the room, or every mob and item that doesn't belong to a player
+// Usage: purge [target]
+func handlePurge(player *Player, args []string) string {
+	room := player.Room
+
+	if len(args) == 0 {
+		mobMutex.RLock()
+		mobs := append([]*MobInstance(nil), GetMobsInRoom(room.ID)...)
+		mobMutex.RUnlock()
+		for _, mob := range mobs {
+			// Pets and charmed followers belong to their masters
+			if mob.Master == nil {
+				purgeMob(mob)
+			}
+		}
+
+		itemMutex.Lock()
+		gold := 0
+		for _, item := range roomItems[room.ID] {
+			gold += itemGold(item)
+		}
+		delete(roomItems, room.ID)
+		itemMutex.Unlock()
+		RecordGoldDestroyed(GoldSourcePurge, gold)
+
+		log.Printf("%s purged room %d", player.Name, room.ID)
+		BroadcastToRoom("$n purges the room!", room, player)
+		return "You purge the room."
+	}
+
+	name := strings.Join(args, " ")
+	if mob := FindMobInRoom(room.ID, name); mob != nil {
+		purgeMob(mob)
+		BroadcastToRoom(fmt.Sprintf("$n purges %s.", mob.ShortDescription), room, player)
+		return fmt.Sprintf("You purge %s.", mob.ShortDescription)
+	}
+	if item := FindItemInList(GetItemsInRoom(room), name); item != nil && RemoveItemFromRoom(item, room) {
+		RecordGoldDestroyed(GoldSourcePurge, itemGold(item))
+		BroadcastToRoom(fmt.Sprintf("$n purges %s.", item.ShortDescription), room, player)
+		return fmt.Sprintf("You purge %s.", item.ShortDescription)
+	}
+	if player.FindVisiblePlayerInRoom(name) != nil {
+		return "You can't purge players."
+	}
+	return "You don't see that here."
+}
+
+// handleTrust shows or sets a character's trust level
+// Usage: trust, trust <player>, trust <player> <level>
+func handleTrust(player *Player, args []string) string {
+	if len(args) == 0 {
+		var sb strings.Builder
+		sb.WriteString("Trust levels:\r\n")
+		for level, name := range trustNames {
+			sb.WriteString(fmt.Sprintf("  %d  %s\r\n", level, name))
+		}
+		sb.WriteString(fmt.Sprintf("Your trust is %s.", trustNames[player.Trust]))
+		return sb.String()
+	}
+
+	name, ok := resolvePlayerName(args[0])
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", args[0])
+	}
+	target := FindPlayerByName(name)
+	current := 0
+	if target != nil {
+		current = target.Trust
+	} else if trust, err := LoadPlayerTrust(name); err != nil {
+		log.Printf("Error loading trust level for %s: %v", name, err)
+		return "Error loading their trust level."
+	} else {
+		current = trust
+	}
+
+	if len(args) < 2 {
+		return fmt.Sprintf("%s's trust is %s.", name, trustNames[current])
+	}
+
+	level, ok := parseTrust(args[1])
+	switch {
+	case !ok:
+		return "Trust must be one of: " + strings.Join(trustNames, ", ") + "."
+	case level > player.Trust:
+		return "You can't grant more trust than you have."
+	case current >= player.Trust && !strings.EqualFold(name, player.Name):
+		return fmt.Sprintf("%s is as trusted as you are.", name)
+	}
+
+	if err := SetPlayerTrust(name, level); err != nil {
+		log.Printf("Error saving trust level for %s: %v", name, err)
+		return "Error saving their trust level."
+	}
+	if target != nil {
+		target.SetTrust(level)
+		if target != player {
+			target.Send(fmt.Sprintf("{W}%s has made you %s.{x}", player.Name, trustNames[level]))
+		}
+	}
+	log.Printf("%s set %s's trust to %s", player.Name, name, trustNames[level])
+	return fmt.Sprintf("%s is now %s.", name, trustNames[level])
+}
@@ -0,0 +1,192 @@
+/*
+ * inactive.go
+ *
+ * This file tracks when players were last seen and clears out characters
+ * that have been abandoned. Each login and logout is recorded, and whois
+ * shows how long ago an offline player was last on. Characters that
+ * haven't logged in for the configured number of days can be purged,
+ * after being archived to a file so they can be recovered by hand. Staff,
+ * characters at or above the protected level, and house owners are kept.
+ * Staff review the candidates with the 'inactive' command before purging
+ * them, or the server can purge them once a day on its own.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveDir holds the characters saved before being purged
+const ArchiveDir = "archive"
+
+// purgeInterval is how many ticks pass between automatic purges, about a day
+const purgeInterval = 24 * 60
+
+// purgeTicks counts the ticks toward the next automatic purge
+var purgeTicks int
+
+// RecordLogin notes when the player logged in
+func (p *Player) RecordLogin() {
+	if err := UpdatePlayerLastLogin(p.Name, time.Now()); err != nil {
+		log.Printf("Error saving login time for %s: %v", p.Name, err)
+	}
+}
+
+// RecordLogout notes when the player logged out
+func (p *Player) RecordLogout() {
+	if err := UpdatePlayerLastLogout(p.Name, time.Now()); err != nil {
+		log.Printf("Error saving logout time for %s: %v", p.Name, err)
+	}
+}
+
+// keepReason explains why an inactive character won't be purged, or returns "" if it will
+func keepReason(p InactivePlayer) string {
+	switch {
+	case p.Staff:
+		return "staff"
+	case config.Purge.ProtectLevel > 0 && p.Level >= config.Purge.ProtectLevel:
+		return fmt.Sprintf("level %d", p.Level)
+	case p.House:
+		return "owns a house"
+	case FindPlayerByName(p.Name) != nil:
+		return "online"
+	}
+	return ""
+}
+
+// archivePlayer writes everything saved about a character to a file in the archive folder
+func archivePlayer(name string) (string, error) {
+	data, err := LoadPlayerData(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(ArchiveDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(ArchiveDir, fmt.Sprintf("%s-%s.json", strings.ToLower(name), time.Now().Format("20060102")))
+	return path, os.WriteFile(path, content, 0644)
+}
+
+// purgePlayer archives a character if configured to, then deletes it
+func purgePlayer(name string) error {
+	if config.Purge.Archive {
+		if _, err := archivePlayer(name); err != nil {
+			return fmt.Errorf("archiving: %w", err)
+		}
+	}
+	return DeletePlayerData(name)
+}
+
+// PurgeInactive purges the characters unseen for the given number of days, returning those purged and those kept
+// With dryRun set, it only reports who would be.
+func PurgeInactive(days int, dryRun bool) (purged, kept []InactivePlayer, err error) {
+	candidates, err := LoadInactivePlayers(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, p := range candidates {
+		if keepReason(p) != "" {
+			kept = append(kept, p)
+			continue
+		}
+		if !dryRun {
+			if err := purgePlayer(p.Name); err != nil {
+				log.Printf("Error purging %s: %v", p.Name, err)
+				continue
+			}
+			log.Printf("Purged %s, last seen %s", p.Name, p.LastSeen.Format("2006-01-02"))
+		}
+		purged = append(purged, p)
+	}
+	return purged, kept, nil
+}
+
+// ProcessInactivePurge purges inactive characters once a day if automatic purging is on
+func ProcessInactivePurge() {
+	purgeTicks++
+	if purgeTicks < purgeInterval || !config.Purge.Auto {
+		return
+	}
+	purgeTicks = 0
+
+	purged, _, err := PurgeInactive(config.Purge.InactiveDays, false)
+	if err != nil {
+		log.Printf("Error purging inactive characters: %v", err)
+		return
+	}
+	if len(purged) > 0 {
+		log.Printf("Purged %d characters inactive for %d days", len(purged), config.Purge.InactiveDays)
+	}
+}
+
+// handleInactive lists the characters that would be purged, or purges them (staff only)
+// Usage: inactive [days], inactive purge [days]
+func handleInactive(player *Player, args []string) string {
+	dryRun := true
+	if len(args) > 0 && strings.EqualFold(args[0], "purge") {
+		dryRun = false
+		args = args[1:]
+	}
+
+	days := config.Purge.InactiveDays
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "Usage: inactive [days], inactive purge [days]"
+		}
+		days = n
+	}
+
+	purged, kept, err := PurgeInactive(days, dryRun)
+	if err != nil {
+		log.Printf("Error finding inactive characters: %v", err)
+		return "Error finding inactive characters."
+	}
+	if len(purged) == 0 && len(kept) == 0 {
+		return fmt.Sprintf("No characters have been inactive for %d days.", days)
+	}
+
+	var sb strings.Builder
+	if dryRun {
+		sb.WriteString(fmt.Sprintf("{Y}Characters inactive for %d days{x} (nothing has been purged)\r\n", days))
+	} else {
+		sb.WriteString(fmt.Sprintf("{Y}Purged characters inactive for %d days{x}\r\n", days))
+		log.Printf("%s purged %d characters inactive for %d days", player.Name, len(purged), days)
+	}
+	sb.WriteString(fmt.Sprintf("%-15s %5s  %-12s %s\r\n", "Name", "Level", "Last seen", "Status"))
+
+	status := "{R}purged{x}"
+	if dryRun {
+		status = "{R}will be purged{x}"
+	}
+	for _, p := range purged {
+		sb.WriteString(fmt.Sprintf("%-15s %5d  %-12s %s\r\n", p.Name, p.Level, p.LastSeen.Format("2006-01-02"), status))
+	}
+	for _, p := range kept {
+		sb.WriteString(fmt.Sprintf("%-15s %5d  %-12s {G}kept{x} (%s)\r\n", p.Name, p.Level, p.LastSeen.Format("2006-01-02"), keepReason(p)))
+	}
+
+	if dryRun && len(purged) > 0 {
+		sb.WriteString("Type 'inactive purge")
+		if len(args) > 0 {
+			sb.WriteString(" " + args[0])
+		}
+		sb.WriteString("' to purge them.")
+	} else if !dryRun && config.Purge.Archive && len(purged) > 0 {
+		sb.WriteString(fmt.Sprintf("Purged characters were archived in the %s folder.", ArchiveDir))
+	}
+	return strings.TrimRight(sb.String(), "\r\n")
+}
@@ -2,102 +2,44 @@
  * info.go
  *
  * This file contains functions for displaying information to players.
- * It implements room descriptions, the look command, direction viewing,
- * and player scorecard functionality. The file handles formatting output
- * with appropriate colors and organizing information in a readable way
- * for players to understand their surroundings and character status.
+ * It implements the look command, direction viewing, and player scorecard
+ * functionality; rooms themselves are drawn in display.go. The file handles
+ * formatting output with appropriate colors and organizing information in a
+ * readable way for players to understand their surroundings and character
+ * status.
  */
 
 package main
 
 import (
 	"fmt"
-	"sort"
 	"strings"
+	"time"
 )
 
-// DescribeRoom prints the description of the current room
-func DescribeRoom(room *Room, viewer *Player) string {
-	// Get available exits and sort them
-	var exits []string
-	for direction, exit := range room.Exits {
-		if exit.Door != nil && exit.Door.Closed {
-			// Show closed doors in parentheses
-			exits = append(exits, fmt.Sprintf("(%s)", direction))
-		} else {
-			exits = append(exits, direction)
-		}
+// HandleLook processes the look command and its arguments
+func HandleLook(player *Player, args []string) string {
+	if len(args) == 0 {
+		return RenderRoom(player.Room, player, player.RoomView(player.Room, true))
 	}
-	sort.Strings(exits)
-
-	// Get list of other players in the room (excluding the viewer)
-	playersMutex.Lock()
-	var otherPlayers []string
-	for _, p := range activePlayers {
-		if p != viewer && // Not the viewing player
-			p.Room != nil && viewer.Room != nil && // Both rooms exist
-			p.Room == viewer.Room { // Exact same room instance
-			// Include the player's title if they have one
-			if p.Title != "" {
-				otherPlayers = append(otherPlayers, fmt.Sprintf("%s %s", p.Name, p.Title))
-			} else {
-				otherPlayers = append(otherPlayers, p.Name)
-			}
-		}
+	if !player.CanSee() {
+		return "It's too dark to see anything."
 	}
-	playersMutex.Unlock()
-
-	// Build the room description with colors
-	description := fmt.Sprintf("{C}%s{x}\n%s",
-		room.Name,
-		room.Description)
-
-	// Add mobs in the room
-	mobMutex.RLock()
-	mobs := GetMobsInRoom(room.ID)
-
-	if len(mobs) > 0 {
-		description += "\n" // Single newline before mobs
-
-		// Display mobs without numbering in the description
-		for _, mob := range mobs {
-			if mob != nil {
-				// Check if this mob is in combat with any player
-				combatStatus := ""
-				playersMutex.Lock()
-				for _, p := range activePlayers {
-					if p.IsInCombat() && p.Target == mob {
-						if p == viewer {
-							combatStatus = " {R}[FIGHTING YOU]{x}"
-						} else {
-							combatStatus = fmt.Sprintf(" {R}[FIGHTING %s]{x}", p.Name)
-						}
-						break
-					}
-				}
-				playersMutex.Unlock()
 
-				description += fmt.Sprintf("%s%s\n", mob.LongDescription, combatStatus)
-			}
+	// "look in bag" looks at what's inside a container
+	if len(args) > 1 && strings.EqualFold(args[0], "in") {
+		target := strings.ToLower(strings.Join(args[1:], " "))
+		container := FindItemInList(GetItemsInRoom(player.Room), target)
+		if container == nil {
+			container = FindItemInList(player.Inventory, target)
 		}
-	}
-	mobMutex.RUnlock()
-
-	// Add exits after mobs
-	description += fmt.Sprintf("\n{G}Available exits:{x} [%s]", strings.Join(exits, ", "))
-
-	// Add other players if present
-	if len(otherPlayers) > 0 {
-		description += fmt.Sprintf("\n{Y}Also here:{x} %s", strings.Join(otherPlayers, ", "))
-	}
-
-	return description
-}
-
-// HandleLook processes the look command and its arguments
-func HandleLook(player *Player, args []string) string {
-	if len(args) == 0 {
-		return DescribeRoom(player.Room, player)
+		if container == nil {
+			return "You do not see that here."
+		}
+		if !container.IsContainer() {
+			return fmt.Sprintf("%s is not a container.", capitalizeFirst(container.ShortDescription))
+		}
+		return DescribeItemContents(container)
 	}
 
 	// Check if looking at a direction
@@ -106,7 +48,7 @@ func HandleLook(player *Player, args []string) string {
 		direction = fullDirection
 	}
 	// If it's a direction (either an alias or full name), handle it
-	if _, exists := player.Room.Exits[direction]; exists {
+	if exit, exists := player.Room.Exits[direction]; exists && player.CanSeeExit(exit) {
 		return LookDirection(player.Room, direction)
 	}
 	// If it's a valid direction but no exit exists
@@ -138,8 +80,36 @@ func HandleLook(player *Player, args []string) string {
 		}
 
 		// Return the mob's description along with some basic stats and combat status
-		return fmt.Sprintf("%s\n[Level %d %s] [HP: %d/%d]%s",
+		output := fmt.Sprintf("%s\n[Level %d %s] [HP: %d/%d]%s",
 			mob.Description, mob.Level, mob.Toughness, mob.HP, mob.MaxHP, combatStatus)
+		if player.MobAura(mob) != "" {
+			output += "\n{R}A red aura of evil surrounds " + mob.ShortDescription + ".{x}"
+		}
+		return output
+	}
+
+	// Check items on the ground and in the player's inventory
+	item := FindItemInList(GetItemsInRoom(player.Room), lookTarget)
+	if item == nil {
+		item = FindItemInList(player.Inventory, lookTarget)
+	}
+	if item != nil {
+		description := item.Description
+		if player.ItemAura(item) != "" {
+			description += "\n{B}It glows with a faint blue aura of magic.{x}"
+		}
+		if item.IsContainer() {
+			return fmt.Sprintf("%s\n%s", description, DescribeItemContents(item))
+		}
+		if item.Type == "drink" {
+			return fmt.Sprintf("%s\n%s", description, item.describeFill())
+		}
+		return description
+	}
+
+	// Check resource nodes
+	if node := findResourceNode(player.Room, lookTarget); node != nil {
+		return lookAtResourceNode(node)
 	}
 
 	// Check environment attributes
@@ -168,13 +138,25 @@ func LookDirection(room *Room, direction string) string {
 	return fmt.Sprintf("You see a passage leading %s.", direction)
 }
 
+// formatAttribute shows an attribute's effective value, noting any change from affects and sets
+func formatAttribute(player *Player, attribute string) string {
+	if mod := player.Modifier(attribute); mod != 0 {
+		return fmt.Sprintf("%d (%+d)", player.Stat(attribute), mod)
+	}
+	return fmt.Sprintf("%d", player.Stat(attribute))
+}
+
 // GetScorecard returns a formatted string containing the player's complete stats
 func GetScorecard(player *Player) string {
 	// Update derived stats before displaying
 	player.UpdateDerivedStats()
 
-	// Format status effects (placeholder for now)
+	// Format status effects
 	status := "[No active effects]"
+	affects := player.Affects.Summary()
+	if len(affects) > 0 {
+		status = affects[0]
+	}
 
 	// Build the scorecard using strings.Builder for efficiency
 	var sb strings.Builder
@@ -193,14 +175,31 @@ func GetScorecard(player *Player) string {
 	}
 	sb.WriteString(fmt.Sprintf(" Title:        %s\n", titleToShow))
 
-	sb.WriteString(fmt.Sprintf(" XP:           %-12s  Gold:      %-6d\n", fmt.Sprintf("%d / %d", player.XP, player.NextLevelXP), player.Gold))
+	sb.WriteString(fmt.Sprintf(" XP:           %-12s  Gold:      %-6s\n", fmt.Sprintf("%s / %s", player.Locale.Number(player.XP), player.Locale.Number(player.NextLevelXP)), player.Locale.Number(player.Gold)))
+	sb.WriteString(fmt.Sprintf(" Carrying:     %s\n", player.describeLoad()))
+	if survivalEnabled() {
+		sb.WriteString(fmt.Sprintf(" Hunger:       %-12s  Thirst:    %-6s\n", player.describeHunger(), player.describeThirst()))
+	}
+	sb.WriteString(fmt.Sprintf(" Played:       %-12s  Session:   %-6s\n", formatDuration(time.Duration(player.Playtime)*time.Minute), formatDuration(player.SessionTime())))
+	if mount := player.Riding(); mount != nil {
+		sb.WriteString(fmt.Sprintf(" Riding:       %s\n", capitalizeFirst(mount.ShortDescription)))
+	}
 	sb.WriteString(fmt.Sprintf(" Status:       %-32s\n", status))
+	for _, line := range affects[min(1, len(affects)):] {
+		sb.WriteString(fmt.Sprintf("               %-32s\n", line))
+	}
+	if sets := player.SetBonusSummary(); len(sets) > 0 {
+		sb.WriteString(fmt.Sprintf(" Set Bonuses:  %-32s\n", sets[0]))
+		for _, line := range sets[1:] {
+			sb.WriteString(fmt.Sprintf("               %-32s\n", line))
+		}
+	}
 	sb.WriteString("-------------------------------------------------\n")
 	sb.WriteString("                   ATTRIBUTES                     \n")
 	sb.WriteString("-------------------------------------------------\n")
-	sb.WriteString(fmt.Sprintf(" Strength:     %-8d  Dexterity:    %-8d\n", player.STR, player.DEX))
-	sb.WriteString(fmt.Sprintf(" Constitution: %-8d  Intelligence: %-8d\n", player.CON, player.INT))
-	sb.WriteString(fmt.Sprintf(" Wisdom:       %-8d  Presence:     %-8d\n", player.WIS, player.PRE))
+	sb.WriteString(fmt.Sprintf(" Strength:     %-8s  Dexterity:    %-8s\n", formatAttribute(player, ApplySTR), formatAttribute(player, ApplyDEX)))
+	sb.WriteString(fmt.Sprintf(" Constitution: %-8s  Intelligence: %-8s\n", formatAttribute(player, ApplyCON), formatAttribute(player, ApplyINT)))
+	sb.WriteString(fmt.Sprintf(" Wisdom:       %-8s  Presence:     %-8s\n", formatAttribute(player, ApplyWIS), formatAttribute(player, ApplyPRE)))
 	sb.WriteString("-------------------------------------------------\n")
 	sb.WriteString("                  COMBAT STATS                    \n")
 	sb.WriteString("-------------------------------------------------\n")
@@ -0,0 +1,836 @@
+/*
+ * item.go
+ *
+ * This file implements the object (item) system for the MUD.
+ * It defines item templates loaded from area files, the live items lying
+ * in rooms or carried by players, and corpses left behind when mobs and
+ * players die. Corpses hold the gold and loot of the deceased and decay
+ * after a number of ticks driven by the TimeManager. Items belong to a
+ * rarity tier (common, uncommon, rare, epic) that colors their names and
+ * makes the rarer ones less likely to drop. Wearable items name the slot
+ * they are worn in and may belong to an item set (see equipment.go).
+ * Powerful items can be soulbound to the player who picks them up or wears
+ * them, after which they can't be dropped or handed to anyone else. The
+ * file also provides the get/put/drop/inventory/loot command handlers.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Item represents an object in the game world
+type Item struct {
+	ID               int      `yaml:"id"`
+	Keywords         []string `yaml:"keywords"`
+	ShortDescription string   `yaml:"short_description"`   // Used in inventory listings and action messages
+	LongDescription  string   `yaml:"long_description"`    // Displayed when the item is lying in a room
+	Description      string   `yaml:"description"`         // Displayed when a player looks at the item
+	Type             string   `yaml:"type"`                // trash, treasure, container, corpse, ...
+	Value            int      `yaml:"value"`               // Base value in gold
+	Rarity           string   `yaml:"rarity,omitempty"`    // common (default), uncommon, rare, or epic
+	WearSlot         string   `yaml:"wear_slot,omitempty"` // Where the item is worn ("" means it can't be worn)
+	Set              string   `yaml:"set,omitempty"`       // ID of the item set this piece belongs to
+	Bind             string   `yaml:"bind,omitempty"`      // "pickup" or "equip" to soulbind the item ("" = never)
+	Light            bool     `yaml:"light,omitempty"`     // Lights up dark rooms while worn or held
+	Magic            bool     `yaml:"magic,omitempty"`     // Marked as magical to players who can detect magic
+	Weight           int      `yaml:"weight,omitempty"`    // Weight in pounds
+	Capacity         int      `yaml:"capacity,omitempty"`  // Weight a container can hold (0 = no limit)
+	Quest            bool     `yaml:"quest,omitempty"`     // Needed for a quest, so it can't be sacrificed or junked
+	Cursed           bool     `yaml:"cursed,omitempty"`    // The gods won't accept it as a sacrifice
+	Nutrition        int      `yaml:"nutrition,omitempty"` // Food a meal gives, or water each sip of a drink gives
+	Sips             int      `yaml:"sips,omitempty"`      // Sips a full drink container holds
+	Liquid           string   `yaml:"liquid,omitempty"`    // What a drink container holds (default: water)
+
+	// Instance data (not part of the template)
+	Contents   []*Item // Items held inside a container or corpse
+	Gold       int     // Coins held inside a container or corpse
+	Owner      string  // Player allowed to loot this corpse ("" means anyone)
+	Looter     string  // Player owed the contents of a mob's corpse ("" means anyone)
+	Timer      int     // Ticks remaining before the item decays (0 = never)
+	Victim     string  // Whose corpse this is, to describe it as it decays (see corpse.go)
+	Lifetime   int     // Ticks a corpse lasts in all, to work out how far it has decayed
+	DecayStage int     // Stage of decay the corpse has reached
+	BoundTo    string  // Player this item is soulbound to ("" means it isn't bound)
+	SipsLeft   int     // Sips remaining in a drink container
+}
+
+// When an item becomes soulbound
+const (
+	BindOnPickup = "pickup" // Binds to the first player to pick it up
+	BindOnEquip  = "equip"  // Binds to the first player to wear it
+)
+
+// LootDrop represents an entry in a mob's loot table
+type LootDrop struct {
+	ItemVnum int `yaml:"item_vnum"`
+	Chance   int `yaml:"chance"` // Percent chance (1-100) that the item drops
+}
+
+// Item rarity tiers
+const (
+	RarityCommon   = "common"
+	RarityUncommon = "uncommon"
+	RarityRare     = "rare"
+	RarityEpic     = "epic"
+)
+
+// RarityDropWeight scales a loot table's drop chance by the item's rarity (percent)
+var RarityDropWeight = map[string]int{
+	RarityCommon:   100,
+	RarityUncommon: 60,
+	RarityRare:     25,
+	RarityEpic:     8,
+}
+
+// Corpse decay timers, in ticks (1 tick = 1 minute)
+const (
+	MobCorpseDecayTicks    = 5
+	PlayerCorpseDecayTicks = 30
+)
+
+// Global variables for item management
+var (
+	itemRegistry = make(map[int]*Item)   // Maps item vnum to item template
+	roomItems    = make(map[int][]*Item) // Maps room ID to items lying in that room
+	itemMutex    sync.RWMutex            // Mutex for thread-safe item operations
+)
+
+// RegisterItem adds an item template to the registry
+func RegisterItem(item *Item) {
+	// Trim any extra whitespace from descriptions
+	item.ShortDescription = strings.TrimSpace(item.ShortDescription)
+	item.LongDescription = strings.TrimSpace(item.LongDescription)
+	item.Description = strings.TrimSpace(item.Description)
+
+	// Anything without a recognized rarity is common
+	item.Rarity = strings.ToLower(strings.TrimSpace(item.Rarity))
+	if _, ok := RarityDropWeight[item.Rarity]; !ok {
+		if item.Rarity != "" {
+			log.Printf("Warning: item %d has unknown rarity %q, treating it as common", item.ID, item.Rarity)
+		}
+		item.Rarity = RarityCommon
+	}
+
+	item.Bind = strings.ToLower(strings.TrimSpace(item.Bind))
+	if item.Bind != "" && item.Bind != BindOnPickup && item.Bind != BindOnEquip {
+		log.Printf("Warning: item %d has unknown bind type %q, it will not bind", item.ID, item.Bind)
+		item.Bind = ""
+	}
+
+	item.WearSlot = strings.ToLower(strings.TrimSpace(item.WearSlot))
+	if item.WearSlot != "" && !isWearSlot(item.WearSlot) {
+		log.Printf("Warning: item %d has unknown wear slot %q and can't be worn", item.ID, item.WearSlot)
+		item.WearSlot = ""
+	}
+
+	itemMutex.Lock()
+	defer itemMutex.Unlock()
+
+	itemRegistry[item.ID] = item
+}
+
+// CreateItem creates a new instance of an item template
+func CreateItem(vnum int) (*Item, error) {
+	itemMutex.RLock()
+	template := itemRegistry[vnum]
+	itemMutex.RUnlock()
+
+	if template == nil {
+		return nil, fmt.Errorf("item vnum %d not found in registry", vnum)
+	}
+
+	return &Item{
+		ID:               template.ID,
+		Keywords:         template.Keywords,
+		ShortDescription: template.ShortDescription,
+		LongDescription:  template.LongDescription,
+		Description:      template.Description,
+		Type:             template.Type,
+		Value:            template.Value,
+		Rarity:           template.Rarity,
+		WearSlot:         template.WearSlot,
+		Set:              template.Set,
+		Bind:             template.Bind,
+		Light:            template.Light,
+		Magic:            template.Magic,
+		Weight:           template.Weight,
+		Capacity:         template.Capacity,
+		Quest:            template.Quest,
+		Cursed:           template.Cursed,
+		Nutrition:        template.Nutrition,
+		Sips:             template.Sips,
+		Liquid:           template.Liquid,
+		SipsLeft:         template.Sips,
+	}, nil
+}
+
+// GetItemTemplate returns the registered template for an item vnum, or nil
+func GetItemTemplate(vnum int) *Item {
+	itemMutex.RLock()
+	defer itemMutex.RUnlock()
+	return itemRegistry[vnum]
+}
+
+// Name returns the item's short description colored by its rarity
+func (i *Item) Name() string {
+	return ColorizeByRarity(i.ShortDescription, i.Rarity)
+}
+
+// RoomLine returns the item's long description colored by its rarity
+func (i *Item) RoomLine() string {
+	return ColorizeByRarity(i.LongDescription, i.Rarity)
+}
+
+// ListName returns the item's name for inventory listings, marking soulbound items
+func (i *Item) ListName() string {
+	if i.BoundTo != "" {
+		return i.Name() + " {D}(soulbound){x}"
+	}
+	return i.Name()
+}
+
+// BindTo soulbinds the item to a player if its bind type matches the trigger
+// Items that bind on pickup also bind when worn. Returns a message for the
+// player if the item became bound, or "" if nothing changed.
+func (i *Item) BindTo(player *Player, trigger string) string {
+	if i.BoundTo != "" || i.Bind == "" {
+		return ""
+	}
+	if i.Bind == BindOnEquip && trigger != BindOnEquip {
+		return ""
+	}
+	i.BoundTo = player.Name
+	return fmt.Sprintf("{D}%s binds itself to your soul.{x}", capitalizeFirst(i.ShortDescription))
+}
+
+// CanTransfer reports whether an item may be given away, traded, or dropped
+// Soulbound items stay with the player they are bound to, and so do containers holding them.
+func (i *Item) CanTransfer() bool {
+	if i.BoundTo != "" {
+		return false
+	}
+	for _, content := range i.Contents {
+		if !content.CanTransfer() {
+			return false
+		}
+	}
+	return true
+}
+
+// refuseTransfer tells the player why they can't part with an item
+func refuseTransfer(item *Item) string {
+	if item.BoundTo == "" {
+		return fmt.Sprintf("You can't part with %s while it holds something soulbound to you.", item.Name())
+	}
+	return fmt.Sprintf("You can't part with %s. It is soulbound to you.", item.Name())
+}
+
+// dropChance returns the percent chance that a loot table entry drops, weighted by rarity
+func dropChance(drop LootDrop, item *Item) int {
+	weight, ok := RarityDropWeight[item.Rarity]
+	if !ok {
+		weight = 100
+	}
+	return drop.Chance * weight / 100
+}
+
+// IsContainer reports whether other items can be taken out of this item
+func (i *Item) IsContainer() bool {
+	return i.Type == "container" || i.Type == "corpse"
+}
+
+// AddItemToRoom places an item on the floor of a room
+func AddItemToRoom(item *Item, room *Room) {
+	if item == nil || room == nil {
+		return
+	}
+
+	itemMutex.Lock()
+	defer itemMutex.Unlock()
+
+	roomItems[room.ID] = append(roomItems[room.ID], item)
+}
+
+// RemoveItemFromRoom removes an item from the floor of a room, reporting whether it was still there
+func RemoveItemFromRoom(item *Item, room *Room) bool {
+	if item == nil || room == nil {
+		return false
+	}
+
+	itemMutex.Lock()
+	defer itemMutex.Unlock()
+
+	before := len(roomItems[room.ID])
+	roomItems[room.ID] = removeItemFromList(roomItems[room.ID], item)
+	return len(roomItems[room.ID]) < before
+}
+
+// GetItemsInRoom returns a copy of the items lying in a room
+func GetItemsInRoom(room *Room) []*Item {
+	if room == nil {
+		return nil
+	}
+
+	itemMutex.RLock()
+	defer itemMutex.RUnlock()
+
+	items := make([]*Item, len(roomItems[room.ID]))
+	copy(items, roomItems[room.ID])
+	return items
+}
+
+// removeItemFromList returns the list without the given item, preserving order
+func removeItemFromList(items []*Item, item *Item) []*Item {
+	for i, it := range items {
+		if it == item {
+			return append(items[:i], items[i+1:]...)
+		}
+	}
+	return items
+}
+
+// FindItemInList finds an item in a list by keyword or short description
+// Supports numeric prefixes: "2.corpse" finds the second corpse in the list
+func FindItemInList(items []*Item, searchTerm string) *Item {
+	searchTerm = strings.ToLower(strings.TrimSpace(searchTerm))
+	if searchTerm == "" {
+		return nil
+	}
+
+	// Check for a numeric prefix
+	index := 1
+	if parts := strings.SplitN(searchTerm, ".", 2); len(parts) == 2 {
+		if n, err := strconv.Atoi(parts[0]); err == nil && n >= 1 {
+			index = n
+			searchTerm = parts[1]
+		}
+	}
+
+	// First pass: exact keyword matches
+	count := 0
+	for _, item := range items {
+		for _, keyword := range item.Keywords {
+			if strings.ToLower(keyword) == searchTerm {
+				count++
+				if count == index {
+					return item
+				}
+				break
+			}
+		}
+	}
+
+	// Second pass: partial matches in the short description
+	count = 0
+	for _, item := range items {
+		if strings.Contains(strings.ToLower(item.ShortDescription), searchTerm) {
+			count++
+			if count == index {
+				return item
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreateMobCorpse builds the corpse of a slain mob, rolling its gold and loot table
+func CreateMobCorpse(mob *MobInstance) *Item {
+	corpse := newCorpse(mob.ShortDescription, mob.Keywords, MobCorpseDecayTicks)
+
+	// Mobs without an explicit purse carry a little gold based on their level
+	gold := mob.Gold
+	if gold == 0 {
+		gold = rng.Intn(mob.Level*5 + 1)
+	}
+	corpse.Gold = gold
+	RecordGoldCreated(GoldSourceMobDrops, gold)
+
+	// Roll each entry of the loot table
+	for _, drop := range mob.Loot {
+		item, err := CreateItem(drop.ItemVnum)
+		if err != nil {
+			continue
+		}
+		if rng.Intn(100) >= dropChance(drop, item) {
+			continue
+		}
+		corpse.Contents = append(corpse.Contents, item)
+	}
+
+	return corpse
+}
+
+// CreatePlayerCorpse builds the corpse of a dead player holding their carried items
+func CreatePlayerCorpse(p *Player) *Item {
+	corpse := newCorpse(p.Name, []string{strings.ToLower(p.Name)}, PlayerCorpseDecayTicks)
+	corpse.Owner = p.Name
+	corpse.Contents = p.Inventory
+	for _, slot := range WearSlots {
+		if item := p.Equipment[slot]; item != nil {
+			corpse.Contents = append(corpse.Contents, item)
+		}
+	}
+	p.Inventory = nil
+	p.Equipment = nil
+	p.SetBonuses = nil
+	return corpse
+}
+
+// newCorpse creates an empty corpse item for the named victim
+func newCorpse(victim string, keywords []string, timer int) *Item {
+	corpseKeywords := append([]string{"corpse"}, keywords...)
+	corpse := &Item{
+		Keywords: corpseKeywords,
+		Type:     "corpse",
+		Timer:    timer,
+		Victim:   victim,
+		Lifetime: timer,
+	}
+	corpse.setCorpseStage(0)
+	return corpse
+}
+
+// ProcessItemDecay counts down item timers each tick and removes decayed items
+func ProcessItemDecay() {
+	type decayed struct {
+		item *Item
+		room *Room
+	}
+	var expired []decayed
+	var rotting []struct {
+		message string
+		room    *Room
+	}
+
+	itemMutex.Lock()
+	for roomID, items := range roomItems {
+		remaining := items[:0]
+		for _, item := range items {
+			if item.Timer > 0 {
+				item.Timer--
+				if item.Timer == 0 {
+					if room, err := GetRoom(roomID); err == nil {
+						expired = append(expired, decayed{item, room})
+					}
+					continue
+				}
+				if message := item.advanceCorpse(); message != "" {
+					if room, err := GetRoom(roomID); err == nil {
+						rotting = append(rotting, struct {
+							message string
+							room    *Room
+						}{message, room})
+					}
+				}
+			}
+			remaining = append(remaining, item)
+		}
+		roomItems[roomID] = remaining
+	}
+	itemMutex.Unlock()
+
+	// Notify players outside the lock
+	for _, r := range rotting {
+		BroadcastToRoom(ColorizeByType(capitalizeFirst(r.message), "notification"), r.room, nil)
+	}
+	for _, d := range expired {
+		// Coins left in a rotting corpse are gone for good
+		RecordGoldDestroyed(GoldSourceCorpseDecay, d.item.Gold)
+
+		message := fmt.Sprintf("%s decays into dust.", capitalizeFirst(d.item.ShortDescription))
+		BroadcastToRoom(ColorizeByType(message, "notification"), d.room, nil)
+	}
+}
+
+// capitalizeFirst upper-cases the first letter of a string
+func capitalizeFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// DescribeItemContents lists what is inside a container or corpse
+func DescribeItemContents(item *Item) string {
+	if len(item.Contents) == 0 && item.Gold == 0 {
+		return fmt.Sprintf("%s is empty.", capitalizeFirst(item.ShortDescription))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s contains:\r\n", capitalizeFirst(item.ShortDescription)))
+	if item.Gold > 0 {
+		sb.WriteString(fmt.Sprintf("  {Y}%d gold coins{x}\r\n", item.Gold))
+	}
+	for _, content := range item.Contents {
+		sb.WriteString(fmt.Sprintf("  %s\r\n", content.Name()))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// canLoot checks whether a player is allowed to take items out of a container
+func canLoot(player *Player, container *Item) bool {
+	return (container.Owner == "" || strings.EqualFold(container.Owner, player.Name)) &&
+		(container.Looter == "" || strings.EqualFold(container.Looter, player.Name))
+}
+
+// handleGet processes the get command
+// Usage: get <item>, get all, get <item|all> from <container>
+func handleGet(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Get what?"
+	}
+
+	// Check for "get X from Y"
+	for i, arg := range args {
+		if strings.ToLower(arg) == "from" {
+			if i == 0 || i == len(args)-1 {
+				return "Get what from what?"
+			}
+			return getFromContainer(player, strings.Join(args[:i], " "), strings.Join(args[i+1:], " "))
+		}
+	}
+
+	target := strings.ToLower(strings.Join(args, " "))
+	items := GetItemsInRoom(player.Room)
+
+	if target == "all" {
+		var taken, bound []string
+		heavy := false
+		for _, item := range items {
+			if item.Type == "corpse" {
+				continue // Corpses are too heavy to carry
+			}
+			if !player.CanLift(item) {
+				heavy = true
+				continue
+			}
+			if !RemoveItemFromRoom(item, player.Room) {
+				continue // Someone else picked it up first
+			}
+			player.Inventory = append(player.Inventory, item)
+			taken = append(taken, item.ShortDescription)
+			if message := item.BindTo(player, BindOnPickup); message != "" {
+				bound = append(bound, message)
+			}
+		}
+		if heavy {
+			bound = append(bound, "You can't carry any more.")
+		}
+		if len(taken) == 0 {
+			if heavy {
+				return "You can't carry any more."
+			}
+			return "You see nothing here you can take."
+		}
+		BroadcastToRoom("$n picks up some items.", player.Room, player)
+		return strings.Join(append([]string{fmt.Sprintf("You get %s.", strings.Join(taken, ", "))}, bound...), "\r\n")
+	}
+
+	item := FindItemInList(items, target)
+	if item == nil {
+		return "You don't see that here."
+	}
+	if item.Type == "corpse" {
+		return "You can't carry that. Try 'get all from corpse' instead."
+	}
+	if !player.CanLift(item) {
+		return fmt.Sprintf("%s is too heavy for you to carry.", capitalizeFirst(item.ShortDescription))
+	}
+
+	if !RemoveItemFromRoom(item, player.Room) {
+		return "You don't see that here."
+	}
+	player.Inventory = append(player.Inventory, item)
+	BroadcastToRoom(fmt.Sprintf("$n gets %s.", item.ShortDescription), player.Room, player)
+	output := fmt.Sprintf("You get %s.", item.Name())
+	if message := item.BindTo(player, BindOnPickup); message != "" {
+		output += "\r\n" + message
+	}
+	return output
+}
+
+// getFromContainer moves items (and gold) out of a container in the room or inventory
+func getFromContainer(player *Player, target string, containerName string) string {
+	container := FindItemInList(GetItemsInRoom(player.Room), containerName)
+	carried := false
+	if container == nil {
+		container = FindItemInList(player.Inventory, containerName)
+		carried = true
+	}
+	if container == nil {
+		return "You don't see that here."
+	}
+	if !container.IsContainer() {
+		return fmt.Sprintf("%s is not a container.", capitalizeFirst(container.ShortDescription))
+	}
+	if !canLoot(player, container) {
+		return "You cannot loot another player's corpse."
+	}
+	return takeFromContainer(player, target, container, carried)
+}
+
+// takeFromContainer moves items (and gold) out of a container the player may loot
+// carried says whether the player is holding the container already.
+func takeFromContainer(player *Player, target string, container *Item, carried bool) string {
+	// Taking something out of a bag you carry doesn't change your load
+	load := player.CarriedWeight()
+	limit := player.CarryCapacity() * MaxLoadMultiple
+	heavy := false
+
+	itemMutex.Lock()
+	var taken []*Item
+	gold := 0
+	if strings.ToLower(target) == "all" {
+		var left []*Item
+		for _, item := range container.Contents {
+			if !carried && load+item.TotalWeight() > limit {
+				left = append(left, item)
+				heavy = true
+				continue
+			}
+			load += item.TotalWeight()
+			taken = append(taken, item)
+		}
+		container.Contents = left
+		gold = container.Gold
+		container.Gold = 0
+	} else if strings.ToLower(target) == "gold" || strings.ToLower(target) == "coins" {
+		gold = container.Gold
+		container.Gold = 0
+	} else if item := FindItemInList(container.Contents, target); item != nil {
+		if !carried && load+item.TotalWeight() > limit {
+			heavy = true
+		} else {
+			taken = []*Item{item}
+			container.Contents = removeItemFromList(container.Contents, item)
+		}
+	}
+	itemMutex.Unlock()
+
+	if len(taken) == 0 && gold == 0 && heavy {
+		return "You can't carry any more."
+	}
+	if len(taken) == 0 && gold == 0 {
+		if strings.ToLower(target) == "all" {
+			return fmt.Sprintf("%s is empty.", capitalizeFirst(container.ShortDescription))
+		}
+		return fmt.Sprintf("You don't see that in %s.", container.ShortDescription)
+	}
+
+	var messages []string
+	for _, item := range taken {
+		player.Inventory = append(player.Inventory, item)
+		messages = append(messages, fmt.Sprintf("You get %s from %s.",
+			item.Name(), container.ShortDescription))
+		if message := item.BindTo(player, BindOnPickup); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	if gold > 0 {
+		// Gold taken from a mob's corpse is taxed; a player's own coins are not
+		tax := 0
+		if container.Type == "corpse" && container.Owner == "" {
+			gold, tax = ApplyLootTax(gold)
+		}
+		player.Gold += gold
+		messages = append(messages, fmt.Sprintf("You get {Y}%d{x} gold coins from %s.", gold, container.ShortDescription))
+		if tax > 0 {
+			messages = append(messages, fmt.Sprintf("The tax collector claims {Y}%d{x} gold.", tax))
+		}
+	}
+	if heavy {
+		messages = append(messages, "You can't carry any more.")
+	}
+
+	BroadcastToRoom(fmt.Sprintf("$n gets something from %s.", container.ShortDescription), player.Room, player)
+	return strings.Join(messages, "\r\n")
+}
+
+// handlePut places an item from the player's inventory into a container
+// Usage: put <item> in <container>
+func handlePut(player *Player, args []string) string {
+	split := -1
+	for i, arg := range args {
+		if strings.EqualFold(arg, "in") || strings.EqualFold(arg, "into") {
+			split = i
+			break
+		}
+	}
+	if split <= 0 || split == len(args)-1 {
+		return "Put what in what?"
+	}
+	target := strings.ToLower(strings.Join(args[:split], " "))
+	containerName := strings.ToLower(strings.Join(args[split+1:], " "))
+
+	container := FindItemInList(player.Inventory, containerName)
+	carried := container != nil
+	if container == nil {
+		container = FindItemInList(GetItemsInRoom(player.Room), containerName)
+	}
+	if container == nil {
+		return "You don't see that here."
+	}
+	if container.Type != "container" {
+		return fmt.Sprintf("You can't put things in %s.", container.ShortDescription)
+	}
+
+	item := FindItemInList(player.Inventory, target)
+	if item == nil {
+		return "You do not have that item."
+	}
+	if item == container {
+		return "You can't fold it into itself."
+	}
+	if !carried && !item.CanTransfer() {
+		return refuseTransfer(item)
+	}
+
+	itemMutex.Lock()
+	fits := container.Capacity == 0 ||
+		container.TotalWeight()-container.Weight+item.TotalWeight() <= container.Capacity
+	if fits {
+		container.Contents = append(container.Contents, item)
+	}
+	itemMutex.Unlock()
+	if !fits {
+		return fmt.Sprintf("%s won't fit in %s.", capitalizeFirst(item.ShortDescription), container.ShortDescription)
+	}
+
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	BroadcastToRoom(fmt.Sprintf("$n puts %s in %s.", item.ShortDescription, container.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You put %s in %s.", item.Name(), container.ShortDescription)
+}
+
+// handleLoot takes everything out of a corpse
+// Usage: loot [corpse]
+func handleLoot(player *Player, args []string) string {
+	target := "corpse"
+	if len(args) > 0 {
+		target = strings.Join(args, " ")
+	}
+	return getFromContainer(player, "all", target)
+}
+
+// handleDrop processes the drop command
+func handleDrop(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Drop what?"
+	}
+
+	target := strings.ToLower(strings.Join(args, " "))
+
+	if target == "all" {
+		if len(player.Inventory) == 0 {
+			return "You are not carrying anything."
+		}
+		var kept []*Item
+		for _, item := range player.Inventory {
+			if !item.CanTransfer() {
+				kept = append(kept, item)
+				continue
+			}
+			AddItemToRoom(item, player.Room)
+		}
+		if len(kept) == len(player.Inventory) {
+			return "Everything you are carrying is soulbound to you."
+		}
+		player.Inventory = kept
+		BroadcastToRoom("$n drops some items.", player.Room, player)
+		if len(kept) > 0 {
+			return "You drop everything you are carrying except your soulbound items."
+		}
+		return "You drop everything you are carrying."
+	}
+
+	item := FindItemInList(player.Inventory, target)
+	if item == nil {
+		return "You do not have that item."
+	}
+	if !item.CanTransfer() {
+		return refuseTransfer(item)
+	}
+
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	AddItemToRoom(item, player.Room)
+	BroadcastToRoom(fmt.Sprintf("$n drops %s.", item.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You drop %s.", item.Name())
+}
+
+// handleGive hands an item to a player or mob in the room
+// Usage: give <item> [to] <target>
+func handleGive(player *Player, args []string) string {
+	if len(args) > 2 && strings.EqualFold(args[len(args)-2], "to") {
+		args = append(args[:len(args)-2], args[len(args)-1])
+	}
+	if len(args) < 2 {
+		return "Give what to whom?"
+	}
+	targetName := args[len(args)-1]
+
+	item := FindItemInList(player.Inventory, strings.ToLower(strings.Join(args[:len(args)-1], " ")))
+	if item == nil {
+		return "You do not have that item."
+	}
+	if !item.CanTransfer() {
+		return refuseTransfer(item)
+	}
+
+	if target := player.FindVisiblePlayerInRoom(targetName); target != nil {
+		if target == player {
+			return "You already have it."
+		}
+		if !target.CanLift(item) {
+			return fmt.Sprintf("%s can't carry that much weight.", target.Name)
+		}
+		player.Inventory = removeItemFromList(player.Inventory, item)
+		target.Inventory = append(target.Inventory, item)
+		target.Send(fmt.Sprintf("%s gives you %s.", player.Name, item.Name()))
+		for _, p := range playersInRoom(player.Room) {
+			if p != player && p != target && p.CanSeePlayer(player) {
+				p.Send(fmt.Sprintf("%s gives %s to %s.", player.Name, item.ShortDescription, target.Name))
+			}
+		}
+		return fmt.Sprintf("You give %s to %s.", item.Name(), target.Name)
+	}
+
+	mob := FindMobByTarget(player.Room.ID, targetName)
+	if mob == nil {
+		return "They aren't here."
+	}
+	if IsMobInCombat(mob) || mob.Fighting != nil {
+		return fmt.Sprintf("%s is too busy fighting to take it.", capitalizeFirst(mob.ShortDescription))
+	}
+
+	// Mobs only take what their programs ask for, and the item is used up
+	prog := mob.GiveProg(item)
+	if prog == nil {
+		return fmt.Sprintf("%s doesn't want %s.", capitalizeFirst(mob.ShortDescription), item.ShortDescription)
+	}
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	player.Send(fmt.Sprintf("You give %s to %s.", item.Name(), mob.ShortDescription))
+	BroadcastToRoom(fmt.Sprintf("$n gives %s to %s.", item.ShortDescription, mob.ShortDescription), player.Room, player)
+	runMobProg(mob, player, *prog)
+	return ""
+}
+
+// handleInventory lists the items the player is carrying
+func handleInventory(player *Player, args []string) string {
+	if len(player.Inventory) == 0 {
+		return "You are carrying:\r\n  Nothing."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("You are carrying:\r\n")
+	for _, item := range player.Inventory {
+		sb.WriteString(fmt.Sprintf("  %s\r\n", item.ListName()))
+	}
+	sb.WriteString(fmt.Sprintf("Total weight: %s", player.describeLoad()))
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+/*
+ * killsteal.go
+ *
+ * This file protects players from having their kills stolen. The first
+ * player to attack a mob tags it, and the experience and the right to loot
+ * its corpse go to them no matter who lands the killing blow. Their charmed
+ * followers fight on their behalf. The tag lapses once they stop fighting
+ * the mob, whether they flee, die, or leave.
+ *
+ * Each area decides what happens when someone else tries to join a fight
+ * over a tagged mob. By default they're stopped; areas may instead just
+ * warn them that the kill isn't theirs, or turn tagging off altogether so
+ * anyone can pile in and the killing blow earns the kill, as suits a raid.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+// What happens when a player attacks a mob someone else has tagged
+const (
+	KillStealPrevent = "prevent" // They're stopped (the default)
+	KillStealWarn    = "warn"    // They may join in but are told the kill isn't theirs
+	KillStealAllow   = "allow"   // Mobs aren't tagged; the killing blow earns the kill
+)
+
+// validKillStealing lists the settings an area may use for kill_stealing
+var validKillStealing = map[string]bool{
+	KillStealPrevent: true,
+	KillStealWarn:    true,
+	KillStealAllow:   true,
+}
+
+// killStealing returns how the area a mob is in treats attacks on mobs tagged by someone else
+func killStealing(mob *MobInstance) string {
+	if mob.Room == nil {
+		return KillStealPrevent
+	}
+	if area := allAreas()[mob.Room.Area]; area != nil && area.KillStealing != "" {
+		return area.KillStealing
+	}
+	return KillStealPrevent
+}
+
+// isFightingMob reports whether the player, or one of their followers, is fighting a mob
+func (p *Player) isFightingMob(mob *MobInstance) bool {
+	if p.Target == mob && p.IsInCombat() {
+		return true
+	}
+	for _, follower := range p.Followers() {
+		if follower.Fighting == mob {
+			return true
+		}
+	}
+	return false
+}
+
+// TagHolder returns the player owed a mob's kill, or nil if no one has a claim on it
+// A tag lapses once its holder has stopped fighting the mob.
+func (m *MobInstance) TagHolder() *Player {
+	holder := m.TaggedBy
+	if holder == nil {
+		return nil
+	}
+	if killStealing(m) == KillStealAllow || holder.IsDead || holder.Room != m.Room ||
+		FindPlayerByName(holder.Name) != holder || !holder.isFightingMob(m) {
+		m.TaggedBy = nil
+		return nil
+	}
+	return holder
+}
+
+// tagMob gives the player a claim on a mob they've started fighting, if no one else has one
+// Practice dummies are never tagged, so anyone may share one.
+func tagMob(mob *MobInstance, p *Player) {
+	if !mob.Dummy && killStealing(mob) != KillStealAllow && mob.TagHolder() == nil {
+		mob.TaggedBy = p
+	}
+}
+
+// mayAttack reports whether the player may attack a mob someone else may have tagged
+// The message explains a refusal, or warns a player who may attack that the kill isn't theirs.
+func (p *Player) mayAttack(mob *MobInstance) (bool, string) {
+	holder := mob.TagHolder()
+	if holder == nil || holder == p {
+		return true, ""
+	}
+	switch killStealing(mob) {
+	case KillStealWarn:
+		holder.Send(fmt.Sprintf("%s joins your fight with %s.", p.Name, mob.ShortDescription))
+		return true, fmt.Sprintf("{Y}%s is already fighting %s. The kill will be theirs.{x}", holder.Name, mob.ShortDescription)
+	}
+	return false, fmt.Sprintf("%s is already fighting %s.", holder.Name, mob.ShortDescription)
+}
+
+// creditKill gives a player who tagged a mob the kill someone else finished
+func creditKill(holder *Player, mob *MobInstance, killer string) {
+	FireDeathProgs(mob, holder)
+	xpGain := CalculateXPGain(holder.Level, mob.Level)
+	holder.GainXP(xpGain)
+	holder.SendType(fmt.Sprintf("%s finishes off %s, but the kill is yours.", killer, mob.ShortDescription), "combat")
+	Publish(Event{Type: EventMobKilled, Player: holder, Mob: mob, Room: mob.Room})
+	holder.Send(fmt.Sprintf("You gain {G}%s{x} experience points.", holder.Locale.Number(xpGain)))
+	holder.ExitCombat()
+	holder.SendStatus()
+}
@@ -0,0 +1,223 @@
+/*
+ * language.go
+ *
+ * This file implements spoken languages. Everyone speaks Common, and each
+ * race also has a language of its own that its members know from birth.
+ * Players choose the language they talk in with 'speak'. Listeners who
+ * don't know that language hear garbled nonsense instead of the words:
+ * each letter is swapped for one from the language's own alphabet, so a
+ * word always garbles the same way. Other languages can be learned for
+ * gold from a tutor with 'learn'. Learned languages and the language a
+ * player is speaking are saved with the character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// LanguageCommon is the language every player speaks
+const LanguageCommon = "common"
+
+// Language is a tongue players can speak
+type Language struct {
+	Name     string
+	Race     string // Race that knows the language from birth ("" for none)
+	Price    int    // Gold a tutor charges to teach it
+	Alphabet string // Letters substituted for a to z when garbled
+}
+
+// languages lists every language players can speak
+var languages = []*Language{
+	{Name: LanguageCommon, Alphabet: "abcdefghijklmnopqrstuvwxyz"},
+	{Name: "elvish", Race: "Elf", Price: 100, Alphabet: "ealiethnoarilwenyaesoluimn"},
+	{Name: "dwarvish", Race: "Dwarf", Price: 100, Alphabet: "ukdargzbhumkrgodbrkazgundr"},
+	{Name: "orcish", Race: "Orc", Price: 100, Alphabet: "ugrakhzgubuknagrokshzugmar"},
+}
+
+// FindLanguage returns the language with the given name or name prefix, or nil
+func FindLanguage(name string) *Language {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	for _, lang := range languages {
+		if strings.HasPrefix(lang.Name, name) {
+			return lang
+		}
+	}
+	return nil
+}
+
+// Title returns the language's name as it appears in speech
+func (l *Language) Title() string {
+	return capitalizeFirst(l.Name)
+}
+
+// Garble turns a message into nonsense in the language's alphabet
+func (l *Language) Garble(message string) string {
+	var sb strings.Builder
+	for _, r := range message {
+		lower := unicode.ToLower(r)
+		if lower < 'a' || lower > 'z' {
+			sb.WriteRune(r)
+			continue
+		}
+		sub := rune(l.Alphabet[lower-'a'])
+		if unicode.IsUpper(r) {
+			sub = unicode.ToUpper(sub)
+		}
+		sb.WriteRune(sub)
+	}
+	return sb.String()
+}
+
+// KnowsLanguage reports whether the player understands a language
+func (p *Player) KnowsLanguage(lang *Language) bool {
+	return lang.Name == LanguageCommon || strings.EqualFold(lang.Race, p.Race) || p.Languages[lang.Name]
+}
+
+// SpeakingLanguage returns the language the player is talking in
+func (p *Player) SpeakingLanguage() *Language {
+	if lang := FindLanguage(p.Speaking); lang != nil && p.KnowsLanguage(lang) {
+		return lang
+	}
+	return languages[0]
+}
+
+// Hear returns a message spoken in a language as the player understands it
+func (p *Player) Hear(lang *Language, message string) string {
+	if p.KnowsLanguage(lang) {
+		return message
+	}
+	return lang.Garble(message)
+}
+
+// languageTag names the language in speech, or is empty for Common
+func languageTag(lang *Language) string {
+	if lang.Name == LanguageCommon {
+		return ""
+	}
+	return " in " + lang.Title()
+}
+
+// LoadLanguages restores the languages the player has learned and the one they speak
+func (p *Player) LoadLanguages() {
+	p.Languages = make(map[string]bool)
+	learned, err := LoadPlayerLanguages(p.Name)
+	if err != nil {
+		log.Printf("Error loading languages for %s: %v", p.Name, err)
+	}
+	for _, name := range learned {
+		p.Languages[name] = true
+	}
+
+	speaking, err := LoadPlayerSpeaking(p.Name)
+	if err != nil {
+		log.Printf("Error loading spoken language for %s: %v", p.Name, err)
+		return
+	}
+	p.Speaking = speaking
+}
+
+// handleSpeak shows the languages the player knows or switches the one they speak
+// Usage: speak [language]
+func handleSpeak(player *Player, args []string) string {
+	if len(args) == 0 {
+		var known []string
+		for _, lang := range languages {
+			if player.KnowsLanguage(lang) {
+				known = append(known, lang.Title())
+			}
+		}
+		return fmt.Sprintf("You are speaking %s.\r\nYou know: %s",
+			player.SpeakingLanguage().Title(), strings.Join(known, ", "))
+	}
+
+	lang := FindLanguage(args[0])
+	if lang == nil {
+		return fmt.Sprintf("There is no language called '%s'.", args[0])
+	}
+	if !player.KnowsLanguage(lang) {
+		return fmt.Sprintf("You don't know how to speak %s.", lang.Title())
+	}
+
+	player.Speaking = lang.Name
+	if err := UpdatePlayerSpeaking(player.Name, lang.Name); err != nil {
+		log.Printf("Error saving spoken language for %s: %v", player.Name, err)
+	}
+	return fmt.Sprintf("You will now speak %s.", lang.Title())
+}
+
+// findTutor returns the language tutor in the player's room, or nil
+func findTutor(player *Player) *MobInstance {
+	for _, mob := range GetMobsInRoom(player.Room.ID) {
+		if len(mob.TeachesLanguages) > 0 {
+			return mob
+		}
+	}
+	return nil
+}
+
+// tutorTeaches reports whether a tutor teaches the given language
+func tutorTeaches(tutor *MobInstance, lang *Language) bool {
+	for _, name := range tutor.TeachesLanguages {
+		if strings.EqualFold(name, lang.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleLearn lists or teaches the languages offered by a tutor
+// Usage: learn [language]
+func handleLearn(player *Player, args []string) string {
+	tutor := findTutor(player)
+	if tutor == nil {
+		return "There is no one here to teach you."
+	}
+	name := capitalizeFirst(tutor.ShortDescription)
+
+	if len(args) == 0 {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s can teach you:\r\n", name))
+		for _, lang := range languages {
+			if !tutorTeaches(tutor, lang) {
+				continue
+			}
+			status := fmt.Sprintf("%d gold", HagglePrice(player, tutor, lang.Price))
+			if player.KnowsLanguage(lang) {
+				status = "{G}known{x}"
+			}
+			sb.WriteString(fmt.Sprintf("  %-10s %s\r\n", lang.Title(), status))
+		}
+		sb.WriteString("Use 'learn <language>' to learn one.")
+		return sb.String()
+	}
+
+	lang := FindLanguage(args[0])
+	if lang == nil || !tutorTeaches(tutor, lang) {
+		return fmt.Sprintf("%s says, 'I can't teach you that.'", name)
+	}
+	if player.KnowsLanguage(lang) {
+		return fmt.Sprintf("You already speak %s.", lang.Title())
+	}
+	price := HagglePrice(player, tutor, lang.Price)
+	if !ChargeGold(player, price, GoldSourceTraining) {
+		return fmt.Sprintf("%s says, 'Lessons in %s cost %d gold. You can't afford them.'", name, lang.Title(), price)
+	}
+
+	if player.Languages == nil {
+		player.Languages = make(map[string]bool)
+	}
+	player.Languages[lang.Name] = true
+	if err := AddPlayerLanguage(player.Name, lang.Name); err != nil {
+		log.Printf("Error saving language %s for %s: %v", lang.Name, player.Name, err)
+	}
+
+	return fmt.Sprintf("You pay %d gold and %s teaches you to speak %s.%s", price, tutor.ShortDescription, lang.Title(), haggleNote(lang.Price, price))
+}
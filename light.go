@@ -0,0 +1,55 @@
+/*
+ * light.go
+ *
+ * This file implements lighting. Rooms flagged as dark, and rooms out in
+ * the wilds at night, can't be seen in unless someone there is holding or
+ * wearing a light source. In the dark,
+ * players can't see the room's contents or exits, can't look at anything,
+ * and fight at a penalty to their chance to hit.
+ */
+
+package main
+
+// DarkHitPenalty is taken off a player's chance to hit when fighting in the dark
+const DarkHitPenalty = 0.25
+
+// DarkMessage is shown in place of a dark room's description
+const DarkMessage = "{D}It is pitch black...{x}\nYou can't see a thing without a light."
+
+// HasLight reports whether the player is holding or wearing a light source
+func (p *Player) HasLight() bool {
+	for _, item := range p.Equipment {
+		if item.Light {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDark reports whether a room needs a light to see in
+// Outdoors is dark at night, except in the city, where the streets are lit.
+func (r *Room) IsDark() bool {
+	if r.Dark {
+		return true
+	}
+	return IsNight() && r.Sector != SectorInside && r.Sector != SectorCity
+}
+
+// RoomIsLit reports whether a room can be seen in, either because it isn't
+// dark or because someone there has a light
+func RoomIsLit(room *Room) bool {
+	if room == nil || !room.IsDark() {
+		return true
+	}
+	for _, p := range playersInRoom(room) {
+		if p.HasLight() {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSee reports whether the player can see their surroundings
+func (p *Player) CanSee() bool {
+	return RoomIsLit(p.Room)
+}
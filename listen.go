@@ -0,0 +1,209 @@
+/*
+ * listen.go
+ *
+ * This file opens the ports players connect on. Each listener in
+ * config.yml names an address and a protocol: plain telnet, telnet over
+ * TLS, or the web server with its WebSocket client, which can also be
+ * served over TLS. Any number can be open at once, and every connection,
+ * whichever port it came in on, goes through the same login and game loop.
+ * Every listener is opened before any is served, so a port that can't be
+ * opened stops the server at startup rather than leaving it half running.
+ *
+ * Connections are counted as they're accepted. Once the server holds as
+ * many as it allows in all, or from one address, further ones are told so
+ * and closed straight away. A connection has a limited time to log in,
+ * so sockets that connect and never answer don't pile up.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Listener protocols
+const (
+	ProtocolTelnet    = "telnet"    // Plain telnet
+	ProtocolTLS       = "tls"       // Telnet over TLS
+	ProtocolWebSocket = "websocket" // The web client and WebSocket connections, over TLS if a certificate is given
+)
+
+// ConnectionsConfig holds the limits on connections to the server
+type ConnectionsConfig struct {
+	MaxTotal     int `yaml:"max_total"`     // Most connections open at once (0 = no limit)
+	MaxPerIP     int `yaml:"max_per_ip"`    // Most connections open at once from one address (0 = no limit)
+	LoginTimeout int `yaml:"login_timeout"` // Seconds a connection has to log in before it's closed (0 = no limit)
+}
+
+// Open connections, counted as they're accepted
+var (
+	connectionCount int            // Connections open in all
+	connectionsByIP map[string]int // Connections open from each address
+	connectionMutex sync.Mutex     // Mutex for thread-safe connection counting
+)
+
+// ListenerConfig is a port the server accepts connections on
+type ListenerConfig struct {
+	Address  string   `yaml:"address"`           // Host and port to listen on, such as 0.0.0.0:4000
+	Protocol string   `yaml:"protocol"`          // ProtocolTelnet, ProtocolTLS, or ProtocolWebSocket
+	Cert     string   `yaml:"cert,omitempty"`    // TLS certificate file (needed for tls, optional for websocket)
+	Key      string   `yaml:"key,omitempty"`     // TLS private key file, paired with the certificate
+	Origins  []string `yaml:"origins,omitempty"` // Other sites whose pages may open the web client's socket, such as https://example.com
+}
+
+// secure reports whether the listener serves TLS
+func (l ListenerConfig) secure() bool {
+	return l.Protocol == ProtocolTLS || l.Cert != ""
+}
+
+// validate checks a listener's settings, where i is its place in the list
+func (l ListenerConfig) validate(i int) error {
+	switch {
+	case l.Address == "":
+		return fmt.Errorf("listeners[%d].address must be set", i)
+	case l.Protocol != ProtocolTelnet && l.Protocol != ProtocolTLS && l.Protocol != ProtocolWebSocket:
+		return fmt.Errorf("listeners[%d].protocol must be telnet, tls, or websocket, got %q", i, l.Protocol)
+	case l.Protocol == ProtocolTelnet && l.Cert != "":
+		return fmt.Errorf("listeners[%d] is plain telnet, so it can't have a certificate; use protocol tls", i)
+	case l.secure() && (l.Cert == "" || l.Key == ""):
+		return fmt.Errorf("listeners[%d] needs both cert and key for TLS", i)
+	}
+	return nil
+}
+
+// open binds the listener's address, wrapping it in TLS if it's secure
+func (l ListenerConfig) open() (net.Listener, error) {
+	listener, err := net.Listen("tcp", l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if !l.secure() {
+		return listener, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.Cert, l.Key)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+	return tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}}), nil
+}
+
+// StartListeners opens every configured listener and starts accepting connections on them
+func StartListeners() error {
+	listeners := make([]net.Listener, len(config.Listeners))
+	for i, l := range config.Listeners {
+		listener, err := l.open()
+		if err != nil {
+			for _, opened := range listeners[:i] {
+				opened.Close()
+			}
+			return fmt.Errorf("error listening on %s: %w", l.Address, err)
+		}
+		listeners[i] = listener
+	}
+
+	for i, l := range config.Listeners {
+		switch l.Protocol {
+		case ProtocolWebSocket:
+			scheme := "http"
+			if l.secure() {
+				scheme = "https"
+			}
+			fmt.Printf("Web client available on %s://%s/\n", scheme, l.Address)
+			go ServeWebSocket(listeners[i], l.Origins)
+		default:
+			fmt.Printf("MUD server listening for %s on %s...\n", l.Protocol, l.Address)
+			go acceptConnections(listeners[i])
+		}
+	}
+	return nil
+}
+
+// acceptConnections hands each connection made to a telnet listener to the session handler
+func acceptConnections(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Println("Connection error:", err)
+			continue
+		}
+
+		release, refusal := admitConnection(addrIP(conn.RemoteAddr()))
+		if refusal != "" {
+			log.Printf("Refused a connection from %s: %s", conn.RemoteAddr(), refusal)
+			go refuseConnection(conn, refusal)
+			continue
+		}
+		go func() {
+			defer release()
+			handleConnection(conn)
+		}()
+	}
+}
+
+// refuseTimeout bounds how long a refused client has to take the refusal, TLS handshake included
+const refuseTimeout = 10 * time.Second
+
+// refuseConnection tells the client why it was refused and hangs up
+// It runs apart from the accept loop, since on TLS the write does the handshake and a slow client would hold up everyone else.
+func refuseConnection(conn net.Conn, refusal string) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(refuseTimeout))
+	conn.Write([]byte(refusal + "\r\n"))
+}
+
+// admitConnection counts a new connection from the address if the limits allow it
+// Returns a function to call when the connection closes, or why the connection is refused.
+func admitConnection(addr net.IP) (func(), string) {
+	limits := config.Connections
+	ip := ""
+	if addr != nil {
+		ip = addr.String()
+	}
+
+	connectionMutex.Lock()
+	defer connectionMutex.Unlock()
+
+	if limits.MaxTotal > 0 && connectionCount >= limits.MaxTotal {
+		return nil, "The server is full. Please try again later."
+	}
+	if limits.MaxPerIP > 0 && ip != "" && connectionsByIP[ip] >= limits.MaxPerIP {
+		return nil, "Too many connections from your address."
+	}
+
+	if connectionsByIP == nil {
+		connectionsByIP = make(map[string]int)
+	}
+	connectionCount++
+	connectionsByIP[ip]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			connectionMutex.Lock()
+			defer connectionMutex.Unlock()
+			connectionCount--
+			if connectionsByIP[ip]--; connectionsByIP[ip] <= 0 {
+				delete(connectionsByIP, ip)
+			}
+		})
+	}, ""
+}
+
+// startLoginTimer gives a new connection the configured time to log in
+func startLoginTimer(conn net.Conn) {
+	if timeout := config.Connections.LoginTimeout; timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
+	}
+}
+
+// stopLoginTimer lifts the login time limit once the connection has logged in
+func stopLoginTimer(conn net.Conn) {
+	conn.SetReadDeadline(time.Time{})
+}
@@ -17,15 +17,29 @@ import (
 	"path/filepath" // Package for manipulating filename paths
 	"strconv"       // Package for string conversion
 	"strings"       // Package for string manipulation
+	"sync/atomic"   // Package for publishing the world maps safely
 
 	"gopkg.in/yaml.v3" // Package for parsing YAML files
 )
 
 // Exit represents a direction-specific exit from a room
 type Exit struct {
-	ID          interface{} `yaml:"id"`             // Can be int or string (for cross-area references)
-	Description string      `yaml:"description"`    // Optional description of what's visible in that direction
-	Door        *Door       `yaml:"door,omitempty"` // Optional door information
+	ID          interface{}      `yaml:"id"`                    // Can be int or string (for cross-area references)
+	To          int              `yaml:"-"`                     // Destination room ID, resolved once every area has loaded
+	Description string           `yaml:"description,omitempty"` // Optional description of what's visible in that direction
+	Door        *Door            `yaml:"door,omitempty"`        // Optional door information
+	Requires    *ExitRequirement `yaml:"requires,omitempty"`    // Optional conditions a player must meet to pass
+	Hidden      bool             `yaml:"hidden,omitempty"`      // Only listed for players who can detect hidden
+}
+
+// ExitRequirement restricts who may pass through an exit
+type ExitRequirement struct {
+	MinLevel int    `yaml:"min_level,omitempty"` // Lowest level allowed through
+	MaxLevel int    `yaml:"max_level,omitempty"` // Highest level allowed through
+	Class    string `yaml:"class,omitempty"`     // Only members of this class may pass
+	Quest    string `yaml:"quest,omitempty"`     // ID of a quest the player must have completed
+	Item     int    `yaml:"item,omitempty"`      // Vnum of an item the player must carry or wear
+	Message  string `yaml:"message,omitempty"`   // Shown instead of the default when a player is turned away
 }
 
 // Door represents a door that can be opened, closed, and locked
@@ -51,18 +65,63 @@ type Room struct {
 	Exits       map[string]*Exit       `yaml:"exits"`
 	Environment []EnvironmentAttribute `yaml:"environment,omitempty"`
 	NoWandering bool                   `yaml:"no_wandering,omitempty"` // If true, mobs cannot wander into this room
+	Inn         bool                   `yaml:"inn,omitempty"`          // If true, players can log out instantly here
+	Triggers    []*RoomTrigger         `yaml:"triggers,omitempty"`     // Ambushes sprung by players in this room
+	Dark        bool                   `yaml:"dark,omitempty"`         // If true, players need a light to see here
+	Sector      string                 `yaml:"sector,omitempty"`       // Terrain of the room (default: the area's sector)
+	HousePrice  int                    `yaml:"house_price,omitempty"`  // If set, players can buy the room as a house for this much gold
+	Resources   []*ResourceNode        `yaml:"resources,omitempty"`    // Ore veins, herb patches, and other sources of crafting materials
+	Arena       bool                   `yaml:"arena,omitempty"`        // If true, players may fight each other here without a duel
+	NoMount     bool                   `yaml:"no_mount,omitempty"`     // If true, players must dismount to enter
+	Fountain    bool                   `yaml:"fountain,omitempty"`     // If true, players can drink and fill containers here
+	Soundproof  bool                   `yaml:"soundproof,omitempty"`   // If true, shouts and yells don't get in or out
 }
 
 // Area represents a collection of rooms
 type Area struct {
-	Name      string        `yaml:"name"`
-	Rooms     map[int]*Room `yaml:"rooms"`
-	Mobiles   map[int]*Mob  `yaml:"mobiles"`
-	MobResets []MobReset    `yaml:"mob_resets"`
+	Name         string              `yaml:"name"`
+	Rooms        map[int]*Room       `yaml:"rooms"`
+	Mobiles      map[int]*Mob        `yaml:"mobiles"`
+	Objects      map[int]*Item       `yaml:"objects"`
+	MobResets    []MobReset          `yaml:"mob_resets"`
+	Recipes      map[string]*Recipe  `yaml:"recipes,omitempty"`       // Crafting recipes, keyed by name
+	Sets         map[string]*ItemSet `yaml:"sets,omitempty"`          // Item sets, keyed by the ID items refer to
+	LevelScaling *LevelScaling       `yaml:"level_scaling,omitempty"` // Optional dynamic mob level scaling
+	Sector       string              `yaml:"sector,omitempty"`        // Default terrain of the area's rooms (default: city)
+	Script       string              `yaml:"script,omitempty"`        // Lua script in the scripts folder that runs the area's quests
+	KillStealing string              `yaml:"kill_stealing,omitempty"` // prevent, warn, or allow attacks on mobs another player tagged (default: prevent)
 }
 
-// Global storage for rooms, initialized as an empty map
-var rooms = make(map[int]*Room)
+// LevelScaling bounds the levels mobs in an area may be scaled to
+type LevelScaling struct {
+	MinLevel int `yaml:"min_level"`
+	MaxLevel int `yaml:"max_level"`
+}
+
+// worldMaps holds the loaded rooms and areas
+// Game goroutines read the maps without a lock, so they're never changed once
+// the game is running: 'loadarea' builds new ones and publishes them in their place.
+type worldMaps struct {
+	rooms map[int]*Room    // Every room, by ID
+	areas map[string]*Area // Loaded areas, keyed by area file name (matches Room.Area)
+}
+
+// world is the current set of rooms and areas
+var world atomic.Pointer[worldMaps]
+
+func init() {
+	world.Store(&worldMaps{rooms: make(map[int]*Room), areas: make(map[string]*Area)})
+}
+
+// allRooms returns every room, by ID
+func allRooms() map[int]*Room {
+	return world.Load().rooms
+}
+
+// allAreas returns the loaded areas, keyed by area file name
+func allAreas() map[string]*Area {
+	return world.Load().areas
+}
 
 // LoadAreas loads all YAML files from the "areas" folder.
 func LoadAreas() error {
@@ -82,33 +141,227 @@ func LoadAreas() error {
 			// Generate the full path to the area file.
 			areaPath := filepath.Join(areaDir, file.Name())
 			// Load the area from the file and log any errors.
-			if err := loadArea(areaPath); err != nil {
-				log.Printf("Error loading area: %v", err)
+			if err := loadArea(areaPath, allRooms(), allAreas()); err != nil {
+				areaProblem(file.Name(), "", "Skipping the area: %v", err)
 			}
 		}
 	}
+
+	// Exits may lead into areas loaded after their own, so link them last,
+	// and likewise check the items recipes and resource nodes refer to
+	linkExits()
+	validateCrafting()
+	validateMobResets()
+	rebuildMobResets()
 	return nil // Return nil indicating success in loading areas.
 }
 
-// LoadArea loads a single area file
-func loadArea(path string) error {
+// findAreaFile returns the file name of the area with the given file or display name, or ""
+// "midgaard", "midgaard.yml", and "Midgaard" all name the same area.
+func findAreaFile(name string) string {
+	for file, area := range allAreas() {
+		if strings.EqualFold(file, name) || strings.EqualFold(strings.TrimSuffix(file, ".yml"), name) ||
+			strings.EqualFold(area.Name, name) {
+			return file
+		}
+	}
+	return ""
+}
+
+// parseExitID splits an exit's ID into the area it names ("" for none) and its room ID
+func parseExitID(id interface{}) (string, int, error) {
+	switch exitID := id.(type) {
+	case int:
+		return "", exitID, nil
+	case string:
+		roomInfo := strings.Split(exitID, ":")
+		if len(roomInfo) != 2 {
+			return "", 0, fmt.Errorf("invalid room reference %q, expected \"area:vnum\"", exitID)
+		}
+		roomID, err := strconv.Atoi(strings.TrimSpace(roomInfo[1]))
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid room ID in %q", exitID)
+		}
+		return strings.TrimSpace(roomInfo[0]), roomID, nil
+	}
+	return "", 0, fmt.Errorf("invalid exit type")
+}
+
+// linkExits resolves every exit once all areas have loaded
+func linkExits() {
+	linked, crossArea := 0, 0
+	for id, room := range allRooms() {
+		l, c := linkRoomExits(id, room)
+		linked += l
+		crossArea += c
+	}
+	log.Printf("Linked %d exits, %d of them between areas", linked, crossArea)
+
+	for id, room := range allRooms() {
+		linkRoomDoors(id, room, false)
+	}
+}
+
+// linkRoomExits resolves a room's exits, returning how many were linked and how many of those lead to other areas
+// Exits whose destination doesn't exist, or doesn't belong to the area they
+// name, are reported and removed so players can't walk into nowhere.
+func linkRoomExits(id int, room *Room) (int, int) {
+	linked, crossArea := 0, 0
+	for direction, exit := range room.Exits {
+		path := fmt.Sprintf("rooms.%d.exits.%s", id, direction)
+		areaName, destID, err := parseExitID(exit.ID)
+		if err != nil {
+			areaProblem(room.Area, path, "Room %d has a broken %s exit, removing it: %v", id, direction, err)
+			delete(room.Exits, direction)
+			continue
+		}
+
+		dest, exists := allRooms()[destID]
+		if !exists {
+			areaProblem(room.Area, path, "Room %d has a %s exit to room %d, which doesn't exist. Removing the exit.",
+				id, direction, destID)
+			delete(room.Exits, direction)
+			continue
+		}
+
+		if areaName != "" {
+			file := findAreaFile(areaName)
+			if file == "" {
+				areaProblem(room.Area, path, "Room %d has a %s exit into unknown area %q. Removing the exit.",
+					id, direction, areaName)
+				delete(room.Exits, direction)
+				continue
+			}
+			if dest.Area != file {
+				areaProblem(room.Area, path, "Room %d has a %s exit to room %d in %s, but that room belongs to %s. Removing the exit.",
+					id, direction, destID, file, dest.Area)
+				delete(room.Exits, direction)
+				continue
+			}
+		}
+
+		exit.To = destID
+		linked++
+		if dest.Area != room.Area {
+			crossArea++
+		}
+	}
+	return linked, crossArea
+}
+
+// linkRoomDoors makes sure each door in a room can be seen and used from both of its sides
+// With sameArea set, doors leading into other areas are left one-sided.
+func linkRoomDoors(id int, room *Room, sameArea bool) {
+	for direction, exit := range room.Exits {
+		if exit.Door != nil {
+			destRoomID := exit.To
+			destRoom := allRooms()[destRoomID]
+			if destRoom == nil || sameArea && destRoom.Area != room.Area {
+				continue
+			}
+
+			// Find the opposite direction
+			oppositeDirection := GetOppositeDirection(direction)
+
+			// Check if the destination room has a corresponding exit
+			destExit, exists := destRoom.Exits[oppositeDirection]
+			if !exists {
+				// Create a corresponding exit with a door
+				areaProblem(room.Area, fmt.Sprintf("rooms.%d.exits.%s", id, direction),
+					"Room %d has a door to %d, but %d has no exit back. Adding reciprocal exit.", id, destRoomID, destRoomID)
+				destRoom.Exits[oppositeDirection] = &Exit{
+					ID:          id,
+					To:          id,
+					Description: fmt.Sprintf("You see %s.", room.Name),
+					Door: &Door{
+						ShortDescription: exit.Door.ShortDescription,
+						Keywords:         exit.Door.Keywords,
+						Locked:           exit.Door.Locked,
+						Closed:           exit.Door.Closed,
+					},
+				}
+			} else if destExit.Door == nil {
+				// Add a door to the destination exit
+				areaProblem(room.Area, fmt.Sprintf("rooms.%d.exits.%s", id, direction),
+					"Room %d has a door to %d, but %d has no door back. Adding reciprocal door.", id, destRoomID, destRoomID)
+				destExit.Door = &Door{
+					ShortDescription: exit.Door.ShortDescription,
+					Keywords:         exit.Door.Keywords,
+					Locked:           exit.Door.Locked,
+					Closed:           exit.Door.Closed,
+				}
+			} else {
+				// Ensure door states are synchronized
+				destExit.Door.Closed = exit.Door.Closed
+				destExit.Door.Locked = exit.Door.Locked
+			}
+		}
+	}
+}
+
+// LoadArea loads a single area file, adding its rooms and the area itself to the given maps
+func loadArea(path string, roomMap map[int]*Room, areaMap map[string]*Area) error {
 	areaName := filepath.Base(path)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
+	// Decode through a node so problems can be reported with their line numbers
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
 	var area Area
-	err = yaml.Unmarshal(data, &area)
-	if err != nil {
+	if err := root.Decode(&area); err != nil {
 		return err
 	}
+	indexAreaLines(areaName, &root)
+
+	// Remember the area so its settings can be looked up later
+	areaMap[areaName] = &area
+
+	if area.KillStealing != "" && !validKillStealing[area.KillStealing] {
+		areaProblem(areaName, "kill_stealing", "Area has unknown kill_stealing %q, using %s", area.KillStealing, KillStealPrevent)
+		area.KillStealing = KillStealPrevent
+	}
+	if area.Sector == "" {
+		area.Sector = SectorCity
+	} else if !validSectors[area.Sector] {
+		areaProblem(areaName, "sector", "Area has unknown sector %q, using %s", area.Sector, SectorCity)
+		area.Sector = SectorCity
+	}
 
 	// Set the area name and ID for each room
 	for id, room := range area.Rooms {
+		path := fmt.Sprintf("rooms.%d", id)
 		room.ID = id
 		room.Area = areaName
 
+		// Drop any triggers that can't work rather than failing the whole area
+		triggers := room.Triggers[:0]
+		for _, trigger := range room.Triggers {
+			if err := validateTrigger(trigger); err != nil {
+				areaProblem(areaName, path+".triggers", "Room %d has an invalid trigger, ignoring it: %v", id, err)
+				continue
+			}
+			triggers = append(triggers, trigger)
+		}
+		room.Triggers = triggers
+
+		// Rooms take the area's terrain unless they set their own
+		if room.Sector == "" {
+			room.Sector = area.Sector
+		} else if !validSectors[room.Sector] {
+			areaProblem(areaName, path+".sector", "Room %d has unknown sector %q, using %s", id, room.Sector, area.Sector)
+			room.Sector = area.Sector
+		}
+
+		if room.HousePrice < 0 {
+			areaProblem(areaName, path+".house_price", "Room %d has a negative house price, it won't be for sale", id)
+			room.HousePrice = 0
+		}
+
 		// Set default closed state for doors
 		for _, exit := range room.Exits {
 			if exit.Door != nil && !exit.Door.Closed {
@@ -116,99 +369,111 @@ func loadArea(path string) error {
 			}
 		}
 
-		rooms[id] = room
-		//fmt.Printf("Loaded Room [%d]: %s (Area: %s)\n", id, room.Name, room.Area)
-	}
-
-	// After all rooms are loaded, ensure door consistency between connected rooms
-	for id, room := range area.Rooms {
-		for direction, exit := range room.Exits {
-			if exit.Door != nil {
-				// Get the destination room
-				var destRoomID int
-				switch exitID := exit.ID.(type) {
-				case int:
-					destRoomID = exitID
-				case string:
-					// Handle cross-area references
-					roomInfo := strings.Split(exitID, ":")
-					if len(roomInfo) != 2 {
-						continue
-					}
-					var err error
-					destRoomID, err = strconv.Atoi(roomInfo[1])
-					if err != nil {
-						continue
-					}
-				default:
-					continue
-				}
-
-				// Get the destination room
-				destRoom, exists := rooms[destRoomID]
-				if !exists {
-					continue // Destination room not loaded yet
-				}
-
-				// Find the opposite direction
-				oppositeDirection := GetOppositeDirection(direction)
-
-				// Check if the destination room has a corresponding exit
-				destExit, exists := destRoom.Exits[oppositeDirection]
-				if !exists {
-					// Create a corresponding exit with a door
-					log.Printf("[WARNING] Room %d has a door to %d, but %d has no exit back. Adding reciprocal exit.",
-						id, destRoomID, destRoomID)
-					destRoom.Exits[oppositeDirection] = &Exit{
-						ID:          id,
-						Description: fmt.Sprintf("You see %s.", room.Name),
-						Door: &Door{
-							ShortDescription: exit.Door.ShortDescription,
-							Keywords:         exit.Door.Keywords,
-							Locked:           exit.Door.Locked,
-							Closed:           exit.Door.Closed,
-						},
-					}
-				} else if destExit.Door == nil {
-					// Add a door to the destination exit
-					log.Printf("[WARNING] Room %d has a door to %d, but %d has no door back. Adding reciprocal door.",
-						id, destRoomID, destRoomID)
-					destExit.Door = &Door{
-						ShortDescription: exit.Door.ShortDescription,
-						Keywords:         exit.Door.Keywords,
-						Locked:           exit.Door.Locked,
-						Closed:           exit.Door.Closed,
-					}
-				} else {
-					// Ensure door states are synchronized
-					destExit.Door.Closed = exit.Door.Closed
-					destExit.Door.Locked = exit.Door.Locked
-				}
-			}
+		if existing := roomMap[id]; existing != nil {
+			areaProblem(areaName, path, "Room %d is also defined in %s; this one replaces it", id, existing.Area)
 		}
+		roomMap[id] = room
 	}
 
 	// Load mobs from the mobiles section
 	for id, mob := range area.Mobiles {
-		//fmt.Printf("Loading mob [%d]: %s\nLong Description: %s\n", id, mob.ShortDescription, mob.LongDescription)
+		path := fmt.Sprintf("mobiles.%d", id)
 		mob.ID = id
+		mob.HomeArea = areaName
+		if existing := mobRegistry[id]; existing != nil {
+			areaProblem(areaName, path, "Mob %d is also defined in %s; this one replaces it", id, existing.HomeArea)
+		}
+		if _, ok := toughnessMultipliers[strings.ToLower(mob.Toughness)]; mob.Toughness != "" && !ok {
+			areaProblem(areaName, path+".toughness", "Mob %d has unknown toughness %q, using medium", id, mob.Toughness)
+		}
+		if mob.Patrol != nil {
+			if err := validatePatrol(mob.Patrol, &area); err != nil {
+				areaProblem(areaName, path+".patrol", "Mob %d has an invalid patrol route, it will stay put: %v", id, err)
+				mob.Patrol = nil
+			}
+		}
+		if mob.Blocks != nil {
+			if err := validateExitGuard(mob.Blocks); err != nil {
+				areaProblem(areaName, path+".blocks", "Mob %d has an invalid guarded exit, it won't block anything: %v", id, err)
+				mob.Blocks = nil
+			}
+		}
+		var specials []SpecialAttack
+		for _, special := range mob.SpecialAttacks {
+			if err := validateSpecialAttack(special); err != nil {
+				areaProblem(areaName, path+".special_attacks", "Mob %d has an invalid special attack, ignoring it: %v", id, err)
+				continue
+			}
+			specials = append(specials, special)
+		}
+		mob.SpecialAttacks = specials
+		var progs []MobProg
+		for _, prog := range mob.Progs {
+			if err := validateMobProg(prog); err != nil {
+				areaProblem(areaName, path+".progs", "Mob %d has an invalid program, ignoring it: %v", id, err)
+				continue
+			}
+			progs = append(progs, prog)
+		}
+		mob.Progs = progs
+		var taught []string
+		for _, name := range mob.TeachesLanguages {
+			if FindLanguage(name) == nil {
+				areaProblem(areaName, path+".tutor", "Mob %d teaches unknown language %q, ignoring it", id, name)
+				continue
+			}
+			taught = append(taught, name)
+		}
+		mob.TeachesLanguages = taught
 		RegisterMob(mob)
 	}
 
-	// Store mob resets
-	mobResets = append(mobResets, area.MobResets...)
+	// Load item sets before the items that belong to them
+	for id, set := range area.Sets {
+		set.ID = id
+		RegisterItemSet(set)
+	}
+
+	// Load items from the objects section
+	for id, item := range area.Objects {
+		item.ID = id
+		if err := validateProvision(item); err != nil {
+			areaProblem(areaName, fmt.Sprintf("objects.%d", id), "Item %d %v", id, err)
+		}
+		RegisterItem(item)
+	}
+
+	// Register crafting recipes; their items are checked once every area has loaded
+	for name, recipe := range area.Recipes {
+		recipe.Name = name
+		recipe.Area = areaName
+		RegisterRecipe(recipe)
+	}
 
 	return nil
 }
 
 // GetRoom fetches a room by its ID
 func GetRoom(id int) (*Room, error) {
-	room, exists := rooms[id]
+	room, exists := allRooms()[id]
 	if !exists {
 		return nil, fmt.Errorf("room ID %d not found", id)
 	}
-
-	// Debug logging
-	//fmt.Printf("Getting Room [%d]: %s (Area: %s)\n", id, room.Name, room.Area)
 	return room, nil
 }
+
+// ResolveExitRoomID returns the destination room ID of an exit
+// Exit IDs may be plain ints or "area:vnum" strings for cross-area links,
+// where the area is its file name or display name.
+func ResolveExitRoomID(exit *Exit) (int, error) {
+	if exit.To != 0 {
+		return exit.To, nil
+	}
+	_, roomID, err := parseExitID(exit.ID)
+	return roomID, err
+}
+
+// GetArea fetches a loaded area by its file name
+func GetArea(name string) *Area {
+	return allAreas()[name]
+}
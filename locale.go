@@ -0,0 +1,167 @@
+/*
+ * locale.go
+ *
+ * This file implements per-player locale settings for numbers and times.
+ * Players choose how large numbers such as gold and experience are grouped
+ * (1,234,567 or 1.234.567, for example), whether times are shown on a 12 or
+ * 24-hour clock, and what order dates are written in. Output that shows a
+ * player a timestamp or a large number formats it through their Locale.
+ * The settings are saved with the character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How numbers may be grouped into thousands
+const (
+	NumbersComma  = "comma"  // 1,234,567 (the default)
+	NumbersPeriod = "period" // 1.234.567
+	NumbersSpace  = "space"  // 1 234 567
+	NumbersPlain  = "plain"  // 1234567
+)
+
+// numberSeparators maps each way of grouping numbers to the separator it uses
+var numberSeparators = map[string]string{
+	NumbersComma:  ",",
+	NumbersPeriod: ".",
+	NumbersSpace:  " ",
+	NumbersPlain:  "",
+}
+
+// The orders dates may be written in, with the layout for each
+var dateLayouts = map[string]string{
+	"ymd": "2006-01-02", // The default
+	"mdy": "01/02/2006",
+	"dmy": "02/01/2006",
+}
+
+// Locale holds how a player likes numbers and times written
+type Locale struct {
+	Numbers string // How thousands are grouped (see NumbersComma)
+	Clock   int    // 12 or 24
+	Dates   string // "ymd", "mdy", or "dmy"
+}
+
+// DefaultLocale is the locale players start with
+var DefaultLocale = Locale{Numbers: NumbersComma, Clock: 24, Dates: "ymd"}
+
+// Number writes n with its thousands grouped
+func (l Locale) Number(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	sep, ok := numberSeparators[l.Numbers]
+	if !ok {
+		sep = numberSeparators[DefaultLocale.Numbers]
+	}
+	if sep == "" || len(digits) <= 3 {
+		return sign + digits
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	lead := len(digits) % 3
+	if lead > 0 {
+		sb.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(digits[i : i+3])
+	}
+	return sb.String()
+}
+
+// Time writes the time of day, such as "15:04" or "3:04 PM"
+func (l Locale) Time(t time.Time) string {
+	if l.Clock == 12 {
+		return t.Format("3:04 PM")
+	}
+	return t.Format("15:04")
+}
+
+// Date writes the date, such as "2006-01-02"
+func (l Locale) Date(t time.Time) string {
+	layout, ok := dateLayouts[l.Dates]
+	if !ok {
+		layout = dateLayouts[DefaultLocale.Dates]
+	}
+	return t.Format(layout)
+}
+
+// DateTime writes the date and time of day
+func (l Locale) DateTime(t time.Time) string {
+	return l.Date(t) + " " + l.Time(t)
+}
+
+// describe lists the locale's settings, with an example of each
+func (l Locale) describe() string {
+	example := time.Date(2006, time.January, 2, 15, 4, 0, 0, time.Local)
+	var sb strings.Builder
+	sb.WriteString("Your locale settings:\r\n")
+	sb.WriteString(fmt.Sprintf("  numbers  %-7s (%s)\r\n", l.Numbers, l.Number(1234567)))
+	sb.WriteString(fmt.Sprintf("  clock    %-7d (%s)\r\n", l.Clock, l.Time(example)))
+	sb.WriteString(fmt.Sprintf("  dates    %-7s (%s)\r\n", l.Dates, l.Date(example)))
+	sb.WriteString("Type 'locale <setting> <value>' to change one.")
+	return sb.String()
+}
+
+// LoadLocale restores the player's locale settings
+func (p *Player) LoadLocale() {
+	locale, err := LoadPlayerLocale(p.Name)
+	if err != nil {
+		log.Printf("Error loading locale for %s: %v", p.Name, err)
+		return
+	}
+	p.Locale = locale
+}
+
+// handleLocale shows or changes how numbers and times are written for the player
+// Usage: locale [numbers comma|period|space|plain] [clock 12|24] [dates ymd|mdy|dmy]
+func handleLocale(player *Player, args []string) string {
+	if len(args) == 0 {
+		return player.Locale.describe()
+	}
+	if len(args) != 2 {
+		return "Usage: locale [numbers comma|period|space|plain] [clock 12|24] [dates ymd|mdy|dmy]"
+	}
+
+	value := strings.ToLower(args[1])
+	locale := player.Locale
+	switch strings.ToLower(args[0]) {
+	case "numbers":
+		if _, ok := numberSeparators[value]; !ok {
+			return "Numbers may be grouped by comma, period, space, or plain."
+		}
+		locale.Numbers = value
+	case "clock":
+		clock, err := strconv.Atoi(value)
+		if err != nil || (clock != 12 && clock != 24) {
+			return "The clock may be 12 or 24."
+		}
+		locale.Clock = clock
+	case "dates":
+		if _, ok := dateLayouts[value]; !ok {
+			return "Dates may be written ymd, mdy, or dmy."
+		}
+		locale.Dates = value
+	default:
+		return "You can set numbers, clock, or dates."
+	}
+
+	player.Locale = locale
+	if err := UpdatePlayerLocale(player.Name, locale); err != nil {
+		log.Printf("Error saving locale for %s: %v", player.Name, err)
+	}
+	return player.Locale.describe()
+}
@@ -0,0 +1,163 @@
+/*
+ * lore.go
+ *
+ * This file implements the 'lore' command, which lets a player recall what
+ * they know of an item without casting a spell on it. How much they recall
+ * depends on their lore score, the sum of their Intelligence and Wisdom
+ * plus half their level. Anyone can tell what sort of thing an item is and
+ * how heavy it is; a better score reveals roughly what it's worth, where
+ * it's worn, and how rare it is, then its exact worth and any magic, curse,
+ * or binding on it, and finally the set it belongs to and what the set
+ * grants. Rarer items are harder to place. The same player always recalls
+ * the same things about an item, so there's nothing to gain by trying
+ * again until they've grown wiser.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lore scores needed to recall each kind of fact about a common item
+const (
+	LoreValueApprox = 18 // Roughly what it's worth
+	LoreDetails     = 24 // Where it's worn and how rare it is
+	LoreProperties  = 28 // Exactly what it's worth, and whether it's magical, cursed, or binds
+	LoreSet         = 32 // The set it belongs to and the bonuses the set grants
+)
+
+// loreRarityPenalty is how much harder a rarer item is to place
+var loreRarityPenalty = map[string]int{
+	RarityUncommon: 2,
+	RarityRare:     5,
+	RarityEpic:     8,
+}
+
+// LoreScore is how much the player knows of items
+func (p *Player) LoreScore() int {
+	return p.Stat(ApplyINT) + p.Stat(ApplyWIS) + p.Level/2
+}
+
+// findLoreItem returns an item the player is carrying, wearing, or can see in the room, or nil
+func findLoreItem(player *Player, target string) *Item {
+	if item := FindItemInList(player.Inventory, target); item != nil {
+		return item
+	}
+	var worn []*Item
+	for _, slot := range WearSlots {
+		if item := player.Equipment[slot]; item != nil {
+			worn = append(worn, item)
+		}
+	}
+	if item := FindItemInList(worn, target); item != nil {
+		return item
+	}
+	return FindItemInList(GetItemsInRoom(player.Room), target)
+}
+
+// approximateValue rounds an item's worth to a figure someone could guess at
+func approximateValue(value int) int {
+	switch {
+	case value < 10:
+		return value
+	case value < 100:
+		return (value + 5) / 10 * 10
+	}
+	return (value + 50) / 100 * 100
+}
+
+// loreFacts returns what a player with the given lore score recalls about an item
+// The second result reports whether there was anything they couldn't recall.
+func loreFacts(item *Item, score int) ([]string, bool) {
+	score -= loreRarityPenalty[item.Rarity]
+	facts := []string{fmt.Sprintf("It is %s item, weighing %d pounds.", itemTypeArticle(item.Type), item.Weight)}
+	hidden := false
+
+	switch {
+	case score >= LoreProperties:
+		facts = append(facts, fmt.Sprintf("It is worth %d gold.", item.Value))
+	case score >= LoreValueApprox:
+		facts = append(facts, fmt.Sprintf("It looks to be worth about %d gold.", approximateValue(item.Value)))
+	default:
+		hidden = true
+	}
+
+	if score >= LoreDetails {
+		if item.WearSlot != "" {
+			facts = append(facts, fmt.Sprintf("It can be %s.", strings.Trim(wearSlotLabels[item.WearSlot], "<>")))
+		}
+		rarity := item.Rarity
+		if rarity == "" {
+			rarity = RarityCommon
+		}
+		facts = append(facts, fmt.Sprintf("It is %s.", rarity))
+	} else {
+		hidden = true
+	}
+
+	if score >= LoreProperties {
+		if item.Magic {
+			facts = append(facts, "{B}It is magical.{x}")
+		}
+		if item.Cursed {
+			facts = append(facts, "{R}It is cursed.{x}")
+		}
+		switch item.Bind {
+		case BindOnPickup:
+			facts = append(facts, "It binds to whoever picks it up.")
+		case BindOnEquip:
+			facts = append(facts, "It binds to whoever wears it.")
+		}
+	} else {
+		hidden = true
+	}
+
+	if set := GetItemSet(item.Set); set != nil {
+		if score >= LoreSet {
+			facts = append(facts, fmt.Sprintf("It is part of {Y}%s{x}.", set.Name))
+			for _, bonus := range set.Bonuses {
+				facts = append(facts, fmt.Sprintf("  %d pieces: %s", bonus.Pieces, describeModifiers(bonus.Modifiers)))
+			}
+		} else {
+			hidden = true
+		}
+	}
+	return facts, hidden
+}
+
+// itemTypeArticle names an item type with an indefinite article
+func itemTypeArticle(itemType string) string {
+	if itemType == "" {
+		itemType = "plain"
+	}
+	if strings.ContainsRune("aeiou", rune(itemType[0])) {
+		return "an " + itemType
+	}
+	return "a " + itemType
+}
+
+// handleLore recalls what the player knows of an item
+// Usage: lore <item>
+func handleLore(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Recall the lore of what?"
+	}
+
+	item := findLoreItem(player, strings.ToLower(strings.Join(args, " ")))
+	if item == nil {
+		return "You don't see that here."
+	}
+
+	facts, hidden := loreFacts(item, player.LoreScore())
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("You study %s and recall what you know of it.\r\n", item.ShortDescription))
+	for _, fact := range facts {
+		sb.WriteString(fact + "\r\n")
+	}
+	if hidden {
+		sb.WriteString("There is more to it than you can make out.\r\n")
+	}
+	return strings.TrimRight(sb.String(), "\r\n")
+}
@@ -0,0 +1,242 @@
+//go:build !nolottery
+
+/*
+ * lottery.go
+ *
+ * This file implements the weekly lottery. Players buy tickets with gold and
+ * the money goes into a shared pot. Once every game week a winning ticket is
+ * drawn at random; its owner receives the pot, less a house cut that is
+ * destroyed to keep gold from piling up in the economy. If nobody bought a
+ * ticket the pot rolls over to the next drawing. Tickets, the pot, and the
+ * time until the next drawing are all persisted so a reboot doesn't lose them.
+ * The lottery is a plugin; build with -tags nolottery to leave it out.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// lotteryTicksKey is the server state key counting down to the next drawing
+const lotteryTicksKey = "lottery_ticks"
+
+// lotteryMutex serializes ticket purchases and drawings
+var lotteryMutex sync.Mutex
+
+// lotteryPlugin adds the lottery to the server
+type lotteryPlugin struct{}
+
+func init() {
+	RegisterPlugin(lotteryPlugin{})
+}
+
+// Name is how config.yml refers to the lottery
+func (lotteryPlugin) Name() string {
+	return "lottery"
+}
+
+// Init has nothing to prepare; the pot and tickets are loaded as they're needed
+func (lotteryPlugin) Init() error {
+	return nil
+}
+
+// RegisterCommands adds the 'lottery' command
+func (lotteryPlugin) RegisterCommands(register func(name string, handler CommandHandler)) {
+	register("lottery", handleLottery)
+}
+
+// RegisterTicks adds the weekly drawing
+func (lotteryPlugin) RegisterTicks(tm *TimeManager) {
+	tm.RegisterTickFunc(ProcessLottery)
+}
+
+// lotteryDrawTicks returns the number of ticks between drawings
+func lotteryDrawTicks() int {
+	return TicksPerGameDay * DaysPerGameWeek * economy.LotteryDrawIntervals
+}
+
+// ProcessLottery advances the lottery clock and holds the drawing when it's due
+func ProcessLottery() {
+	lotteryMutex.Lock()
+	defer lotteryMutex.Unlock()
+
+	ticks, err := GetServerStateInt(lotteryTicksKey)
+	if err != nil {
+		log.Printf("Error loading lottery clock: %v", err)
+		return
+	}
+
+	ticks++
+	if ticks >= lotteryDrawTicks() {
+		drawLottery()
+		ticks = 0
+	}
+
+	if err := SetServerStateInt(lotteryTicksKey, ticks); err != nil {
+		log.Printf("Error saving lottery clock: %v", err)
+	}
+}
+
+// drawLottery picks a winning ticket and pays out the pot
+// The caller must hold lotteryMutex.
+func drawLottery() {
+	pot, err := GetServerStateInt(lotteryPotKey)
+	if err != nil {
+		log.Printf("Error loading lottery pot: %v", err)
+		return
+	}
+
+	tickets, err := LoadLotteryTickets()
+	if err != nil {
+		log.Printf("Error loading lottery tickets: %v", err)
+		return
+	}
+
+	if len(tickets) == 0 {
+		if pot > 0 {
+			announceLottery(fmt.Sprintf("No tickets were sold this week. The pot of %d gold rolls over!", pot))
+		}
+		return
+	}
+
+	// Sort names so the draw only depends on the random roll
+	names := make([]string, 0, len(tickets))
+	total := 0
+	for name, count := range tickets {
+		names = append(names, name)
+		total += count
+	}
+	sort.Strings(names)
+
+	roll := rng.Intn(total)
+	var winner string
+	for _, name := range names {
+		if roll < tickets[name] {
+			winner = name
+			break
+		}
+		roll -= tickets[name]
+	}
+
+	houseCut := pot * economy.LotteryHouseCut / 100
+	prize := pot - houseCut
+
+	// Pay online winners in memory so their next save doesn't overwrite the prize
+	if p := FindPlayerByName(winner); p != nil && p.Name == winner {
+		p.Gold += prize
+		if err := UpdatePlayerGold(p.Name, p.Gold); err != nil {
+			log.Printf("Error saving lottery prize for %s: %v", p.Name, err)
+		}
+		p.SendStatus()
+	} else if err := AddPlayerGold(winner, prize); err != nil {
+		log.Printf("Error paying lottery prize to %s: %v", winner, err)
+	}
+
+	RecordGoldCreated(GoldSourceLotteryPrizes, prize)
+
+	if err := ClearLotteryTickets(); err != nil {
+		log.Printf("Error clearing lottery tickets: %v", err)
+	}
+	if err := SetServerStateInt(lotteryPotKey, 0); err != nil {
+		log.Printf("Error resetting lottery pot: %v", err)
+	}
+
+	log.Printf("[LOTTERY] %s won %d gold (%d tickets sold, %d gold house cut)", winner, prize, total, houseCut)
+	announceLottery(fmt.Sprintf("%s wins the lottery and takes home %d gold!", winner, prize))
+}
+
+// announceLottery sends a lottery message to every player online
+func announceLottery(message string) {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	for _, p := range activePlayers {
+		p.Send("{Y}[Lottery]{x} " + message)
+	}
+}
+
+// handleLottery shows the lottery status or buys tickets
+func handleLottery(player *Player, args []string) string {
+	if len(args) == 0 {
+		return lotteryStatus(player)
+	}
+
+	if strings.ToLower(args[0]) != "buy" {
+		return "Usage: lottery [buy <count>]"
+	}
+
+	count := 1
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "How many tickets do you want to buy?"
+		}
+		count = n
+	}
+
+	lotteryMutex.Lock()
+	defer lotteryMutex.Unlock()
+
+	tickets, err := LoadLotteryTickets()
+	if err != nil {
+		log.Printf("Error loading lottery tickets: %v", err)
+		return "The lottery office is closed right now."
+	}
+
+	held := tickets[player.Name]
+	if held+count > economy.LotteryMaxTickets {
+		return fmt.Sprintf("You may hold at most %d tickets per drawing. You have %d.", economy.LotteryMaxTickets, held)
+	}
+
+	cost := count * economy.LotteryTicketPrice
+	if !ChargeGold(player, cost, GoldSourceLotteryTickets) {
+		return fmt.Sprintf("%d tickets cost %d gold. You can't afford that.", count, cost)
+	}
+
+	if err := AddLotteryTickets(player.Name, count); err != nil {
+		log.Printf("Error saving lottery tickets for %s: %v", player.Name, err)
+	}
+
+	pot, err := GetServerStateInt(lotteryPotKey)
+	if err != nil {
+		log.Printf("Error loading lottery pot: %v", err)
+	}
+	if err := SetServerStateInt(lotteryPotKey, pot+cost); err != nil {
+		log.Printf("Error saving lottery pot: %v", err)
+	}
+
+	return fmt.Sprintf("You buy %d lottery ticket(s) for %d gold. Good luck!", count, cost)
+}
+
+// lotteryStatus describes the current pot and the player's tickets
+func lotteryStatus(player *Player) string {
+	lotteryMutex.Lock()
+	defer lotteryMutex.Unlock()
+
+	pot, err := GetServerStateInt(lotteryPotKey)
+	if err != nil {
+		log.Printf("Error loading lottery pot: %v", err)
+	}
+	ticks, err := GetServerStateInt(lotteryTicksKey)
+	if err != nil {
+		log.Printf("Error loading lottery clock: %v", err)
+	}
+	tickets, err := LoadLotteryTickets()
+	if err != nil {
+		log.Printf("Error loading lottery tickets: %v", err)
+	}
+
+	hoursLeft := lotteryDrawTicks() - ticks
+	output := "{Y}The Midgaard Lottery{x}\r\n"
+	output += fmt.Sprintf("Current pot:   {Y}%s gold{x}\r\n", player.Locale.Number(pot))
+	output += fmt.Sprintf("Ticket price:  %d gold (up to %d per drawing)\r\n", economy.LotteryTicketPrice, economy.LotteryMaxTickets)
+	output += fmt.Sprintf("Your tickets:  %d\r\n", tickets[player.Name])
+	output += fmt.Sprintf("Next drawing:  in %d game day(s) and %d hour(s)", hoursLeft/TicksPerGameDay, hoursLeft%TicksPerGameDay)
+	return output
+}
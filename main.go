@@ -12,6 +12,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
@@ -24,22 +25,40 @@ import (
 )
 
 // Global variables
-var oocManager *OOCManager
 var timeManager *TimeManager
 
 // Global random number generator
 var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 
 // handleConnection manages player login and the overall lifecycle of the player's session
-func handleConnection(conn net.Conn) {
-	defer conn.Close()              // Ensure the connection is closed when the function exits
+func handleConnection(rawConn net.Conn) {
+	defer rawConn.Close() // Ensure the connection is closed when the function exits
+
+	// Turn away banned sites before anything else
+	if ban := SiteBan(rawConn.RemoteAddr()); ban != nil {
+		log.Printf("Refused a connection from banned site %s", rawConn.RemoteAddr())
+		rawConn.Write([]byte(BanMessage(ban)))
+		return
+	}
+
+	// Filter telnet negotiation out of the input, and negotiate GMCP, window size, and terminal type with the client
+	tconn := NewTelnetConn(rawConn)
+	var conn net.Conn = tconn
+	if _, isWeb := rawConn.(*WebSocketConn); !isWeb {
+		// Browsers can't answer telnet negotiation, so only offer it over TCP
+		tconn.Negotiate()
+	}
+
 	reader := bufio.NewReader(conn) // Create a buffered reader for reading from the connection
 
+	// Close connections that don't finish logging in
+	startLoginTimer(conn)
+
 	// First, ask about ANSI color before showing any colored content
 	conn.Write([]byte("Would you like to enable ANSI colors? (yes/no): "))
 
-	colorResponse, _ := reader.ReadString('\n')
-	colorResponse = strings.TrimSpace(strings.ToLower(colorResponse))
+	colorResponse, _ := ReadInput(reader)
+	colorResponse = strings.ToLower(colorResponse)
 	colorEnabled := colorResponse != "no" // Enable colors unless explicitly declined
 
 	// Now display the splash screen with or without colors
@@ -73,15 +92,49 @@ func handleConnection(conn net.Conn) {
 		conn.Write([]byte("What's your name, traveler? "))
 	}
 
-	name, _ := reader.ReadString('\n') // Read name input from the player
-	name = strings.TrimSpace(name)     // Remove any surrounding whitespace
+	// Read the name, asking again until it belongs to a player or could be a new one
+	var name string
+	for {
+		input, err := ReadInput(reader)
+		if err != nil {
+			return
+		}
+		name = input
+		if PlayerExists(name) {
+			break
+		}
+		if !ValidName(name) {
+			conn.Write([]byte(fmt.Sprintf("Names must be %d to %d letters, with no spaces or symbols.\r\nWhat's your name, traveler? ", MinNameLength, MaxNameLength)))
+			continue
+		}
+		if FilteredName(name) {
+			conn.Write([]byte("That name isn't allowed.\r\nWhat's your name, traveler? "))
+			continue
+		}
+		break
+	}
+
+	// Keep banned characters out
+	if ban := PlayerBan(name); ban != nil {
+		log.Printf("Refused a login by banned character %s", name)
+		conn.Write([]byte(BanMessage(ban)))
+		return
+	}
+
+	// A character already in the game is taken over rather than loaded again
+	if existing := FindPlayerByName(name); existing != nil {
+		stopLoginTimer(conn)
+		existing.TakeOver(conn, colorEnabled)
+		resumeTakenOver(existing, conn, reader)
+		return
+	}
 
 	// Check if the player already exists in the system
 	if !PlayerExists(name) {
 		// If the player does not exist, prompt to create a new character
 		conn.Write([]byte("Character not found. Would you like to create a new character? (yes/no) "))
-		response, _ := reader.ReadString('\n')                  // Read the player's response
-		response = strings.TrimSpace(strings.ToLower(response)) // Normalize the response to lowercase
+		response, _ := ReadInput(reader)     // Read the player's response
+		response = strings.ToLower(response) // Normalize the response to lowercase
 
 		if response != "yes" { // If the response is not "yes"
 			conn.Write([]byte("Goodbye!\r\n")) // Bid goodbye and exit
@@ -94,6 +147,7 @@ func handleConnection(conn net.Conn) {
 			conn.Write([]byte("Error creating character. Please try again.\r\n")) // Handle creation errors
 			return
 		}
+		stopLoginTimer(conn)
 
 		// Set the color preference from the initial prompt
 		player.ColorEnabled = colorEnabled
@@ -110,37 +164,84 @@ func handleConnection(conn net.Conn) {
 
 		// After successful player creation or loading, use AddPlayer
 		AddPlayer(player)
+		player.RecordLogin()
 
 		// Broadcast player join
-		oocManager.BroadcastMessage(fmt.Sprintf("[OOC] %s has connected.", player.Name), player)
+		channelOOC.Notify(fmt.Sprintf("%s has connected.", player.Name), player)
+		NotifyFriends(player, true)
 
 		// Send initial room description to the player
 		player.Send(DescribeRoom(player.Room, player))
+		player.SendRoomInfo()
+		player.SendStatus()
+		player.SendTime("IRE.Time.Info")
+		player.SendDefences()
 
 		// Calculate derived stats for loaded player
 		player.UpdateDerivedStats()
 
 		playGame(player, reader) // Start the game for the newly created player
 
-		// When player disconnects, use RemovePlayer
-		RemovePlayer(player)
-		oocManager.BroadcastMessage(fmt.Sprintf("[OOC] %s has disconnected.", player.Name), player)
+		endSession(player, conn)
 		return
 	}
 	// Player already exists; load their existing information from the database
-	race, class, title, roomID, str, dex, con, int_, wis, pre, level, xp, nextLevelXP, hp, maxHP, mp, maxMP, stamina, maxStamina, gold, dbColorEnabled, err := LoadPlayer(name)
+	player, err := loadExistingPlayer(name, conn)
 	if err != nil {
 		log.Printf("Error loading player %s: %v", name, err)
 		conn.Write([]byte("Error loading character.\r\n")) // Handle loading errors
 		return
 	}
+	stopLoginTimer(conn)
+
+	// Update the player's color preference in the database if it's different from the stored value
+	if colorEnabled != player.ColorEnabled {
+		err = UpdatePlayerColorPreference(name, colorEnabled)
+		if err != nil {
+			log.Printf("Error updating color preference: %v\n", err)
+		}
+	}
+
+	// Welcome the player back
+	player.Send(fmt.Sprintf("Welcome back, %s!", player.Name))
+
+	// After successful player creation or loading, use AddPlayer
+	AddPlayer(player)
+	player.RecordLogin()
+
+	// Broadcast player join
+	channelOOC.Notify(fmt.Sprintf("%s has connected.", player.Name), player)
+	NotifyFriends(player, true)
+
+	// Remind anyone who has notes on this player
+	NotifyNoteHolders(player)
+
+	// Bring back the pet the player stabled when they left
+	player.FetchPet()
+
+	// Send initial room description to the player
+	player.Send(DescribeRoom(player.Room, player))
+	player.SendRoomInfo()
+	player.SendStatus()
+	player.SendTime("IRE.Time.Info")
+	player.SendDefences()
+
+	playGame(player, reader) // Start the game for the loaded player
+
+	endSession(player, conn)
+}
+
+// loadExistingPlayer builds a Player for a saved character from the database
+func loadExistingPlayer(name string, conn net.Conn) (*Player, error) {
+	race, class, title, roomID, str, dex, con, int_, wis, pre, level, xp, nextLevelXP, hp, maxHP, mp, maxMP, stamina, maxStamina, gold, dbColorEnabled, err := LoadPlayer(name)
+	if err != nil {
+		return nil, err
+	}
 
 	// Fetch the room associated with the loaded player
 	room, err := GetRoom(roomID)
 	if err != nil {
-		log.Printf("Error getting room %d for player %s: %v", roomID, name, err)
-		conn.Write([]byte("Error loading game world.\r\n")) // Handle room loading errors
-		return
+		return nil, fmt.Errorf("room %d: %w", roomID, err)
 	}
 
 	// Create a new player with the loaded information
@@ -148,7 +249,7 @@ func handleConnection(conn net.Conn) {
 		Name:         name,
 		Race:         race,
 		Class:        class,
-		Title:        title,
+		Title:        SanitizeText(title),
 		STR:          str,
 		DEX:          dex,
 		CON:          con,
@@ -170,34 +271,107 @@ func handleConnection(conn net.Conn) {
 		ColorEnabled: dbColorEnabled,
 	}
 
-	// Update the player's color preference in the database if it's different from the stored value
-	if colorEnabled != dbColorEnabled {
-		err = UpdatePlayerColorPreference(name, colorEnabled)
-		if err != nil {
-			log.Printf("Error updating color preference: %v\n", err)
-		}
+	// Restore the player's spam squelch preference
+	if squelch, err := LoadPlayerSquelch(name); err != nil {
+		log.Printf("Error loading squelch preference for %s: %v", name, err)
+		player.SquelchEnabled = true
+	} else {
+		player.SquelchEnabled = squelch
 	}
 
-	// Welcome the player back
-	player.Send(fmt.Sprintf("Welcome back, %s!", player.Name))
+	// Restore the player's roleplay flag
+	if roleplay, err := LoadPlayerRoleplay(name); err != nil {
+		log.Printf("Error loading RP flag for %s: %v", name, err)
+	} else {
+		player.Roleplay = roleplay
+	}
 
-	// After successful player creation or loading, use AddPlayer
-	AddPlayer(player)
+	// Restore the player's trust level
+	if trust, err := LoadPlayerTrust(name); err != nil {
+		log.Printf("Error loading trust level for %s: %v", name, err)
+	} else {
+		player.SetTrust(trust)
+	}
 
-	// Broadcast player join
-	oocManager.BroadcastMessage(fmt.Sprintf("[OOC] %s has connected.", player.Name), player)
+	// Restore the player's low resource alerts and automatic actions
+	player.LoadAlerts()
+	player.LoadAutoToggles()
+	player.LoadDisplay()
+	player.LoadPrompt()
+	player.LoadAliases()
+	player.LoadPageLength()
+	player.LoadWidth()
+	player.LoadLocale()
+
+	// Restore how hungry and thirsty the player is
+	player.LoadSurvival()
+
+	// Restore how long the player has played, and start the session clock
+	player.LoadPlaytime()
+
+	// Restore the player's bank balance
+	if balance, err := LoadPlayerBank(name); err != nil {
+		log.Printf("Error loading bank balance for %s: %v", name, err)
+	} else {
+		player.BankBalance = balance
+	}
 
-	// Send initial room description to the player
-	player.Send(DescribeRoom(player.Room, player))
+	// Restore the items the player was carrying and wearing
+	player.LoadInventory()
+
+	// Restore any buffs and debuffs that hadn't worn off
+	player.LoadAffects()
+
+	// Restore the quests the player has completed and the skills they've learned
+	player.LoadQuests()
+	player.LoadSkills()
+
+	// Restore the player's achievements and the rooms they've explored
+	player.LoadAchievements()
+	player.VisitRoom(player.Room)
+
+	// Restore the languages the player has learned and the one they speak
+	player.LoadLanguages()
+
+	// Restore the channels the player has turned off, and who they ignore and count as friends
+	player.LoadChannels()
+	player.LoadIgnores()
+	player.LoadFriends()
+
+	// Restore the player's clan membership
+	player.LoadClan()
 
 	// Calculate derived stats for loaded player
 	player.UpdateDerivedStats()
 
-	playGame(player, reader) // Start the game for the loaded player
+	return player, nil
+}
+
+// endSession cleans up after a player's game loop on a connection ends
+// Nothing is done if the player has since been taken over by another connection.
+func endSession(player *Player, conn net.Conn) {
+	if player.Conn != conn {
+		return
+	}
+
+	// Save any session transcript that was still being recorded
+	if _, err := FinishTranscript(player); err != nil {
+		log.Printf("Error saving transcript for %s: %v", player.Name, err)
+	}
+
+	// Nobody goes on watching a session that has ended
+	endSnoops(player)
+
+	// Pets wait in the stables; charmed followers don't outlast their master's session
+	player.Dismount()
+	player.StablePet()
+	player.ReleaseFollowers()
 
 	// When player disconnects, use RemovePlayer
 	RemovePlayer(player)
-	oocManager.BroadcastMessage(fmt.Sprintf("[OOC] %s has disconnected.", player.Name), player)
+	player.RecordLogout()
+	channelOOC.Notify(fmt.Sprintf("%s has disconnected.", player.Name), player)
+	NotifyFriends(player, false)
 }
 
 // playGame handles the main game loop for a player
@@ -207,15 +381,28 @@ func playGame(player *Player, reader *bufio.Reader) {
 
 	for {
 		// Read input from the player
-		input, err := reader.ReadString('\n')
+		input, err := ReadInput(reader)
 		if err != nil {
 			// Handle connection errors
 			log.Printf("Error reading from connection: %v", err)
 			return
 		}
 
-		// Process the input
-		input = strings.TrimSpace(input)
+		// Throw away input from a client sending it faster than allowed
+		allowed, disconnect := player.CheckFlood()
+		if disconnect {
+			return
+		}
+		if !allowed {
+			continue
+		}
+
+		// Input at a MORE prompt pages on, or stops the pager
+		if player.Paging() && player.ContinuePaging(input) {
+			continue
+		}
+
+		// Process the input, which has already been trimmed and sanitized
 		if input == "" {
 			// Display prompt again if empty input
 			displayPrompt(player)
@@ -225,19 +412,25 @@ func playGame(player *Player, reader *bufio.Reader) {
 		// Store the last command for reference (needed for movement)
 		player.LastCommand = input
 
+		// Add the command to the session transcript if logging is on
+		player.RecordInput(input)
+		player.SnoopInput(input)
+
 		// Handle the command and get the response
 		response := HandleCommand(player, input)
 
-		// Send the response back to the player
+		// Send the response back to the player, a page at a time if it's long
 		if response != "" {
-			player.Send(response)
+			player.Page(response)
 		}
 
-		// Always display the prompt after a command
-		displayPrompt(player)
+		// Always display the prompt after a command, unless the pager is waiting
+		if !player.Paging() {
+			displayPrompt(player)
+		}
 
-		// Check if the player wants to quit
-		if input == "quit" {
+		// Check if the player has logged out
+		if player.Quitting {
 			return
 		}
 	}
@@ -250,25 +443,52 @@ func setupSignalHandler() {
 
 	go func() {
 		<-c
-		fmt.Println("Shutting down server...")
+		stopServer()
+	}()
+}
 
-		// Stop the time manager
-		if timeManager != nil {
-			timeManager.Stop()
-		}
+// stopServer saves the world and exits
+func stopServer() {
+	fmt.Println("Shutting down server...")
 
-		// Close database connection
-		if db != nil {
-			db.Close()
-		}
+	// Stop the time manager
+	if timeManager != nil {
+		timeManager.Stop()
+	}
 
-		fmt.Println("Server shutdown complete")
-		os.Exit(0)
-	}()
+	// Keep the items lying in rooms for the next boot
+	if db != nil {
+		SaveRoomItems()
+	}
+
+	// Close database connection
+	if db != nil {
+		db.Close()
+	}
+
+	fmt.Println("Server shutdown complete")
+	os.Exit(0)
 }
 
 // main initializes the MUD server and starts listening for connections
 func main() {
+	// Parse command line flags
+	copyover := flag.Bool("copyover", false, "reattach player sessions saved by a copyover")
+	importROM := flag.Bool("import-rom", false, "convert the ROM or Merc .are files named after the flags into area files, then exit")
+	validateOnly := flag.Bool("validate-only", false, "check the area files for problems, then exit")
+	flag.Parse()
+
+	// Converting areas doesn't need the game running
+	if *importROM {
+		if err := ImportROMAreas(flag.Args()); err != nil {
+			log.Fatalf("Error importing areas: %v", err)
+		}
+		return
+	}
+	if *validateOnly {
+		os.Exit(ValidateAreas())
+	}
+
 	// Setup signal handler for graceful shutdown
 	setupSignalHandler()
 
@@ -278,8 +498,28 @@ func main() {
 	// Initialize the database
 	InitDB()
 
-	// Initialize OOC manager with the player mutex and active players map
-	oocManager = NewOOCManager(&playersMutex, activePlayers)
+	// Note when the server came up, keeping the original boot time across a copyover
+	InitUptime(*copyover)
+
+	// Apply any server setting overrides (death penalties)
+	if err := LoadServerConfig(); err != nil {
+		log.Fatalf("Error loading server settings: %v", err)
+	}
+
+	// Apply any economy overrides (gold sink prices, lottery settings)
+	if err := LoadEconomyConfig(); err != nil {
+		log.Fatalf("Error loading economy settings: %v", err)
+	}
+
+	// Load the bans keeping characters and sites out
+	if err := LoadBans(); err != nil {
+		log.Fatalf("Error loading bans: %v", err)
+	}
+
+	// Load the clans
+	if err := LoadClans(); err != nil {
+		log.Fatalf("Error loading clans: %v", err)
+	}
 
 	// Initialize and start the time manager
 	timeManager = NewTimeManager()
@@ -290,12 +530,14 @@ func main() {
 	// timeManager.RegisterPulseFunc(DebugPulse)
 	// timeManager.RegisterHeartbeatFunc(DebugHeartbeat)
 
-	// Register player regeneration on tick
+	// Register player playtime, hunger, thirst, and regeneration on tick
 	timeManager.RegisterTickFunc(func() {
 		playersMutex.Lock()
 		defer playersMutex.Unlock()
 
 		for _, player := range activePlayers {
+			player.PlaytimeTick()
+			player.SurvivalTick()
 			player.RegenTick()
 		}
 	})
@@ -323,14 +565,84 @@ func main() {
 						log.Printf("[ERROR] Panic in player pulse update for %s: %v", p.Name, r)
 					}
 				}()
+				p.FlushStaleRepeats()
 				p.PulseUpdate()
 			}(player)
 		}
 	})
 
+	// Register item decay (corpses rotting away) on tick
+	timeManager.RegisterTickFunc(ProcessItemDecay)
+
+	// Register scavengers eating rotting corpses
+	timeManager.RegisterTickFunc(ProcessScavengers)
+
+	// Register exhausted resource nodes replenishing
+	timeManager.RegisterTickFunc(ProcessResourceNodes)
+
+	// Register affect durations counting down
+	timeManager.RegisterTickFunc(ProcessAffects)
+
+	// Register the weather changing
+	timeManager.RegisterTickFunc(ProcessWeather)
+
+	// Register mob programs that run at random
+	timeManager.RegisterTickFunc(ProcessRandomProgs)
+
+	// Register area scripts' tick handlers
+	timeManager.RegisterTickFunc(ProcessScripts)
+
+	// Register the daily purge of inactive characters
+	timeManager.RegisterTickFunc(ProcessInactivePurge)
+
+	// Start the optional systems compiled in and turned on in config.yml
+	InitPlugins(timeManager)
+
+	// Subscribe achievements to the kills, levels, and rooms that earn them
+	Subscribe(EventMobKilled, onAchievementKill)
+	Subscribe(EventLevelUp, onAchievementLevel)
+	Subscribe(EventRoomEntered, onAchievementEnter)
+
+	// Subscribe ambushes and mob greetings to players walking into rooms
+	Subscribe(EventRoomEntered, onAmbushEnter)
+	Subscribe(EventRoomEntered, onProgEnter)
+
+	// Subscribe area scripts to the events they handle
+	Subscribe(EventRoomEntered, onScriptEnter)
+	Subscribe(EventMobKilled, onScriptKill)
+	Subscribe(EventPlayerDied, onScriptDeath)
+
+	// Register the game clock and the rising and setting of the sun
+	InitCalendar()
+	timeManager.RegisterTickFunc(AdvanceCalendar)
+
 	// Register mob wandering behavior
 	timeManager.RegisterPulseFunc(ProcessMobWandering)
 
+	// Register mobs chasing players who fled from them
+	timeManager.RegisterPulseFunc(ProcessMobPursuit)
+
+	// Register charmed followers fighting and guarding
+	timeManager.RegisterPulseFunc(ProcessFollowers)
+
+	// Register mobs of opposing factions fighting each other
+	timeManager.RegisterPulseFunc(ProcessMobBrawls)
+
+	// Register fighters getting up, shaking off stuns, and recovering from disarms
+	timeManager.RegisterPulseFunc(ProcessCombatStates)
+
+	// Register ambient mob emotes
+	timeManager.RegisterPulseFunc(ProcessMobEmotes)
+
+	// Register dynamic level scaling for areas that enable it
+	timeManager.RegisterPulseFunc(ProcessMobScaling)
+
+	// Register logging out players who don't answer a bot check in time
+	timeManager.RegisterPulseFunc(ProcessBotChecks)
+
+	// Register the countdown to a scheduled shutdown or reboot
+	timeManager.RegisterPulseFunc(ProcessShutdown)
+
 	// Schedule periodic resets (doors and mobs)
 	ScheduleResets(timeManager)
 
@@ -347,44 +659,70 @@ func main() {
 		log.Fatalf("Error loading areas: %v", err)
 	}
 
+	// Load the Lua scripts areas use for their quests
+	LoadScripts()
+
 	// Process mob resets after loading areas
 	ResetMobs()
 
-	// Start the MUD server
-	listener, err := net.Listen("tcp", "0.0.0.0:4000")
-	if err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	// Index room and mob text for builders' searches
+	BuildSearchIndex()
+
+	// Load player houses, which need the rooms and items in place
+	if err := LoadHouses(); err != nil {
+		log.Fatalf("Error loading houses: %v", err)
 	}
-	defer listener.Close()
 
-	fmt.Println("MUD server listening on port 4000...")
+	// Put back the items left lying in rooms at the last shutdown
+	RestoreRoomItems()
 
-	// Accept and handle incoming connections
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			log.Println("Connection error:", err)
-			continue
-		}
-		go handleConnection(conn)
+	// Start accepting connections on every configured port
+	if err := StartListeners(); err != nil {
+		log.Fatalf("Error starting server: %v", err)
 	}
+
+	// Pick up the players who were connected before a copyover
+	if *copyover {
+		RestoreCopyover()
+	}
+
+	// The listeners serve connections from here on
+	select {}
 }
 
 // displayPrompt shows the player's current stats (HP, MP, Stamina) as a prompt
 func displayPrompt(player *Player) {
+	// Summarize any squelched repeats before drawing the prompt
+	player.FlushRepeats()
+
 	// Format: [HP: 100/100 | MP: 100/100 | ST: 100/100]>
 	prompt := fmt.Sprintf("[HP: %d/%d | MP: %d/%d | ST: %d/%d]> ",
 		player.HP, player.MaxHP,
 		player.MP, player.MaxMP,
 		player.Stamina, player.MaxStamina)
 
+	// Keep GMCP clients' vitals in sync with the prompt
+	player.SendVitals()
+
+	// Players who wrote their own prompt color it themselves
+	if player.Prompt != "" {
+		player.Conn.Write([]byte(ProcessColors(RenderPrompt(player, player.Prompt), player.ColorEnabled)))
+		return
+	}
+
 	// Apply color to the prompt based on health percentage
 	healthPercent := float64(player.HP) / float64(player.MaxHP)
 
+	// Pick out mana in its own color while it's below the player's alert
+	if player.LowMP() && player.ColorEnabled {
+		prompt = strings.Replace(prompt, "MP:", "{M}MP:", 1)
+		prompt = strings.Replace(prompt, " | ST:", "{x} | ST:", 1)
+	}
+
 	if player.ColorEnabled {
 		var colorCode string
-		if healthPercent < 0.3 {
-			// Red for low health
+		if player.LowHP() {
+			// Red once the player's low health alert has been crossed
 			colorCode = "{R}"
 		} else if healthPercent < 0.6 {
 			// Yellow for medium health
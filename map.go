@@ -0,0 +1,228 @@
+/*
+ * map.go
+ *
+ * This file implements the 'map' command, which draws an ASCII map of the
+ * rooms around the player. The map is built by walking north, south, east,
+ * and west exits out to a chosen depth and laying the rooms out on a grid.
+ * The player's room, exits leading off the edge of the map, closed doors,
+ * and rooms with exits up or down are all marked.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Map depth limits, in rooms from the player
+const (
+	DefaultMapDepth = 2
+	MaxMapDepth     = 5
+)
+
+// mapOffsets gives the grid step for each direction drawn on the map
+var mapOffsets = map[string][2]int{
+	"north": {0, -1},
+	"south": {0, 1},
+	"east":  {1, 0},
+	"west":  {-1, 0},
+}
+
+// mapGrid is a map being drawn, one colored character per cell
+type mapGrid struct {
+	radius int
+	cells  [][]string
+}
+
+// newMapGrid creates a blank grid large enough for rooms up to radius steps away
+func newMapGrid(radius int) *mapGrid {
+	size := radius*2 + 1
+	cells := make([][]string, size*2-1)
+	for row := range cells {
+		cells[row] = make([]string, size*4-1)
+		for col := range cells[row] {
+			cells[row][col] = " "
+		}
+	}
+	return &mapGrid{radius: radius, cells: cells}
+}
+
+// inBounds reports whether a room position fits on the grid
+func (g *mapGrid) inBounds(pos [2]int) bool {
+	return pos[0] >= -g.radius && pos[0] <= g.radius && pos[1] >= -g.radius && pos[1] <= g.radius
+}
+
+// set draws a character at a screen position relative to a room position
+func (g *mapGrid) set(pos [2]int, colOffset, rowOffset int, char, color string) {
+	row := (pos[1]+g.radius)*2 + rowOffset
+	col := (pos[0]+g.radius)*4 + colOffset
+	if row < 0 || row >= len(g.cells) || col < 0 || col >= len(g.cells[row]) {
+		return
+	}
+	g.cells[row][col] = color + char + "{x}"
+}
+
+// drawRoom draws a room's box with the given marker inside
+func (g *mapGrid) drawRoom(pos [2]int, marker, markerColor, color string) {
+	g.set(pos, 0, 0, "[", color)
+	g.set(pos, 1, 0, marker, markerColor)
+	g.set(pos, 2, 0, "]", color)
+}
+
+// drawExit draws the connector between a room and its neighbor in the given direction
+func (g *mapGrid) drawExit(pos [2]int, direction, char, color string) {
+	switch direction {
+	case "north":
+		g.set(pos, 1, -1, char, color)
+	case "south":
+		g.set(pos, 1, 1, char, color)
+	case "east":
+		g.set(pos, 3, 0, char, color)
+	case "west":
+		g.set(pos, -1, 0, char, color)
+	}
+}
+
+// String renders the grid, dropping blank lines above and below the map
+func (g *mapGrid) String() string {
+	var lines []string
+	for _, row := range g.cells {
+		lines = append(lines, strings.TrimRight(strings.Join(row, ""), " "))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// verticalMarker shows whether a room has exits up or down
+func verticalMarker(room *Room, viewer *Player) string {
+	up := viewer.CanSeeExit(room.Exits["up"])
+	down := viewer.CanSeeExit(room.Exits["down"])
+	switch {
+	case up && down:
+		return "="
+	case up:
+		return "^"
+	case down:
+		return "v"
+	}
+	return " "
+}
+
+// BuildMap lays out the rooms within depth steps of the start room and draws them
+// Exits the viewer can't see are left off the map.
+func BuildMap(start *Room, depth int, viewer *Player) string {
+	// Walk the exits breadth first so nearer rooms claim their grid positions first
+	positions := map[int][2]int{start.ID: {0, 0}}
+	occupied := map[[2]int]*Room{{0, 0}: start}
+	order := []*Room{start}
+	distance := map[int]int{start.ID: 0}
+
+	for i := 0; i < len(order); i++ {
+		room := order[i]
+		if distance[room.ID] == depth {
+			continue
+		}
+		for direction, offset := range mapOffsets {
+			exit, ok := room.Exits[direction]
+			if !ok || !viewer.CanSeeExit(exit) {
+				continue
+			}
+			id, err := ResolveExitRoomID(exit)
+			if err != nil {
+				continue
+			}
+			if _, seen := positions[id]; seen {
+				continue
+			}
+			next, err := GetRoom(id)
+			if err != nil {
+				continue
+			}
+
+			pos := positions[room.ID]
+			pos = [2]int{pos[0] + offset[0], pos[1] + offset[1]}
+			if occupied[pos] != nil {
+				continue // The area doesn't fit on a flat grid here
+			}
+
+			positions[id] = pos
+			occupied[pos] = next
+			distance[id] = distance[room.ID] + 1
+			order = append(order, next)
+		}
+	}
+
+	// One extra ring around the map leaves room for unexplored exits
+	grid := newMapGrid(depth + 1)
+	for _, room := range order {
+		pos := positions[room.ID]
+		if room == start {
+			grid.drawRoom(pos, "*", "{R}", "{C}")
+		} else {
+			grid.drawRoom(pos, verticalMarker(room, viewer), "{W}", "{C}")
+		}
+
+		for direction, offset := range mapOffsets {
+			exit, ok := room.Exits[direction]
+			if !ok || !viewer.CanSeeExit(exit) {
+				continue
+			}
+
+			char, color := "-", "{W}"
+			if direction == "north" || direction == "south" {
+				char = "|"
+			}
+			if exit.Door != nil && exit.Door.Closed {
+				char, color = "#", "{Y}"
+			}
+
+			neighbor := [2]int{pos[0] + offset[0], pos[1] + offset[1]}
+			id, _ := ResolveExitRoomID(exit)
+			switch {
+			case occupied[neighbor] != nil && occupied[neighbor].ID == id:
+				grid.drawExit(pos, direction, char, color)
+			case occupied[neighbor] == nil && grid.inBounds(neighbor):
+				// Leads somewhere not yet drawn
+				grid.drawExit(pos, direction, char, color)
+				grid.drawRoom(neighbor, "?", "{D}", "{D}")
+			default:
+				// Leads to a room drawn elsewhere on the map
+				grid.drawExit(pos, direction, char, "{D}")
+			}
+		}
+	}
+
+	return grid.String()
+}
+
+// handleMap processes the map command
+func handleMap(player *Player, args []string) string {
+	if player.Room == nil {
+		return "You are nowhere."
+	}
+	if !player.CanSee() {
+		return "It's too dark to make out where you are."
+	}
+
+	depth := DefaultMapDepth
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > MaxMapDepth {
+			return fmt.Sprintf("Usage: map [depth]  (depth 1-%d)", MaxMapDepth)
+		}
+		depth = n
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("{C}%s{x}\r\n\r\n", player.Room.Name))
+	sb.WriteString(BuildMap(player.Room, depth, player))
+	sb.WriteString("\r\n\r\n{R}*{x} You  {D}?{x} Unexplored  {Y}#{x} Closed door  ^ Up  v Down  = Up and down")
+	return sb.String()
+}
@@ -20,23 +20,44 @@ import (
 
 // Mob represents a mobile entity in the game
 type Mob struct {
-	ID               int      `yaml:"id"`
-	Keywords         []string `yaml:"keywords"`
-	ShortDescription string   `yaml:"short_description"` // Used when the mob performs an action
-	LongDescription  string   `yaml:"long_description"`  // Displayed when the mob is in a room
-	Description      string   `yaml:"description"`       // Displayed when a player looks at the mob
-	Race             string   `yaml:"race"`
-	Level            int      `yaml:"level"`
-	Toughness        string   `yaml:"toughness"`
-	Wandering        bool     `yaml:"wandering"` // Whether this mob wanders around
-	HomeArea         string   // The area this mob belongs to and should stay within
+	ID               int             `yaml:"-"` // Set from the mobiles section's key
+	Keywords         []string        `yaml:"keywords"`
+	ShortDescription string          `yaml:"short_description"` // Used when the mob performs an action
+	LongDescription  string          `yaml:"long_description"`  // Displayed when the mob is in a room
+	Description      string          `yaml:"description"`       // Displayed when a player looks at the mob
+	Race             string          `yaml:"race"`
+	Level            int             `yaml:"level"`
+	Toughness        string          `yaml:"toughness,omitempty"`
+	Wandering        bool            `yaml:"wandering,omitempty"`       // Whether this mob wanders around
+	Gold             int             `yaml:"gold,omitempty"`            // Coins carried (0 = derived from level)
+	Loot             []LootDrop      `yaml:"loot,omitempty"`            // Items that may drop on death
+	Guildmaster      *Guildmaster    `yaml:"guildmaster,omitempty"`     // Set if this mob trains a class guild
+	Banker           bool            `yaml:"banker,omitempty"`          // Takes deposits and withdrawals
+	PetShop          int             `yaml:"pet_shop,omitempty"`        // Kennel room whose creatures this mob sells as pets
+	Mount            bool            `yaml:"mount,omitempty"`           // Can be ridden by players
+	TeachesLanguages []string        `yaml:"tutor,omitempty"`           // Languages this mob tutors players in
+	Wimpy            int             `yaml:"wimpy,omitempty"`           // Percent of max HP below which it may flee (0 = default)
+	Fearless         bool            `yaml:"fearless,omitempty"`        // Never flees
+	Pursues          bool            `yaml:"pursues,omitempty"`         // Chases players who flee from it
+	Patrol           *Patrol         `yaml:"patrol,omitempty"`          // Fixed route walked instead of wandering
+	Emotes           []MobEmote      `yaml:"emotes,omitempty"`          // Ambient actions performed near players
+	Evil             bool            `yaml:"evil,omitempty"`            // Marked with a red aura to players who can detect evil
+	SpecialAttacks   []SpecialAttack `yaml:"special_attacks,omitempty"` // Attacks on mana or stamina used in place of a swing
+	Progs            []MobProg       `yaml:"progs,omitempty"`           // Scripted reactions to players
+	Nocturnal        bool            `yaml:"nocturnal,omitempty"`       // Only spawns at night and leaves at dawn
+	Scavenger        bool            `yaml:"scavenger,omitempty"`       // Eats the corpses of other mobs once they rot
+	Faction          string          `yaml:"faction,omitempty"`         // Side this mob is on in fights between mobs
+	Enemies          []string        `yaml:"enemies,omitempty"`         // Factions this mob attacks on sight
+	Blocks           *ExitGuard      `yaml:"blocks,omitempty"`          // Exit this mob stops players from taking
+	Dummy            bool            `yaml:"dummy,omitempty"`           // Practice dummy: never loses health or strikes back
+	HomeArea         string          `yaml:"-"`                         // The area this mob belongs to and should stay within
 
 	// Derived stats
-	HP    int
-	MaxHP int
+	HP    int `yaml:"-"`
+	MaxHP int `yaml:"-"`
 
 	// Current room
-	Room *Room
+	Room *Room `yaml:"-"`
 }
 
 // MobReset represents a mob spawn configuration
@@ -51,7 +72,28 @@ type MobReset struct {
 // MobInstance represents an actual mob in the game world
 type MobInstance struct {
 	*Mob
-	InstanceID int // Unique identifier for this specific instance
+	InstanceID int        // Unique identifier for this specific instance
+	Affects    AffectList // Buffs and debuffs currently on this mob
+	TaggedBy   *Player    // Player who attacked first and is owed the kill
+
+	CombatState // Knocked down, stunned, or disarmed
+
+	Pursuing      *Player // Player this mob is chasing after they fled
+	PursuitPulses int     // Pulses left before the mob gives up the chase
+
+	PatrolIndex int // Position on the patrol route the mob is at or heading back to
+	PatrolStep  int // Direction along the route: 1 forward, -1 back
+	PatrolWait  int // Pulses before the mob takes its next patrol step
+
+	Master   *Player      // Player this mob is charmed into following
+	Guarding *Player      // Player this follower defends
+	Fighting *MobInstance // Mob this one is fighting: a follower's ordered target or a faction enemy
+	Pet      bool         // Bought from a pet shop rather than charmed
+	PetName  string       // Name the owner gave the pet, if any
+	Rider    *Player      // Player riding this mob
+
+	Bribed    map[string]bool // Players this guard has been paid to let by
+	Regarding string          // Player a merchant was charmed by, for better or worse
 }
 
 // Global variables for mob management
@@ -137,6 +179,15 @@ func SpawnMob(mobID int, room *Room) (*MobInstance, error) {
 	}
 
 	// Create a new instance
+	instance := createMobInstance(mobTemplate, room)
+
+	//log.Printf("Spawned mob [%d] instance %d in room %d", mobID, instance.InstanceID, room.ID)
+	return instance, nil
+}
+
+// createMobInstance builds a live instance of a mob template in the given room
+// and registers it with the tracking maps. The caller must hold mobMutex.
+func createMobInstance(mobTemplate *Mob, room *Room) *MobInstance {
 	instance := &MobInstance{
 		Mob: &Mob{
 			ID:               mobTemplate.ID,
@@ -148,6 +199,26 @@ func SpawnMob(mobID int, room *Room) (*MobInstance, error) {
 			Level:            mobTemplate.Level,
 			Toughness:        mobTemplate.Toughness,
 			Wandering:        mobTemplate.Wandering,
+			Gold:             mobTemplate.Gold,
+			Loot:             mobTemplate.Loot,
+			Guildmaster:      mobTemplate.Guildmaster,
+			Wimpy:            mobTemplate.Wimpy,
+			Fearless:         mobTemplate.Fearless,
+			Pursues:          mobTemplate.Pursues,
+			Patrol:           mobTemplate.Patrol,
+			Emotes:           mobTemplate.Emotes,
+			Banker:           mobTemplate.Banker,
+			PetShop:          mobTemplate.PetShop,
+			Mount:            mobTemplate.Mount,
+			TeachesLanguages: mobTemplate.TeachesLanguages,
+			SpecialAttacks:   mobTemplate.SpecialAttacks,
+			Evil:             mobTemplate.Evil,
+			Nocturnal:        mobTemplate.Nocturnal,
+			Scavenger:        mobTemplate.Scavenger,
+			Faction:          mobTemplate.Faction,
+			Enemies:          mobTemplate.Enemies,
+			Blocks:           mobTemplate.Blocks,
+			Progs:            mobTemplate.Progs,
 			HomeArea:         room.Area,
 			MaxHP:            mobTemplate.MaxHP,
 			HP:               mobTemplate.MaxHP,
@@ -157,9 +228,13 @@ func SpawnMob(mobID int, room *Room) (*MobInstance, error) {
 	}
 	nextMobInstanceID++
 
+	if instance.Patrol != nil {
+		instance.startPatrol()
+	}
+
 	// Add to tracking maps
 	mobInstances[instance.InstanceID] = instance
-	worldMobCounts[mobID]++
+	worldMobCounts[mobTemplate.ID]++
 
 	// Add to room
 	if roomMobs[room.ID] == nil {
@@ -167,8 +242,7 @@ func SpawnMob(mobID int, room *Room) (*MobInstance, error) {
 	}
 	roomMobs[room.ID] = append(roomMobs[room.ID], instance)
 
-	//log.Printf("Spawned mob [%d] instance %d in room %d", mobID, instance.InstanceID, room.ID)
-	return instance, nil
+	return instance
 }
 
 // GetMobsInRoom returns all mobs in a specific room
@@ -328,15 +402,29 @@ var mobResets []MobReset
 
 // ProcessMobResets spawns mobs according to the reset configuration
 func ProcessMobResets() {
+	processMobResets(false)
+}
+
+// processMobResets spawns mobs, or only nocturnal mobs if nocturnalOnly is set
+// Nocturnal mobs are only spawned at night.
+func processMobResets(nocturnalOnly bool) {
 	//log.Println("Processing mob resets...")
 
 	// Lock the mob mutex to prevent race conditions
 	mobMutex.Lock()
 	defer mobMutex.Unlock()
 
+	spawnResets(mobResets, nocturnalOnly)
+
+	//log.Println("Mob resets completed")
+}
+
+// spawnResets spawns mobs for the given resets, or only nocturnal mobs if nocturnalOnly is set
+// The caller must hold mobMutex.
+func spawnResets(resets []MobReset, nocturnalOnly bool) {
 	// Group resets by mob ID to handle world limits properly
 	mobResetsByID := make(map[int][]MobReset)
-	for _, reset := range mobResets {
+	for _, reset := range resets {
 		mobResetsByID[reset.MobVnum] = append(mobResetsByID[reset.MobVnum], reset)
 	}
 
@@ -347,6 +435,9 @@ func ProcessMobResets() {
 			//log.Printf("Skipping resets for mob %d: not found in registry", mobID)
 			continue
 		}
+		if mobRegistry[mobID].Nocturnal && !IsNight() || !mobRegistry[mobID].Nocturnal && nocturnalOnly {
+			continue
+		}
 
 		// Get current world count and max world limit
 		currentWorldCount := worldMobCounts[mobID]
@@ -394,39 +485,8 @@ func ProcessMobResets() {
 
 				// If room doesn't have this mob yet and room limit allows, spawn one
 				if !roomHasMob && reset.Limit > 0 {
-					// Get the mob template
-					mobTemplate := mobRegistry[mobID]
-
-					// Create a new instance
-					instance := &MobInstance{
-						Mob: &Mob{
-							ID:               mobTemplate.ID,
-							Keywords:         mobTemplate.Keywords,
-							ShortDescription: mobTemplate.ShortDescription,
-							LongDescription:  strings.TrimSpace(mobTemplate.LongDescription),
-							Description:      strings.TrimSpace(mobTemplate.Description),
-							Race:             mobTemplate.Race,
-							Level:            mobTemplate.Level,
-							Toughness:        mobTemplate.Toughness,
-							Wandering:        mobTemplate.Wandering,
-							HomeArea:         room.Area,
-							MaxHP:            mobTemplate.MaxHP,
-							HP:               mobTemplate.MaxHP,
-							Room:             room,
-						},
-						InstanceID: nextMobInstanceID,
-					}
-					nextMobInstanceID++
-
-					// Add to tracking maps
-					mobInstances[instance.InstanceID] = instance
-					worldMobCounts[mobID]++
-
-					// Add to room
-					if roomMobs[room.ID] == nil {
-						roomMobs[room.ID] = make([]*MobInstance, 0)
-					}
-					roomMobs[room.ID] = append(roomMobs[room.ID], instance)
+					// Create a new instance from the template
+					createMobInstance(mobRegistry[mobID], room)
 
 					remainingAllowed--
 				}
@@ -469,39 +529,8 @@ func ProcessMobResets() {
 
 				// Spawn the mobs
 				for i := 0; i < roomRemaining; i++ {
-					// Get the mob template
-					mobTemplate := mobRegistry[mobID]
-
-					// Create a new instance
-					instance := &MobInstance{
-						Mob: &Mob{
-							ID:               mobTemplate.ID,
-							Keywords:         mobTemplate.Keywords,
-							ShortDescription: mobTemplate.ShortDescription,
-							LongDescription:  strings.TrimSpace(mobTemplate.LongDescription),
-							Description:      strings.TrimSpace(mobTemplate.Description),
-							Race:             mobTemplate.Race,
-							Level:            mobTemplate.Level,
-							Toughness:        mobTemplate.Toughness,
-							Wandering:        mobTemplate.Wandering,
-							HomeArea:         room.Area,
-							MaxHP:            mobTemplate.MaxHP,
-							HP:               mobTemplate.MaxHP,
-							Room:             room,
-						},
-						InstanceID: nextMobInstanceID,
-					}
-					nextMobInstanceID++
-
-					// Add to tracking maps
-					mobInstances[instance.InstanceID] = instance
-					worldMobCounts[mobID]++
-
-					// Add to room
-					if roomMobs[room.ID] == nil {
-						roomMobs[room.ID] = make([]*MobInstance, 0)
-					}
-					roomMobs[room.ID] = append(roomMobs[room.ID], instance)
+					// Create a new instance from the template
+					createMobInstance(mobRegistry[mobID], room)
 
 					remainingAllowed--
 					if remainingAllowed <= 0 {
@@ -511,8 +540,6 @@ func ProcessMobResets() {
 			}
 		}
 	}
-
-	//log.Println("Mob resets completed")
 }
 
 // MoveMob moves a mob from one room to another
@@ -538,23 +565,9 @@ func MoveMob(mob *MobInstance, direction string) error {
 	}
 
 	// Get the destination room
-	var destRoomID int
-	switch exitID := exit.ID.(type) {
-	case int:
-		destRoomID = exitID
-	case string:
-		// Handle cross-area movement (simplified)
-		parts := strings.Split(exitID, ":")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid exit format")
-		}
-		var err error
-		destRoomID, err = strconv.Atoi(parts[1])
-		if err != nil {
-			return fmt.Errorf("invalid room ID in exit")
-		}
-	default:
-		return fmt.Errorf("unsupported exit type")
+	destRoomID, err := ResolveExitRoomID(exit)
+	if err != nil {
+		return err
 	}
 
 	destRoom, err := GetRoom(destRoomID)
@@ -563,8 +576,9 @@ func MoveMob(mob *MobInstance, direction string) error {
 	}
 
 	// Check if the destination room has the NoWandering flag set
-	// If it does, prevent mobs from wandering into it
-	if destRoom.NoWandering {
+	// If it does, prevent mobs from wandering into it unless it's on their patrol route
+	// Followers go wherever their master leads.
+	if destRoom.NoWandering && mob.Master == nil && (mob.Patrol == nil || !mob.Patrol.onRoute(destRoom.ID)) {
 		return fmt.Errorf("room has no_wandering flag set")
 	}
 
@@ -620,6 +634,7 @@ func RemoveMobFromRoom(mob *MobInstance) {
 	if mob == nil || mob.Room == nil {
 		return
 	}
+	throwRider(mob)
 
 	mobMutex.Lock()
 	defer mobMutex.Unlock()
@@ -664,7 +679,12 @@ func IsMobInCombat(mob *MobInstance) bool {
 }
 
 // ProcessMobWandering makes certain mobs wander randomly between rooms
+// Mobs with a patrol route follow it instead.
 func ProcessMobWandering() {
+	for _, mob := range patrollingMobs() {
+		advancePatrol(mob)
+	}
+
 	// Global chance to process wandering at all (15% chance per pulse)
 	// This means wandering will only be considered in 15% of pulses
 	if rng.Intn(100) >= 15 {
@@ -676,13 +696,13 @@ func ProcessMobWandering() {
 
 	// Process each mob instance
 	for _, mob := range mobInstances {
-		// Skip if this mob type shouldn't wander
-		if !mob.Wandering {
+		// Skip if this mob type shouldn't wander, walks a patrol route, follows a player, or is being ridden
+		if !mob.Wandering || mob.Patrol != nil || mob.Master != nil || mob.Rider != nil {
 			continue
 		}
 
-		// Skip if this mob is in combat with any player
-		if IsMobInCombat(mob) {
+		// Skip if this mob is in combat with any player or another mob
+		if IsMobInCombat(mob) || mob.Fighting != nil {
 			continue
 		}
 
@@ -741,3 +761,78 @@ func FindMobByTarget(roomID int, targetName string) *MobInstance {
 
 	return mob
 }
+
+// ProcessMobScaling adjusts the level and HP of mobs in areas with level scaling
+// enabled so they match the average level of the players currently in that area.
+// Mobs return to their template level once the area is empty of players.
+func ProcessMobScaling() {
+	// Work out the average player level for each scaling area
+	levelTotals := make(map[string]int)
+	playerCounts := make(map[string]int)
+
+	playersMutex.Lock()
+	for _, p := range activePlayers {
+		if p.Room == nil || p.IsDead {
+			continue
+		}
+		if area := GetArea(p.Room.Area); area != nil && area.LevelScaling != nil {
+			levelTotals[p.Room.Area] += p.Level
+			playerCounts[p.Room.Area]++
+		}
+	}
+	playersMutex.Unlock()
+
+	mobMutex.Lock()
+	var toScale []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.Room == nil {
+			continue
+		}
+		if area := GetArea(mob.Room.Area); area != nil && area.LevelScaling != nil {
+			toScale = append(toScale, mob)
+		}
+	}
+	mobMutex.Unlock()
+
+	for _, mob := range toScale {
+		// Never rescale a mob mid-fight
+		if IsMobInCombat(mob) {
+			continue
+		}
+
+		mobMutex.Lock()
+		template := mobRegistry[mob.ID]
+		targetLevel := template.Level
+		if count := playerCounts[mob.Room.Area]; count > 0 {
+			scaling := GetArea(mob.Room.Area).LevelScaling
+			targetLevel = levelTotals[mob.Room.Area] / count
+			if scaling.MinLevel > 0 && targetLevel < scaling.MinLevel {
+				targetLevel = scaling.MinLevel
+			}
+			if scaling.MaxLevel > 0 && targetLevel > scaling.MaxLevel {
+				targetLevel = scaling.MaxLevel
+			}
+		}
+		if mob.Level != targetLevel {
+			scaleMobToLevel(mob, targetLevel)
+		}
+		mobMutex.Unlock()
+	}
+}
+
+// scaleMobToLevel sets a mob's level and recalculates its max HP, keeping the
+// same fraction of health it had before. The caller must hold mobMutex.
+func scaleMobToLevel(mob *MobInstance, level int) {
+	healthFraction := 1.0
+	if mob.MaxHP > 0 {
+		healthFraction = float64(mob.HP) / float64(mob.MaxHP)
+	}
+
+	mob.Level = level
+	calculateMobStats(mob.Mob)
+
+	mob.HP = int(float64(mob.MaxHP) * healthFraction)
+	if mob.HP < 1 {
+		mob.HP = 1
+	}
+}
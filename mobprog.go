@@ -0,0 +1,312 @@
+/*
+ * mobprog.go
+ *
+ * This file implements mob programs, which let builders script how mobs
+ * react to players without changing the server. A mob's area file may
+ * give it programs that run when a player enters its room, says something
+ * to it, gives it an item, or kills it, or that run now and then on the
+ * tick while players are near. Each program is a list of actions in a
+ * small language: the mob can say or emote something, give the player an
+ * item, cast a spell on them, transfer them to another room, or call a
+ * function in its area's script. Messages use $N for the player, as in
+ * mob emotes.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Events that run a mob program
+const (
+	ProgOnGreet  = "greet"  // A player the mob can see enters its room
+	ProgOnSpeech = "speech" // A player in the room says something with one of the keywords
+	ProgOnGive   = "give"   // A player gives the mob an item
+	ProgOnDeath  = "death"  // A player kills the mob
+	ProgOnRandom = "random" // Each tick, while players are in the room
+)
+
+// MobProg is a scripted reaction to something a player does
+type MobProg struct {
+	On       string   `yaml:"on"`                 // greet, speech, give, death, or random
+	Keywords []string `yaml:"keywords,omitempty"` // Words that set off a speech program (none = anything said)
+	Item     int      `yaml:"item,omitempty"`     // Vnum of the item that sets off a give program (0 = any item)
+	Chance   int      `yaml:"chance,omitempty"`   // Percent chance to run (0 = always)
+	Actions  []string `yaml:"actions"`            // Lines such as "say Hello, $N!" run in order
+}
+
+// progActions maps each action to a check of its argument
+var progActions = map[string]func(arg string) error{
+	"say":      requireText,
+	"emote":    requireText,
+	"give":     requireNumber,
+	"transfer": requireNumber,
+	"call": func(arg string) error {
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			return fmt.Errorf("%q isn't a script function name", arg)
+		}
+		return nil
+	},
+	"cast": func(arg string) error {
+		if skill := FindSkill(arg); skill == nil || skill.Use != nil {
+			return fmt.Errorf("%q isn't a spell that can be cast on a player", arg)
+		}
+		return nil
+	},
+}
+
+// requireText checks that an action has something to say
+func requireText(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("nothing to say")
+	}
+	return nil
+}
+
+// requireNumber checks that an action names a vnum
+func requireNumber(arg string) error {
+	if _, err := strconv.Atoi(arg); err != nil {
+		return fmt.Errorf("%q isn't a vnum", arg)
+	}
+	return nil
+}
+
+// splitAction separates an action into its verb and argument
+func splitAction(action string) (string, string) {
+	verb, arg, _ := strings.Cut(strings.TrimSpace(action), " ")
+	return strings.ToLower(verb), strings.TrimSpace(arg)
+}
+
+// validateMobProg checks that a mob program is well formed
+func validateMobProg(prog MobProg) error {
+	switch prog.On {
+	case ProgOnGreet, ProgOnSpeech, ProgOnGive, ProgOnDeath, ProgOnRandom:
+	default:
+		return fmt.Errorf("unknown event %q", prog.On)
+	}
+	if prog.Chance < 0 || prog.Chance > 100 {
+		return fmt.Errorf("chance must be between 0 and 100, got %d", prog.Chance)
+	}
+	if len(prog.Actions) == 0 {
+		return fmt.Errorf("no actions")
+	}
+	for _, action := range prog.Actions {
+		verb, arg := splitAction(action)
+		check, ok := progActions[verb]
+		if !ok {
+			return fmt.Errorf("unknown action %q", verb)
+		}
+		if err := check(arg); err != nil {
+			return fmt.Errorf("%s: %w", verb, err)
+		}
+	}
+	return nil
+}
+
+// heard reports whether a speech program's keywords are in what was said
+func (prog MobProg) heard(message string) bool {
+	if len(prog.Keywords) == 0 {
+		return true
+	}
+	// Compare whole words, ignoring case and punctuation
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(StripColorCodes(message)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}), " ") + " "
+	for _, keyword := range prog.Keywords {
+		if strings.Contains(words, " "+strings.ToLower(keyword)+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// runMobProg performs a program's actions toward the player who set it off
+func runMobProg(mob *MobInstance, player *Player, prog MobProg) {
+	if prog.Chance > 0 && rng.Intn(100) >= prog.Chance {
+		return
+	}
+	for _, action := range prog.Actions {
+		if mob.Room == nil || player.Room != mob.Room {
+			return // One of them has left, so the rest can't play out
+		}
+		verb, arg := splitAction(action)
+		switch verb {
+		case "say":
+			BroadcastToRoom(ColorizeByType(fmt.Sprintf("%s says '%s'", capitalizeFirst(mob.ShortDescription), strings.ReplaceAll(arg, "$N", player.Name)), "say"), mob.Room, nil)
+		case "emote":
+			for _, p := range playersInRoom(mob.Room) {
+				p.Send(act("$n "+arg, mob.ShortDescription, player.Name, p == player))
+			}
+		case "give":
+			progGive(mob, player, arg)
+		case "cast":
+			progCast(mob, player, arg)
+		case "transfer":
+			progTransfer(mob, player, arg)
+		case "call":
+			callScript(mob.HomeArea, arg, lua.LNumber(mob.InstanceID), lua.LString(player.Name))
+		}
+	}
+}
+
+// progGive creates an item and hands it to the player
+func progGive(mob *MobInstance, player *Player, arg string) {
+	vnum, _ := strconv.Atoi(arg)
+	item, err := CreateItem(vnum)
+	if err != nil {
+		log.Printf("[WARNING] Mob %d's program gives item %d, which doesn't exist", mob.ID, vnum)
+		return
+	}
+	BroadcastToRoom(fmt.Sprintf("%s gives %s to $n.", capitalizeFirst(mob.ShortDescription), item.ShortDescription), mob.Room, player)
+	if !player.CanLift(item) {
+		AddItemToRoom(item, player.Room)
+		player.Send(fmt.Sprintf("%s gives you %s, but you can't carry it and it falls to the ground.", capitalizeFirst(mob.ShortDescription), item.Name()))
+		return
+	}
+	player.Inventory = append(player.Inventory, item)
+	player.Send(fmt.Sprintf("%s gives you %s.", capitalizeFirst(mob.ShortDescription), item.Name()))
+	if message := item.BindTo(player, BindOnPickup); message != "" {
+		player.Send(message)
+	}
+}
+
+// progCast casts a spell on the player, as strong as the mob's level
+func progCast(mob *MobInstance, player *Player, arg string) {
+	skill := FindSkill(arg)
+	if skill == nil || skill.Use != nil {
+		return
+	}
+	BroadcastToRoom(fmt.Sprintf("%s utters the words, '%s'.", capitalizeFirst(mob.ShortDescription), skill.Name), mob.Room, nil)
+	affect := skill.Affect
+	affect.Level = mob.Level
+	affect.Magical = true
+	player.ApplyAffect(&affect)
+}
+
+// progTransfer sends the player to another room
+func progTransfer(mob *MobInstance, player *Player, arg string) {
+	vnum, _ := strconv.Atoi(arg)
+	room, err := GetRoom(vnum)
+	if err != nil {
+		log.Printf("[WARNING] Mob %d's program transfers players to room %d, which doesn't exist", mob.ID, vnum)
+		return
+	}
+	TransferPlayer(player, room)
+}
+
+// mobsWithProgs returns the mobs in a room that have programs for an event
+func mobsWithProgs(room *Room, event string) []*MobInstance {
+	var mobs []*MobInstance
+	for _, mob := range GetMobsInRoom(room.ID) {
+		for _, prog := range mob.Progs {
+			if prog.On == event {
+				mobs = append(mobs, mob)
+				break
+			}
+		}
+	}
+	return mobs
+}
+
+// onProgEnter lets the mobs in a room greet a player who walked in
+func onProgEnter(event Event) {
+	if event.Direction != "" {
+		FireGreetProgs(event.Player)
+	}
+}
+
+// FireGreetProgs lets the mobs in the room the player just entered greet them
+func FireGreetProgs(player *Player) {
+	if player.Room == nil {
+		return
+	}
+	for _, mob := range mobsWithProgs(player.Room, ProgOnGreet) {
+		if mob.Fighting != nil || IsMobInCombat(mob) || !MobCanSeePlayer(mob, player) {
+			continue
+		}
+		for _, prog := range mob.Progs {
+			if prog.On == ProgOnGreet {
+				runMobProg(mob, player, prog)
+			}
+		}
+	}
+}
+
+// FireSpeechProgs lets the mobs in the room answer something the player said
+func FireSpeechProgs(player *Player, message string) {
+	for _, mob := range mobsWithProgs(player.Room, ProgOnSpeech) {
+		if mob.Fighting != nil || IsMobInCombat(mob) {
+			continue
+		}
+		for _, prog := range mob.Progs {
+			if prog.On == ProgOnSpeech && prog.heard(message) {
+				runMobProg(mob, player, prog)
+				break // One answer is enough
+			}
+		}
+	}
+}
+
+// GiveProg returns the mob's program for being given an item, or nil if it has no use for the item
+func (m *MobInstance) GiveProg(item *Item) *MobProg {
+	for i, prog := range m.Progs {
+		if prog.On == ProgOnGive && (prog.Item == 0 || prog.Item == item.ID) {
+			return &m.Progs[i]
+		}
+	}
+	return nil
+}
+
+// FireDeathProgs runs the mob's last acts as the player kills it
+func FireDeathProgs(mob *MobInstance, player *Player) {
+	for _, prog := range mob.Progs {
+		if prog.On == ProgOnDeath {
+			runMobProg(mob, player, prog)
+		}
+	}
+}
+
+// ProcessRandomProgs gives mobs with random programs a chance to run them each tick
+func ProcessRandomProgs() {
+	mobMutex.RLock()
+	var mobs []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.Room == nil {
+			continue
+		}
+		for _, prog := range mob.Progs {
+			if prog.On == ProgOnRandom {
+				mobs = append(mobs, mob)
+				break
+			}
+		}
+	}
+	mobMutex.RUnlock()
+
+	for _, mob := range mobs {
+		if mob.Fighting != nil || IsMobInCombat(mob) {
+			continue
+		}
+		var visible []*Player
+		for _, p := range playersInRoom(mob.Room) {
+			if MobCanSeePlayer(mob, p) {
+				visible = append(visible, p)
+			}
+		}
+		if len(visible) == 0 {
+			continue
+		}
+		for _, prog := range mob.Progs {
+			if prog.On == ProgOnRandom {
+				runMobProg(mob, visible[rng.Intn(len(visible))], prog)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * mount.go
+ *
+ * This file implements riding. Mobs flagged as mounts can be ridden with
+ * 'mount' and left with 'dismount'. A mount carries its rider from room to
+ * room, so a rider pays no stamina for a heavy load and isn't slowed by it,
+ * and fighting from the saddle makes a rider more accurate and harder
+ * hitting. Some rooms, usually cramped indoor ones, have no room for a
+ * mount and must be entered on foot. A mount that belongs to a player, as
+ * a pet or charmed follower, can only be ridden by that player.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Riding bonuses
+const (
+	MountedHitroll = 5 // Added to a rider's hitroll
+	MountedDamroll = 2 // Added to a rider's damroll
+)
+
+// mountModifiers are the changes riding makes to a player's modifiers
+var mountModifiers = map[string]int{
+	ApplyHitroll: MountedHitroll,
+	ApplyDamroll: MountedDamroll,
+}
+
+// Riding returns the mob the player is riding, or nil if they're on foot
+// A player parted from their mount, such as by recall or death, is
+// dismounted.
+func (p *Player) Riding() *MobInstance {
+	mount := p.Mount
+	if mount == nil {
+		return nil
+	}
+	if mount.Rider != p || mount.Room != p.Room || mount.HP <= 0 {
+		p.Dismount()
+		return nil
+	}
+	return mount
+}
+
+// Dismount takes the player off their mount
+func (p *Player) Dismount() {
+	if p.Mount != nil && p.Mount.Rider == p {
+		p.Mount.Rider = nil
+	}
+	p.Mount = nil
+}
+
+// throwRider dismounts anyone riding a mob that is leaving the world
+func throwRider(mob *MobInstance) {
+	rider := mob.Rider
+	if rider == nil {
+		return
+	}
+	rider.Dismount()
+	rider.Send(fmt.Sprintf("You are no longer riding %s.", mob.ShortDescription))
+}
+
+// CheckMountAccess returns an error if the player would ride into a room with no space for a mount
+func CheckMountAccess(player *Player, room *Room) error {
+	if room.NoMount && player.Riding() != nil {
+		return fmt.Errorf("there's no room to ride in there; dismount first")
+	}
+	return nil
+}
+
+// relocateMob moves a mob to another room without announcing it
+func relocateMob(mob *MobInstance, room *Room) {
+	mobMutex.Lock()
+	defer mobMutex.Unlock()
+
+	oldRoom := mob.Room
+	for i, m := range roomMobs[oldRoom.ID] {
+		if m.InstanceID == mob.InstanceID {
+			roomMobs[oldRoom.ID] = append(roomMobs[oldRoom.ID][:i], roomMobs[oldRoom.ID][i+1:]...)
+			break
+		}
+	}
+	roomMobs[room.ID] = append(roomMobs[room.ID], mob)
+	mob.Room = room
+}
+
+// handleMount climbs onto a mob that can be ridden
+// Usage: mount <creature>
+func handleMount(player *Player, args []string) string {
+	if mount := player.Riding(); mount != nil {
+		return fmt.Sprintf("You are already riding %s.", mount.ShortDescription)
+	}
+	if len(args) == 0 {
+		return "Mount what?"
+	}
+	if player.Room.NoMount {
+		return "There's no room to ride in here."
+	}
+
+	mob := FindMobByTarget(player.Room.ID, strings.ToLower(strings.Join(args, " ")))
+	if mob == nil || mob.HP <= 0 {
+		return "You don't see that here."
+	}
+	switch {
+	case !mob.Mount:
+		return fmt.Sprintf("%s won't let you ride it.", capitalizeFirst(mob.ShortDescription))
+	case mob.Master != nil && mob.Master != player:
+		return fmt.Sprintf("%s belongs to someone else.", capitalizeFirst(mob.ShortDescription))
+	case mob.Rider != nil:
+		return fmt.Sprintf("%s already has a rider.", capitalizeFirst(mob.ShortDescription))
+	case mob.Fighting != nil || IsMobInCombat(mob):
+		return fmt.Sprintf("%s is too busy fighting to carry you.", capitalizeFirst(mob.ShortDescription))
+	}
+
+	mob.Rider = player
+	player.Mount = mob
+	player.CancelCamp("You stop making camp.")
+	BroadcastToRoom(fmt.Sprintf("$n climbs onto %s.", mob.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You climb onto %s.", mob.ShortDescription)
+}
+
+// handleDismount climbs down from the player's mount
+func handleDismount(player *Player, args []string) string {
+	mount := player.Riding()
+	if mount == nil {
+		return "You aren't riding anything."
+	}
+
+	player.Dismount()
+	BroadcastToRoom(fmt.Sprintf("$n climbs down from %s.", mount.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You climb down from %s.", mount.ShortDescription)
+}
@@ -5,16 +5,17 @@
  * It provides functions for handling player movement between rooms,
  * processing direction commands, and managing the transitions between
  * different areas of the game world. The file includes logic for
- * validating movement requests and updating player locations in both
- * memory and the database.
+ * validating movement requests, including exits restricted by level,
+ * class, completed quests, or carried items, or blocked by a guard, and
+ * updating player locations in both memory and the database.
  */
 
 package main
 
 import (
+	"errors"  // Importing the errors package for requirement messages
 	"fmt"     // Importing the fmt package for formatted I/O operations
 	"log"     // Importing the log package for logging
-	"strconv" // Importing the strconv package for converting strings to integers
 	"strings" // Importing the strings package for string manipulation functions
 )
 
@@ -43,54 +44,80 @@ func MovePlayer(player *Player, direction string) (*Room, error) {
 		return currentRoom, fmt.Errorf("the %s is closed", exit.Door.ShortDescription)
 	}
 
+	// Check any level, quest, or item requirements on the exit
+	if err := CheckExitRequirement(player, exit.Requires); err != nil {
+		return currentRoom, err
+	}
+
+	// Check for a mob guarding the exit
+	if err := CheckExitGuard(player, currentRoom, direction); err != nil {
+		return currentRoom, err
+	}
+
 	// Debug logging
 	// fmt.Printf("Debug - MovePlayer: Moving from Room %d to %v\n",
 	// 	currentRoom.ID, exit)
 
-	// Handle different types of room movement based on exit ID type
-	switch exitID := exit.ID.(type) {
-	case int:
-		newRoom, err := GetRoom(exitID)
-		if err != nil {
-			return currentRoom, err
-		}
-		err = UpdatePlayerRoom(player.Name, exitID)
-		if err != nil {
-			return currentRoom, err
-		}
-		// fmt.Printf("Debug - Moved to Room: ID=%d, Name=%s, Area=%s\n",
-		// 	newRoom.ID, newRoom.Name, newRoom.Area)
-		return newRoom, nil
-
-	case string:
-		// Handle cross-area movement
-		roomInfo := strings.Split(exitID, ":")
-		if len(roomInfo) != 2 {
-			return currentRoom, fmt.Errorf("invalid room reference")
-		}
+	// Exits are linked to their destination, in this area or another, at load time
+	roomID, err := ResolveExitRoomID(exit)
+	if err != nil {
+		return currentRoom, err
+	}
+	newRoom, err := GetRoom(roomID)
+	if err != nil {
+		return currentRoom, err
+	}
+	if err := CheckClanHall(player, newRoom); err != nil {
+		return currentRoom, err
+	}
+	if err := CheckHouseAccess(player, newRoom); err != nil {
+		return currentRoom, err
+	}
+	if err := CheckMountAccess(player, newRoom); err != nil {
+		return currentRoom, err
+	}
 
-		roomID, err := strconv.Atoi(roomInfo[1])
-		if err != nil {
-			return currentRoom, fmt.Errorf("invalid room ID")
-		}
+	err = UpdatePlayerRoom(player.Name, roomID)
+	if err != nil {
+		return currentRoom, err
+	}
+	// fmt.Printf("Debug - Moved to Room: ID=%d, Name=%s, Area=%s\n",
+	// 	newRoom.ID, newRoom.Name, newRoom.Area)
+	return newRoom, nil
+}
 
-		newRoom, err := GetRoom(roomID)
-		if err != nil {
-			return currentRoom, err
-		}
+// CheckExitRequirement returns an error if the player doesn't meet an exit's requirements
+// The exit's custom message is used when one is set.
+func CheckExitRequirement(player *Player, req *ExitRequirement) error {
+	if req == nil {
+		return nil
+	}
 
-		err = UpdatePlayerRoom(player.Name, roomID)
-		if err != nil {
-			return currentRoom, err
-		}
-		// fmt.Printf("Debug - Moved to Room (cross-area): ID=%d, Name=%s, Area=%s\n",
-		// 	newRoom.ID, newRoom.Name, newRoom.Area)
-		return newRoom, nil
+	var reason string
+	switch {
+	case req.MinLevel > 0 && player.Level < req.MinLevel:
+		reason = fmt.Sprintf("you must be at least level %d to go that way", req.MinLevel)
+	case req.MaxLevel > 0 && player.Level > req.MaxLevel:
+		reason = "you are too experienced to go that way"
+	case req.Class != "" && !strings.EqualFold(req.Class, player.Class):
+		reason = fmt.Sprintf("only members of the %s class may go that way", req.Class)
+	case req.Quest != "" && !player.HasCompletedQuest(req.Quest):
+		reason = "you have not yet earned the right to go that way"
+	case req.Item != 0 && !player.HasItem(req.Item):
+		reason = "you lack what you need to go that way"
+	default:
+		return nil
 	}
 
-	return currentRoom, fmt.Errorf("invalid exit type")
+	if req.Message != "" {
+		return errors.New(req.Message)
+	}
+	return errors.New(reason)
 }
 
+// Directions lists every direction, in the order exits are listed
+var Directions = []string{"north", "east", "south", "west", "up", "down"}
+
 // DirectionAliases maps shorthand commands to full direction names
 var DirectionAliases = map[string]string{
 	"n": "north",
@@ -113,8 +140,13 @@ func HandleMovement(player *Player, command string) error {
 		command = fullDirection
 	}
 
-	// Store the old room for notifications
+	// Store the old room for notifications, and the mount that carries the player
 	oldRoom := player.Room
+	mount := player.Riding()
+	leaves, arrives, moves := "leaves", "arrives", "move"
+	if mount != nil {
+		leaves, arrives, moves = "rides", fmt.Sprintf("arrives, riding %s", mount.ShortDescription), "ride"
+	}
 
 	// Attempt to move the player
 	newRoom, err := MovePlayer(player, command)
@@ -122,31 +154,44 @@ func HandleMovement(player *Player, command string) error {
 		return err
 	}
 
+	// Walking away abandons any camp the player was making
+	player.CancelCamp("You abandon your camp.")
+
 	// Notify players in the old room about departure
 	playersMutex.Lock()
 	for _, p := range activePlayers {
-		if p != player && p.Room == oldRoom {
-			p.Send(fmt.Sprintf("%s leaves %s.", player.Name, command))
+		if p != player && p.Room == oldRoom && p.CanSeePlayer(player) {
+			p.Send(fmt.Sprintf("%s %s %s.", player.Name, leaves, command))
 		}
 	}
 	playersMutex.Unlock()
 
 	// Update player's room
 	player.Room = newRoom
+	if mount != nil {
+		relocateMob(mount, newRoom)
+	}
 
 	// Send movement message and room description to moving player
-	player.Send(fmt.Sprintf("You move %s.", command))
+	player.Send(fmt.Sprintf("You %s %s.", moves, command))
 	player.Send(DescribeRoom(newRoom, player))
+	player.SendRoomInfo()
 
 	// Notify players in the new room about arrival
 	playersMutex.Lock()
 	for _, p := range activePlayers {
-		if p != player && p.Room == newRoom {
-			p.Send(fmt.Sprintf("%s arrives.", player.Name))
+		if p != player && p.Room == newRoom && p.CanSeePlayer(player) {
+			p.Send(fmt.Sprintf("%s %s.", player.Name, arrives))
 		}
 	}
 	playersMutex.Unlock()
 
+	// Followers come along
+	player.MoveFollowers(oldRoom, command)
+
+	// Let exploration, ambushes, mob programs, and scripts know the player has arrived
+	Publish(Event{Type: EventRoomEntered, Player: player, Room: newRoom, Direction: command})
+
 	return nil
 }
 
@@ -171,22 +216,8 @@ func SynchronizeDoor(sourceRoomID int, direction string, closed bool) {
 	}
 
 	// Get the destination room
-	var destRoomID int
-	switch exitID := exit.ID.(type) {
-	case int:
-		destRoomID = exitID
-	case string:
-		// Handle cross-area references
-		roomInfo := strings.Split(exitID, ":")
-		if len(roomInfo) != 2 {
-			return
-		}
-		var err error
-		destRoomID, err = strconv.Atoi(roomInfo[1])
-		if err != nil {
-			return
-		}
-	default:
+	destRoomID, err := ResolveExitRoomID(exit)
+	if err != nil {
 		return
 	}
 
@@ -221,3 +252,24 @@ func SynchronizeDoor(sourceRoomID int, direction string, closed bool) {
 
 // getOppositeDirection returns the opposite of a given direction
 // ... existing code ...
+
+// TransferPlayer whisks a player away to another room, ending any fight they're in
+// Entering the room this way doesn't set off its triggers.
+func TransferPlayer(player *Player, room *Room) {
+	player.ExitCombat()
+	BroadcastToRoom("$n vanishes!", player.Room, player)
+	player.Room = room
+	if mount := player.Riding(); mount != nil {
+		relocateMob(mount, room)
+	}
+	if err := UpdatePlayerRoom(player.Name, room.ID); err != nil {
+		log.Printf("Error saving %s's room after a transfer: %v", player.Name, err)
+	}
+	BroadcastToRoom("$n appears out of nowhere.", room, player)
+
+	player.Send("You are whisked away!")
+	player.Send(DescribeRoom(room, player))
+	player.SendRoomInfo()
+
+	Publish(Event{Type: EventRoomEntered, Player: player, Room: room})
+}
@@ -0,0 +1,239 @@
+/*
+ * notes.go
+ *
+ * This file implements player notes: short annotations one player keeps
+ * about another ("helped me in mud school", "owes me 50 gold"). Ordinary
+ * notes are private to their author. Staff members may also write account
+ * notes that every other staff member can read. Notes are stored in the
+ * database and shown when the noted player logs in or is looked up with
+ * the whois command.
+ */
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxNoteLength is the longest note a player may write
+const MaxNoteLength = 200
+
+// PlayerNote is a note one player has written about another
+type PlayerNote struct {
+	ID        int
+	Author    string
+	Subject   string
+	Note      string
+	Staff     bool // Staff account notes are visible to all staff
+	CreatedAt time.Time
+}
+
+// resolvePlayerName finds the stored name for a player, whether or not they are online
+func resolvePlayerName(name string) (string, bool) {
+	if p := FindPlayerByName(name); p != nil {
+		return p.Name, true
+	}
+	if PlayerExists(name) {
+		return name, true
+	}
+	if capitalized := capitalizeFirst(strings.ToLower(name)); PlayerExists(capitalized) {
+		return capitalized, true
+	}
+	return "", false
+}
+
+// formatNotes renders a list of notes, one per line
+func formatNotes(notes []PlayerNote) string {
+	var sb strings.Builder
+	for _, n := range notes {
+		if n.Staff {
+			sb.WriteString(fmt.Sprintf("  {R}#%d{x} [%s] {Y}(staff, %s){x} %s\r\n",
+				n.ID, n.CreatedAt.Format("2006-01-02"), n.Author, n.Note))
+		} else {
+			sb.WriteString(fmt.Sprintf("  {C}#%d{x} [%s] %s\r\n",
+				n.ID, n.CreatedAt.Format("2006-01-02"), n.Note))
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// handlePnote adds, lists, and deletes notes about other players
+func handlePnote(player *Player, args []string) string {
+	usage := "Usage: pnote <player> [text] | pnote staff <player> <text> | pnote delete <id>"
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "delete":
+		if len(args) < 2 {
+			return "Delete which note?"
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil {
+			return "Note IDs are numbers. Use 'pnote <player>' to see them."
+		}
+		deleted, err := DeletePlayerNote(player.Name, id, player.Staff)
+		if err != nil {
+			log.Printf("Error deleting note %d for %s: %v", id, player.Name, err)
+			return "Error deleting note."
+		}
+		if !deleted {
+			return "You have no such note."
+		}
+		return fmt.Sprintf("Note #%d deleted.", id)
+
+	case "staff":
+		if !player.Staff {
+			return "Only staff can write account notes."
+		}
+		if len(args) < 3 {
+			return "Usage: pnote staff <player> <text>"
+		}
+		return addNote(player, args[1], strings.Join(args[2:], " "), true)
+	}
+
+	// With no text, list the notes on that player
+	if len(args) == 1 {
+		subject, ok := resolvePlayerName(args[0])
+		if !ok {
+			return fmt.Sprintf("There is no player named %s.", args[0])
+		}
+		notes, err := LoadPlayerNotes(player.Name, subject, player.Staff)
+		if err != nil {
+			log.Printf("Error loading notes on %s for %s: %v", subject, player.Name, err)
+			return "Error loading notes."
+		}
+		if len(notes) == 0 {
+			return fmt.Sprintf("You have no notes on %s.", subject)
+		}
+		return fmt.Sprintf("{Y}Notes on %s:{x}\r\n%s", subject, formatNotes(notes))
+	}
+
+	return addNote(player, args[0], strings.Join(args[1:], " "), false)
+}
+
+// addNote validates and stores a new note
+func addNote(player *Player, target string, text string, staff bool) string {
+	subject, ok := resolvePlayerName(target)
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", target)
+	}
+	if subject == player.Name && !staff {
+		return "You already know what you think of yourself."
+	}
+	text = SanitizeText(text)
+	if len(text) > MaxNoteLength {
+		return fmt.Sprintf("Notes are limited to %d characters.", MaxNoteLength)
+	}
+
+	id, err := AddPlayerNote(player.Name, subject, text, staff)
+	if err != nil {
+		log.Printf("Error saving note on %s for %s: %v", subject, player.Name, err)
+		return "Error saving note."
+	}
+
+	if staff {
+		return fmt.Sprintf("Staff note #%d added to %s.", id, subject)
+	}
+	return fmt.Sprintf("Note #%d added to %s.", id, subject)
+}
+
+// handleWhois shows information about a player along with any notes on them
+func handleWhois(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Whois whom?"
+	}
+
+	name, ok := resolvePlayerName(args[0])
+	if !ok {
+		return fmt.Sprintf("There is no player named %s.", args[0])
+	}
+
+	var race, class, title, played, lastSeen string
+	var level int
+	status := "{R}offline{x}"
+	if target := FindPlayerByName(name); target != nil {
+		race, class, title, level = target.Race, target.Class, target.Title, target.Level
+		status = "{G}online{x}"
+		played = fmt.Sprintf("%s, on for %s", formatDuration(time.Duration(target.Playtime)*time.Minute), formatDuration(target.SessionTime()))
+	} else {
+		var err error
+		race, class, title, _, _, _, _, _, _, _, level, _, _, _, _, _, _, _, _, _, _, err = LoadPlayer(name)
+		if err != nil {
+			log.Printf("Error loading %s for whois: %v", name, err)
+			return "Error looking up that player."
+		}
+		if minutes, err := LoadPlayerPlaytime(name); err != nil {
+			log.Printf("Error loading playtime for %s: %v", name, err)
+		} else {
+			played = formatDuration(time.Duration(minutes) * time.Minute)
+		}
+		if seen, err := LoadPlayerLastSeen(name); err != nil {
+			log.Printf("Error loading last seen time for %s: %v", name, err)
+		} else {
+			lastSeen = formatDuration(time.Since(seen)) + " ago"
+		}
+	}
+
+	output := fmt.Sprintf("{W}%s{x} %s\r\n", name, title)
+	output += fmt.Sprintf("Level {M}%d{x} {G}%s{x} {B}%s{x} (%s)", level, race, class, status)
+	if played != "" {
+		output += "\r\nPlayed: " + played
+	}
+	if lastSeen != "" {
+		output += "\r\nLast seen: " + lastSeen
+	}
+
+	// Show the badges the player has earned
+	if earned, err := LoadPlayerAchievements(name); err != nil {
+		log.Printf("Error loading achievements for %s: %v", name, err)
+	} else if len(earned) > 0 {
+		output += "\r\nBadges: " + formatBadges(earned)
+	}
+
+	// Show the player's clan
+	if clan, rank, err := LoadClanMember(name); err == nil {
+		output += fmt.Sprintf("\r\nClan: %s (%s)", clan, rankName(rank))
+	} else if err != sql.ErrNoRows {
+		log.Printf("Error loading clan for %s: %v", name, err)
+	}
+
+	notes, err := LoadPlayerNotes(player.Name, name, player.Staff)
+	if err != nil {
+		log.Printf("Error loading notes on %s for %s: %v", name, player.Name, err)
+	} else if len(notes) > 0 {
+		output += "\r\n{Y}Your notes:{x}\r\n" + formatNotes(notes)
+	}
+
+	return output
+}
+
+// NotifyNoteHolders reminds online players of their notes when the noted player logs in
+func NotifyNoteHolders(subject *Player) {
+	// Copy the player list so the database isn't queried while holding the lock
+	playersMutex.Lock()
+	var online []*Player
+	for _, p := range activePlayers {
+		if p != subject {
+			online = append(online, p)
+		}
+	}
+	playersMutex.Unlock()
+
+	for _, p := range online {
+		notes, err := LoadPlayerNotes(p.Name, subject.Name, p.Staff)
+		if err != nil {
+			log.Printf("Error loading notes on %s for %s: %v", subject.Name, p.Name, err)
+			continue
+		}
+		if len(notes) > 0 {
+			p.Send(fmt.Sprintf("{Y}%s has logged in. Your notes:{x}\r\n%s", subject.Name, formatNotes(notes)))
+		}
+	}
+}
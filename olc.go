@@ -0,0 +1,582 @@
+/*
+ * olc.go
+ *
+ * This file implements online creation of mobs and their resets, so staff
+ * can build without editing area files by hand and rebooting. 'medit'
+ * creates mob templates and changes their fields, 'reset' places mobs in
+ * rooms to be spawned on each reset, and 'asave' writes an area's mobs and
+ * resets back to its YAML file. Only those sections of the file are
+ * rewritten, so its rooms, objects, and comments are left as the builder
+ * wrote them, and runtime state such as an unlocked door is never saved.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AreaDir holds the area files
+const AreaDir = "areas"
+
+var (
+	changedAreas = make(map[string]bool) // Areas edited since they were last saved, keyed by file name
+	olcMutex     sync.Mutex              // Keeps builders' edits and saves from overlapping
+)
+
+// areaSections returns the sections of an area that can be edited online, in the order they're written
+func areaSections(area *Area) []struct {
+	key   string
+	value interface{}
+} {
+	return []struct {
+		key   string
+		value interface{}
+	}{
+		{"mobiles", area.Mobiles},
+		{"mob_resets", area.MobResets},
+	}
+}
+
+// medit fields and how each is set from what the builder typed
+var meditFields = map[string]func(mob *Mob, value string) error{
+	"keywords": func(mob *Mob, value string) error {
+		mob.Keywords = strings.Fields(strings.ToLower(value))
+		return requireValue(value)
+	},
+	"short": func(mob *Mob, value string) error {
+		mob.ShortDescription = value
+		return requireValue(value)
+	},
+	"long": func(mob *Mob, value string) error {
+		mob.LongDescription = value
+		return requireValue(value)
+	},
+	"desc": func(mob *Mob, value string) error {
+		mob.Description = value
+		return requireValue(value)
+	},
+	"race": func(mob *Mob, value string) error {
+		mob.Race = strings.ToLower(value)
+		return requireValue(value)
+	},
+	"level": func(mob *Mob, value string) error {
+		return setNumber(&mob.Level, value, 1)
+	},
+	"gold": func(mob *Mob, value string) error {
+		return setNumber(&mob.Gold, value, 0)
+	},
+	"wimpy": func(mob *Mob, value string) error {
+		return setNumber(&mob.Wimpy, value, 0)
+	},
+	"toughness": func(mob *Mob, value string) error {
+		value = strings.ToLower(value)
+		if _, ok := toughnessMultipliers[value]; !ok {
+			return fmt.Errorf("toughness must be easy, medium, hard, savage, boss, or god")
+		}
+		mob.Toughness = value
+		return nil
+	},
+	"faction": func(mob *Mob, value string) error {
+		mob.Faction = strings.ToLower(value)
+		return nil
+	},
+	"enemies": func(mob *Mob, value string) error {
+		mob.Enemies = strings.Fields(strings.ToLower(value))
+		return nil
+	},
+	"wandering": func(mob *Mob, value string) error { return setFlag(&mob.Wandering, value) },
+	"evil":      func(mob *Mob, value string) error { return setFlag(&mob.Evil, value) },
+	"nocturnal": func(mob *Mob, value string) error { return setFlag(&mob.Nocturnal, value) },
+	"fearless":  func(mob *Mob, value string) error { return setFlag(&mob.Fearless, value) },
+	"pursues":   func(mob *Mob, value string) error { return setFlag(&mob.Pursues, value) },
+	"mount":     func(mob *Mob, value string) error { return setFlag(&mob.Mount, value) },
+	"banker":    func(mob *Mob, value string) error { return setFlag(&mob.Banker, value) },
+	"scavenger": func(mob *Mob, value string) error { return setFlag(&mob.Scavenger, value) },
+	"dummy":     func(mob *Mob, value string) error { return setFlag(&mob.Dummy, value) },
+}
+
+// requireValue checks that a field was given something
+func requireValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("it can't be blank")
+	}
+	return nil
+}
+
+// setNumber parses a whole number no lower than min
+func setNumber(field *int, value string, min int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return fmt.Errorf("it must be a number of at least %d", min)
+	}
+	*field = n
+	return nil
+}
+
+// setFlag parses on or off
+func setFlag(field *bool, value string) error {
+	switch strings.ToLower(value) {
+	case "on", "yes", "true":
+		*field = true
+	case "off", "no", "false":
+		*field = false
+	default:
+		return fmt.Errorf("it must be on or off")
+	}
+	return nil
+}
+
+// mobArea returns the file name of the area a mob template belongs to, or ""
+func mobArea(vnum int) string {
+	for name, area := range allAreas() {
+		if _, ok := area.Mobiles[vnum]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// describeMobTemplate lists a mob template's fields for a builder
+func describeMobTemplate(mob *Mob) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("{W}Mob %d{x} in %s\r\n", mob.ID, mobArea(mob.ID)))
+	sb.WriteString(fmt.Sprintf("Keywords:  %s\r\n", strings.Join(mob.Keywords, " ")))
+	sb.WriteString(fmt.Sprintf("Short:     %s\r\n", mob.ShortDescription))
+	sb.WriteString(fmt.Sprintf("Long:      %s\r\n", mob.LongDescription))
+	sb.WriteString(fmt.Sprintf("Desc:      %s\r\n", mob.Description))
+	sb.WriteString(fmt.Sprintf("Race:      %-12s Level: %-4d Toughness: %s (%d HP)\r\n", mob.Race, mob.Level, mob.Toughness, mob.MaxHP))
+	sb.WriteString(fmt.Sprintf("Gold:      %-12d Wimpy: %d%%\r\n", mob.Gold, mob.Wimpy))
+	if mob.Faction != "" || len(mob.Enemies) > 0 {
+		sb.WriteString(fmt.Sprintf("Faction:   %-12s Enemies: %s\r\n", mob.Faction, strings.Join(mob.Enemies, " ")))
+	}
+
+	var flags []string
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"wandering", mob.Wandering}, {"evil", mob.Evil}, {"nocturnal", mob.Nocturnal}, {"fearless", mob.Fearless},
+		{"pursues", mob.Pursues}, {"mount", mob.Mount}, {"banker", mob.Banker},
+		{"scavenger", mob.Scavenger}, {"dummy", mob.Dummy},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+	if len(flags) == 0 {
+		flags = []string{"none"}
+	}
+	sb.WriteString("Flags:     " + strings.Join(flags, " "))
+	if len(mob.Progs) > 0 {
+		sb.WriteString(fmt.Sprintf("\r\nPrograms:  %d (edit them in the area file)", len(mob.Progs)))
+	}
+	return sb.String()
+}
+
+// handleMedit shows, creates, and edits mob templates (staff only)
+// Usage: medit <vnum>, medit create <vnum>, medit <vnum> <field> <value>
+func handleMedit(player *Player, args []string) string {
+	olcMutex.Lock()
+	defer olcMutex.Unlock()
+	usage := "Usage: medit <vnum>, medit create <vnum>, medit <vnum> <field> <value>"
+	if len(args) == 0 {
+		return usage
+	}
+
+	if strings.EqualFold(args[0], "create") {
+		if len(args) < 2 {
+			return usage
+		}
+		return meditCreate(player, args[1])
+	}
+
+	vnum, err := strconv.Atoi(args[0])
+	if err != nil {
+		return usage
+	}
+	mobMutex.Lock()
+	defer mobMutex.Unlock()
+	mob := mobRegistry[vnum]
+	if mob == nil {
+		return fmt.Sprintf("There is no mob %d. Use 'medit create %d' to make one.", vnum, vnum)
+	}
+	if len(args) == 1 {
+		return describeMobTemplate(mob)
+	}
+
+	field := strings.ToLower(args[1])
+	set, ok := meditFields[field]
+	if !ok {
+		var names []string
+		for name := range meditFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "Fields you can set: " + strings.Join(names, ", ")
+	}
+
+	// Work on a copy so a bad value leaves the mob as it was
+	edited := *mob
+	if err := set(&edited, SanitizeText(strings.Join(args[2:], " "))); err != nil {
+		return fmt.Sprintf("Can't set %s: %v.", field, err)
+	}
+	*mob = edited
+	calculateMobStats(mob)
+
+	areaName := mobArea(vnum)
+	changedAreas[areaName] = true
+	log.Printf("%s set %s on mob %d", player.Name, field, vnum)
+	return fmt.Sprintf("Mob %d's %s set. Mobs already in the world keep the old one until they reset. Use 'asave' to save %s.", vnum, field, areaName)
+}
+
+// meditCreate makes a new mob template in the area of the room the builder is standing in
+func meditCreate(player *Player, arg string) string {
+	vnum, err := strconv.Atoi(arg)
+	if err != nil || vnum < 1 {
+		return "Mob vnums are positive numbers."
+	}
+	if mobRegistry[vnum] != nil {
+		return fmt.Sprintf("Mob %d already exists.", vnum)
+	}
+	area := GetArea(player.Room.Area)
+	if area == nil {
+		return "This room doesn't belong to an area."
+	}
+
+	mob := &Mob{
+		ID:               vnum,
+		Keywords:         []string{"mob"},
+		ShortDescription: "a new mob",
+		LongDescription:  "A new mob is here, waiting to be described.",
+		Description:      "It hasn't been described yet.",
+		Race:             "human",
+		Level:            1,
+		Toughness:        "medium",
+	}
+	if area.Mobiles == nil {
+		area.Mobiles = make(map[int]*Mob)
+	}
+	area.Mobiles[vnum] = mob
+	RegisterMob(mob)
+
+	changedAreas[player.Room.Area] = true
+	log.Printf("%s created mob %d in %s", player.Name, vnum, player.Room.Area)
+	return fmt.Sprintf("Mob %d created in %s. Set its fields with 'medit %d <field> <value>'.", vnum, player.Room.Area, vnum)
+}
+
+// rebuildMobResets gathers every area's resets into the list the reset cycle uses
+func rebuildMobResets() {
+	var names []string
+	for name := range allAreas() {
+		names = append(names, name)
+	}
+	sort.Strings(names) // The order the areas were loaded in
+
+	var resets []MobReset
+	for _, name := range names {
+		resets = append(resets, allAreas()[name].MobResets...)
+	}
+
+	mobMutex.Lock()
+	mobResets = resets
+	mobMutex.Unlock()
+}
+
+// roomResets returns the indexes into the area's resets of those for the given room
+func roomResets(area *Area, roomID int) []int {
+	var indexes []int
+	for i, reset := range area.MobResets {
+		if reset.RoomVnum == roomID {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// handleReset lists, adds, and removes the mob resets of the room the builder is in (staff only)
+// Usage: reset, reset add <mob vnum> [limit] [max world], reset remove <number>
+func handleReset(player *Player, args []string) string {
+	olcMutex.Lock()
+	defer olcMutex.Unlock()
+	area := GetArea(player.Room.Area)
+	if area == nil {
+		return "This room doesn't belong to an area."
+	}
+	usage := "Usage: reset, reset add <mob vnum> [limit] [max world], reset remove <number>"
+
+	if len(args) == 0 || strings.EqualFold(args[0], "list") {
+		indexes := roomResets(area, player.Room.ID)
+		if len(indexes) == 0 {
+			return "No mobs reset in this room."
+		}
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("{W}Mob resets in room %d{x}\r\n", player.Room.ID))
+		for n, i := range indexes {
+			reset := area.MobResets[i]
+			name := "{R}(no such mob){x}"
+			if mob := mobRegistry[reset.MobVnum]; mob != nil {
+				name = mob.ShortDescription
+			}
+			sb.WriteString(fmt.Sprintf("%2d) Mob %d, %s: %d in the room, %d in the world\r\n", n+1, reset.MobVnum, name, reset.Limit, reset.MaxWorld))
+		}
+		return strings.TrimRight(sb.String(), "\r\n")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		if len(args) < 2 {
+			return usage
+		}
+		reset := MobReset{RoomVnum: player.Room.ID, Limit: 1, MaxWorld: 1}
+		numbers := []*int{&reset.MobVnum, &reset.Limit, &reset.MaxWorld}
+		for i, arg := range args[1:] {
+			if i >= len(numbers) {
+				return usage
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return usage
+			}
+			*numbers[i] = n
+		}
+		mob := mobRegistry[reset.MobVnum]
+		if mob == nil {
+			return fmt.Sprintf("There is no mob %d.", reset.MobVnum)
+		}
+		if reset.MaxWorld < reset.Limit {
+			reset.MaxWorld = reset.Limit
+		}
+		reset.Comment = mob.ShortDescription
+		area.MobResets = append(area.MobResets, reset)
+		rebuildMobResets()
+
+		changedAreas[player.Room.Area] = true
+		log.Printf("%s added a reset of mob %d to room %d", player.Name, reset.MobVnum, player.Room.ID)
+		return fmt.Sprintf("%s will reset here, up to %d in the room and %d in the world. Use 'asave' to save %s.",
+			capitalizeFirst(mob.ShortDescription), reset.Limit, reset.MaxWorld, player.Room.Area)
+
+	case "remove":
+		indexes := roomResets(area, player.Room.ID)
+		if len(args) < 2 {
+			return usage
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || n > len(indexes) {
+			return "There's no reset with that number here. Type 'reset' to list them."
+		}
+		i := indexes[n-1]
+		removed := area.MobResets[i]
+		area.MobResets = append(area.MobResets[:i:i], area.MobResets[i+1:]...)
+		rebuildMobResets()
+
+		changedAreas[player.Room.Area] = true
+		log.Printf("%s removed a reset of mob %d from room %d", player.Name, removed.MobVnum, player.Room.ID)
+		return fmt.Sprintf("Reset of mob %d removed. Mobs already here stay until they die. Use 'asave' to save %s.", removed.MobVnum, player.Room.Area)
+	}
+	return usage
+}
+
+// SaveArea writes an area's editable sections back to its file
+// The caller must hold olcMutex.
+func SaveArea(areaName string) error {
+	area := GetArea(areaName)
+	if area == nil {
+		return fmt.Errorf("no area named %s", areaName)
+	}
+	path := filepath.Join(AreaDir, areaName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	// Templates are read while they're written out, so hold off anyone changing them
+	mobMutex.RLock()
+	defer mobMutex.RUnlock()
+	for _, section := range areaSections(area) {
+		if reflect.ValueOf(section.value).Len() == 0 && !hasSection(lines, section.key) {
+			continue // Nothing to add
+		}
+		text, err := encodeSection(section.key, section.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.key, err)
+		}
+		lines = replaceSection(lines, section.key, text)
+	}
+
+	// Write to a temporary file first so a failure can't leave the area half written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	delete(changedAreas, areaName)
+	return nil
+}
+
+// encodeSection writes one top-level section of an area file in the style the files are written in
+func encodeSection(key string, value interface{}) ([]string, error) {
+	var node yaml.Node
+	if err := node.Encode(map[string]interface{}{key: value}); err != nil {
+		return nil, err
+	}
+	styleAreaNode(&node, "")
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = quotedKey.ReplaceAllString(line, "$1$2:")
+	}
+	return lines, nil
+}
+
+// styleAreaNode writes values the way the area files are written by hand: strings quoted,
+// descriptions as blocks, and lists of single words or numbers on one line
+func styleAreaNode(node *yaml.Node, key string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag != "!!str" {
+			return
+		}
+		if key == "on" {
+			return // Mob program events are written bare, like keys
+		}
+		if node.Value != "" && (strings.Contains(node.Value, "\n") || key == "description" || key == "long_description") {
+			// Trailing spaces would force the text into a quoted string
+			lines := strings.Split(strings.TrimRight(node.Value, "\n"), "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimRight(line, " \t")
+			}
+			node.Value = strings.Join(lines, "\n") + "\n"
+			node.Style = yaml.LiteralStyle
+		} else {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	case yaml.SequenceNode:
+		flow := len(node.Content) > 0
+		for _, child := range node.Content {
+			if child.Kind != yaml.ScalarNode || strings.Contains(child.Value, " ") {
+				flow = false
+			}
+		}
+		if flow {
+			node.Style = yaml.FlowStyle
+		}
+	}
+
+	for i, child := range node.Content {
+		switch {
+		case node.Kind != yaml.MappingNode:
+			styleAreaNode(child, "")
+		case i%2 == 1:
+			styleAreaNode(child, node.Content[i-1].Value)
+		}
+	}
+}
+
+// quotedKey matches a map key the encoder quoted, such as "on", which the files write bare
+var quotedKey = regexp.MustCompile(`^(\s*(?:- )?)"(\w+)":`)
+
+// hasSection reports whether an area file has a top-level section
+func hasSection(lines []string, key string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, key+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceSection swaps a top-level section of an area file for new text, or adds it at the end
+// Comments and blank lines before the next section are kept with it.
+func replaceSection(lines []string, key string, text []string) []string {
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, key+":") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		return append(append(lines, text...), "")
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		if line != "" && line[0] != ' ' && line[0] != '#' && line[0] != '-' {
+			end = i
+			break
+		}
+	}
+	for end > start+1 && (strings.TrimSpace(lines[end-1]) == "" || strings.HasPrefix(lines[end-1], "#")) {
+		end--
+	}
+	replaced := append([]string{}, lines[:start]...)
+	replaced = append(replaced, text...)
+	return append(replaced, lines[end:]...)
+}
+
+// handleAsave writes areas edited online back to their files (staff only)
+// Usage: asave, asave <area>, asave changed
+func handleAsave(player *Player, args []string) string {
+	olcMutex.Lock()
+	defer olcMutex.Unlock()
+
+	var names []string
+	switch {
+	case len(args) == 0:
+		names = []string{player.Room.Area}
+	case strings.EqualFold(args[0], "changed"):
+		for name := range changedAreas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return "No areas have unsaved changes."
+		}
+	default:
+		name := findAreaFile(args[0])
+		if name == "" {
+			return fmt.Sprintf("There is no area called %s.", args[0])
+		}
+		names = []string{name}
+	}
+
+	var lines []string
+	for _, name := range names {
+		if err := SaveArea(name); err != nil {
+			log.Printf("Error saving area %s: %v", name, err)
+			lines = append(lines, fmt.Sprintf("{R}%s couldn't be saved:{x} %v", name, err))
+			continue
+		}
+		log.Printf("%s saved area %s", player.Name, name)
+		lines = append(lines, fmt.Sprintf("{G}%s saved.{x}", name))
+	}
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,156 @@
+/*
+ * pager.go
+ *
+ * This file implements paging of long output, so things like 'help index'
+ * don't scroll off a small terminal. When the response to a command runs
+ * longer than a page, the player is shown one page at a time, with a MORE
+ * prompt in between: Enter shows the next page, and 'q' stops. Typing any
+ * other command stops the pager and runs it.
+ *
+ * A page is as tall as the player's window, for clients that report their
+ * window size, less a line for the MORE prompt. 'pagelength' sets a fixed
+ * number of lines instead, or turns paging off, and is saved with the
+ * character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Settings for the page length besides a number of lines
+const (
+	PageLengthAuto = 0  // Fit pages to the window, if the client reports its size
+	PageLengthOff  = -1 // Never page output
+)
+
+// Bounds on a page length the player sets themselves
+const (
+	MinPageLength = 10
+	MaxPageLength = 200
+)
+
+// pageHeight returns how many lines of output fit on a page for the player, or 0 for no paging
+func (p *Player) pageHeight() int {
+	switch {
+	case p.PageLength == PageLengthOff:
+		return 0
+	case p.PageLength > 0:
+		return p.PageLength
+	}
+	tc, ok := p.Conn.(*TelnetConn)
+	if !ok {
+		return 0
+	}
+	// Leave a line for the MORE prompt, but don't page a tiny window a line or two at a time
+	if _, height := tc.WindowSize(); height > 0 {
+		return max(height-1, MinPageLength)
+	}
+	return 0
+}
+
+// Paging reports whether the player has output waiting to be paged through
+func (p *Player) Paging() bool {
+	return len(p.pageLines) > 0
+}
+
+// Page sends a command's response to the player, a page at a time if it's too long for their window
+func (p *Player) Page(message string) {
+	// Wrap first, so each page counts the lines the player will actually see
+	height := p.pageHeight()
+	lines := strings.SplitAfter(strings.TrimSuffix(p.wrap(message), "\r\n"), "\n")
+	if height == 0 || len(lines) <= height {
+		p.Send(message)
+		return
+	}
+	p.pageLines = lines
+	p.nextPage(height)
+}
+
+// nextPage sends the next page of waiting output, and a MORE prompt if there's still more to come
+func (p *Player) nextPage(height int) {
+	n := min(height, len(p.pageLines))
+	p.Send(strings.Join(p.pageLines[:n], ""))
+	p.pageLines = p.pageLines[n:]
+	if len(p.pageLines) == 0 {
+		p.pageLines = nil
+		return
+	}
+	more := fmt.Sprintf("{W}[MORE: Enter to continue, q to stop (%d lines left)]{x} ", len(p.pageLines))
+	p.Conn.Write([]byte(ProcessColors(more, p.ColorEnabled)))
+}
+
+// ContinuePaging handles input typed at a MORE prompt, reporting whether it was used up
+// Enter shows the next page and 'q' stops; anything else stops the pager and is left to run as a command.
+func (p *Player) ContinuePaging(input string) bool {
+	switch {
+	case input == "":
+		// Paging may have been turned off since the last page
+		p.nextPage(max(p.pageHeight(), MinPageLength))
+	case strings.EqualFold(input, "q"):
+		p.pageLines = nil
+	default:
+		p.pageLines = nil
+		return false
+	}
+	if !p.Paging() {
+		displayPrompt(p)
+	}
+	return true
+}
+
+// LoadPageLength restores the player's page length
+func (p *Player) LoadPageLength() {
+	length, err := LoadPlayerPageLength(p.Name)
+	if err != nil {
+		log.Printf("Error loading page length for %s: %v", p.Name, err)
+		return
+	}
+	p.PageLength = length
+}
+
+// describePageLength says how the player's output is paged
+func (p *Player) describePageLength() string {
+	switch {
+	case p.PageLength == PageLengthOff:
+		return "Long output isn't paged."
+	case p.PageLength > 0:
+		return fmt.Sprintf("Long output is paged every %d lines.", p.PageLength)
+	}
+	if height := p.pageHeight(); height > 0 {
+		return fmt.Sprintf("Long output is paged to fit your window, every %d lines.", height)
+	}
+	return "Long output will be paged to fit your window, but your client hasn't said how tall it is."
+}
+
+// handlePageLength shows or sets how long output is paged
+// Usage: pagelength [<lines>|auto|off]
+func handlePageLength(player *Player, args []string) string {
+	if len(args) == 0 {
+		return player.describePageLength()
+	}
+
+	var length int
+	switch strings.ToLower(args[0]) {
+	case "auto":
+		length = PageLengthAuto
+	case "off":
+		length = PageLengthOff
+	default:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < MinPageLength || n > MaxPageLength {
+			return fmt.Sprintf("Usage: pagelength <%d-%d>|auto|off", MinPageLength, MaxPageLength)
+		}
+		length = n
+	}
+
+	player.PageLength = length
+	if err := UpdatePlayerPageLength(player.Name, length); err != nil {
+		log.Printf("Error saving page length for %s: %v", player.Name, err)
+	}
+	return player.describePageLength()
+}
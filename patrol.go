@@ -0,0 +1,224 @@
+/*
+ * patrol.go
+ *
+ * This file implements patrol routes. Instead of wandering at random, a mob
+ * with a patrol walks a fixed, ordered list of rooms defined by the builder.
+ * At the end of the route it turns around and walks back, or returns to the
+ * first room if the route is a loop. The mob stops for a while at each
+ * waypoint along the way. A mob knocked off its route (by fleeing or chasing
+ * a player) finds its way back to where it left off.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+// Patrol tuning
+const (
+	PatrolStepPulses   = 3  // Pulses a patrolling mob spends in each room it passes through
+	DefaultPatrolPause = 10 // Pulses a patrolling mob waits at a waypoint if the area doesn't say
+	PatrolSearchDepth  = 50 // Most rooms searched when finding the way back to a route
+)
+
+// Patrol is a fixed route a mob walks instead of wandering
+type Patrol struct {
+	Route     []int `yaml:"route"`               // Rooms to walk through, in order; each must connect to the next
+	Waypoints []int `yaml:"waypoints,omitempty"` // Rooms to pause in (default: the ends of the route)
+	Pause     int   `yaml:"pause,omitempty"`     // Pulses to wait at each waypoint (0 = default)
+	Loop      bool  `yaml:"loop,omitempty"`      // Return to the first room after the last instead of walking back
+}
+
+// validatePatrol checks that a patrol route can be walked within its area
+func validatePatrol(patrol *Patrol, area *Area) error {
+	if len(patrol.Route) < 2 {
+		return fmt.Errorf("route needs at least two rooms")
+	}
+	if patrol.Pause < 0 {
+		return fmt.Errorf("pause must not be negative")
+	}
+
+	for i, roomID := range patrol.Route {
+		room, ok := area.Rooms[roomID]
+		if !ok {
+			return fmt.Errorf("room %d is not in this area", roomID)
+		}
+
+		next := i + 1
+		if next == len(patrol.Route) {
+			if !patrol.Loop {
+				break
+			}
+			next = 0
+		}
+		if !connects(room, patrol.Route[next]) {
+			return fmt.Errorf("room %d has no exit to room %d", roomID, patrol.Route[next])
+		}
+	}
+
+	for _, roomID := range patrol.Waypoints {
+		if !patrol.onRoute(roomID) {
+			return fmt.Errorf("waypoint %d is not on the route", roomID)
+		}
+	}
+	return nil
+}
+
+// connects reports whether a room has an exit leading to the given room
+func connects(room *Room, toID int) bool {
+	for _, exit := range room.Exits {
+		if id, err := ResolveExitRoomID(exit); err == nil && id == toID {
+			return true
+		}
+	}
+	return false
+}
+
+// onRoute reports whether a room is part of the patrol route
+func (p *Patrol) onRoute(roomID int) bool {
+	for _, id := range p.Route {
+		if id == roomID {
+			return true
+		}
+	}
+	return false
+}
+
+// isWaypoint reports whether the mob should pause in the room at the given route index
+func (p *Patrol) isWaypoint(index int) bool {
+	if len(p.Waypoints) == 0 {
+		// Pause at the ends of the route, or at the start of a loop
+		return index == 0 || (!p.Loop && index == len(p.Route)-1)
+	}
+	for _, id := range p.Waypoints {
+		if id == p.Route[index] {
+			return true
+		}
+	}
+	return false
+}
+
+// pause returns how many pulses the mob waits at a waypoint
+func (p *Patrol) pause() int {
+	if p.Pause > 0 {
+		return p.Pause
+	}
+	return DefaultPatrolPause
+}
+
+// startPatrol sets a newly spawned mob's place on its route
+// A mob that spawns off its route heads for the first room.
+func (m *MobInstance) startPatrol() {
+	m.PatrolIndex = 0
+	m.PatrolStep = 1
+	for i, id := range m.Patrol.Route {
+		if id == m.Room.ID {
+			m.PatrolIndex = i
+			break
+		}
+	}
+}
+
+// nextPatrolIndex returns the route index the mob walks to next, turning around at the ends
+func (m *MobInstance) nextPatrolIndex() int {
+	last := len(m.Patrol.Route) - 1
+	if m.Patrol.Loop {
+		return (m.PatrolIndex + 1) % len(m.Patrol.Route)
+	}
+	if m.PatrolIndex+m.PatrolStep > last || m.PatrolIndex+m.PatrolStep < 0 {
+		m.PatrolStep = -m.PatrolStep
+	}
+	return m.PatrolIndex + m.PatrolStep
+}
+
+// patrollingMobs returns every mob currently following a patrol route
+func patrollingMobs() []*MobInstance {
+	mobMutex.RLock()
+	defer mobMutex.RUnlock()
+
+	var patrollers []*MobInstance
+	for _, mob := range mobInstances {
+		if mob.Patrol != nil {
+			patrollers = append(patrollers, mob)
+		}
+	}
+	return patrollers
+}
+
+// advancePatrol moves a patrolling mob one step along its route
+func advancePatrol(mob *MobInstance) {
+	if mob.Room == nil || mob.Pursuing != nil || mob.Master != nil || mob.Rider != nil || mob.Fighting != nil || IsMobInCombat(mob) {
+		return
+	}
+	if mob.PatrolWait > 0 {
+		mob.PatrolWait--
+		return
+	}
+
+	// Off the route: make for the room where the patrol left off
+	if current := mob.Patrol.Route[mob.PatrolIndex]; mob.Room.ID != current {
+		if direction := directionToward(mob.Room, current); direction != "" {
+			MoveMob(mob, direction)
+		}
+		mob.PatrolWait = PatrolStepPulses
+		return
+	}
+
+	next := mob.nextPatrolIndex()
+	destRoom, err := GetRoom(mob.Patrol.Route[next])
+	if err != nil {
+		return
+	}
+
+	// A closed door holds the patrol up until someone opens it
+	direction := exitTo(mob.Room, destRoom)
+	if direction == "" || MoveMob(mob, direction) != nil {
+		return
+	}
+
+	mob.PatrolIndex = next
+	mob.PatrolWait = PatrolStepPulses
+	if mob.Patrol.isWaypoint(next) {
+		mob.PatrolWait = mob.Patrol.pause()
+	}
+}
+
+// directionToward returns the first step of the shortest open path from one room
+// to another within the same area, or "" if there is no such path nearby
+func directionToward(from *Room, toID int) string {
+	type step struct {
+		room  *Room
+		first string
+	}
+
+	visited := map[int]bool{from.ID: true}
+	queue := []step{{room: from}}
+	for len(queue) > 0 && len(visited) <= PatrolSearchDepth {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, dir := range openExits(current.room) {
+			id, err := ResolveExitRoomID(current.room.Exits[dir])
+			if err != nil || visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			first := current.first
+			if first == "" {
+				first = dir
+			}
+			if id == toID {
+				return first
+			}
+
+			room, err := GetRoom(id)
+			if err != nil || room.Area != from.Area {
+				continue
+			}
+			queue = append(queue, step{room: room, first: first})
+		}
+	}
+	return ""
+}
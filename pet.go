@@ -0,0 +1,215 @@
+/*
+ * pet.go
+ *
+ * This file implements pets and pet shops. A pet shopkeeper sells copies of
+ * the creatures kept in its kennel, a room set by the shopkeeper's pet_shop
+ * field. A pet bought from a shop is a follower like a charmed creature, but
+ * it isn't held by a charm, so it stays with its owner for good. When the
+ * owner leaves the game, the pet goes to the stables and is waiting for them
+ * when they return. A player may keep one pet at a time.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Pet name limits
+const (
+	MinPetNameLength = 2
+	MaxPetNameLength = 12
+)
+
+// PetPrice returns what a pet shop charges for a creature
+func PetPrice(template *Mob) int {
+	return 10 * template.Level * template.Level
+}
+
+// Pet returns the pet following the player, or nil if they have none
+func (p *Player) Pet() *MobInstance {
+	for _, mob := range p.Followers() {
+		if mob.Pet {
+			return mob
+		}
+	}
+	return nil
+}
+
+// petShopkeeper returns a mob in the room that sells pets
+func petShopkeeper(room *Room) *MobInstance {
+	for _, mob := range GetMobsInRoom(room.ID) {
+		if mob.PetShop != 0 {
+			return mob
+		}
+	}
+	return nil
+}
+
+// validPetName reports whether a name may be given to a pet
+func validPetName(name string) bool {
+	if len(name) < MinPetNameLength || len(name) > MaxPetNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// namePet gives a pet its name, which it then answers to
+func namePet(mob *MobInstance, name string) {
+	mob.PetName = name
+	mob.Keywords = append([]string{strings.ToLower(name)}, mob.Keywords...)
+
+	kind := mob.ShortDescription
+	for _, article := range []string{"the ", "a ", "an "} {
+		kind = strings.TrimPrefix(kind, article)
+	}
+	mob.ShortDescription = fmt.Sprintf("%s the %s", name, kind)
+}
+
+// adoptPet creates a pet from a mob template and puts it in the room with its owner
+func adoptPet(owner *Player, template *Mob, name string) *MobInstance {
+	mobMutex.Lock()
+	mob := createMobInstance(template, owner.Room)
+	mobMutex.Unlock()
+
+	mob.Master = owner
+	mob.Pet = true
+	mob.Wandering = false
+	mob.HomeArea = "" // Pets go wherever their owner leads
+	if name != "" {
+		namePet(mob, name)
+	}
+	return mob
+}
+
+// handleList lists what the shopkeeper in the room has for sale
+func handleList(player *Player, args []string) string {
+	keeper := petShopkeeper(player.Room)
+	if keeper == nil {
+		return "You can't buy anything here."
+	}
+
+	stock := GetMobsInRoom(keeper.PetShop)
+	if len(stock) == 0 {
+		return fmt.Sprintf("%s has no pets for sale right now.", capitalizeFirst(keeper.ShortDescription))
+	}
+
+	var templates []*Mob
+	for _, mob := range stock {
+		templates = append(templates, mobRegistry[mob.ID])
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Level < templates[j].Level })
+
+	var sb strings.Builder
+	sb.WriteString("Pets for sale:\r\n")
+	for _, template := range templates {
+		sb.WriteString(fmt.Sprintf("  [Lv %2d] %-30s %6d gold\r\n", template.Level, capitalizeFirst(template.ShortDescription), HagglePrice(player, keeper, PetPrice(template))))
+	}
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// handleBuy buys a pet from the shopkeeper in the room, optionally naming it
+// Usage: buy <pet> [name]
+func handleBuy(player *Player, args []string) string {
+	keeper := petShopkeeper(player.Room)
+	if keeper == nil {
+		return "You can't buy anything here."
+	}
+	if len(args) == 0 {
+		return "Usage: buy <pet> [name]"
+	}
+
+	stock := FindMobByTarget(keeper.PetShop, strings.ToLower(args[0]))
+	if stock == nil {
+		return fmt.Sprintf("%s doesn't sell that.", capitalizeFirst(keeper.ShortDescription))
+	}
+	template := mobRegistry[stock.ID]
+
+	name := ""
+	if len(args) > 1 {
+		name = capitalizeFirst(strings.ToLower(args[1]))
+		if !validPetName(name) {
+			return fmt.Sprintf("Pet names must be %d to %d letters long.", MinPetNameLength, MaxPetNameLength)
+		}
+	}
+
+	switch {
+	case player.Pet() != nil:
+		return "You already have a pet."
+	case len(player.Followers()) >= MaxFollowers:
+		return "You can't control any more followers."
+	case template.Level > player.Level:
+		return fmt.Sprintf("You aren't experienced enough to handle %s.", template.ShortDescription)
+	}
+
+	listed := PetPrice(template)
+	price := HagglePrice(player, keeper, listed)
+	if !ChargeGold(player, price, GoldSourcePets) {
+		return fmt.Sprintf("%s costs %d gold, which you can't afford.", capitalizeFirst(template.ShortDescription), price)
+	}
+
+	pet := adoptPet(player, template, name)
+	BroadcastToRoom(fmt.Sprintf("$n buys %s as a pet.", pet.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You pay %d gold for %s.%s Enjoy your pet.", price, pet.ShortDescription, haggleNote(listed, price))
+}
+
+// SavePet records the player's pet in the stables without taking it away
+func (p *Player) SavePet() {
+	pet := p.Pet()
+	if pet == nil {
+		return
+	}
+	if err := SavePlayerPet(p.Name, pet.ID, pet.PetName, pet.HP); err != nil {
+		log.Printf("Error stabling %s's pet: %v", p.Name, err)
+	}
+}
+
+// StablePet sends the player's pet to the stables when they leave the game
+func (p *Player) StablePet() {
+	pet := p.Pet()
+	if pet == nil {
+		return
+	}
+	p.SavePet()
+
+	pet.Master = nil
+	pet.Guarding = nil
+	pet.Fighting = nil
+	BroadcastToRoom(fmt.Sprintf("%s trots off to the stables.", capitalizeFirst(pet.ShortDescription)), pet.Room, p)
+	RemoveMobFromRoom(pet)
+}
+
+// FetchPet brings the player's pet back from the stables
+func (p *Player) FetchPet() {
+	stabled, err := LoadPlayerPet(p.Name)
+	if err != nil {
+		return // No pet stabled
+	}
+	if err := DeletePlayerPet(p.Name); err != nil {
+		log.Printf("Error taking %s's pet out of the stables: %v", p.Name, err)
+	}
+
+	mobMutex.RLock()
+	template := mobRegistry[stabled.Vnum]
+	mobMutex.RUnlock()
+	if template == nil {
+		log.Printf("[WARNING] %s's stabled pet is mob %d, which no longer exists", p.Name, stabled.Vnum)
+		return
+	}
+
+	pet := adoptPet(p, template, stabled.Name)
+	if stabled.HP > 0 && stabled.HP < pet.MaxHP {
+		pet.HP = stabled.HP
+	}
+	BroadcastToRoom(fmt.Sprintf("%s bounds up to $n.", capitalizeFirst(pet.ShortDescription)), p.Room, p)
+	p.Send(fmt.Sprintf("%s bounds up to you.", capitalizeFirst(pet.ShortDescription)))
+}
@@ -28,22 +28,59 @@ type Player struct {
 	Class string
 	Title string // Player's custom title
 	// Core Stats
-	STR         int
-	DEX         int
-	CON         int
-	INT         int
-	WIS         int
-	PRE         int
-	Level       int
-	XP          int
-	NextLevelXP int
-	MaxHP       int
-	HP          int
-	MaxMP       int
-	MP          int
-	Stamina     int
-	MaxStamina  int
-	Gold        int
+	STR             int
+	DEX             int
+	CON             int
+	INT             int
+	WIS             int
+	PRE             int
+	Level           int
+	XP              int
+	NextLevelXP     int
+	MaxHP           int
+	HP              int
+	MaxMP           int
+	MP              int
+	Stamina         int
+	MaxStamina      int
+	Gold            int
+	BankBalance     int                  // Gold deposited with a banker
+	AlertHP         int                  // Percent of max HP below which the player is alerted (0 = off)
+	AlertMP         int                  // Percent of max mana below which the player is alerted (0 = off)
+	AlertBell       bool                 // Whether alerts ring the client's bell
+	alertedHP       bool                 // Whether the player has already been alerted about low HP
+	alertedMP       bool                 // Whether the player has already been alerted about low mana
+	nextMoveAt      time.Time            // When an encumbered player may take their next step
+	AutoLoot        bool                 // Take everything from the corpses of kills
+	AutoGold        bool                 // Take the gold from the corpses of kills
+	AutoSac         bool                 // Sacrifice empty corpses
+	Brief           bool                 // Leave out room descriptions when moving
+	ScreenReader    bool                 // Show rooms as plain sentences
+	Minimap         bool                 // Draw a map above each room
+	Prompt          string               // Custom prompt format ("" = the standard prompt)
+	Aliases         map[string]string    // Commands the player has given short names, keyed by name (see alias.go)
+	PageLength      int                  // Lines per page of long output (see pager.go)
+	Width           int                  // Columns output is wrapped to (see wrap.go)
+	Locale          Locale               // How numbers and times are written for the player
+	pageLines       []string             // Output waiting at a MORE prompt
+	Inventory       []*Item              // Items the player is carrying
+	Affects         AffectList           // Buffs and debuffs currently on the player
+	Equipment       map[string]*Item     // Items being worn, keyed by wear slot
+	SetBonuses      []ActiveSetBonus     // Bonuses from wearing pieces of item sets
+	CompletedQuests map[string]bool      // IDs of the quests the player has finished
+	Skills          map[string]bool      // Names of the skills and spells the player has learned
+	Achievements    map[string]time.Time // Achievements the player has earned, and when
+	Kills           int                  // Mobs the player has slain
+	Food            int                  // Ticks until the player starves (0 = starving)
+	Water           int                  // Ticks until the player is parched (0 = parched)
+	Playtime        int                  // Minutes played over the character's lifetime
+	LoginTime       time.Time            // When the current session began
+	VisitedRooms    map[int]bool         // IDs of the rooms the player has entered
+	Languages       map[string]bool      // Languages the player has learned beyond their own
+	Speaking        string               // Language the player talks in
+	Clan            *Clan                // Clan the player belongs to (nil for none)
+	ClanRank        int                  // Player's rank in their clan
+	clanInvite      *Clan                // Clan that has invited the player to join
 
 	// Derived Combat Stats
 	HitChance     float64
@@ -54,17 +91,52 @@ type Player struct {
 	CastSpeed     float64
 
 	// Combat state
-	InCombat bool
-	Target   *MobInstance
-	IsDead   bool // New flag to track death state
+	InCombat       bool
+	Target         *MobInstance
+	Opponent       *Player      // Player being fought in a duel or the arena
+	CombatState                 // Knocked down, stunned, or disarmed
+	Meter          *DamageMeter // Tallies a spar or practice on a dummy (see spar.go)
+	duelChallenger *Player      // Player who has challenged this one to a duel
+	sparChallenger *Player      // Player who has challenged this one to a spar
+	IsDead         bool         // New flag to track death state
 
 	// Session-specific data
-	Room        *Room    // Current room the player is in
-	Conn        net.Conn // Network connection for the player
-	LastCommand string   // Store the last command for reference
+	Room        *Room                // Current room the player is in
+	Mount       *MobInstance         // Mob the player is riding
+	Conn        net.Conn             // Network connection for the player
+	LastCommand string               // Store the last command for reference
+	ReplyTo     string               // Name of the last player who sent this player a tell
+	CampTimer   int                  // Seconds remaining until a camping player logs out (0 = not camping)
+	Quitting    bool                 // Set when the player has logged out and the session should end
+	cooldowns   map[string]time.Time // When the player may next use each command with a cooldown
+	Staff       bool                 // Set for any trust above mortal; staff can use staff commands and read account notes
+	Trust       int                  // Staff trust level (see immortal.go)
+	WizInvis    bool                 // Hidden from players and mobs of lower trust
+	Snooping    *Player              // Player whose session this staff member is watching (see snoop.go)
+	snoopRecord int                  // Audit record of the snoop in progress
 
 	// Color preferences
 	ColorEnabled bool // Whether ANSI colors are enabled for this player
+
+	Roleplay    bool            // Flagged as looking to roleplay (see rp.go)
+	ChannelsOff map[string]bool // Channels the player has turned off (see channel.go)
+
+	// Ignore and friends lists
+	listsMu  sync.Mutex      // Guards the lists below, which other players' sessions read
+	Ignoring map[string]bool // Players whose tells and channel talk are hidden (see ignore.go)
+	Friends  map[string]bool // Players whose comings and goings are announced (see friends.go)
+
+	flood floodBucket // Limits how fast the player's input is run (see flood.go)
+
+	// Spam squelch state (see squelch.go)
+	SquelchEnabled bool       // Whether repeated combat/broadcast lines are collapsed
+	outputMu       sync.Mutex // Guards the squelch state below
+	lastRepeatable string     // Last repeatable line shown to the player
+	repeatCount    int        // Identical copies suppressed since it was shown
+	lastRepeatAt   time.Time  // When the last copy arrived
+
+	// Anti-cheat watchdog state (see watchdog.go)
+	watch watchState
 }
 
 // Global session management
@@ -93,8 +165,15 @@ func (p *Player) Send(message string) {
 		return
 	}
 
-	// Process color codes
-	processedMessage := ProcessColors(message, p.ColorEnabled)
+	// Anything new ends a run of squelched repeats
+	p.FlushRepeats()
+	p.write(message)
+}
+
+// write wraps a message to the player's window, processes colors, and writes it straight to the connection
+func (p *Player) write(message string) {
+	// Break long lines between words, then process color codes
+	processedMessage := ProcessColors(p.wrap(message), p.ColorEnabled)
 
 	// Ensure the message ends with a newline
 	if !strings.HasSuffix(processedMessage, "\r\n") {
@@ -108,9 +187,15 @@ func (p *Player) Send(message string) {
 // SendType sends a message to the player with the default color for the specified message type
 func (p *Player) SendType(message string, messageType string) {
 	colorizedMessage := ColorizeByType(message, messageType)
+	if messageType == "combat" {
+		p.SendRepeatable(colorizedMessage)
+		return
+	}
 	p.Send(colorizedMessage)
 }
 
+// BroadcastToRoom shows a message to everyone in the room but the sender
+// $n in the message stands for the sender, named as each player sees them (see SeenName).
 func BroadcastToRoom(message string, room *Room, sender *Player) {
 	playersMutex.Lock()
 	defer playersMutex.Unlock()
@@ -118,7 +203,11 @@ func BroadcastToRoom(message string, room *Room, sender *Player) {
 	for _, p := range activePlayers {
 		if p != sender && p.Room != nil && room != nil &&
 			p.Room.ID == room.ID && p.Room == room {
-			p.Send(message)
+			if sender != nil {
+				p.SendRepeatable(actorMessage(message, sender, p))
+				continue
+			}
+			p.SendRepeatable(message)
 		}
 	}
 }
@@ -145,6 +234,7 @@ func calculateNextLevelXP(level int) int {
 
 // Add function to handle XP gain and level ups
 func (p *Player) GainXP(amount int) {
+	p.WatchXP(amount)
 	p.XP += amount
 
 	for p.XP >= p.NextLevelXP {
@@ -172,6 +262,8 @@ func (p *Player) GainXP(amount int) {
 
 		// Update derived stats after level up
 		p.UpdateDerivedStats()
+		p.SendStatus()
+		p.SendVitals()
 
 		// Update the database
 		if err := UpdatePlayerLevel(p.Name, p.Level, p.XP, p.NextLevelXP); err != nil {
@@ -180,6 +272,8 @@ func (p *Player) GainXP(amount int) {
 		if err := UpdatePlayerHPMP(p.Name, p.HP, p.MaxHP, p.MP, p.MaxMP); err != nil {
 			log.Printf("Error updating player HP/MP: %v", err)
 		}
+
+		Publish(Event{Type: EventLevelUp, Player: p, Room: p.Room})
 	}
 
 	// Always update XP in the database, even if the player didn't level up
@@ -229,12 +323,16 @@ func (p *Player) RestoreStamina(amount int) {
 // Add function to update derived stats
 func (p *Player) UpdateDerivedStats() {
 	// Calculate derived stats using the formulas
-	p.HitChance = 50 + (float64(p.DEX) * 1.5) + (float64(p.WIS) * 0.5) + (float64(p.Level) * 0.5)
-	p.EvasionChance = (float64(p.DEX) * 1.8) + (float64(p.WIS) * 0.2) - (float64(p.Level) * 0.3)
-	p.CritChance = (float64(p.DEX) * 0.5) + (float64(p.PRE) * 0.7) + (float64(p.Level) * 0.2)
-	p.CritDamage = 150 + (float64(p.STR) * 1.2) + (float64(p.WIS) * 0.5)
-	p.AttackSpeed = 100 + (float64(p.DEX) * 1.5) + (float64(p.STR) * 0.5)
-	p.CastSpeed = 100 + (float64(p.INT) * 1.3) + (float64(p.WIS) * 0.8)
+	// Attributes include any modifiers from active affects
+	str, dex, int_ := float64(p.Stat(ApplySTR)), float64(p.Stat(ApplyDEX)), float64(p.Stat(ApplyINT))
+	wis, pre := float64(p.Stat(ApplyWIS)), float64(p.Stat(ApplyPRE))
+
+	p.HitChance = 50 + (dex * 1.5) + (wis * 0.5) + (float64(p.Level) * 0.5) + float64(p.Modifier(ApplyHitroll))
+	p.EvasionChance = (dex * 1.8) + (wis * 0.2) - (float64(p.Level) * 0.3)
+	p.CritChance = (dex * 0.5) + (pre * 0.7) + (float64(p.Level) * 0.2)
+	p.CritDamage = 150 + (str * 1.2) + (wis * 0.5)
+	p.AttackSpeed = 100 + (dex * 1.5) + (str * 0.5)
+	p.CastSpeed = 100 + (int_ * 1.3) + (wis * 0.8)
 }
 
 // Update GetStatsDisplay to include combat stats
@@ -303,18 +401,28 @@ func (p *Player) RegenTick() {
 	}
 
 	// Calculate regeneration amounts
-	hpRegen := p.CON / 2
+	hpRegen := p.Stat(ApplyCON) / 2
 	if hpRegen < 1 {
 		hpRegen = 1
 	}
 
-	mpRegen := (p.INT + p.WIS) / 4
+	mpRegen := (p.Stat(ApplyINT) + p.Stat(ApplyWIS)) / 4
 	if mpRegen < 1 {
 		mpRegen = 1
 	}
 
 	staminaRegen := 10 // 10% stamina per minute
 
+	// Sheltering indoors speeds recovery
+	hpRegen = regenBonus(p.Room, hpRegen)
+	mpRegen = regenBonus(p.Room, mpRegen)
+	staminaRegen = regenBonus(p.Room, staminaRegen)
+
+	// Hunger and thirst slow recovery
+	hpRegen = p.survivalPenalty(hpRegen)
+	mpRegen = p.survivalPenalty(mpRegen)
+	staminaRegen = p.survivalPenalty(staminaRegen)
+
 	// Apply regeneration
 	if p.HP < p.MaxHP {
 		p.Heal(hpRegen)
@@ -327,6 +435,8 @@ func (p *Player) RegenTick() {
 	if p.Stamina < p.MaxStamina {
 		p.RestoreStamina(staminaRegen)
 	}
+
+	p.SendVitals()
 }
 
 // PulseUpdate handles updates that occur every second
@@ -336,9 +446,22 @@ func (p *Player) PulseUpdate() {
 		return
 	}
 
-	// Check for low health notification
-	if p.HP > 0 && p.HP < p.MaxHP/5 {
-		p.Conn.Write([]byte("\r\n*Your health is critically low!*\r\n> "))
+	// Count down a camping player's logout timer
+	if p.CampTimer > 0 {
+		p.processCamp()
+		if p.Quitting {
+			return
+		}
+	}
+
+	// Warn the player once when HP or mana runs low
+	p.CheckAlerts()
+
+	// Fights with other players are handled in duel.go
+	if p.InCombat && p.Opponent != nil {
+		p.ExecutePvPAttack()
+		p.SendVitals()
+		return
 	}
 
 	// Handle combat state - only if player is in combat
@@ -367,8 +490,10 @@ func (p *Player) PulseUpdate() {
 			return
 		}
 
-		// Execute player's attack
-		p.ExecuteAttack()
+		// Execute player's attack, unless they're stunned
+		if p.Stunned == 0 {
+			p.ExecuteAttack()
+		}
 
 		// Check if player is still in combat after their attack
 		// (they might have killed the target)
@@ -376,19 +501,58 @@ func (p *Player) PulseUpdate() {
 			return
 		}
 
+		// A badly wounded mob may lose its nerve and run, if it's on its feet
+		if !p.Target.IsDown() && p.Target.CheckMorale() && MobFlee(p.Target) {
+			return
+		}
+
 		// Add a small delay to make combat easier to follow
 		time.Sleep(100 * time.Millisecond)
 
-		// Execute mob's counter-attack if it's still alive
-		if p.Target != nil && p.Target.HP > 0 {
+		// Execute mob's counter-attack if it's still alive, not stunned, and not a practice dummy
+		if p.Target != nil && p.Target.HP > 0 && p.Target.Stunned == 0 && !p.Target.Dummy {
 			p.ReceiveAttack(p.Target)
 		}
+
+		// Send updated vitals to GMCP clients after each combat round
+		p.SendVitals()
 	}
 
 	// Regeneration is now handled only in the tick function (once per minute)
 	// p.RegenTick() - Removed to prevent healing every second
 }
 
+// CampDurationSeconds is how long a player must camp before logging out outside an inn
+const CampDurationSeconds = 15
+
+// processCamp counts down a camping player's timer and logs them out when it expires
+func (p *Player) processCamp() {
+	p.CampTimer--
+	if p.CampTimer > 0 {
+		if p.CampTimer%5 == 0 {
+			p.Send(fmt.Sprintf("You will leave the realm in %d seconds.", p.CampTimer))
+		}
+		return
+	}
+
+	BroadcastToRoom("$n finishes making camp and leaves the realm.", p.Room, p)
+	p.Send(saveAndQuit(p))
+
+	// Closing the connection ends the player's game loop
+	p.Conn.Close()
+}
+
+// CancelCamp interrupts a player's camping countdown
+func (p *Player) CancelCamp(message string) {
+	if p.CampTimer == 0 {
+		return
+	}
+
+	p.CampTimer = 0
+	p.Send(message)
+	BroadcastToRoom("$n stops making camp.", p.Room, p)
+}
+
 // ExecuteAttack handles a player's attack against a mob
 func (p *Player) ExecuteAttack() {
 	// Check if player is in combat and has a valid target
@@ -402,8 +566,13 @@ func (p *Player) ExecuteAttack() {
 		return
 	}
 
-	// Calculate hit chance
-	hitChance := CalculateHitChance(p.Level, p.Target.Level)
+	// Calculate hit chance, including any hitroll from affects
+	hitChance := CalculateHitChance(p.Level, p.Target.Level) + float64(p.Modifier(ApplyHitroll))/100
+	if !p.CanSee() {
+		hitChance -= DarkHitPenalty // Fighting blind
+	}
+	hitChance -= CombatHitPenalty(p.Room)
+	hitChance += stateHitModifier(&p.CombatState, &p.Target.CombatState)
 	hitRoll := rng.Float64()
 
 	// Check if attack misses
@@ -411,6 +580,7 @@ func (p *Player) ExecuteAttack() {
 		// Attack missed
 		missMessage := fmt.Sprintf("You miss %s.", p.Target.ShortDescription)
 		p.SendType(missMessage, "combat")
+		p.Meter.miss()
 
 		// Broadcast miss message to room
 		roomMessage := fmt.Sprintf("%s misses %s.", p.Name, p.Target.ShortDescription)
@@ -423,6 +593,7 @@ func (p *Player) ExecuteAttack() {
 		// Target evaded
 		evadeMessage := fmt.Sprintf("%s evades your attack.", p.Target.ShortDescription)
 		p.SendType(evadeMessage, "combat")
+		p.Meter.evade()
 
 		// Broadcast evasion message to room
 		roomMessage := fmt.Sprintf("%s evades %s's attack.", p.Target.ShortDescription, p.Name)
@@ -430,8 +601,11 @@ func (p *Player) ExecuteAttack() {
 		return
 	}
 
-	// Calculate damage
-	damage := CalculateDamage(p.Level)
+	// Calculate damage, including any damroll from affects
+	damage := CalculateDamage(p.Level) + p.Modifier(ApplyDamroll)
+	if damage < 1 {
+		damage = 1
+	}
 
 	// Check for critical hit
 	isCritical := ProcessCriticalHit(p.Level, p.Target.Level)
@@ -440,8 +614,11 @@ func (p *Player) ExecuteAttack() {
 		damage *= 2
 	}
 
-	// Apply damage to target
-	p.Target.HP -= damage
+	// Apply damage to target, unless it's a practice dummy
+	if !p.Target.Dummy {
+		p.Target.HP -= damage
+	}
+	p.Meter.hit(damage, isCritical)
 
 	// Send attack message to player
 	var attackMessage string
@@ -467,6 +644,34 @@ func (p *Player) ExecuteAttack() {
 	}
 }
 
+// What an attack damages
+const (
+	DamageHP      = "hp"
+	DamageMana    = "mana"
+	DamageStamina = "stamina"
+)
+
+// TakeDamage lowers the player's HP, mana, or stamina without going below zero
+// Returns how much was actually lost.
+func (p *Player) TakeDamage(kind string, amount int) int {
+	var pool *int
+	switch kind {
+	case DamageHP:
+		pool = &p.HP
+	case DamageMana:
+		pool = &p.MP
+	case DamageStamina:
+		pool = &p.Stamina
+	default:
+		return 0
+	}
+	if amount > *pool {
+		amount = *pool
+	}
+	*pool -= amount
+	return amount
+}
+
 // ReceiveAttack handles an attack from a mob against the player
 func (p *Player) ReceiveAttack(attacker *MobInstance) {
 	// Safety check - ensure player is in combat and has a valid target
@@ -474,6 +679,9 @@ func (p *Player) ReceiveAttack(attacker *MobInstance) {
 		return
 	}
 
+	// Being attacked interrupts any attempt to camp
+	p.CancelCamp("You are attacked and stop making camp!")
+
 	// Check if the player evades the attack
 	if ProcessEvasion(p.Level, attacker.Level) {
 		// Player evaded the attack
@@ -486,15 +694,29 @@ func (p *Player) ReceiveAttack(attacker *MobInstance) {
 		return
 	}
 
+	// Mobs with special attacks sometimes use one in place of a swing
+	if special := attacker.chooseSpecialAttack(); special != nil {
+		p.ReceiveSpecialAttack(attacker, special)
+		return
+	}
+
 	// Calculate hit chance for the mob using the utility function
-	finalHitChance := CalculateHitChance(attacker.Level, p.Level)
+	finalHitChance := CalculateHitChance(attacker.Level, p.Level) + float64(attacker.Affects.Modifier(ApplyHitroll))/100
+	finalHitChance -= CombatHitPenalty(p.Room)
+	finalHitChance += stateHitModifier(&attacker.CombatState, &p.CombatState)
 
 	// Roll to hit
 	hitRoll := rng.Float64()
 
 	if hitRoll <= finalHitChance {
 		// Hit! Calculate damage using the utility function
-		damage := CalculateDamage(attacker.Level)
+		damage := CalculateDamage(attacker.Level) + attacker.Affects.Modifier(ApplyDamroll)
+		if attacker.Disarmed > 0 {
+			damage /= 2
+		}
+		if damage < 1 {
+			damage = 1
+		}
 
 		// Check for critical hit
 		isCritical := ProcessCriticalHit(attacker.Level, p.Level)
@@ -504,10 +726,7 @@ func (p *Player) ReceiveAttack(attacker *MobInstance) {
 		}
 
 		// Apply damage to player
-		p.HP -= damage
-		if p.HP < 0 {
-			p.HP = 0
-		}
+		p.TakeDamage(DamageHP, damage)
 
 		// Send hit message to player
 		var attackMessage string
@@ -546,59 +765,109 @@ func (p *Player) ReceiveAttack(attacker *MobInstance) {
 func (p *Player) EnterCombat(target *MobInstance) {
 	p.InCombat = true
 	p.Target = target
+	tagMob(target, p)
+	p.practiceAgainst(target)
 }
 
 // ExitCombat takes the player out of combat
 func (p *Player) ExitCombat() {
 	p.InCombat = false
 	p.Target = nil
+	p.Opponent = nil
+	p.endPractice()
 }
 
-// IsInCombat checks if the player is in combat
+// IsInCombat checks if the player is in combat with a mob or another player
 func (p *Player) IsInCombat() bool {
-	return p.InCombat && p.Target != nil
+	return p.InCombat && (p.Target != nil || p.Opponent != nil)
 }
 
 // HandleMobDeath processes a mob's death
 func (p *Player) HandleMobDeath(mob *MobInstance) {
+	// Whoever tagged the mob earns the kill, even if someone else finished it
+	holder := mob.TagHolder()
+
 	// Exit combat
 	p.ExitCombat()
 
-	// Calculate XP gain
-	xpGain := CalculateXPGain(p.Level, mob.Level)
-	p.GainXP(xpGain)
+	if holder != nil && holder != p {
+		p.SendType(fmt.Sprintf("You have slain %s, but the kill belongs to %s.", mob.ShortDescription, holder.Name), "combat")
+		p.SendStatus()
+		creditKill(holder, mob, p.Name)
+	} else {
+		// Let the mob play out any last acts
+		FireDeathProgs(mob, p)
+
+		// Calculate XP gain
+		xpGain := CalculateXPGain(p.Level, mob.Level)
+		p.GainXP(xpGain)
+
+		// Send death message to player
+		deathMessage := fmt.Sprintf("You have slain %s!", mob.ShortDescription)
+		p.SendType(deathMessage, "combat")
 
-	// Send death message to player
-	deathMessage := fmt.Sprintf("You have slain %s!", mob.ShortDescription)
-	p.SendType(deathMessage, "combat")
+		// Let achievements, quests, and scripts know of the kill
+		Publish(Event{Type: EventMobKilled, Player: p, Mob: mob, Room: p.Room})
 
-	// Send XP gain message
-	xpMessage := fmt.Sprintf("You gain {G}%d{x} experience points.", xpGain)
-	p.Send(xpMessage)
+		// Send XP gain message
+		xpMessage := fmt.Sprintf("You gain {G}%s{x} experience points.", p.Locale.Number(xpGain))
+		p.Send(xpMessage)
+		p.SendStatus()
+	}
 
 	// Broadcast death message to room
 	roomMessage := fmt.Sprintf("%s has slain %s!", p.Name, mob.ShortDescription)
 	BroadcastCombatMessage(roomMessage, p.Room, p)
 
+	// Leave a corpse holding the mob's gold and loot, kept for whoever tagged it
+	corpse := CreateMobCorpse(mob)
+	if holder != nil {
+		corpse.Looter = holder.Name
+	} else {
+		holder = p
+	}
+	AddItemToRoom(corpse, p.Room)
+
 	// Remove the mob from the world
 	RemoveMobFromRoom(mob)
+
+	// Collect or sacrifice the corpse if the player has asked to
+	holder.autoLoot(corpse)
 }
 
 // Die handles player death
 func (p *Player) Die(killer *MobInstance) {
+	p.die(killer.ShortDescription, killer)
+}
+
+// die kills the player; killerName is who killed them, and killer the mob that did it, if any
+func (p *Player) die(killerName string, killer *MobInstance) {
 	// Set the player's death state
 	p.IsDead = true
 	p.HP = 0
 	p.ExitCombat()
+	p.CombatState = CombatState{}
+	p.Dismount()
+	p.ReleaseFollowers()
 
 	// Notify the player of their death
-	deathMessage := fmt.Sprintf("You have been killed by %s!", killer.ShortDescription)
+	deathMessage := fmt.Sprintf("You have been killed by %s!", killerName)
 	p.SendType(deathMessage, "death")
 
 	// Broadcast the death to the room
-	roomMessage := fmt.Sprintf("%s has been killed by %s!", p.Name, killer.ShortDescription)
+	roomMessage := fmt.Sprintf("$n has been killed by %s!", killerName)
 	BroadcastToRoom(ColorizeByType(roomMessage, "death"), p.Room, p)
 
+	// Leave a corpse holding everything the player was carrying
+	corpse := CreatePlayerCorpse(p)
+	p.applyDeathPenalties(corpse)
+	AddItemToRoom(corpse, p.Room)
+	if err := SavePlayerInventory(p.Name, p.Inventory, p.Equipment); err != nil {
+		log.Printf("Error saving player inventory on death: %v", err)
+	}
+
+	Publish(Event{Type: EventPlayerDied, Player: p, Mob: killer, Room: p.Room})
+
 	// Provide instructions for respawning
 	p.Send("{W}Type 'respawn' to return to life.{x}")
 
@@ -606,6 +875,39 @@ func (p *Player) Die(killer *MobInstance) {
 	p.ScheduleRespawn()
 }
 
+// applyDeathPenalties charges the player the configured price of dying
+// Lost XP never costs a level, and dropped gold goes into the corpse.
+func (p *Player) applyDeathPenalties(corpse *Item) {
+	penalty := config.Death
+
+	if xpLoss := p.XP * penalty.XPLossPercent / 100; xpLoss > 0 {
+		p.XP -= xpLoss
+		p.Send(fmt.Sprintf("You lose {R}%s{x} experience points.", p.Locale.Number(xpLoss)))
+		if err := UpdatePlayerXP(p.Name, p.XP, p.NextLevelXP); err != nil {
+			log.Printf("Error updating player XP on death: %v", err)
+		}
+	}
+
+	if goldDrop := p.Gold * penalty.GoldDropPercent / 100; goldDrop > 0 {
+		p.Gold -= goldDrop
+		corpse.Gold += goldDrop
+		p.Send(fmt.Sprintf("{Y}%s gold coins{x} spill from your purse.", p.Locale.Number(goldDrop)))
+		if err := UpdatePlayerGold(p.Name, p.Gold); err != nil {
+			log.Printf("Error updating player gold on death: %v", err)
+		}
+	}
+
+	if penalty.DebuffDuration > 0 && len(penalty.DebuffModifiers) > 0 {
+		p.ApplyAffect(&Affect{
+			Name:          "death sickness",
+			Modifiers:     penalty.DebuffModifiers,
+			Duration:      penalty.DebuffDuration,
+			ApplyMessage:  "{D}The chill of death lingers in your bones.{x}",
+			ExpireMessage: "{G}You feel the chill of death leave your bones.{x}",
+		})
+	}
+}
+
 // ScheduleRespawn schedules a player to respawn after a delay
 func (p *Player) ScheduleRespawn() {
 	// Wait for respawn time (5 seconds)
@@ -636,7 +938,7 @@ func (p *Player) ScheduleRespawn() {
 
 			// Broadcast departure from old room if it's different from respawn room
 			if oldRoom != startRoom {
-				BroadcastToRoom(fmt.Sprintf("%s's body fades away.", p.Name), oldRoom, p)
+				BroadcastToRoom("$n's body fades away.", oldRoom, p)
 			}
 		}
 
@@ -649,13 +951,15 @@ func (p *Player) ScheduleRespawn() {
 		}
 
 		// Broadcast arrival to respawn room
-		arrivalMsg := fmt.Sprintf("%s appears in a flash of divine light.", p.Name)
+		arrivalMsg := "$n appears in a flash of divine light."
 		BroadcastToRoom(ColorizeByType(arrivalMsg, "system"), startRoom, p)
 	}
 
 	// Send respawn message
 	p.SendType("You have been resurrected!", "system")
 	p.Send("{C}Your blurred vision comes to focus and you find yourself next to the Temple Altar.{x}")
+	p.SendRoomInfo()
+	p.SendVitals()
 
 	// Update player stats in database
 	UpdatePlayerHPMP(p.Name, p.HP, p.MaxHP, p.MP, p.MaxMP)
@@ -707,7 +1011,7 @@ func BroadcastCombatMessage(message string, room *Room, sender *Player) {
 	for _, p := range activePlayers {
 		if p != sender && p.Room != nil && room != nil &&
 			p.Room.ID == room.ID && p.Room == room {
-			p.Send(colorizedMessage)
+			p.SendRepeatable(colorizedMessage)
 		}
 	}
 }
@@ -767,6 +1071,23 @@ func (p *Player) AutoSave() {
 		log.Printf("Error auto-saving player stats: %v", err)
 	}
 
+	// Save gold and carried items
+	if err := UpdatePlayerGold(p.Name, p.Gold); err != nil {
+		log.Printf("Error auto-saving player gold: %v", err)
+	}
+	if err := SavePlayerInventory(p.Name, p.Inventory, p.Equipment); err != nil {
+		log.Printf("Error auto-saving player inventory: %v", err)
+	}
+	if err := SavePlayerAffects(p.Name, p.Affects); err != nil {
+		log.Printf("Error auto-saving player affects: %v", err)
+	}
+	if err := p.SaveSurvival(); err != nil {
+		log.Printf("Error auto-saving player hunger and thirst: %v", err)
+	}
+	if err := p.SavePlaytime(); err != nil {
+		log.Printf("Error auto-saving player playtime: %v", err)
+	}
+
 	// Save room location
 	if p.Room != nil {
 		if err := UpdatePlayerRoom(p.Name, p.Room.ID); err != nil {
@@ -774,3 +1095,39 @@ func (p *Player) AutoSave() {
 		}
 	}
 }
+
+// LoadInventory restores the player's carried and worn items from the database
+func (p *Player) LoadInventory() {
+	saved, err := LoadPlayerItems(p.Name)
+	if err != nil {
+		log.Printf("Error loading inventory for %s: %v", p.Name, err)
+		return
+	}
+
+	p.Equipment = make(map[string]*Item)
+	containers := make(map[int]*Item)
+	for _, s := range saved {
+		item, err := CreateItem(s.Vnum)
+		if err != nil {
+			log.Printf("Skipping unknown item %d in %s's inventory: %v", s.Vnum, p.Name, err)
+			continue
+		}
+		if s.Bound {
+			item.BoundTo = p.Name
+		}
+		if s.Sips >= 0 && item.Type == "drink" {
+			item.SipsLeft = min(s.Sips, item.Sips)
+		}
+		containers[s.ID] = item
+		if container := containers[s.Container]; container != nil {
+			container.Contents = append(container.Contents, item)
+		} else if s.Worn != "" {
+			p.Equipment[s.Worn] = item
+		} else {
+			p.Inventory = append(p.Inventory, item)
+		}
+	}
+
+	// Calculate set bonuses quietly; the player hasn't gained anything new
+	p.SetBonuses = calculateSetBonuses(p.Equipment)
+}
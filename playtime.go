@@ -0,0 +1,99 @@
+/*
+ * playtime.go
+ *
+ * This file tracks how long players have played and how long the server
+ * has been up. Every tick a player is online adds a minute to their
+ * lifetime playtime, which is saved with the rest of their character.
+ * Score and whois show a player's playtime, and the 'uptime' command shows
+ * when the server was booted. The boot time is kept in the database so a
+ * copyover doesn't reset it.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// bootTimeKey is the server state key holding when the server was booted
+const bootTimeKey = "boot_time"
+
+var (
+	bootTime     time.Time // When the server was booted
+	copyoverTime time.Time // When the last copyover finished (zero if none since boot)
+)
+
+// InitUptime records when the server was booted, or recovers it after a copyover
+func InitUptime(copyover bool) {
+	now := time.Now()
+	bootTime = now
+	if copyover {
+		copyoverTime = now
+		if unix, err := GetServerStateInt(bootTimeKey); err != nil {
+			log.Printf("Error loading the boot time: %v", err)
+		} else if unix > 0 {
+			bootTime = time.Unix(int64(unix), 0)
+		}
+		return
+	}
+	if err := SetServerStateInt(bootTimeKey, int(now.Unix())); err != nil {
+		log.Printf("Error saving the boot time: %v", err)
+	}
+}
+
+// formatDuration describes a length of time in days, hours, and minutes, such as "2d 3h 15m"
+func formatDuration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	days, hours := minutes/(24*60), minutes/60%24
+	minutes %= 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// PlaytimeTick adds a minute to the player's lifetime playtime
+func (p *Player) PlaytimeTick() {
+	p.Playtime++
+}
+
+// SessionTime returns how long the player has been logged in
+func (p *Player) SessionTime() time.Duration {
+	return time.Since(p.LoginTime)
+}
+
+// LoadPlaytime restores the player's lifetime playtime and starts their session
+func (p *Player) LoadPlaytime() {
+	p.LoginTime = time.Now()
+
+	minutes, err := LoadPlayerPlaytime(p.Name)
+	if err != nil {
+		log.Printf("Error loading playtime for %s: %v", p.Name, err)
+		return
+	}
+	p.Playtime = minutes
+}
+
+// SavePlaytime records the player's lifetime playtime
+func (p *Player) SavePlaytime() error {
+	return UpdatePlayerPlaytime(p.Name, p.Playtime)
+}
+
+// handleUptime shows when the server was booted and how long it has been up
+func handleUptime(player *Player, args []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Booted:      %s %s\r\n", bootTime.Format("Mon"), player.Locale.DateTime(bootTime)))
+	sb.WriteString(fmt.Sprintf("Uptime:      %s", formatDuration(time.Since(bootTime))))
+	if !copyoverTime.IsZero() {
+		sb.WriteString(fmt.Sprintf("\r\nCopyover:    %s ago", formatDuration(time.Since(copyoverTime))))
+	}
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+/*
+ * plugin.go
+ *
+ * This file lets optional systems plug into the server without main() or
+ * the command table knowing about them. A plugin registers itself from
+ * its own file, which a build tag can leave out of the binary, and when
+ * the server starts each plugin that isn't turned off in config.yml is
+ * initialized and adds its commands and tick functions. The 'plugins'
+ * command shows staff which plugins are compiled in and running.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Plugin is an optional system that can be compiled in or out of the server
+type Plugin interface {
+	Name() string                                                        // Short name used in config.yml
+	Init() error                                                         // Prepares the plugin; an error leaves it off
+	RegisterCommands(register func(name string, handler CommandHandler)) // Adds the plugin's commands
+	RegisterTicks(tm *TimeManager)                                       // Adds the plugin's tick and pulse functions
+}
+
+// Plugin states shown by the 'plugins' command
+const (
+	PluginRunning  = "running"
+	PluginDisabled = "disabled"
+	PluginFailed   = "failed"
+)
+
+var (
+	plugins      = make(map[string]Plugin) // Plugins compiled into the server, keyed by name
+	pluginStates = make(map[string]string) // What became of each plugin at startup
+)
+
+// RegisterPlugin compiles a plugin into the server; call it from the plugin file's init function
+func RegisterPlugin(p Plugin) {
+	if _, exists := plugins[p.Name()]; exists {
+		panic(fmt.Sprintf("plugin %s registered twice", p.Name()))
+	}
+	plugins[p.Name()] = p
+}
+
+// pluginEnabled reports whether config.yml leaves a plugin on; plugins it doesn't mention are on
+func pluginEnabled(name string) bool {
+	enabled, listed := config.Plugins[name]
+	return enabled || !listed
+}
+
+// InitPlugins starts each enabled plugin and registers its commands and tick functions
+func InitPlugins(tm *TimeManager) {
+	for name := range config.Plugins {
+		if _, ok := plugins[name]; !ok {
+			log.Printf("[WARNING] config.yml mentions plugin %q, which isn't compiled in", name)
+		}
+	}
+
+	var names []string
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := plugins[name]
+		if !pluginEnabled(name) {
+			pluginStates[name] = PluginDisabled
+			continue
+		}
+		if err := p.Init(); err != nil {
+			log.Printf("[WARNING] Plugin %s couldn't start: %v", name, err)
+			pluginStates[name] = PluginFailed
+			continue
+		}
+		p.RegisterCommands(func(command string, handler CommandHandler) {
+			if _, exists := commandHandlers[command]; exists {
+				log.Printf("[WARNING] Plugin %s replaces the %s command", name, command)
+			}
+			commandHandlers[command] = handler
+		})
+		p.RegisterTicks(tm)
+		pluginStates[name] = PluginRunning
+		log.Printf("Started plugin %s", name)
+	}
+}
+
+// handlePlugins lists the plugins compiled into the server and whether they're running (staff only)
+func handlePlugins(player *Player, args []string) string {
+	if len(plugins) == 0 {
+		return "No plugins are compiled in."
+	}
+
+	var lines []string
+	for name := range plugins {
+		state := pluginStates[name]
+		switch state {
+		case PluginRunning:
+			state = "{G}" + state + "{x}"
+		case PluginFailed:
+			state = "{R}" + state + "{x}"
+		default:
+			state = "{D}" + state + "{x}"
+		}
+		lines = append(lines, fmt.Sprintf("  %-15s %s", name, state))
+	}
+	sort.Strings(lines)
+	return "Plugins:\r\n" + strings.Join(lines, "\r\n")
+}
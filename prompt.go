@@ -0,0 +1,187 @@
+/*
+ * prompt.go
+ *
+ * This file implements custom prompts. Players who'd rather not have the
+ * standard health, mana, and stamina prompt can write their own with
+ * 'prompt', using tokens such as %h for their health, %t for whoever
+ * they're fighting, and %w for the weather, along with color codes. The prompt is saved with the
+ * character, and 'prompt default' brings back the standard one.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// MaxPromptLength is the longest a custom prompt may be, not counting color codes
+const MaxPromptLength = 80
+
+// promptToken is a value a custom prompt can show, written as % and a letter
+type promptToken struct {
+	letter byte
+	help   string
+	value  func(p *Player) string
+}
+
+// promptTokens lists the tokens a custom prompt can use
+var promptTokens = []promptToken{
+	{'h', "current health", func(p *Player) string { return strconv.Itoa(p.HP) }},
+	{'H', "maximum health", func(p *Player) string { return strconv.Itoa(p.MaxHP) }},
+	{'m', "current mana", func(p *Player) string { return strconv.Itoa(p.MP) }},
+	{'M', "maximum mana", func(p *Player) string { return strconv.Itoa(p.MaxMP) }},
+	{'v', "current stamina", func(p *Player) string { return strconv.Itoa(p.Stamina) }},
+	{'V', "maximum stamina", func(p *Player) string { return strconv.Itoa(p.MaxStamina) }},
+	{'x', "experience earned this level", func(p *Player) string { return strconv.Itoa(p.XP) }},
+	{'X', "experience needed to level", func(p *Player) string { return strconv.Itoa(p.NextLevelXP - p.XP) }},
+	{'l', "level", func(p *Player) string { return strconv.Itoa(p.Level) }},
+	{'g', "gold carried", func(p *Player) string { return strconv.Itoa(p.Gold) }},
+	{'t', "who you're fighting", promptTarget},
+	{'T', "their health, as a percentage", promptTargetHealth},
+	{'r', "the room you're in", func(p *Player) string { return p.Room.Name }},
+	{'e', "the exits you can see", promptExits},
+	{'k', "the hour of the day, such as 3pm", func(p *Player) string { return CurrentTime().shortTime() }},
+	{'w', "the weather, or indoors", func(p *Player) string { return p.skyWeather() }},
+	{'a', "the affects on you", promptAffects},
+	{'c', "a new line", func(p *Player) string { return "\r\n" }},
+}
+
+// promptTarget returns the name of who the player is fighting, or "" if no one
+func promptTarget(p *Player) string {
+	if opp := p.Opponent; opp != nil {
+		return opp.Name
+	}
+	if target := p.Target; target != nil {
+		return target.ShortDescription
+	}
+	return ""
+}
+
+// promptTargetHealth returns the health of who the player is fighting as a percentage, or "" if no one
+func promptTargetHealth(p *Player) string {
+	if opp := p.Opponent; opp != nil && opp.MaxHP > 0 {
+		return fmt.Sprintf("%d%%", opp.HP*100/opp.MaxHP)
+	}
+	if target := p.Target; target != nil && target.MaxHP > 0 {
+		return fmt.Sprintf("%d%%", target.HP*100/target.MaxHP)
+	}
+	return ""
+}
+
+// promptExits returns the first letters of the exits the player can see, such as "NEU"
+func promptExits(p *Player) string {
+	if !p.CanSee() {
+		return "?"
+	}
+	var exits string
+	for _, dir := range Directions {
+		if exit, ok := p.Room.Exits[dir]; ok && p.CanSeeExit(exit) {
+			exits += strings.ToUpper(dir[:1])
+		}
+	}
+	if exits == "" {
+		return "none"
+	}
+	return exits
+}
+
+// promptAffects returns the names of the affects on the player, such as "bless,armor", or "" if none
+func promptAffects(p *Player) string {
+	names := make([]string, len(p.Affects))
+	for i, a := range p.Affects {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// findPromptToken returns the token written with a letter, or nil
+func findPromptToken(letter byte) *promptToken {
+	for i := range promptTokens {
+		if promptTokens[i].letter == letter {
+			return &promptTokens[i]
+		}
+	}
+	return nil
+}
+
+// RenderPrompt fills in the tokens of a custom prompt for the player
+// %% is a literal percent sign, and anything else after a % is left alone.
+func RenderPrompt(p *Player, format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+		i++
+		if token := findPromptToken(format[i]); token != nil {
+			sb.WriteString(token.value(p))
+		} else if format[i] == '%' {
+			sb.WriteByte('%')
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(format[i])
+		}
+	}
+	return sb.String()
+}
+
+// promptHelp lists the tokens a custom prompt can use
+func promptHelp() string {
+	var sb strings.Builder
+	sb.WriteString("Prompt tokens:\r\n")
+	for _, token := range promptTokens {
+		sb.WriteString(fmt.Sprintf("  %%%c  %s\r\n", token.letter, token.help))
+	}
+	sb.WriteString("  %%  a percent sign\r\n")
+	sb.WriteString("Example: prompt {G}%h/%H{x}hp %m/%Mmp %x exp %t>")
+	return sb.String()
+}
+
+// LoadPrompt restores the player's custom prompt
+func (p *Player) LoadPrompt() {
+	prompt, err := LoadPlayerPrompt(p.Name)
+	if err != nil {
+		log.Printf("Error loading prompt for %s: %v", p.Name, err)
+		return
+	}
+	p.Prompt = prompt
+}
+
+// handlePrompt shows, sets, or resets the player's prompt
+// Usage: prompt [<format>|default|tokens]
+func handlePrompt(player *Player, args []string) string {
+	if len(args) == 0 {
+		if player.Prompt == "" {
+			return "You are using the standard prompt. Type 'prompt tokens' to see how to write your own."
+		}
+		return fmt.Sprintf("Your prompt is: %s{x}", player.Prompt)
+	}
+
+	var prompt string
+	switch {
+	case len(args) == 1 && strings.EqualFold(args[0], "tokens"):
+		return promptHelp()
+	case len(args) == 1 && strings.EqualFold(args[0], "default"):
+		prompt = ""
+	default:
+		prompt = strings.TrimSpace(SanitizeText(strings.Join(args, " ")))
+		if LengthWithoutColorCodes(prompt) > MaxPromptLength {
+			return fmt.Sprintf("Prompts must be no longer than %d characters.", MaxPromptLength)
+		}
+		// Leave a space before the player's input, as the standard prompt does
+		prompt += " "
+	}
+
+	player.Prompt = prompt
+	if err := UpdatePlayerPrompt(player.Name, prompt); err != nil {
+		log.Printf("Error saving prompt for %s: %v", player.Name, err)
+	}
+	if prompt == "" {
+		return "Your prompt is back to the standard one."
+	}
+	return "Prompt set."
+}
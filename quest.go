@@ -0,0 +1,53 @@
+/*
+ * quest.go
+ *
+ * This file keeps track of the quests each player has completed. Completed
+ * quests are identified by a short ID chosen by area builders, saved with
+ * the character, and loaded when the player logs in. Other systems, such as
+ * quest-gated exits, check them with HasCompletedQuest.
+ */
+
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// HasCompletedQuest reports whether the player has finished the given quest
+func (p *Player) HasCompletedQuest(quest string) bool {
+	return p.CompletedQuests[strings.ToLower(quest)]
+}
+
+// CompleteQuest records that the player has finished a quest
+// Returns false if the quest was already complete.
+func (p *Player) CompleteQuest(quest string) bool {
+	quest = strings.ToLower(quest)
+	if p.CompletedQuests[quest] {
+		return false
+	}
+
+	if p.CompletedQuests == nil {
+		p.CompletedQuests = make(map[string]bool)
+	}
+	p.CompletedQuests[quest] = true
+
+	if err := AddCompletedQuest(p.Name, quest); err != nil {
+		log.Printf("Error saving completed quest %s for %s: %v", quest, p.Name, err)
+	}
+	return true
+}
+
+// LoadQuests restores the player's completed quests from the database
+func (p *Player) LoadQuests() {
+	quests, err := LoadCompletedQuests(p.Name)
+	if err != nil {
+		log.Printf("Error loading quests for %s: %v", p.Name, err)
+		return
+	}
+
+	p.CompletedQuests = make(map[string]bool)
+	for _, quest := range quests {
+		p.CompletedQuests[quest] = true
+	}
+}
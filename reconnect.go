@@ -0,0 +1,56 @@
+/*
+ * reconnect.go
+ *
+ * This file implements session takeover. When someone logs in as a
+ * character who is already in the game, whether because their client
+ * dropped without the server noticing or because they moved to another
+ * machine, the new connection takes over the character where it stands
+ * instead of loading a second copy from the database. The old connection
+ * is told and closed, and its game loop ends without logging the character
+ * out, so fights, followers, and everything else carry on as they were.
+ */
+
+package main
+
+import (
+	"bufio"
+	"log"
+	"net"
+)
+
+// TakeOver moves a player already in the game onto a new connection and closes the old one
+func (p *Player) TakeOver(conn net.Conn, colorEnabled bool) {
+	// A transcript belongs to the old connection, so it's saved now
+	if _, err := FinishTranscript(p); err != nil {
+		log.Printf("Error saving transcript for %s: %v", p.Name, err)
+	}
+
+	old := p.Conn
+	if oldTC, ok := old.(*TelnetConn); ok {
+		if newTC, ok := conn.(*TelnetConn); ok {
+			newTC.setSnooper(oldTC.Snooper())
+		}
+		oldTC.setSnooper(nil)
+	}
+
+	old.Write([]byte("\r\nThis character has been taken over by another connection.\r\n"))
+	p.Conn = conn
+	p.ColorEnabled = colorEnabled
+	old.Close()
+
+	log.Printf("%s reconnected from %s, taking over the session from %s", p.Name, conn.RemoteAddr(), old.RemoteAddr())
+	BroadcastToRoom("$n has reconnected.", p.Room, p)
+}
+
+// resumeTakenOver puts a player taken over by a new connection back into the game loop
+func resumeTakenOver(player *Player, conn net.Conn, reader *bufio.Reader) {
+	player.Send("{G}Reconnecting. You take over your character where you left off.{x}")
+	player.Send(DescribeRoom(player.Room, player))
+	player.SendRoomInfo()
+	player.SendStatus()
+	player.SendVitals()
+
+	playGame(player, reader)
+
+	endSession(player, conn)
+}
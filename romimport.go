@@ -0,0 +1,736 @@
+/*
+ * romimport.go
+ *
+ * This file converts area files written for ROM and Merc muds into the
+ * YAML area files this server loads. Running the server with -import-rom
+ * and the paths of one or more .are files reads their rooms, exits, doors,
+ * extra descriptions, mobiles, and mob resets, writes each area to the
+ * areas folder, and exits without starting the game. Objects, shops,
+ * specials, and mob programs have no counterpart here yet, so they are
+ * skipped and counted. An area whose room or mob vnums are already used by
+ * another area is refused rather than written over it.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ROM room flags the importer understands
+const (
+	romRoomDark    = 1 << 0 // A
+	romRoomNoMob   = 1 << 2 // C
+	romRoomIndoors = 1 << 3 // D
+)
+
+// ROM act flags the importer understands
+const (
+	romActSentinel  = 1 << 1 // B
+	romActScavenger = 1 << 2 // C
+	romActWimpy     = 1 << 7 // H
+)
+
+// romWimpy is the wimpy percent given to mobs flagged as wimpy
+const romWimpy = 50
+
+// romEvilAlignment is the alignment at or below which a mob is marked evil
+const romEvilAlignment = -350
+
+// romDirections maps ROM exit numbers to direction names
+var romDirections = []string{"north", "east", "south", "west", "up", "down"}
+
+// romSectors maps ROM sector numbers to sectors; unlisted ones become fields
+var romSectors = map[int]string{
+	0: SectorInside,
+	1: SectorCity,
+	2: SectorField,
+	3: SectorForest,
+	4: SectorHills,
+	5: SectorMountain,
+	6: SectorWater, // Swimmable water
+	7: SectorWater, // Water that needs a boat
+}
+
+// romImport is an area being converted and what was left out of it
+type romImport struct {
+	area    *Area
+	skipped map[string]int // Things with no counterpart here, and how many were dropped
+}
+
+// areReader reads the words, numbers, and ~-terminated strings of a .are file
+type areReader struct {
+	data []byte
+	pos  int
+	line int
+	err  error // First error met; later reads return zero values
+}
+
+// fail records the first error met while reading
+func (r *areReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf("line %d: %s", r.line, fmt.Sprintf(format, args...))
+	}
+}
+
+// eof reports whether the reader has run out of input or hit an error
+func (r *areReader) eof() bool {
+	return r.err != nil || r.pos >= len(r.data)
+}
+
+// next returns the next byte and moves past it
+func (r *areReader) next() byte {
+	if r.eof() {
+		r.fail("unexpected end of file")
+		return 0
+	}
+	c := r.data[r.pos]
+	r.pos++
+	if c == '\n' {
+		r.line++
+	}
+	return c
+}
+
+// skipSpace moves past whitespace
+func (r *areReader) skipSpace() {
+	for !r.eof() && strings.IndexByte(" \t\r\n", r.data[r.pos]) >= 0 {
+		r.next()
+	}
+}
+
+// letter returns the next character that isn't whitespace
+func (r *areReader) letter() byte {
+	r.skipSpace()
+	return r.next()
+}
+
+// peek returns the next character that isn't whitespace without reading it
+func (r *areReader) peek() byte {
+	r.skipSpace()
+	if r.eof() {
+		return 0
+	}
+	return r.data[r.pos]
+}
+
+// word returns the next run of characters up to whitespace
+func (r *areReader) word() string {
+	r.skipSpace()
+	start := r.pos
+	for !r.eof() && strings.IndexByte(" \t\r\n", r.data[r.pos]) < 0 {
+		r.next()
+	}
+	return string(r.data[start:r.pos])
+}
+
+// number returns the next number, which may carry a sign
+func (r *areReader) number() int {
+	w := r.word()
+	n, err := strconv.Atoi(w)
+	if err != nil {
+		r.fail("expected a number, found %q", w)
+	}
+	return n
+}
+
+// flags returns the next set of flags, written as a number or as letters (A = 1, B = 2, a = 1<<26), joined by |
+func (r *areReader) flags() int {
+	value := 0
+	for _, part := range strings.Split(r.word(), "|") {
+		if n, err := strconv.Atoi(part); err == nil {
+			value |= n
+			continue
+		}
+		for _, c := range part {
+			switch {
+			case c >= 'A' && c <= 'Z':
+				value |= 1 << (c - 'A')
+			case c >= 'a' && c <= 'z':
+				value |= 1 << (26 + c - 'a')
+			default:
+				r.fail("invalid flags %q", part)
+				return 0
+			}
+		}
+	}
+	return value
+}
+
+// str returns the next string, which runs up to a ~
+func (r *areReader) str() string {
+	r.skipSpace()
+	start := r.pos
+	for !r.eof() && r.data[r.pos] != '~' {
+		r.next()
+	}
+	text := string(r.data[start:r.pos])
+	r.next() // The ~
+	return strings.ReplaceAll(text, "\r", "")
+}
+
+// toEOL returns the rest of the current line
+func (r *areReader) toEOL() string {
+	start := r.pos
+	for !r.eof() && r.data[r.pos] != '\n' {
+		r.next()
+	}
+	text := string(r.data[start:r.pos])
+	if !r.eof() {
+		r.next()
+	}
+	return strings.TrimSpace(text)
+}
+
+// nextLine returns the next line that isn't blank without reading it
+func (r *areReader) nextLine() string {
+	for _, line := range strings.Split(string(r.data[r.pos:]), "\n") {
+		if strings.TrimSpace(line) != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// skipUntil reads whole lines until one that is just the given marker
+func (r *areReader) skipUntil(marker string) {
+	for !r.eof() {
+		if r.toEOL() == marker {
+			return
+		}
+	}
+	r.fail("no %q ends the section", marker)
+}
+
+// ImportROMAreas converts each .are file given and writes it to the areas folder
+func ImportROMAreas(paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no area files given; usage: go-mud -import-rom <file.are> ...")
+	}
+
+	used, err := usedVnums()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		imported, err := readROMArea(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		file := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".yml"
+		out := filepath.Join(AreaDir, file)
+		if _, err := os.Stat(out); err == nil {
+			return fmt.Errorf("%s: %s already exists", path, out)
+		}
+		if clashes := vnumClashes(imported.area, used); len(clashes) > 0 {
+			return fmt.Errorf("%s: vnums already in use: %s", path, strings.Join(clashes, ", "))
+		}
+		if err := writeImportedArea(out, imported.area); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		// Later files in the same run mustn't reuse these vnums either
+		for vnum := range imported.area.Rooms {
+			used["room "+strconv.Itoa(vnum)] = file
+		}
+		for vnum := range imported.area.Mobiles {
+			used["mob "+strconv.Itoa(vnum)] = file
+		}
+
+		fmt.Printf("Imported %s as %s: %d rooms, %d mobiles, %d mob resets\n", path, out,
+			len(imported.area.Rooms), len(imported.area.Mobiles), len(imported.area.MobResets))
+		var skipped []string
+		for what, count := range imported.skipped {
+			skipped = append(skipped, fmt.Sprintf("%d %s", count, what))
+		}
+		if len(skipped) > 0 {
+			sort.Strings(skipped)
+			fmt.Printf("  Skipped %s\n", strings.Join(skipped, ", "))
+		}
+	}
+	return nil
+}
+
+// usedVnums returns the room and mob vnums used by the area files already in place, keyed like "room 3001"
+func usedVnums() (map[string]string, error) {
+	used := make(map[string]string)
+	files, err := filepath.Glob(filepath.Join(AreaDir, "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var area struct {
+			Rooms   map[int]yaml.Node `yaml:"rooms"`
+			Mobiles map[int]yaml.Node `yaml:"mobiles"`
+		}
+		if err := yaml.Unmarshal(data, &area); err != nil {
+			log.Printf("[WARNING] Couldn't read %s to check its vnums: %v", path, err)
+			continue
+		}
+		for vnum := range area.Rooms {
+			used["room "+strconv.Itoa(vnum)] = filepath.Base(path)
+		}
+		for vnum := range area.Mobiles {
+			used["mob "+strconv.Itoa(vnum)] = filepath.Base(path)
+		}
+	}
+	return used, nil
+}
+
+// vnumClashes lists the first few of an area's vnums that another area already uses
+func vnumClashes(area *Area, used map[string]string) []string {
+	var keys []string
+	for vnum := range area.Rooms {
+		keys = append(keys, "room "+strconv.Itoa(vnum))
+	}
+	for vnum := range area.Mobiles {
+		keys = append(keys, "mob "+strconv.Itoa(vnum))
+	}
+	sort.Strings(keys)
+
+	var clashes []string
+	for _, key := range keys {
+		if file, ok := used[key]; ok {
+			clashes = append(clashes, fmt.Sprintf("%s (%s)", key, file))
+			if len(clashes) == 5 {
+				clashes = append(clashes, "...")
+				break
+			}
+		}
+	}
+	return clashes
+}
+
+// readROMArea parses a ROM or Merc area file
+func readROMArea(path string) (*romImport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := &romImport{
+		area: &Area{
+			Rooms:   make(map[int]*Room),
+			Mobiles: make(map[int]*Mob),
+		},
+		skipped: make(map[string]int),
+	}
+	r := &areReader{data: data, line: 1}
+	for {
+		if r.letter() != '#' {
+			r.fail("expected a section starting with #")
+		}
+		section := r.word()
+		switch section {
+		case "AREA":
+			readROMAreaHeader(r, imported.area)
+		case "AREADATA":
+			readROMAreaData(r, imported.area)
+		case "HELPS":
+			readROMHelps(r, imported)
+		case "MOBILES":
+			readROMMobiles(r, imported)
+		case "ROOMS":
+			readROMRooms(r, imported)
+		case "RESETS":
+			readROMResets(r, imported)
+		case "OBJECTS", "OBJOLD", "MOBPROGS", "SOCIALS":
+			imported.skipped[strings.ToLower(section)+" sections"]++
+			r.skipUntil("#0")
+		case "SHOPS":
+			imported.skipped["shops sections"]++
+			r.skipUntil("0")
+		case "SPECIALS":
+			imported.skipped["specials sections"]++
+			r.skipUntil("S")
+		case "$":
+			if imported.area.Name == "" {
+				imported.area.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+			return imported, nil
+		default:
+			r.fail("unknown section #%s", section)
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+}
+
+// readROMAreaHeader reads an #AREA header in the Merc form (one line) or the ROM form (file, name, credits, vnums)
+func readROMAreaHeader(r *areReader, area *Area) {
+	if line := r.toEOL(); line != "" {
+		// Merc: #AREA { 5 35} Author  Area Name~
+		line = strings.TrimSuffix(line, "~")
+		if end := strings.Index(line, "}"); end >= 0 {
+			line = line[end+1:]
+		}
+		if fields := strings.Fields(line); len(fields) > 1 {
+			line = strings.Join(fields[1:], " ")
+		}
+		area.Name = strings.TrimSpace(line)
+		return
+	}
+	r.str() // File name
+	area.Name = strings.TrimSpace(r.str())
+	r.str()    // Credits
+	r.number() // Lowest vnum
+	r.number() // Highest vnum
+}
+
+// readROMAreaData reads the keyed #AREADATA header written by ROM's online editor
+func readROMAreaData(r *areReader, area *Area) {
+	for !r.eof() {
+		switch key := r.word(); key {
+		case "End":
+			return
+		case "Name":
+			area.Name = strings.TrimSpace(r.str())
+		case "Builders", "Credits", "Filename":
+			r.str()
+		default:
+			r.toEOL()
+		}
+	}
+}
+
+// readROMHelps skips the help entries some area files carry
+func readROMHelps(r *areReader, imported *romImport) {
+	for !r.eof() {
+		r.number() // Level
+		if strings.TrimSpace(r.str()) == "$" {
+			return
+		}
+		r.str() // Text
+		imported.skipped["helps"]++
+	}
+}
+
+// readROMMobiles reads a #MOBILES section in either the ROM or the Merc layout
+func readROMMobiles(r *areReader, imported *romImport) {
+	for !r.eof() {
+		if r.letter() != '#' {
+			r.fail("expected # before a mobile's vnum")
+			return
+		}
+		vnum := r.number()
+		if vnum == 0 {
+			return
+		}
+
+		mob := &Mob{
+			Keywords:         strings.Fields(strings.ToLower(r.str())),
+			ShortDescription: strings.TrimSpace(r.str()),
+			LongDescription:  romText(r.str()),
+			Description:      romText(r.str()),
+			Race:             "human",
+		}
+
+		// ROM writes the race next as a string; Merc goes straight to the flags
+		if strings.Contains(r.nextLine(), "~") {
+			mob.Race = strings.ToLower(strings.TrimSpace(r.str()))
+			readROMMobStats(r, mob)
+		} else {
+			readMercMobStats(r, mob)
+		}
+
+		// Mob programs and flag changes that follow the stats
+		for !r.eof() && (r.peek() == 'F' || r.peek() == 'M') {
+			if r.letter() == 'M' {
+				imported.skipped["mob programs"]++
+			}
+			r.toEOL()
+		}
+
+		if _, exists := imported.area.Mobiles[vnum]; exists {
+			r.fail("mobile %d is defined twice", vnum)
+			return
+		}
+		imported.area.Mobiles[vnum] = mob
+	}
+}
+
+// readROMMobStats reads the numbers that follow a ROM mobile's race
+func readROMMobStats(r *areReader, mob *Mob) {
+	act := r.flags()
+	r.flags() // Affects
+	alignment := r.number()
+	r.number() // Group
+	mob.Level = r.number()
+	r.number() // Hitroll
+	r.word()   // Hit dice
+	r.word()   // Mana dice
+	r.word()   // Damage dice
+	r.word()   // Damage type
+	for i := 0; i < 4; i++ {
+		r.number() // Armor class
+	}
+	for i := 0; i < 4; i++ {
+		r.flags() // Offensive, immune, resistant, and vulnerable flags
+	}
+	r.word()   // Start position
+	r.word()   // Default position
+	r.word()   // Sex
+	r.number() // Wealth
+	r.flags()  // Form
+	r.flags()  // Parts
+	r.word()   // Size
+	r.word()   // Material
+	applyROMMobFlags(mob, act, alignment)
+}
+
+// readMercMobStats reads the numbers that follow a Merc mobile's descriptions
+func readMercMobStats(r *areReader, mob *Mob) {
+	act := r.flags()
+	r.flags() // Affects
+	alignment := r.number()
+	if kind := r.letter(); kind != 'S' {
+		r.fail("expected S after a mobile's alignment, found %q", kind)
+		return
+	}
+	mob.Level = r.number()
+	r.number() // Hitroll
+	r.number() // Armor class
+	r.word()   // Hit dice
+	r.word()   // Damage dice
+	r.number() // Gold
+	r.number() // Experience
+	r.number() // Position
+	r.number() // Default position
+	r.number() // Sex
+	applyROMMobFlags(mob, act, alignment)
+}
+
+// applyROMMobFlags carries over the act flags and alignment that have a counterpart here
+func applyROMMobFlags(mob *Mob, act, alignment int) {
+	mob.Wandering = act&romActSentinel == 0
+	mob.Scavenger = act&romActScavenger != 0
+	if act&romActWimpy != 0 {
+		mob.Wimpy = romWimpy
+	}
+	mob.Evil = alignment <= romEvilAlignment
+	if mob.Level < 1 {
+		mob.Level = 1
+	}
+}
+
+// readROMRooms reads a #ROOMS section
+func readROMRooms(r *areReader, imported *romImport) {
+	for !r.eof() {
+		if r.letter() != '#' {
+			r.fail("expected # before a room's vnum")
+			return
+		}
+		vnum := r.number()
+		if vnum == 0 {
+			return
+		}
+
+		room := &Room{
+			Name:        strings.TrimSpace(r.str()),
+			Description: romText(r.str()),
+			Exits:       make(map[string]*Exit),
+		}
+		r.number() // Area number, unused
+		roomFlags := r.flags()
+		sector, known := romSectors[r.number()]
+		if !known {
+			sector = SectorField
+		}
+		if roomFlags&romRoomIndoors != 0 {
+			sector = SectorInside
+		}
+		room.Sector = sector
+		room.Dark = roomFlags&romRoomDark != 0
+		room.NoWandering = roomFlags&romRoomNoMob != 0
+
+		for !r.eof() {
+			switch c := r.letter(); c {
+			case 'S':
+				if _, exists := imported.area.Rooms[vnum]; exists {
+					r.fail("room %d is defined twice", vnum)
+					return
+				}
+				imported.area.Rooms[vnum] = room
+			case 'D':
+				readROMExit(r, room, imported)
+				continue
+			case 'E':
+				room.Environment = append(room.Environment, EnvironmentAttribute{
+					Keywords:    strings.Fields(strings.ToLower(r.str())),
+					Description: romText(r.str()),
+				})
+				continue
+			case 'H', 'M':
+				r.number() // Healing and mana rates
+				continue
+			case 'C', 'O':
+				r.str() // Clan and owner
+				imported.skipped["room owners and clans"]++
+				continue
+			default:
+				r.fail("unknown room field %q in room %d", c, vnum)
+			}
+			break
+		}
+	}
+}
+
+// readROMExit reads one exit of a room; its direction follows the D
+func readROMExit(r *areReader, room *Room, imported *romImport) {
+	dir := r.number()
+	description := strings.TrimSpace(r.str())
+	keywords := strings.Fields(strings.ToLower(r.str()))
+	lock := r.number()
+	r.number() // Key vnum
+	to := r.number()
+
+	if dir < 0 || dir >= len(romDirections) {
+		r.fail("invalid exit direction %d", dir)
+		return
+	}
+	if to <= 0 {
+		imported.skipped["exits to nowhere"]++
+		return
+	}
+
+	exit := &Exit{ID: to, Description: description}
+	if lock > 0 {
+		if len(keywords) == 0 {
+			keywords = []string{"door"}
+		}
+		exit.Door = &Door{
+			ShortDescription: keywords[0],
+			Keywords:         keywords,
+		}
+	}
+	room.Exits[romDirections[dir]] = exit
+}
+
+// readROMResets reads a #RESETS section, keeping the mob resets and the doors they lock
+func readROMResets(r *areReader, imported *romImport) {
+	area := imported.area
+	for !r.eof() {
+		command := r.letter()
+		if command == 'S' {
+			return
+		}
+		if command == '*' {
+			r.toEOL()
+			continue
+		}
+
+		fields := strings.Fields(r.toEOL())
+		var args []int
+		for _, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				break // The rest is a comment
+			}
+			args = append(args, n)
+		}
+
+		switch command {
+		case 'M':
+			// M 0 mob world_limit room [room_limit]
+			if len(args) < 4 {
+				r.fail("mob reset needs at least four numbers")
+				return
+			}
+			reset := MobReset{MobVnum: args[1], MaxWorld: args[2], RoomVnum: args[3], Limit: 1}
+			if len(args) > 4 && args[4] > 0 {
+				reset.Limit = args[4]
+			}
+			if reset.MaxWorld < reset.Limit {
+				reset.MaxWorld = reset.Limit
+			}
+			if mob := area.Mobiles[reset.MobVnum]; mob != nil {
+				reset.Comment = mob.ShortDescription
+			}
+			area.MobResets = append(area.MobResets, reset)
+		case 'D':
+			// D 0 room direction state, where 2 is locked
+			if len(args) < 4 {
+				r.fail("door reset needs four numbers")
+				return
+			}
+			room := area.Rooms[args[1]]
+			if room == nil || args[2] < 0 || args[2] >= len(romDirections) {
+				imported.skipped["door resets for unknown exits"]++
+				continue
+			}
+			if exit := room.Exits[romDirections[args[2]]]; exit != nil && exit.Door != nil {
+				exit.Door.Locked = args[3] == 2
+			}
+		case 'O', 'P', 'G', 'E':
+			imported.skipped["object resets"]++
+		case 'R':
+			imported.skipped["exit shuffles"]++
+		default:
+			r.fail("unknown reset command %q", command)
+			return
+		}
+	}
+}
+
+// romText tidies a ROM description: line endings, trailing spaces, and blank lines at either end
+func romText(text string) string {
+	lines := strings.Split(strings.Trim(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// writeImportedArea writes a converted area in the style the area files are written in
+func writeImportedArea(path string, area *Area) error {
+	// Most rooms share one sector; make it the area's and keep only the exceptions
+	counts := make(map[string]int)
+	for _, room := range area.Rooms {
+		counts[room.Sector]++
+	}
+	for sector, count := range counts {
+		if count > counts[area.Sector] || count == counts[area.Sector] && sector < area.Sector {
+			area.Sector = sector
+		}
+	}
+	for _, room := range area.Rooms {
+		if room.Sector == area.Sector {
+			room.Sector = ""
+		}
+	}
+
+	var lines []string
+	sections := []struct {
+		key   string
+		value interface{}
+	}{
+		{"name", area.Name},
+		{"sector", area.Sector},
+		{"rooms", area.Rooms},
+		{"mobiles", area.Mobiles},
+		{"mob_resets", area.MobResets},
+	}
+	for _, section := range sections {
+		text, err := encodeSection(section.key, section.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.key, err)
+		}
+		lines = append(lines, text...)
+	}
+	return os.WriteFile(path, []byte("---\n"+strings.Join(lines, "\n")+"\n"), 0644)
+}
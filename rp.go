@@ -0,0 +1,107 @@
+/*
+ * rp.go
+ *
+ * This file implements roleplay support. Players who want to roleplay turn
+ * on their RP flag with the 'rp' command. Flagged players are marked in
+ * the who list and listed on their own by 'rpwho', so they can find each
+ * other. Speech in a room is in character: flagged players see 'say' lines
+ * tagged IC, and anyone can step out of character with 'looc', which talks
+ * to the room with an OOC tag so it isn't mistaken for their character
+ * speaking.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// rpWhoTag marks players with the RP flag in the who list
+const rpWhoTag = "{M}(RP){x}"
+
+// icTag returns the tag shown before in-character speech to the listener, or "" if they don't roleplay
+func icTag(listener *Player) string {
+	if listener.Roleplay {
+		return "{D}[IC]{x} "
+	}
+	return ""
+}
+
+// handleRP shows or sets the player's roleplay flag
+// Usage: rp [on|off]
+func handleRP(player *Player, args []string) string {
+	if len(args) == 0 {
+		if player.Roleplay {
+			return "Your RP flag is {G}ON{x}. Use 'rp off' to clear it."
+		}
+		return "Your RP flag is OFF. Use 'rp on' to show you're looking to roleplay."
+	}
+
+	var on bool
+	switch strings.ToLower(args[0]) {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	default:
+		return "Usage: rp [on|off]"
+	}
+
+	player.Roleplay = on
+	if err := UpdatePlayerRoleplay(player.Name, on); err != nil {
+		log.Printf("Error saving RP flag for %s: %v", player.Name, err)
+		return "Error saving your RP flag. It's set for this session only."
+	}
+	if on {
+		return "You are now flagged for roleplay. Speech in rooms is shown in character."
+	}
+	return "You are no longer flagged for roleplay."
+}
+
+// handleRPWho lists the players online who are flagged for roleplay
+func handleRPWho(player *Player, args []string) string {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	output := "{Y}Roleplayers currently online:{x}\r\n"
+	output += "{C}----------------------------------------{x}\r\n"
+
+	count := 0
+	for _, p := range activePlayers {
+		if !p.Roleplay || !player.CanSeePlayer(p) {
+			continue
+		}
+		count++
+
+		line := fmt.Sprintf("[{G}%-6s{x} {B}%-8s{x}] {W}%s{x}", p.Race, p.Class, p.Name)
+		if p.Title != "" {
+			line += " " + p.Title
+		}
+		output += line + "\r\n"
+	}
+	if count == 0 {
+		output += "Nobody is flagged for roleplay right now.\r\n"
+	}
+
+	output += "{C}----------------------------------------{x}\r\n"
+	output += fmt.Sprintf("{Y}Total roleplayers online: {W}%d{x}\r\n", count)
+	return output
+}
+
+// handleLOOC talks to the room out of character
+func handleLOOC(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Say what out of character?"
+	}
+
+	message := FilterText(player, SanitizeText(strings.Join(args, " ")))
+	for _, p := range playersInRoom(player.Room) {
+		if p == player || p.Ignores(player) {
+			continue
+		}
+		p.Send(fmt.Sprintf("{D}[OOC]{x} %s: %s", staffName(player, p), message))
+	}
+	return fmt.Sprintf("{D}[OOC]{x} You: %s", message)
+}
@@ -0,0 +1,123 @@
+/*
+ * rsearch.go
+ *
+ * This file implements 'rsearch', a staff command that helps builders find
+ * content across the loaded areas. When the areas load, the names and
+ * descriptions of every room and mob are gathered into a search index of
+ * lowercased text. 'rsearch' then reports the rooms and mobs whose text
+ * contains every word of the query, along with their vnums, so a builder
+ * can 'goto' a room or look up a mob without digging through YAML.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// MaxSearchResults is the most matches 'rsearch' lists of each kind
+const MaxSearchResults = 50
+
+// searchEntry is a room or mob in the search index
+type searchEntry struct {
+	Vnum int
+	Name string // Shown in the results
+	Area string // Area file the entry was loaded from (rooms only)
+	Text string // Lowercased text searched
+}
+
+var (
+	roomSearchIndex []searchEntry
+	mobSearchIndex  []searchEntry
+)
+
+// BuildSearchIndex gathers the text of every loaded room and mob for 'rsearch'
+func BuildSearchIndex() {
+	roomSearchIndex = nil
+	for id, room := range allRooms() {
+		roomSearchIndex = append(roomSearchIndex, searchEntry{
+			Vnum: id,
+			Name: room.Name,
+			Area: room.Area,
+			Text: strings.ToLower(room.Name + "\n" + room.Description),
+		})
+	}
+	sort.Slice(roomSearchIndex, func(i, j int) bool { return roomSearchIndex[i].Vnum < roomSearchIndex[j].Vnum })
+
+	mobMutex.RLock()
+	mobSearchIndex = nil
+	for id, mob := range mobRegistry {
+		mobSearchIndex = append(mobSearchIndex, searchEntry{
+			Vnum: id,
+			Name: mob.ShortDescription,
+			Text: strings.ToLower(strings.Join([]string{
+				strings.Join(mob.Keywords, " "), mob.ShortDescription, mob.LongDescription, mob.Description,
+			}, "\n")),
+		})
+	}
+	mobMutex.RUnlock()
+	sort.Slice(mobSearchIndex, func(i, j int) bool { return mobSearchIndex[i].Vnum < mobSearchIndex[j].Vnum })
+
+	log.Printf("Indexed %d rooms and %d mobs for searching", len(roomSearchIndex), len(mobSearchIndex))
+}
+
+// searchIndex returns the entries whose text contains every word
+func searchIndex(index []searchEntry, words []string) []searchEntry {
+	var matches []searchEntry
+	for _, entry := range index {
+		found := true
+		for _, word := range words {
+			if !strings.Contains(entry.Text, word) {
+				found = false
+				break
+			}
+		}
+		if found {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// handleRsearch searches room and mob text across the loaded areas (staff only)
+// Usage: rsearch <text>
+func handleRsearch(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Usage: rsearch <text>"
+	}
+
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = strings.ToLower(arg)
+	}
+	roomMatches := searchIndex(roomSearchIndex, words)
+	mobMatches := searchIndex(mobSearchIndex, words)
+	if len(roomMatches) == 0 && len(mobMatches) == 0 {
+		return fmt.Sprintf("Nothing matches '%s'.", strings.Join(args, " "))
+	}
+
+	var sb strings.Builder
+	writeMatches := func(title string, matches []searchEntry) {
+		if len(matches) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("{C}%s (%d):{x}\r\n", title, len(matches)))
+		for i, m := range matches {
+			if i == MaxSearchResults {
+				sb.WriteString(fmt.Sprintf("  ...and %d more\r\n", len(matches)-MaxSearchResults))
+				break
+			}
+			if m.Area != "" {
+				sb.WriteString(fmt.Sprintf("  [%5d] %s {D}(%s){x}\r\n", m.Vnum, m.Name, m.Area))
+			} else {
+				sb.WriteString(fmt.Sprintf("  [%5d] %s\r\n", m.Vnum, m.Name))
+			}
+		}
+	}
+	writeMatches("Rooms", roomMatches)
+	writeMatches("Mobs", mobMatches)
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
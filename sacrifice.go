@@ -0,0 +1,132 @@
+/*
+ * sacrifice.go
+ *
+ * This file implements the sacrifice and junk commands, which keep the
+ * world tidy by destroying unwanted items. Sacrificing a corpse or an item
+ * lying in the room offers it to the gods, who grant a little experience
+ * for a corpse or a few gold coins for an item. Junking destroys an item
+ * the player is carrying with no reward. Items needed for quests can't be
+ * destroyed either way, the gods refuse cursed offerings, and players'
+ * corpses and anything that still holds items are left alone.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SacrificeXP is the experience the gods grant for a sacrificed corpse
+const SacrificeXP = 5
+
+// SacrificeValueDivisor sets the gold granted for a sacrificed item as a fraction of its value
+const SacrificeValueDivisor = 10
+
+// sacrificeReward returns the gold the gods grant for a sacrificed item
+func sacrificeReward(item *Item) int {
+	gold := item.Value / SacrificeValueDivisor
+	if gold < 1 {
+		gold = 1
+	}
+	return gold
+}
+
+// checkDestroy returns why an item may not be destroyed, or "" if it may
+func checkDestroy(item *Item) string {
+	if item.Quest {
+		return fmt.Sprintf("You might still need %s for a quest.", item.ShortDescription)
+	}
+	if item.IsContainer() && (len(item.Contents) > 0 || item.Gold > 0) {
+		return fmt.Sprintf("You'll have to empty %s first.", item.ShortDescription)
+	}
+	return ""
+}
+
+// handleSacrifice offers a corpse or an item in the room to the gods
+// Usage: sacrifice <corpse|item>
+func handleSacrifice(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Sacrifice what?"
+	}
+
+	target := strings.ToLower(strings.Join(args, " "))
+	item := FindItemInList(GetItemsInRoom(player.Room), target)
+	if item == nil {
+		if FindItemInList(player.Inventory, target) != nil {
+			return "You'll have to drop it first, or junk it."
+		}
+		return "You don't see that here."
+	}
+
+	if item.Type == "corpse" && item.Owner != "" {
+		return "The gods wouldn't accept the corpse of an adventurer."
+	}
+	if !canLoot(player, item) {
+		return fmt.Sprintf("That kill belongs to %s.", item.Looter)
+	}
+	if reason := checkDestroy(item); reason != "" {
+		return reason
+	}
+	if item.Cursed {
+		return fmt.Sprintf("The gods refuse to accept %s. It is cursed.", item.ShortDescription)
+	}
+
+	if item.Type == "corpse" {
+		return sacrificeCorpse(player, item)
+	}
+	return sacrificeItem(player, item)
+}
+
+// sacrificeCorpse offers a corpse to the gods, destroying it for a little experience
+func sacrificeCorpse(p *Player, corpse *Item) string {
+	if !RemoveItemFromRoom(corpse, p.Room) {
+		return "You don't see that here."
+	}
+	p.GainXP(SacrificeXP)
+	BroadcastToRoom(fmt.Sprintf("$n sacrifices %s to the gods.", corpse.ShortDescription), p.Room, p)
+	return fmt.Sprintf("You sacrifice %s to the gods. They grant you {G}%d{x} experience.", corpse.ShortDescription, SacrificeXP)
+}
+
+// sacrificeItem offers an item to the gods, destroying it for a few gold coins
+func sacrificeItem(p *Player, item *Item) string {
+	if !RemoveItemFromRoom(item, p.Room) {
+		return "You don't see that here."
+	}
+
+	gold := sacrificeReward(item)
+	p.Gold += gold
+	if err := UpdatePlayerGold(p.Name, p.Gold); err != nil {
+		log.Printf("Error saving gold for %s: %v", p.Name, err)
+	}
+	RecordGoldCreated(GoldSourceSacrifice, gold)
+	p.SendStatus()
+
+	BroadcastToRoom(fmt.Sprintf("$n sacrifices %s to the gods.", item.ShortDescription), p.Room, p)
+	return fmt.Sprintf("You sacrifice %s to the gods. They grant you {Y}%d{x} gold.", item.Name(), gold)
+}
+
+// handleJunk destroys an item the player is carrying
+// Usage: junk <item>
+func handleJunk(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Junk what?"
+	}
+
+	target := strings.ToLower(strings.Join(args, " "))
+	item := FindItemInList(player.Inventory, target)
+	if item == nil {
+		if FindItemInList(GetItemsInRoom(player.Room), target) != nil {
+			return "You aren't carrying that. Try sacrificing it instead."
+		}
+		return "You do not have that item."
+	}
+	if reason := checkDestroy(item); reason != "" {
+		return reason
+	}
+
+	player.Inventory = removeItemFromList(player.Inventory, item)
+	BroadcastToRoom(fmt.Sprintf("$n junks %s.", item.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You junk %s. It crumbles to dust.", item.Name())
+}
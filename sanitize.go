@@ -0,0 +1,94 @@
+/*
+ * sanitize.go
+ *
+ * This file cleans up the text players send. Input lines are capped in
+ * length and backspaces are applied. Terminal escape sequences, control
+ * characters, and invalid UTF-8 are removed, so a player can't send raw
+ * ANSI codes or junk bytes that would corrupt other players' terminals.
+ * Text players author for others to see, such as says and titles, is
+ * cleaned again on its way out, in case it reached the game some other
+ * way. Game color codes such as {R} are left alone; they're translated
+ * safely when the text is sent.
+ */
+
+package main
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Character name limits
+const (
+	MinNameLength = 3
+	MaxNameLength = 12
+)
+
+// escapeSequence matches terminal escape sequences: CSI codes such as colors
+// and cursor movement, OSC codes such as window titles, and two-byte escapes
+var escapeSequence = regexp.MustCompile("\x1b\\[[0-?]*[ -/]*[@-~]|\x1b\\][^\x07\x1b]*(\x07|\x1b\\\\)?|\x1b.?")
+
+// ReadInput reads a line of player input and sanitizes it
+// The rest of a line longer than the configured maximum is read and thrown away.
+func ReadInput(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if room := config.Flood.MaxInputLength - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue // Keep reading to the end of an overlong line
+		}
+		if err != nil {
+			return "", err
+		}
+		return SanitizeInput(string(line)), nil
+	}
+}
+
+// SanitizeInput applies backspaces in a line of input and removes anything that isn't printable text
+func SanitizeInput(input string) string {
+	var runes []rune
+	for _, r := range strings.ToValidUTF8(input, "") {
+		if r == '\b' || r == 0x7f {
+			if len(runes) > 0 {
+				runes = runes[:len(runes)-1]
+			}
+			continue
+		}
+		runes = append(runes, r)
+	}
+	return strings.TrimSpace(SanitizeText(string(runes)))
+}
+
+// SanitizeText removes escape sequences, control characters, invisible
+// formatting characters, and invalid UTF-8 from text a player wrote
+// Tabs become spaces.
+func SanitizeText(text string) string {
+	text = escapeSequence.ReplaceAllString(strings.ToValidUTF8(text, ""), "")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// ValidName reports whether a new character may take a name
+func ValidName(name string) bool {
+	if len(name) < MinNameLength || len(name) > MaxNameLength {
+		return false
+	}
+	for _, r := range name {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+/*
+ * scan.go
+ *
+ * This file implements the 'exits' and 'scan' commands. 'exits' lists each
+ * way out of the room with the name of the room it leads to, and 'scan'
+ * peeks one room away in every direction to report who is there. Neither
+ * command can see past a closed door, and neither works in the dark.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exitDestination resolves where an exit leads
+func exitDestination(exit *Exit) *Room {
+	id, err := ResolveExitRoomID(exit)
+	if err != nil {
+		return nil
+	}
+	room, err := GetRoom(id)
+	if err != nil {
+		return nil
+	}
+	return room
+}
+
+// handleExits lists the room's exits and where they lead
+func handleExits(player *Player, args []string) string {
+	room := player.Room
+	if !player.CanSee() {
+		return "It's too dark to see any exits."
+	}
+	if room == nil || len(room.Exits) == 0 {
+		return "There are no obvious exits."
+	}
+
+	var sb strings.Builder
+	for _, direction := range Directions {
+		exit, ok := room.Exits[direction]
+		if !ok || !player.CanSeeExit(exit) {
+			continue
+		}
+
+		label := fmt.Sprintf("  %-6s - ", capitalizeFirst(direction))
+		if exit.Door != nil && exit.Door.Closed {
+			state := "closed"
+			if exit.Door.Locked {
+				state = "closed and locked"
+			}
+			sb.WriteString(fmt.Sprintf("%s{Y}The %s is %s.{x}\r\n", label, exit.Door.ShortDescription, state))
+			continue
+		}
+
+		name := "Somewhere unknown"
+		if dest := exitDestination(exit); dest != nil {
+			name = dest.Name
+		}
+		line := label + "{C}" + name + "{x}"
+		if exit.Door != nil {
+			line += fmt.Sprintf(" (through an open %s)", exit.Door.ShortDescription)
+		}
+		sb.WriteString(line + "\r\n")
+	}
+
+	if sb.Len() == 0 {
+		return "There are no obvious exits."
+	}
+	return "Obvious exits:\r\n" + strings.TrimSuffix(sb.String(), "\r\n")
+}
+
+// handleScan lists the mobs and players one room away in each direction
+func handleScan(player *Player, args []string) string {
+	room := player.Room
+	if room == nil {
+		return "You see nothing nearby."
+	}
+	if !player.CanSee() {
+		return "It's too dark to see anything."
+	}
+
+	var sb strings.Builder
+	for _, direction := range Directions {
+		exit, ok := room.Exits[direction]
+		if !ok || !player.CanSeeExit(exit) || (exit.Door != nil && exit.Door.Closed) {
+			continue
+		}
+		dest := exitDestination(exit)
+		if dest == nil {
+			continue
+		}
+
+		var seen []string
+		for _, mob := range GetMobsInRoom(dest.ID) {
+			seen = append(seen, player.MobAura(mob)+mob.ShortDescription)
+		}
+		for _, p := range player.VisiblePlayersInRoom(dest) {
+			seen = append(seen, p.Name)
+		}
+		if len(seen) == 0 {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("  {W}%s{x}: %s\r\n", capitalizeFirst(direction), strings.Join(seen, ", ")))
+	}
+
+	if sb.Len() == 0 {
+		return "You see nothing nearby."
+	}
+	BroadcastToRoom("$n scans the surroundings.", room, player)
+	return "Looking around, you see:\r\n" + strings.TrimSuffix(sb.String(), "\r\n")
+}
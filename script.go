@@ -0,0 +1,428 @@
+/*
+ * script.go
+ *
+ * This file embeds a Lua scripting engine for quests and boss fights too
+ * involved for mob programs. An area names its script in its area file,
+ * and the script is loaded from the scripts folder along with the area.
+ * Each script runs in its own sandbox with only the safe parts of the
+ * standard library, a limit on how long any call may run, and the 'mud'
+ * table for acting on the world: messaging players, spawning and removing
+ * mobs, teleporting players, and reading and changing their stats.
+ *
+ * The server calls a script's on_enter, on_speech, on_kill, on_death, and
+ * on_tick functions if it defines them, and mob programs can call any of its functions with
+ * the 'call' action. Players are passed to scripts by name and mobs by
+ * instance ID. Staff can reload a script without restarting the server.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ScriptDir holds the area scripts
+const ScriptDir = "scripts"
+
+// ScriptTimeout is the longest a single call into a script may run
+const ScriptTimeout = 100 * time.Millisecond
+
+// unsafeGlobals are base library functions removed so scripts can't reach the file system or load code
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage"}
+
+// AreaScript is a loaded script and the Lua state it runs in
+type AreaScript struct {
+	Area  string // File name of the area the script belongs to
+	Path  string // Where the script was loaded from
+	state *lua.LState
+	mutex sync.Mutex // Lua states can only run one call at a time
+}
+
+var (
+	areaScripts  = make(map[string]*AreaScript) // Loaded scripts, keyed by area file name
+	scriptsMutex sync.RWMutex
+)
+
+// LoadScripts loads the script of every area that names one
+func LoadScripts() {
+	for name, area := range allAreas() {
+		if area.Script == "" {
+			continue
+		}
+		if err := LoadAreaScript(name); err != nil {
+			log.Printf("[WARNING] Area %s's script couldn't be loaded: %v", name, err)
+		}
+	}
+}
+
+// LoadAreaScript loads, or reloads, the script for an area
+// If the new script fails to load, the old one keeps running.
+func LoadAreaScript(areaName string) error {
+	area := GetArea(areaName)
+	if area == nil {
+		return fmt.Errorf("no area named %s", areaName)
+	}
+	if area.Script == "" {
+		return fmt.Errorf("area %s has no script", areaName)
+	}
+
+	path := filepath.Join(ScriptDir, filepath.Base(area.Script))
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	script := &AreaScript{Area: areaName, Path: path, state: newScriptState()}
+	ctx, cancel := context.WithTimeout(context.Background(), ScriptTimeout)
+	defer cancel()
+	script.state.SetContext(ctx)
+	err := script.state.DoFile(path)
+	script.state.RemoveContext()
+	if err != nil {
+		script.state.Close()
+		return err
+	}
+
+	scriptsMutex.Lock()
+	old := areaScripts[areaName]
+	areaScripts[areaName] = script
+	scriptsMutex.Unlock()
+
+	if old != nil {
+		old.mutex.Lock()
+		old.state.Close()
+		old.mutex.Unlock()
+	}
+	return nil
+}
+
+// newScriptState creates a sandboxed Lua state with the mud API
+func newScriptState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true, CallStackSize: 120, RegistrySize: 1024 * 20})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("mud", L.SetFuncs(L.NewTable(), scriptAPI))
+	return L
+}
+
+// getScript returns an area's script, or nil if it has none
+func getScript(areaName string) *AreaScript {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
+	return areaScripts[areaName]
+}
+
+// Call runs a function the script defines, if it does, reporting any error
+// Script API functions must never call back into scripts, or this would deadlock.
+func (s *AreaScript) Call(function string, args ...lua.LValue) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fn, ok := s.state.GetGlobal(function).(*lua.LFunction)
+	if !ok {
+		return nil // Scripts only handle the events they care about
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ScriptTimeout)
+	defer cancel()
+	s.state.SetContext(ctx)
+	defer s.state.RemoveContext()
+	return s.state.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...)
+}
+
+// callScript runs a function in an area's script, logging any error
+func callScript(areaName, function string, args ...lua.LValue) {
+	script := getScript(areaName)
+	if script == nil {
+		return
+	}
+	if err := script.Call(function, args...); err != nil {
+		log.Printf("[WARNING] Script error in %s, %s: %v", script.Path, function, err)
+	}
+}
+
+// onScriptEnter tells an area's script that a player walked into one of its rooms
+func onScriptEnter(event Event) {
+	if event.Direction != "" {
+		callScript(event.Room.Area, "on_enter", lua.LString(event.Player.Name), lua.LNumber(event.Room.ID))
+	}
+}
+
+// onScriptKill tells an area's script that a player killed a mob in one of its rooms
+func onScriptKill(event Event) {
+	callScript(event.Room.Area, "on_kill", lua.LString(event.Player.Name), lua.LNumber(event.Mob.ID), lua.LNumber(event.Room.ID))
+}
+
+// onScriptDeath tells an area's script that a player died in one of its rooms
+func onScriptDeath(event Event) {
+	callScript(event.Room.Area, "on_death", lua.LString(event.Player.Name), lua.LNumber(event.Room.ID))
+}
+
+// FireScriptSpeech tells the area's script that a player said something
+func FireScriptSpeech(player *Player, message string) {
+	if player.Room != nil {
+		callScript(player.Room.Area, "on_speech", lua.LString(player.Name), lua.LNumber(player.Room.ID), lua.LString(StripColorCodes(message)))
+	}
+}
+
+// ProcessScripts runs each script's on_tick function
+func ProcessScripts() {
+	scriptsMutex.RLock()
+	var names []string
+	for name := range areaScripts {
+		names = append(names, name)
+	}
+	scriptsMutex.RUnlock()
+
+	for _, name := range names {
+		callScript(name, "on_tick")
+	}
+}
+
+// scriptAPI is the 'mud' table scripts use to act on the world
+var scriptAPI = map[string]lua.LGFunction{
+	"send":           luaSend,
+	"echo":           luaEcho,
+	"teleport":       luaTeleport,
+	"spawn":          luaSpawn,
+	"purge":          luaPurge,
+	"get":            luaGet,
+	"set":            luaSet,
+	"give":           luaGive,
+	"players":        luaPlayers,
+	"room":           luaRoom,
+	"has_quest":      luaHasQuest,
+	"complete_quest": luaCompleteQuest,
+}
+
+// scriptPlayer returns the online player named by a script's argument, raising a Lua error if they aren't
+func scriptPlayer(L *lua.LState, n int) *Player {
+	name := L.CheckString(n)
+	player := FindPlayerByName(name)
+	if player == nil {
+		L.RaiseError("no player named %s is online", name)
+	}
+	return player
+}
+
+// scriptRoom returns the room named by a script's argument, raising a Lua error if it doesn't exist
+func scriptRoom(L *lua.LState, n int) *Room {
+	room, err := GetRoom(L.CheckInt(n))
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	return room
+}
+
+// mud.send(player, text) sends a message to a player
+func luaSend(L *lua.LState) int {
+	scriptPlayer(L, 1).Send(L.CheckString(2))
+	return 0
+}
+
+// mud.echo(room, text) sends a message to everyone in a room
+func luaEcho(L *lua.LState) int {
+	BroadcastToRoom(L.CheckString(2), scriptRoom(L, 1), nil)
+	return 0
+}
+
+// mud.teleport(player, room) sends a player to a room
+func luaTeleport(L *lua.LState) int {
+	player := scriptPlayer(L, 1)
+	TransferPlayer(player, scriptRoom(L, 2))
+	return 0
+}
+
+// mud.spawn(vnum, room) creates a mob in a room and returns its ID, or nil at its world limit
+func luaSpawn(L *lua.LState) int {
+	vnum := L.CheckInt(1)
+	room := scriptRoom(L, 2)
+	if mobRegistry[vnum] == nil {
+		L.RaiseError("no mob with vnum %d", vnum)
+	}
+	mob := spawnTriggeredMob(vnum, room)
+	if mob == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	BroadcastToRoom(fmt.Sprintf("%s appears!", capitalizeFirst(mob.ShortDescription)), room, nil)
+	L.Push(lua.LNumber(mob.InstanceID))
+	return 1
+}
+
+// mud.purge(mob) removes a mob from the world without a corpse
+func luaPurge(L *lua.LState) int {
+	mobMutex.RLock()
+	mob := mobInstances[L.CheckInt(1)]
+	mobMutex.RUnlock()
+	if mob != nil {
+		RemoveMobFromRoom(mob)
+	}
+	return 0
+}
+
+// scriptStats are the player stats scripts can read, and whether they can change them
+var scriptStats = map[string]bool{
+	"level": false, "xp": false, "gold": false,
+	"hp": true, "maxhp": false, "mp": true, "maxmp": false, "stamina": true, "maxstamina": false,
+}
+
+// mud.get(player, stat) returns one of a player's stats
+func luaGet(L *lua.LState) int {
+	player := scriptPlayer(L, 1)
+	stat := strings.ToLower(L.CheckString(2))
+	values := map[string]int{
+		"level": player.Level, "xp": player.XP, "gold": player.Gold,
+		"hp": player.HP, "maxhp": player.MaxHP, "mp": player.MP, "maxmp": player.MaxMP,
+		"stamina": player.Stamina, "maxstamina": player.MaxStamina,
+	}
+	value, ok := values[stat]
+	if !ok {
+		L.RaiseError("unknown stat %q", stat)
+	}
+	L.Push(lua.LNumber(value))
+	return 1
+}
+
+// mud.set(player, stat, value) changes a player's HP, mana, or stamina, within their limits
+func luaSet(L *lua.LState) int {
+	player := scriptPlayer(L, 1)
+	stat := strings.ToLower(L.CheckString(2))
+	value := max(L.CheckInt(3), 0)
+	if !scriptStats[stat] {
+		L.RaiseError("stat %q can't be changed by scripts", stat)
+	}
+	switch stat {
+	case "hp":
+		player.HP = min(max(value, 1), player.MaxHP) // Scripts can hurt but not kill
+	case "mp":
+		player.MP = min(value, player.MaxMP)
+	case "stamina":
+		player.Stamina = min(value, player.MaxStamina)
+	}
+	player.SendStatus()
+	player.SendVitals()
+	return 0
+}
+
+// mud.give(player, vnum) gives a player a new item
+func luaGive(L *lua.LState) int {
+	player := scriptPlayer(L, 1)
+	vnum := L.CheckInt(2)
+	item, err := CreateItem(vnum)
+	if err != nil {
+		L.RaiseError("no item with vnum %d", vnum)
+	}
+	if player.CanLift(item) {
+		player.Inventory = append(player.Inventory, item)
+		if message := item.BindTo(player, BindOnPickup); message != "" {
+			player.Send(message)
+		}
+	} else {
+		AddItemToRoom(item, player.Room)
+	}
+	return 0
+}
+
+// mud.players(room) returns the names of the players in a room
+func luaPlayers(L *lua.LState) int {
+	table := L.NewTable()
+	for _, p := range playersInRoom(scriptRoom(L, 1)) {
+		table.Append(lua.LString(p.Name))
+	}
+	L.Push(table)
+	return 1
+}
+
+// mud.room(player) returns the ID of the room a player is in
+func luaRoom(L *lua.LState) int {
+	L.Push(lua.LNumber(scriptPlayer(L, 1).Room.ID))
+	return 1
+}
+
+// mud.has_quest(player, quest) reports whether a player has completed a quest
+func luaHasQuest(L *lua.LState) int {
+	L.Push(lua.LBool(scriptPlayer(L, 1).HasCompletedQuest(L.CheckString(2))))
+	return 1
+}
+
+// mud.complete_quest(player, quest) records a quest as completed, returning false if it already was
+func luaCompleteQuest(L *lua.LState) int {
+	L.Push(lua.LBool(scriptPlayer(L, 1).CompleteQuest(L.CheckString(2))))
+	return 1
+}
+
+// handleScripts lists the loaded area scripts or reloads one (staff only)
+// Usage: scripts, scripts reload <area|all>
+func handleScripts(player *Player, args []string) string {
+	if len(args) == 0 {
+		scriptsMutex.RLock()
+		var lines []string
+		for name, script := range areaScripts {
+			lines = append(lines, fmt.Sprintf("  %-20s %s", name, script.Path))
+		}
+		scriptsMutex.RUnlock()
+		if len(lines) == 0 {
+			return "No area scripts are loaded."
+		}
+		sort.Strings(lines)
+		return "Loaded area scripts:\r\n" + strings.Join(lines, "\r\n")
+	}
+
+	if strings.ToLower(args[0]) != "reload" || len(args) < 2 {
+		return "Usage: scripts, scripts reload <area|all>"
+	}
+
+	var names []string
+	if strings.EqualFold(args[1], "all") {
+		for name, area := range allAreas() {
+			if area.Script != "" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	} else {
+		name := findAreaFile(args[1])
+		if name == "" {
+			return fmt.Sprintf("There is no area called %s.", args[1])
+		}
+		names = []string{name}
+	}
+	if len(names) == 0 {
+		return "No areas have scripts."
+	}
+
+	var lines []string
+	for _, name := range names {
+		if err := LoadAreaScript(name); err != nil {
+			lines = append(lines, fmt.Sprintf("{R}%s failed to reload:{x} %v", name, err))
+			continue
+		}
+		log.Printf("%s reloaded the script for %s", player.Name, name)
+		lines = append(lines, fmt.Sprintf("{G}%s reloaded.{x}", name))
+	}
+	return strings.Join(lines, "\r\n")
+}
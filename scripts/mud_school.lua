@@ -0,0 +1,31 @@
+-- mud_school.lua
+--
+-- Script for Mud School. It warns new players about the dark room before
+-- the diploma beast and holds a small ceremony when they first graduate.
+
+local DARK_ROOM = 3720
+local DIPLOMA_ROOM = 3721
+local BAG = 3708
+
+-- Players who have already been told about the dark, so they aren't nagged
+local warned = {}
+
+function on_enter(player, room)
+  if room == DARK_ROOM and not warned[player] then
+    warned[player] = true
+    mud.send(player, "{D}It is hard to see in here.  A torch held in your hand would help.{x}")
+  end
+end
+
+-- Called by the diploma beast's death program
+function graduate(mob, player)
+  if mud.has_quest(player, "mud_school_diploma") then
+    return
+  end
+  mud.complete_quest(player, "mud_school_diploma")
+  mud.echo(mud.room(player), "{Y}A chorus of distant cheers rings out for " .. player .. "!{x}")
+  mud.set(player, "hp", mud.get(player, "maxhp"))
+  mud.set(player, "mp", mud.get(player, "maxmp"))
+  mud.give(player, BAG)
+  mud.send(player, "You feel refreshed, and find a small leather bag pressed into your hands.")
+end
@@ -0,0 +1,238 @@
+/*
+ * sheet.go
+ *
+ * This file implements character sheet export. 'score export' writes out a
+ * player's character sheet as plain text or JSON so they can copy it and
+ * share their build. Adding 'share' saves the sheet instead and gives the
+ * player a link to it on the web server, at the address set in config.yml.
+ * Each player has one shared sheet of each format at a time; sharing again
+ * replaces it.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Character sheet formats
+const (
+	SheetText = "text"
+	SheetJSON = "json"
+)
+
+// sheetPath is where shared character sheets are served on the web server
+const sheetPath = "/sheet/"
+
+// CharacterSheet is a snapshot of a character's build
+type CharacterSheet struct {
+	Name       string            `json:"name"`
+	Title      string            `json:"title,omitempty"`
+	Race       string            `json:"race"`
+	Class      string            `json:"class"`
+	Level      int               `json:"level"`
+	XP         int               `json:"xp"`
+	NextXP     int               `json:"next_level_xp"`
+	Clan       string            `json:"clan,omitempty"`
+	Played     string            `json:"played"`
+	Attributes map[string]int    `json:"attributes"`
+	Combat     SheetCombat       `json:"combat"`
+	Equipment  map[string]string `json:"equipment"`
+	SetBonuses []string          `json:"set_bonuses,omitempty"`
+	Skills     []string          `json:"skills,omitempty"`
+	Exported   time.Time         `json:"exported"`
+}
+
+// SheetCombat is the combat part of a character sheet
+type SheetCombat struct {
+	MaxHP       int     `json:"max_hp"`
+	MaxMP       int     `json:"max_mp"`
+	MaxStamina  int     `json:"max_stamina"`
+	HitChance   float64 `json:"hit_chance"`
+	Evasion     float64 `json:"evasion"`
+	CritChance  float64 `json:"crit_chance"`
+	CritDamage  float64 `json:"crit_damage"`
+	AttackSpeed float64 `json:"attack_speed"`
+	CastSpeed   float64 `json:"cast_speed"`
+}
+
+// BuildCharacterSheet takes a snapshot of the player's build
+func BuildCharacterSheet(player *Player) *CharacterSheet {
+	player.UpdateDerivedStats()
+
+	sheet := &CharacterSheet{
+		Name:       player.Name,
+		Title:      ProcessColors(player.Title, false),
+		Race:       player.Race,
+		Class:      player.Class,
+		Level:      player.Level,
+		XP:         player.XP,
+		NextXP:     player.NextLevelXP,
+		Played:     formatDuration(time.Duration(player.Playtime) * time.Minute),
+		Attributes: make(map[string]int),
+		Combat: SheetCombat{
+			MaxHP:       player.MaxHP,
+			MaxMP:       player.MaxMP,
+			MaxStamina:  player.MaxStamina,
+			HitChance:   player.HitChance,
+			Evasion:     player.EvasionChance,
+			CritChance:  player.CritChance,
+			CritDamage:  player.CritDamage,
+			AttackSpeed: player.AttackSpeed,
+			CastSpeed:   player.CastSpeed,
+		},
+		Equipment:  make(map[string]string),
+		SetBonuses: player.SetBonusSummary(),
+		Exported:   time.Now().UTC().Truncate(time.Second),
+	}
+	if player.Clan != nil {
+		sheet.Clan = player.Clan.Name
+	}
+	for _, attribute := range []string{ApplySTR, ApplyDEX, ApplyCON, ApplyINT, ApplyWIS, ApplyPRE} {
+		sheet.Attributes[attribute] = player.Stat(attribute)
+	}
+	for slot, item := range player.Equipment {
+		sheet.Equipment[slot] = ProcessColors(item.ShortDescription, false)
+	}
+	for skill := range player.Skills {
+		sheet.Skills = append(sheet.Skills, skill)
+	}
+	sort.Strings(sheet.Skills)
+	return sheet
+}
+
+// Text renders the character sheet as plain text
+func (s *CharacterSheet) Text() string {
+	var sb strings.Builder
+	sb.WriteString(s.Name)
+	if s.Title != "" {
+		sb.WriteString(" " + s.Title)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("Level %d %s %s\n", s.Level, s.Race, s.Class))
+	sb.WriteString(fmt.Sprintf("XP: %d / %d\n", s.XP, s.NextXP))
+	if s.Clan != "" {
+		sb.WriteString(fmt.Sprintf("Clan: %s\n", s.Clan))
+	}
+	sb.WriteString(fmt.Sprintf("Played: %s\n", s.Played))
+
+	sb.WriteString("\nAttributes\n")
+	sb.WriteString(fmt.Sprintf("  STR %-3d DEX %-3d CON %-3d\n", s.Attributes[ApplySTR], s.Attributes[ApplyDEX], s.Attributes[ApplyCON]))
+	sb.WriteString(fmt.Sprintf("  INT %-3d WIS %-3d PRE %-3d\n", s.Attributes[ApplyINT], s.Attributes[ApplyWIS], s.Attributes[ApplyPRE]))
+
+	c := s.Combat
+	sb.WriteString("\nCombat\n")
+	sb.WriteString(fmt.Sprintf("  HP %d  MP %d  Stamina %d\n", c.MaxHP, c.MaxMP, c.MaxStamina))
+	sb.WriteString(fmt.Sprintf("  Hit %.1f%%  Evasion %.1f%%  Crit %.1f%%  Crit DMG %.1f%%\n", c.HitChance, c.Evasion, c.CritChance, c.CritDamage))
+	sb.WriteString(fmt.Sprintf("  Attack SPD %.1f%%  Cast SPD %.1f%%\n", c.AttackSpeed, c.CastSpeed))
+
+	sb.WriteString("\nEquipment\n")
+	if len(s.Equipment) == 0 {
+		sb.WriteString("  Nothing\n")
+	}
+	for _, slot := range WearSlots {
+		if item, ok := s.Equipment[slot]; ok {
+			sb.WriteString(fmt.Sprintf("  %-20s %s\n", wearSlotLabels[slot], item))
+		}
+	}
+	if len(s.SetBonuses) > 0 {
+		sb.WriteString("\nSet Bonuses\n")
+		for _, line := range s.SetBonuses {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	if len(s.Skills) > 0 {
+		sb.WriteString("\nSkills\n")
+		sb.WriteString("  " + strings.Join(s.Skills, ", ") + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("\nExported %s\n", s.Exported.Format("2006-01-02 15:04 MST")))
+	return sb.String()
+}
+
+// render returns the character sheet in the given format
+func (s *CharacterSheet) render(format string) (string, error) {
+	if format == SheetJSON {
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return s.Text(), nil
+}
+
+// newSheetToken returns a random token naming a shared sheet
+func newSheetToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sheetURL is the link to a shared sheet
+func sheetURL(token string) string {
+	return strings.TrimRight(config.Web.PublicURL, "/") + sheetPath + token
+}
+
+// handleScoreExport writes out the player's character sheet, or shares it on the web server
+// Usage: score export [text|json] [share]
+func handleScoreExport(player *Player, args []string) string {
+	format, share := SheetText, false
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case SheetText:
+			format = SheetText
+		case SheetJSON:
+			format = SheetJSON
+		case "share":
+			share = true
+		default:
+			return "Usage: score export [text|json] [share]"
+		}
+	}
+
+	content, err := BuildCharacterSheet(player).render(format)
+	if err != nil {
+		log.Printf("Error exporting character sheet for %s: %v", player.Name, err)
+		return "Error exporting your character sheet."
+	}
+	if !share {
+		return strings.ReplaceAll(strings.TrimRight(content, "\n"), "\n", "\r\n")
+	}
+
+	token, err := newSheetToken()
+	if err == nil {
+		err = SaveCharacterSheet(token, player.Name, format, content)
+	}
+	if err != nil {
+		log.Printf("Error sharing character sheet for %s: %v", player.Name, err)
+		return "Error sharing your character sheet."
+	}
+	return fmt.Sprintf("Your character sheet is shared at {C}%s{x}\r\nSharing it again replaces this link.", sheetURL(token))
+}
+
+// handleSheetRequest serves a shared character sheet on the web server
+func handleSheetRequest(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, sheetPath)
+	format, content, err := LoadCharacterSheet(token)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if format == SheetJSON {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write([]byte(content))
+}
@@ -0,0 +1,122 @@
+/*
+ * shout.go
+ *
+ * This file implements the 'shout' and 'yell' commands, which carry a
+ * player's voice beyond the room they're in. A shout is heard throughout
+ * the area, and a yell in the rooms next door through open exits, with
+ * listeners told which way it came from. Raising your voice costs stamina.
+ * Sound doesn't get into or out of a soundproof room: a shout or yell from
+ * inside one is only heard there, and players inside one don't hear those
+ * from outside.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// raisedVoice holds what's needed to pass a shout or yell to a listener
+type raisedVoice struct {
+	speaker *Player
+	verb    string // "shouts" or "yells"
+	lang    *Language
+	message string
+}
+
+// hear sends the raised voice to a listener, with where it came from if they're elsewhere
+func (v raisedVoice) hear(listener *Player, from string) {
+	if listener.Ignores(v.speaker) {
+		return
+	}
+	// Those who can't see the speaker don't learn who it was
+	name := v.speaker.Name
+	if !listener.CanSeePlayer(v.speaker) {
+		name = "Someone"
+	}
+	line := fmt.Sprintf("%s %s%s%s '%s'", name, v.verb, from, languageTag(v.lang), listener.Hear(v.lang, v.message))
+	listener.SendRepeatable(icTag(listener) + ColorizeByType(line, "shout"))
+}
+
+// raiseVoice sets up a shout or yell, or returns why the player can't be heard
+func raiseVoice(player *Player, verb string, args []string) (raisedVoice, string) {
+	if len(args) == 0 {
+		return raisedVoice{}, capitalizeFirst(verb) + " what?"
+	}
+	return raisedVoice{
+		speaker: player,
+		verb:    verb + "s",
+		lang:    player.SpeakingLanguage(),
+		message: FilterText(player, SanitizeText(strings.Join(args, " "))),
+	}, ""
+}
+
+// handleShout speaks to everyone in the player's area
+// Usage: shout <message>
+func handleShout(player *Player, args []string) string {
+	voice, problem := raiseVoice(player, "shout", args)
+	if problem != "" {
+		return problem
+	}
+
+	room := player.Room
+	for _, p := range otherPlayers(player) {
+		if p.Room == nil || p.IsDead {
+			continue
+		}
+		if p.Room == room || (p.Room.Area == room.Area && !room.Soundproof && !p.Room.Soundproof) {
+			voice.hear(p, "")
+		}
+	}
+	return icTag(player) + ColorizeByType(fmt.Sprintf("You shout%s '%s'", languageTag(voice.lang), voice.message), "shout")
+}
+
+// handleYell speaks to the player's room and the rooms next to it through open exits
+// Usage: yell <message>
+func handleYell(player *Player, args []string) string {
+	voice, problem := raiseVoice(player, "yell", args)
+	if problem != "" {
+		return problem
+	}
+
+	room := player.Room
+	for _, p := range playersInRoom(room) {
+		if p != player {
+			voice.hear(p, "")
+		}
+	}
+	if !room.Soundproof {
+		heard := map[int]bool{room.ID: true}
+		for _, dir := range openExits(room) {
+			id, err := ResolveExitRoomID(room.Exits[dir])
+			if err != nil || heard[id] {
+				continue
+			}
+			heard[id] = true
+			next, err := GetRoom(id)
+			if err != nil || next.Soundproof {
+				continue
+			}
+			from := " from somewhere nearby"
+			if back := exitTo(next, room); back != "" {
+				from = " from " + directionFrom(back)
+			}
+			for _, p := range playersInRoom(next) {
+				voice.hear(p, from)
+			}
+		}
+	}
+	return icTag(player) + ColorizeByType(fmt.Sprintf("You yell%s '%s'", languageTag(voice.lang), voice.message), "shout")
+}
+
+// directionFrom describes where a sound through an exit comes from, such as "the north" or "above"
+func directionFrom(dir string) string {
+	switch dir {
+	case "up":
+		return "above"
+	case "down":
+		return "below"
+	}
+	return "the " + dir
+}
@@ -0,0 +1,206 @@
+/*
+ * shutdown.go
+ *
+ * This file implements the 'shutdown' and 'reboot' commands. Either one
+ * can take effect at once or be scheduled some minutes ahead, in which
+ * case the countdown is announced on the info channel as it runs down so
+ * players can find somewhere safe to stop. When it runs out, a shutdown
+ * saves and logs out every player and stops the server, while a reboot
+ * performs a copyover so everyone stays connected. 'shutdown cancel'
+ * calls off whichever is scheduled.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxShutdownDelay is the furthest ahead a shutdown or reboot can be scheduled, in minutes
+const MaxShutdownDelay = 24 * 60
+
+// shutdownWarnings are the times left at which a scheduled shutdown or reboot is announced
+var shutdownWarnings = []time.Duration{
+	30 * time.Minute, 15 * time.Minute, 10 * time.Minute, 5 * time.Minute,
+	3 * time.Minute, 2 * time.Minute, time.Minute, 30 * time.Second, 10 * time.Second,
+}
+
+// scheduledShutdown is a shutdown or reboot waiting to happen
+type scheduledShutdown struct {
+	Reboot bool          // Copyover rather than stop
+	By     string        // Staff member who scheduled it
+	At     time.Time     // When it happens
+	warned time.Duration // Time left at the last announcement, or when it was scheduled
+}
+
+// Global variables for the scheduled shutdown
+var (
+	pendingShutdown *scheduledShutdown // Shutdown or reboot waiting to happen, or nil
+	shutdownMutex   sync.Mutex         // Mutex for thread-safe shutdown scheduling
+)
+
+// verb names what the scheduled shutdown does
+func (s *scheduledShutdown) verb() string {
+	if s.Reboot {
+		return "reboot"
+	}
+	return "shut down"
+}
+
+// noun names the scheduled shutdown
+func (s *scheduledShutdown) noun() string {
+	if s.Reboot {
+		return "reboot"
+	}
+	return "shutdown"
+}
+
+// describeTimeLeft describes a countdown in minutes, or seconds under a minute
+func describeTimeLeft(d time.Duration) string {
+	if d >= time.Minute {
+		minutes := int((d + 30*time.Second) / time.Minute)
+		if minutes == 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return fmt.Sprintf("%d seconds", int(d.Round(time.Second)/time.Second))
+}
+
+// ProcessShutdown announces a scheduled shutdown or reboot as it approaches, and carries it out when due
+func ProcessShutdown() {
+	shutdownMutex.Lock()
+	s := pendingShutdown
+	if s == nil {
+		shutdownMutex.Unlock()
+		return
+	}
+
+	left := time.Until(s.At)
+	if left <= 0 {
+		pendingShutdown = nil
+		shutdownMutex.Unlock()
+		carryOutShutdown(s)
+		return
+	}
+
+	// Announce each warning time as the countdown passes it
+	var announce bool
+	for _, warning := range shutdownWarnings {
+		if left <= warning && warning < s.warned {
+			s.warned = warning
+			announce = true
+		}
+	}
+	shutdownMutex.Unlock()
+
+	if announce {
+		AnnounceInfo(fmt.Sprintf("{R}The server will %s in %s.{x}", s.verb(), describeTimeLeft(left)))
+	}
+}
+
+// carryOutShutdown reboots or stops the server
+func carryOutShutdown(s *scheduledShutdown) {
+	if s.Reboot {
+		log.Printf("Reboot by %s", s.By)
+		if err := performCopyover(s.By); err != nil {
+			log.Printf("Reboot failed: %v", err)
+			AnnounceInfo("{R}The reboot failed. Carry on.{x}")
+		}
+		return
+	}
+
+	log.Printf("Shutdown by %s", s.By)
+	playersMutex.Lock()
+	var players []*Player
+	for _, p := range activePlayers {
+		players = append(players, p)
+	}
+	playersMutex.Unlock()
+
+	for _, p := range players {
+		p.Send(fmt.Sprintf("{R}*** SHUTDOWN by %s ***{x}", s.By))
+		p.Send(saveAndQuit(p))
+		endSession(p, p.Conn)
+	}
+	stopServer()
+}
+
+// scheduleShutdown schedules a shutdown or reboot, or carries it out at once with no delay
+func scheduleShutdown(player *Player, reboot bool, args []string) string {
+	minutes := 0
+	if len(args) > 0 && !strings.EqualFold(args[0], "now") {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 || n > MaxShutdownDelay {
+			return fmt.Sprintf("Give a number of minutes from 0 to %d, or 'now'.", MaxShutdownDelay)
+		}
+		minutes = n
+	}
+
+	s := &scheduledShutdown{Reboot: reboot, By: player.Name, At: time.Now().Add(time.Duration(minutes) * time.Minute)}
+	shutdownMutex.Lock()
+	replaced := pendingShutdown
+	pendingShutdown = nil
+	if minutes > 0 {
+		s.warned = time.Until(s.At)
+		pendingShutdown = s
+	}
+	shutdownMutex.Unlock()
+
+	if minutes == 0 {
+		log.Printf("%s ordered an immediate %s", player.Name, s.noun())
+		carryOutShutdown(s)
+
+		// Only a failed reboot gets this far
+		return ""
+	}
+
+	log.Printf("%s scheduled a %s in %d minutes", player.Name, s.noun(), minutes)
+	AnnounceInfo(fmt.Sprintf("{R}The server will %s in %s.{x}", s.verb(), describeTimeLeft(time.Until(s.At))))
+	if replaced != nil {
+		return fmt.Sprintf("The earlier %s is replaced. The server will %s in %d minutes.", replaced.noun(), s.verb(), minutes)
+	}
+	return fmt.Sprintf("The server will %s in %d minutes. Use 'shutdown cancel' to call it off.", s.verb(), minutes)
+}
+
+// handleShutdown stops the server, now or after a countdown, or cancels a scheduled shutdown or reboot
+// Usage: shutdown [minutes|now|cancel|status]
+func handleShutdown(player *Player, args []string) string {
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "cancel":
+			shutdownMutex.Lock()
+			s := pendingShutdown
+			pendingShutdown = nil
+			shutdownMutex.Unlock()
+
+			if s == nil {
+				return "Nothing is scheduled."
+			}
+			log.Printf("%s cancelled the %s scheduled by %s", player.Name, s.noun(), s.By)
+			AnnounceInfo(fmt.Sprintf("{G}The scheduled %s has been cancelled.{x}", s.noun()))
+			return fmt.Sprintf("You cancel the %s.", s.noun())
+		case "status":
+			shutdownMutex.Lock()
+			s := pendingShutdown
+			shutdownMutex.Unlock()
+
+			if s == nil {
+				return "Nothing is scheduled."
+			}
+			return fmt.Sprintf("%s scheduled the server to %s in %s.", s.By, s.verb(), describeTimeLeft(time.Until(s.At)))
+		}
+	}
+	return scheduleShutdown(player, false, args)
+}
+
+// handleReboot performs a copyover, now or after a countdown
+// Usage: reboot [minutes|now]
+func handleReboot(player *Player, args []string) string {
+	return scheduleShutdown(player, true, args)
+}
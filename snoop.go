@@ -0,0 +1,199 @@
+/*
+ * snoop.go
+ *
+ * This file implements snooping. A staff member can snoop a player of
+ * lower trust to see everything the player sees and every command they
+ * type, which helps when debugging a player who is stuck or looking into
+ * abuse. The player isn't told. A staff member snoops one player at a
+ * time, and nobody can snoop someone who is already watching them, so
+ * snoops never loop back on themselves. Every snoop is written to the
+ * server log and kept in the database, where 'snoop list' shows who has
+ * been watching whom.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// snoopPrefix marks the lines a snooper sees from the player they are watching
+const snoopPrefix = "% "
+
+// SnoopHistorySize is how many past snoops 'snoop list' shows
+const SnoopHistorySize = 10
+
+// SnoopRecord is a snoop kept for the audit trail
+type SnoopRecord struct {
+	ID        int
+	Snooper   string
+	Target    string
+	StartedAt time.Time
+	EndedAt   time.Time // Zero while the snoop is still going
+}
+
+// Snooper returns the staff member watching output written to the connection, or nil
+func (t *TelnetConn) Snooper() *Player {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snooper
+}
+
+// setSnooper sets the staff member watching the connection, or clears it with nil
+func (t *TelnetConn) setSnooper(p *Player) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snooper = p
+}
+
+// mirror passes a copy of text written to a snooped connection on to the snooper
+func mirror(snooper *Player, text string) {
+	text = ansiEscape.ReplaceAllString(text, "")
+	text = strings.TrimRight(text, "\r\n")
+	if text == "" {
+		return
+	}
+	text = snoopPrefix + strings.ReplaceAll(text, "\r\n", "\r\n"+snoopPrefix)
+	snooper.Conn.Write([]byte(text + "\r\n"))
+}
+
+// SnoopInput passes a line the player typed on to anyone snooping them
+func (p *Player) SnoopInput(input string) {
+	if tc, ok := p.Conn.(*TelnetConn); ok {
+		if snooper := tc.Snooper(); snooper != nil {
+			mirror(snooper, "> "+input)
+		}
+	}
+}
+
+// snooperOf returns the staff member snooping the player, or nil
+func snooperOf(p *Player) *Player {
+	if tc, ok := p.Conn.(*TelnetConn); ok {
+		return tc.Snooper()
+	}
+	return nil
+}
+
+// startSnoop begins mirroring the target's session to the snooper
+func startSnoop(snooper, target *Player) {
+	tc := target.Conn.(*TelnetConn)
+	tc.setSnooper(snooper)
+	snooper.Snooping = target
+
+	id, err := AddSnoopRecord(snooper.Name, target.Name, time.Now())
+	if err != nil {
+		log.Printf("Error recording snoop of %s by %s: %v", target.Name, snooper.Name, err)
+	}
+	snooper.snoopRecord = id
+	log.Printf("%s started snooping %s", snooper.Name, target.Name)
+}
+
+// StopSnoop ends whatever snoop the player has running
+// Returns the player who was being snooped, or nil if there wasn't one.
+func StopSnoop(snooper *Player) *Player {
+	target := snooper.Snooping
+	if target == nil {
+		return nil
+	}
+	if tc, ok := target.Conn.(*TelnetConn); ok && tc.Snooper() == snooper {
+		tc.setSnooper(nil)
+	}
+	snooper.Snooping = nil
+
+	if snooper.snoopRecord != 0 {
+		if err := EndSnoopRecord(snooper.snoopRecord, time.Now()); err != nil {
+			log.Printf("Error recording end of snoop of %s by %s: %v", target.Name, snooper.Name, err)
+		}
+		snooper.snoopRecord = 0
+	}
+	log.Printf("%s stopped snooping %s", snooper.Name, target.Name)
+	return target
+}
+
+// endSnoops stops any snoop the player was running or was the target of when they leave
+func endSnoops(player *Player) {
+	StopSnoop(player)
+	if snooper := snooperOf(player); snooper != nil {
+		StopSnoop(snooper)
+		snooper.Send(fmt.Sprintf("{Y}Your snoop on %s ends as they leave the game.{x}", player.Name))
+	}
+}
+
+// handleSnoop watches a player's session, stops watching, or lists snoops
+// Usage: snoop <player> | snoop [self] | snoop list
+func handleSnoop(player *Player, args []string) string {
+	if len(args) == 0 || strings.EqualFold(args[0], "self") || strings.EqualFold(args[0], player.Name) {
+		target := StopSnoop(player)
+		if target == nil {
+			return "You aren't snooping anyone."
+		}
+		return fmt.Sprintf("You stop snooping %s.", target.Name)
+	}
+	if strings.EqualFold(args[0], "list") {
+		return snoopList(player)
+	}
+
+	target := FindPlayerByName(args[0])
+	switch {
+	case target == nil:
+		return "They aren't here."
+	case target == player.Snooping:
+		return fmt.Sprintf("You're already snooping %s.", target.Name)
+	case target.Trust >= player.Trust:
+		return fmt.Sprintf("%s is as trusted as you are.", target.Name)
+	}
+	tc, ok := target.Conn.(*TelnetConn)
+	if !ok {
+		return fmt.Sprintf("%s's connection can't be snooped.", target.Name)
+	}
+	if other := tc.Snooper(); other != nil {
+		return fmt.Sprintf("%s is already being snooped by %s.", target.Name, other.Name)
+	}
+
+	// Refuse if the target is watching the player, however indirectly
+	for watcher := snooperOf(player); watcher != nil; watcher = snooperOf(watcher) {
+		if watcher == target {
+			return "No snoop loops."
+		}
+	}
+
+	StopSnoop(player)
+	startSnoop(player, target)
+	return fmt.Sprintf("You are now snooping %s. Type 'snoop' to stop.", target.Name)
+}
+
+// snoopList shows the snoops running now and the most recent ones on record
+func snoopList(player *Player) string {
+	var sb strings.Builder
+	sb.WriteString("{Y}Active snoops{x}\r\n")
+	active := 0
+	for _, p := range append(otherPlayers(player), player) {
+		if p.Snooping != nil {
+			sb.WriteString(fmt.Sprintf("  %-12s is snooping %s\r\n", p.Name, p.Snooping.Name))
+			active++
+		}
+	}
+	if active == 0 {
+		sb.WriteString("  None.\r\n")
+	}
+
+	records, err := RecentSnoopRecords(SnoopHistorySize)
+	if err != nil {
+		log.Printf("Error loading snoop history: %v", err)
+		return strings.TrimRight(sb.String(), "\r\n")
+	}
+	if len(records) > 0 {
+		sb.WriteString("\r\n{Y}Recent snoops{x}\r\n")
+		for _, r := range records {
+			ended := "ongoing"
+			if !r.EndedAt.IsZero() {
+				ended = r.EndedAt.Format("15:04")
+			}
+			sb.WriteString(fmt.Sprintf("  %s-%-7s %-12s snooped %s\r\n", r.StartedAt.Format("2006-01-02 15:04"), ended, r.Snooper, r.Target))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\r\n")
+}
@@ -0,0 +1,112 @@
+/*
+ * social.go
+ *
+ * This file implements socials, the canned actions such as 'smile' and
+ * 'bow' that players use to express themselves. A social can be performed
+ * alone or directed at a player or mob in the room. Directed socials are
+ * built with the act() formatter, so the target sees the action from their
+ * own point of view ("Alice bows before you.") while everyone else sees it
+ * from the outside ("Alice bows before Bob.").
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Social is a canned action a player can perform
+// Messages use $n for the actor and $N for the target.
+type Social struct {
+	Name       string
+	Self       string // Seen by the actor when performed alone
+	Room       string // Seen by the room when performed alone
+	SelfTarget string // Seen by the actor when directed at someone
+	RoomTarget string // Seen by the room when directed at someone; the target sees it as "you"
+}
+
+// socials lists every social, in the order 'socials' shows them
+var socials = []*Social{
+	{Name: "bow", Self: "You bow deeply.", Room: "$n bows deeply.",
+		SelfTarget: "You bow before $N.", RoomTarget: "$n bows before $N."},
+	{Name: "cheer", Self: "You cheer loudly.", Room: "$n cheers loudly.",
+		SelfTarget: "You cheer for $N.", RoomTarget: "$n cheers for $N."},
+	{Name: "grin", Self: "You grin evilly.", Room: "$n grins evilly.",
+		SelfTarget: "You grin evilly at $N.", RoomTarget: "$n grins evilly at $N."},
+	{Name: "hug", Self: "You hug yourself.", Room: "$n hugs themselves.",
+		SelfTarget: "You hug $N.", RoomTarget: "$n hugs $N."},
+	{Name: "laugh", Self: "You fall down laughing.", Room: "$n falls down laughing.",
+		SelfTarget: "You laugh at $N mercilessly.", RoomTarget: "$n laughs at $N mercilessly."},
+	{Name: "nod", Self: "You nod.", Room: "$n nods.",
+		SelfTarget: "You nod at $N.", RoomTarget: "$n nods at $N."},
+	{Name: "poke", Self: "Poke whom?", Room: "",
+		SelfTarget: "You poke $N in the ribs.", RoomTarget: "$n pokes $N in the ribs."},
+	{Name: "shrug", Self: "You shrug.", Room: "$n shrugs helplessly.",
+		SelfTarget: "You shrug at $N.", RoomTarget: "$n shrugs at $N."},
+	{Name: "sigh", Self: "You sigh.", Room: "$n sighs loudly.",
+		SelfTarget: "You sigh at $N.", RoomTarget: "$n sighs at $N."},
+	{Name: "smile", Self: "You smile happily.", Room: "$n smiles happily.",
+		SelfTarget: "You smile at $N.", RoomTarget: "$n smiles at $N."},
+	{Name: "thank", Self: "Thank whom?", Room: "",
+		SelfTarget: "You thank $N heartily.", RoomTarget: "$n thanks $N heartily."},
+	{Name: "wave", Self: "You wave.", Room: "$n waves happily.",
+		SelfTarget: "You wave goodbye to $N.", RoomTarget: "$n waves goodbye to $N."},
+}
+
+// FindSocial looks up a social by name
+func FindSocial(name string) *Social {
+	for _, s := range socials {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// PerformSocial carries out a social, directed at the named target if one is given
+func PerformSocial(player *Player, social *Social, args []string) string {
+	if len(args) == 0 {
+		if social.Room != "" {
+			BroadcastToRoom(act(social.Room, "$n", "", false), player.Room, player)
+		}
+		return social.Self
+	}
+
+	// A social can be aimed at a player or a mob in the room
+	if target := player.FindVisiblePlayerInRoom(args[0]); target != nil {
+		if target == player {
+			return social.Self
+		}
+		actToRoom(player, target, social.RoomTarget)
+		return act(social.SelfTarget, player.Name, target.Name, false)
+	}
+
+	if mob := FindMobByTarget(player.Room.ID, args[0]); mob != nil {
+		BroadcastToRoom(act(social.RoomTarget, "$n", mob.ShortDescription, false), player.Room, player)
+		return act(social.SelfTarget, player.Name, mob.ShortDescription, false)
+	}
+
+	return "They aren't here."
+}
+
+// actToRoom shows an action directed at a player to everyone else in the room
+// The target sees it from their own point of view.
+func actToRoom(actor, target *Player, format string) {
+	for _, p := range playersInRoom(actor.Room) {
+		if p == actor || !p.CanSeePlayer(actor) {
+			continue
+		}
+		p.Send(act(format, actor.Name, target.Name, p == target))
+	}
+}
+
+// handleSocials lists the socials players can perform
+func handleSocials(player *Player, args []string) string {
+	var names []string
+	for _, s := range socials {
+		names = append(names, s.Name)
+	}
+	return fmt.Sprintf("{C}Socials:{x} %s\r\nUse a social alone, or follow it with the name of someone in the room.",
+		strings.Join(names, ", "))
+}
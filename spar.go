@@ -0,0 +1,242 @@
+/*
+ * spar.go
+ *
+ * This file implements practice fights, for trying out a build without
+ * risking anything. Practice dummies are mobs that never lose health and
+ * never strike back; anyone may attack one, and several players may share
+ * it. Players may also spar with each other by consent, challenging one
+ * another with 'spar'. A spar plays out like a duel, with every swing and
+ * every message, but no health is lost: each blow comes off the target's
+ * health on paper, and whoever would have brought the other down wins.
+ *
+ * Throughout a practice fight, a damage meter tallies the player's swings,
+ * hits, misses, and the damage they dealt. 'spar' shows it mid-fight, and
+ * it's shown again when the fight ends.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DamageMeter tallies a player's attacks in a practice fight
+// Its methods do nothing on a nil meter, so fights that aren't practice can call them freely.
+type DamageMeter struct {
+	Started time.Time
+	Swings  int // Attacks made, whether they landed or not
+	Hits    int
+	Crits   int
+	Misses  int
+	Evaded  int
+	Damage  int // Damage dealt, on paper
+	Biggest int // The hardest single blow
+	simHP   int // Health a sparring partner would have left
+}
+
+// newDamageMeter starts a meter for a fight against a target with hp health, or 0 for a dummy
+func newDamageMeter(hp int) *DamageMeter {
+	return &DamageMeter{Started: time.Now(), simHP: hp}
+}
+
+// miss records an attack that missed
+func (m *DamageMeter) miss() {
+	if m == nil {
+		return
+	}
+	m.Swings++
+	m.Misses++
+}
+
+// evade records an attack the target evaded
+func (m *DamageMeter) evade() {
+	if m == nil {
+		return
+	}
+	m.Swings++
+	m.Evaded++
+}
+
+// hit records damage dealt by an attack or skill
+func (m *DamageMeter) hit(damage int, critical bool) {
+	if m == nil {
+		return
+	}
+	m.Swings++
+	m.Hits++
+	if critical {
+		m.Crits++
+	}
+	m.Damage += damage
+	m.Biggest = max(m.Biggest, damage)
+	m.simHP -= damage
+}
+
+// report describes the meter's tallies so far
+func (m *DamageMeter) report() string {
+	seconds := max(time.Since(m.Started).Seconds(), 1)
+	accuracy := 0
+	if m.Swings > 0 {
+		accuracy = m.Hits * 100 / m.Swings
+	}
+	return fmt.Sprintf("{C}Damage meter{x} (%.0f seconds):\r\n"+
+		"  Swings: %d   Hits: %d (%d%%)   Criticals: %d   Misses: %d   Evaded: %d\r\n"+
+		"  Damage: %d total, %d biggest, %.1f per second",
+		seconds, m.Swings, m.Hits, accuracy, m.Crits, m.Misses, m.Evaded,
+		m.Damage, m.Biggest, float64(m.Damage)/seconds)
+}
+
+// Sparring reports whether the player is in a spar with another player
+func (p *Player) Sparring() bool {
+	return p.Meter != nil && p.Opponent != nil
+}
+
+// practiceAgainst starts the damage meter when the player attacks a dummy,
+// and ends it when they turn from one to a real fight
+func (p *Player) practiceAgainst(target *MobInstance) {
+	if target.Dummy {
+		if p.Meter == nil {
+			p.Meter = newDamageMeter(0)
+		}
+		return
+	}
+	p.endPractice()
+}
+
+// endPractice shows the player their damage meter and puts it away, if they were practicing
+func (p *Player) endPractice() {
+	if p.Meter == nil {
+		return
+	}
+	p.Send("Your practice is over.\r\n" + p.Meter.report())
+	p.Meter = nil
+}
+
+// damageOpponent deals damage to the player's PvP opponent, only on paper if they're sparring
+func (p *Player) damageOpponent(damage int, critical bool) {
+	if p.Sparring() {
+		p.Meter.hit(damage, critical)
+		return
+	}
+	p.Opponent.TakeDamage(DamageHP, damage)
+}
+
+// hasDefeated reports whether the player has brought their PvP opponent down, or would have in a spar
+func (p *Player) hasDefeated(opp *Player) bool {
+	if p.Sparring() {
+		return p.Meter.simHP <= 0
+	}
+	return opp.HP <= 0
+}
+
+// endSpar ends a spar, with neither fighter the worse for it
+func endSpar(winner, loser *Player) {
+	winner.SendType(fmt.Sprintf("You would have defeated %s!", loser.Name), "combat")
+	loser.SendType(fmt.Sprintf("%s would have defeated you!", winner.Name), "combat")
+	sendToOthers(fmt.Sprintf("%s bests %s in a spar.", winner.Name, loser.Name), winner.Room, winner, loser)
+	winner.ExitCombat()
+	loser.ExitCombat()
+	winner.SendStatus()
+	loser.SendStatus()
+}
+
+// handleSpar challenges a player to a spar, answers a challenge, shows the damage meter, or stops practicing
+// Usage: spar [<player>|accept|decline|stop]
+func handleSpar(player *Player, args []string) string {
+	if len(args) == 0 {
+		if player.Meter != nil {
+			return player.Meter.report()
+		}
+		if player.sparChallenger != nil {
+			return fmt.Sprintf("%s has challenged you to a spar. Type 'spar accept' or 'spar decline'.", player.sparChallenger.Name)
+		}
+		return "Usage: spar <player> | spar accept | spar decline | spar stop"
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "accept":
+		return sparAccept(player)
+	case "decline":
+		return sparDecline(player)
+	case "stop":
+		return sparStop(player)
+	}
+
+	if player.IsInCombat() {
+		return "You are already fighting!"
+	}
+	if player.IsDead {
+		return "You can't spar while dead."
+	}
+	target := player.FindVisiblePlayerInRoom(args[0])
+	if target == nil {
+		return "They aren't here."
+	}
+	if target == player {
+		return "You can't spar with yourself. Try a practice dummy."
+	}
+	if target.IsDead || target.IsInCombat() {
+		return fmt.Sprintf("%s is in no state to spar.", target.Name)
+	}
+
+	target.sparChallenger = player
+	target.Send(fmt.Sprintf("{Y}%s challenges you to a spar!{x} Type 'spar accept' or 'spar decline'.", player.Name))
+	sendToOthers(fmt.Sprintf("%s challenges %s to a spar.", player.Name, target.Name), player.Room, player, target)
+	return fmt.Sprintf("You challenge %s to a spar.", target.Name)
+}
+
+// sparAccept starts the spar the player was challenged to
+func sparAccept(player *Player) string {
+	challenger := player.sparChallenger
+	if challenger == nil {
+		return "Nobody has challenged you to a spar."
+	}
+	player.sparChallenger = nil
+
+	if challenger.Room != player.Room || FindPlayerByName(challenger.Name) != challenger {
+		return fmt.Sprintf("%s is no longer here.", challenger.Name)
+	}
+	if player.IsInCombat() || challenger.IsInCombat() || player.IsDead || challenger.IsDead {
+		return "The spar can't go ahead right now."
+	}
+
+	startPvP(challenger, player)
+	challenger.Meter = newDamageMeter(player.HP)
+	player.Meter = newDamageMeter(challenger.HP)
+	challenger.SendType(fmt.Sprintf("%s accepts your challenge. Spar!", player.Name), "combat")
+	sendToOthers(fmt.Sprintf("%s accepts %s's challenge, and they begin to spar.", player.Name, challenger.Name), player.Room, player, challenger)
+	return fmt.Sprintf("You accept %s's challenge. Spar!", challenger.Name)
+}
+
+// sparDecline turns down a spar challenge
+func sparDecline(player *Player) string {
+	challenger := player.sparChallenger
+	if challenger == nil {
+		return "Nobody has challenged you to a spar."
+	}
+	player.sparChallenger = nil
+	challenger.Send(fmt.Sprintf("%s declines your challenge.", player.Name))
+	return fmt.Sprintf("You decline %s's challenge.", challenger.Name)
+}
+
+// sparStop ends the player's spar or their practice on a dummy
+func sparStop(player *Player) string {
+	if player.Meter == nil || !player.IsInCombat() {
+		return "You aren't practicing."
+	}
+
+	if opp := player.Opponent; opp != nil {
+		player.Send(fmt.Sprintf("You lower your guard and end the spar with %s.", opp.Name))
+		if opp.Opponent == player {
+			opp.Send(fmt.Sprintf("%s lowers their guard and ends the spar.", player.Name))
+			opp.ExitCombat()
+		}
+	} else {
+		player.Send(fmt.Sprintf("You step back from %s.", player.Target.ShortDescription))
+		BroadcastCombatMessage(fmt.Sprintf("%s steps back from %s.", player.Name, player.Target.ShortDescription), player.Room, player)
+	}
+	player.ExitCombat()
+	return ""
+}
@@ -0,0 +1,89 @@
+/*
+ * special.go
+ *
+ * This file implements mob special attacks. Instead of a normal swing, a
+ * mob with special attacks may burn away a player's mana or drain their
+ * stamina, leaving them unable to cast or use skills when they need them
+ * most. Special attacks are listed on the mob in its area file and are
+ * checked when the area loads; invalid ones are dropped with a warning.
+ */
+
+package main
+
+import (
+	"fmt"
+)
+
+// Special attack types and the resource each one damages
+var specialAttackDamage = map[string]string{
+	"mana_burn":     DamageMana,
+	"stamina_drain": DamageStamina,
+}
+
+// SpecialAttack is an attack a mob may use in place of a normal swing
+type SpecialAttack struct {
+	Type    string `yaml:"type"`              // mana_burn, stamina_drain, bash, or disarm
+	Chance  int    `yaml:"chance"`            // Percent chance (1-100) to use it instead of swinging
+	Damage  int    `yaml:"damage,omitempty"`  // Amount drained (0 = based on the mob's level)
+	Message string `yaml:"message,omitempty"` // Shown to the victim in place of the default; $n is the mob
+}
+
+// validateSpecialAttack checks a special attack loaded from an area file
+func validateSpecialAttack(special SpecialAttack) error {
+	if _, ok := specialAttackDamage[special.Type]; !ok && special.Type != SpecialBash && special.Type != SpecialDisarm {
+		return fmt.Errorf("unknown type %q", special.Type)
+	}
+	if special.Chance < 1 || special.Chance > 100 {
+		return fmt.Errorf("chance must be between 1 and 100")
+	}
+	if special.Damage < 0 {
+		return fmt.Errorf("damage must not be negative")
+	}
+	return nil
+}
+
+// chooseSpecialAttack picks a special attack to use this round, or nil to swing normally
+func (m *MobInstance) chooseSpecialAttack() *SpecialAttack {
+	for i := range m.SpecialAttacks {
+		if rng.Intn(100) < m.SpecialAttacks[i].Chance {
+			return &m.SpecialAttacks[i]
+		}
+	}
+	return nil
+}
+
+// ReceiveSpecialAttack applies a mob's special attack to the player
+func (p *Player) ReceiveSpecialAttack(attacker *MobInstance, special *SpecialAttack) {
+	if special.Type == SpecialBash || special.Type == SpecialDisarm {
+		p.ReceiveStateAttack(attacker, special)
+		return
+	}
+
+	amount := special.Damage
+	if amount == 0 {
+		amount = CalculateDamage(attacker.Level)
+	}
+	kind := specialAttackDamage[special.Type]
+	drained := p.TakeDamage(kind, amount)
+
+	name := capitalizeFirst(attacker.ShortDescription)
+	var message, roomMessage string
+	switch kind {
+	case DamageMana:
+		message = fmt.Sprintf("%s's touch burns away {B}%d{x} of your mana!", name, drained)
+		roomMessage = fmt.Sprintf("%s's touch burns away %s's mana!", name, p.Name)
+	case DamageStamina:
+		message = fmt.Sprintf("%s's blow leaves you winded, draining {Y}%d{x} stamina!", name, drained)
+		roomMessage = fmt.Sprintf("%s's blow leaves %s winded!", name, p.Name)
+	}
+	if special.Message != "" {
+		message = act(special.Message, attacker.ShortDescription, "", false)
+	}
+	if drained == 0 {
+		message += " You have nothing left to lose."
+	}
+
+	p.SendType(message, "combat")
+	BroadcastCombatMessage(roomMessage, p.Room, p)
+	p.SendVitals()
+}
@@ -0,0 +1,118 @@
+/*
+ * squelch.go
+ *
+ * This file implements the spam squelch for repeated output.
+ * During long fights the same combat or broadcast line can arrive many times
+ * in a row. When a player has squelch enabled, the first copy of a line is
+ * shown as normal and identical copies that follow are counted instead of
+ * printed. As soon as something else is sent to the player, the prompt is
+ * drawn, or the repeats go quiet, the count is written out as a single
+ * summary line such as "Bob hits the rat. (x3)".
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// SquelchQuietPeriod is how long repeats may go unseen before their summary is flushed
+const SquelchQuietPeriod = 3 * time.Second
+
+// SendRepeatable sends a combat or broadcast line, collapsing identical repeats
+func (p *Player) SendRepeatable(message string) {
+	if message == "" {
+		return
+	}
+
+	if !p.SquelchEnabled {
+		p.Send(message)
+		return
+	}
+
+	p.outputMu.Lock()
+	if message == p.lastRepeatable {
+		// Same line as last time; count it instead of printing it
+		p.repeatCount++
+		p.lastRepeatAt = time.Now()
+		p.outputMu.Unlock()
+		return
+	}
+	summary := p.takeRepeatSummary()
+	p.lastRepeatable = message
+	p.lastRepeatAt = time.Now()
+	p.outputMu.Unlock()
+
+	if summary != "" {
+		p.write(summary)
+	}
+	p.write(message)
+}
+
+// FlushRepeats writes out any pending repeat summary and resets the squelch
+func (p *Player) FlushRepeats() {
+	p.outputMu.Lock()
+	summary := p.takeRepeatSummary()
+	p.lastRepeatable = ""
+	p.outputMu.Unlock()
+
+	if summary != "" {
+		p.write(summary)
+	}
+}
+
+// FlushStaleRepeats flushes the repeat summary once the repeats have gone quiet
+func (p *Player) FlushStaleRepeats() {
+	p.outputMu.Lock()
+	stale := p.repeatCount > 0 && time.Since(p.lastRepeatAt) >= SquelchQuietPeriod
+	p.outputMu.Unlock()
+
+	if stale {
+		p.FlushRepeats()
+	}
+}
+
+// takeRepeatSummary builds the "(xN)" line for suppressed repeats and clears the count
+// The caller must hold outputMu.
+func (p *Player) takeRepeatSummary() string {
+	if p.repeatCount == 0 {
+		return ""
+	}
+
+	summary := fmt.Sprintf("%s (x%d)", strings.TrimSuffix(p.lastRepeatable, "\r\n"), p.repeatCount)
+	p.repeatCount = 0
+	return summary
+}
+
+// handleSquelch toggles collapsing of repeated combat and broadcast lines
+func handleSquelch(player *Player, args []string) string {
+	if len(args) == 0 {
+		if player.SquelchEnabled {
+			return "Spam squelch is currently {G}ON{x}. Use 'squelch off' to disable."
+		}
+		return "Spam squelch is currently OFF. Use 'squelch on' to enable."
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		player.SquelchEnabled = true
+		if err := UpdatePlayerSquelch(player.Name, true); err != nil {
+			log.Printf("Error saving squelch preference for %s: %v", player.Name, err)
+			return "Error saving squelch preference. Squelch enabled for this session only."
+		}
+		return "Spam squelch enabled. Repeated lines will be collapsed."
+	case "off":
+		player.FlushRepeats()
+		player.SquelchEnabled = false
+		if err := UpdatePlayerSquelch(player.Name, false); err != nil {
+			log.Printf("Error saving squelch preference for %s: %v", player.Name, err)
+			return "Error saving squelch preference. Squelch disabled for this session only."
+		}
+		return "Spam squelch disabled. Every line will be shown."
+	default:
+		return "Usage: squelch [on|off]"
+	}
+}
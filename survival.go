@@ -0,0 +1,264 @@
+/*
+ * survival.go
+ *
+ * This file implements hunger and thirst. Each tick a player uses up a
+ * little of the food and water they carry inside them, and eating food or
+ * drinking refills them. Drink containers such as waterskins hold a number
+ * of sips and can be refilled at a fountain, which players can also drink
+ * from directly. A starving or parched player regenerates more slowly.
+ * Operators who don't want survival mechanics can turn them off in
+ * config.yml.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Hunger and thirst limits, in ticks
+const (
+	MaxFullness  = 48 // Food or water a player can hold
+	HungryLevel  = 8  // At or below this, the player is warned they're hungry or thirsty
+	FountainSips = 12 // Water gained from one drink at a fountain
+)
+
+// DefaultDrink is what a drink container holds if its liquid isn't set, and what fountains give
+const DefaultDrink = "water"
+
+// survivalEnabled reports whether players grow hungry and thirsty
+func survivalEnabled() bool {
+	return config.Survival.Enabled
+}
+
+// validateProvision explains why food or drink can't be eaten or drunk, or returns nil if it can
+func validateProvision(item *Item) error {
+	switch item.Type {
+	case "food":
+		if item.Nutrition <= 0 {
+			return fmt.Errorf("is food with no nutrition, it won't feed anyone")
+		}
+	case "drink":
+		if item.Sips <= 0 || item.Nutrition <= 0 {
+			return fmt.Errorf("is a drink container with no sips or nutrition, it won't quench anyone")
+		}
+	}
+	return nil
+}
+
+// liquid returns what a drink container holds
+func (i *Item) liquid() string {
+	if i.Liquid == "" {
+		return DefaultDrink
+	}
+	return i.Liquid
+}
+
+// describeFill tells how full a drink container is
+func (i *Item) describeFill() string {
+	switch {
+	case i.SipsLeft <= 0:
+		return "It is empty."
+	case i.SipsLeft >= i.Sips:
+		return fmt.Sprintf("It is full of %s.", i.liquid())
+	case i.SipsLeft*2 > i.Sips:
+		return fmt.Sprintf("It is more than half full of %s.", i.liquid())
+	case i.SipsLeft*2 == i.Sips:
+		return fmt.Sprintf("It is half full of %s.", i.liquid())
+	default:
+		return fmt.Sprintf("It is less than half full of %s.", i.liquid())
+	}
+}
+
+// describeHunger returns how hungry the player is, for the score sheet
+func (p *Player) describeHunger() string {
+	switch {
+	case p.Food == 0:
+		return "Starving"
+	case p.Food <= HungryLevel:
+		return "Hungry"
+	case p.Food < MaxFullness*3/4:
+		return "Satisfied"
+	default:
+		return "Full"
+	}
+}
+
+// describeThirst returns how thirsty the player is, for the score sheet
+func (p *Player) describeThirst() string {
+	switch {
+	case p.Water == 0:
+		return "Parched"
+	case p.Water <= HungryLevel:
+		return "Thirsty"
+	case p.Water < MaxFullness*3/4:
+		return "Satisfied"
+	default:
+		return "Quenched"
+	}
+}
+
+// SurvivalTick uses up some of the player's food and water and warns them when they run low
+func (p *Player) SurvivalTick() {
+	if !survivalEnabled() || p.IsDead || p.HP <= 0 {
+		return
+	}
+
+	p.Food = max(p.Food-config.Survival.HungerPerTick, 0)
+	p.Water = max(p.Water-config.Survival.ThirstPerTick, 0)
+
+	switch {
+	case p.Food == 0:
+		p.Send("{R}You are starving!{x}")
+	case p.Food <= HungryLevel:
+		p.Send("{Y}You are hungry.{x}")
+	}
+	switch {
+	case p.Water == 0:
+		p.Send("{R}You are dying of thirst!{x}")
+	case p.Water <= HungryLevel:
+		p.Send("{Y}You are thirsty.{x}")
+	}
+}
+
+// survivalPenalty reduces a regeneration amount for each of hunger and thirst the player is suffering
+func (p *Player) survivalPenalty(amount int) int {
+	if !survivalEnabled() {
+		return amount
+	}
+	if p.Food == 0 {
+		amount = amount * config.Survival.StarvingRegenPercent / 100
+	}
+	if p.Water == 0 {
+		amount = amount * config.Survival.StarvingRegenPercent / 100
+	}
+	return amount
+}
+
+// LoadSurvival restores how fed and watered the player is
+func (p *Player) LoadSurvival() {
+	p.Food, p.Water = MaxFullness, MaxFullness
+
+	food, water, err := LoadPlayerSurvival(p.Name, MaxFullness)
+	if err != nil {
+		log.Printf("Error loading hunger and thirst for %s: %v", p.Name, err)
+		return
+	}
+	p.Food, p.Water = food, water
+}
+
+// SaveSurvival records how fed and watered the player is
+func (p *Player) SaveSurvival() error {
+	return UpdatePlayerSurvival(p.Name, p.Food, p.Water)
+}
+
+// quench gives the player water and returns any message about being full
+func (p *Player) quench(amount int) string {
+	if !survivalEnabled() {
+		return ""
+	}
+	p.Water = min(p.Water+amount, MaxFullness)
+	if p.Water >= MaxFullness {
+		return "\r\nYou are no longer thirsty."
+	}
+	return ""
+}
+
+// handleEat eats a piece of food the player is carrying
+// Usage: eat <food>
+func handleEat(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Eat what?"
+	}
+	food := FindItemInList(player.Inventory, strings.ToLower(strings.Join(args, " ")))
+	if food == nil {
+		return "You aren't carrying that."
+	}
+	if food.Type != "food" {
+		return fmt.Sprintf("%s isn't food.", capitalizeFirst(food.ShortDescription))
+	}
+	if survivalEnabled() && player.Food >= MaxFullness {
+		return "You are too full to eat any more."
+	}
+
+	player.Inventory = removeItemFromList(player.Inventory, food)
+	BroadcastToRoom(fmt.Sprintf("$n eats %s.", food.ShortDescription), player.Room, player)
+
+	message := fmt.Sprintf("You eat %s.", food.Name())
+	if survivalEnabled() {
+		player.Food = min(player.Food+food.Nutrition, MaxFullness)
+		if player.Food >= MaxFullness {
+			message += "\r\nYou are full."
+		}
+	}
+	return message
+}
+
+// handleDrink drinks from a drink container, or from a fountain in the room
+// Usage: drink [container|fountain]
+func handleDrink(player *Player, args []string) string {
+	target := strings.ToLower(strings.Join(args, " "))
+
+	// Drink from the fountain if there's one here and nothing else was named
+	if player.Room.Fountain && (target == "" || strings.HasPrefix("fountain", target)) {
+		if survivalEnabled() && player.Water >= MaxFullness {
+			return "You aren't thirsty."
+		}
+		BroadcastToRoom("$n drinks from the fountain.", player.Room, player)
+		return "You drink cool water from the fountain." + player.quench(FountainSips)
+	}
+	if target == "" {
+		return "Drink what?"
+	}
+
+	container := FindItemInList(player.Inventory, target)
+	if container == nil {
+		container = FindItemInList(GetItemsInRoom(player.Room), target)
+	}
+	if container == nil {
+		return "You don't see that here."
+	}
+	if container.Type != "drink" {
+		return fmt.Sprintf("You can't drink from %s.", container.ShortDescription)
+	}
+	if container.SipsLeft <= 0 {
+		return fmt.Sprintf("%s is empty.", capitalizeFirst(container.ShortDescription))
+	}
+	if survivalEnabled() && player.Water >= MaxFullness {
+		return "You aren't thirsty."
+	}
+
+	container.SipsLeft--
+	BroadcastToRoom(fmt.Sprintf("$n drinks %s from %s.", container.liquid(), container.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You drink %s from %s.", container.liquid(), container.Name()) + player.quench(container.Nutrition)
+}
+
+// handleFill refills a drink container at a fountain
+// Usage: fill <container>
+func handleFill(player *Player, args []string) string {
+	if len(args) == 0 {
+		return "Fill what?"
+	}
+	container := FindItemInList(player.Inventory, strings.ToLower(strings.Join(args, " ")))
+	if container == nil {
+		return "You aren't carrying that."
+	}
+	if !player.Room.Fountain {
+		return "There is no fountain here."
+	}
+
+	switch {
+	case container.Type != "drink":
+		return fmt.Sprintf("You can't fill %s.", container.ShortDescription)
+	case container.liquid() != DefaultDrink:
+		return fmt.Sprintf("%s is meant for %s, not water.", capitalizeFirst(container.ShortDescription), container.liquid())
+	case container.SipsLeft >= container.Sips:
+		return fmt.Sprintf("%s is already full.", capitalizeFirst(container.ShortDescription))
+	}
+
+	container.SipsLeft = container.Sips
+	BroadcastToRoom(fmt.Sprintf("$n fills %s at the fountain.", container.ShortDescription), player.Room, player)
+	return fmt.Sprintf("You fill %s at the fountain.", container.Name())
+}
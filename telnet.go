@@ -0,0 +1,411 @@
+/*
+ * telnet.go
+ *
+ * This file implements the telnet protocol handling for the MUD.
+ * It defines TelnetConn, a wrapper around a player's network connection
+ * that strips telnet IAC command sequences out of the input stream so they
+ * never reach the line reader, and negotiates telnet options with the
+ * client. The server offers to suppress go-ahead and to speak GMCP, and
+ * asks the client for its window size (NAWS) and terminal type (TTYPE).
+ * Anything else the client proposes is refused, and answers that wouldn't
+ * change an option are never echoed back, so negotiation can't loop. It
+ * also provides helpers for sending option negotiation and subnegotiation
+ * sequences such as GMCP packages.
+ */
+
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// Telnet command bytes
+const (
+	telnetIAC  = 255 // Interpret As Command
+	telnetDONT = 254
+	telnetDO   = 253
+	telnetWONT = 252
+	telnetWILL = 251
+	telnetSB   = 250 // Subnegotiation begin
+	telnetSE   = 240 // Subnegotiation end
+)
+
+// Telnet options
+const (
+	telnetOptSGA   = 3   // Suppress Go-Ahead
+	telnetOptTTYPE = 24  // Terminal Type
+	telnetOptNAWS  = 31  // Negotiate About Window Size
+	telnetOptGMCP  = 201 // Generic MUD Communication Protocol
+)
+
+// TTYPE subnegotiation commands
+const (
+	ttypeIS   = 0
+	ttypeSEND = 1
+)
+
+// MaxSubnegotiation caps the payload buffered between IAC SB and IAC SE, plenty for NAWS, TTYPE, and GMCP
+const MaxSubnegotiation = 1024
+
+// MaxTerminalTypes caps how many terminal types are asked for before giving up on a client that keeps naming new ones
+const MaxTerminalTypes = 4
+
+// Telnet parser states
+const (
+	telnetStateData = iota
+	telnetStateIAC
+	telnetStateOption
+	telnetStateSB
+	telnetStateSBIAC
+	telnetStateDiscard    // Throwing away an overlong subnegotiation until IAC SE
+	telnetStateDiscardIAC // An IAC seen while discarding
+)
+
+// localOptions are the options the server will agree to perform when the client asks
+var localOptions = map[byte]bool{
+	telnetOptSGA:  true,
+	telnetOptGMCP: true,
+}
+
+// remoteOptions are the options the server will let the client perform
+var remoteOptions = map[byte]bool{
+	telnetOptTTYPE: true,
+	telnetOptNAWS:  true,
+}
+
+// optionState tracks one telnet option on one side of the connection
+type optionState struct {
+	enabled bool // The option is in effect
+	asked   bool // We proposed a change and are waiting for the client's answer
+}
+
+// TelnetConn wraps a network connection and filters telnet negotiation out of the input
+type TelnetConn struct {
+	net.Conn
+
+	mu            sync.Mutex
+	local         map[byte]*optionState // Options the server performs (WILL/WONT)
+	remote        map[byte]*optionState // Options the client performs (DO/DONT)
+	width         int                   // Client window size reported through NAWS (0 = unknown)
+	height        int
+	terminalTypes []string    // Terminal types the client reported through TTYPE, in order
+	transcript    *transcript // Captured output while session logging is on
+	snooper       *Player     // Staff member watching this connection (see snoop.go)
+
+	// Parser state
+	state   int
+	command byte   // The negotiation command (DO/DONT/WILL/WONT) being parsed
+	sbData  []byte // Buffered subnegotiation payload
+}
+
+// NewTelnetConn wraps a connection with telnet option handling
+func NewTelnetConn(conn net.Conn) *TelnetConn {
+	return &TelnetConn{
+		Conn:   conn,
+		local:  make(map[byte]*optionState),
+		remote: make(map[byte]*optionState),
+	}
+}
+
+// Negotiate offers the options the server supports and asks for the ones it wants from the client
+func (t *TelnetConn) Negotiate() {
+	t.Offer(telnetOptSGA)
+	t.Offer(telnetOptGMCP)
+	t.Request(telnetOptNAWS)
+	t.Request(telnetOptTTYPE)
+}
+
+// Read reads from the connection, returning only ordinary data bytes
+func (t *TelnetConn) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+
+	for {
+		n, err := t.Conn.Read(buf)
+		out := 0
+		for _, b := range buf[:n] {
+			if t.parseByte(b) {
+				p[out] = b
+				out++
+			}
+		}
+
+		// Keep reading if the chunk held nothing but negotiation
+		if out > 0 || err != nil {
+			return out, err
+		}
+	}
+}
+
+// parseByte advances the telnet state machine, returning true if b is plain data
+func (t *TelnetConn) parseByte(b byte) bool {
+	switch t.state {
+	case telnetStateData:
+		if b == telnetIAC {
+			t.state = telnetStateIAC
+			return false
+		}
+		return true
+
+	case telnetStateIAC:
+		switch b {
+		case telnetIAC:
+			// Escaped 255 is a literal data byte
+			t.state = telnetStateData
+			return true
+		case telnetDO, telnetDONT, telnetWILL, telnetWONT:
+			t.command = b
+			t.state = telnetStateOption
+		case telnetSB:
+			t.sbData = t.sbData[:0]
+			t.state = telnetStateSB
+		default:
+			// Other two-byte commands (NOP, GA, ...) are ignored
+			t.state = telnetStateData
+		}
+		return false
+
+	case telnetStateOption:
+		t.handleNegotiation(t.command, b)
+		t.state = telnetStateData
+		return false
+
+	case telnetStateSB:
+		if b == telnetIAC {
+			t.state = telnetStateSBIAC
+		} else {
+			t.appendSubnegotiation(b)
+		}
+		return false
+
+	case telnetStateSBIAC:
+		switch b {
+		case telnetSE:
+			t.handleSubnegotiation(t.sbData)
+			t.state = telnetStateData
+		case telnetIAC:
+			t.state = telnetStateSB
+			t.appendSubnegotiation(telnetIAC)
+		default:
+			t.state = telnetStateSB
+		}
+		return false
+
+	case telnetStateDiscard:
+		if b == telnetIAC {
+			t.state = telnetStateDiscardIAC
+		}
+		return false
+
+	case telnetStateDiscardIAC:
+		if b == telnetSE {
+			t.state = telnetStateData
+		} else {
+			t.state = telnetStateDiscard
+		}
+		return false
+	}
+
+	return false
+}
+
+// appendSubnegotiation buffers a byte of subnegotiation payload
+// A payload that grows past MaxSubnegotiation is dropped, so a client can't open
+// a subnegotiation and stream bytes into it until the server runs out of memory.
+// The rest of it is discarded up to IAC SE rather than read as input.
+func (t *TelnetConn) appendSubnegotiation(b byte) {
+	if len(t.sbData) >= MaxSubnegotiation {
+		t.sbData = nil
+		t.state = telnetStateDiscard
+		return
+	}
+	t.sbData = append(t.sbData, b)
+}
+
+// stateOf returns the state of an option on one side of the connection, creating it if needed
+// The caller must hold t.mu.
+func stateOf(states map[byte]*optionState, opt byte) *optionState {
+	state, ok := states[opt]
+	if !ok {
+		state = &optionState{}
+		states[opt] = state
+	}
+	return state
+}
+
+// handleNegotiation answers a DO, DONT, WILL, or WONT from the client
+// A request that matches what's already in effect, or answers one of ours, gets no reply.
+func (t *TelnetConn) handleNegotiation(command byte, opt byte) {
+	var states map[byte]*optionState
+	var supported map[byte]bool
+	var yes, no byte
+	switch command {
+	case telnetDO, telnetDONT:
+		states, supported, yes, no = t.local, localOptions, telnetWILL, telnetWONT
+	default:
+		states, supported, yes, no = t.remote, remoteOptions, telnetDO, telnetDONT
+	}
+	enable := command == telnetDO || command == telnetWILL
+
+	t.mu.Lock()
+	state := stateOf(states, opt)
+	asked := state.asked
+	state.asked = false
+	var reply byte
+	switch {
+	case enable && state.enabled, !enable && !state.enabled:
+		// Nothing changes
+	case enable && (asked || supported[opt]):
+		state.enabled = true
+		if !asked {
+			reply = yes
+		}
+	case enable:
+		reply = no // Refuse options we don't support
+	default:
+		state.enabled = false
+		if !asked {
+			reply = no
+		}
+	}
+	enabled := state.enabled
+	t.mu.Unlock()
+
+	if reply != 0 {
+		t.SendCommand(reply, opt)
+	}
+	// Once the client agrees to send its terminal type, ask for it
+	if opt == telnetOptTTYPE && command == telnetWILL && enabled && (asked || reply != 0) {
+		t.SendSubnegotiation(telnetOptTTYPE, []byte{ttypeSEND})
+	}
+}
+
+// handleSubnegotiation processes a completed IAC SB ... IAC SE payload
+func (t *TelnetConn) handleSubnegotiation(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	switch data[0] {
+	case telnetOptNAWS:
+		// NAWS reports the window's width and height as two 16-bit numbers
+		if len(data) >= 5 {
+			t.mu.Lock()
+			t.width = int(data[1])<<8 | int(data[2])
+			t.height = int(data[3])<<8 | int(data[4])
+			t.mu.Unlock()
+		}
+	case telnetOptTTYPE:
+		if len(data) >= 2 && data[1] == ttypeIS {
+			t.addTerminalType(SanitizeText(string(data[2:])))
+		}
+	}
+	// Client-to-server GMCP (Core.Hello, Core.Supports.Set) needs no reply;
+	// other subnegotiations are for options we never agreed to.
+}
+
+// addTerminalType records a terminal type the client reported, and asks for the next
+// Clients that follow MTTS give a new answer each time they're asked (their name, then
+// their terminal, then their capabilities), and repeat the last one when they run out.
+func (t *TelnetConn) addTerminalType(name string) {
+	t.mu.Lock()
+	n := len(t.terminalTypes)
+	repeated := n > 0 && strings.EqualFold(t.terminalTypes[n-1], name)
+	if !repeated && name != "" {
+		t.terminalTypes = append(t.terminalTypes, name)
+	}
+	more := !repeated && name != "" && len(t.terminalTypes) < MaxTerminalTypes
+	t.mu.Unlock()
+
+	if more {
+		t.SendSubnegotiation(telnetOptTTYPE, []byte{ttypeSEND})
+	}
+}
+
+// Offer proposes that the server perform an option, such as IAC WILL GMCP
+func (t *TelnetConn) Offer(opt byte) {
+	t.propose(t.local, opt, telnetWILL)
+}
+
+// Request asks the client to perform an option, such as IAC DO NAWS
+func (t *TelnetConn) Request(opt byte) {
+	t.propose(t.remote, opt, telnetDO)
+}
+
+// propose sends a negotiation to turn an option on, unless it's already on or being asked for
+func (t *TelnetConn) propose(states map[byte]*optionState, opt byte, command byte) {
+	t.mu.Lock()
+	state := stateOf(states, opt)
+	if state.enabled || state.asked {
+		t.mu.Unlock()
+		return
+	}
+	state.asked = true
+	t.mu.Unlock()
+	t.SendCommand(command, opt)
+}
+
+// restoreOption marks an option as already agreed, for a connection carried across a copyover
+func (t *TelnetConn) restoreOption(local bool, opt byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	states := t.remote
+	if local {
+		states = t.local
+	}
+	stateOf(states, opt).enabled = true
+}
+
+// Local reports whether the server is performing an option
+func (t *TelnetConn) Local(opt byte) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.local[opt]
+	return ok && state.enabled
+}
+
+// Remote reports whether the client is performing an option
+func (t *TelnetConn) Remote(opt byte) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.remote[opt]
+	return ok && state.enabled
+}
+
+// GMCPEnabled reports whether the client has agreed to receive GMCP
+func (t *TelnetConn) GMCPEnabled() bool {
+	return t.Local(telnetOptGMCP)
+}
+
+// WindowSize returns the client's window width and height, or zeros if it hasn't said
+func (t *TelnetConn) WindowSize() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.width, t.height
+}
+
+// TerminalTypes returns the terminal types the client has reported, in the order it gave them
+func (t *TelnetConn) TerminalTypes() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.terminalTypes...)
+}
+
+// SendCommand writes a three-byte option negotiation (e.g. IAC WILL GMCP)
+// Negotiation bypasses Write so it never appears in session transcripts.
+func (t *TelnetConn) SendCommand(command byte, option byte) {
+	t.Conn.Write([]byte{telnetIAC, command, option})
+}
+
+// SendSubnegotiation writes IAC SB <option> <payload> IAC SE, escaping any IAC bytes
+func (t *TelnetConn) SendSubnegotiation(option byte, payload []byte) {
+	msg := []byte{telnetIAC, telnetSB, option}
+	for _, b := range payload {
+		if b == telnetIAC {
+			msg = append(msg, telnetIAC)
+		}
+		msg = append(msg, b)
+	}
+	msg = append(msg, telnetIAC, telnetSE)
+	t.Conn.Write(msg)
+}
@@ -14,8 +14,6 @@ package main
 import (
 	"fmt"
 	"log"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -249,27 +247,13 @@ func ResetDoors() {
 	processedDoors := make(map[string]bool)
 
 	// Iterate through all rooms
-	for roomID, room := range rooms {
+	for roomID, room := range allRooms() {
 		// Check each exit for doors
 		for direction, exit := range room.Exits {
 			if exit.Door != nil && !exit.Door.Closed {
 				// Create a unique key for this door connection
-				var destRoomID int
-				switch exitID := exit.ID.(type) {
-				case int:
-					destRoomID = exitID
-				case string:
-					// Handle cross-area references
-					roomInfo := strings.Split(exitID, ":")
-					if len(roomInfo) != 2 {
-						continue
-					}
-					var err error
-					destRoomID, err = strconv.Atoi(roomInfo[1])
-					if err != nil {
-						continue
-					}
-				default:
+				destRoomID, err := ResolveExitRoomID(exit)
+				if err != nil {
 					continue
 				}
 
@@ -0,0 +1,194 @@
+/*
+ * transcript.go
+ *
+ * This file implements per-player session transcripts.
+ * When a player turns logging on, everything written to their connection
+ * is captured with ANSI color and telnet sequences stripped out, along with
+ * the commands they type. Turning logging off (or disconnecting) stores the
+ * transcript in the database, where the player can list and read it back
+ * with the log command.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxTranscriptBytes caps how much output a single transcript may hold
+const MaxTranscriptBytes = 256 * 1024
+
+// ansiEscape matches ANSI color escape sequences
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// TranscriptInfo describes a stored transcript
+type TranscriptInfo struct {
+	ID        int
+	StartedAt time.Time
+	EndedAt   time.Time
+	Size      int
+}
+
+// transcript holds the output captured for a connection while logging is on
+type transcript struct {
+	startedAt time.Time
+	buf       strings.Builder
+	truncated bool
+}
+
+// StartTranscript begins capturing output written to the connection
+func (t *TelnetConn) StartTranscript() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.transcript != nil {
+		return false
+	}
+	t.transcript = &transcript{startedAt: time.Now()}
+	return true
+}
+
+// StopTranscript ends capturing and returns the captured transcript, or nil if none
+func (t *TelnetConn) StopTranscript() *transcript {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tr := t.transcript
+	t.transcript = nil
+	return tr
+}
+
+// Recording reports whether output is currently being captured
+func (t *TelnetConn) Recording() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.transcript != nil
+}
+
+// record appends text to the transcript, stripped of color codes
+func (t *TelnetConn) record(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tr := t.transcript
+	if tr == nil || tr.truncated {
+		return
+	}
+
+	text = ansiEscape.ReplaceAllString(text, "")
+	if tr.buf.Len()+len(text) > MaxTranscriptBytes {
+		tr.buf.WriteString("\r\n[Transcript size limit reached; logging stopped.]\r\n")
+		tr.truncated = true
+		return
+	}
+	tr.buf.WriteString(text)
+}
+
+// Write sends data to the client, capturing it in the transcript if logging is on
+// and passing a copy to anyone snooping the connection
+func (t *TelnetConn) Write(p []byte) (int, error) {
+	if t.Recording() {
+		t.record(string(p))
+	}
+	if snooper := t.Snooper(); snooper != nil {
+		mirror(snooper, string(p))
+	}
+	return t.Conn.Write(p)
+}
+
+// RecordInput adds a line the player typed to their transcript
+func (p *Player) RecordInput(input string) {
+	if tc, ok := p.Conn.(*TelnetConn); ok && tc.Recording() {
+		tc.record(input + "\r\n")
+	}
+}
+
+// FinishTranscript stops logging for a player and stores the transcript
+// Returns the stored transcript ID, or 0 if the player was not logging
+func FinishTranscript(player *Player) (int, error) {
+	tc, ok := player.Conn.(*TelnetConn)
+	if !ok {
+		return 0, nil
+	}
+
+	tr := tc.StopTranscript()
+	if tr == nil {
+		return 0, nil
+	}
+
+	return SaveTranscript(player.Name, tr.startedAt, time.Now(), tr.buf.String())
+}
+
+// handleLog turns session logging on or off and lets players read old transcripts
+// Usage: log [on|off|list|show <id>]
+func handleLog(player *Player, args []string) string {
+	tc, ok := player.Conn.(*TelnetConn)
+	if !ok {
+		return "Session logging is not available on this connection."
+	}
+
+	if len(args) == 0 {
+		if tc.Recording() {
+			return "Session logging is {G}ON{x}. Use 'log off' to stop and save the transcript."
+		}
+		return "Session logging is OFF. Use 'log on' to start recording this session."
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		if !tc.StartTranscript() {
+			return "You are already logging this session."
+		}
+		return "Session logging enabled. Everything you see from now on will be recorded."
+
+	case "off":
+		id, err := FinishTranscript(player)
+		if err != nil {
+			log.Printf("Error saving transcript for %s: %v", player.Name, err)
+			return "Error saving your transcript."
+		}
+		if id == 0 {
+			return "You are not logging this session."
+		}
+		return fmt.Sprintf("Session logging disabled. Transcript #%d saved; use 'log show %d' to read it.", id, id)
+
+	case "list":
+		transcripts, err := ListTranscripts(player.Name)
+		if err != nil {
+			log.Printf("Error listing transcripts for %s: %v", player.Name, err)
+			return "Error retrieving your transcripts."
+		}
+		if len(transcripts) == 0 {
+			return "You have no saved transcripts."
+		}
+		var sb strings.Builder
+		sb.WriteString("{Y}Your saved transcripts:{x}\r\n")
+		for _, t := range transcripts {
+			sb.WriteString(fmt.Sprintf("  #%-4d %s - %s  (%s bytes)\r\n",
+				t.ID, player.Locale.DateTime(t.StartedAt), player.Locale.Time(t.EndedAt), player.Locale.Number(t.Size)))
+		}
+		return strings.TrimSuffix(sb.String(), "\r\n")
+
+	case "show":
+		if len(args) < 2 {
+			return "Usage: log show <id>"
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil {
+			return "Usage: log show <id>"
+		}
+		content, err := LoadTranscript(player.Name, id)
+		if err != nil {
+			return fmt.Sprintf("You have no transcript #%d.", id)
+		}
+		return fmt.Sprintf("{Y}Transcript #%d:{x}\r\n%s", id, content)
+
+	default:
+		return "Usage: log [on|off|list|show <id>]"
+	}
+}
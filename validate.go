@@ -0,0 +1,126 @@
+/*
+ * validate.go
+ *
+ * This file reports the problems found in area files as they load. Each
+ * problem is logged with the file and line it was found on, such as
+ * "areas/midgaard.yml:1102", so builders can go straight to it. Loading
+ * carries on past problems where it can, dropping or defaulting whatever
+ * is broken, and the problems are counted. Running the server with
+ * -validate-only loads the areas, reports what it found, and exits with a
+ * failing status if anything was wrong, without starting the game.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// areaIndexDepth is how deep into an area file lines are indexed, enough to reach a room's exits
+const areaIndexDepth = 4
+
+var (
+	areaProblems int                               // Problems found while loading areas
+	areaLines    = make(map[string]map[string]int) // Line each part of an area file starts on, by file and path such as "rooms.3001.exits.north"
+)
+
+// indexAreaLines records the line each part of an area file starts on
+func indexAreaLines(file string, root *yaml.Node) {
+	lines := make(map[string]int)
+	var walk func(node *yaml.Node, path string, depth int)
+	walk = func(node *yaml.Node, path string, depth int) {
+		if depth > areaIndexDepth {
+			return
+		}
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, path, depth)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				childPath := strings.TrimPrefix(path+"."+key.Value, ".")
+				lines[childPath] = key.Line
+				walk(node.Content[i+1], childPath, depth+1)
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				childPath := path + "." + strconv.Itoa(i)
+				lines[childPath] = child.Line
+				walk(child, childPath, depth+1)
+			}
+		}
+	}
+	walk(root, "", 0)
+	areaLines[file] = lines
+}
+
+// areaLine returns the line a part of an area file starts on, or that of the nearest part containing it
+func areaLine(file, path string) int {
+	lines := areaLines[file]
+	for path != "" {
+		if line, ok := lines[path]; ok {
+			return line
+		}
+		dot := strings.LastIndex(path, ".")
+		if dot < 0 {
+			break
+		}
+		path = path[:dot]
+	}
+	return 0
+}
+
+// areaProblem logs a problem with an area file, pointing at the line of the part it concerns
+func areaProblem(file, path, format string, args ...interface{}) {
+	areaProblems++
+	location := filepath.Join(AreaDir, file)
+	if line := areaLine(file, path); line > 0 {
+		location += ":" + strconv.Itoa(line)
+	}
+	log.Printf("[WARNING] %s: %s", location, fmt.Sprintf(format, args...))
+}
+
+// validateMobResets reports resets that name a mob or room that doesn't exist, or can never spawn anything
+func validateMobResets() {
+	for file, area := range allAreas() {
+		validateAreaResets(file, area)
+	}
+}
+
+// validateAreaResets reports the problems with one area's resets
+func validateAreaResets(file string, area *Area) {
+	for i, reset := range area.MobResets {
+		path := "mob_resets." + strconv.Itoa(i)
+		if mobRegistry[reset.MobVnum] == nil {
+			areaProblem(file, path, "Reset for mob %d names a mob that doesn't exist", reset.MobVnum)
+		}
+		if allRooms()[reset.RoomVnum] == nil {
+			areaProblem(file, path, "Reset for mob %d places it in room %d, which doesn't exist", reset.MobVnum, reset.RoomVnum)
+		}
+		if reset.MaxWorld < 1 {
+			areaProblem(file, path, "Reset for mob %d has a max_world below 1, so it never spawns", reset.MobVnum)
+		}
+	}
+}
+
+// ValidateAreas loads the areas to report their problems, returning the status the server should exit with
+func ValidateAreas() int {
+	if err := LoadAreas(); err != nil {
+		log.Printf("Error loading areas: %v", err)
+		return 1
+	}
+	if areaProblems > 0 {
+		fmt.Printf("Found %d problems in the area files.\n", areaProblems)
+		return 1
+	}
+	fmt.Printf("No problems found in %d area files.\n", len(allAreas()))
+	return 0
+}
@@ -0,0 +1,147 @@
+/*
+ * visibility.go
+ *
+ * This file implements the rules for who can see whom. An invisible player
+ * is left out of room listings, arrival and departure messages, scans, and
+ * the who list, and can't be singled out by other players or by mobs,
+ * unless the observer can detect invisibility. Staff always see everyone
+ * except staff of higher trust hiding with 'invis', who are hidden this way
+ * from every player and mob of lower trust.
+ * Display and targeting code should go through CanSeePlayer rather than
+ * listing every player in a room.
+ *
+ * Detection affects layer on top of this: detect hidden reveals hidden
+ * exits, detect magic marks magical items, and detect evil marks evil
+ * mobs in room listings, look, examine, and scan.
+ */
+
+package main
+
+import (
+	"strings"
+)
+
+// Affects that change visibility
+const (
+	AffectInvisible    = "invisibility"
+	AffectDetectInvis  = "detect invisibility"
+	AffectDetectHidden = "detect hidden"
+	AffectDetectMagic  = "detect magic"
+	AffectDetectEvil   = "detect evil"
+)
+
+// IsInvisible reports whether the player is invisible
+func (p *Player) IsInvisible() bool {
+	return p.Affects.Find(AffectInvisible) != nil
+}
+
+// CanDetectInvisible reports whether the player can see invisible players
+func (p *Player) CanDetectInvisible() bool {
+	return p.Staff || p.Affects.Find(AffectDetectInvis) != nil
+}
+
+// CanSeePlayer reports whether the viewer can see the target player
+func (p *Player) CanSeePlayer(target *Player) bool {
+	if p == target {
+		return true
+	}
+	if target.WizInvis && p.Trust < target.Trust {
+		return false
+	}
+	if !target.IsInvisible() {
+		return true
+	}
+	return p.CanDetectInvisible()
+}
+
+// SeenName returns the player's name as the viewer knows it: their name, or "someone" if the viewer can't see them
+func (p *Player) SeenName(viewer *Player) string {
+	if viewer.CanSeePlayer(p) {
+		return p.Name
+	}
+	return "someone"
+}
+
+// actorMessage fills in each $n in a message with the actor's name as the viewer sees it
+// A name that starts the message, after any color codes, is capitalized.
+func actorMessage(message string, actor, viewer *Player) string {
+	name := actor.SeenName(viewer)
+	var sb strings.Builder
+	for {
+		i := strings.Index(message, "$n")
+		if i < 0 {
+			break
+		}
+		sb.WriteString(message[:i])
+		if visibleWidth(sb.String()) == 0 {
+			sb.WriteString(capitalizeFirst(name))
+		} else {
+			sb.WriteString(name)
+		}
+		message = message[i+2:]
+	}
+	sb.WriteString(message)
+	return sb.String()
+}
+
+// MobCanSeePlayer reports whether a mob can see the player
+// Mobs have no way to detect invisibility.
+func MobCanSeePlayer(mob *MobInstance, target *Player) bool {
+	return !target.IsInvisible() && !target.WizInvis
+}
+
+// VisiblePlayersInRoom returns the players in a room the viewer can see, excluding the viewer
+func (p *Player) VisiblePlayersInRoom(room *Room) []*Player {
+	var visible []*Player
+	for _, other := range playersInRoom(room) {
+		if other != p && p.CanSeePlayer(other) {
+			visible = append(visible, other)
+		}
+	}
+	return visible
+}
+
+// FindVisiblePlayerInRoom looks up a player by name in the viewer's room, ignoring anyone the viewer can't see
+func (p *Player) FindVisiblePlayerInRoom(name string) *Player {
+	for _, other := range playersInRoom(p.Room) {
+		if strings.EqualFold(other.Name, name) && p.CanSeePlayer(other) {
+			return other
+		}
+	}
+	return nil
+}
+
+// BecomeVisible ends the player's invisibility, such as when they attack
+func (p *Player) BecomeVisible() {
+	if p.RemoveAffect(AffectInvisible) {
+		BroadcastToRoom("$n fades into existence.", p.Room, p)
+	}
+}
+
+// CanDetect reports whether the player has the given detection affect
+// Staff detect everything.
+func (p *Player) CanDetect(affect string) bool {
+	return p.Staff || p.Affects.Find(affect) != nil
+}
+
+// CanSeeExit reports whether the player can see an exit, which hidden exits require detect hidden for
+// Hidden exits can still be used by anyone who knows they're there.
+func (p *Player) CanSeeExit(exit *Exit) bool {
+	return exit != nil && (!exit.Hidden || p.CanDetect(AffectDetectHidden))
+}
+
+// MobAura returns the tag shown before an evil mob to players who can detect evil
+func (p *Player) MobAura(mob *MobInstance) string {
+	if mob.Evil && p.CanDetect(AffectDetectEvil) {
+		return "{R}(Red Aura){x} "
+	}
+	return ""
+}
+
+// ItemAura returns the tag shown before a magical item to players who can detect magic
+func (p *Player) ItemAura(item *Item) string {
+	if item.Magic && p.CanDetect(AffectDetectMagic) {
+		return "{B}(Magical){x} "
+	}
+	return ""
+}
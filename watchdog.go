@@ -0,0 +1,247 @@
+/*
+ * watchdog.go
+ *
+ * This file implements the anti-cheat watchdog. It keeps an eye on each
+ * player who isn't staff and flags behavior no honest player should be
+ * capable of: sending commands faster than anyone can type, trying the
+ * staff teleport commands, and gaining XP far faster than the game hands it
+ * out. botcheck.go adds commands sent with a script's regularity. Each flag is saved for staff to review with the 'watchdog' command
+ * and shown to the staff online. A player is flagged for the same kind of
+ * behavior at most once per report interval; anything caught in between
+ * is counted and folded into the next flag so the log isn't flooded.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of suspicious behavior the watchdog flags
+const (
+	WatchSpeed    = "speed"    // Commands sent faster than humanly possible
+	WatchTeleport = "teleport" // Staff teleport commands tried by a player without the trust for them
+	WatchXP       = "xp"       // XP gained far faster than normal
+)
+
+// watchdogListSize is how many flags the 'watchdog' command shows
+const watchdogListSize = 20
+
+// teleportCommands are the staff commands that move players around the world
+var teleportCommands = map[string]bool{
+	"goto":     true,
+	"transfer": true,
+}
+
+// xpGain is XP the player gained and when
+type xpGain struct {
+	at     time.Time
+	amount int
+}
+
+// watchState is what the watchdog remembers about a player this session
+type watchState struct {
+	mu           sync.Mutex
+	commandTimes []time.Time          // When each command in the last second arrived
+	xpGains      []xpGain             // XP gained in the last minute
+	lastFlagged  map[string]time.Time // When each kind of behavior was last flagged
+	unreported   map[string]int       // Times each kind was caught since it was last flagged
+	lastCommand  time.Time            // When the last command arrived
+	intervals    []time.Duration      // Time between each of the last commands (see botcheck.go)
+	check        *botCheck            // Bot check waiting for an answer
+}
+
+// WatchdogFlag is a suspicious action saved for staff review
+type WatchdogFlag struct {
+	ID        int
+	Player    string
+	Kind      string
+	Detail    string
+	Count     int // Times it was caught, including any held back by the report interval
+	CreatedAt time.Time
+}
+
+// watched reports whether the watchdog checks this player
+func (p *Player) watched() bool {
+	return config.Watchdog.Enabled && !p.Staff
+}
+
+// WatchCommand flags the player if they've sent more commands in the last second than anyone can type
+// It also looks for runs of commands sent as regularly as clockwork, in botcheck.go.
+func (p *Player) WatchCommand() {
+	if !p.watched() {
+		return
+	}
+
+	now := time.Now()
+	p.watchRhythm(now)
+	if config.Watchdog.CommandsPerSecond == 0 {
+		return
+	}
+
+	p.watch.mu.Lock()
+	recent := p.watch.commandTimes[:0]
+	for _, t := range p.watch.commandTimes {
+		if now.Sub(t) < time.Second {
+			recent = append(recent, t)
+		}
+	}
+	p.watch.commandTimes = append(recent, now)
+	count := len(p.watch.commandTimes)
+	p.watch.mu.Unlock()
+
+	if count > config.Watchdog.CommandsPerSecond {
+		p.Flag(WatchSpeed, fmt.Sprintf("sent %d commands in one second", count))
+	}
+}
+
+// WatchXP flags the player if they've gained more levels' worth of XP in the last minute than allowed
+func (p *Player) WatchXP(amount int) {
+	if !p.watched() || config.Watchdog.XPLevelsPerMinute == 0 {
+		return
+	}
+
+	now := time.Now()
+	p.watch.mu.Lock()
+	recent := p.watch.xpGains[:0]
+	total := amount
+	for _, g := range p.watch.xpGains {
+		if now.Sub(g.at) < time.Minute {
+			recent = append(recent, g)
+			total += g.amount
+		}
+	}
+	p.watch.xpGains = append(recent, xpGain{at: now, amount: amount})
+	p.watch.mu.Unlock()
+
+	if total > config.Watchdog.XPLevelsPerMinute*p.NextLevelXP {
+		p.Flag(WatchXP, fmt.Sprintf("gained %d XP in one minute at level %d", total, p.Level))
+	}
+}
+
+// WatchRefused flags a player who tried a staff teleport command they don't have the trust for
+func (p *Player) WatchRefused(command string) {
+	if !p.watched() || !teleportCommands[command] {
+		return
+	}
+	p.Flag(WatchTeleport, fmt.Sprintf("tried to use %s", command))
+}
+
+// Flag records suspicious behavior for staff review, at most once per report interval for each kind
+func (p *Player) Flag(kind, detail string) {
+	now := time.Now()
+	interval := time.Duration(config.Watchdog.ReportInterval) * time.Second
+
+	p.watch.mu.Lock()
+	if p.watch.lastFlagged == nil {
+		p.watch.lastFlagged = make(map[string]time.Time)
+		p.watch.unreported = make(map[string]int)
+	}
+	p.watch.unreported[kind]++
+	if now.Sub(p.watch.lastFlagged[kind]) < interval {
+		p.watch.mu.Unlock()
+		return
+	}
+	count := p.watch.unreported[kind]
+	p.watch.unreported[kind] = 0
+	p.watch.lastFlagged[kind] = now
+	p.watch.mu.Unlock()
+
+	if err := SaveWatchdogFlag(p.Name, kind, detail, count, now); err != nil {
+		log.Printf("Error saving watchdog flag for %s: %v", p.Name, err)
+	}
+
+	if count > 1 {
+		detail += fmt.Sprintf(" (x%d)", count)
+	}
+	log.Printf("Watchdog flagged %s for %s: %s", p.Name, kind, detail)
+	notice := fmt.Sprintf("\r\n{R}[Watchdog]{x} %s %s.", p.Name, detail)
+	for _, staff := range onlineStaff() {
+		staff.Send(notice)
+	}
+}
+
+// onlineStaff returns the staff members who are logged in
+func onlineStaff() []*Player {
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+
+	var staff []*Player
+	for _, p := range activePlayers {
+		if p.Staff {
+			staff = append(staff, p)
+		}
+	}
+	return staff
+}
+
+// handleWatchdog lists the suspicious actions the watchdog has flagged, clears a player's, or checks one for botting (staff only)
+// Usage: watchdog [player], watchdog clear <player>, watchdog check <player>
+func handleWatchdog(player *Player, args []string) string {
+	if len(args) > 0 && strings.EqualFold(args[0], "check") {
+		if len(args) < 2 {
+			return "Usage: watchdog check <player>"
+		}
+		target := FindPlayerByName(args[1])
+		if target == nil {
+			return fmt.Sprintf("%s isn't online.", capitalizeFirst(args[1]))
+		}
+		if target.Staff {
+			return "Staff aren't checked."
+		}
+		target.StartBotCheck()
+		log.Printf("%s put a bot check to %s", player.Name, target.Name)
+		return fmt.Sprintf("You ask %s to prove they're at the keyboard.", target.Name)
+	}
+
+	if len(args) > 0 && strings.EqualFold(args[0], "clear") {
+		if len(args) < 2 {
+			return "Usage: watchdog clear <player>"
+		}
+		cleared, err := ClearWatchdogFlags(args[1])
+		if err != nil {
+			log.Printf("Error clearing watchdog flags: %v", err)
+			return "Error clearing watchdog flags."
+		}
+		if cleared == 0 {
+			return fmt.Sprintf("No flags are recorded for %s.", args[1])
+		}
+		log.Printf("%s cleared %d watchdog flags for %s", player.Name, cleared, args[1])
+		return fmt.Sprintf("Cleared %d flags for %s.", cleared, args[1])
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	flags, err := LoadWatchdogFlags(name, watchdogListSize)
+	if err != nil {
+		log.Printf("Error loading watchdog flags: %v", err)
+		return "Error loading watchdog flags."
+	}
+	if len(flags) == 0 {
+		if name != "" {
+			return fmt.Sprintf("No flags are recorded for %s.", name)
+		}
+		return "The watchdog hasn't flagged anyone."
+	}
+
+	var sb strings.Builder
+	if name != "" {
+		sb.WriteString(fmt.Sprintf("{Y}Watchdog flags for %s{x}\r\n", name))
+	} else {
+		sb.WriteString("{Y}Recent watchdog flags{x}\r\n")
+	}
+	for _, f := range flags {
+		sb.WriteString(fmt.Sprintf("%4d  %s  %-12s {R}%-8s{x} %s", f.ID, f.CreatedAt.Format("2006-01-02 15:04"), f.Player, f.Kind, f.Detail))
+		if f.Count > 1 {
+			sb.WriteString(fmt.Sprintf(" (x%d)", f.Count))
+		}
+		sb.WriteString("\r\n")
+	}
+	return strings.TrimRight(sb.String(), "\r\n")
+}
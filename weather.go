@@ -0,0 +1,195 @@
+/*
+ * weather.go
+ *
+ * This file implements the weather and the terrain, or sector, of rooms.
+ * The sky over the world drifts between clear, cloudy, rainy, and stormy,
+ * changing a step at a time on the game tick, and players outside are told
+ * when it turns. Each room has a sector, set on the room or inherited from
+ * its area. Bad weather and rough terrain make it harder to land a blow
+ * outdoors, and sheltering indoors speeds up regeneration. The 'weather'
+ * command shows the sky and any modifiers in effect where the player is.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Room sectors
+const (
+	SectorInside   = "inside"
+	SectorCity     = "city"
+	SectorField    = "field"
+	SectorForest   = "forest"
+	SectorHills    = "hills"
+	SectorMountain = "mountain"
+	SectorWater    = "water"
+)
+
+// validSectors lists the sectors rooms may use
+var validSectors = map[string]bool{
+	SectorInside: true, SectorCity: true, SectorField: true, SectorForest: true,
+	SectorHills: true, SectorMountain: true, SectorWater: true,
+}
+
+// Weather is the state of the sky over the world
+type Weather int
+
+// Weather states, from fairest to foulest
+const (
+	WeatherClear Weather = iota
+	WeatherCloudy
+	WeatherRain
+	WeatherStorm
+)
+
+// WeatherChangeChance is the percent chance per tick that the weather changes
+const WeatherChangeChance = 25
+
+// IndoorRegenBonus is the percent extra HP, mana, and stamina regenerated indoors
+const IndoorRegenBonus = 50
+
+// weatherHitPenalty is taken off the chance to hit when fighting outdoors in bad weather
+var weatherHitPenalty = map[Weather]float64{
+	WeatherRain:  0.05,
+	WeatherStorm: 0.10,
+}
+
+// sectorHitPenalty is taken off the chance to hit when fighting on rough terrain
+var sectorHitPenalty = map[string]float64{
+	SectorMountain: 0.05,
+	SectorWater:    0.10,
+}
+
+// weatherDescriptions describe the sky to players outdoors
+var weatherDescriptions = map[Weather]string{
+	WeatherClear:  "The sky is clear.",
+	WeatherCloudy: "The sky is cloudy.",
+	WeatherRain:   "Rain is falling steadily.",
+	WeatherStorm:  "A storm rages overhead, lashing the land with rain.",
+}
+
+// weatherNames are short names for the weather, for prompts and GMCP
+var weatherNames = map[Weather]string{
+	WeatherClear:  "clear",
+	WeatherCloudy: "cloudy",
+	WeatherRain:   "rain",
+	WeatherStorm:  "storm",
+}
+
+// weatherWorsens are shown to players outdoors when the weather turns fouler, keyed by the new weather
+var weatherWorsens = map[Weather]string{
+	WeatherCloudy: "{D}Clouds roll in and cover the sky.{x}",
+	WeatherRain:   "{B}It starts to rain.{x}",
+	WeatherStorm:  "{B}Lightning flashes as a storm breaks overhead.{x}",
+}
+
+// weatherClears are shown to players outdoors when the weather turns fairer, keyed by the new weather
+var weatherClears = map[Weather]string{
+	WeatherClear:  "{Y}The clouds part and the sun shines through.{x}",
+	WeatherCloudy: "{D}The rain stops.{x}",
+	WeatherRain:   "{B}The storm dies down to a steady rain.{x}",
+}
+
+var (
+	currentWeather = WeatherClear
+	weatherMutex   sync.RWMutex
+)
+
+// CurrentWeather returns the weather over the world
+func CurrentWeather() Weather {
+	weatherMutex.RLock()
+	defer weatherMutex.RUnlock()
+	return currentWeather
+}
+
+// String returns the short name of the weather, such as "rain"
+func (w Weather) String() string {
+	return weatherNames[w]
+}
+
+// skyWeather returns the short name of the weather the player can see, or "indoors"
+func (p *Player) skyWeather() string {
+	if p.Room != nil && p.Room.IsIndoors() {
+		return "indoors"
+	}
+	return CurrentWeather().String()
+}
+
+// IsIndoors reports whether a room is sheltered from the weather
+func (r *Room) IsIndoors() bool {
+	return r.Sector == SectorInside
+}
+
+// CombatHitPenalty returns the chance to hit lost to the weather and terrain of a room
+func CombatHitPenalty(room *Room) float64 {
+	if room == nil {
+		return 0
+	}
+	penalty := sectorHitPenalty[room.Sector]
+	if !room.IsIndoors() {
+		penalty += weatherHitPenalty[CurrentWeather()]
+	}
+	return penalty
+}
+
+// regenBonus adds the indoor bonus to a regeneration amount
+func regenBonus(room *Room, amount int) int {
+	if room != nil && room.IsIndoors() {
+		return amount + amount*IndoorRegenBonus/100
+	}
+	return amount
+}
+
+// ProcessWeather gives the weather a chance to change each tick
+func ProcessWeather() {
+	if rng.Intn(100) >= WeatherChangeChance {
+		return
+	}
+
+	weatherMutex.Lock()
+	old := currentWeather
+	switch {
+	case currentWeather == WeatherClear:
+		currentWeather++
+	case currentWeather == WeatherStorm:
+		currentWeather--
+	case rng.Intn(2) == 0:
+		currentWeather++
+	default:
+		currentWeather--
+	}
+	now := currentWeather
+	weatherMutex.Unlock()
+
+	message := weatherClears[now]
+	if now > old {
+		message = weatherWorsens[now]
+	}
+
+	playersMutex.Lock()
+	defer playersMutex.Unlock()
+	for _, p := range activePlayers {
+		if p.Room != nil && !p.Room.IsIndoors() {
+			p.Send(message)
+		}
+		p.SendVitals() // Status bars show the weather
+	}
+}
+
+// handleWeather describes the sky and how the weather and terrain affect the player
+func handleWeather(player *Player, args []string) string {
+	if player.Room.IsIndoors() {
+		return fmt.Sprintf("You can't see the sky from in here.\r\nSheltering indoors, you recover %d%% faster.", IndoorRegenBonus)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(weatherDescriptions[CurrentWeather()])
+	if penalty := CombatHitPenalty(player.Room); penalty > 0 {
+		sb.WriteString(fmt.Sprintf("\r\nThe conditions here take %.0f%% off everyone's chance to hit.", penalty*100))
+	}
+	return sb.String()
+}
@@ -0,0 +1,268 @@
+/*
+ * websocket.go
+ *
+ * This file implements the WebSocket listener that lets players connect from
+ * a web browser without a telnet client. It serves a small xterm.js based
+ * client page and upgrades requests on /ws to WebSocket connections using
+ * only the standard library. Each WebSocket is wrapped in a WebSocketConn
+ * that satisfies net.Conn, so browser sessions run through exactly the same
+ * login and command loop as telnet sessions. Game output, including ANSI
+ * color codes, is passed through untouched in binary frames for xterm.js
+ * to render. Browsers may only open the socket from a page on the same host,
+ * or from a site the listener's origins allow.
+ */
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WebClientPath is the browser client page served at the site root
+const WebClientPath = "web/index.html"
+
+// MaxWebSocketFrame is the largest frame payload accepted from a client
+const MaxWebSocketFrame = 64 * 1024
+
+// websocketGUID is the fixed GUID from RFC 6455 used to compute Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// WebSocketConn adapts a WebSocket connection to the net.Conn interface
+type WebSocketConn struct {
+	net.Conn
+
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	pending []byte // Payload bytes received but not yet returned by Read
+	closed  bool
+}
+
+// ServeWebSocket serves the browser client and accepts WebSocket connections on the listener
+// origins lists the other sites allowed to open a connection.
+func ServeWebSocket(listener net.Listener, origins []string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, WebClientPath)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if !allowedOrigin(r, origins) {
+			log.Printf("Refused a WebSocket connection from %s: origin %s isn't allowed", r.RemoteAddr, r.Header.Get("Origin"))
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+		handleWebSocket(w, r)
+	})
+	mux.HandleFunc(sheetPath, handleSheetRequest)
+
+	if err := http.Serve(listener, mux); err != nil {
+		log.Printf("Error serving the web client on %s: %v", listener.Addr(), err)
+	}
+}
+
+// allowedOrigin reports whether the page that made the request may connect
+// Requests without an Origin come from clients other than browsers and are allowed.
+func allowedOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWebSocket performs the WebSocket handshake and starts a game session
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		http.Error(w, "Expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+
+	release, refusal := admitConnection(hostIP(r.RemoteAddr))
+	if refusal != "" {
+		log.Printf("Refused a WebSocket connection from %s: %s", r.RemoteAddr, refusal)
+		http.Error(w, refusal, http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSockets are not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("Error hijacking WebSocket connection: %v", err)
+		return
+	}
+
+	// Complete the handshake
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(hash[:])
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	handleConnection(&WebSocketConn{Conn: conn, reader: rw.Reader})
+}
+
+// Read returns the payload of data frames, answering control frames as they arrive
+func (ws *WebSocketConn) Read(p []byte) (int, error) {
+	for len(ws.pending) == 0 {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, err
+		}
+
+		switch opcode {
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			ws.pending = payload
+		case wsOpPing:
+			ws.writeFrame(wsOpPong, payload)
+		case wsOpClose:
+			ws.writeFrame(wsOpClose, nil)
+			ws.writeMu.Lock()
+			ws.closed = true
+			ws.writeMu.Unlock()
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, ws.pending)
+	ws.pending = ws.pending[n:]
+	return n, nil
+}
+
+// readFrame reads a single frame from the client and unmasks its payload
+func (ws *WebSocketConn) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > MaxWebSocketFrame {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+
+	// Clients must mask every frame they send
+	if !masked {
+		return 0, nil, errors.New("unmasked websocket frame from client")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
+
+// Write sends game output to the browser as a binary frame
+func (ws *WebSocketConn) Write(p []byte) (int, error) {
+	if err := ws.writeFrame(wsOpBinary, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFrame writes a single unmasked, unfragmented frame
+func (ws *WebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closed {
+		return net.ErrClosed
+	}
+
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	_, err := ws.Conn.Write(frame)
+	return err
+}
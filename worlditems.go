@@ -0,0 +1,121 @@
+/*
+ * worlditems.go
+ *
+ * This file keeps the items lying in rooms across a reboot. When the
+ * server shuts down or performs a copyover, every item on the floor is
+ * written to the database along with the contents of containers and the
+ * corpses of players, so nobody's belongings vanish with the old process.
+ * The new process puts them back where they were on boot. Mob corpses are
+ * left behind, since they would rot within minutes anyway.
+ *
+ * To keep floors from filling up over many reboots, restored items decay:
+ * anything that wasn't already rotting is given WorldItemBootDecayTicks to
+ * be picked up before it crumbles to dust.
+ */
+
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// WorldItemBootDecayTicks is how long a restored item lasts unless someone picks it up
+const WorldItemBootDecayTicks = 60
+
+// keepOnReboot reports whether an item lying in a room is saved across a reboot
+func keepOnReboot(item *Item) bool {
+	// Generated items have no template; only players' corpses can be rebuilt
+	return item.ID != 0 || (item.Type == "corpse" && item.Owner != "")
+}
+
+// SaveRoomItems stores the items lying in rooms so they survive a reboot
+func SaveRoomItems() {
+	rooms := make(map[int][]*Item)
+	count := 0
+
+	itemMutex.RLock()
+	for roomID, items := range roomItems {
+		for _, item := range items {
+			if keepOnReboot(item) {
+				rooms[roomID] = append(rooms[roomID], item)
+				count++
+			}
+		}
+	}
+	itemMutex.RUnlock()
+
+	if err := SaveWorldItems(rooms); err != nil {
+		log.Printf("Error saving room items: %v", err)
+		return
+	}
+	log.Printf("Saved %d room items", count)
+}
+
+// RestoreRoomItems puts back the items saved at the last shutdown
+func RestoreRoomItems() {
+	saved, err := LoadWorldItems()
+	if err != nil {
+		log.Printf("Error loading room items: %v", err)
+		return
+	}
+
+	// The saved items are back in the world now; clearing them keeps a
+	// crash before the next shutdown from restoring them a second time
+	if err := ClearWorldItems(); err != nil {
+		log.Printf("Error clearing saved room items: %v", err)
+	}
+
+	containers := make(map[int]*Item)
+	count := 0
+	for _, s := range saved {
+		item, err := restoreWorldItem(s)
+		if err != nil {
+			log.Printf("[WARNING] Skipping saved item %d in room %d: %v", s.Vnum, s.RoomID, err)
+			continue
+		}
+		containers[s.ID] = item
+
+		if s.Container != 0 {
+			// Contents of a container that couldn't be restored are lost with it
+			if container := containers[s.Container]; container != nil {
+				container.Contents = append(container.Contents, item)
+			}
+			continue
+		}
+
+		room, err := GetRoom(s.RoomID)
+		if err != nil {
+			log.Printf("[WARNING] Skipping saved items in missing room %d", s.RoomID)
+			continue
+		}
+		if item.Timer == 0 {
+			item.Timer = WorldItemBootDecayTicks
+		}
+		AddItemToRoom(item, room)
+		count++
+	}
+	log.Printf("Restored %d room items", count)
+}
+
+// restoreWorldItem rebuilds a saved item from its template, or a player's corpse
+func restoreWorldItem(s SavedWorldItem) (*Item, error) {
+	var item *Item
+	if s.Vnum == 0 && s.Owner != "" {
+		item = newCorpse(s.Owner, []string{strings.ToLower(s.Owner)}, PlayerCorpseDecayTicks)
+		item.Owner = s.Owner
+		if s.Timer > 0 {
+			item.Timer = s.Timer
+			item.setCorpseStage(corpseStageAt(item.Timer, item.Lifetime))
+		}
+	} else {
+		var err error
+		if item, err = CreateItem(s.Vnum); err != nil {
+			return nil, err
+		}
+		item.Timer = s.Timer
+	}
+	item.BoundTo = s.BoundTo
+	item.Gold = s.Gold
+	return item, nil
+}
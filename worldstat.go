@@ -0,0 +1,263 @@
+/*
+ * worldstat.go
+ *
+ * This file implements the 'worldstat' command, a dashboard of the world's
+ * live state for debugging the reset system while the game runs. It shows
+ * how many mobs each area has against what its resets aim for, resets that
+ * are over their room limit or can't spawn, mob counts that have drifted
+ * from the mobs actually in the world, and doors whose two sides disagree
+ * about whether they're open or locked. Give an area to see only that one.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxWorldstatProblems is the most problems listed in each section of the dashboard
+const MaxWorldstatProblems = 20
+
+// areaPopulation is how many mobs an area has against what its resets aim for
+type areaPopulation struct {
+	File    string
+	Name    string
+	Present int // Mobs in the area's rooms
+	Target  int // Mobs the area's resets would spawn if nothing stopped them
+}
+
+// worldstatAreas returns the area files the dashboard covers, sorted, or none if the filter matches nothing
+func worldstatAreas(filter string) []string {
+	var files []string
+	for file, area := range allAreas() {
+		if filter == "" || strings.EqualFold(strings.TrimSuffix(file, ".yml"), filter) ||
+			strings.EqualFold(file, filter) || strings.HasPrefix(strings.ToLower(area.Name), strings.ToLower(filter)) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// resetTarget is how many of a mob an area's resets aim to keep in the world
+func resetTarget(resets []MobReset) map[int]int {
+	targets := make(map[int]int)
+	for _, reset := range resets {
+		targets[reset.MobVnum] += reset.Limit
+	}
+	for vnum, target := range targets {
+		if maxWorld := GetMobMaxWorld(vnum); target > maxWorld {
+			targets[vnum] = maxWorld
+		}
+	}
+	return targets
+}
+
+// spawnProblems describes resets that are over their room limit or can't spawn anything
+// The caller must hold mobMutex.
+func spawnProblems(files []string) []string {
+	var problems []string
+	for _, file := range files {
+		for _, reset := range allAreas()[file].MobResets {
+			template := mobRegistry[reset.MobVnum]
+			room := allRooms()[reset.RoomVnum]
+			switch {
+			case template == nil:
+				problems = append(problems, fmt.Sprintf("[%5d] mob %d doesn't exist", reset.RoomVnum, reset.MobVnum))
+				continue
+			case room == nil:
+				problems = append(problems, fmt.Sprintf("[%5d] room doesn't exist for mob %d", reset.RoomVnum, reset.MobVnum))
+				continue
+			case reset.Limit <= 0 || reset.MaxWorld <= 0:
+				problems = append(problems, fmt.Sprintf("[%5d] %s never spawns (limit %d, max world %d)", room.ID, template.ShortDescription, reset.Limit, reset.MaxWorld))
+				continue
+			}
+
+			count := 0
+			for _, instance := range roomMobs[room.ID] {
+				if instance.ID == reset.MobVnum {
+					count++
+				}
+			}
+			switch {
+			case count > reset.Limit:
+				problems = append(problems, fmt.Sprintf("[%5d] %s over limit: %d of %d", room.ID, template.ShortDescription, count, reset.Limit))
+			case count < reset.Limit && worldMobCounts[reset.MobVnum] >= GetMobMaxWorld(reset.MobVnum) && (!template.Nocturnal || IsNight()):
+				// Every copy the world allows is somewhere else, so this room can't refill
+				problems = append(problems, fmt.Sprintf("[%5d] %s stuck: %d of %d, world limit of %d reached elsewhere", room.ID, template.ShortDescription, count, reset.Limit, GetMobMaxWorld(reset.MobVnum)))
+			}
+		}
+	}
+	return problems
+}
+
+// countDrift describes mobs whose world count no longer matches the instances in the world
+// The caller must hold mobMutex.
+func countDrift() []string {
+	actual := make(map[int]int)
+	for _, instance := range mobInstances {
+		actual[instance.ID]++
+	}
+
+	var vnums []int
+	for vnum := range worldMobCounts {
+		vnums = append(vnums, vnum)
+	}
+	for vnum := range actual {
+		if _, ok := worldMobCounts[vnum]; !ok {
+			vnums = append(vnums, vnum)
+		}
+	}
+	sort.Ints(vnums)
+
+	var problems []string
+	for _, vnum := range vnums {
+		if worldMobCounts[vnum] != actual[vnum] {
+			problems = append(problems, fmt.Sprintf("mob %d counted %d, but %d are in the world", vnum, worldMobCounts[vnum], actual[vnum]))
+		}
+	}
+	return problems
+}
+
+// doorProblems describes doors whose two sides disagree, listing each door once
+func doorProblems(files []string) []string {
+	inArea := make(map[string]bool)
+	for _, file := range files {
+		inArea[file] = true
+	}
+
+	var ids []int
+	for id, room := range allRooms() {
+		if inArea[room.Area] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	seen := make(map[string]bool)
+	var problems []string
+	for _, id := range ids {
+		room := allRooms()[id]
+		for direction, exit := range room.Exits {
+			if exit.Door == nil {
+				continue
+			}
+			dest := allRooms()[exit.To]
+			if dest == nil {
+				continue
+			}
+
+			// Each door is seen from both sides; report it from the lower room
+			key := fmt.Sprintf("%d:%d", id, dest.ID)
+			if dest.ID < id {
+				key = fmt.Sprintf("%d:%d", dest.ID, id)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			back := dest.Exits[GetOppositeDirection(direction)]
+			switch {
+			case back == nil || back.To != id:
+				problems = append(problems, fmt.Sprintf("[%5d] %s %s: no way back from %d", id, direction, exit.Door.ShortDescription, dest.ID))
+			case back.Door == nil:
+				problems = append(problems, fmt.Sprintf("[%5d] %s %s: no door on the %d side", id, direction, exit.Door.ShortDescription, dest.ID))
+			case back.Door.Closed != exit.Door.Closed || back.Door.Locked != exit.Door.Locked:
+				problems = append(problems, fmt.Sprintf("[%5d] %s %s: %s here, %s from %d", id, direction, exit.Door.ShortDescription,
+					doorState(exit.Door), doorState(back.Door), dest.ID))
+			}
+		}
+	}
+	return problems
+}
+
+// doorState describes whether a door is open, closed, or locked
+func doorState(door *Door) string {
+	switch {
+	case door.Locked:
+		return "locked"
+	case door.Closed:
+		return "closed"
+	default:
+		return "open"
+	}
+}
+
+// writeProblems adds a section of problems to the dashboard, cut short if it runs long
+func writeProblems(sb *strings.Builder, title string, problems []string) {
+	sb.WriteString(fmt.Sprintf("\r\n{C}%s (%d):{x}\r\n", title, len(problems)))
+	if len(problems) == 0 {
+		sb.WriteString("  None\r\n")
+		return
+	}
+	for i, problem := range problems {
+		if i == MaxWorldstatProblems {
+			sb.WriteString(fmt.Sprintf("  ...and %d more\r\n", len(problems)-MaxWorldstatProblems))
+			break
+		}
+		sb.WriteString("  " + problem + "\r\n")
+	}
+}
+
+// handleWorldstat shows the state of the world's mobs, resets, and doors
+// Usage: worldstat [area]
+func handleWorldstat(player *Player, args []string) string {
+	filter := strings.Join(args, " ")
+	files := worldstatAreas(filter)
+	if len(files) == 0 {
+		return fmt.Sprintf("There is no area called '%s'.", filter)
+	}
+
+	mobMutex.RLock()
+	populations := make([]areaPopulation, 0, len(files))
+	for _, file := range files {
+		pop := areaPopulation{File: file, Name: allAreas()[file].Name}
+		for _, target := range resetTarget(allAreas()[file].MobResets) {
+			pop.Target += target
+		}
+		populations = append(populations, pop)
+	}
+	index := make(map[string]int)
+	for i, pop := range populations {
+		index[pop.File] = i
+	}
+	for roomID, mobs := range roomMobs {
+		if room := allRooms()[roomID]; room != nil {
+			if i, ok := index[room.Area]; ok {
+				populations[i].Present += len(mobs)
+			}
+		}
+	}
+	spawns := spawnProblems(files)
+	drift := countDrift()
+	mobMutex.RUnlock()
+
+	var sb strings.Builder
+	sb.WriteString("{C}Mob population:{x}\r\n")
+	sb.WriteString(fmt.Sprintf("  %-30s %7s %7s\r\n", "Area", "Present", "Target"))
+	totalPresent, totalTarget := 0, 0
+	for _, pop := range populations {
+		color := "{x}"
+		if pop.Present < pop.Target {
+			color = "{Y}"
+		} else if pop.Present > pop.Target && pop.Target > 0 {
+			color = "{R}"
+		}
+		sb.WriteString(fmt.Sprintf("  %-30.30s %s%7d{x} %7d\r\n", pop.Name, color, pop.Present, pop.Target))
+		totalPresent += pop.Present
+		totalTarget += pop.Target
+	}
+	if len(populations) > 1 {
+		sb.WriteString(fmt.Sprintf("  %-30s %7d %7d\r\n", "Total", totalPresent, totalTarget))
+	}
+
+	writeProblems(&sb, "Spawn problems", spawns)
+	if filter == "" {
+		writeProblems(&sb, "Mob count drift", drift)
+	}
+	writeProblems(&sb, "Doors out of sync", doorProblems(files))
+	return strings.TrimSuffix(sb.String(), "\r\n")
+}
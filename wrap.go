@@ -0,0 +1,184 @@
+/*
+ * wrap.go
+ *
+ * This file implements word wrapping of output to fit the player's window.
+ * Clients that report their window size have every line longer than the
+ * window broken between words, so room descriptions and help text don't
+ * split mid-word at the edge of the screen. Continuation lines keep the
+ * indentation of the line they came from. 'width' sets a fixed width for
+ * clients that don't report one, or turns wrapping off, and is saved with
+ * the character.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Settings for the wrap width besides a number of columns
+const (
+	WidthAuto = 0  // Wrap to the window, if the client reports its size
+	WidthOff  = -1 // Never wrap output
+)
+
+// Bounds on a wrap width the player sets themselves
+const (
+	MinWidth = 40
+	MaxWidth = 250
+)
+
+// wrapWidth returns how many columns the player's output is wrapped to, or 0 for no wrapping
+func (p *Player) wrapWidth() int {
+	switch {
+	case p.Width == WidthOff:
+		return 0
+	case p.Width > 0:
+		return p.Width
+	}
+	tc, ok := p.Conn.(*TelnetConn)
+	if !ok {
+		return 0
+	}
+	// Ignore sizes too small to wrap anything sensibly
+	if width, _ := tc.WindowSize(); width >= MinWidth {
+		return width
+	}
+	return 0
+}
+
+// wrap breaks the lines of a message that are too long for the player's window
+func (p *Player) wrap(message string) string {
+	width := p.wrapWidth()
+	if width == 0 {
+		return message
+	}
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		line = strings.TrimSuffix(line, "\r")
+		if visibleWidth(line) > width {
+			line = wrapLine(line, width)
+		}
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine breaks one line between words so no part is wider than width
+// Continuation lines are indented as the line was. A word wider than the line is left whole.
+func wrapLine(line string, width int) string {
+	body := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(body)]
+	if len(indent) > width/2 {
+		indent = ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(line[:len(line)-len(body)])
+	column := len(line) - len(body)
+	lineStart := true
+	spaces := 0
+	for _, word := range strings.Split(body, " ") {
+		if word == "" {
+			spaces++ // Runs of spaces are kept within a line
+			continue
+		}
+		w := visibleWidth(word)
+		if !lineStart && column+spaces+w > width {
+			sb.WriteString("\r\n")
+			sb.WriteString(indent)
+			column = len(indent)
+		} else if !lineStart {
+			sb.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+		}
+		sb.WriteString(word)
+		column += w
+		lineStart = false
+		spaces = 1
+	}
+	return sb.String()
+}
+
+// visibleWidth returns how many columns text takes up on screen, not counting color codes
+func visibleWidth(text string) int {
+	width := 0
+	for i := 0; i < len(text); {
+		switch {
+		case text[i] == '{' && i+3 <= len(text) && ColorMap[text[i:i+3]] != "":
+			i += 3
+		case text[i] == '\x1b':
+			// Skip an ANSI escape sequence through its final letter
+			i++
+			for i < len(text) && !(text[i] >= 'A' && text[i] <= 'Z' || text[i] >= 'a' && text[i] <= 'z') {
+				i++
+			}
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			width++
+		}
+	}
+	return width
+}
+
+// LoadWidth restores the player's wrap width
+func (p *Player) LoadWidth() {
+	width, err := LoadPlayerWidth(p.Name)
+	if err != nil {
+		log.Printf("Error loading wrap width for %s: %v", p.Name, err)
+		return
+	}
+	p.Width = width
+}
+
+// describeWidth says how the player's output is wrapped
+func (p *Player) describeWidth() string {
+	switch {
+	case p.Width == WidthOff:
+		return "Output isn't wrapped."
+	case p.Width > 0:
+		return fmt.Sprintf("Output is wrapped at %d columns.", p.Width)
+	}
+	if width := p.wrapWidth(); width > 0 {
+		return fmt.Sprintf("Output is wrapped to fit your window, at %d columns.", width)
+	}
+	return "Output will be wrapped to fit your window, but your client hasn't said how wide it is."
+}
+
+// handleWidth shows or sets the width output is wrapped to
+// Usage: width [<columns>|auto|off]
+func handleWidth(player *Player, args []string) string {
+	if len(args) == 0 {
+		return player.describeWidth()
+	}
+
+	var width int
+	switch strings.ToLower(args[0]) {
+	case "auto":
+		width = WidthAuto
+	case "off":
+		width = WidthOff
+	default:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < MinWidth || n > MaxWidth {
+			return fmt.Sprintf("Usage: width <%d-%d>|auto|off", MinWidth, MaxWidth)
+		}
+		width = n
+	}
+
+	player.Width = width
+	if err := UpdatePlayerWidth(player.Name, width); err != nil {
+		log.Printf("Error saving wrap width for %s: %v", player.Name, err)
+	}
+	return player.describeWidth()
+}